@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// adminClient 管理命令的客户端
+// 分布式相关的接口（/cluster/*、/connz）调用管理端（manager.addr），用户和消息相关的接口调用API（external.apiUrl）
+type adminClient struct {
+	managerUrl string // 管理端地址
+	apiUrl     string // API地址
+	token      string // 管理者token（managerToken）
+	username   string // 管理端用户名（没有token时通过用户名密码登录管理端）
+	password   string // 管理端密码
+	output     string // 输出格式 table 或 json
+	timeout    time.Duration
+
+	jwtToken string // 管理端登录后的jwt
+}
+
+// column 表格输出的列
+type column struct {
+	title string // 列标题
+	key   string // json字段名
+}
+
+func newAdminClient() *adminClient {
+	return &adminClient{}
+}
+
+// bindFlags 绑定公共的参数
+func (a *adminClient) bindFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&a.managerUrl, "manager", "", "manager url, default is http://127.0.0.1:<port of manager.addr>")
+	cmd.PersistentFlags().StringVar(&a.apiUrl, "api", "", "api url, default is external.apiUrl in config")
+	cmd.PersistentFlags().StringVar(&a.token, "token", "", "manager token, default is managerToken in config")
+	cmd.PersistentFlags().StringVar(&a.username, "user", "", "manager username, used to login when there is no manager token")
+	cmd.PersistentFlags().StringVar(&a.password, "password", "", "manager password")
+	cmd.PersistentFlags().StringVarP(&a.output, "output", "o", "table", "output format: table or json")
+	cmd.PersistentFlags().DurationVar(&a.timeout, "timeout", time.Second*30, "request timeout")
+}
+
+func (a *adminClient) getManagerUrl() string {
+	if a.managerUrl != "" {
+		return strings.TrimSuffix(a.managerUrl, "/")
+	}
+	port := "5300"
+	if idx := strings.LastIndex(serverOpts.Manager.Addr, ":"); idx >= 0 {
+		port = serverOpts.Manager.Addr[idx+1:]
+	}
+	return fmt.Sprintf("http://127.0.0.1:%s", port)
+}
+
+func (a *adminClient) getApiUrl() string {
+	if a.apiUrl != "" {
+		return strings.TrimSuffix(a.apiUrl, "/")
+	}
+	return strings.TrimSuffix(serverOpts.External.APIUrl, "/")
+}
+
+func (a *adminClient) getToken() string {
+	if a.token != "" {
+		return a.token
+	}
+	return serverOpts.ManagerToken
+}
+
+// managerGet 调用管理端的GET接口
+func (a *adminClient) managerGet(path string, query url.Values) ([]byte, error) {
+	headers, err := a.managerHeaders()
+	if err != nil {
+		return nil, err
+	}
+	if len(query) > 0 {
+		path = path + "?" + query.Encode()
+	}
+	return a.do(http.MethodGet, a.getManagerUrl()+path, nil, headers)
+}
+
+// managerPost 调用管理端的POST接口
+func (a *adminClient) managerPost(path string, body interface{}) ([]byte, error) {
+	headers, err := a.managerHeaders()
+	if err != nil {
+		return nil, err
+	}
+	return a.do(http.MethodPost, a.getManagerUrl()+path, body, headers)
+}
+
+// apiPost 调用API的POST接口
+func (a *adminClient) apiPost(path string, body interface{}) ([]byte, error) {
+	return a.do(http.MethodPost, a.getApiUrl()+path, body, map[string]string{"token": a.getToken()})
+}
+
+func (a *adminClient) managerHeaders() (map[string]string, error) {
+	if token := a.getToken(); token != "" {
+		return map[string]string{"token": token}, nil
+	}
+	if a.username == "" {
+		return nil, errors.New("manager token or --user/--password is required")
+	}
+	if a.jwtToken == "" {
+		data, err := a.do(http.MethodPost, a.getManagerUrl()+"/manager/login", map[string]string{
+			"username": a.username,
+			"password": a.password,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Token string `json:"token"`
+		}
+		if err = json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+		a.jwtToken = resp.Token
+	}
+	return map[string]string{"Authorization": "Bearer " + a.jwtToken}, nil
+}
+
+func (a *adminClient) do(method string, reqUrl string, body interface{}, headers map[string]string) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, reqUrl, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	client := &http.Client{Timeout: a.timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request %s failed, status: %d, body: %s", reqUrl, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// print 输出结果，json格式原样输出，table格式按columns输出列表（没有指定列时输出所有字段）
+// 列表取自返回值本身（数组）或者返回值中的data、connections、messages字段
+func (a *adminClient) print(data []byte, columns []column) error {
+	if a.output == "json" {
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return err
+		}
+		fmt.Println(out.String())
+		return nil
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		fmt.Println("ok")
+		return nil
+	}
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return err
+	}
+	rows, ok := tableRows(v)
+	if !ok { // 单个对象，按字段输出
+		obj, isObj := v.(map[string]interface{})
+		if !isObj {
+			fmt.Println(formatValue(v))
+			return nil
+		}
+		return printObject(obj)
+	}
+	if len(columns) == 0 && len(rows) > 0 {
+		columns = allColumns(rows[0])
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	titles := make([]string, 0, len(columns))
+	for _, col := range columns {
+		titles = append(titles, col.title)
+	}
+	fmt.Fprintln(w, strings.Join(titles, "\t"))
+	for _, row := range rows {
+		values := make([]string, 0, len(columns))
+		for _, col := range columns {
+			values = append(values, formatValue(row[col.key]))
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+	return w.Flush()
+}
+
+func tableRows(v interface{}) ([]map[string]interface{}, bool) {
+	var list []interface{}
+	switch value := v.(type) {
+	case []interface{}:
+		list = value
+	case map[string]interface{}:
+		found := false
+		for _, key := range []string{"data", "connections", "messages"} {
+			if l, ok := value[key].([]interface{}); ok {
+				list = l
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	rows := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if row, ok := item.(map[string]interface{}); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows, true
+}
+
+func printObject(obj map[string]interface{}) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\n", strings.ToUpper(k), formatValue(obj[k]))
+	}
+	return w.Flush()
+}
+
+func allColumns(row map[string]interface{}) []column {
+	keys := make([]string, 0, len(row))
+	for k := range row {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	columns := make([]column, 0, len(keys))
+	for _, k := range keys {
+		columns = append(columns, column{title: strings.ToUpper(k), key: k})
+	}
+	return columns
+}
+
+func formatValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		if value == "" {
+			return "-"
+		}
+		return value
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, formatValue(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		data, _ := json.Marshal(value)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type backupCMD struct {
+	ctx    *WuKongIMContext
+	apiUrl string
+	token  string
+}
+
+func newBackupCMD(ctx *WuKongIMContext) *backupCMD {
+	return &backupCMD{
+		ctx: ctx,
+	}
+}
+
+func (b *backupCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "take an online backup of every node in the WuKongIM cluster",
+		Long:  "Take consistent snapshots of every node's data while the cluster is running and upload them to backup.target. All nodes must be online.",
+		RunE:  b.run,
+	}
+	cmd.Flags().StringVar(&b.apiUrl, "api", "", "api url of any node in the cluster, default is external.apiUrl in config")
+	cmd.Flags().StringVar(&b.token, "token", "", "manager token, default is managerToken in config")
+	return cmd
+}
+
+func (b *backupCMD) run(cmd *cobra.Command, args []string) error {
+	apiUrl := b.apiUrl
+	if apiUrl == "" {
+		apiUrl = serverOpts.External.APIUrl
+	}
+	token := b.token
+	if token == "" {
+		token = serverOpts.ManagerToken
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(apiUrl, "/")+"/backup", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("token", token)
+
+	client := &http.Client{Timeout: serverOpts.Backup.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backup failed, status: %d, body: %s", resp.StatusCode, string(body))
+	}
+	fmt.Println(string(body))
+	return nil
+}
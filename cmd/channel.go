@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type channelCMD struct {
+	ctx    *WuKongIMContext
+	client *adminClient
+
+	leaderTo uint64
+}
+
+func newChannelCMD(ctx *WuKongIMContext) *channelCMD {
+	return &channelCMD{
+		ctx:    ctx,
+		client: newAdminClient(),
+	}
+}
+
+func (c *channelCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "channel",
+		Short: "channel operations (cluster config, replicas, leader, start and stop)",
+	}
+	c.client.bindFlags(cmd)
+
+	infoCmd := &cobra.Command{
+		Use:   "info <channelId> <channelType>",
+		Short: "show the cluster config of a channel",
+		Args:  cobra.ExactArgs(2),
+		RunE:  c.info,
+	}
+	replicasCmd := &cobra.Command{
+		Use:   "replicas <channelId> <channelType>",
+		Short: "show the replicas of a channel",
+		Args:  cobra.ExactArgs(2),
+		RunE:  c.replicas,
+	}
+	leaderCmd := &cobra.Command{
+		Use:   "leader <channelId> <channelType>",
+		Short: "show the leader of a channel, or transfer the leader with --to",
+		Args:  cobra.ExactArgs(2),
+		RunE:  c.leader,
+	}
+	leaderCmd.Flags().Uint64Var(&c.leaderTo, "to", 0, "transfer the channel leader to this node")
+	startCmd := &cobra.Command{
+		Use:   "start <channelId> <channelType>",
+		Short: "start (load) a channel on its leader",
+		Args:  cobra.ExactArgs(2),
+		RunE:  c.start,
+	}
+	stopCmd := &cobra.Command{
+		Use:   "stop <channelId> <channelType>",
+		Short: "stop (unload) a channel on its replicas",
+		Args:  cobra.ExactArgs(2),
+		RunE:  c.stop,
+	}
+
+	cmd.AddCommand(infoCmd, replicasCmd, leaderCmd, startCmd, stopCmd)
+	return cmd
+}
+
+func channelPath(args []string, action string) string {
+	return fmt.Sprintf("/cluster/channels/%s/%s/%s", args[0], args[1], action)
+}
+
+func (c *channelCMD) info(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerGet(channelPath(args, "config"), nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, nil)
+}
+
+func (c *channelCMD) replicas(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerGet(channelPath(args, "replicas"), nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, []column{
+		{title: "REPLICA", key: "replica_id"},
+		{title: "ROLE", key: "role_format"},
+		{title: "RUNNING", key: "running"},
+		{title: "LAST_MSG_SEQ", key: "last_msg_seq"},
+		{title: "LAST_MSG_TIME", key: "last_msg_time_format"},
+	})
+}
+
+func (c *channelCMD) leader(cmd *cobra.Command, args []string) error {
+	if c.leaderTo != 0 {
+		data, err := c.client.managerPost(channelPath(args, "transferLeader"), map[string]uint64{
+			"to_node_id": c.leaderTo,
+		})
+		if err != nil {
+			return err
+		}
+		return c.client.print(data, nil)
+	}
+	data, err := c.client.managerGet(channelPath(args, "config"), nil)
+	if err != nil {
+		return err
+	}
+	var cfg map[string]interface{}
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+	leader := map[string]interface{}{}
+	for _, key := range []string{"channel_id", "channel_type", "leader_id", "term", "replicas", "learners", "slot_id", "slot_leader_id"} {
+		if v, ok := cfg[key]; ok {
+			leader[key] = v
+		}
+	}
+	data, err = json.Marshal(leader)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, nil)
+}
+
+func (c *channelCMD) start(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerPost(channelPath(args, "start"), nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, nil)
+}
+
+func (c *channelCMD) stop(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerPost(channelPath(args, "stop"), nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, nil)
+}
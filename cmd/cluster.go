@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type clusterCMD struct {
+	ctx    *WuKongIMContext
+	client *adminClient
+
+	migrateFrom uint64
+	migrateTo   uint64
+}
+
+func newClusterCMD(ctx *WuKongIMContext) *clusterCMD {
+	return &clusterCMD{
+		ctx:    ctx,
+		client: newAdminClient(),
+	}
+}
+
+func (c *clusterCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cluster",
+		Short: "cluster operations (nodes, slots, slot migration, replica placement)",
+	}
+	c.client.bindFlags(cmd)
+
+	nodesCmd := &cobra.Command{
+		Use:   "nodes",
+		Short: "list all nodes of the cluster",
+		Args:  cobra.NoArgs,
+		RunE:  c.nodes,
+	}
+	slotsCmd := &cobra.Command{
+		Use:   "slots",
+		Short: "list all slots of the cluster",
+		Args:  cobra.NoArgs,
+		RunE:  c.slots,
+	}
+	migrateCmd := &cobra.Command{
+		Use:   "migrate <slotId>",
+		Short: "migrate a slot replica from one node to another",
+		Args:  cobra.ExactArgs(1),
+		RunE:  c.migrate,
+	}
+	migrateCmd.Flags().Uint64Var(&c.migrateFrom, "from", 0, "node id the slot replica migrates from")
+	migrateCmd.Flags().Uint64Var(&c.migrateTo, "to", 0, "node id the slot replica migrates to")
+	placementCmd := &cobra.Command{
+		Use:   "placement",
+		Short: "list slots and channels whose replicas are not spread across zones/racks",
+		Args:  cobra.NoArgs,
+		RunE:  c.placement,
+	}
+
+	cmd.AddCommand(nodesCmd, slotsCmd, migrateCmd, placementCmd)
+	return cmd
+}
+
+func (c *clusterCMD) nodes(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerGet("/cluster/nodes", nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, []column{
+		{title: "ID", key: "id"},
+		{title: "LEADER", key: "is_leader"},
+		{title: "ONLINE", key: "online"},
+		{title: "STATUS", key: "status_format"},
+		{title: "CLUSTER_ADDR", key: "cluster_addr"},
+		{title: "API_ADDR", key: "api_server_addr"},
+		{title: "SLOTS", key: "slot_count"},
+		{title: "SLOT_LEADERS", key: "slot_leader_count"},
+		{title: "TERM", key: "term"},
+		{title: "ZONE", key: "zone"},
+		{title: "RACK", key: "rack"},
+		{title: "UPTIME", key: "uptime"},
+		{title: "VERSION", key: "app_version"},
+	})
+}
+
+func (c *clusterCMD) slots(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerGet("/cluster/allslot", nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, []column{
+		{title: "ID", key: "id"},
+		{title: "LEADER", key: "leader_id"},
+		{title: "TERM", key: "term"},
+		{title: "REPLICAS", key: "replicas"},
+		{title: "CHANNELS", key: "channel_count"},
+		{title: "LOG_INDEX", key: "log_index"},
+		{title: "STATUS", key: "status_format"},
+	})
+}
+
+func (c *clusterCMD) migrate(cmd *cobra.Command, args []string) error {
+	if c.migrateFrom == 0 || c.migrateTo == 0 {
+		return errors.New("--from and --to are required")
+	}
+	data, err := c.client.managerPost(fmt.Sprintf("/cluster/slots/%s/migrate", args[0]), map[string]uint64{
+		"migrate_from": c.migrateFrom,
+		"migrate_to":   c.migrateTo,
+	})
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, nil)
+}
+
+func (c *clusterCMD) placement(cmd *cobra.Command, args []string) error {
+	data, err := c.client.managerGet("/cluster/placement/violations", nil)
+	if err != nil {
+		return err
+	}
+	return c.client.print(data, []column{
+		{title: "TYPE", key: "type"},
+		{title: "SLOT", key: "slot_id"},
+		{title: "CHANNEL_ID", key: "channel_id"},
+		{title: "CHANNEL_TYPE", key: "channel_type"},
+		{title: "REPLICAS", key: "replicas"},
+		{title: "ZONES", key: "zones"},
+		{title: "LEVEL", key: "level"},
+		{title: "ACTUAL", key: "actual"},
+		{title: "EXPECTED", key: "expected"},
+	})
+}
@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/spf13/cobra"
+)
+
+type dbCMD struct {
+	ctx *WuKongIMContext
+
+	dataDir     string
+	channelId   string
+	channelType uint8
+	limit       int
+	table       string
+	maxIssues   int
+	jsonOutput  bool
+}
+
+func newDbCMD(ctx *WuKongIMContext) *dbCMD {
+	return &dbCMD{
+		ctx: ctx,
+	}
+}
+
+func (d *dbCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "inspect and repair the data of a stopped node",
+		Long:  "Open the data dir of a stopped node to dump tables, verify index consistency and log indexes, and rebuild indexes. The node must be stopped.",
+	}
+	cmd.PersistentFlags().StringVar(&d.dataDir, "dataDir", "", "data dir of the node, default is dataDir in config")
+
+	dumpCmd := &cobra.Command{
+		Use:       "dump <messages|users|channels|conversations|clusterconfigs>",
+		Short:     "dump a table as json lines",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"messages", "users", "channels", "conversations", "clusterconfigs"},
+		RunE:      d.dump,
+	}
+	dumpCmd.Flags().StringVar(&d.channelId, "channel", "", "only dump messages of this channel")
+	dumpCmd.Flags().Uint8Var(&d.channelType, "channel-type", 0, "channel type of --channel")
+	dumpCmd.Flags().IntVar(&d.limit, "limit", 100, "max number of rows, 0 means no limit")
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "verify secondary indexes against rows, channel last message seq and applied index against last log index",
+		Args:  cobra.NoArgs,
+		RunE:  d.verify,
+	}
+	verifyCmd.Flags().StringVar(&d.table, "table", "all", fmt.Sprintf("table to verify: all, log, %v", wkdb.InspectTables))
+	verifyCmd.Flags().IntVar(&d.maxIssues, "max-issues", 100, "max number of issues to print per table, 0 means no limit")
+	verifyCmd.Flags().BoolVar(&d.jsonOutput, "json", false, "print the result as json")
+
+	rebuildCmd := &cobra.Command{
+		Use:   "rebuild-index",
+		Short: "delete all indexes of a table and rebuild them from rows",
+		Args:  cobra.NoArgs,
+		RunE:  d.rebuildIndex,
+	}
+	rebuildCmd.Flags().StringVar(&d.table, "table", "", fmt.Sprintf("table to rebuild: all, %v", wkdb.InspectTables))
+
+	cmd.AddCommand(dumpCmd, verifyCmd, rebuildCmd)
+	return cmd
+}
+
+func (d *dbCMD) getDataDir() string {
+	if d.dataDir != "" {
+		return d.dataDir
+	}
+	return serverOpts.DataDir
+}
+
+func (d *dbCMD) openDB(readOnly bool) (wkdb.DB, wkdb.Inspector, error) {
+	opts := []wkdb.Option{
+		wkdb.WithDir(path.Join(d.getDataDir(), "db")),
+		wkdb.WithShardNum(serverOpts.Db.ShardNum),
+		wkdb.WithSlotCount(serverOpts.Cluster.SlotCount),
+		wkdb.WithNodeId(serverOpts.Cluster.NodeId),
+	}
+	if readOnly {
+		opts = append(opts, wkdb.WithReadOnly())
+	}
+	db := wkdb.NewWukongDB(wkdb.NewOptions(opts...))
+	if err := db.Open(); err != nil {
+		return nil, nil, err
+	}
+	inspector, ok := db.(wkdb.Inspector)
+	if !ok {
+		db.Close()
+		return nil, nil, fmt.Errorf("db does not support inspect")
+	}
+	return db, inspector, nil
+}
+
+func (d *dbCMD) dump(cmd *cobra.Command, args []string) error {
+	db, inspector, err := d.openDB(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	encoder := json.NewEncoder(os.Stdout)
+	count := 0
+	var encodeErr error
+	write := func(v interface{}) bool {
+		if encodeErr = encoder.Encode(v); encodeErr != nil {
+			return false
+		}
+		count++
+		return d.limit <= 0 || count < d.limit
+	}
+
+	switch args[0] {
+	case "messages":
+		err = inspector.ScanMessages(d.channelId, d.channelType, func(m wkdb.Message) bool {
+			return write(newMessageDump(m))
+		})
+	case "users":
+		err = inspector.ScanUsers(func(u wkdb.User) bool {
+			return write(u)
+		})
+	case "channels":
+		err = inspector.ScanChannels(func(ch wkdb.ChannelInfo) bool {
+			return write(ch)
+		})
+	case "conversations":
+		err = inspector.ScanConversations(func(c wkdb.Conversation) bool {
+			return write(c)
+		})
+	case "clusterconfigs":
+		err = inspector.ScanChannelClusterConfigs(func(cfg wkdb.ChannelClusterConfig) bool {
+			return write(cfg)
+		})
+	default:
+		return fmt.Errorf("unknown table: %s", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return encodeErr
+}
+
+// messageDump 导出的消息
+type messageDump struct {
+	MessageId   int64  `json:"message_id"`
+	MessageSeq  uint32 `json:"message_seq"`
+	ClientMsgNo string `json:"client_msg_no"`
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+	FromUid     string `json:"from_uid"`
+	Timestamp   int32  `json:"timestamp"`
+	Topic       string `json:"topic,omitempty"`
+	Expire      uint32 `json:"expire,omitempty"`
+	Term        uint64 `json:"term"`
+	Payload     string `json:"payload,omitempty"`     // 内容是文本时原样输出
+	PayloadRaw  []byte `json:"payload_raw,omitempty"` // 内容不是文本时base64输出
+}
+
+func newMessageDump(m wkdb.Message) messageDump {
+	dump := messageDump{
+		MessageId:   m.MessageID,
+		MessageSeq:  m.MessageSeq,
+		ClientMsgNo: m.ClientMsgNo,
+		ChannelId:   m.ChannelID,
+		ChannelType: m.ChannelType,
+		FromUid:     m.FromUID,
+		Timestamp:   m.Timestamp,
+		Topic:       m.Topic,
+		Expire:      m.Expire,
+		Term:        m.Term,
+	}
+	if utf8.Valid(m.Payload) {
+		dump.Payload = string(m.Payload)
+	} else {
+		dump.PayloadRaw = m.Payload
+	}
+	return dump
+}
+
+func (d *dbCMD) verify(cmd *cobra.Command, args []string) error {
+	tables := []string{d.table}
+	if d.table == "all" {
+		tables = append([]string{"log"}, wkdb.InspectTables...)
+	}
+
+	results := make([]*wkdb.VerifyResult, 0, len(tables))
+	for _, table := range tables {
+		if table != "log" {
+			continue
+		}
+		result, err := d.verifyLogs()
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	var (
+		db        wkdb.DB
+		inspector wkdb.Inspector
+		err       error
+	)
+	for _, table := range tables {
+		if table == "log" {
+			continue
+		}
+		if db == nil {
+			if db, inspector, err = d.openDB(true); err != nil {
+				return err
+			}
+			defer db.Close()
+		}
+		result, err := inspector.Verify(table, d.maxIssues)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+
+	issueCount := 0
+	for _, result := range results {
+		issueCount += result.IssueCount
+	}
+	if d.jsonOutput {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		d.printVerifyResults(results)
+	}
+	if issueCount > 0 {
+		return fmt.Errorf("found %d issues", issueCount)
+	}
+	return nil
+}
+
+// verifyLogs 检查集群配置日志和槽日志的已应用下标与最后日志下标
+func (d *dbCMD) verifyLogs() (*wkdb.VerifyResult, error) {
+	states, err := cluster.InspectLogs(path.Join(d.getDataDir(), "cluster"), serverOpts.Db.SlotShardNum, uint32(serverOpts.Cluster.SlotCount))
+	if err != nil {
+		return nil, err
+	}
+	result := &wkdb.VerifyResult{Table: "log", Rows: len(states)}
+	for _, st := range states {
+		if issue := st.Issue(); issue != "" {
+			result.IssueCount++
+			if d.maxIssues <= 0 || len(result.Issues) < d.maxIssues {
+				result.Issues = append(result.Issues, wkdb.VerifyIssue{Kind: "log", Detail: fmt.Sprintf("%s %s", st.Name, issue)})
+			}
+		}
+	}
+	if !d.jsonOutput {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LOG\tLAST_INDEX\tLAST_TERM\tAPPLIED_INDEX\tUNAPPLIED")
+		for _, st := range states {
+			unapplied := uint64(0)
+			if st.LastIndex > st.AppliedIndex {
+				unapplied = st.LastIndex - st.AppliedIndex
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", st.Name, st.LastIndex, st.LastTerm, st.AppliedIndex, unapplied)
+		}
+		w.Flush()
+		fmt.Println()
+	}
+	return result, nil
+}
+
+func (d *dbCMD) printVerifyResults(results []*wkdb.VerifyResult) {
+	for _, result := range results {
+		fmt.Printf("[%s] rows: %d indexes: %d", result.Table, result.Rows, result.Indexes)
+		if result.Table == wkdb.InspectTableMessage {
+			fmt.Printf(" channels: %d", result.Channels)
+		}
+		fmt.Printf(" issues: %d\n", result.IssueCount)
+		for _, issue := range result.Issues {
+			fmt.Printf("  %s shard[%d] %s\n", issue.Kind, issue.Shard, issue.Detail)
+		}
+		if result.IssueCount > len(result.Issues) {
+			fmt.Printf("  ... %d more issues\n", result.IssueCount-len(result.Issues))
+		}
+	}
+}
+
+func (d *dbCMD) rebuildIndex(cmd *cobra.Command, args []string) error {
+	if d.table == "" {
+		return fmt.Errorf("--table is required")
+	}
+	tables := []string{d.table}
+	if d.table == "all" {
+		tables = wkdb.InspectTables
+	}
+	db, inspector, err := d.openDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	for _, table := range tables {
+		rows, err := inspector.RebuildIndexes(table)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("[%s] rebuilt indexes of %d rows\n", table, rows)
+	}
+	return nil
+}
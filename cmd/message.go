@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+type messageCMD struct {
+	ctx    *WuKongIMContext
+	client *adminClient
+
+	fromUid     string
+	channelId   string
+	channelType uint8
+	clientMsgNo string
+	nodeId      uint64
+	limit       int
+	uid         string
+	keyword     string
+	page        int
+}
+
+func newMessageCMD(ctx *WuKongIMContext) *messageCMD {
+	return &messageCMD{
+		ctx:    ctx,
+		client: newAdminClient(),
+	}
+}
+
+func (m *messageCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "message",
+		Short: "message operations (get, search)",
+	}
+	m.client.bindFlags(cmd)
+
+	getCmd := &cobra.Command{
+		Use:   "get <messageId>",
+		Short: "get a message by message id",
+		Args:  cobra.ExactArgs(1),
+		RunE:  m.get,
+	}
+	getCmd.Flags().Uint64Var(&m.nodeId, "node", 0, "node id to query, default is the node of --manager")
+	searchCmd := &cobra.Command{
+		Use:   "search",
+		Short: "search messages by sender, channel or client msg no; with --keyword and --uid search by keyword",
+		Args:  cobra.NoArgs,
+		RunE:  m.search,
+	}
+	searchCmd.Flags().StringVar(&m.fromUid, "from", "", "sender uid")
+	searchCmd.Flags().StringVar(&m.channelId, "channel", "", "channel id")
+	searchCmd.Flags().Uint8Var(&m.channelType, "channel-type", 0, "channel type")
+	searchCmd.Flags().StringVar(&m.clientMsgNo, "client-msg-no", "", "client msg no")
+	searchCmd.Flags().Uint64Var(&m.nodeId, "node", 0, "node id to query, default is the node of --manager")
+	searchCmd.Flags().IntVar(&m.limit, "limit", 20, "max number of messages")
+	searchCmd.Flags().StringVar(&m.keyword, "keyword", "", "keyword to search, requires --uid")
+	searchCmd.Flags().StringVar(&m.uid, "uid", "", "search the channels this user is in (used with --keyword)")
+	searchCmd.Flags().IntVar(&m.page, "page", 1, "page of keyword search")
+
+	cmd.AddCommand(getCmd, searchCmd)
+	return cmd
+}
+
+var messageColumns = []column{
+	{title: "MESSAGE_ID", key: "message_id"},
+	{title: "SEQ", key: "message_seq"},
+	{title: "CHANNEL_ID", key: "channel_id"},
+	{title: "CHANNEL_TYPE", key: "channel_type"},
+	{title: "FROM", key: "from_uid"},
+	{title: "CLIENT_MSG_NO", key: "client_msg_no"},
+	{title: "TIME", key: "timestamp_format"},
+	{title: "PAYLOAD", key: "payload"},
+}
+
+func (m *messageCMD) get(cmd *cobra.Command, args []string) error {
+	query := url.Values{}
+	query.Set("message_id", args[0])
+	if m.nodeId != 0 {
+		query.Set("node_id", strconv.FormatUint(m.nodeId, 10))
+	}
+	data, err := m.client.managerGet("/cluster/messages", query)
+	if err != nil {
+		return err
+	}
+	return m.printMessages(data)
+}
+
+func (m *messageCMD) search(cmd *cobra.Command, args []string) error {
+	if m.keyword != "" {
+		if m.uid == "" {
+			return errors.New("--uid is required when searching by --keyword")
+		}
+		data, err := m.client.apiPost("/message/search", map[string]interface{}{
+			"uid":          m.uid,
+			"keyword":      m.keyword,
+			"channel_id":   m.channelId,
+			"channel_type": m.channelType,
+			"page":         m.page,
+			"limit":        m.limit,
+		})
+		if err != nil {
+			return err
+		}
+		return m.printMessages(data)
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(m.limit))
+	if m.fromUid != "" {
+		query.Set("from_uid", m.fromUid)
+	}
+	if m.channelId != "" {
+		query.Set("channel_id", m.channelId)
+		query.Set("channel_type", strconv.Itoa(int(m.channelType)))
+	}
+	if m.clientMsgNo != "" {
+		query.Set("client_msg_no", m.clientMsgNo)
+	}
+	if m.nodeId != 0 {
+		query.Set("node_id", strconv.FormatUint(m.nodeId, 10))
+	}
+	data, err := m.client.managerGet("/cluster/messages", query)
+	if err != nil {
+		return err
+	}
+	return m.printMessages(data)
+}
+
+// printMessages 输出消息，表格格式时将base64的payload解码为文本
+func (m *messageCMD) printMessages(data []byte) error {
+	if m.client.output == "json" {
+		return m.client.print(data, nil)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	rows, _ := tableRows(resp)
+	for _, row := range rows {
+		if _, ok := row["timestamp_format"]; !ok { // 关键词检索的结果只有timestamp
+			if ts, ok := row["timestamp"].(float64); ok {
+				row["timestamp_format"] = time.Unix(int64(ts), 0).Format("2006-01-02 15:04:05")
+			}
+		}
+		payloadStr, ok := row["payload"].(string)
+		if !ok {
+			continue
+		}
+		if payload, err := base64.StdEncoding.DecodeString(payloadStr); err == nil && utf8.Valid(payload) {
+			row["payload"] = string(payload)
+		}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return m.client.print(data, messageColumns)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/spf13/cobra"
+)
+
+type reloadCMD struct {
+	ctx    *WuKongIMContext
+	client *adminClient
+
+	cluster bool
+}
+
+func newReloadCMD(ctx *WuKongIMContext) *reloadCMD {
+	return &reloadCMD{
+		ctx:    ctx,
+		client: newAdminClient(),
+	}
+}
+
+func (r *reloadCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reload",
+		Short: "reload the reloadable config options (log level, webhook, datasource, token auth, auth, rate limits) without restarting",
+		Args:  cobra.NoArgs,
+		RunE:  r.run,
+	}
+	r.client.bindFlags(cmd)
+	cmd.Flags().BoolVar(&r.cluster, "cluster", false, "reload every node of the cluster through the manager api and print the result of each node")
+	return cmd
+}
+
+func (r *reloadCMD) run(cmd *cobra.Command, args []string) error {
+	if r.cluster {
+		data, err := r.client.managerPost("/manager/config/reload", nil)
+		if err != nil {
+			return err
+		}
+		return r.client.print(data, []column{
+			{title: "NODE", key: "node_id"},
+			{title: "SUCCESS", key: "success"},
+			{title: "CHANGED", key: "changed"},
+			{title: "ERROR", key: "error"},
+		})
+	}
+
+	// 给本机的服务发送SIGHUP，结果见服务日志
+	strb, err := os.ReadFile(path.Join(".", pidfile))
+	if err != nil {
+		return err
+	}
+	process, err := os.FindProcess(wkutil.ParseInt(string(strb)))
+	if err != nil {
+		return err
+	}
+	if err = process.Signal(syscall.SIGHUP); err != nil {
+		return err
+	}
+	fmt.Println("SIGHUP sent, see the server log for the reload result")
+	return nil
+}
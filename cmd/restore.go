@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkbackup"
+	"github.com/spf13/cobra"
+)
+
+type restoreCMD struct {
+	ctx      *WuKongIMContext
+	target   string
+	backupId string
+	before   string
+	nodeId   uint64
+	all      bool
+	dataDir  string
+	list     bool
+}
+
+func newRestoreCMD(ctx *WuKongIMContext) *restoreCMD {
+	return &restoreCMD{
+		ctx: ctx,
+	}
+}
+
+func (r *restoreCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "restore a node or the whole cluster from an online backup",
+		Long: `Restore node data from an online backup. The node must be stopped and its data dir must not contain db or cluster data.
+Restore a single node:   wk restore --config wk.yaml --backup 20240102T030405Z
+Restore to a time point: wk restore --config wk.yaml --before 2024-01-02T15:00:00+08:00
+Restore whole cluster:   wk restore --config wk.yaml --backup 20240102T030405Z --all --dataDir ./restore (data of node N is written to ./restore/nodeN)`,
+		RunE: r.run,
+	}
+	cmd.Flags().StringVar(&r.target, "target", "", "backup target, default is backup.target in config")
+	cmd.Flags().StringVar(&r.backupId, "backup", "", "backup id to restore")
+	cmd.Flags().StringVar(&r.before, "before", "", "restore the latest backup taken at or before this time (RFC3339)")
+	cmd.Flags().Uint64Var(&r.nodeId, "node", 0, "node id to restore, default is cluster.nodeId in config")
+	cmd.Flags().BoolVar(&r.all, "all", false, "restore all nodes of the backup, each node to <dataDir>/node<nodeId>")
+	cmd.Flags().StringVar(&r.dataDir, "dataDir", "", "data dir to restore to, default is dataDir in config")
+	cmd.Flags().BoolVar(&r.list, "list", false, "list all backups")
+	return cmd
+}
+
+func (r *restoreCMD) run(cmd *cobra.Command, args []string) error {
+	targetUrl := r.target
+	if targetUrl == "" {
+		targetUrl = serverOpts.Backup.Target
+	}
+	target, err := wkbackup.NewTarget(targetUrl, serverOpts.Backup.S3)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	manifests, err := wkbackup.ListBackups(ctx, target)
+	if err != nil {
+		return err
+	}
+	if r.list {
+		for _, m := range manifests {
+			fmt.Printf("%s\t%s\tnodes: %d\tversion: %s\n", m.BackupId, m.CreatedAt.Format(time.RFC3339), len(m.Nodes), m.AppVersion)
+		}
+		return nil
+	}
+
+	manifest, err := r.pickManifest(manifests)
+	if err != nil {
+		return err
+	}
+
+	dataDir := r.dataDir
+	if dataDir == "" {
+		dataDir = serverOpts.DataDir
+	}
+	if r.all {
+		for _, n := range manifest.Nodes {
+			nodeDataDir := filepath.Join(dataDir, fmt.Sprintf("node%d", n.NodeId))
+			if err = wkbackup.RestoreNode(ctx, target, manifest, n.NodeId, nodeDataDir); err != nil {
+				return err
+			}
+			fmt.Printf("restored node[%d] from backup[%s] to %s\n", n.NodeId, manifest.BackupId, nodeDataDir)
+		}
+		return nil
+	}
+
+	nodeId := r.nodeId
+	if nodeId == 0 {
+		nodeId = serverOpts.Cluster.NodeId
+	}
+	if err = wkbackup.RestoreNode(ctx, target, manifest, nodeId, dataDir); err != nil {
+		return err
+	}
+	fmt.Printf("restored node[%d] from backup[%s] to %s\n", nodeId, manifest.BackupId, dataDir)
+	return nil
+}
+
+func (r *restoreCMD) pickManifest(manifests []*wkbackup.Manifest) (*wkbackup.Manifest, error) {
+	if r.backupId != "" {
+		for _, m := range manifests {
+			if m.BackupId == r.backupId {
+				return m, nil
+			}
+		}
+		return nil, wkbackup.ErrBackupNotFound
+	}
+	if r.before == "" {
+		return nil, errors.New("--backup or --before is required")
+	}
+	before, err := time.Parse(time.RFC3339, r.before)
+	if err != nil {
+		return nil, err
+	}
+	manifest := wkbackup.PickBackup(manifests, before)
+	if manifest == nil {
+		return nil, fmt.Errorf("no backup taken at or before %s", r.before)
+	}
+	return manifest, nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -10,10 +11,12 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/WuKongIM/WuKongIM/internal/server"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -139,8 +142,21 @@ func cmdRun() error {
 			}
 		}
 
-		select {}
-
+		// 收到SIGHUP（wk reload）重新加载配置
+		// 收到SIGTERM（wk stop --drain）后先下线再停止：转移领导、分批断开客户端、等待消息投递完成
+		sigC := make(chan os.Signal, 1)
+		signal.Notify(sigC, syscall.SIGTERM, syscall.SIGHUP)
+		for sig := range sigC {
+			if sig != syscall.SIGHUP {
+				break
+			}
+			result := s.ReloadConfig()
+			wklog.Info("config reload", zap.Bool("success", result.Success), zap.Strings("changed", result.Changed), zap.String("error", result.Error))
+		}
+		wklog.Info("received SIGTERM, draining...")
+		status := s.Drain(context.Background())
+		wklog.Info("drain finished", zap.String("status", status.Status), zap.Int("slotLeaders", status.SlotLeaders), zap.Int("channelLeaders", status.ChannelLeaders), zap.Int("disconnectedConns", status.DisconnectedConns), zap.Strings("errors", status.Errors))
+		s.StopNoErr()
 	}
 	return nil
 }
@@ -260,6 +276,14 @@ func addCommand(cmd CMD) {
 func Execute() {
 	ctx := &WuKongIMContext{}
 	addCommand(newStopCMD(ctx))
+	addCommand(newReloadCMD(ctx))
+	addCommand(newBackupCMD(ctx))
+	addCommand(newRestoreCMD(ctx))
+	addCommand(newClusterCMD(ctx))
+	addCommand(newChannelCMD(ctx))
+	addCommand(newUserCMD(ctx))
+	addCommand(newMessageCMD(ctx))
+	addCommand(newDbCMD(ctx))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"syscall"
+	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"github.com/spf13/cobra"
@@ -11,6 +13,9 @@ import (
 
 type stopCMD struct {
 	ctx *WuKongIMContext
+
+	drain   bool
+	timeout time.Duration
 }
 
 func newStopCMD(ctx *WuKongIMContext) *stopCMD {
@@ -25,6 +30,8 @@ func (s *stopCMD) CMD() *cobra.Command {
 		Short: "stop the WuKongIM server",
 		RunE:  s.run,
 	}
+	cmd.Flags().BoolVar(&s.drain, "drain", false, "drain the node before stopping: hand off leaderships, disconnect clients gradually and wait for in-flight deliveries")
+	cmd.Flags().DurationVar(&s.timeout, "timeout", time.Minute*3, "max time to wait for the drained server to exit (only used with --drain)")
 	return cmd
 }
 
@@ -40,6 +47,10 @@ func (s *stopCMD) run(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	if s.drain {
+		return s.drainAndWait(process)
+	}
+
 	err = process.Kill()
 	if err != nil {
 		return err
@@ -47,3 +58,22 @@ func (s *stopCMD) run(cmd *cobra.Command, args []string) error {
 	fmt.Println("WuKongIM server stopped")
 	return nil
 }
+
+// drainAndWait 发送SIGTERM让服务下线后退出，并等待进程退出
+func (s *stopCMD) drainAndWait(process *os.Process) error {
+	err := process.Signal(syscall.SIGTERM)
+	if err != nil {
+		return err
+	}
+	fmt.Println("WuKongIM server is draining...")
+
+	deadline := time.Now().Add(s.timeout)
+	for time.Now().Before(deadline) {
+		if err = process.Signal(syscall.Signal(0)); err != nil { // 进程已退出
+			fmt.Println("WuKongIM server stopped")
+			return nil
+		}
+		time.Sleep(time.Millisecond * 500)
+	}
+	return fmt.Errorf("WuKongIM server did not exit within %s", s.timeout)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+type userCMD struct {
+	ctx    *WuKongIMContext
+	client *adminClient
+
+	deviceFlag int
+}
+
+func newUserCMD(ctx *WuKongIMContext) *userCMD {
+	return &userCMD{
+		ctx:    ctx,
+		client: newAdminClient(),
+	}
+}
+
+func (u *userCMD) CMD() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "user operations (connections, kick)",
+	}
+	u.client.bindFlags(cmd)
+
+	connsCmd := &cobra.Command{
+		Use:   "conns <uid>",
+		Short: "list the connections of a user on every node",
+		Args:  cobra.ExactArgs(1),
+		RunE:  u.conns,
+	}
+	kickCmd := &cobra.Command{
+		Use:   "kick <uid>",
+		Short: "force the devices of a user to quit",
+		Args:  cobra.ExactArgs(1),
+		RunE:  u.kick,
+	}
+	kickCmd.Flags().IntVar(&u.deviceFlag, "device-flag", -1, "device flag to kick (0.app 1.web 2.pc), -1 means all devices")
+
+	cmd.AddCommand(connsCmd, kickCmd)
+	return cmd
+}
+
+func (u *userCMD) conns(cmd *cobra.Command, args []string) error {
+	data, err := u.client.managerGet("/cluster/nodes", nil)
+	if err != nil {
+		return err
+	}
+	var nodesResp struct {
+		Data []struct {
+			Id     uint64 `json:"id"`
+			Online bool   `json:"online"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(data, &nodesResp); err != nil {
+		return err
+	}
+
+	// 连接分布在各个节点上，逐个节点查询后合并
+	connections := make([]map[string]interface{}, 0)
+	for _, node := range nodesResp.Data {
+		if !node.Online {
+			continue
+		}
+		query := url.Values{}
+		query.Set("uid", args[0])
+		query.Set("node_id", strconv.FormatUint(node.Id, 10))
+		query.Set("limit", "1000")
+		data, err = u.client.managerGet("/connz", query)
+		if err != nil {
+			return err
+		}
+		var connzResp struct {
+			Connections []map[string]interface{} `json:"connections"`
+		}
+		if err = json.Unmarshal(data, &connzResp); err != nil {
+			return err
+		}
+		for _, conn := range connzResp.Connections {
+			conn["node_id"] = node.Id
+			connections = append(connections, conn)
+		}
+	}
+	data, err = json.Marshal(map[string]interface{}{
+		"connections": connections,
+		"total":       len(connections),
+	})
+	if err != nil {
+		return err
+	}
+	return u.client.print(data, []column{
+		{title: "NODE", key: "node_id"},
+		{title: "ID", key: "id"},
+		{title: "UID", key: "uid"},
+		{title: "DEVICE", key: "device"},
+		{title: "DEVICE_ID", key: "device_id"},
+		{title: "IP", key: "ip"},
+		{title: "PORT", key: "port"},
+		{title: "VERSION", key: "version"},
+		{title: "PROXY", key: "proxy_type_format"},
+		{title: "UPTIME", key: "uptime"},
+		{title: "IDLE", key: "idle"},
+	})
+}
+
+func (u *userCMD) kick(cmd *cobra.Command, args []string) error {
+	data, err := u.client.apiPost("/user/device_quit", map[string]interface{}{
+		"uid":         args[0],
+		"device_flag": u.deviceFlag,
+	})
+	if err != nil {
+		return err
+	}
+	return u.client.print(data, nil)
+}
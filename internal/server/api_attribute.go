@@ -0,0 +1,350 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// AttributeAPI 用户和频道的自定义属性
+type AttributeAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewAttributeAPI(s *Server) *AttributeAPI {
+	return &AttributeAPI{
+		s:   s,
+		Log: wklog.NewWKLog("AttributeAPI"),
+	}
+}
+
+// Route 路由
+func (a *AttributeAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/user/attributes", a.userAttributesSet)                 // 设置用户属性
+	r.GET("/user/attributes", a.userAttributesGet)                  // 获取用户属性
+	r.POST("/user/attributes/delete", a.userAttributesDelete)       // 删除用户属性
+	r.POST("/channel/attributes", a.channelAttributesSet)           // 设置频道属性
+	r.GET("/channel/attributes", a.channelAttributesGet)            // 获取频道属性
+	r.POST("/channel/attributes/delete", a.channelAttributesDelete) // 删除频道属性
+}
+
+type attributeReq struct {
+	Name    string          `json:"name"`    // 属性名
+	Type    string          `json:"type"`    // 属性类型 string/int/float/bool/json
+	Value   json.RawMessage `json:"value"`   // 属性值
+	Version uint64          `json:"version"` // 期望的当前版本（check_version为true时有效，新增的属性为0）
+}
+
+type attributeResp struct {
+	Name      string          `json:"name"`       // 属性名
+	Type      string          `json:"type"`       // 属性类型
+	Value     json.RawMessage `json:"value"`      // 属性值
+	Version   uint64          `json:"version"`    // 版本
+	UpdatedAt int64           `json:"updated_at"` // 更新时间（unix秒）
+}
+
+func newAttributeResps(attrs []wkdb.Attribute) []attributeResp {
+	resps := make([]attributeResp, 0, len(attrs))
+	for _, attr := range attrs {
+		resp := attributeResp{
+			Name:    attr.Name,
+			Type:    attr.Type.String(),
+			Value:   json.RawMessage(attr.Value),
+			Version: attr.Version,
+		}
+		if attr.UpdatedAt != nil {
+			resp.UpdatedAt = attr.UpdatedAt.Unix()
+		}
+		resps = append(resps, resp)
+	}
+	return resps
+}
+
+// checkAttributes 校验要设置的属性，olds为当前的所有属性
+func (a *AttributeAPI) checkAttributes(reqs []attributeReq, olds []wkdb.Attribute, checkVersion bool) ([]wkdb.Attribute, error) {
+	if len(reqs) == 0 {
+		return nil, errors.New("attributes不能为空！")
+	}
+	opts := a.s.opts.Attribute
+	oldMap := make(map[string]wkdb.Attribute, len(olds))
+	for _, old := range olds {
+		oldMap[old.Name] = old
+	}
+	names := make(map[string]struct{}, len(olds)+len(reqs))
+	for name := range oldMap {
+		names[name] = struct{}{}
+	}
+
+	updatedAt := time.Now()
+	attrs := make([]wkdb.Attribute, 0, len(reqs))
+	for _, req := range reqs {
+		if strings.TrimSpace(req.Name) == "" {
+			return nil, errors.New("属性名不能为空！")
+		}
+		if len(req.Name) > opts.MaxNameLen {
+			return nil, fmt.Errorf("属性名[%s]不能超过%d个字符！", req.Name, opts.MaxNameLen)
+		}
+		if strings.Contains(req.Name, ",") {
+			return nil, fmt.Errorf("属性名[%s]不能包含逗号！", req.Name)
+		}
+		attrType := wkdb.ParseAttributeType(req.Type)
+		if attrType == wkdb.AttributeTypeUnknown {
+			return nil, fmt.Errorf("属性[%s]的类型[%s]有误！", req.Name, req.Type)
+		}
+		value, err := checkAttributeValue(attrType, req.Value)
+		if err != nil {
+			return nil, fmt.Errorf("属性[%s]的值有误：%s", req.Name, err.Error())
+		}
+		if len(value) > opts.MaxValueSize {
+			return nil, fmt.Errorf("属性[%s]的值不能超过%d字节！", req.Name, opts.MaxValueSize)
+		}
+		if checkVersion && oldMap[req.Name].Version != req.Version {
+			return nil, fmt.Errorf("属性[%s]的版本不一致，当前版本为%d！", req.Name, oldMap[req.Name].Version)
+		}
+		names[req.Name] = struct{}{}
+		attrs = append(attrs, wkdb.Attribute{
+			Name:      req.Name,
+			Type:      attrType,
+			Value:     value,
+			Version:   req.Version,
+			UpdatedAt: &updatedAt,
+		})
+	}
+	if len(names) > opts.MaxCount {
+		return nil, fmt.Errorf("属性数量不能超过%d个！", opts.MaxCount)
+	}
+	return attrs, nil
+}
+
+// checkAttributeValue 校验属性值是否符合类型，返回压缩后的json
+func checkAttributeValue(attrType wkdb.AttributeType, value json.RawMessage) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, errors.New("不能为空")
+	}
+	var err error
+	switch attrType {
+	case wkdb.AttributeTypeString:
+		var v string
+		err = json.Unmarshal(value, &v)
+	case wkdb.AttributeTypeInt:
+		var v int64
+		err = json.Unmarshal(value, &v)
+	case wkdb.AttributeTypeFloat:
+		var v float64
+		err = json.Unmarshal(value, &v)
+	case wkdb.AttributeTypeBool:
+		var v bool
+		err = json.Unmarshal(value, &v)
+	case wkdb.AttributeTypeJSON:
+		if !json.Valid(value) {
+			err = errors.New("不是有效的json")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	buff := new(bytes.Buffer)
+	if err = json.Compact(buff, value); err != nil {
+		return nil, err
+	}
+	return buff.Bytes(), nil
+}
+
+// attributeNames 解析逗号分隔的属性名
+func attributeNames(names string) []string {
+	if strings.TrimSpace(names) == "" {
+		return nil
+	}
+	var result []string
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func (a *AttributeAPI) userAttributesSet(c *wkhttp.Context) {
+	var req struct {
+		UID          string         `json:"uid"`
+		Attributes   []attributeReq `json:"attributes"`
+		CheckVersion bool           `json:"check_version"` // 是否检查版本（乐观锁），版本不一致时不修改
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if a.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+	unlock := a.s.attributeManager.lockUser(req.UID)
+	defer unlock()
+
+	olds, err := a.s.store.GetUserAttributes(req.UID, nil)
+	if err != nil {
+		a.Error("获取用户属性失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("获取用户属性失败！"))
+		return
+	}
+	attrs, err := a.checkAttributes(req.Attributes, olds, req.CheckVersion)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if err = a.s.store.SetUserAttributes(req.UID, attrs, req.CheckVersion); err != nil {
+		a.Error("设置用户属性失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("设置用户属性失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (a *AttributeAPI) userAttributesGet(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	attrs, err := a.s.attributeManager.getUserAttributes(uid, attributeNames(c.Query("names")))
+	if err != nil {
+		a.Error("获取用户属性失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取用户属性失败！"))
+		return
+	}
+	c.ResponseOKWithData(newAttributeResps(attrs))
+}
+
+func (a *AttributeAPI) userAttributesDelete(c *wkhttp.Context) {
+	var req struct {
+		UID   string   `json:"uid"`
+		Names []string `json:"names"` // 为空时删除所有属性
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if a.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+	unlock := a.s.attributeManager.lockUser(req.UID)
+	defer unlock()
+
+	if err = a.s.store.DeleteUserAttributes(req.UID, req.Names); err != nil {
+		a.Error("删除用户属性失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("删除用户属性失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (a *AttributeAPI) channelAttributesSet(c *wkhttp.Context) {
+	var req struct {
+		ChannelID    string         `json:"channel_id"`
+		ChannelType  uint8          `json:"channel_type"`
+		Attributes   []attributeReq `json:"attributes"`
+		CheckVersion bool           `json:"check_version"` // 是否检查版本（乐观锁），版本不一致时不修改
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.ChannelID) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if a.s.forwardToSlotLeaderOfChannel(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+	unlock := a.s.attributeManager.lockChannel(req.ChannelID, req.ChannelType)
+	defer unlock()
+
+	olds, err := a.s.store.GetChannelAttributes(req.ChannelID, req.ChannelType, nil)
+	if err != nil {
+		a.Error("获取频道属性失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道属性失败！"))
+		return
+	}
+	attrs, err := a.checkAttributes(req.Attributes, olds, req.CheckVersion)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if err = a.s.store.SetChannelAttributes(req.ChannelID, req.ChannelType, attrs, req.CheckVersion); err != nil {
+		a.Error("设置频道属性失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("设置频道属性失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (a *AttributeAPI) channelAttributesGet(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	attrs, err := a.s.attributeManager.getChannelAttributes(channelId, channelType, attributeNames(c.Query("names")))
+	if err != nil {
+		a.Error("获取频道属性失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道属性失败！"))
+		return
+	}
+	c.ResponseOKWithData(newAttributeResps(attrs))
+}
+
+func (a *AttributeAPI) channelAttributesDelete(c *wkhttp.Context) {
+	var req struct {
+		ChannelID   string   `json:"channel_id"`
+		ChannelType uint8    `json:"channel_type"`
+		Names       []string `json:"names"` // 为空时删除所有属性
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		a.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.ChannelID) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if a.s.forwardToSlotLeaderOfChannel(c, req.ChannelID, req.ChannelType, bodyBytes) {
+		return
+	}
+	unlock := a.s.attributeManager.lockChannel(req.ChannelID, req.ChannelType)
+	defer unlock()
+
+	if err = a.s.store.DeleteChannelAttributes(req.ChannelID, req.ChannelType, req.Names); err != nil {
+		a.Error("删除频道属性失败！", zap.Error(err), zap.String("channelId", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("删除频道属性失败！"))
+		return
+	}
+	c.ResponseOK()
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAttributeValue(t *testing.T) {
+	value, err := checkAttributeValue(wkdb.AttributeTypeString, json.RawMessage(`"tom"`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`"tom"`), value)
+
+	_, err = checkAttributeValue(wkdb.AttributeTypeString, json.RawMessage(`1`))
+	assert.Error(t, err)
+	_, err = checkAttributeValue(wkdb.AttributeTypeInt, json.RawMessage(`1.5`))
+	assert.Error(t, err)
+	_, err = checkAttributeValue(wkdb.AttributeTypeFloat, json.RawMessage(`1.5`))
+	assert.NoError(t, err)
+	_, err = checkAttributeValue(wkdb.AttributeTypeBool, json.RawMessage(`"true"`))
+	assert.Error(t, err)
+	_, err = checkAttributeValue(wkdb.AttributeTypeJSON, json.RawMessage(`{"a":`))
+	assert.Error(t, err)
+
+	// json会被压缩
+	value, err = checkAttributeValue(wkdb.AttributeTypeJSON, json.RawMessage(`{ "a" : [1, 2] }`))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"a":[1,2]}`), value)
+}
+
+func TestCheckAttributes(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	s.opts.Attribute.MaxCount = 2
+	a := NewAttributeAPI(s)
+
+	olds := []wkdb.Attribute{{Name: "nickname", Type: wkdb.AttributeTypeString, Value: []byte(`"tom"`), Version: 3}}
+
+	attrs, err := a.checkAttributes([]attributeReq{
+		{Name: "nickname", Type: "string", Value: json.RawMessage(`"jerry"`), Version: 3},
+		{Name: "age", Type: "int", Value: json.RawMessage(`18`)},
+	}, olds, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(attrs))
+	assert.Equal(t, wkdb.AttributeTypeInt, attrs[1].Type)
+
+	// 版本不一致
+	_, err = a.checkAttributes([]attributeReq{
+		{Name: "nickname", Type: "string", Value: json.RawMessage(`"jerry"`), Version: 2},
+	}, olds, true)
+	assert.Error(t, err)
+
+	// 超过数量限制
+	_, err = a.checkAttributes([]attributeReq{
+		{Name: "age", Type: "int", Value: json.RawMessage(`18`)},
+		{Name: "vip", Type: "bool", Value: json.RawMessage(`true`)},
+	}, olds, false)
+	assert.Error(t, err)
+
+	// 属性名和值的限制
+	_, err = a.checkAttributes([]attributeReq{{Name: strings.Repeat("a", s.opts.Attribute.MaxNameLen+1), Type: "int", Value: json.RawMessage(`1`)}}, nil, false)
+	assert.Error(t, err)
+	_, err = a.checkAttributes([]attributeReq{{Name: "a,b", Type: "int", Value: json.RawMessage(`1`)}}, nil, false)
+	assert.Error(t, err)
+	_, err = a.checkAttributes([]attributeReq{{Name: "a", Type: "unknown", Value: json.RawMessage(`1`)}}, nil, false)
+	assert.Error(t, err)
+	_, err = a.checkAttributes([]attributeReq{{Name: "a", Type: "string", Value: json.RawMessage(`"` + strings.Repeat("a", s.opts.Attribute.MaxValueSize) + `"`)}}, nil, false)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"a", "b"}, attributeNames(" a, ,b"))
+	assert.Nil(t, attributeNames(""))
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// BackupAPI 在线备份
+type BackupAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewBackupAPI(s *Server) *BackupAPI {
+	return &BackupAPI{
+		s:   s,
+		Log: wklog.NewWKLog("BackupAPI"),
+	}
+}
+
+// Route 路由
+func (b *BackupAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/backup", b.backup) // 发起集群在线备份
+	r.GET("/backups", b.list)   // 获取所有完整的备份
+}
+
+// backup 发起集群在线备份，所有节点上传完成后返回备份清单
+func (b *BackupAPI) backup(c *wkhttp.Context) {
+	timeoutCtx, cancel := context.WithTimeout(b.s.ctx, b.s.opts.Backup.Timeout)
+	defer cancel()
+	manifest, err := b.s.backupManager.backup(timeoutCtx)
+	if err != nil {
+		b.Error("backup failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, manifest)
+}
+
+func (b *BackupAPI) list(c *wkhttp.Context) {
+	manifests, err := b.s.backupManager.list(c.Request.Context())
+	if err != nil {
+		b.Error("list backups failed", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, manifests)
+}
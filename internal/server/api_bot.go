@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// BotAPI 机器人
+type BotAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewBotAPI(s *Server) *BotAPI {
+	return &BotAPI{
+		s:   s,
+		Log: wklog.NewWKLog("BotAPI"),
+	}
+}
+
+// Route 路由
+func (b *BotAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/bot/register", b.register)     // 注册机器人（已存在则更新）
+	r.POST("/bot/unregister", b.unregister) // 注销机器人
+	r.GET("/bot/list", b.list)              // 获取所有机器人
+	r.GET("/bot", b.get)                    // 获取机器人
+
+	r.POST("/bot/add_to_cache", b.addToCache)           // 仅仅添加机器人至缓存
+	r.POST("/bot/remove_from_cache", b.removeFromCache) // 仅仅从缓存中移除机器人
+}
+
+// botSlotId 机器人存储在slot 0上，写入和查询都转发到slot 0的领导节点
+const botSlotId uint32 = 0
+
+// 注册机器人
+func (b *BotAPI) register(c *wkhttp.Context) {
+	var req botRegisterReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		b.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(b.s.opts); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if b.s.forwardToSlotLeader(c, botSlotId, bodyBytes) {
+		return
+	}
+
+	oldBot, err := b.s.store.GetBot(req.Uid)
+	if err != nil {
+		b.Error("获取机器人失败！", zap.Error(err), zap.String("uid", req.Uid))
+		c.ResponseError(errors.New("获取机器人失败！"))
+		return
+	}
+	triggers, _ := parseBotTriggers(req.Triggers)
+	now := time.Now()
+	bot := wkdb.Bot{
+		Uid:           req.Uid,
+		CallbackUrl:   req.CallbackUrl,
+		Secret:        req.Secret,
+		Channels:      req.Channels,
+		Triggers:      triggers,
+		CommandPrefix: req.CommandPrefix,
+		RateLimit:     req.RateLimit,
+		Timeout:       time.Duration(req.TimeoutMs) * time.Millisecond,
+		CreatedAt:     &now,
+		UpdatedAt:     &now,
+	}
+	if !wkdb.IsEmptyBot(oldBot) {
+		bot.CreatedAt = oldBot.CreatedAt
+		if bot.Secret == "" {
+			bot.Secret = oldBot.Secret
+		}
+	}
+	if bot.Secret == "" {
+		bot.Secret = wkutil.GenUUID()
+	}
+
+	err = b.s.botManager.AddOrUpdateBot(bot)
+	if err != nil {
+		b.Error("保存机器人失败！", zap.Error(err), zap.String("uid", req.Uid))
+		c.ResponseError(errors.New("保存机器人失败！"))
+		return
+	}
+
+	err = b.requestAllNodes(func(n *pb.Node) error {
+		return b.requestBotAddToCache(n, bot)
+	})
+	if err != nil {
+		b.Error("添加机器人到缓存失败！", zap.Error(err))
+		c.ResponseError(errors.New("添加机器人到缓存失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, newBotResp(bot))
+}
+
+// 注销机器人
+func (b *BotAPI) unregister(c *wkhttp.Context) {
+	var req struct {
+		Uid string `json:"uid"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		b.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.Uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if b.s.forwardToSlotLeader(c, botSlotId, bodyBytes) {
+		return
+	}
+
+	err = b.s.botManager.RemoveBot(req.Uid)
+	if err != nil {
+		b.Error("删除机器人失败！", zap.Error(err), zap.String("uid", req.Uid))
+		c.ResponseError(errors.New("删除机器人失败！"))
+		return
+	}
+
+	err = b.requestAllNodes(func(n *pb.Node) error {
+		return b.requestBotRemoveFromCache(n, req.Uid)
+	})
+	if err != nil {
+		b.Error("从缓存中移除机器人失败！", zap.Error(err))
+		c.ResponseError(errors.New("从缓存中移除机器人失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+func (b *BotAPI) list(c *wkhttp.Context) {
+	if b.s.forwardToSlotLeader(c, botSlotId, nil) {
+		return
+	}
+	bots, err := b.s.store.GetBots()
+	if err != nil {
+		b.Error("获取机器人失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取机器人失败！"))
+		return
+	}
+	resps := make([]*botResp, 0, len(bots))
+	for _, bot := range bots {
+		resps = append(resps, newBotResp(bot))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+func (b *BotAPI) get(c *wkhttp.Context) {
+	uid := strings.TrimSpace(c.Query("uid"))
+	if uid == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if b.s.forwardToSlotLeader(c, botSlotId, nil) {
+		return
+	}
+	bot, err := b.s.store.GetBot(uid)
+	if err != nil {
+		b.Error("获取机器人失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(errors.New("获取机器人失败！"))
+		return
+	}
+	if wkdb.IsEmptyBot(bot) {
+		c.ResponseError(errors.New("机器人不存在！"))
+		return
+	}
+	c.JSON(http.StatusOK, newBotResp(bot))
+}
+
+func (b *BotAPI) addToCache(c *wkhttp.Context) {
+	var req botResp
+	if err := c.BindJSON(&req); err != nil {
+		b.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.Uid) != "" {
+		b.s.botManager.AddBotToCache(req.toBot())
+	}
+	c.ResponseOK()
+}
+
+func (b *BotAPI) removeFromCache(c *wkhttp.Context) {
+	var req struct {
+		Uid string `json:"uid"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		b.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if strings.TrimSpace(req.Uid) != "" {
+		b.s.botManager.RemoveBotFromCache(req.Uid)
+	}
+	c.ResponseOK()
+}
+
+// requestAllNodes 请求其他在线节点（更新各个节点的机器人缓存）
+func (b *BotAPI) requestAllNodes(fnc func(n *pb.Node) error) error {
+	nodes := b.s.clusterServer.GetConfig().Nodes
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), b.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, node := range nodes {
+		if node.Id == b.s.opts.Cluster.NodeId {
+			continue
+		}
+		if !node.Online {
+			continue
+		}
+		requestGroup.Go(func(n *pb.Node) func() error {
+			return func() error {
+				return fnc(n)
+			}
+		}(node))
+	}
+	return requestGroup.Wait()
+}
+
+func (b *BotAPI) requestBotAddToCache(nodeInfo *pb.Node, bot wkdb.Bot) error {
+	reqURL := fmt.Sprintf("%s/bot/add_to_cache", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(newBotResp(bot))), managerTokenHeader(b.s.opts))
+	if err != nil {
+		b.Error("添加机器人到缓存失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("添加机器人到缓存请求状态错误！[%d]", resp.StatusCode)
+	}
+	return nil
+}
+
+func (b *BotAPI) requestBotRemoveFromCache(nodeInfo *pb.Node, uid string) error {
+	reqURL := fmt.Sprintf("%s/bot/remove_from_cache", nodeInfo.ApiServerAddr)
+	resp, err := network.Post(reqURL, []byte(wkutil.ToJSON(map[string]interface{}{
+		"uid": uid,
+	})), managerTokenHeader(b.s.opts))
+	if err != nil {
+		b.Error("从缓存中移除机器人失败！", zap.Error(err), zap.String("reqURL", reqURL))
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("从缓存中移除机器人请求状态错误！[%d]", resp.StatusCode)
+	}
+	return nil
+}
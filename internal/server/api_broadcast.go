@@ -0,0 +1,194 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"go.uber.org/zap"
+)
+
+// BroadcastAPI 广播（以系统账号给所有用户或部分用户发送消息）
+type BroadcastAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewBroadcastAPI(s *Server) *BroadcastAPI {
+	return &BroadcastAPI{
+		s:   s,
+		Log: wklog.NewWKLog("BroadcastAPI"),
+	}
+}
+
+// Route 路由
+func (b *BroadcastAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/broadcast", b.create)        // 创建广播
+	r.GET("/broadcast", b.progress)       // 获取广播进度
+	r.POST("/broadcast/cancel", b.cancel) // 取消广播
+}
+
+type broadcastReq struct {
+	Header         MessageHeader `json:"header"`           // 消息头
+	Payload        []byte        `json:"payload"`          // 消息内容
+	Uids           []string      `json:"uids"`             // 指定的用户，为空表示所有用户
+	DeviceFlags    []uint8       `json:"device_flags"`     // 只发送给有这些设备的用户（任意一个）
+	CreatedAtStart int64         `json:"created_at_start"` // 只发送给在此时间及之后创建的用户（unix秒）
+	CreatedAtEnd   int64         `json:"created_at_end"`   // 只发送给在此时间之前创建的用户（unix秒）
+	Rate           int           `json:"rate"`             // 每秒最多发送的消息数量（整个集群），为0使用默认配置
+}
+
+func (b broadcastReq) check() error {
+	if len(b.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
+	if len(b.Uids) > 0 && (len(b.DeviceFlags) > 0 || b.CreatedAtStart > 0 || b.CreatedAtEnd > 0) {
+		return errors.New("uids不能和筛选条件同时使用！")
+	}
+	for _, flag := range b.DeviceFlags {
+		if flag >= 8 {
+			return errors.New("device_flags有误！")
+		}
+	}
+	if b.CreatedAtEnd > 0 && b.CreatedAtEnd <= b.CreatedAtStart {
+		return errors.New("created_at_end必须大于created_at_start！")
+	}
+	if b.Rate < 0 {
+		return errors.New("rate不能小于0！")
+	}
+	return nil
+}
+
+// deviceFlagMask 设备标记的位掩码
+func (b broadcastReq) deviceFlagMask() uint8 {
+	var mask uint8
+	for _, flag := range b.DeviceFlags {
+		mask |= 1 << flag
+	}
+	return mask
+}
+
+func (b *BroadcastAPI) create(c *wkhttp.Context) {
+	var req broadcastReq
+	if err := c.BindJSON(&req); err != nil {
+		b.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if !b.s.opts.ClusterOn() {
+		c.ResponseError(errors.New("广播需要开启分布式！"))
+		return
+	}
+
+	jobId, err := b.s.broadcastManager.create(req)
+	if err != nil {
+		b.Error("创建广播失败！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"job_id": jobId,
+	})
+}
+
+// broadcastProgressResp 广播进度
+type broadcastProgressResp struct {
+	JobId         uint64               `json:"job_id"`
+	Status        string               `json:"status"`          // running: 发送中 done: 已完成 cancelled: 已取消
+	TaskCount     int                  `json:"task_count"`      // 获取到的槽任务数量（槽的领导节点离线时获取不到）
+	DoneTaskCount int                  `json:"done_task_count"` // 已结束的槽任务数量
+	SentCount     uint64               `json:"sent_count"`      // 已发送的数量
+	FailedCount   uint64               `json:"failed_count"`    // 发送失败的数量
+	Tasks         []wkdb.BroadcastTask `json:"tasks"`           // 各个槽的任务进度
+}
+
+func newBroadcastProgressResp(jobId uint64, tasks []wkdb.BroadcastTask) *broadcastProgressResp {
+	resp := &broadcastProgressResp{
+		JobId:     jobId,
+		Status:    wkdb.BroadcastStatusDone.String(),
+		TaskCount: len(tasks),
+		Tasks:     tasks,
+	}
+	var running, cancelled bool
+	for _, t := range tasks {
+		resp.SentCount += t.SentCount
+		resp.FailedCount += t.FailedCount
+		switch t.Status {
+		case wkdb.BroadcastStatusRunning:
+			running = true
+		case wkdb.BroadcastStatusCancelled:
+			cancelled = true
+		}
+		if t.Status.Finished() {
+			resp.DoneTaskCount++
+		}
+	}
+	if running {
+		resp.Status = wkdb.BroadcastStatusRunning.String()
+	} else if cancelled {
+		resp.Status = wkdb.BroadcastStatusCancelled.String()
+	}
+	return resp
+}
+
+func (b *BroadcastAPI) progress(c *wkhttp.Context) {
+	jobId, err := strconv.ParseUint(c.Query("job_id"), 10, 64)
+	if err != nil || jobId == 0 {
+		c.ResponseError(errors.New("job_id有误！"))
+		return
+	}
+	tasks, err := b.s.broadcastManager.clusterTasks(jobId)
+	if err != nil {
+		b.Error("获取广播进度失败！", zap.Error(err), zap.Uint64("jobId", jobId))
+		c.ResponseError(err)
+		return
+	}
+	if len(tasks) == 0 {
+		c.ResponseError(errors.New("广播不存在！"))
+		return
+	}
+	c.JSON(http.StatusOK, newBroadcastProgressResp(jobId, tasks))
+}
+
+func (b *BroadcastAPI) cancel(c *wkhttp.Context) {
+	var req struct {
+		JobId uint64 `json:"job_id"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		b.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if req.JobId == 0 {
+		c.ResponseError(errors.New("job_id不能为空！"))
+		return
+	}
+	tasks, err := b.s.broadcastManager.clusterTasks(req.JobId)
+	if err != nil {
+		b.Error("获取广播任务失败！", zap.Error(err), zap.Uint64("jobId", req.JobId))
+		c.ResponseError(err)
+		return
+	}
+	if len(tasks) == 0 {
+		c.ResponseError(errors.New("广播不存在！"))
+		return
+	}
+	for _, t := range tasks {
+		if t.Status.Finished() {
+			continue
+		}
+		if err = b.s.store.CancelBroadcastTask(t.JobId, t.SlotId); err != nil {
+			b.Error("取消广播任务失败！", zap.Error(err), zap.Uint64("jobId", t.JobId), zap.Uint32("slotId", t.SlotId))
+			c.ResponseError(err)
+			return
+		}
+	}
+	c.ResponseOK()
+}
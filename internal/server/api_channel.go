@@ -40,8 +40,11 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/channel/delete", ch.channelDelete)        // 删除频道
 
 	//################### 订阅者 ###################// 删除频道
-	r.POST("/channel/subscriber_add", ch.addSubscriber)       // 添加订阅者
-	r.POST("/channel/subscriber_remove", ch.removeSubscriber) // 移除订阅者
+	r.POST("/channel/subscriber_add", ch.addSubscriber)          // 添加订阅者
+	r.POST("/channel/subscriber_remove", ch.removeSubscriber)    // 移除订阅者
+	r.POST("/channel/subscriber_role", ch.subscriberRole)        // 设置订阅者角色
+	r.POST("/channel/subscriber_mute", ch.subscriberMute)        // 设置订阅者禁言
+	r.POST("/channel/subscriber_mute_all", ch.subscriberMuteAll) // 设置频道全员禁言
 
 	//################### 黑名单 ###################// 删除频道
 	r.POST("/channel/blacklist_add", ch.blacklistAdd)       // 添加黑名单
@@ -53,6 +56,11 @@ func (ch *ChannelAPI) Route(r *wkhttp.WKHttp) {
 	r.POST("/channel/whitelist_set", ch.whitelistSet) // 设置白明单（覆盖
 	r.POST("/channel/whitelist_remove", ch.whitelistRemove)
 	r.GET("/channel/whitelist", ch.whitelistGet) // 获取白名单
+	//################### 社区话题 ###################
+	r.POST("/channel/topic", ch.topicCreateOrUpdate)  // 创建或更新社区话题
+	r.GET("/channel/topics", ch.topicList)            // 获取社区的话题列表
+	r.POST("/channel/topic/archive", ch.topicArchive) // 归档社区话题
+
 	//################### 频道消息 ###################
 	// 同步频道消息
 	r.POST("/channel/messagesync", ch.syncMessages)
@@ -95,7 +103,7 @@ func (ch *ChannelAPI) channelCreateOrUpdate(c *wkhttp.Context) {
 
 	// channelInfo := wkstore.NewChannelInfo(req.ChannelID, req.ChannelType)
 	channelInfo := req.ToChannelInfo()
-	err = ch.addOrUpdateChannel(channelInfo)
+	channelInfo, err = ch.addOrUpdateChannel(channelInfo)
 	if err != nil && err != wkdb.ErrNotFound {
 		ch.Error("创建或更新频道失败", zap.Error(err), zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
 		c.ResponseError(errors.New("创建或更新频道失败"))
@@ -161,7 +169,7 @@ func (ch *ChannelAPI) updateOrAddChannelInfo(c *wkhttp.Context) {
 	}
 
 	channelInfo := req.ToChannelInfo()
-	err = ch.addOrUpdateChannel(channelInfo)
+	channelInfo, err = ch.addOrUpdateChannel(channelInfo)
 	if err != nil {
 		ch.Error("添加或更新频道信息失败！", zap.Error(err))
 		c.ResponseError(errors.New("添加或更新频道信息失败！"))
@@ -281,6 +289,7 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 		for _, subscriber := range newSubscribers {
 			members = append(members, wkdb.Member{
 				Uid:       subscriber,
+				Role:      wkdb.MemberRole(req.Role),
 				CreatedAt: &createdAt,
 				UpdatedAt: &updatedAt,
 			})
@@ -291,6 +300,10 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 			return err
 		}
 
+		conversationType := wkdb.ConversationTypeChat
+		if req.ChannelType == wkproto.ChannelTypeCommunityTopic {
+			conversationType = wkdb.ConversationTypeTopic
+		}
 		// 添加或更新订阅者的最近会话最新消息序号
 		for _, subscriber := range newSubscribers {
 			createdAt := time.Now()
@@ -301,7 +314,7 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 					Uid:          subscriber,
 					ChannelId:    req.ChannelId,
 					ChannelType:  req.ChannelType,
-					Type:         wkdb.ConversationTypeChat,
+					Type:         conversationType,
 					UnreadCount:  0,
 					ReadToMsgSeq: lastMsgSeq,
 					CreatedAt:    &createdAt,
@@ -325,6 +338,9 @@ func (ch *ChannelAPI) addSubscriberWithReq(req subscriberAddReq) error {
 			return err
 		}
 	}
+	if req.ChannelType == wkproto.ChannelTypeCommunity {
+		ch.s.refreshTopicReceiverTags(req.ChannelId)
+	}
 	return nil
 }
 
@@ -376,6 +392,9 @@ func (ch *ChannelAPI) removeSubscriber(c *wkhttp.Context) {
 			return
 		}
 	}
+	if req.ChannelType == wkproto.ChannelTypeCommunity {
+		ch.s.refreshTopicReceiverTags(req.ChannelID)
+	}
 
 	c.ResponseOK()
 }
@@ -803,7 +822,20 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 	if req.ChannelType == wkproto.ChannelTypePerson {
 		fakeChannelID = GetFakeChannelIDWith(req.LoginUID, req.ChannelID)
 	}
-	if ch.s.opts.ClusterOn() {
+	var followerRead bool // 是否由本节点作为追随者副本提供读
+	if ch.s.opts.ClusterOn() && ch.s.opts.FollowerRead.On {
+		knownSeq, latest := syncMessagesReadBound(req.StartMessageSeq, req.EndMessageSeq, req.PullMode)
+		followerRead = ch.s.followerReadable(fakeChannelID, req.ChannelType, knownSeq, latest)
+		if !followerRead && c.GetHeader(followerReadHeader) == "" {
+			if nodeInfo := ch.s.pickFollowerReadNode(fakeChannelID, req.ChannelType); nodeInfo != nil {
+				c.Request.Header.Set(followerReadHeader, "1")
+				ch.Debug("转发请求到副本：", zap.String("url", fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)))
+				c.ForwardWithBody(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path), bodyBytes)
+				return
+			}
+		}
+	}
+	if ch.s.opts.ClusterOn() && !followerRead {
 		leaderInfo, err := ch.s.cluster.LeaderOfChannelForRead(fakeChannelID, req.ChannelType) // 获取频道的领导节点
 		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
 			ch.Info("频道集群从未初始化，返回空消息.", zap.String("channelID", req.ChannelID), zap.Uint8("channelType", req.ChannelType))
@@ -837,9 +869,13 @@ func (ch *ChannelAPI) syncMessages(c *wkhttp.Context) {
 	}
 	messageResps := make([]*MessageResp, 0, len(messages))
 	if len(messages) > 0 {
+		extras := ch.s.threadManager.messageExtras(fakeChannelID, req.ChannelType, messages)
 		for _, message := range messages {
 			messageResp := &MessageResp{}
 			messageResp.from(message, ch.s)
+			if extra, ok := extras[uint64(message.MessageSeq)]; ok {
+				messageResp.fillExtra(extra)
+			}
 			messageResps = append(messageResps, messageResp)
 		}
 	}
@@ -910,22 +946,24 @@ func (ch *ChannelAPI) getChannelMaxMessageSeq(c *wkhttp.Context) {
 	})
 }
 
-func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) error {
+// addOrUpdateChannel 添加或更新频道基础信息，返回实际保存的频道信息（更新时保留全员禁言等不由请求指定的字段）
+func (ch *ChannelAPI) addOrUpdateChannel(channelInfo wkdb.ChannelInfo) (wkdb.ChannelInfo, error) {
 	existChannel, err := ch.s.store.GetChannel(channelInfo.ChannelId, channelInfo.ChannelType)
 	if err != nil && err != wkdb.ErrNotFound {
-		return err
+		return channelInfo, err
 	}
 
 	if wkdb.IsEmptyChannelInfo(existChannel) {
 		err = ch.s.store.AddChannelInfo(channelInfo)
 		if err != nil {
-			return err
+			return channelInfo, err
 		}
 	} else {
+		channelInfo.MuteAll = existChannel.MuteAll
 		err = ch.s.store.UpdateChannelInfo(channelInfo)
 		if err != nil {
-			return err
+			return channelInfo, err
 		}
 	}
-	return nil
+	return channelInfo, nil
 }
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 设置订阅者角色
+func (ch *ChannelAPI) subscriberRole(c *wkhttp.Context) {
+	var req subscriberRoleReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.ChannelType == 0 {
+		req.ChannelType = wkproto.ChannelTypeGroup //默认为群
+	}
+
+	if ch.s.forwardToSlotLeaderOfChannel(c, req.ChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	err = ch.s.store.UpdateSubscribersRole(req.ChannelId, req.ChannelType, req.Subscribers, wkdb.MemberRole(req.Role))
+	if err != nil {
+		ch.Error("设置订阅者角色失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("设置订阅者角色失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 设置订阅者禁言
+func (ch *ChannelAPI) subscriberMute(c *wkhttp.Context) {
+	var req subscriberMuteReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.ChannelType == 0 {
+		req.ChannelType = wkproto.ChannelTypeGroup //默认为群
+	}
+
+	if ch.s.forwardToSlotLeaderOfChannel(c, req.ChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	var muteExpireAt uint64
+	if req.Duration > 0 {
+		muteExpireAt = uint64(time.Now().Unix() + req.Duration)
+	}
+	err = ch.s.store.UpdateSubscribersMute(req.ChannelId, req.ChannelType, req.Subscribers, muteExpireAt)
+	if err != nil {
+		ch.Error("设置订阅者禁言失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("设置订阅者禁言失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+// 设置频道全员禁言（管理员和群主除外）
+func (ch *ChannelAPI) subscriberMuteAll(c *wkhttp.Context) {
+	var req subscriberMuteAllReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.ChannelType == 0 {
+		req.ChannelType = wkproto.ChannelTypeGroup //默认为群
+	}
+
+	if ch.s.forwardToSlotLeaderOfChannel(c, req.ChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	channelInfo, err := ch.s.store.GetChannel(req.ChannelId, req.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		ch.Error("获取频道信息失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("获取频道信息失败！"))
+		return
+	}
+	if wkdb.IsEmptyChannelInfo(channelInfo) {
+		c.ResponseError(errors.New("频道不存在！"))
+		return
+	}
+	now := time.Now()
+	channelInfo.MuteAll = req.MuteAll == 1
+	channelInfo.UpdatedAt = &now
+	err = ch.s.store.UpdateChannelInfo(channelInfo)
+	if err != nil {
+		ch.Error("设置全员禁言失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType))
+		c.ResponseError(errors.New("设置全员禁言失败！"))
+		return
+	}
+
+	channelKey := wkutil.ChannelToKey(req.ChannelId, req.ChannelType)
+	cacheChannel := ch.s.channelReactor.reactorSub(channelKey).channel(channelKey)
+	if cacheChannel != nil {
+		cacheChannel.info = channelInfo
+	}
+	c.ResponseOK()
+}
+
+type subscriberRoleReq struct {
+	ChannelId   string   `json:"channel_id"`   // 频道ID
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	Subscribers []string `json:"subscribers"`  // 订阅者
+	Role        uint8    `json:"role"`         // 角色 (0.普通成员 1.管理员 2.群主)
+}
+
+func (r subscriberRoleReq) Check() error {
+	if err := checkSubscriberChannel(r.ChannelId, r.ChannelType); err != nil {
+		return err
+	}
+	if stringArrayIsEmpty(r.Subscribers) {
+		return errors.New("订阅者不能为空！")
+	}
+	if wkdb.MemberRole(r.Role) > wkdb.MemberRoleOwner {
+		return errors.New("订阅者角色不正确！")
+	}
+	return nil
+}
+
+type subscriberMuteReq struct {
+	ChannelId   string   `json:"channel_id"`   // 频道ID
+	ChannelType uint8    `json:"channel_type"` // 频道类型
+	Subscribers []string `json:"subscribers"`  // 订阅者
+	Duration    int64    `json:"duration"`     // 禁言时长（单位秒），0表示解除禁言
+}
+
+func (r subscriberMuteReq) Check() error {
+	if err := checkSubscriberChannel(r.ChannelId, r.ChannelType); err != nil {
+		return err
+	}
+	if stringArrayIsEmpty(r.Subscribers) {
+		return errors.New("订阅者不能为空！")
+	}
+	if r.Duration < 0 {
+		return errors.New("禁言时长不能小于0！")
+	}
+	return nil
+}
+
+type subscriberMuteAllReq struct {
+	ChannelId   string `json:"channel_id"`   // 频道ID
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	MuteAll     int    `json:"mute_all"`     // 是否全员禁言 (1.是 0.否)
+}
+
+func (r subscriberMuteAllReq) Check() error {
+	return checkSubscriberChannel(r.ChannelId, r.ChannelType)
+}
+
+func checkSubscriberChannel(channelId string, channelType uint8) error {
+	if strings.TrimSpace(channelId) == "" {
+		return errors.New("频道ID不能为空！")
+	}
+	if IsSpecialCharOfChannel(channelId, channelType) {
+		return errors.New("频道ID不能包含特殊字符！")
+	}
+	if channelType == wkproto.ChannelTypePerson {
+		return errors.New("个人频道不支持此操作！")
+	}
+	return nil
+}
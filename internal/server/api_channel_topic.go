@@ -0,0 +1,236 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 社区话题的接口都转发到社区（父频道）所在的槽领导节点处理
+func (ch *ChannelAPI) forwardToCommunityLeader(c *wkhttp.Context, parentChannelId string, bodyBytes []byte) bool {
+	return ch.s.forwardToSlotLeaderOfChannel(c, parentChannelId, wkproto.ChannelTypeCommunity, bodyBytes)
+}
+
+// 创建或更新社区话题
+func (ch *ChannelAPI) topicCreateOrUpdate(c *wkhttp.Context) {
+	var req channelTopicReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if ch.forwardToCommunityLeader(c, req.ChannelId, bodyBytes) {
+		return
+	}
+
+	parentInfo, err := ch.s.store.GetChannel(req.ChannelId, wkproto.ChannelTypeCommunity)
+	if err != nil && err != wkdb.ErrNotFound {
+		ch.Error("获取社区信息失败！", zap.Error(err), zap.String("channelId", req.ChannelId))
+		c.ResponseError(errors.New("获取社区信息失败！"))
+		return
+	}
+	if wkdb.IsEmptyChannelInfo(parentInfo) {
+		c.ResponseError(errors.New("社区不存在！"))
+		return
+	}
+	if parentInfo.Disband {
+		c.ResponseError(errors.New("社区已解散！"))
+		return
+	}
+
+	topic, err := ch.s.store.GetChannelTopic(req.ChannelId, wkproto.ChannelTypeCommunity, req.TopicId)
+	if err != nil {
+		ch.Error("获取话题失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.String("topicId", req.TopicId))
+		c.ResponseError(errors.New("获取话题失败！"))
+		return
+	}
+	now := time.Now()
+	if wkdb.IsEmptyChannelTopic(topic) {
+		topic = wkdb.ChannelTopic{
+			ParentChannelId:   req.ChannelId,
+			ParentChannelType: wkproto.ChannelTypeCommunity,
+			TopicId:           req.TopicId,
+			CreatedAt:         &now,
+		}
+	}
+	topic.Name = req.Name
+	topic.UpdatedAt = &now
+	err = ch.s.store.AddOrUpdateChannelTopic(topic)
+	if err != nil {
+		ch.Error("保存话题失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.String("topicId", req.TopicId))
+		c.ResponseError(errors.New("保存话题失败！"))
+		return
+	}
+
+	// 话题频道的基础信息
+	topicChannelId := topic.ChannelId()
+	exist, err := ch.s.store.ExistChannel(topicChannelId, wkproto.ChannelTypeCommunityTopic)
+	if err != nil {
+		ch.Error("查询话题频道失败！", zap.Error(err), zap.String("channelId", topicChannelId))
+		c.ResponseError(errors.New("查询话题频道失败！"))
+		return
+	}
+	if !exist {
+		err = ch.s.store.AddChannelInfo(wkdb.NewChannelInfo(topicChannelId, wkproto.ChannelTypeCommunityTopic))
+		if err != nil {
+			ch.Error("创建话题频道失败！", zap.Error(err), zap.String("channelId", topicChannelId))
+			c.ResponseError(errors.New("创建话题频道失败！"))
+			return
+		}
+	}
+
+	// 指定了订阅者则话题使用自己的订阅者，否则继承社区的订阅者
+	if len(req.Subscribers) > 0 {
+		err = ch.addSubscriberWithReq(subscriberAddReq{
+			ChannelId:   topicChannelId,
+			ChannelType: wkproto.ChannelTypeCommunityTopic,
+			Subscribers: req.Subscribers,
+		})
+		if err != nil {
+			ch.Error("添加话题订阅者失败！", zap.Error(err), zap.String("channelId", topicChannelId))
+			c.ResponseError(errors.New("添加话题订阅者失败！"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, newChannelTopicResp(topic))
+}
+
+// 获取社区的话题列表
+func (ch *ChannelAPI) topicList(c *wkhttp.Context) {
+	channelId := strings.TrimSpace(c.Query("channel_id"))
+	includeArchived := c.Query("archived") == "1"
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+
+	if ch.forwardToCommunityLeader(c, channelId, nil) {
+		return
+	}
+
+	topics, err := ch.s.store.GetChannelTopics(channelId, wkproto.ChannelTypeCommunity)
+	if err != nil {
+		ch.Error("获取话题列表失败！", zap.Error(err), zap.String("channelId", channelId))
+		c.ResponseError(errors.New("获取话题列表失败！"))
+		return
+	}
+	resps := make([]*channelTopicResp, 0, len(topics))
+	for _, topic := range topics {
+		if topic.Archived && !includeArchived {
+			continue
+		}
+		resps = append(resps, newChannelTopicResp(topic))
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+// 归档社区话题，归档后话题内不能再发送消息
+func (ch *ChannelAPI) topicArchive(c *wkhttp.Context) {
+	var req channelTopicArchiveReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.ChannelId) == "" || strings.TrimSpace(req.TopicId) == "" {
+		c.ResponseError(errors.New("channel_id和topic_id不能为空！"))
+		return
+	}
+
+	if ch.forwardToCommunityLeader(c, req.ChannelId, bodyBytes) {
+		return
+	}
+
+	topic, err := ch.s.store.GetChannelTopic(req.ChannelId, wkproto.ChannelTypeCommunity, req.TopicId)
+	if err != nil {
+		ch.Error("获取话题失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.String("topicId", req.TopicId))
+		c.ResponseError(errors.New("获取话题失败！"))
+		return
+	}
+	if wkdb.IsEmptyChannelTopic(topic) {
+		c.ResponseError(errors.New("话题不存在！"))
+		return
+	}
+	if topic.Archived {
+		c.ResponseOK()
+		return
+	}
+	now := time.Now()
+	topic.Archived = true
+	topic.UpdatedAt = &now
+	err = ch.s.store.AddOrUpdateChannelTopic(topic)
+	if err != nil {
+		ch.Error("归档话题失败！", zap.Error(err), zap.String("channelId", req.ChannelId), zap.String("topicId", req.TopicId))
+		c.ResponseError(errors.New("归档话题失败！"))
+		return
+	}
+	c.ResponseOK()
+}
+
+type channelTopicReq struct {
+	ChannelId   string   `json:"channel_id"`  // 社区频道ID
+	TopicId     string   `json:"topic_id"`    // 话题ID
+	Name        string   `json:"name"`        // 话题名称
+	Subscribers []string `json:"subscribers"` // 话题自己的订阅者（为空则继承社区的订阅者）
+}
+
+func (r channelTopicReq) check() error {
+	if strings.TrimSpace(r.ChannelId) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if strings.TrimSpace(r.TopicId) == "" {
+		return errors.New("topic_id不能为空！")
+	}
+	if IsSpecialChar(r.ChannelId) || IsSpecialChar(r.TopicId) {
+		return errors.New("频道ID或话题ID不能包含特殊字符！")
+	}
+	return nil
+}
+
+type channelTopicArchiveReq struct {
+	ChannelId string `json:"channel_id"` // 社区频道ID
+	TopicId   string `json:"topic_id"`   // 话题ID
+}
+
+type channelTopicResp struct {
+	ChannelId       string `json:"channel_id"`        // 话题频道ID
+	ChannelType     uint8  `json:"channel_type"`      // 话题频道类型
+	ParentChannelId string `json:"parent_channel_id"` // 社区频道ID
+	TopicId         string `json:"topic_id"`          // 话题ID
+	Name            string `json:"name"`              // 话题名称
+	Archived        int    `json:"archived"`          // 是否已归档 1.是 0.否
+	CreatedAt       int64  `json:"created_at"`        // 创建时间
+	UpdatedAt       int64  `json:"updated_at"`        // 更新时间
+}
+
+func newChannelTopicResp(t wkdb.ChannelTopic) *channelTopicResp {
+	resp := &channelTopicResp{
+		ChannelId:       t.ChannelId(),
+		ChannelType:     wkproto.ChannelTypeCommunityTopic,
+		ParentChannelId: t.ParentChannelId,
+		TopicId:         t.TopicId,
+		Name:            t.Name,
+	}
+	if t.Archived {
+		resp.Archived = 1
+	}
+	if t.CreatedAt != nil {
+		resp.CreatedAt = t.CreatedAt.Unix()
+	}
+	if t.UpdatedAt != nil {
+		resp.UpdatedAt = t.UpdatedAt.Unix()
+	}
+	return resp
+}
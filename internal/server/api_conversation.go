@@ -351,8 +351,18 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 		return
 	}
 
+	// 社区话题的最近会话
+	topicConversations, err := s.s.store.GetLastConversations(req.UID, wkdb.ConversationTypeTopic, 0, s.s.opts.Conversation.UserMaxCount)
+	if err != nil && err != wkdb.ErrNotFound {
+		s.Error("获取话题conversation失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("获取话题conversation失败！"))
+		return
+	}
+	conversations = append(conversations, topicConversations...)
+
 	// 获取用户缓存的最近会话
 	cacheConversations := s.s.conversationManager.GetUserConversationFromCache(req.UID, wkdb.ConversationTypeChat)
+	cacheConversations = append(cacheConversations, s.s.conversationManager.GetUserConversationFromCache(req.UID, wkdb.ConversationTypeTopic)...)
 
 	for _, cacheConversation := range cacheConversations {
 		exist := false
@@ -361,6 +371,10 @@ func (s *ConversationAPI) syncUserConversation(c *wkhttp.Context) {
 				if cacheConversation.ReadToMsgSeq > conversation.ReadToMsgSeq {
 					conversations[i].ReadToMsgSeq = cacheConversation.ReadToMsgSeq
 				}
+				if cacheConversation.MentionMsgSeq > conversation.MentionMsgSeq { // 缓存中有还未保存的@
+					conversations[i].MentionMsgSeq = cacheConversation.MentionMsgSeq
+					conversations[i].MentionAll = cacheConversation.MentionAll
+				}
 				exist = true
 				break
 			}
@@ -501,7 +515,28 @@ func (s *Server) getRecentMessagesForCluster(uid string, msgCount int, channels
 	)
 	localPeerChannelRecentMessageReqs := make([]*channelRecentMessageReq, 0)
 	peerChannelRecentMessageReqsMap := make(map[uint64][]*channelRecentMessageReq)
-	for _, channelRecentMsgReq := range channels {
+
+	// 本节点是已追上领导的频道副本时，直接由本节点提供读
+	var followerReadable map[int]bool
+	if s.opts.FollowerRead.On {
+		readChannels := make([]wkdb.Channel, 0, len(channels))
+		knownSeqs := make([]uint64, 0, len(channels))
+		for _, channelRecentMsgReq := range channels {
+			fakeChannelId := channelRecentMsgReq.ChannelId
+			if channelRecentMsgReq.ChannelType == wkproto.ChannelTypePerson {
+				fakeChannelId = GetFakeChannelIDWith(uid, channelRecentMsgReq.ChannelId)
+			}
+			readChannels = append(readChannels, wkdb.Channel{ChannelId: fakeChannelId, ChannelType: channelRecentMsgReq.ChannelType})
+			knownSeqs = append(knownSeqs, channelRecentMsgReq.LastMsgSeq)
+		}
+		followerReadable = s.followerReadableBatch(readChannels, knownSeqs)
+	}
+
+	for i, channelRecentMsgReq := range channels {
+		if followerReadable[i] {
+			localPeerChannelRecentMessageReqs = append(localPeerChannelRecentMessageReqs, channelRecentMsgReq)
+			continue
+		}
 		fakeChannelId := channelRecentMsgReq.ChannelId
 		if channelRecentMsgReq.ChannelType == wkproto.ChannelTypePerson {
 			fakeChannelId = GetFakeChannelIDWith(uid, channelRecentMsgReq.ChannelId)
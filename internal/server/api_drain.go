@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+)
+
+// DrainAPI 节点下线
+type DrainAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewDrainAPI(s *Server) *DrainAPI {
+	return &DrainAPI{
+		s:   s,
+		Log: wklog.NewWKLog("DrainAPI"),
+	}
+}
+
+// Route 路由
+func (d *DrainAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/node/drain", d.drain)    // 开始下线，下线完成（或请求取消）后返回下线状态
+	r.GET("/node/drain", d.getStatus) // 获取下线状态
+}
+
+func (d *DrainAPI) drain(c *wkhttp.Context) {
+	status := d.s.Drain(c.Request.Context())
+	c.JSON(http.StatusOK, status)
+}
+
+func (d *DrainAPI) getStatus(c *wkhttp.Context) {
+	c.JSON(http.StatusOK, d.s.drainManager.getStatus())
+}
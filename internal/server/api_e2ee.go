@@ -0,0 +1,260 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	e2eePublicKeyMinLen = 32 // X25519/Ed25519公钥的长度
+	e2eePublicKeyMaxLen = 33 // 压缩格式的P-256公钥（或带类型前缀的Curve25519公钥）的长度
+	e2eeSignatureLen    = 64 // Ed25519/XEdDSA签名的长度
+)
+
+// E2EEAPI 端到端加密的密钥分发，只接收和分发公钥，私钥只保存在客户端
+type E2EEAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewE2EEAPI(s *Server) *E2EEAPI {
+	return &E2EEAPI{
+		s:   s,
+		Log: wklog.NewWKLog("E2EEAPI"),
+	}
+}
+
+// Route 路由
+func (e *E2EEAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/e2ee/keys/upload", e.upload)     // 上传设备的公钥
+	r.POST("/e2ee/keys/prekeys", e.prekeys)   // 补充一次性预共享公钥
+	r.POST("/e2ee/keys/claim", e.claim)       // 领取用户设备的公钥（发起会话）
+	r.GET("/e2ee/keys", e.keys)               // 获取用户设备的公钥（不领取一次性预共享公钥）
+	r.GET("/e2ee/keys/count", e.prekeyCount)  // 获取设备剩余的一次性预共享公钥数量
+	r.POST("/e2ee/keys/delete", e.deleteKeys) // 删除设备的公钥
+}
+
+type e2eeUploadReq struct {
+	UID            string            `json:"uid"`
+	DeviceId       string            `json:"device_id"`
+	IdentityKey    []byte            `json:"identity_key"`     // 身份公钥（base64）
+	SignedPrekey   wkdb.E2EEPrekey   `json:"signed_prekey"`    // 签名预共享公钥（base64），签名由身份私钥生成
+	OneTimePrekeys []wkdb.E2EEPrekey `json:"one_time_prekeys"` // 一次性预共享公钥（base64）
+}
+
+func (r e2eeUploadReq) check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if strings.TrimSpace(r.DeviceId) == "" {
+		return errors.New("device_id不能为空！")
+	}
+	if err := checkE2EEPublicKey("identity_key", r.IdentityKey); err != nil {
+		return err
+	}
+	if err := checkE2EEPublicKey("signed_prekey.public_key", r.SignedPrekey.PublicKey); err != nil {
+		return err
+	}
+	if len(r.SignedPrekey.Signature) != e2eeSignatureLen {
+		return fmt.Errorf("signed_prekey.signature的长度必须为%d字节！", e2eeSignatureLen)
+	}
+	return checkE2EEOneTimePrekeys(r.OneTimePrekeys)
+}
+
+// checkE2EEPublicKey 只接收公钥长度的数据，服务端不接收私钥
+func checkE2EEPublicKey(name string, key []byte) error {
+	if len(key) < e2eePublicKeyMinLen || len(key) > e2eePublicKeyMaxLen {
+		return fmt.Errorf("%s的长度必须为%d或%d字节！", name, e2eePublicKeyMinLen, e2eePublicKeyMaxLen)
+	}
+	return nil
+}
+
+func checkE2EEOneTimePrekeys(prekeys []wkdb.E2EEPrekey) error {
+	keyIds := make(map[uint32]struct{}, len(prekeys))
+	for _, prekey := range prekeys {
+		if _, ok := keyIds[prekey.KeyId]; ok {
+			return fmt.Errorf("one_time_prekeys的key_id[%d]重复！", prekey.KeyId)
+		}
+		keyIds[prekey.KeyId] = struct{}{}
+		if err := checkE2EEPublicKey("one_time_prekeys.public_key", prekey.PublicKey); err != nil {
+			return err
+		}
+		if len(prekey.Signature) > 0 {
+			return errors.New("one_time_prekeys不需要签名！")
+		}
+	}
+	return nil
+}
+
+func (e *E2EEAPI) upload(c *wkhttp.Context) {
+	var req e2eeUploadReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		e.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err = req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if e.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+	err = e.s.e2eeManager.upload(wkdb.E2EEDeviceKeys{
+		Uid:          req.UID,
+		DeviceId:     req.DeviceId,
+		IdentityKey:  req.IdentityKey,
+		SignedPrekey: req.SignedPrekey,
+	}, req.OneTimePrekeys)
+	if err != nil {
+		e.Error("上传设备公钥失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("deviceId", req.DeviceId))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+func (e *E2EEAPI) prekeys(c *wkhttp.Context) {
+	var req struct {
+		UID            string            `json:"uid"`
+		DeviceId       string            `json:"device_id"`
+		OneTimePrekeys []wkdb.E2EEPrekey `json:"one_time_prekeys"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		e.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" || strings.TrimSpace(req.DeviceId) == "" {
+		c.ResponseError(errors.New("uid和device_id不能为空！"))
+		return
+	}
+	if len(req.OneTimePrekeys) == 0 {
+		c.ResponseError(errors.New("one_time_prekeys不能为空！"))
+		return
+	}
+	if err = checkE2EEOneTimePrekeys(req.OneTimePrekeys); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if e.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+	if err = e.s.e2eeManager.addOneTimePrekeys(req.UID, req.DeviceId, req.OneTimePrekeys); err != nil {
+		e.Error("补充一次性预共享公钥失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("deviceId", req.DeviceId))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+func (e *E2EEAPI) claim(c *wkhttp.Context) {
+	var req struct {
+		UID       string   `json:"uid"`        // 要领取公钥的用户
+		DeviceIds []string `json:"device_ids"` // 要领取公钥的设备，为空时领取所有设备
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		e.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if e.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+	bundles, err := e.s.e2eeManager.claim(req.UID, req.DeviceIds)
+	if err != nil {
+		e.Error("领取设备公钥失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(bundles)
+}
+
+func (e *E2EEAPI) keys(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if e.s.forwardToSlotLeaderOfChannel(c, uid, wkproto.ChannelTypePerson, nil) {
+		return
+	}
+	devices, err := e.s.store.GetE2EEDeviceKeys(uid)
+	if err != nil {
+		e.Error("获取设备公钥失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(err)
+		return
+	}
+	bundles := make([]e2eeKeyBundle, 0, len(devices))
+	for _, device := range devices {
+		bundle := newE2EEKeyBundle(device)
+		if bundle.RemainPrekeys, err = e.s.store.GetE2EEOneTimePrekeyCount(uid, device.DeviceId); err != nil {
+			e.Error("获取一次性预共享公钥数量失败！", zap.Error(err), zap.String("uid", uid))
+			c.ResponseError(err)
+			return
+		}
+		bundles = append(bundles, bundle)
+	}
+	c.ResponseOKWithData(bundles)
+}
+
+func (e *E2EEAPI) prekeyCount(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	deviceId := c.Query("device_id")
+	if strings.TrimSpace(uid) == "" || strings.TrimSpace(deviceId) == "" {
+		c.ResponseError(errors.New("uid和device_id不能为空！"))
+		return
+	}
+	if e.s.forwardToSlotLeaderOfChannel(c, uid, wkproto.ChannelTypePerson, nil) {
+		return
+	}
+	count, err := e.s.store.GetE2EEOneTimePrekeyCount(uid, deviceId)
+	if err != nil {
+		e.Error("获取一次性预共享公钥数量失败！", zap.Error(err), zap.String("uid", uid), zap.String("deviceId", deviceId))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"count": count,
+	})
+}
+
+func (e *E2EEAPI) deleteKeys(c *wkhttp.Context) {
+	var req struct {
+		UID      string `json:"uid"`
+		DeviceId string `json:"device_id"`
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		e.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" || strings.TrimSpace(req.DeviceId) == "" {
+		c.ResponseError(errors.New("uid和device_id不能为空！"))
+		return
+	}
+	if e.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+	if err = e.s.e2eeManager.delete(req.UID, req.DeviceId); err != nil {
+		e.Error("删除设备公钥失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("deviceId", req.DeviceId))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestE2EEUploadReqCheck(t *testing.T) {
+	newReq := func() e2eeUploadReq {
+		return e2eeUploadReq{
+			UID:         "u1",
+			DeviceId:    "d1",
+			IdentityKey: bytes.Repeat([]byte{1}, 32),
+			SignedPrekey: wkdb.E2EEPrekey{
+				KeyId:     1,
+				PublicKey: bytes.Repeat([]byte{2}, 33),
+				Signature: bytes.Repeat([]byte{3}, 64),
+			},
+			OneTimePrekeys: []wkdb.E2EEPrekey{
+				{KeyId: 1, PublicKey: bytes.Repeat([]byte{4}, 32)},
+				{KeyId: 2, PublicKey: bytes.Repeat([]byte{5}, 32)},
+			},
+		}
+	}
+	assert.NoError(t, newReq().check())
+
+	req := newReq()
+	req.DeviceId = ""
+	assert.Error(t, req.check())
+
+	// 私钥或其他长度的数据不接收
+	req = newReq()
+	req.IdentityKey = bytes.Repeat([]byte{1}, 64)
+	assert.Error(t, req.check())
+
+	req = newReq()
+	req.SignedPrekey.Signature = nil
+	assert.Error(t, req.check())
+
+	req = newReq()
+	req.OneTimePrekeys[1].KeyId = 1
+	assert.Error(t, req.check())
+
+	req = newReq()
+	req.OneTimePrekeys[0].Signature = bytes.Repeat([]byte{3}, 64)
+	assert.Error(t, req.check())
+}
+
+func TestE2EEUploadReqJSON(t *testing.T) {
+	// 公钥使用base64编码
+	var req e2eeUploadReq
+	err := json.Unmarshal([]byte(`{"uid":"u1","device_id":"d1","identity_key":"AQID","signed_prekey":{"key_id":7,"public_key":"BAU=","signature":"Bg=="}}`), &req)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, req.IdentityKey)
+	assert.Equal(t, uint32(7), req.SignedPrekey.KeyId)
+	assert.Equal(t, []byte{4, 5}, req.SignedPrekey.PublicKey)
+	assert.Equal(t, []byte{6}, req.SignedPrekey.Signature)
+}
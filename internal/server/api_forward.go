@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"go.uber.org/zap"
+)
+
+// api请求在集群模式下转发到数据所在节点处理，以下方法返回true表示请求已处理（已转发或已返回错误），调用方直接返回即可
+
+// forwardToSlotLeaderOfChannel 转发到频道所在槽的领导节点（频道、用户等按槽存储的数据）
+func (s *Server) forwardToSlotLeaderOfChannel(c *wkhttp.Context, channelId string, channelType uint8, bodyBytes []byte) bool {
+	if !s.opts.ClusterOn() {
+		return false
+	}
+	leaderInfo, err := s.cluster.SlotLeaderOfChannel(channelId, channelType)
+	if err != nil {
+		s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return true
+	}
+	return s.forwardRequest(c, leaderInfo, bodyBytes)
+}
+
+// forwardToSlotLeader 转发到指定槽的领导节点（例如存储在槽0上的全局数据）
+func (s *Server) forwardToSlotLeader(c *wkhttp.Context, slotId uint32, bodyBytes []byte) bool {
+	if !s.opts.ClusterOn() {
+		return false
+	}
+	leaderInfo, err := s.cluster.SlotLeaderNodeInfo(slotId)
+	if err != nil {
+		s.Error("获取slot所在节点失败！", zap.Error(err), zap.Uint32("slotId", slotId))
+		c.ResponseError(errors.New("获取slot所在节点失败！"))
+		return true
+	}
+	return s.forwardRequest(c, leaderInfo, bodyBytes)
+}
+
+// forwardToChannelLeaderForRead 转发到频道的领导节点（频道日志数据），不会激活频道，频道还没有分布式配置时在本节点处理
+func (s *Server) forwardToChannelLeaderForRead(c *wkhttp.Context, channelId string, channelType uint8, bodyBytes []byte) bool {
+	if !s.opts.ClusterOn() {
+		return false
+	}
+	leaderInfo, err := s.cluster.LeaderOfChannelForRead(channelId, channelType)
+	if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) {
+		return false
+	}
+	if err != nil {
+		s.Error("获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(errors.New("获取频道所在节点失败！"))
+		return true
+	}
+	return s.forwardRequest(c, leaderInfo, bodyBytes)
+}
+
+// forwardRequest 目标节点不是本节点时转发请求（查询参数由ForwardWithBody带上）
+func (s *Server) forwardRequest(c *wkhttp.Context, nodeInfo *pb.Node, bodyBytes []byte) bool {
+	if nodeInfo.Id == s.opts.Cluster.NodeId {
+		return false
+	}
+	url := fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, c.Request.URL.Path)
+	s.Debug("转发请求：", zap.String("url", url))
+	c.ForwardWithBody(url, bodyBytes)
+	return true
+}
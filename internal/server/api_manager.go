@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/auth"
+	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/gin-gonic/gin"
@@ -28,7 +30,8 @@ func NewManagerAPI(s *Server) *ManagerAPI {
 // Route Route
 func (m *ManagerAPI) Route(r *wkhttp.WKHttp) {
 
-	r.POST("/manager/login", m.login) // 登录
+	r.POST("/manager/login", m.login)                // 登录
+	r.POST("/manager/config/reload", m.configReload) // 所有节点重新加载配置
 }
 
 func (m *ManagerAPI) login(c *wkhttp.Context) {
@@ -57,7 +60,7 @@ func (m *ManagerAPI) login(c *wkhttp.Context) {
 		return
 	}
 
-	if m.s.opts.Auth.Auth(req.Username, req.Password) != nil {
+	if m.s.opts.GetAuth().Auth(req.Username, req.Password) != nil {
 		c.ResponseError(errors.New("用户名或密码错误"))
 		return
 	}
@@ -79,7 +82,7 @@ func (m *ManagerAPI) login(c *wkhttp.Context) {
 	}
 
 	persmissionStr := ""
-	persmissions := m.s.opts.Auth.Persmissions(req.Username)
+	persmissions := m.s.opts.GetAuth().Persmissions(req.Username)
 	if len(persmissions) > 0 {
 		persmissionStr = persmissions.Format()
 	}
@@ -92,3 +95,14 @@ func (m *ManagerAPI) login(c *wkhttp.Context) {
 	})
 
 }
+
+// configReload 所有节点重新加载各自的配置文件，返回每个节点的结果
+func (m *ManagerAPI) configReload(c *wkhttp.Context) {
+	if !m.s.opts.GetAuth().HasPermissionWithContext(c, resource.Config.Reload, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+	results := m.s.configReloader.reloadCluster(c.Request.Context())
+	m.Info("config reload", zap.String("username", c.Username()), zap.Any("results", results))
+	c.JSON(http.StatusOK, results)
+}
@@ -49,6 +49,12 @@ func (m *MessageAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/message", m.searchMessage) // 搜索单条消息
 
+	r.POST("/message/search", m.searchMessagesByKeyword) // 通过关键词检索消息
+
+	r.GET("/message/scheduled", m.scheduledMessages)              // 频道的定时消息列表
+	r.POST("/message/scheduled/update", m.updateScheduledMessage) // 修改定时消息的发送时间
+	r.POST("/message/scheduled/cancel", m.cancelScheduledMessage) // 取消定时消息
+
 }
 
 func (m *MessageAPI) send(c *wkhttp.Context) {
@@ -66,6 +72,10 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 	if strings.TrimSpace(req.FromUID) == "" {
 		req.FromUID = m.s.opts.SystemUID
 	}
+	if err := m.checkSendAt(req.SendAt); err != nil {
+		c.ResponseError(err)
+		return
+	}
 
 	channelId := req.ChannelID
 	channelType := req.ChannelType
@@ -84,6 +94,24 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 			return
 		}
 
+		if req.IsScheduled() {
+			scheduledIds := make([]uint64, 0, len(req.Subscribers))
+			for _, subscriber := range req.Subscribers {
+				clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
+				sm, err := m.s.scheduledMessageManager.schedule(req, subscriber, wkproto.ChannelTypePerson, clientMsgNo)
+				if err != nil {
+					m.Error("保存定时消息失败！", zap.Error(err), zap.String("subscriber", subscriber))
+					c.ResponseError(err)
+					return
+				}
+				scheduledIds = append(scheduledIds, sm.Id)
+			}
+			c.ResponseOKWithData(map[string]interface{}{
+				"scheduled_ids": scheduledIds,
+			})
+			return
+		}
+
 		for _, subscriber := range req.Subscribers {
 			clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
 			// 发送消息
@@ -104,6 +132,26 @@ func (m *MessageAPI) send(c *wkhttp.Context) {
 		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
 	}
 
+	// 定时消息
+	if req.IsScheduled() {
+		if strings.TrimSpace(req.StreamNo) != "" {
+			c.ResponseError(errors.New("流消息不支持定时发送！"))
+			return
+		}
+		sm, err := m.s.scheduledMessageManager.schedule(req, channelId, channelType, clientMsgNo)
+		if err != nil {
+			m.Error("保存定时消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+			c.ResponseError(err)
+			return
+		}
+		c.ResponseOKWithData(map[string]interface{}{
+			"scheduled_id":  sm.Id,
+			"client_msg_no": clientMsgNo,
+			"send_at":       sm.SendAt,
+		})
+		return
+	}
+
 	// 发送消息
 	messageId, err := m.sendMessageToChannel(req, channelId, channelType, clientMsgNo, wkproto.StreamFlagIng)
 	if err != nil {
@@ -173,6 +221,7 @@ func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
 		FromUID     string        `json:"from_uid"`    // 发送者UID
 		Subscribers []string      `json:"subscribers"` // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
 		Payload     []byte        `json:"payload"`     // 消息内容
+		SendAt      int64         `json:"send_at"`     // 定时发送的时间（unix秒）
 	}
 	if err := c.BindJSON(&req); err != nil {
 		m.Error("数据格式有误！", zap.Error(err))
@@ -191,26 +240,184 @@ func (m *MessageAPI) sendBatch(c *wkhttp.Context) {
 		c.ResponseError(errors.New("payload不能为空！"))
 		return
 	}
+	if err := m.checkSendAt(req.SendAt); err != nil {
+		c.ResponseError(err)
+		return
+	}
 	failUids := make([]string, 0)
 	reasons := make([]string, 0)
+	scheduledIds := make([]uint64, 0)
 	for _, subscriber := range req.Subscribers {
 		clientMsgNo := fmt.Sprintf("%s0", wkutil.GenUUID())
-		_, err := m.sendMessageToChannel(MessageSendReq{
+		sendReq := MessageSendReq{
 			Header:      req.Header,
 			FromUID:     req.FromUID,
 			ChannelID:   subscriber,
 			ChannelType: wkproto.ChannelTypePerson,
 			Payload:     req.Payload,
-		}, subscriber, wkproto.ChannelTypePerson, clientMsgNo, wkproto.StreamFlagIng)
+			SendAt:      req.SendAt,
+		}
+		if sendReq.IsScheduled() {
+			sm, err := m.s.scheduledMessageManager.schedule(sendReq, subscriber, wkproto.ChannelTypePerson, clientMsgNo)
+			if err != nil {
+				failUids = append(failUids, subscriber)
+				reasons = append(reasons, err.Error())
+				continue
+			}
+			scheduledIds = append(scheduledIds, sm.Id)
+			continue
+		}
+		_, err := m.sendMessageToChannel(sendReq, subscriber, wkproto.ChannelTypePerson, clientMsgNo, wkproto.StreamFlagIng)
 		if err != nil {
 			failUids = append(failUids, subscriber)
 			reasons = append(reasons, err.Error())
 		}
 	}
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"fail_uids": failUids,
 		"reason":    reasons,
-	})
+	}
+	if len(scheduledIds) > 0 {
+		resp["scheduled_ids"] = scheduledIds
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// checkSendAt 检查定时发送的时间，send_at小于或等于当前时间的消息立即发送
+func (m *MessageAPI) checkSendAt(sendAt int64) error {
+	if sendAt <= 0 {
+		return nil
+	}
+	if sendAt > time.Now().Add(m.s.opts.ScheduledMessage.MaxDelay).Unix() {
+		return fmt.Errorf("send_at不能超过%s后！", m.s.opts.ScheduledMessage.MaxDelay)
+	}
+	return nil
+}
+
+// 频道的定时消息列表（按发送时间排序）
+func (m *MessageAPI) scheduledMessages(c *wkhttp.Context) {
+	channelId := c.Query("channel_id")
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	if strings.TrimSpace(channelId) == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if m.s.forwardToSlotLeaderOfChannel(c, channelId, channelType, nil) {
+		return
+	}
+
+	messages, err := m.s.store.GetScheduledMessagesOfChannel(channelId, channelType)
+	if err != nil {
+		m.Error("获取定时消息失败！", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		c.ResponseError(err)
+		return
+	}
+	if messages == nil {
+		messages = make([]wkdb.ScheduledMessage, 0)
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+type scheduledMessageReq struct {
+	Id          uint64 `json:"id"`           // 定时消息ID
+	ChannelId   string `json:"channel_id"`   // 定时消息的目标频道
+	ChannelType uint8  `json:"channel_type"` // 定时消息的目标频道类型
+	SendAt      int64  `json:"send_at"`      // 新的发送时间（unix秒），只有修改时需要
+}
+
+func (s scheduledMessageReq) check() error {
+	if s.Id == 0 {
+		return errors.New("id不能为空！")
+	}
+	if strings.TrimSpace(s.ChannelId) == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	return nil
+}
+
+// getScheduledMessage 获取请求对应的定时消息，频道不一致时视为不存在
+func (m *MessageAPI) getScheduledMessage(req scheduledMessageReq) (wkdb.ScheduledMessage, error) {
+	sm, err := m.s.store.GetScheduledMessage(req.Id)
+	if err != nil {
+		return wkdb.EmptyScheduledMessage, err
+	}
+	if wkdb.IsEmptyScheduledMessage(sm) || sm.ChannelId != req.ChannelId || sm.ChannelType != req.ChannelType {
+		return wkdb.EmptyScheduledMessage, errors.New("定时消息不存在或已发送！")
+	}
+	return sm, nil
+}
+
+// 修改定时消息的发送时间
+func (m *MessageAPI) updateScheduledMessage(c *wkhttp.Context) {
+	var req scheduledMessageReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if req.SendAt <= time.Now().Unix() {
+		c.ResponseError(errors.New("send_at必须大于当前时间！"))
+		return
+	}
+	if err := m.checkSendAt(req.SendAt); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if m.s.forwardToSlotLeaderOfChannel(c, req.ChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	sm, err := m.getScheduledMessage(req)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	updatedAt := time.Now()
+	sm.SendAt = req.SendAt
+	sm.UpdatedAt = &updatedAt
+	err = m.s.store.AddOrUpdateScheduledMessage(sm)
+	if err != nil {
+		m.Error("修改定时消息失败！", zap.Error(err), zap.Uint64("id", req.Id))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+// 取消定时消息
+func (m *MessageAPI) cancelScheduledMessage(c *wkhttp.Context) {
+	var req scheduledMessageReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+	if m.s.forwardToSlotLeaderOfChannel(c, req.ChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	sm, err := m.getScheduledMessage(req)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	err = m.s.store.DeleteScheduledMessage(sm.ChannelId, sm.Id)
+	if err != nil {
+		m.Error("取消定时消息失败！", zap.Error(err), zap.Uint64("id", req.Id))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
 }
 
 // 消息同步
@@ -631,3 +838,81 @@ func (m *MessageAPI) searchMessage(c *wkhttp.Context) {
 	resp.from(messages[0], m.s)
 	c.JSON(http.StatusOK, resp)
 }
+
+func (m *MessageAPI) searchMessagesByKeyword(c *wkhttp.Context) {
+	var req struct {
+		UID         string `json:"uid"`          // 检索者uid，只能检索此用户所在的频道
+		Keyword     string `json:"keyword"`      // 关键词
+		ChannelId   string `json:"channel_id"`   // 指定频道检索（可选）
+		ChannelType uint8  `json:"channel_type"` // 指定频道类型（可选）
+		Page        int    `json:"page"`         // 页码，从1开始
+		Limit       int    `json:"limit"`        // 每页数量
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		m.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if !m.s.opts.MessageSearch.On {
+		c.ResponseError(errors.New("消息检索未开启！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if strings.TrimSpace(req.Keyword) == "" {
+		c.ResponseError(errors.New("keyword不能为空！"))
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 || req.Limit > 100 {
+		req.Limit = 20
+	}
+
+	// 用户的最近会话存储在用户所在的槽领导节点上
+	if m.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+
+	// 指定频道时检索该频道，否则检索用户的最近会话频道，都只保留用户当前是订阅者的频道
+	var channels []*messageSearchChannelReq
+	if strings.TrimSpace(req.ChannelId) != "" {
+		fakeChannelId := req.ChannelId
+		if req.ChannelType == wkproto.ChannelTypePerson {
+			fakeChannelId = GetFakeChannelIDWith(req.UID, req.ChannelId)
+		}
+		channels = []*messageSearchChannelReq{{ChannelId: fakeChannelId, ChannelType: req.ChannelType}}
+	} else {
+		channels, err = m.s.userSearchChannels(req.UID)
+		if err != nil {
+			m.Error("获取用户可检索的频道失败！", zap.Error(err), zap.String("uid", req.UID))
+			c.ResponseError(errors.New("获取用户可检索的频道失败！"))
+			return
+		}
+	}
+	channels, err = m.s.searchableChannels(req.UID, channels)
+	if err != nil {
+		m.Error("校验用户可检索的频道失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("获取用户可检索的频道失败！"))
+		return
+	}
+
+	results, err := m.s.searchMessagesForCluster(req.Keyword, channels, req.Page, req.Limit)
+	if err != nil {
+		m.Error("检索消息失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("检索消息失败！"))
+		return
+	}
+	if results == nil {
+		results = make([]*messageSearchResult, 0)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"page":     req.Page,
+		"limit":    req.Limit,
+		"messages": results,
+	})
+}
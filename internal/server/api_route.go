@@ -5,7 +5,6 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
-	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
@@ -31,11 +30,20 @@ func (a *RouteAPI) Route(r *wkhttp.WKHttp) {
 }
 
 // 路由用户的IM连接地址
+// 根据用户所在槽的领导节点、节点的连接数和健康状态选择连接节点（跳过离线和下线中的节点），
+// tcp_addr/ws_addr/wss_addr为选中节点的地址，nodes为所有可用节点的地址
 func (a *RouteAPI) routeUserIMAddr(c *wkhttp.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"tcp_addr": a.s.opts.External.TCPAddr,
-		"ws_addr":  a.s.opts.External.WSAddr,
-		"wss_addr": a.s.opts.External.WSSAddr,
+	uid := c.Query("uid")
+	nodes := a.s.routeManager.availableNodes()
+	route := a.s.routeManager.routeOfNodes(uid, nodes)
+
+	routes := make([]nodeRoute, 0, len(nodes))
+	for _, node := range nodes {
+		routes = append(routes, node.nodeRoute)
+	}
+	c.JSON(http.StatusOK, routeResp{
+		nodeRoute: *route,
+		Nodes:     routes,
 	})
 }
 
@@ -48,17 +56,35 @@ func (a *RouteAPI) routeUserIMAddrOfBatch(c *wkhttp.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, []userAddrResp{
-		{
-			UIDs:    uids,
-			TCPAddr: a.s.opts.External.TCPAddr,
-			WSAddr:  a.s.opts.External.WSAddr,
-			WSSAddr: a.s.opts.External.WSSAddr,
-		},
-	})
+	nodes := a.s.routeManager.availableNodes()
+	resps := make([]*userAddrResp, 0, len(nodes))
+	respMap := make(map[uint64]*userAddrResp)
+	for _, uid := range uids {
+		route := a.s.routeManager.routeOfNodes(uid, nodes)
+		resp := respMap[route.NodeId]
+		if resp == nil {
+			resp = &userAddrResp{
+				NodeId:  route.NodeId,
+				TCPAddr: route.TCPAddr,
+				WSAddr:  route.WSAddr,
+				WSSAddr: route.WSSAddr,
+				UIDs:    make([]string, 0),
+			}
+			respMap[route.NodeId] = resp
+			resps = append(resps, resp)
+		}
+		resp.UIDs = append(resp.UIDs, uid)
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+type routeResp struct {
+	nodeRoute
+	Nodes []nodeRoute `json:"nodes"` // 所有可用节点的地址
 }
 
 type userAddrResp struct {
+	NodeId  uint64   `json:"node_id"`
 	TCPAddr string   `json:"tcp_addr"`
 	WSAddr  string   `json:"ws_addr"`
 	WSSAddr string   `json:"wss_addr"`
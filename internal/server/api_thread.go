@@ -0,0 +1,175 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// ThreadAPI 消息的子区
+type ThreadAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewThreadAPI(s *Server) *ThreadAPI {
+	return &ThreadAPI{
+		s:   s,
+		Log: wklog.NewWKLog("ThreadAPI"),
+	}
+}
+
+// Route 路由
+func (t *ThreadAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/thread/reply", t.reply) // 回复消息（发送到消息的子区）
+	r.POST("/thread/sync", t.sync)   // 同步子区的消息
+	r.GET("/thread/list", t.list)    // 获取频道内活跃的子区
+}
+
+// 回复消息，回复保存在根消息的子区内，不会出现在父频道
+func (t *ThreadAPI) reply(c *wkhttp.Context) {
+	var req threadReplyReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	// 在父频道的领导节点上校验根消息和权限
+	fakeChannelId := threadFakeChannelId(req.FromUid, req.ChannelId, req.ChannelType)
+	if t.s.forwardToChannelLeaderForRead(c, fakeChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	msg, err := t.s.threadManager.reply(req, fakeChannelId)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOKWithData(map[string]interface{}{
+		"message_id":       msg.MessageID,
+		"message_seq":      msg.MessageSeq,
+		"client_msg_no":    msg.ClientMsgNo,
+		"thread_id":        msg.ChannelID,
+		"root_message_seq": req.RootMessageSeq,
+	})
+}
+
+// 同步子区的消息
+func (t *ThreadAPI) sync(c *wkhttp.Context) {
+	var req struct {
+		LoginUid        string `json:"login_uid"` // 当前登录用户的uid
+		ChannelId       string `json:"channel_id"`
+		ChannelType     uint8  `json:"channel_type"`
+		RootMessageSeq  uint64 `json:"root_message_seq"`
+		StartMessageSeq uint64 `json:"start_message_seq"` // 开始消息列号（结果包含start_message_seq的消息）
+		Limit           int    `json:"limit"`             // 每次同步数量限制
+	}
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.ChannelId) == "" || req.RootMessageSeq == 0 {
+		c.ResponseError(errors.New("channel_id和root_message_seq不能为空！"))
+		return
+	}
+	if req.ChannelType == wkproto.ChannelTypePerson && strings.TrimSpace(req.LoginUid) == "" {
+		c.ResponseError(errors.New("login_uid不能为空！"))
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > 10000 {
+		limit = 10000
+	}
+
+	// 子区的消息在子区频道的领导节点上读取
+	fakeChannelId := threadFakeChannelId(req.LoginUid, req.ChannelId, req.ChannelType)
+	threadChannelId := wkdb.ThreadChannelId(fakeChannelId, req.RootMessageSeq)
+	if t.s.forwardToChannelLeaderForRead(c, threadChannelId, req.ChannelType, bodyBytes) {
+		return
+	}
+
+	thread, err := t.s.store.GetThread(fakeChannelId, req.ChannelType, req.RootMessageSeq)
+	if err != nil {
+		t.Error("获取子区失败！", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("rootMessageSeq", req.RootMessageSeq))
+		c.ResponseError(errors.New("获取子区失败！"))
+		return
+	}
+	if wkdb.IsEmptyThread(thread) {
+		thread = wkdb.Thread{ChannelType: req.ChannelType, RootMessageSeq: req.RootMessageSeq}
+	}
+
+	startMessageSeq := req.StartMessageSeq
+	if startMessageSeq == 0 {
+		startMessageSeq = 1
+	}
+	messages, err := t.s.store.LoadNextRangeMsgs(threadChannelId, req.ChannelType, startMessageSeq, 0, limit)
+	if err != nil {
+		t.Error("获取子区消息失败！", zap.Error(err), zap.String("threadChannelId", threadChannelId))
+		c.ResponseError(errors.New("获取子区消息失败！"))
+		return
+	}
+	messageResps := make([]*MessageResp, 0, len(messages))
+	for _, message := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(message, t.s)
+		messageResps = append(messageResps, messageResp)
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"thread":   newThreadResp(req.ChannelId, thread),
+		"more":     wkutil.BoolToInt(len(messageResps) >= limit),
+		"messages": messageResps,
+	})
+}
+
+// 获取频道内的子区，按最后回复时间从新到旧排序
+func (t *ThreadAPI) list(c *wkhttp.Context) {
+	loginUid := strings.TrimSpace(c.Query("login_uid"))
+	channelId := strings.TrimSpace(c.Query("channel_id"))
+	channelType := wkutil.StringToUint8(c.Query("channel_type"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if channelId == "" {
+		c.ResponseError(errors.New("channel_id不能为空！"))
+		return
+	}
+	if channelType == wkproto.ChannelTypePerson && loginUid == "" {
+		c.ResponseError(errors.New("login_uid不能为空！"))
+		return
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	fakeChannelId := threadFakeChannelId(loginUid, channelId, channelType)
+	if t.s.forwardToChannelLeaderForRead(c, fakeChannelId, channelType, nil) {
+		return
+	}
+
+	threads, err := t.s.store.GetThreads(fakeChannelId, channelType, limit)
+	if err != nil {
+		t.Error("获取子区列表失败！", zap.Error(err), zap.String("channelId", fakeChannelId))
+		c.ResponseError(errors.New("获取子区列表失败！"))
+		return
+	}
+	resps := make([]*threadResp, 0, len(threads))
+	for _, thread := range threads {
+		resps = append(resps, newThreadResp(channelId, thread))
+	}
+	c.JSON(http.StatusOK, resps)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,8 +38,11 @@ func NewUserAPI(s *Server) *UserAPI {
 func (u *UserAPI) Route(r *wkhttp.WKHttp) {
 
 	r.POST("/user/token", u.updateToken)                  // 更新用户token
+	r.POST("/user/token/revoke", u.revokeToken)           // 吊销签名令牌
 	r.POST("/user/device_quit", u.deviceQuit)             // 强制设备退出
 	r.POST("/user/onlinestatus", u.getOnlineStatus)       // 获取用户在线状态
+	r.POST("/user/presence", u.setPresence)               // 设置用户自定义状态
+	r.POST("/user/presence/get", u.getPresences)          // 获取用户状态（包含自定义状态和最后在线时间）
 	r.POST("/user/systemuids_add", u.systemUidsAdd)       // 添加系统uid
 	r.POST("/user/systemuids_remove", u.systemUidsRemove) // 移除系统uid
 	r.GET("/user/systemuids", u.getSystemUids)            // 获取系统uid
@@ -640,6 +644,69 @@ func (u UpdateTokenReq) Check() error {
 	return nil
 }
 
+// 吊销签名令牌（tokenAuth.mode为jwt时生效），吊销后此令牌不能再用于连接
+func (u *UserAPI) revokeToken(c *wkhttp.Context) {
+	var req revokeTokenReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if err := req.Check(); err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if u.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+
+	expireAt := req.ExpireAt
+	if expireAt == 0 { // 未指定令牌的过期时间，吊销记录保留revokedTokenTTL
+		_, tokenAuth := u.s.opts.GetTokenAuth()
+		if tokenAuth.RevokedTokenTTL > 0 {
+			expireAt = uint64(time.Now().Add(tokenAuth.RevokedTokenTTL).Unix())
+		}
+	}
+	err = u.s.store.AddRevokedToken(wkdb.RevokedToken{
+		Uid:      req.UID,
+		TokenId:  req.TokenId,
+		ExpireAt: expireAt,
+	})
+	if err != nil {
+		u.Error("吊销令牌失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("tokenId", req.TokenId))
+		c.ResponseError(err)
+		return
+	}
+	// 已经用此令牌认证的连接不会再验证令牌，需要踢掉
+	if err = u.s.kickUserByToken(req.UID, req.TokenId); err != nil {
+		u.Error("踢掉使用已吊销令牌的连接失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("tokenId", req.TokenId))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
+type revokeTokenReq struct {
+	UID      string `json:"uid"`       // 用户uid
+	TokenId  string `json:"token_id"`  // 令牌ID（jti）
+	ExpireAt uint64 `json:"expire_at"` // 令牌的过期时间（unix秒），到期后吊销记录自动失效，0表示按tokenAuth.revokedTokenTTL保留
+}
+
+func (r revokeTokenReq) Check() error {
+	if strings.TrimSpace(r.UID) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if IsSpecialChar(r.UID) {
+		return errors.New("uid不能包含特殊字符！")
+	}
+	if strings.TrimSpace(r.TokenId) == "" {
+		return errors.New("token_id不能为空！")
+	}
+	return nil
+}
+
 type OnlinestatusResp struct {
 	UID        string `json:"uid"`         // 在线用户uid
 	DeviceFlag uint8  `json:"device_flag"` // 设备标记 0. APP 1.web
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 设置用户自定义状态（online/away/busy），请求转发到用户所在的领导节点处理
+func (u *UserAPI) setPresence(c *wkhttp.Context) {
+	if !u.s.opts.Presence.On {
+		c.ResponseError(errors.New("未开启用户在线状态功能！"))
+		return
+	}
+	var req presenceSetReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	status, err := req.check()
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+
+	if u.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+
+	presence, err := u.s.presenceManager.setStatus(req.UID, status, req.Text)
+	if err != nil {
+		u.Error("设置用户状态失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(errors.New("设置用户状态失败！"))
+		return
+	}
+	c.JSON(http.StatusOK, presence.toResp())
+}
+
+// 获取用户状态，跨节点汇总
+func (u *UserAPI) getPresences(c *wkhttp.Context) {
+	if !u.s.opts.Presence.On {
+		c.ResponseError(errors.New("未开启用户在线状态功能！"))
+		return
+	}
+	var uids []string
+	if err := c.BindJSON(&uids); err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if len(uids) == 0 {
+		c.JSON(http.StatusOK, []*presenceResp{})
+		return
+	}
+	presences, err := u.s.presenceManager.getPresences(uids)
+	if err != nil {
+		u.Error("获取用户状态失败！", zap.Error(err))
+		c.ResponseError(errors.New("获取用户状态失败！"))
+		return
+	}
+	resps := make([]*presenceResp, 0, len(presences))
+	for _, presence := range presences {
+		resps = append(resps, presence.toResp())
+	}
+	c.JSON(http.StatusOK, resps)
+}
+
+type presenceSetReq struct {
+	UID    string `json:"uid"`    // 用户uid
+	Status string `json:"status"` // 状态 online/away/busy
+	Text   string `json:"text"`   // 自定义状态文本
+}
+
+func (r presenceSetReq) check() (PresenceStatus, error) {
+	if strings.TrimSpace(r.UID) == "" {
+		return PresenceStatusOffline, errors.New("uid不能为空！")
+	}
+	if IsSpecialChar(r.UID) {
+		return PresenceStatusOffline, errors.New("uid不能包含特殊字符！")
+	}
+	status, ok := ParsePresenceStatus(r.Status)
+	if !ok {
+		return PresenceStatusOffline, errors.New("状态不正确，只支持online/away/busy！")
+	}
+	if len([]rune(r.Text)) > 100 {
+		return PresenceStatusOffline, errors.New("状态文本不能超过100个字符！")
+	}
+	return status, nil
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 转发导出文件下载请求时的标记，防止导出文件不存在时来回转发
+const userDataForwardedHeader = "X-WK-UserData-Forwarded"
+
+// UserDataAPI 用户数据的导出和删除
+type UserDataAPI struct {
+	s *Server
+	wklog.Log
+}
+
+func NewUserDataAPI(s *Server) *UserDataAPI {
+	return &UserDataAPI{
+		s:   s,
+		Log: wklog.NewWKLog("UserDataAPI"),
+	}
+}
+
+// Route 路由
+func (u *UserDataAPI) Route(r *wkhttp.WKHttp) {
+	r.POST("/user/data/export", u.export)                 // 创建导出任务
+	r.POST("/user/data/erase", u.erase)                   // 创建删除任务
+	r.GET("/user/data/jobs", u.jobs)                      // 获取用户的任务
+	r.GET("/user/data/export/download", u.exportDownload) // 下载导出文件
+}
+
+type userDataJobReq struct {
+	UID      string `json:"uid"`      // 用户uid
+	Operator string `json:"operator"` // 操作者（记录在任务内，用于审计）
+}
+
+// userDataJobResp 用户数据任务
+type userDataJobResp struct {
+	JobId             uint64   `json:"job_id"`
+	UID               string   `json:"uid"`
+	Type              string   `json:"type"`   // export: 导出 erase: 删除
+	Status            string   `json:"status"` // running: 执行中 done: 已完成 failed: 执行失败
+	Operator          string   `json:"operator,omitempty"`
+	Error             string   `json:"error,omitempty"`    // 失败原因或未完成的原因（例如有节点离线，等待重试）
+	NodeId            uint64   `json:"node_id,omitempty"`  // 导出文件所在的节点
+	DeviceCount       uint64   `json:"device_count"`       // 导出或删除的设备数量
+	ConversationCount uint64   `json:"conversation_count"` // 导出或删除的最近会话数量
+	ChannelCount      uint64   `json:"channel_count"`      // 导出或移除的频道成员关系数量
+	MessageCount      uint64   `json:"message_count"`      // 导出或匿名化的消息数量
+	ErasedNodes       []uint64 `json:"erased_nodes,omitempty"`
+	CreatedAt         int64    `json:"created_at"` // 创建时间（unix秒）
+	UpdatedAt         int64    `json:"updated_at"` // 更新时间（unix秒）
+}
+
+func newUserDataJobResp(j wkdb.UserDataJob) *userDataJobResp {
+	resp := &userDataJobResp{
+		JobId:             j.Id,
+		UID:               j.Uid,
+		Type:              j.Type.String(),
+		Status:            j.Status.String(),
+		Operator:          j.Operator,
+		Error:             j.Error,
+		NodeId:            j.NodeId,
+		DeviceCount:       j.DeviceCount,
+		ConversationCount: j.ConversationCount,
+		ChannelCount:      j.ChannelCount,
+		MessageCount:      j.MessageCount,
+		ErasedNodes:       j.ErasedNodes,
+	}
+	if j.CreatedAt != nil {
+		resp.CreatedAt = j.CreatedAt.Unix()
+	}
+	if j.UpdatedAt != nil {
+		resp.UpdatedAt = j.UpdatedAt.Unix()
+	}
+	return resp
+}
+
+func (u *UserDataAPI) export(c *wkhttp.Context) {
+	u.createJob(c, wkdb.UserDataJobTypeExport)
+}
+
+func (u *UserDataAPI) erase(c *wkhttp.Context) {
+	u.createJob(c, wkdb.UserDataJobTypeErase)
+}
+
+func (u *UserDataAPI) createJob(c *wkhttp.Context, jobType wkdb.UserDataJobType) {
+	var req userDataJobReq
+	bodyBytes, err := BindJSON(&req, c)
+	if err != nil {
+		u.Error("数据格式有误！", zap.Error(err))
+		c.ResponseError(errors.New("数据格式有误！"))
+		return
+	}
+	if strings.TrimSpace(req.UID) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if !u.s.opts.ClusterOn() {
+		c.ResponseError(errors.New("用户数据任务需要开启分布式！"))
+		return
+	}
+	if u.s.forwardToSlotLeaderOfChannel(c, req.UID, wkproto.ChannelTypePerson, bodyBytes) {
+		return
+	}
+
+	// 同一个用户同时只能有一个同类型的任务在执行
+	jobs, err := u.s.userDataManager.jobsOfUid(req.UID)
+	if err != nil {
+		u.Error("获取用户数据任务失败！", zap.Error(err), zap.String("uid", req.UID))
+		c.ResponseError(err)
+		return
+	}
+	for _, job := range jobs {
+		if job.Type == jobType && job.Status == wkdb.UserDataJobStatusRunning {
+			c.ResponseOKWithData(newUserDataJobResp(job))
+			return
+		}
+	}
+
+	job, err := u.s.userDataManager.create(req.UID, jobType, req.Operator)
+	if err != nil {
+		u.Error("创建用户数据任务失败！", zap.Error(err), zap.String("uid", req.UID), zap.String("type", jobType.String()))
+		c.ResponseError(err)
+		return
+	}
+	u.Info("创建用户数据任务", zap.Uint64("jobId", job.Id), zap.String("uid", req.UID), zap.String("type", jobType.String()), zap.String("operator", req.Operator))
+	c.ResponseOKWithData(newUserDataJobResp(job))
+}
+
+func (u *UserDataAPI) jobs(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	if u.s.forwardToSlotLeaderOfChannel(c, uid, wkproto.ChannelTypePerson, nil) {
+		return
+	}
+	jobs, err := u.s.userDataManager.jobsOfUid(uid)
+	if err != nil {
+		u.Error("获取用户数据任务失败！", zap.Error(err), zap.String("uid", uid))
+		c.ResponseError(err)
+		return
+	}
+	resps := make([]*userDataJobResp, 0, len(jobs))
+	for _, job := range jobs {
+		resps = append(resps, newUserDataJobResp(job))
+	}
+	c.ResponseOKWithData(resps)
+}
+
+// exportDownload 导出文件保存在执行任务的节点上，先从槽的领导节点获取任务并校验任务属于该用户，
+// 文件不在本节点时原样转发到文件所在的节点（文件所在的节点同样会校验任务）
+func (u *UserDataAPI) exportDownload(c *wkhttp.Context) {
+	uid := c.Query("uid")
+	if strings.TrimSpace(uid) == "" {
+		c.ResponseError(errors.New("uid不能为空！"))
+		return
+	}
+	jobId, err := strconv.ParseUint(c.Query("job_id"), 10, 64)
+	if err != nil || jobId == 0 {
+		c.ResponseError(errors.New("job_id有误！"))
+		return
+	}
+
+	job, err := u.s.userDataManager.getJob(uid, jobId)
+	if err != nil {
+		u.Error("获取用户数据任务失败！", zap.Error(err), zap.String("uid", uid), zap.Uint64("jobId", jobId))
+		c.ResponseError(err)
+		return
+	}
+	if wkdb.IsEmptyUserDataJob(job) || job.Uid != uid || job.Type != wkdb.UserDataJobTypeExport {
+		c.ResponseError(errors.New("导出任务不存在！"))
+		return
+	}
+	if job.Status != wkdb.UserDataJobStatusDone {
+		c.ResponseError(errors.New("导出任务未完成！"))
+		return
+	}
+
+	if job.NodeId == u.s.opts.Cluster.NodeId {
+		archivePath := path.Join(u.s.userDataManager.exportDir(), userDataArchiveName(jobId))
+		if _, err = os.Stat(archivePath); err != nil {
+			c.ResponseError(errors.New("导出文件不存在！"))
+			return
+		}
+		c.FileAttachment(archivePath, fmt.Sprintf("%s_%s", uid, userDataArchiveName(jobId)))
+		return
+	}
+	if c.GetHeader(userDataForwardedHeader) != "" {
+		c.ResponseError(errors.New("导出文件不存在！"))
+		return
+	}
+	for _, n := range u.s.clusterServer.GetConfig().Nodes {
+		if n.Id != job.NodeId {
+			continue
+		}
+		if !n.Online {
+			c.ResponseError(errors.New("导出文件所在的节点离线！"))
+			return
+		}
+		c.Request.Header.Set(userDataForwardedHeader, "1")
+		c.ForwardRaw(fmt.Sprintf("%s%s", n.ApiServerAddr, c.Request.URL.Path))
+		return
+	}
+	c.ResponseError(errors.New("导出文件所在的节点不存在！"))
+}
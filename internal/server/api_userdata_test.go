@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserDataJobResp(t *testing.T) {
+	createdAt := time.Unix(1700000000, 0)
+	updatedAt := time.Unix(1700000060, 0)
+	resp := newUserDataJobResp(wkdb.UserDataJob{
+		Id:           1,
+		Uid:          "u1",
+		Type:         wkdb.UserDataJobTypeErase,
+		Status:       wkdb.UserDataJobStatusRunning,
+		Operator:     "admin",
+		Error:        "node 2 offline",
+		MessageCount: 10,
+		ErasedNodes:  []uint64{1},
+		CreatedAt:    &createdAt,
+		UpdatedAt:    &updatedAt,
+	})
+
+	data, err := json.Marshal(resp)
+	assert.NoError(t, err)
+
+	var result map[string]interface{}
+	err = json.Unmarshal(data, &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "erase", result["type"])
+	assert.Equal(t, "running", result["status"])
+	assert.Equal(t, "admin", result["operator"])
+	assert.Equal(t, float64(10), result["message_count"])
+	assert.Equal(t, float64(1700000000), result["created_at"])
+	assert.Equal(t, float64(1700000060), result["updated_at"])
+	assert.NotContains(t, result, "node_id")
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/WuKongIM/WuKongIM/pkg/keylock"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// attributeManager 用户和频道的自定义属性
+// 用户属性与用户、频道属性与频道存放在同一个槽内，读取时如果本节点不是槽的领导节点则向槽的领导节点获取，
+// 修改和删除都在槽的领导节点上执行
+type attributeManager struct {
+	s *Server
+	wklog.Log
+
+	// 同一个用户或频道的属性修改串行执行，保证版本校验（乐观锁）和提交之间属性不会被其他请求修改，
+	// 版本不一致在提交前就返回错误，而不是在应用日志时被跳过
+	writeLock *keylock.KeyLock
+}
+
+func newAttributeManager(s *Server) *attributeManager {
+	return &attributeManager{
+		s:         s,
+		Log:       wklog.NewWKLog("attributeManager"),
+		writeLock: keylock.NewKeyLock(),
+	}
+}
+
+func (a *attributeManager) start() error {
+	a.writeLock.StartCleanLoop()
+	return nil
+}
+
+func (a *attributeManager) stop() {
+	a.writeLock.StopCleanLoop()
+}
+
+// lockUser 锁定用户属性的修改
+func (a *attributeManager) lockUser(uid string) func() {
+	key := wkutil.ChannelToKey(uid, wkproto.ChannelTypePerson)
+	a.writeLock.Lock(key)
+	return func() {
+		a.writeLock.Unlock(key)
+	}
+}
+
+// lockChannel 锁定频道属性的修改
+func (a *attributeManager) lockChannel(channelId string, channelType uint8) func() {
+	key := wkutil.ChannelToKey(channelId, channelType)
+	a.writeLock.Lock(key)
+	return func() {
+		a.writeLock.Unlock(key)
+	}
+}
+
+type attributeGetReq struct {
+	Uid         string   `json:"uid,omitempty"`
+	ChannelId   string   `json:"channel_id,omitempty"`
+	ChannelType uint8    `json:"channel_type,omitempty"`
+	Names       []string `json:"names,omitempty"`
+}
+
+// getUserAttributes 获取用户属性，names为空时获取所有属性
+func (a *attributeManager) getUserAttributes(uid string, names []string) ([]wkdb.Attribute, error) {
+	leaderId, err := a.slotLeaderId(uid)
+	if err != nil {
+		return nil, err
+	}
+	if leaderId == 0 || leaderId == a.s.opts.Cluster.NodeId {
+		return a.s.store.GetUserAttributes(uid, names)
+	}
+	return a.requestAttributes(leaderId, attributeGetReq{Uid: uid, Names: names})
+}
+
+// getChannelAttributes 获取频道属性，names为空时获取所有属性
+func (a *attributeManager) getChannelAttributes(channelId string, channelType uint8, names []string) ([]wkdb.Attribute, error) {
+	leaderId, err := a.slotLeaderId(channelId)
+	if err != nil {
+		return nil, err
+	}
+	if leaderId == 0 || leaderId == a.s.opts.Cluster.NodeId {
+		return a.s.store.GetChannelAttributes(channelId, channelType, names)
+	}
+	return a.requestAttributes(leaderId, attributeGetReq{ChannelId: channelId, ChannelType: channelType, Names: names})
+}
+
+// slotLeaderId 获取key所在槽的领导节点，未开启分布式时返回0
+func (a *attributeManager) slotLeaderId(key string) (uint64, error) {
+	if !a.s.opts.ClusterOn() {
+		return 0, nil
+	}
+	// 槽只和频道id有关，这里的频道类型不影响结果
+	return a.s.cluster.SlotLeaderIdOfChannel(key, wkproto.ChannelTypePerson)
+}
+
+func (a *attributeManager) requestAttributes(nodeId uint64, req attributeGetReq) ([]wkdb.Attribute, error) {
+	timeoutCtx, cancel := context.WithTimeout(a.s.ctx, a.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := a.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/getAttributes", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var attrs []wkdb.Attribute
+	if err = wkutil.ReadJSONByByte(resp.Body, &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// handleGetAttributesReq 获取本节点上的用户属性或频道属性
+func (a *attributeManager) handleGetAttributesReq(c *wkserver.Context) {
+	var req attributeGetReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	var (
+		attrs []wkdb.Attribute
+		err   error
+	)
+	if req.Uid != "" {
+		attrs, err = a.s.store.GetUserAttributes(req.Uid, req.Names)
+	} else {
+		attrs, err = a.s.store.GetChannelAttributes(req.ChannelId, req.ChannelType, req.Names)
+	}
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(attrs)))
+}
+
+// fillMessageAttributes 给webhook的消息附带配置的发送者属性和频道属性，同一批消息内相同的发送者和频道只获取一次
+func (a *attributeManager) fillMessageAttributes(resps []*MessageResp) {
+	userNames := a.s.opts.Attribute.MessageUserAttributes
+	channelNames := a.s.opts.Attribute.MessageChannelAttributes
+	if len(userNames) == 0 && len(channelNames) == 0 {
+		return
+	}
+	userAttrs := make(map[string]map[string]json.RawMessage)
+	channelAttrs := make(map[string]map[string]json.RawMessage)
+	for _, resp := range resps {
+		if len(userNames) > 0 && resp.FromUID != "" {
+			attrs, ok := userAttrs[resp.FromUID]
+			if !ok {
+				list, err := a.getUserAttributes(resp.FromUID, userNames)
+				if err != nil {
+					a.Warn("get user attributes failed", zap.Error(err), zap.String("uid", resp.FromUID))
+				}
+				attrs = attributeValues(list)
+				userAttrs[resp.FromUID] = attrs
+			}
+			resp.FromUserAttributes = attrs
+		}
+		// 个人频道的频道属性没有意义，使用发送者属性即可
+		if len(channelNames) > 0 && resp.ChannelType != wkproto.ChannelTypePerson {
+			channelKey := wkutil.ChannelToKey(resp.ChannelID, resp.ChannelType)
+			attrs, ok := channelAttrs[channelKey]
+			if !ok {
+				list, err := a.getChannelAttributes(resp.ChannelID, resp.ChannelType, channelNames)
+				if err != nil {
+					a.Warn("get channel attributes failed", zap.Error(err), zap.String("channelId", resp.ChannelID), zap.Uint8("channelType", resp.ChannelType))
+				}
+				attrs = attributeValues(list)
+				channelAttrs[channelKey] = attrs
+			}
+			resp.ChannelAttributes = attrs
+		}
+	}
+}
+
+func attributeValues(attrs []wkdb.Attribute) map[string]json.RawMessage {
+	if len(attrs) == 0 {
+		return nil
+	}
+	values := make(map[string]json.RawMessage, len(attrs))
+	for _, attr := range attrs {
+		values[attr.Name] = json.RawMessage(attr.Value)
+	}
+	return values
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkbackup"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/WuKongIM/WuKongIM/version"
+	"go.uber.org/zap"
+)
+
+var errBackupRunning = errors.New("backup is running")
+
+// backupManager 在线备份
+// 每个节点对自己的数据（wkdb的所有分片、槽日志、集群配置日志和配置文件）生成pebble一致性快照后上传到备份目标，
+// 所有节点都上传成功后由发起备份的节点写入集群备份清单
+type backupManager struct {
+	s *Server
+	wklog.Log
+
+	mu      sync.Mutex // 发起备份的锁，同一时间只能有一个集群备份
+	localMu sync.Mutex // 本节点快照的锁
+}
+
+func newBackupManager(s *Server) *backupManager {
+	return &backupManager{
+		s:   s,
+		Log: wklog.NewWKLog("backupManager"),
+	}
+}
+
+func (b *backupManager) target() (wkbackup.Target, error) {
+	return wkbackup.NewTarget(b.s.opts.Backup.Target, b.s.opts.Backup.S3)
+}
+
+// backup 发起集群备份，所有节点必须在线
+func (b *backupManager) backup(ctx context.Context) (*wkbackup.Manifest, error) {
+	if !b.mu.TryLock() {
+		return nil, errBackupRunning
+	}
+	defer b.mu.Unlock()
+
+	target, err := b.target()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := b.s.clusterServer.GetConfig()
+	for _, node := range cfg.Nodes {
+		if !node.Online {
+			return nil, fmt.Errorf("node[%d] is offline, backup requires all nodes online", node.Id)
+		}
+	}
+
+	createdAt := time.Now()
+	manifest := &wkbackup.Manifest{
+		BackupId:      wkbackup.NewBackupId(createdAt),
+		CreatedAt:     createdAt,
+		AppVersion:    version.Version,
+		ConfigVersion: cfg.Version,
+		Nodes:         make([]*wkbackup.NodeManifest, 0, len(cfg.Nodes)),
+	}
+	b.Info("backup start", zap.String("backupId", manifest.BackupId), zap.Int("nodes", len(cfg.Nodes)))
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		reqErr error
+	)
+	for _, node := range cfg.Nodes {
+		wg.Add(1)
+		go func(nodeId uint64) {
+			defer wg.Done()
+			var (
+				nodeManifest *wkbackup.NodeManifest
+				err          error
+			)
+			if nodeId == b.s.opts.Cluster.NodeId {
+				nodeManifest, err = b.backupLocal(ctx, manifest.BackupId)
+			} else {
+				nodeManifest, err = b.requestBackup(ctx, nodeId, manifest.BackupId)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				b.Error("backup node failed", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("backupId", manifest.BackupId))
+				reqErr = err
+				return
+			}
+			manifest.Nodes = append(manifest.Nodes, nodeManifest)
+		}(node.Id)
+	}
+	wg.Wait()
+	if reqErr != nil {
+		return nil, reqErr
+	}
+	sort.Slice(manifest.Nodes, func(i, j int) bool {
+		return manifest.Nodes[i].NodeId < manifest.Nodes[j].NodeId
+	})
+
+	if err = wkbackup.WriteManifest(ctx, target, manifest); err != nil {
+		return nil, err
+	}
+	b.Info("backup finished", zap.String("backupId", manifest.BackupId), zap.Duration("cost", time.Since(createdAt)))
+	return manifest, nil
+}
+
+// backupLocal 生成本节点的快照并上传
+func (b *backupManager) backupLocal(ctx context.Context, backupId string) (*wkbackup.NodeManifest, error) {
+	b.localMu.Lock()
+	defer b.localMu.Unlock()
+
+	target, err := b.target()
+	if err != nil {
+		return nil, err
+	}
+
+	// 快照目录的结构与数据目录一致，恢复时直接下载到数据目录
+	snapshotDir := path.Join(b.s.opts.DataDir, "backup", backupId)
+	if err = os.RemoveAll(snapshotDir); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(snapshotDir); err != nil {
+			b.Warn("remove backup snapshot dir failed", zap.Error(err), zap.String("dir", snapshotDir))
+		}
+	}()
+
+	// 槽的已应用索引保存在分布式日志库内，必须先生成分布式日志库的快照，最后生成wkdb的快照
+	// 这样快照里的已应用索引只会落后于wkdb的数据，恢复后落后的日志会重新应用（槽日志的应用是幂等的），不会丢数据
+	if err = b.s.clusterServer.Checkpoint(path.Join(snapshotDir, "cluster")); err != nil {
+		return nil, err
+	}
+	if err = b.s.store.DB().Checkpoint(path.Join(snapshotDir, "db")); err != nil {
+		return nil, err
+	}
+	return wkbackup.UploadNode(ctx, target, backupId, b.s.opts.Cluster.NodeId, snapshotDir)
+}
+
+func (b *backupManager) requestBackup(ctx context.Context, nodeId uint64, backupId string) (*wkbackup.NodeManifest, error) {
+	resp, err := b.s.cluster.RequestWithContext(ctx, nodeId, "/wk/backup", []byte(backupId))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	nodeManifest := &wkbackup.NodeManifest{}
+	if err = wkutil.ReadJSONByByte(resp.Body, nodeManifest); err != nil {
+		return nil, err
+	}
+	return nodeManifest, nil
+}
+
+// handleBackupReq 处理发起备份的节点的快照请求
+func (b *backupManager) handleBackupReq(c *wkserver.Context) {
+	backupId := string(c.Body())
+	if backupId == "" {
+		c.WriteErr(errors.New("backupId is empty"))
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(b.s.ctx, b.s.opts.Backup.Timeout)
+	defer cancel()
+	nodeManifest, err := b.backupLocal(timeoutCtx, backupId)
+	if err != nil {
+		b.Error("handleBackupReq: backup local failed", zap.Error(err), zap.String("backupId", backupId))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(nodeManifest)))
+}
+
+// list 获取所有完整的备份
+func (b *backupManager) list(ctx context.Context) ([]*wkbackup.Manifest, error) {
+	target, err := b.target()
+	if err != nil {
+		return nil, err
+	}
+	return wkbackup.ListBackups(ctx, target)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkbackup"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackup(t *testing.T) {
+	// 服务停止后不会释放api等端口，使用单独的端口，避免影响其他测试
+	s := NewTestServer(t, WithDemoOn(false), WithWSAddr("ws://0.0.0.0:5261"), WithManagerAddr("0.0.0.0:5361"), WithAddr("tcp://0.0.0.0:5161"), WithHTTPAddr("0.0.0.0:5017"), WithClusterAddr("tcp://0.0.0.0:11127"), WithClusterServerAddr("0.0.0.0:11127"))
+	s.opts.Mode = TestMode
+	s.opts.Backup.Target = t.TempDir()
+	err := s.Start()
+	assert.Nil(t, err)
+	s.MustWaitAllSlotsReady()
+
+	tn := time.Now()
+	err = s.store.DB().AddUser(wkdb.User{Uid: "backupUser", CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+
+	manifest, err := s.backupManager.backup(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(manifest.Nodes))
+	assert.Equal(t, s.opts.Cluster.NodeId, manifest.Nodes[0].NodeId)
+
+	manifests, err := s.backupManager.list(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(manifests))
+	assert.Equal(t, manifest.BackupId, manifests[0].BackupId)
+
+	// 快照目录上传后会被删除
+	_, err = os.Stat(filepath.Join(s.opts.DataDir, "backup", manifest.BackupId))
+	assert.True(t, os.IsNotExist(err))
+
+	restoreRootDir := t.TempDir()
+	dataDir := filepath.Join(restoreRootDir, "data")
+	err = wkbackup.RestoreNode(context.Background(), wkbackup.NewLocalTarget(s.opts.Backup.Target), manifests[0], s.opts.Cluster.NodeId, dataDir)
+	assert.NoError(t, err)
+	for _, p := range []string{"db/wukongimdb/shard000", "cluster/logdb/shard000", "cluster/config/cfglogdb", "cluster/config/remote.json"} {
+		_, err = os.Stat(filepath.Join(dataDir, p))
+		assert.NoError(t, err, p)
+	}
+
+	// 用恢复的数据启动节点
+	s.StopNoErr()
+	restored := NewTestServer(t, WithRootDir(restoreRootDir), WithDemoOn(false), WithWSAddr("ws://0.0.0.0:5260"), WithManagerAddr("0.0.0.0:5360"), WithAddr("tcp://0.0.0.0:5160"), WithHTTPAddr("0.0.0.0:5016"), WithClusterAddr("tcp://0.0.0.0:11126"), WithClusterServerAddr("0.0.0.0:11126"))
+	restored.opts.Mode = TestMode
+	err = restored.Start()
+	assert.Nil(t, err)
+	defer restored.StopNoErr()
+	restored.MustWaitAllSlotsReady()
+
+	u, err := restored.store.DB().GetUser("backupUser")
+	assert.NoError(t, err)
+	assert.Equal(t, "backupUser", u.Uid)
+}
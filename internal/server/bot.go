@@ -0,0 +1,655 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	botEventMessage = "bot.message" // 推送给机器人的消息事件
+
+	botHeaderTimestamp = "X-WuKongIM-Timestamp" // 推送的时间戳（unix秒）
+	botHeaderSignature = "X-WuKongIM-Signature" // 推送的签名 sha256=hex(hmac_sha256(secret, timestamp + "." + body))
+
+	// 机器人的触发方式（推送事件里的trigger）
+	botTriggerNameChannel = "channel" // 绑定的频道
+	botTriggerNameDirect  = "direct"  // 单聊
+	botTriggerNameMention = "mention" // @机器人
+	botTriggerNameCommand = "command" // 命令前缀
+)
+
+// botManager 机器人管理
+// 频道领导节点存储消息后，将匹配机器人的消息签名后推送到机器人的回调地址，回调的响应里的回复消息以机器人的身份发送到原会话
+// 匹配在共享的协程里执行，推送放入每个机器人自己的队列按顺序执行，回调慢的机器人只会堆积自己的队列，不影响其他机器人
+// 机器人数据全局存放在槽0上，每个节点缓存所有的机器人
+type botManager struct {
+	s *Server
+	wklog.Log
+	stopper    *syncutil.Stopper
+	messageAPI *MessageAPI
+	httpClient *http.Client
+	msgC       chan botMessages
+
+	mu     sync.RWMutex
+	bots   map[string]*botEntry // 缓存的机器人，key为机器人uid
+	loaded atomic.Bool
+}
+
+type botEntry struct {
+	bot      wkdb.Bot
+	limiter  *rate.Limiter    // 机器人的推送速率限制
+	deliverC chan botDelivery // 机器人的推送队列
+	stopC    chan struct{}    // 机器人删除或更新后关闭，停止推送
+}
+
+// botDelivery 待推送给机器人的消息
+type botDelivery struct {
+	trigger     string
+	channelId   string
+	channelType uint8
+	msg         wkdb.Message
+}
+
+// botMessages 频道内存储成功的消息（channelId为存储的频道ID，个人频道为两个uid组合的频道ID）
+type botMessages struct {
+	channelId   string
+	channelType uint8
+	messages    []wkdb.Message
+}
+
+func newBotManager(s *Server) *botManager {
+	return &botManager{
+		s:          s,
+		Log:        wklog.NewWKLog("botManager"),
+		stopper:    syncutil.NewStopper(),
+		messageAPI: NewMessageAPI(s),
+		msgC:       make(chan botMessages, 1024),
+		bots:       make(map[string]*botEntry),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConns:        200,
+				MaxIdleConnsPerHost: 20,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 5 * time.Second,
+			},
+		},
+	}
+}
+
+func (b *botManager) start() error {
+	workerCount := b.s.opts.Bot.WorkerCount
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+	for i := 0; i < workerCount; i++ {
+		b.stopper.RunWorker(b.loop)
+	}
+	return nil
+}
+
+func (b *botManager) stop() {
+	b.stopper.Stop()
+}
+
+// addMessages 添加频道内存储成功的消息，队列满时丢弃（不能阻塞消息存储）
+func (b *botManager) addMessages(channelId string, channelType uint8, messages []wkdb.Message) {
+	if len(messages) == 0 {
+		return
+	}
+	if b.loaded.Load() && b.botCount() == 0 { // 没有机器人
+		return
+	}
+	select {
+	case b.msgC <- botMessages{channelId: channelId, channelType: channelType, messages: messages}:
+	default:
+		b.Warn("bot message queue is full, discard", zap.String("channelId", channelId), zap.Int("msgCount", len(messages)))
+	}
+}
+
+func (b *botManager) loop() {
+	for {
+		select {
+		case msgs := <-b.msgC:
+			b.handleMessages(msgs)
+		case <-b.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+func (b *botManager) handleMessages(msgs botMessages) {
+	if err := b.LoadIfNeed(); err != nil {
+		b.Warn("load bots failed", zap.Error(err))
+		return
+	}
+	for _, msg := range msgs.messages {
+		if msg.SyncOnce || b.isBot(msg.FromUID) || b.s.systemUIDManager.SystemUID(msg.FromUID) { // 机器人和系统账号发送的消息不推送，避免循环
+			continue
+		}
+		mention := parseMessageMention(msg.Payload)
+		content := messageContent(msg.Payload)
+		for _, entry := range b.entries() {
+			trigger := b.match(entry.bot, msgs.channelId, msgs.channelType, msg, mention, content)
+			if trigger == "" {
+				continue
+			}
+			if !entry.limiter.Allow() {
+				b.Warn("bot rate limit exceeded, discard", zap.String("botUid", entry.bot.Uid), zap.String("channelId", msgs.channelId), zap.Int64("messageId", msg.MessageID))
+				continue
+			}
+			select {
+			case entry.deliverC <- botDelivery{trigger: trigger, channelId: msgs.channelId, channelType: msgs.channelType, msg: msg}:
+			default:
+				b.Warn("bot deliver queue is full, discard", zap.String("botUid", entry.bot.Uid), zap.String("channelId", msgs.channelId), zap.Int64("messageId", msg.MessageID))
+			}
+		}
+	}
+}
+
+// newBotEntry 创建机器人的缓存并启动机器人的推送协程
+func (b *botManager) newBotEntry(bot wkdb.Bot) *botEntry {
+	queueSize := b.s.opts.Bot.QueueSize
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+	entry := &botEntry{
+		bot:      bot,
+		limiter:  b.newLimiter(bot),
+		deliverC: make(chan botDelivery, queueSize),
+		stopC:    make(chan struct{}),
+	}
+	b.stopper.RunWorker(func() {
+		b.deliverLoop(entry)
+	})
+	return entry
+}
+
+// deliverLoop 按顺序推送机器人队列里的消息
+func (b *botManager) deliverLoop(entry *botEntry) {
+	for {
+		select {
+		case d := <-entry.deliverC:
+			b.deliver(entry.bot, d.trigger, d.channelId, d.channelType, d.msg)
+		case <-entry.stopC:
+			return
+		case <-b.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// match 消息是否匹配机器人，返回触发方式，不匹配返回空
+func (b *botManager) match(bot wkdb.Bot, channelId string, channelType uint8, msg wkdb.Message, mention *messageMention, content string) string {
+	if msg.FromUID == bot.Uid {
+		return ""
+	}
+	direct := false
+	if channelType == wkproto.ChannelTypePerson {
+		uid1, uid2 := GetFromUIDAndToUIDWith(channelId)
+		direct = uid1 == bot.Uid || uid2 == bot.Uid
+	}
+	if !direct {
+		for _, channel := range bot.Channels {
+			if channel.ChannelId == channelId && channel.ChannelType == channelType {
+				return botTriggerNameChannel
+			}
+		}
+	}
+	if direct && bot.Triggers.Has(wkdb.BotTriggerDirect) {
+		return botTriggerNameDirect
+	}
+	if !direct && mention != nil && bot.Triggers.Has(wkdb.BotTriggerMention) {
+		for _, uid := range mention.UIDs { // 只匹配明确@机器人的消息，@所有人不推送
+			if uid == bot.Uid {
+				return botTriggerNameMention
+			}
+		}
+	}
+	if bot.CommandPrefix != "" && strings.HasPrefix(content, bot.CommandPrefix) {
+		if direct {
+			return botTriggerNameCommand
+		}
+		if channelType == wkproto.ChannelTypePerson { // 别人之间的单聊
+			return ""
+		}
+		// 频道内的命令只推送给频道内的机器人
+		exist, err := b.s.store.ExistSubscriber(channelId, channelType, bot.Uid)
+		if err != nil {
+			b.Warn("check bot subscriber failed", zap.Error(err), zap.String("botUid", bot.Uid), zap.String("channelId", channelId))
+			return ""
+		}
+		if exist {
+			return botTriggerNameCommand
+		}
+	}
+	return ""
+}
+
+// deliver 推送消息给机器人，并发送回调响应里的回复消息
+func (b *botManager) deliver(bot wkdb.Bot, trigger string, channelId string, channelType uint8, msg wkdb.Message) {
+	messageResp := &MessageResp{}
+	messageResp.from(msg, b.s)
+	if channelType == wkproto.ChannelTypePerson { // 机器人看到的单聊频道是发送者
+		messageResp.ChannelID = msg.FromUID
+	}
+	body := []byte(wkutil.ToJSON(map[string]interface{}{
+		"event":   botEventMessage,
+		"bot_uid": bot.Uid,
+		"trigger": trigger,
+		"message": messageResp,
+	}))
+
+	timeoutCtx, cancel := context.WithTimeout(b.s.ctx, b.timeout(bot))
+	defer cancel()
+	req, err := http.NewRequestWithContext(timeoutCtx, http.MethodPost, bot.CallbackUrl, bytes.NewReader(body))
+	if err != nil {
+		b.Warn("new bot request failed", zap.Error(err), zap.String("botUid", bot.Uid))
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(botHeaderTimestamp, timestamp)
+	req.Header.Set(botHeaderSignature, botSignature(bot.Secret, timestamp, body))
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		b.Warn("post bot message failed", zap.Error(err), zap.String("botUid", bot.Uid), zap.String("callbackUrl", bot.CallbackUrl))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b.Warn("bot callback status error", zap.Int("status", resp.StatusCode), zap.String("botUid", bot.Uid))
+		return
+	}
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		b.Warn("read bot response failed", zap.Error(err), zap.String("botUid", bot.Uid))
+		return
+	}
+	replies, err := parseBotReplies(respBody, b.s.opts.Bot.MaxReplies)
+	if err != nil {
+		b.Warn("parse bot replies failed", zap.Error(err), zap.String("botUid", bot.Uid))
+		return
+	}
+	if len(replies) == 0 {
+		return
+	}
+
+	replyChannelId := channelId
+	if channelType == wkproto.ChannelTypePerson {
+		replyChannelId = msg.FromUID
+	}
+	for _, reply := range replies {
+		clientMsgNo := reply.ClientMsgNo
+		if clientMsgNo == "" {
+			clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+		}
+		_, err = b.messageAPI.sendMessageToChannel(MessageSendReq{
+			Header:      reply.Header,
+			ClientMsgNo: clientMsgNo,
+			FromUID:     bot.Uid,
+			ChannelID:   replyChannelId,
+			ChannelType: channelType,
+			Payload:     reply.Payload,
+		}, replyChannelId, channelType, clientMsgNo, wkproto.StreamFlagIng)
+		if err != nil {
+			b.Warn("send bot reply failed", zap.Error(err), zap.String("botUid", bot.Uid), zap.String("channelId", replyChannelId))
+		}
+	}
+}
+
+func (b *botManager) timeout(bot wkdb.Bot) time.Duration {
+	timeout := bot.Timeout
+	if timeout <= 0 {
+		timeout = b.s.opts.Bot.DefaultTimeout
+	}
+	if b.s.opts.Bot.MaxTimeout > 0 && timeout > b.s.opts.Bot.MaxTimeout {
+		timeout = b.s.opts.Bot.MaxTimeout
+	}
+	return timeout
+}
+
+func (b *botManager) newLimiter(bot wkdb.Bot) *rate.Limiter {
+	limit := int(bot.RateLimit)
+	if limit <= 0 {
+		limit = b.s.opts.Bot.DefaultRateLimit
+	}
+	if limit <= 0 {
+		return rate.NewLimiter(rate.Inf, 1)
+	}
+	return rate.NewLimiter(rate.Limit(limit), limit)
+}
+
+// LoadIfNeed 从槽0的领导节点加载机器人
+func (b *botManager) LoadIfNeed() error {
+	if b.loaded.Load() {
+		return nil
+	}
+	bots, err := b.getOrRequestBots()
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.loaded.Load() {
+		return nil
+	}
+	for _, bot := range bots {
+		b.bots[bot.Uid] = b.newBotEntry(bot)
+	}
+	b.loaded.Store(true)
+	return nil
+}
+
+// AddOrUpdateBot 保存机器人并添加到缓存
+func (b *botManager) AddOrUpdateBot(bot wkdb.Bot) error {
+	err := b.s.store.AddOrUpdateBot(bot)
+	if err != nil {
+		return err
+	}
+	b.AddBotToCache(bot)
+	return nil
+}
+
+// AddBotToCache 添加机器人到缓存，机器人已存在时替换（旧的推送队列里未推送的消息丢弃）
+func (b *botManager) AddBotToCache(bot wkdb.Bot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if old := b.bots[bot.Uid]; old != nil {
+		close(old.stopC)
+	}
+	b.bots[bot.Uid] = b.newBotEntry(bot)
+}
+
+// RemoveBot 删除机器人并从缓存中移除
+func (b *botManager) RemoveBot(uid string) error {
+	err := b.s.store.RemoveBot(uid)
+	if err != nil {
+		return err
+	}
+	b.RemoveBotFromCache(uid)
+	return nil
+}
+
+func (b *botManager) RemoveBotFromCache(uid string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if entry := b.bots[uid]; entry != nil {
+		close(entry.stopC)
+	}
+	delete(b.bots, uid)
+}
+
+func (b *botManager) isBot(uid string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.bots[uid]
+	return ok
+}
+
+func (b *botManager) botCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.bots)
+}
+
+func (b *botManager) entries() []*botEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	entries := make([]*botEntry, 0, len(b.bots))
+	for _, entry := range b.bots {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (b *botManager) getOrRequestBots() ([]wkdb.Bot, error) {
+	nodeInfo, err := b.s.cluster.SlotLeaderNodeInfo(botSlotId)
+	if err != nil {
+		return nil, err
+	}
+	if nodeInfo.Id == b.s.opts.Cluster.NodeId {
+		return b.s.store.GetBots()
+	}
+	return b.requestBots(nodeInfo)
+}
+
+func (b *botManager) requestBots(nodeInfo *pb.Node) ([]wkdb.Bot, error) {
+	resp, err := network.Get(fmt.Sprintf("%s%s", nodeInfo.ApiServerAddr, "/bot/list"), nil, managerTokenHeader(b.s.opts))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requestBots error: %s", resp.Body)
+	}
+	var resps []*botResp
+	err = wkutil.ReadJSONByByte([]byte(resp.Body), &resps)
+	if err != nil {
+		return nil, err
+	}
+	bots := make([]wkdb.Bot, 0, len(resps))
+	for _, r := range resps {
+		bots = append(bots, r.toBot())
+	}
+	return bots, nil
+}
+
+// managerTokenHeader 节点之间请求api时带上管理者的token
+func managerTokenHeader(opts *Options) map[string]string {
+	if strings.TrimSpace(opts.ManagerToken) == "" {
+		return nil
+	}
+	return map[string]string{
+		"token": opts.ManagerToken,
+	}
+}
+
+// botSignature 推送的签名，机器人用相同的方式计算后比较
+func botSignature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// messageContent 获取json格式的payload的content字段
+func messageContent(payload []byte) string {
+	if len(payload) == 0 || payload[0] != '{' {
+		return ""
+	}
+	var content struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(payload, &content); err != nil {
+		return ""
+	}
+	return content.Content
+}
+
+// botReply 机器人回调的响应里的回复消息
+type botReply struct {
+	Header      MessageHeader `json:"header"`
+	ClientMsgNo string        `json:"client_msg_no"`
+	Payload     []byte        `json:"payload"`
+}
+
+// parseBotReplies 解析机器人回调的响应，格式为 {"replies":[{"payload":"base64"}]}，响应为空时没有回复
+func parseBotReplies(data []byte, maxReplies int) ([]botReply, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var resp struct {
+		Replies []botReply `json:"replies"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	replies := make([]botReply, 0, len(resp.Replies))
+	for _, reply := range resp.Replies {
+		if len(reply.Payload) == 0 {
+			continue
+		}
+		replies = append(replies, reply)
+	}
+	if maxReplies > 0 && len(replies) > maxReplies {
+		replies = replies[:maxReplies]
+	}
+	return replies, nil
+}
+
+// botRegisterReq 注册机器人请求
+type botRegisterReq struct {
+	Uid           string            `json:"uid"`            // 机器人的uid
+	CallbackUrl   string            `json:"callback_url"`   // 推送消息的回调地址
+	Secret        string            `json:"secret"`         // 签名密钥，为空时保留原密钥或自动生成
+	Channels      []wkdb.BotChannel `json:"channels"`       // 绑定的频道，频道内的所有消息都推送
+	Triggers      []string          `json:"triggers"`       // 触发方式：direct（单聊）、mention（@机器人）
+	CommandPrefix string            `json:"command_prefix"` // 命令前缀，单聊或机器人所在频道内的消息内容以此开头时推送
+	RateLimit     uint32            `json:"rate_limit"`     // 每秒最多推送的消息数量，0为默认配置
+	TimeoutMs     int64             `json:"timeout_ms"`     // 回调超时时间（毫秒），0为默认配置
+}
+
+func (r botRegisterReq) check(opts *Options) error {
+	if strings.TrimSpace(r.Uid) == "" {
+		return errors.New("uid不能为空！")
+	}
+	if !strings.HasPrefix(r.CallbackUrl, "http://") && !strings.HasPrefix(r.CallbackUrl, "https://") {
+		return errors.New("callback_url必须是http或https地址！")
+	}
+	if _, err := parseBotTriggers(r.Triggers); err != nil {
+		return err
+	}
+	if len(r.Channels) == 0 && len(r.Triggers) == 0 && r.CommandPrefix == "" {
+		return errors.New("channels、triggers和command_prefix不能都为空！")
+	}
+	for _, channel := range r.Channels {
+		if channel.ChannelId == "" || channel.ChannelType == 0 {
+			return errors.New("channels里的channel_id和channel_type不能为空！")
+		}
+		if channel.ChannelType == wkproto.ChannelTypePerson {
+			return errors.New("不能绑定个人频道，单聊请使用direct触发方式！")
+		}
+	}
+	if r.TimeoutMs < 0 {
+		return errors.New("timeout_ms不能小于0！")
+	}
+	if opts.Bot.MaxTimeout > 0 && time.Duration(r.TimeoutMs)*time.Millisecond > opts.Bot.MaxTimeout {
+		return fmt.Errorf("timeout_ms不能大于%d！", opts.Bot.MaxTimeout.Milliseconds())
+	}
+	return nil
+}
+
+func parseBotTriggers(triggers []string) (wkdb.BotTrigger, error) {
+	var botTriggers wkdb.BotTrigger
+	for _, trigger := range triggers {
+		switch trigger {
+		case botTriggerNameDirect:
+			botTriggers |= wkdb.BotTriggerDirect
+		case botTriggerNameMention:
+			botTriggers |= wkdb.BotTriggerMention
+		default:
+			return 0, fmt.Errorf("不支持的触发方式[%s]！", trigger)
+		}
+	}
+	return botTriggers, nil
+}
+
+func botTriggerNames(triggers wkdb.BotTrigger) []string {
+	names := make([]string, 0, 2)
+	if triggers.Has(wkdb.BotTriggerDirect) {
+		names = append(names, botTriggerNameDirect)
+	}
+	if triggers.Has(wkdb.BotTriggerMention) {
+		names = append(names, botTriggerNameMention)
+	}
+	return names
+}
+
+// botResp 机器人返回（节点之间同步缓存也使用此格式）
+type botResp struct {
+	Uid           string            `json:"uid"`
+	CallbackUrl   string            `json:"callback_url"`
+	Secret        string            `json:"secret"`
+	Channels      []wkdb.BotChannel `json:"channels"`
+	Triggers      []string          `json:"triggers"`
+	CommandPrefix string            `json:"command_prefix"`
+	RateLimit     uint32            `json:"rate_limit"`
+	TimeoutMs     int64             `json:"timeout_ms"`
+	CreatedAt     int64             `json:"created_at"` // 创建时间（unix秒）
+	UpdatedAt     int64             `json:"updated_at"` // 更新时间（unix秒）
+}
+
+func newBotResp(bot wkdb.Bot) *botResp {
+	resp := &botResp{
+		Uid:           bot.Uid,
+		CallbackUrl:   bot.CallbackUrl,
+		Secret:        bot.Secret,
+		Channels:      bot.Channels,
+		Triggers:      botTriggerNames(bot.Triggers),
+		CommandPrefix: bot.CommandPrefix,
+		RateLimit:     bot.RateLimit,
+		TimeoutMs:     bot.Timeout.Milliseconds(),
+	}
+	if resp.Channels == nil {
+		resp.Channels = make([]wkdb.BotChannel, 0)
+	}
+	if bot.CreatedAt != nil {
+		resp.CreatedAt = bot.CreatedAt.Unix()
+	}
+	if bot.UpdatedAt != nil {
+		resp.UpdatedAt = bot.UpdatedAt.Unix()
+	}
+	return resp
+}
+
+func (r *botResp) toBot() wkdb.Bot {
+	triggers, _ := parseBotTriggers(r.Triggers)
+	bot := wkdb.Bot{
+		Uid:           r.Uid,
+		CallbackUrl:   r.CallbackUrl,
+		Secret:        r.Secret,
+		Channels:      r.Channels,
+		Triggers:      triggers,
+		CommandPrefix: r.CommandPrefix,
+		RateLimit:     r.RateLimit,
+		Timeout:       time.Duration(r.TimeoutMs) * time.Millisecond,
+	}
+	if r.CreatedAt > 0 {
+		createdAt := time.Unix(r.CreatedAt, 0)
+		bot.CreatedAt = &createdAt
+	}
+	if r.UpdatedAt > 0 {
+		updatedAt := time.Unix(r.UpdatedAt, 0)
+		bot.UpdatedAt = &updatedAt
+	}
+	return bot
+}
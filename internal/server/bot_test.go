@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBotTestMessage(fromUid string, payload string) wkdb.Message {
+	return wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:  1,
+			MessageSeq: 1,
+			FromUID:    fromUid,
+			Timestamp:  int32(time.Now().Unix()),
+			Payload:    []byte(payload),
+		},
+	}
+}
+
+func TestBotMatch(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+	err = s.store.DB().AddSubscribers("g2", wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: "bot1"}})
+	assert.NoError(t, err)
+
+	b := s.botManager
+	bot := wkdb.Bot{
+		Uid:           "bot1",
+		Channels:      []wkdb.BotChannel{{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup}},
+		Triggers:      wkdb.BotTriggerDirect | wkdb.BotTriggerMention,
+		CommandPrefix: "/",
+	}
+	match := func(channelId string, channelType uint8, msg wkdb.Message) string {
+		return b.match(bot, channelId, channelType, msg, parseMessageMention(msg.Payload), messageContent(msg.Payload))
+	}
+
+	// 绑定的频道
+	assert.Equal(t, botTriggerNameChannel, match("g1", wkproto.ChannelTypeGroup, newBotTestMessage("u1", `{"content":"hello"}`)))
+	// 单聊
+	assert.Equal(t, botTriggerNameDirect, match(GetFakeChannelIDWith("u1", "bot1"), wkproto.ChannelTypePerson, newBotTestMessage("u1", `{"content":"hello"}`)))
+	assert.Equal(t, "", match(GetFakeChannelIDWith("u1", "u2"), wkproto.ChannelTypePerson, newBotTestMessage("u1", `{"content":"/help"}`)))
+	// @机器人，@所有人不推送
+	assert.Equal(t, botTriggerNameMention, match("g3", wkproto.ChannelTypeGroup, newBotTestMessage("u1", `{"content":"@bot1","mention":{"uids":["bot1"]}}`)))
+	assert.Equal(t, "", match("g3", wkproto.ChannelTypeGroup, newBotTestMessage("u1", `{"content":"@all","mention":{"all":1}}`)))
+	// 命令只推送机器人所在的频道
+	assert.Equal(t, botTriggerNameCommand, match("g2", wkproto.ChannelTypeGroup, newBotTestMessage("u1", `{"content":"/help"}`)))
+	assert.Equal(t, "", match("g3", wkproto.ChannelTypeGroup, newBotTestMessage("u1", `{"content":"/help"}`)))
+	assert.Equal(t, "", match("g2", wkproto.ChannelTypeGroup, newBotTestMessage("u1", `{"content":"help"}`)))
+	// 机器人自己发送的消息
+	assert.Equal(t, "", match("g1", wkproto.ChannelTypeGroup, newBotTestMessage("bot1", `{"content":"hello"}`)))
+
+	bot.Triggers = 0
+	assert.Equal(t, "", match(GetFakeChannelIDWith("u1", "bot1"), wkproto.ChannelTypePerson, newBotTestMessage("u1", `{"content":"hello"}`)))
+	assert.Equal(t, botTriggerNameCommand, match(GetFakeChannelIDWith("u1", "bot1"), wkproto.ChannelTypePerson, newBotTestMessage("u1", `{"content":"/help"}`)))
+}
+
+func TestBotDeliver(t *testing.T) {
+	s := NewTestServer(t)
+
+	var (
+		body      []byte
+		timestamp string
+		signature string
+	)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		timestamp = r.Header.Get(botHeaderTimestamp)
+		signature = r.Header.Get(botHeaderSignature)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer callback.Close()
+
+	bot := wkdb.Bot{Uid: "bot1", CallbackUrl: callback.URL, Secret: "secret"}
+	s.botManager.deliver(bot, botTriggerNameDirect, GetFakeChannelIDWith("u1", "bot1"), wkproto.ChannelTypePerson, newBotTestMessage("u1", `{"content":"hello"}`))
+
+	assert.NotEmpty(t, timestamp)
+	assert.Equal(t, botSignature("secret", timestamp, body), signature)
+
+	var event struct {
+		Event   string      `json:"event"`
+		BotUid  string      `json:"bot_uid"`
+		Trigger string      `json:"trigger"`
+		Message MessageResp `json:"message"`
+	}
+	err := json.Unmarshal(body, &event)
+	assert.NoError(t, err)
+	assert.Equal(t, botEventMessage, event.Event)
+	assert.Equal(t, "bot1", event.BotUid)
+	assert.Equal(t, botTriggerNameDirect, event.Trigger)
+	assert.Equal(t, "u1", event.Message.ChannelID)
+	assert.Equal(t, "u1", event.Message.FromUID)
+}
+
+func TestBotDeliverQueue(t *testing.T) {
+	s := NewTestServer(t)
+	b := s.botManager
+	b.loaded.Store(true)
+	defer b.stop()
+
+	release := make(chan struct{})
+	slowCallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slowCallback.Close()
+	defer close(release)
+
+	delivered := make(chan struct{}, 10)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer callback.Close()
+
+	channels := []wkdb.BotChannel{{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup}}
+	b.AddBotToCache(wkdb.Bot{Uid: "slowBot", CallbackUrl: slowCallback.URL, Channels: channels, Timeout: time.Second * 30})
+	b.AddBotToCache(wkdb.Bot{Uid: "bot2", CallbackUrl: callback.URL, Channels: channels})
+
+	// 回调慢的机器人不影响其他机器人的推送
+	for i := 0; i < 3; i++ {
+		b.handleMessages(botMessages{channelId: "g1", channelType: wkproto.ChannelTypeGroup, messages: []wkdb.Message{newBotTestMessage("u1", `{"content":"hello"}`)}})
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-delivered:
+		case <-time.After(time.Second * 3):
+			t.Fatal("bot2 message not delivered")
+		}
+	}
+}
+
+func TestParseBotReplies(t *testing.T) {
+	replies, err := parseBotReplies(nil, 10)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 0)
+
+	replies, err = parseBotReplies([]byte(`{"replies":[{"payload":"aGVsbG8="},{"payload":""},{"payload":"d29ybGQ=","client_msg_no":"c1"}]}`), 10)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 2)
+	assert.Equal(t, []byte("hello"), replies[0].Payload)
+	assert.Equal(t, "c1", replies[1].ClientMsgNo)
+
+	replies, err = parseBotReplies([]byte(`{"replies":[{"payload":"aGVsbG8="},{"payload":"d29ybGQ="}]}`), 1)
+	assert.NoError(t, err)
+	assert.Len(t, replies, 1)
+
+	_, err = parseBotReplies([]byte(`not json`), 10)
+	assert.Error(t, err)
+}
+
+func TestBotRegisterReqCheck(t *testing.T) {
+	opts := NewOptions()
+	req := botRegisterReq{
+		Uid:         "bot1",
+		CallbackUrl: "http://127.0.0.1/bot",
+		Triggers:    []string{botTriggerNameDirect},
+	}
+	assert.NoError(t, req.check(opts))
+
+	req.Triggers = []string{"unknown"}
+	assert.Error(t, req.check(opts))
+	req.Triggers = nil
+	assert.Error(t, req.check(opts))
+	req.CommandPrefix = "/"
+	assert.NoError(t, req.check(opts))
+	req.CallbackUrl = "127.0.0.1/bot"
+	assert.Error(t, req.check(opts))
+	req.CallbackUrl = "http://127.0.0.1/bot"
+	req.TimeoutMs = opts.Bot.MaxTimeout.Milliseconds() + 1
+	assert.Error(t, req.check(opts))
+	req.TimeoutMs = 0
+	req.Channels = []wkdb.BotChannel{{ChannelId: "u2", ChannelType: wkproto.ChannelTypePerson}}
+	assert.Error(t, req.check(opts))
+}
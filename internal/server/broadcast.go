@@ -0,0 +1,436 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// broadcastManager 广播管理
+// 一个广播按槽拆分为多个任务（wkdb.BroadcastTask），任务和进度保存在对应的槽内，
+// 每个节点为每个广播启动一个worker，负责本节点作为领导的槽的任务，每批处理完成后保存进度，
+// 节点重启或槽领导切换后由新的领导节点从进度处继续发送
+type broadcastManager struct {
+	s *Server
+	wklog.Log
+
+	messageAPI *MessageAPI
+
+	mu      sync.Mutex
+	workers map[uint64]struct{} // 正在执行的广播
+
+	scanner *intervalScanner
+}
+
+func newBroadcastManager(s *Server) *broadcastManager {
+	b := &broadcastManager{
+		s:          s,
+		Log:        wklog.NewWKLog("broadcastManager"),
+		messageAPI: NewMessageAPI(s),
+		workers:    make(map[uint64]struct{}),
+	}
+	b.scanner = newIntervalScanner(s.opts.Broadcast.ScanInterval, b.scan)
+	return b
+}
+
+func (b *broadcastManager) start() error {
+	if b.s.opts.ClusterOn() {
+		b.scanner.start()
+	}
+	return nil
+}
+
+func (b *broadcastManager) stop() {
+	b.scanner.stop()
+}
+
+// scan 为本节点需要执行的广播启动worker
+func (b *broadcastManager) scan() {
+	tasks, err := b.s.store.GetBroadcastTasks(0)
+	if err != nil {
+		b.Error("get broadcast tasks failed", zap.Error(err))
+		return
+	}
+	leaderSlots := b.leaderSlots()
+	for _, t := range tasks {
+		if t.Status != wkdb.BroadcastStatusRunning {
+			continue
+		}
+		if _, ok := leaderSlots[t.SlotId]; !ok {
+			continue
+		}
+		b.mu.Lock()
+		_, running := b.workers[t.JobId]
+		if !running {
+			b.workers[t.JobId] = struct{}{}
+		}
+		b.mu.Unlock()
+		if !running {
+			go b.runWorker(t.JobId)
+		}
+	}
+}
+
+// leaderSlots 本节点作为领导的槽
+func (b *broadcastManager) leaderSlots() map[uint32]struct{} {
+	slots := make(map[uint32]struct{})
+	for _, slot := range b.s.clusterServer.GetConfig().Slots {
+		if slot.Leader == b.s.opts.Cluster.NodeId {
+			slots[slot.Id] = struct{}{}
+		}
+	}
+	return slots
+}
+
+// localTasks 本节点作为领导的槽内的广播任务
+func (b *broadcastManager) localTasks(jobId uint64) ([]wkdb.BroadcastTask, error) {
+	tasks, err := b.s.store.GetBroadcastTasks(jobId)
+	if err != nil {
+		return nil, err
+	}
+	leaderSlots := b.leaderSlots()
+	localTasks := make([]wkdb.BroadcastTask, 0, len(tasks))
+	for _, t := range tasks {
+		if _, ok := leaderSlots[t.SlotId]; ok {
+			localTasks = append(localTasks, t)
+		}
+	}
+	return localTasks, nil
+}
+
+func (b *broadcastManager) runWorker(jobId uint64) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.workers, jobId)
+		b.mu.Unlock()
+	}()
+
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	for {
+		select {
+		case <-b.scanner.stopped():
+			return
+		default:
+		}
+
+		// 每批都重新获取任务，槽领导变化或广播被取消后及时停止
+		localTasks, err := b.localTasks(jobId)
+		if err != nil {
+			b.Error("get broadcast tasks failed", zap.Error(err), zap.Uint64("jobId", jobId))
+			return
+		}
+		var (
+			uidTasks  []wkdb.BroadcastTask
+			scanTasks = make(map[uint32]*wkdb.BroadcastTask)
+			limit     float64
+		)
+		for i, t := range localTasks {
+			if t.Status != wkdb.BroadcastStatusRunning {
+				continue
+			}
+			limit += float64(t.Rate)
+			if len(t.Uids) > 0 {
+				uidTasks = append(uidTasks, t)
+			} else {
+				scanTasks[t.SlotId] = &localTasks[i]
+			}
+		}
+		if len(uidTasks) == 0 && len(scanTasks) == 0 {
+			return
+		}
+		if limit > 0 {
+			limiter.SetLimit(rate.Limit(limit))
+		}
+
+		for _, t := range uidTasks {
+			if !b.processUidTask(t, limiter) {
+				return
+			}
+		}
+		if len(scanTasks) > 0 {
+			if !b.processScanTasks(jobId, scanTasks, limiter) {
+				return
+			}
+		}
+	}
+}
+
+// processUidTask 发送一批指定的用户，返回false表示需要停止
+func (b *broadcastManager) processUidTask(t wkdb.BroadcastTask, limiter *rate.Limiter) bool {
+	end := t.Cursor + uint64(b.s.opts.Broadcast.BatchSize)
+	if end > uint64(len(t.Uids)) {
+		end = uint64(len(t.Uids))
+	}
+	for _, uid := range t.Uids[t.Cursor:end] {
+		if !b.send(&t, uid, limiter) {
+			return false
+		}
+	}
+	t.Cursor = end
+	if t.Cursor >= uint64(len(t.Uids)) {
+		t.Status = wkdb.BroadcastStatusDone
+	}
+	return b.saveProgress(t)
+}
+
+// processScanTasks 按用户主键顺序遍历一批用户，发送给属于这些槽并且满足条件的用户，返回false表示需要停止
+func (b *broadcastManager) processScanTasks(jobId uint64, tasks map[uint32]*wkdb.BroadcastTask, limiter *rate.Limiter) bool {
+	var cursor uint64 = 0
+	first := true
+	for _, t := range tasks {
+		if first || t.Cursor < cursor {
+			cursor = t.Cursor
+			first = false
+		}
+	}
+	users, err := b.s.store.GetUsersAfter(cursor, b.s.opts.Broadcast.BatchSize)
+	if err != nil {
+		b.Error("get users failed", zap.Error(err), zap.Uint64("jobId", jobId))
+		return false
+	}
+	for _, u := range users {
+		t := tasks[b.s.getSlotId(u.Uid)]
+		if t == nil || u.Id <= t.Cursor {
+			continue
+		}
+		if !b.matchUser(t, u) {
+			continue
+		}
+		if !b.send(t, u.Uid, limiter) {
+			return false
+		}
+	}
+
+	slotIds := make([]uint32, 0, len(tasks))
+	for slotId := range tasks {
+		slotIds = append(slotIds, slotId)
+	}
+	sort.Slice(slotIds, func(i, j int) bool {
+		return slotIds[i] < slotIds[j]
+	})
+	for _, slotId := range slotIds {
+		t := tasks[slotId]
+		if len(users) == 0 {
+			t.Status = wkdb.BroadcastStatusDone
+		} else if lastId := users[len(users)-1].Id; lastId > t.Cursor {
+			t.Cursor = lastId
+		}
+		if !b.saveProgress(*t) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchUser 用户是否满足广播的筛选条件
+func (b *broadcastManager) matchUser(t *wkdb.BroadcastTask, u wkdb.User) bool {
+	if t.UserCreatedGte > 0 || t.UserCreatedLt > 0 {
+		if u.CreatedAt == nil {
+			return false
+		}
+		createdAt := u.CreatedAt.Unix()
+		if t.UserCreatedGte > 0 && createdAt < t.UserCreatedGte {
+			return false
+		}
+		if t.UserCreatedLt > 0 && createdAt >= t.UserCreatedLt {
+			return false
+		}
+	}
+	if t.DeviceFlags == 0 {
+		return true
+	}
+	for flag := 0; flag < 8; flag++ {
+		if t.DeviceFlags&(1<<flag) == 0 {
+			continue
+		}
+		device, err := b.s.store.GetDevice(u.Uid, wkproto.DeviceFlag(flag))
+		if err != nil {
+			if err != wkdb.ErrNotFound {
+				b.Warn("get device failed", zap.Error(err), zap.String("uid", u.Uid))
+			}
+			continue
+		}
+		if !wkdb.IsEmptyDevice(device) {
+			return true
+		}
+	}
+	return false
+}
+
+// send 以系统账号给用户发送广播消息，返回false表示需要停止
+func (b *broadcastManager) send(t *wkdb.BroadcastTask, uid string, limiter *rate.Limiter) bool {
+	// 服务停止时取消等待
+	if err := limiter.Wait(b.s.ctx); err != nil {
+		return false
+	}
+
+	req := MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: wkutil.BoolToInt(t.NoPersist),
+			RedDot:    wkutil.BoolToInt(t.RedDot),
+		},
+		FromUID:     t.FromUid,
+		ChannelID:   uid,
+		ChannelType: wkproto.ChannelTypePerson,
+		Payload:     t.Payload,
+	}
+	// clientMsgNo固定，切换领导后重复发送的消息客户端可以去重
+	_, err := b.messageAPI.sendMessageToChannel(req, uid, wkproto.ChannelTypePerson, broadcastClientMsgNo(t.JobId), wkproto.StreamFlagIng)
+	if err != nil {
+		b.Debug("send broadcast message failed", zap.Error(err), zap.Uint64("jobId", t.JobId), zap.String("uid", uid))
+		t.FailedCount++
+	} else {
+		t.SentCount++
+	}
+	return true
+}
+
+// saveProgress 保存任务进度，返回false表示需要停止
+func (b *broadcastManager) saveProgress(t wkdb.BroadcastTask) bool {
+	updatedAt := time.Now()
+	t.UpdatedAt = &updatedAt
+	if err := b.s.store.UpdateBroadcastTaskProgress(t); err != nil {
+		b.Error("save broadcast progress failed", zap.Error(err), zap.Uint64("jobId", t.JobId), zap.Uint32("slotId", t.SlotId))
+		return false
+	}
+	return true
+}
+
+func broadcastClientMsgNo(jobId uint64) string {
+	return fmt.Sprintf("broadcast_%d", jobId)
+}
+
+// create 创建广播，按槽拆分为任务并保存到各个槽
+func (b *broadcastManager) create(req broadcastReq) (uint64, error) {
+	jobId := b.s.store.NextPrimaryKey()
+	createdAt := time.Now()
+	newTask := func(slotId uint32, uids []string) wkdb.BroadcastTask {
+		return wkdb.BroadcastTask{
+			JobId:          jobId,
+			SlotId:         slotId,
+			Status:         wkdb.BroadcastStatusRunning,
+			FromUid:        b.s.opts.SystemUID,
+			NoPersist:      wkutil.IntToBool(req.Header.NoPersist),
+			RedDot:         wkutil.IntToBool(req.Header.RedDot),
+			Payload:        req.Payload,
+			Uids:           uids,
+			DeviceFlags:    req.deviceFlagMask(),
+			UserCreatedGte: req.CreatedAtStart,
+			UserCreatedLt:  req.CreatedAtEnd,
+			CreatedAt:      &createdAt,
+			UpdatedAt:      &createdAt,
+		}
+	}
+
+	var tasks []wkdb.BroadcastTask
+	if len(req.Uids) > 0 {
+		slotUids := make(map[uint32][]string)
+		for _, uid := range req.Uids {
+			slotId := b.s.getSlotId(uid)
+			slotUids[slotId] = append(slotUids[slotId], uid)
+		}
+		for slotId, uids := range slotUids {
+			tasks = append(tasks, newTask(slotId, uids))
+		}
+	} else {
+		for _, slot := range b.s.clusterServer.GetConfig().Slots {
+			tasks = append(tasks, newTask(slot.Id, nil))
+		}
+	}
+	if len(tasks) == 0 {
+		return 0, errors.New("no slot")
+	}
+
+	// 发送速率平分到各个槽
+	total := req.Rate
+	if total <= 0 {
+		total = b.s.opts.GetBroadcastDefaultRate()
+	}
+	slotRate := uint32((total + len(tasks) - 1) / len(tasks))
+	if slotRate == 0 {
+		slotRate = 1
+	}
+	for _, t := range tasks {
+		t.Rate = slotRate
+		if err := b.s.store.AddBroadcastTask(t); err != nil {
+			b.Error("add broadcast task failed", zap.Error(err), zap.Uint64("jobId", jobId), zap.Uint32("slotId", t.SlotId))
+			return 0, err
+		}
+	}
+	return jobId, nil
+}
+
+// clusterTasks 获取集群内广播的所有任务（由各个槽的领导节点返回）
+func (b *broadcastManager) clusterTasks(jobId uint64) ([]wkdb.BroadcastTask, error) {
+	tasks, err := b.localTasks(jobId)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range b.s.clusterServer.GetConfig().Nodes {
+		if node.Id == b.s.opts.Cluster.NodeId || !node.Online {
+			continue
+		}
+		nodeTasks, err := b.requestTasks(node.Id, jobId)
+		if err != nil {
+			b.Warn("request broadcast tasks failed", zap.Error(err), zap.Uint64("nodeId", node.Id))
+			continue
+		}
+		tasks = append(tasks, nodeTasks...)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].SlotId < tasks[j].SlotId
+	})
+	return tasks, nil
+}
+
+func (b *broadcastManager) requestTasks(nodeId uint64, jobId uint64) ([]wkdb.BroadcastTask, error) {
+	timeoutCtx, cancel := context.WithTimeout(b.s.ctx, b.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := b.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/broadcastTasks", []byte(wkutil.ToJSON(map[string]interface{}{
+		"job_id": jobId,
+	})))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var tasks []wkdb.BroadcastTask
+	if err = wkutil.ReadJSONByByte(resp.Body, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// handleTasksReq 返回本节点作为领导的槽内的广播任务（不包含消息内容和用户列表）
+func (b *broadcastManager) handleTasksReq(c *wkserver.Context) {
+	var req struct {
+		JobId uint64 `json:"job_id"`
+	}
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	tasks, err := b.localTasks(req.JobId)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	for i := range tasks {
+		tasks[i].Payload = nil
+		tasks[i].Uids = nil
+	}
+	c.Write([]byte(wkutil.ToJSON(tasks)))
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastReqCheck(t *testing.T) {
+	assert.Error(t, broadcastReq{}.check())
+	assert.NoError(t, broadcastReq{Payload: []byte("hi")}.check())
+	assert.NoError(t, broadcastReq{Payload: []byte("hi"), Uids: []string{"u1"}}.check())
+	assert.Error(t, broadcastReq{Payload: []byte("hi"), Uids: []string{"u1"}, DeviceFlags: []uint8{0}}.check())
+	assert.Error(t, broadcastReq{Payload: []byte("hi"), DeviceFlags: []uint8{8}}.check())
+	assert.Error(t, broadcastReq{Payload: []byte("hi"), CreatedAtStart: 20, CreatedAtEnd: 10}.check())
+
+	assert.Equal(t, uint8(0b101), broadcastReq{DeviceFlags: []uint8{0, 2}}.deviceFlagMask())
+}
+
+func TestBroadcastMatchUser(t *testing.T) {
+	b := newBroadcastManager(&Server{opts: NewOptions()})
+
+	createdAt := func(sec int64) wkdb.User {
+		u := wkdb.User{Uid: "u1"}
+		tm := time.Unix(sec, 0)
+		u.CreatedAt = &tm
+		return u
+	}
+	task := &wkdb.BroadcastTask{UserCreatedGte: 100, UserCreatedLt: 200}
+	assert.True(t, b.matchUser(task, createdAt(100)))
+	assert.True(t, b.matchUser(task, createdAt(199)))
+	assert.False(t, b.matchUser(task, createdAt(200)))
+	assert.False(t, b.matchUser(task, createdAt(99)))
+	assert.False(t, b.matchUser(task, wkdb.User{Uid: "u1"}))
+
+	assert.True(t, b.matchUser(&wkdb.BroadcastTask{}, wkdb.User{Uid: "u1"}))
+}
+
+func TestBroadcastProgressResp(t *testing.T) {
+	resp := newBroadcastProgressResp(1, []wkdb.BroadcastTask{
+		{JobId: 1, SlotId: 0, Status: wkdb.BroadcastStatusDone, SentCount: 10, FailedCount: 1},
+		{JobId: 1, SlotId: 1, Status: wkdb.BroadcastStatusRunning, SentCount: 5},
+	})
+	assert.Equal(t, "running", resp.Status)
+	assert.Equal(t, 2, resp.TaskCount)
+	assert.Equal(t, 1, resp.DoneTaskCount)
+	assert.Equal(t, uint64(15), resp.SentCount)
+	assert.Equal(t, uint64(1), resp.FailedCount)
+
+	resp = newBroadcastProgressResp(1, []wkdb.BroadcastTask{
+		{JobId: 1, SlotId: 0, Status: wkdb.BroadcastStatusDone},
+		{JobId: 1, SlotId: 1, Status: wkdb.BroadcastStatusCancelled},
+	})
+	assert.Equal(t, "cancelled", resp.Status)
+	assert.Equal(t, 2, resp.DoneTaskCount)
+}
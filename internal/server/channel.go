@@ -465,12 +465,22 @@ func (c *channel) makeReceiverTag() (*tag, error) {
 		if c.r.s.opts.IsCmdChannel(c.channelId) {
 			realChannelId = c.r.opts.CmdChannelConvertOrginalChannel(c.channelId)
 		}
-		members, err := c.r.s.store.GetSubscribers(realChannelId, c.channelType)
-		if err != nil {
-			return nil, err
-		}
-		for _, member := range members {
-			subscribers = append(subscribers, member.Uid)
+		if c.channelType == wkproto.ChannelTypeCommunityTopic { // 话题频道未设置自己的订阅者时继承社区的订阅者
+			subscriberChannel, _, _, err := c.r.s.topicInheritChannels(realChannelId)
+			if err != nil {
+				return nil, err
+			}
+			if subscribers, err = c.r.s.topicSubscribers(subscriberChannel); err != nil {
+				return nil, err
+			}
+		} else {
+			members, err := c.r.s.store.GetSubscribers(realChannelId, c.channelType)
+			if err != nil {
+				return nil, err
+			}
+			for _, member := range members {
+				subscribers = append(subscribers, member.Uid)
+			}
 		}
 	}
 
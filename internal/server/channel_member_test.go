@@ -0,0 +1,26 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMemberMute(t *testing.T) {
+	now := time.Now()
+	muted := wkdb.Member{Uid: "u1", MuteExpireAt: uint64(now.Add(time.Minute).Unix())}
+	expired := wkdb.Member{Uid: "u1", MuteExpireAt: uint64(now.Add(-time.Minute).Unix())}
+	admin := wkdb.Member{Uid: "u1", Role: wkdb.MemberRoleAdmin}
+	owner := wkdb.Member{Uid: "u1", Role: wkdb.MemberRoleOwner}
+	member := wkdb.Member{Uid: "u1"}
+
+	assert.Equal(t, ReasonMemberMuted, checkMemberMute(muted, false, now))
+	assert.Equal(t, wkproto.ReasonSuccess, checkMemberMute(expired, false, now))
+	assert.Equal(t, ReasonChannelMuteAll, checkMemberMute(member, true, now))
+	assert.Equal(t, wkproto.ReasonSuccess, checkMemberMute(admin, true, now))
+	assert.Equal(t, wkproto.ReasonSuccess, checkMemberMute(owner, true, now))
+	assert.Equal(t, wkproto.ReasonSuccess, checkMemberMute(member, false, now))
+}
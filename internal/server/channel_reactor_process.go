@@ -47,6 +47,20 @@ func (r *channelReactor) processInit(req *initReq) {
 		})
 		return
 	}
+	// 加载频道基础信息（封禁、解散、全员禁言等）
+	channelInfo, err := r.s.store.GetChannel(req.ch.channelId, req.ch.channelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		r.Error("processInit: get channel info failed", zap.Error(err), zap.String("channelId", req.ch.channelId), zap.Uint8("channelType", req.ch.channelType))
+		sub.step(req.ch, &ChannelAction{
+			UniqueNo:   req.ch.uniqueNo,
+			ActionType: ChannelActionInitResp,
+			LeaderId:   node.Id,
+			Reason:     ReasonError,
+		})
+		return
+	}
+	req.ch.info = channelInfo
+
 	_, err = req.ch.makeReceiverTag()
 	if err != nil {
 		r.Error("processInit: makeReceiverTag failed", zap.Error(err))
@@ -377,7 +391,7 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 
 		r.MessageTrace("权限验证", msg.SendPacket.ClientMsgNo, "processPermission")
 
-		reasonCode, err := r.hasPermission(req.ch.channelId, req.ch.channelType, msg.FromUid, req.ch)
+		reasonCode, err := r.hasPermission(req.ch.channelId, req.ch.channelType, msg.FromUid, req.ch.info)
 		if err != nil {
 			r.Error("hasPermission error", zap.Error(err))
 			req.messages[i].ReasonCode = wkproto.ReasonSystemError
@@ -403,7 +417,7 @@ func (r *channelReactor) processPermission(req *permissionReq) {
 	})
 }
 
-func (r *channelReactor) hasPermission(channelId string, channelType uint8, fromUid string, ch *channel) (wkproto.ReasonCode, error) {
+func (r *channelReactor) hasPermission(channelId string, channelType uint8, fromUid string, channelInfo wkdb.ChannelInfo) (wkproto.ReasonCode, error) {
 
 	if channelType == wkproto.ChannelTypeInfo { // 资讯频道是公开的，直接通过
 		return wkproto.ReasonSuccess, nil
@@ -438,8 +452,6 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		return reasonCode, nil
 	}
 
-	channelInfo := ch.info
-
 	if channelInfo.Ban { // 频道被封禁
 		return wkproto.ReasonBan, nil
 	}
@@ -454,36 +466,77 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		realChannelId = r.opts.CmdChannelConvertOrginalChannel(channelId)
 	}
 
-	// 判断是否是黑名单内
-	isDenylist, err := r.s.store.ExistDenylist(realChannelId, channelType, fromUid)
-	if err != nil {
-		r.Error("ExistDenylist error", zap.Error(err))
-		return wkproto.ReasonSystemError, err
+	// 社区话题频道的名单默认继承父频道（社区），继承的名单在社区所在槽的领导节点上校验
+	subscriberChannel := topicListChannel{channelId: realChannelId, channelType: channelType}
+	denylistChannel := subscriberChannel
+	allowlistChannel := subscriberChannel
+	muteAll := channelInfo.MuteAll
+	var parentReq topicParentReq
+	var parentMember *wkdb.Member
+	if channelType == wkproto.ChannelTypeCommunityTopic {
+		var err error
+		subscriberChannel, denylistChannel, allowlistChannel, err = r.s.topicInheritChannels(realChannelId)
+		if err != nil {
+			r.Error("topicInheritChannels error", zap.Error(err))
+			return wkproto.ReasonSystemError, err
+		}
+		parentReq = topicParentReq{
+			TopicChannelId: realChannelId,
+			Uid:            fromUid,
+			Subscriber:     subscriberChannel.channelType == wkproto.ChannelTypeCommunity,
+			Denylist:       denylistChannel.channelType == wkproto.ChannelTypeCommunity,
+			Allowlist:      allowlistChannel.channelType == wkproto.ChannelTypeCommunity,
+		}
+		parentResp, err := r.s.checkTopicParent(parentReq)
+		if err != nil {
+			r.Error("checkTopicParent error", zap.Error(err))
+			return wkproto.ReasonSystemError, err
+		}
+		if reasonCode := wkproto.ReasonCode(parentResp.ReasonCode); reasonCode != wkproto.ReasonSuccess {
+			return reasonCode, nil
+		}
+		muteAll = muteAll || parentResp.MuteAll // 社区全员禁言对话题同样生效
+		parentMember = parentResp.Member
 	}
-	if isDenylist {
-		return wkproto.ReasonInBlacklist, nil
+
+	// 判断是否是黑名单内
+	if !parentReq.Denylist {
+		isDenylist, err := r.s.store.ExistDenylist(denylistChannel.channelId, denylistChannel.channelType, fromUid)
+		if err != nil {
+			r.Error("ExistDenylist error", zap.Error(err))
+			return wkproto.ReasonSystemError, err
+		}
+		if isDenylist {
+			return wkproto.ReasonInBlacklist, nil
+		}
 	}
 
 	// 判断是否是订阅者
-	isSubscriber, err := r.s.store.ExistSubscriber(realChannelId, channelType, fromUid)
-	if err != nil {
-		r.Error("ExistSubscriber error", zap.Error(err))
-		return wkproto.ReasonSystemError, err
-	}
-	if !isSubscriber {
-		return wkproto.ReasonSubscriberNotExist, nil
+	var member wkdb.Member
+	if parentMember != nil {
+		member = *parentMember
+	} else {
+		var err error
+		member, err = r.s.store.GetSubscriber(subscriberChannel.channelId, subscriberChannel.channelType, fromUid)
+		if err != nil {
+			r.Error("GetSubscriber error", zap.Error(err))
+			return wkproto.ReasonSystemError, err
+		}
+		if wkdb.IsEmptyMember(member) {
+			return wkproto.ReasonSubscriberNotExist, nil
+		}
 	}
 
 	// 判断是否在白名单内
-	if !r.opts.WhitelistOffOfPerson || channelType != wkproto.ChannelTypePerson { // 如果不是个人频道或者个人频道白名单开关打开，则判断是否在白名单内
-		hasAllowlist, err := r.s.store.HasAllowlist(realChannelId, channelType)
+	if !parentReq.Allowlist && (!r.opts.WhitelistOffOfPerson || channelType != wkproto.ChannelTypePerson) { // 如果不是个人频道或者个人频道白名单开关打开，则判断是否在白名单内
+		hasAllowlist, err := r.s.store.HasAllowlist(allowlistChannel.channelId, allowlistChannel.channelType)
 		if err != nil {
 			r.Error("HasAllowlist error", zap.Error(err))
 			return wkproto.ReasonSystemError, err
 		}
 
 		if hasAllowlist { // 如果频道有白名单，则判断是否在白名单内
-			isAllowlist, err := r.s.store.ExistAllowlist(realChannelId, channelType, fromUid)
+			isAllowlist, err := r.s.store.ExistAllowlist(allowlistChannel.channelId, allowlistChannel.channelType, fromUid)
 			if err != nil {
 				r.Error("ExistAllowlist error", zap.Error(err))
 				return wkproto.ReasonSystemError, err
@@ -494,9 +547,25 @@ func (r *channelReactor) hasPermission(channelId string, channelType uint8, from
 		}
 	}
 
+	// 判断成员是否被禁言
+	if reasonCode := checkMemberMute(member, muteAll, time.Now()); reasonCode != wkproto.ReasonSuccess {
+		return reasonCode, nil
+	}
+
 	return wkproto.ReasonSuccess, nil
 }
 
+// checkMemberMute 成员禁言未到期或频道全员禁言（管理员和群主除外）都不允许发送
+func checkMemberMute(member wkdb.Member, muteAll bool, now time.Time) wkproto.ReasonCode {
+	if member.IsMuted(now) {
+		return ReasonMemberMuted
+	}
+	if muteAll && !member.Role.IsManager() {
+		return ReasonChannelMuteAll
+	}
+	return wkproto.ReasonSuccess
+}
+
 func (r *channelReactor) requestAllowSend(from, to string) (wkproto.ReasonCode, error) {
 
 	leaderNode, err := r.s.cluster.SlotLeaderOfChannel(to, wkproto.ChannelTypePerson)
@@ -707,6 +776,21 @@ func (r *channelReactor) processStorage(reqs []*storageReq) {
 			}
 		}
 
+		if reason == ReasonSuccess && len(sotreMessages) > 0 && !r.opts.IsCmdChannel(req.ch.channelId) {
+			// 异步推送匹配的消息给机器人
+			botMessages := make([]wkdb.Message, 0, len(sotreMessages))
+			for _, msg := range sotreMessages {
+				for _, cmsg := range req.messages {
+					if msg.MessageID == cmsg.MessageId {
+						msg.MessageSeq = cmsg.MessageSeq
+						botMessages = append(botMessages, msg)
+						break
+					}
+				}
+			}
+			r.s.botManager.addMessages(req.ch.channelId, req.ch.channelType, botMessages)
+		}
+
 		if r.opts.WebhookOn() {
 			// 赋值messageeq
 			for i, msg := range messages {
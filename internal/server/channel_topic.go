@@ -0,0 +1,312 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// topicListChannel 名单（订阅者/黑名单/白名单）实际所属的频道
+type topicListChannel struct {
+	channelId   string
+	channelType uint8
+}
+
+// topicInheritChannel 话题设置了自己的名单（ownCount>0）则使用话题自己的名单，否则继承父频道（社区）的名单
+func topicInheritChannel(topicChannelId string, ownCount int) topicListChannel {
+	if ownCount > 0 {
+		return topicListChannel{
+			channelId:   topicChannelId,
+			channelType: wkproto.ChannelTypeCommunityTopic,
+		}
+	}
+	return topicListChannel{
+		channelId:   GetCommunityTopicParentChannelID(topicChannelId),
+		channelType: wkproto.ChannelTypeCommunity,
+	}
+}
+
+// topicInheritChannels 获取话题频道的订阅者、黑名单、白名单实际所属的频道
+func (s *Server) topicInheritChannels(topicChannelId string) (subscriber, denylist, allowlist topicListChannel, err error) {
+	topicInfo, err := s.store.GetChannel(topicChannelId, wkproto.ChannelTypeCommunityTopic)
+	if err != nil && err != wkdb.ErrNotFound {
+		return
+	}
+	err = nil
+	subscriber = topicInheritChannel(topicChannelId, topicInfo.SubscriberCount)
+	denylist = topicInheritChannel(topicChannelId, topicInfo.DenylistCount)
+	allowlist = topicInheritChannel(topicChannelId, topicInfo.AllowlistCount)
+	return
+}
+
+type topicParentReq struct {
+	TopicChannelId string `json:"topic_channel_id"`
+	Uid            string `json:"uid,omitempty"`        // 发送者，为空时只检查话题是否可用
+	Subscriber     bool   `json:"subscriber,omitempty"` // 订阅者名单继承社区
+	Denylist       bool   `json:"denylist,omitempty"`   // 黑名单继承社区
+	Allowlist      bool   `json:"allowlist,omitempty"`  // 白名单继承社区
+}
+
+type topicParentResp struct {
+	ReasonCode uint8        `json:"reason_code"`
+	MuteAll    bool         `json:"mute_all,omitempty"` // 社区是否全员禁言
+	Member     *wkdb.Member `json:"member,omitempty"`   // 发送者在社区的成员信息（订阅者名单继承社区时才有）
+}
+
+// checkTopicParent 检查话题频道中由父频道（社区）决定的部分：父频道被封禁、解散或话题已归档都不允许发送，
+// 继承社区名单时同时校验发送者是否在社区的名单内。社区的数据在社区所在槽的领导节点上校验
+func (s *Server) checkTopicParent(req topicParentReq) (*topicParentResp, error) {
+	parentChannelId := GetCommunityTopicParentChannelID(req.TopicChannelId)
+	if parentChannelId == "" || GetCommunityTopicID(req.TopicChannelId) == "" {
+		return &topicParentResp{ReasonCode: uint8(wkproto.ReasonChannelIDError)}, nil
+	}
+	if s.opts.ClusterOn() {
+		leaderId, err := s.cluster.SlotLeaderIdOfChannel(parentChannelId, wkproto.ChannelTypeCommunity)
+		if err != nil {
+			return nil, err
+		}
+		if leaderId != s.opts.Cluster.NodeId {
+			return s.requestTopicParent(leaderId, req)
+		}
+	}
+	return s.checkLocalTopicParent(req)
+}
+
+func (s *Server) checkLocalTopicParent(req topicParentReq) (*topicParentResp, error) {
+	parentChannelId := GetCommunityTopicParentChannelID(req.TopicChannelId)
+	topicId := GetCommunityTopicID(req.TopicChannelId)
+
+	parentInfo, err := s.store.GetChannel(parentChannelId, wkproto.ChannelTypeCommunity)
+	if err != nil && err != wkdb.ErrNotFound {
+		return nil, err
+	}
+	resp := &topicParentResp{ReasonCode: uint8(wkproto.ReasonSuccess), MuteAll: parentInfo.MuteAll}
+	if parentInfo.Ban {
+		resp.ReasonCode = uint8(wkproto.ReasonBan)
+		return resp, nil
+	}
+	if parentInfo.Disband {
+		resp.ReasonCode = uint8(wkproto.ReasonDisband)
+		return resp, nil
+	}
+	topic, err := s.store.GetChannelTopic(parentChannelId, wkproto.ChannelTypeCommunity, topicId)
+	if err != nil {
+		return nil, err
+	}
+	if topic.Archived {
+		resp.ReasonCode = uint8(wkproto.ReasonNotAllowSend)
+		return resp, nil
+	}
+	if req.Uid == "" {
+		return resp, nil
+	}
+
+	if req.Denylist {
+		isDenylist, err := s.store.ExistDenylist(parentChannelId, wkproto.ChannelTypeCommunity, req.Uid)
+		if err != nil {
+			return nil, err
+		}
+		if isDenylist {
+			resp.ReasonCode = uint8(wkproto.ReasonInBlacklist)
+			return resp, nil
+		}
+	}
+	if req.Subscriber {
+		member, err := s.store.GetSubscriber(parentChannelId, wkproto.ChannelTypeCommunity, req.Uid)
+		if err != nil {
+			return nil, err
+		}
+		if wkdb.IsEmptyMember(member) {
+			resp.ReasonCode = uint8(wkproto.ReasonSubscriberNotExist)
+			return resp, nil
+		}
+		resp.Member = &member
+	}
+	if req.Allowlist {
+		hasAllowlist, err := s.store.HasAllowlist(parentChannelId, wkproto.ChannelTypeCommunity)
+		if err != nil {
+			return nil, err
+		}
+		if hasAllowlist {
+			isAllowlist, err := s.store.ExistAllowlist(parentChannelId, wkproto.ChannelTypeCommunity, req.Uid)
+			if err != nil {
+				return nil, err
+			}
+			if !isAllowlist {
+				resp.ReasonCode = uint8(wkproto.ReasonNotInWhitelist)
+				return resp, nil
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) requestTopicParent(nodeId uint64, req topicParentReq) (*topicParentResp, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/topic/checkParent", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var result topicParentResp
+	if err = wkutil.ReadJSONByByte(resp.Body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// handleTopicParentReq 处理其他节点校验话题父频道（社区）的请求
+func (s *Server) handleTopicParentReq(c *wkserver.Context) {
+	var req topicParentReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		s.Error("handleTopicParentReq: decode req failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp, err := s.checkLocalTopicParent(req)
+	if err != nil {
+		s.Error("handleTopicParentReq: check failed", zap.Error(err), zap.String("topicChannelId", req.TopicChannelId))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(resp)))
+}
+
+// topicSubscribers 获取话题频道的订阅者，继承社区的订阅者时从社区所在槽的领导节点获取
+func (s *Server) topicSubscribers(subscriberChannel topicListChannel) ([]string, error) {
+	if s.opts.ClusterOn() && subscriberChannel.channelType == wkproto.ChannelTypeCommunity {
+		leaderId, err := s.cluster.SlotLeaderIdOfChannel(subscriberChannel.channelId, subscriberChannel.channelType)
+		if err != nil {
+			return nil, err
+		}
+		if leaderId != s.opts.Cluster.NodeId {
+			return s.requestTopicSubscribers(leaderId, subscriberChannel.channelId)
+		}
+	}
+	return s.localSubscriberUids(subscriberChannel.channelId, subscriberChannel.channelType)
+}
+
+func (s *Server) localSubscriberUids(channelId string, channelType uint8) ([]string, error) {
+	members, err := s.store.GetSubscribers(channelId, channelType)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]string, 0, len(members))
+	for _, member := range members {
+		uids = append(uids, member.Uid)
+	}
+	return uids, nil
+}
+
+func (s *Server) requestTopicSubscribers(nodeId uint64, parentChannelId string) ([]string, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/topic/parentSubscribers", []byte(parentChannelId))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var uids []string
+	if err = wkutil.ReadJSONByByte(resp.Body, &uids); err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// handleTopicSubscribersReq 处理其他节点获取社区订阅者的请求
+func (s *Server) handleTopicSubscribersReq(c *wkserver.Context) {
+	uids, err := s.localSubscriberUids(string(c.Body()), wkproto.ChannelTypeCommunity)
+	if err != nil {
+		s.Error("handleTopicSubscribersReq: get subscribers failed", zap.Error(err), zap.String("parentChannelId", string(c.Body())))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(uids)))
+}
+
+// refreshTopicReceiverTags 社区订阅者变更后，重新生成继承社区订阅者的话题频道的接收者标签
+// 话题频道只在其领导节点的频道处理器内有接收者标签，需要在各个话题频道的领导节点上重新生成
+func (s *Server) refreshTopicReceiverTags(parentChannelId string) {
+	topics, err := s.store.GetChannelTopics(parentChannelId, wkproto.ChannelTypeCommunity)
+	if err != nil {
+		s.Error("refreshTopicReceiverTags: get topics failed", zap.Error(err), zap.String("parentChannelId", parentChannelId))
+		return
+	}
+	nodeTopicChannelIds := make(map[uint64][]string)
+	for _, topic := range topics {
+		topicChannelId := topic.ChannelId()
+		if !s.opts.ClusterOn() {
+			nodeTopicChannelIds[s.opts.Cluster.NodeId] = append(nodeTopicChannelIds[s.opts.Cluster.NodeId], topicChannelId)
+			continue
+		}
+		leaderInfo, err := s.cluster.LeaderOfChannelForRead(topicChannelId, wkproto.ChannelTypeCommunityTopic)
+		if errors.Is(err, cluster.ErrChannelClusterConfigNotFound) { // 话题频道还没有激活过，没有接收者标签
+			continue
+		}
+		if err != nil {
+			s.Error("refreshTopicReceiverTags: get topic leader failed", zap.Error(err), zap.String("channelId", topicChannelId))
+			continue
+		}
+		nodeTopicChannelIds[leaderInfo.Id] = append(nodeTopicChannelIds[leaderInfo.Id], topicChannelId)
+	}
+	for nodeId, topicChannelIds := range nodeTopicChannelIds {
+		if nodeId == s.opts.Cluster.NodeId {
+			s.refreshLocalTopicReceiverTags(topicChannelIds)
+			continue
+		}
+		if err = s.requestRefreshTopicReceiverTags(nodeId, topicChannelIds); err != nil {
+			s.Error("refreshTopicReceiverTags: request failed", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("parentChannelId", parentChannelId))
+		}
+	}
+}
+
+// refreshLocalTopicReceiverTags 重新生成本节点频道处理器内话题频道的接收者标签
+func (s *Server) refreshLocalTopicReceiverTags(topicChannelIds []string) {
+	for _, topicChannelId := range topicChannelIds {
+		channelKey := wkutil.ChannelToKey(topicChannelId, wkproto.ChannelTypeCommunityTopic)
+		ch := s.channelReactor.reactorSub(channelKey).channel(channelKey)
+		if ch == nil {
+			continue
+		}
+		if _, err := ch.makeReceiverTag(); err != nil {
+			s.Error("refreshTopicReceiverTags: makeReceiverTag failed", zap.Error(err), zap.String("channelId", topicChannelId))
+		}
+	}
+}
+
+func (s *Server) requestRefreshTopicReceiverTags(nodeId uint64, topicChannelIds []string) error {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/refreshTopicReceiverTags", []byte(wkutil.ToJSON(topicChannelIds)))
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return errors.New(string(resp.Body))
+	}
+	return nil
+}
+
+// handleRefreshTopicReceiverTags 处理其他节点重新生成话题频道接收者标签的请求
+func (s *Server) handleRefreshTopicReceiverTags(c *wkserver.Context) {
+	var topicChannelIds []string
+	if err := wkutil.ReadJSONByByte(c.Body(), &topicChannelIds); err != nil {
+		s.Error("handleRefreshTopicReceiverTags: decode req failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.refreshLocalTopicReceiverTags(topicChannelIds)
+	c.WriteOk()
+}
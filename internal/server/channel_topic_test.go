@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSpecialCharOfChannel(t *testing.T) {
+	assert.False(t, IsSpecialCharOfChannel("community@topic", wkproto.ChannelTypeCommunityTopic))
+	assert.True(t, IsSpecialCharOfChannel("community@", wkproto.ChannelTypeCommunityTopic))
+	assert.True(t, IsSpecialCharOfChannel("a@b@c", wkproto.ChannelTypeCommunityTopic))
+	assert.True(t, IsSpecialCharOfChannel("community@topic", wkproto.ChannelTypeGroup))
+	assert.False(t, IsSpecialCharOfChannel("group", wkproto.ChannelTypeGroup))
+}
+
+func TestTopicInheritChannel(t *testing.T) {
+	ch := topicInheritChannel("community@topic", 0)
+	assert.Equal(t, "community", ch.channelId)
+	assert.Equal(t, wkproto.ChannelTypeCommunity, ch.channelType)
+
+	ch = topicInheritChannel("community@topic", 2)
+	assert.Equal(t, "community@topic", ch.channelId)
+	assert.Equal(t, wkproto.ChannelTypeCommunityTopic, ch.channelType)
+}
+
+func TestCheckLocalTopicParent(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	_, err = s.store.DB().AddChannel(wkdb.ChannelInfo{ChannelId: "c1", ChannelType: wkproto.ChannelTypeCommunity, MuteAll: true})
+	assert.NoError(t, err)
+	err = s.store.DB().AddOrUpdateChannelTopic(wkdb.ChannelTopic{ParentChannelId: "c1", ParentChannelType: wkproto.ChannelTypeCommunity, TopicId: "t1"})
+	assert.NoError(t, err)
+	err = s.store.DB().AddOrUpdateChannelTopic(wkdb.ChannelTopic{ParentChannelId: "c1", ParentChannelType: wkproto.ChannelTypeCommunity, TopicId: "t2", Archived: true})
+	assert.NoError(t, err)
+	err = s.store.DB().AddSubscribers("c1", wkproto.ChannelTypeCommunity, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.NoError(t, err)
+	err = s.store.DB().AddDenylist("c1", wkproto.ChannelTypeCommunity, []wkdb.Member{{Uid: "u2"}})
+	assert.NoError(t, err)
+
+	resp, err := s.checkLocalTopicParent(topicParentReq{TopicChannelId: "c1@t1", Uid: "u1", Subscriber: true, Denylist: true, Allowlist: true})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(wkproto.ReasonSuccess), resp.ReasonCode)
+	assert.True(t, resp.MuteAll)
+	assert.Equal(t, "u1", resp.Member.Uid)
+
+	// 继承社区的黑名单
+	resp, err = s.checkLocalTopicParent(topicParentReq{TopicChannelId: "c1@t1", Uid: "u2", Subscriber: true, Denylist: true})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(wkproto.ReasonInBlacklist), resp.ReasonCode)
+
+	// 话题有自己的名单时不校验社区的名单
+	resp, err = s.checkLocalTopicParent(topicParentReq{TopicChannelId: "c1@t1", Uid: "u3"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(wkproto.ReasonSuccess), resp.ReasonCode)
+	assert.Nil(t, resp.Member)
+
+	resp, err = s.checkLocalTopicParent(topicParentReq{TopicChannelId: "c1@t1", Uid: "u3", Subscriber: true})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(wkproto.ReasonSubscriberNotExist), resp.ReasonCode)
+
+	resp, err = s.checkLocalTopicParent(topicParentReq{TopicChannelId: "c1@t2"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(wkproto.ReasonNotAllowSend), resp.ReasonCode)
+}
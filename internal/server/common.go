@@ -9,6 +9,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/sendgrid/rest"
 	"go.uber.org/zap"
 )
@@ -20,8 +21,16 @@ const (
 	ClusterMsgTypeNodePing ClusterMsgType = 1001
 	// 节点Pong
 	ClusterMsgTypeNodePong ClusterMsgType = 1002
+	// 用户在线状态变化
+	ClusterMsgTypePresence ClusterMsgType = 1003
+	// 瞬时信号投递
+	ClusterMsgTypeEphemeral ClusterMsgType = 1004
 )
 
+// SettingEphemeral 发送包设置此标记表示是瞬时信号（比如"正在输入"），
+// 瞬时信号不存储、不更新最近会话，只投递给当前在线的订阅者
+const SettingEphemeral wkproto.Setting = 1 << 6
+
 type channelRole int
 
 const (
@@ -233,6 +242,20 @@ func GetCommunityTopicParentChannelID(channelID string) string {
 	return ""
 }
 
+// GetCommunityTopicID 获取社区话题频道的话题ID
+func GetCommunityTopicID(channelID string) string {
+	channelIDs := strings.Split(channelID, "@")
+	if len(channelIDs) == 2 {
+		return channelIDs[1]
+	}
+	return ""
+}
+
+// GetCommunityTopicChannelID 获取社区话题频道的频道ID
+func GetCommunityTopicChannelID(parentChannelID string, topicID string) string {
+	return fmt.Sprintf("%s@%s", parentChannelID, topicID)
+}
+
 type Reason int
 
 const (
@@ -242,6 +265,13 @@ const (
 	ReasonTimeout
 )
 
+// 服务端扩展的发送失败原因（wkproto内置的原因码之外，从100开始避免与协议内置的冲突）
+const (
+	ReasonMemberMuted    wkproto.ReasonCode = 100 + iota // 成员被禁言
+	ReasonChannelMuteAll                                 // 频道全员禁言中
+	ReasonNodeDraining                                   // 节点下线中（断开连接的原因，Reason里带有建议重连的节点地址）
+)
+
 func parseAddr(addr string) (string, int64) {
 	addrPairs := strings.Split(addr, ":")
 	if len(addrPairs) < 2 {
@@ -330,6 +360,19 @@ func IsSpecialChar(s string) bool {
 	return strings.Contains(s, "@") || strings.Contains(s, "#") || strings.Contains(s, "&")
 }
 
+// IsSpecialCharOfChannel 判断频道ID是否存在特殊字符，社区话题频道允许使用@分隔父频道ID和话题ID
+func IsSpecialCharOfChannel(channelID string, channelType uint8) bool {
+	if channelType == wkproto.ChannelTypeCommunityTopic {
+		parentChannelID := GetCommunityTopicParentChannelID(channelID)
+		topicID := GetCommunityTopicID(channelID)
+		if strings.TrimSpace(parentChannelID) == "" || strings.TrimSpace(topicID) == "" {
+			return true
+		}
+		return IsSpecialChar(parentChannelID) || IsSpecialChar(topicID)
+	}
+	return IsSpecialChar(channelID)
+}
+
 // 连接上下文的key
 type ConnKey string
 
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/grpcpool"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// ConfigReloadResult 节点重新加载配置的结果
+type ConfigReloadResult struct {
+	NodeId  uint64   `json:"node_id"`
+	Success bool     `json:"success"`
+	Changed []string `json:"changed,omitempty"` // 发生变化的配置项
+	Error   string   `json:"error,omitempty"`   // 失败原因，失败时节点继续使用旧的配置
+}
+
+// configReloader 运行时重新加载配置（SIGHUP或管理接口），只重新加载Options.LoadReloadable里定义的配置项
+type configReloader struct {
+	s *Server
+	wklog.Log
+	mu sync.Mutex // 同一时间只能有一个重新加载
+}
+
+func newConfigReloader(s *Server) *configReloader {
+	return &configReloader{
+		s:   s,
+		Log: wklog.NewWKLog("configReloader"),
+	}
+}
+
+// reload 重新加载本节点的配置，配置有误时不做任何修改
+// 先按新的配置创建依赖的组件（webhook的grpc连接池、令牌验证的公钥），全部成功后再让配置生效并替换组件，
+// 避免出现新的配置搭配旧的组件
+func (r *configReloader) reload() ConfigReloadResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := ConfigReloadResult{
+		NodeId: r.s.opts.Cluster.NodeId,
+	}
+	n, err := r.s.opts.LoadReloadable()
+	if err != nil {
+		r.Error("reload config failed", zap.Error(err), zap.String("config", r.s.opts.ConfigFileUsed()))
+		result.Error = err.Error()
+		return result
+	}
+
+	changed := r.s.opts.ReloadableChanged(n)
+	prepared, err := r.prepare(n, changed)
+	if err != nil {
+		r.Error("reload config failed", zap.Error(err), zap.Strings("changed", changed), zap.String("config", r.s.opts.ConfigFileUsed()))
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Changed = r.s.opts.ApplyReloadable(n)
+	r.swap(prepared)
+	result.Success = true
+	r.Info("config reloaded", zap.Strings("changed", result.Changed))
+	return result
+}
+
+// reloadPrepared 按新的配置提前创建好的组件
+type reloadPrepared struct {
+	opts *Options
+
+	loggerChanged     bool
+	datasourceChanged bool
+	authChanged       bool
+
+	webhookChanged  bool
+	grpcAddrChanged bool
+	grpcPool        *grpcpool.Pool // grpcAddrChanged时新的连接池，没有配置grpc地址时为nil
+
+	tokenChanged bool
+	tokenKeys    *tokenVerifierKeys
+}
+
+// prepare 按新的配置创建依赖变化配置项的组件，失败时释放已创建的组件
+func (r *configReloader) prepare(n *Options, changed []string) (*reloadPrepared, error) {
+	p := &reloadPrepared{opts: n}
+	for _, key := range changed {
+		switch {
+		case key == "logger.level":
+			p.loggerChanged = true
+		case key == "webhook.grpcAddr":
+			p.grpcAddrChanged = true
+			p.webhookChanged = true
+		case strings.HasPrefix(key, "webhook."):
+			p.webhookChanged = true
+		case key == "datasource.addr":
+			p.datasourceChanged = true
+		case key == "tokenAuthOn", key == "tokenAuth":
+			p.tokenChanged = true
+		case key == "auth":
+			p.authChanged = true
+		}
+	}
+	var err error
+	if p.tokenChanged {
+		if p.tokenKeys, err = loadTokenVerifierKeysWithOptions(n); err != nil {
+			return nil, err
+		}
+	}
+	if p.grpcAddrChanged {
+		if p.grpcPool, err = r.s.webhook.newGRPCPoolWithOptions(n); err != nil {
+			return nil, fmt.Errorf("webhook: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// swap 配置生效后替换依赖变化配置项的组件
+func (r *configReloader) swap(p *reloadPrepared) {
+	if p.loggerChanged {
+		wklog.SetLevel(p.opts.Logger.Level)
+	}
+	if p.datasourceChanged {
+		r.s.systemUIDManager.Reset() // 系统账号可能来自数据源
+	}
+	if p.authChanged && r.s.clusterServer != nil {
+		r.s.clusterServer.SetAuth(p.opts.GetAuth())
+	}
+	if p.webhookChanged {
+		r.s.webhook.reload(p.grpcAddrChanged, p.grpcPool)
+	}
+	if p.tokenChanged {
+		_, cfg := p.opts.GetTokenAuth()
+		r.s.tokenVerifier.reload(cfg, p.tokenKeys)
+	}
+}
+
+// reloadCluster 所有节点重新加载各自的配置，返回每个节点的结果
+func (r *configReloader) reloadCluster(ctx context.Context) []ConfigReloadResult {
+	if !r.s.opts.ClusterOn() || r.s.clusterServer == nil {
+		return []ConfigReloadResult{r.reload()}
+	}
+	nodes := r.s.clusterServer.GetConfig().Nodes
+	results := make([]ConfigReloadResult, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		if node.Id == r.s.opts.Cluster.NodeId {
+			results[i] = r.reload()
+			continue
+		}
+		if !node.Online {
+			results[i] = ConfigReloadResult{NodeId: node.Id, Error: "node offline"}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, nodeId uint64) {
+			defer wg.Done()
+			results[i] = r.requestReload(ctx, nodeId)
+		}(i, node.Id)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].NodeId < results[j].NodeId
+	})
+	return results
+}
+
+func (r *configReloader) requestReload(ctx context.Context, nodeId uint64) ConfigReloadResult {
+	result := ConfigReloadResult{NodeId: nodeId}
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := r.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/config/reload", nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if resp.Status != proto.Status_OK {
+		result.Error = string(resp.Body)
+		return result
+	}
+	if err = wkutil.ReadJSONByByte(resp.Body, &result); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// handleReloadReq 其他节点通知本节点重新加载配置
+func (r *configReloader) handleReloadReq(c *wkserver.Context) {
+	result := r.reload()
+	c.Write([]byte(wkutil.ToJSON(result)))
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestOptionsReloadable(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "wk.yaml")
+	writeConfig := func(content string) {
+		err := os.WriteFile(cfgFile, []byte("rootDir: "+dir+"\n"+content), 0644)
+		assert.NoError(t, err)
+	}
+	writeConfig(`
+mode: release
+logger:
+  level: 2
+webhook:
+  httpAddr: http://127.0.0.1:6979/webhook
+broadcast:
+  defaultRate: 100
+`)
+	vp := viper.New()
+	vp.SetConfigFile(cfgFile)
+	err := vp.ReadInConfig()
+	assert.NoError(t, err)
+
+	opts := NewOptions()
+	opts.ConfigureWithViper(vp)
+	assert.Equal(t, zapcore.InfoLevel, opts.Logger.Level)
+	assert.Equal(t, "http://127.0.0.1:6979/webhook", opts.Webhook.HTTPAddr)
+
+	// 修改可重新加载的配置项和需要重启的配置项
+	writeConfig(`
+mode: release
+httpAddr: 0.0.0.0:6001
+logger:
+  level: 3
+webhook:
+  grpcAddr: 127.0.0.1:6980
+broadcast:
+  defaultRate: 100
+auth:
+  on: true
+  users:
+    - "admin:pwd:*"
+`)
+	n, err := opts.LoadReloadable()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"logger.level", "webhook.httpAddr", "webhook.grpcAddr", "auth"}, opts.ReloadableChanged(n))
+	assert.Equal(t, zapcore.InfoLevel, opts.Logger.Level) // 只比较不修改
+	changed := opts.ApplyReloadable(n)
+	assert.ElementsMatch(t, []string{"logger.level", "webhook.httpAddr", "webhook.grpcAddr", "auth"}, changed)
+	assert.Equal(t, zapcore.WarnLevel, opts.Logger.Level)
+	assert.Equal(t, "", opts.Webhook.HTTPAddr) // 删除的配置项恢复为默认值
+	assert.Equal(t, "127.0.0.1:6980", opts.Webhook.GRPCAddr)
+	assert.True(t, opts.Auth.On)
+	assert.NoError(t, opts.Auth.Auth("admin", "pwd"))
+	assert.Equal(t, "0.0.0.0:5001", opts.HTTPAddr) // 需要重启的配置项不变
+
+	// 配置有误时不修改当前配置
+	writeConfig(`
+mode: release
+logger:
+  level: 4
+webhook:
+  httpAddr: ftp://127.0.0.1/webhook
+`)
+	_, err = opts.LoadReloadable()
+	assert.Error(t, err)
+	assert.Equal(t, zapcore.WarnLevel, opts.Logger.Level)
+	assert.Equal(t, "127.0.0.1:6980", opts.Webhook.GRPCAddr)
+
+	// 没有变化
+	writeConfig(`
+mode: release
+logger:
+  level: 3
+webhook:
+  grpcAddr: 127.0.0.1:6980
+broadcast:
+  defaultRate: 100
+auth:
+  on: true
+  users:
+    - "admin:pwd:*"
+`)
+	n, err = opts.LoadReloadable()
+	assert.NoError(t, err)
+	assert.Empty(t, opts.ApplyReloadable(n))
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
+	"github.com/WuKongIM/WuKongIM/pkg/wkcipher"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wknet"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -35,10 +36,13 @@ type connInfo struct {
 	deviceId     string
 	deviceFlag   wkproto.DeviceFlag
 	deviceLevel  wkproto.DeviceLevel
+	tokenId      string // 连接使用的签名令牌的令牌ID（jti），令牌被吊销时用于踢掉连接
 	aesKey       string
 	aesIV        string
 	protoVersion uint8
 
+	cipherSession *wkcipher.Session // AEAD加密会话，为空时使用旧的AES-CBC加密
+
 	closed atomic.Bool
 
 	isAuth atomic.Bool // 是否已经认证
@@ -162,7 +166,7 @@ func (c *connContext) addSendPacket(packet *wkproto.SendPacket) {
 	c.MessageTrace("收到消息", packet.ClientMsgNo, "processMessage")
 
 	// 非法频道id，直接返回发送失败
-	if strings.TrimSpace(packet.ChannelID) == "" || IsSpecialChar(packet.ChannelID) {
+	if strings.TrimSpace(packet.ChannelID) == "" || IsSpecialCharOfChannel(packet.ChannelID, packet.ChannelType) {
 		c.Error("addSendPacket failed, channelId is illegal", zap.String("uid", c.uid), zap.String("channelId", packet.ChannelID))
 		c.MessageTrace("addSendPacket failed, channelId is illegal", packet.ClientMsgNo, "processMessage", zap.Error(errors.New("channelId is illegal")))
 		sendack := &wkproto.SendackPacket{
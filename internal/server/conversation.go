@@ -68,6 +68,21 @@ func (c *ConversationManager) Push(fakeChannelId string, channelType uint8, uids
 		worker.getOrCreateUserConversation(message.FromUid).updateOrAddConversation(fakeChannelId, channelType, message.MessageSeq)
 	}
 
+	// 消息的@信息
+	var (
+		mentions   = make([]*messageMention, len(messages))
+		hasMention bool
+	)
+	for i, message := range messages {
+		if message.SendPacket.NoPersist || message.MessageSeq == 0 {
+			continue
+		}
+		mentions[i] = parseMessageMention(message.SendPacket.Payload)
+		if mentions[i] != nil {
+			hasMention = true
+		}
+	}
+
 	// 处理接受者的最近会话
 	for _, uid := range uids {
 
@@ -96,6 +111,15 @@ func (c *ConversationManager) Push(fakeChannelId string, channelType uint8, uids
 			}
 		}
 
+		// 记录@了此用户的消息
+		if hasMention {
+			for i, mention := range mentions {
+				if mention != nil && mention.mentioned(uid, messages[i].FromUid) {
+					userConversation.addMention(fakeChannelId, channelType, messages[i].MessageSeq, mention.mentionAll())
+				}
+			}
+		}
+
 	}
 
 }
@@ -127,6 +151,13 @@ func (c *ConversationManager) Stop() {
 	c.saveToFile()
 }
 
+// Flush 立即保存所有需要更新的最近会话（节点下线前调用）
+func (c *ConversationManager) Flush() {
+	for _, w := range c.workers {
+		w.propose()
+	}
+}
+
 func (c *ConversationManager) saveToFile() {
 	c.Lock()
 	defer c.Unlock()
@@ -221,6 +252,11 @@ func (c *ConversationManager) DeleteUserConversationFromCache(uid string, channe
 	userconversation.deleteConversation(channelId, channelType)
 }
 
+// DeleteUserFromCache 删除用户的所有最近会话缓存（删除用户数据时使用，防止缓存中未保存的会话再被写回）
+func (c *ConversationManager) DeleteUserFromCache(uid string) {
+	c.worker(uid).removeUserConversation(uid)
+}
+
 // func (c *ConversationManager) existConversationInCache(uid string, channelId string, channelType uint8) bool {
 // 	userconversation := c.worker(uid).getUserConversation(uid)
 // 	if userconversation == nil {
@@ -295,20 +331,24 @@ func (c *conversationWorker) propose() {
 				var conversationType wkdb.ConversationType
 				if c.s.opts.IsCmdChannel(conversation.ChannelId) {
 					conversationType = wkdb.ConversationTypeCMD
+				} else if conversation.ChannelType == wkproto.ChannelTypeCommunityTopic {
+					conversationType = wkdb.ConversationTypeTopic
 				} else {
 					conversationType = wkdb.ConversationTypeChat
 				}
 				createdAt := time.Now()
 				updatedAt := time.Now()
 				conversations = append(conversations, wkdb.Conversation{
-					Id:           conversation.Id,
-					Uid:          cc.uid,
-					Type:         conversationType,
-					ChannelId:    conversation.ChannelId,
-					ChannelType:  conversation.ChannelType,
-					ReadToMsgSeq: uint64(conversation.ReadedMsgSeq),
-					CreatedAt:    &createdAt,
-					UpdatedAt:    &updatedAt,
+					Id:            conversation.Id,
+					Uid:           cc.uid,
+					Type:          conversationType,
+					ChannelId:     conversation.ChannelId,
+					ChannelType:   conversation.ChannelType,
+					ReadToMsgSeq:  uint64(conversation.ReadedMsgSeq),
+					MentionMsgSeq: uint64(conversation.MentionMsgSeq),
+					MentionAll:    conversation.MentionAll,
+					CreatedAt:     &createdAt,
+					UpdatedAt:     &updatedAt,
 				})
 			}
 		}
@@ -358,6 +398,22 @@ func (c *conversationWorker) getUserConversation(uid string) *userConversation {
 
 }
 
+func (c *conversationWorker) removeUserConversation(uid string) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i, cc := range c.userConversations {
+		if cc.uid == uid {
+			// 正在提案的worker可能已经复制了切片，清空会话防止再被保存
+			cc.Lock()
+			cc.conversations = nil
+			cc.Unlock()
+			c.userConversations = append(c.userConversations[:i], c.userConversations[i+1:]...)
+			return
+		}
+	}
+}
+
 type userConversation struct {
 	uid           string
 	conversations []*channelConversation
@@ -411,13 +467,15 @@ func (c *userConversation) getConversationsByType(conversationType wkdb.Conversa
 	for _, s := range c.conversations {
 		if s.ConversationType == conversationType {
 			conversations = append(conversations, wkdb.Conversation{
-				Uid:          c.uid,
-				Type:         s.ConversationType,
-				ChannelId:    s.ChannelId,
-				ChannelType:  s.ChannelType,
-				ReadToMsgSeq: uint64(s.ReadedMsgSeq),
-				CreatedAt:    &s.CreatedAt,
-				UpdatedAt:    &s.UpdatedAt,
+				Uid:           c.uid,
+				Type:          s.ConversationType,
+				ChannelId:     s.ChannelId,
+				ChannelType:   s.ChannelType,
+				ReadToMsgSeq:  uint64(s.ReadedMsgSeq),
+				MentionMsgSeq: uint64(s.MentionMsgSeq),
+				MentionAll:    s.MentionAll,
+				CreatedAt:     &s.CreatedAt,
+				UpdatedAt:     &s.UpdatedAt,
 			})
 		}
 	}
@@ -468,6 +526,10 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 		if conversation.ReadedMsgSeq < readedMsgSeq {
 			conversation.ReadedMsgSeq = readedMsgSeq
 			conversation.NeedUpdate = true
+			if conversation.MentionMsgSeq <= readedMsgSeq { // 已读过@的消息
+				conversation.MentionMsgSeq = 0
+				conversation.MentionAll = false
+			}
 		}
 		return
 	}
@@ -475,6 +537,8 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 	var conversationType wkdb.ConversationType
 	if c.s.opts.IsCmdChannel(channelId) {
 		conversationType = wkdb.ConversationTypeCMD
+	} else if channelType == wkproto.ChannelTypeCommunityTopic {
+		conversationType = wkdb.ConversationTypeTopic
 	} else {
 		conversationType = wkdb.ConversationTypeChat
 	}
@@ -490,11 +554,30 @@ func (c *userConversation) updateOrAddConversation(channelId string, channelType
 	})
 }
 
+// addMention 记录@了此用户的消息，只保留最近一条
+func (c *userConversation) addMention(channelId string, channelType uint8, messageSeq uint32, all bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	conversation := c.getConversationNotLock(channelId, channelType)
+	if conversation == nil {
+		return
+	}
+	if messageSeq <= conversation.ReadedMsgSeq || messageSeq <= conversation.MentionMsgSeq {
+		return
+	}
+	conversation.MentionMsgSeq = messageSeq
+	conversation.MentionAll = all
+	conversation.NeedUpdate = true
+}
+
 func (c *userConversation) addConversationNotLock(conversationId uint64, channelId string, channelType uint8, readedMsgSeq uint32) *channelConversation {
 
 	var conversationType wkdb.ConversationType
 	if c.s.opts.IsCmdChannel(channelId) {
 		conversationType = wkdb.ConversationTypeCMD
+	} else if channelType == wkproto.ChannelTypeCommunityTopic {
+		conversationType = wkdb.ConversationTypeTopic
 	} else {
 		conversationType = wkdb.ConversationTypeChat
 	}
@@ -520,6 +603,8 @@ type channelConversation struct {
 	ChannelType      uint8                 `json:"channel_type"`
 	ReadedMsgSeq     uint32                `json:"readed_msg_seq"`
 	NeedUpdate       bool                  `json:"need_update"`
+	MentionMsgSeq    uint32                `json:"mention_msg_seq"` // 最近一条@此用户的消息序号
+	MentionAll       bool                  `json:"mention_all"`     // 最近一条@是否是@所有人
 	ConversationType wkdb.ConversationType `json:"conversation_type"`
 	CreatedAt        time.Time             `json:"created_at"`
 	UpdatedAt        time.Time             `json:"updated_at"`
@@ -38,4 +38,64 @@ func TestConversationGet(t *testing.T) {
 	assert.Equal(t, uint64(102), conversations1[0].ReadToMsgSeq)
 	assert.Equal(t, uint64(0), conversations2[0].ReadToMsgSeq)
 
+	// 删除用户的缓存后不影响其他用户
+	s.conversationManager.DeleteUserFromCache("u1")
+	assert.Equal(t, 0, len(s.conversationManager.GetUserConversationFromCache("u1", wkdb.ConversationTypeChat)))
+	assert.Equal(t, 1, len(s.conversationManager.GetUserConversationFromCache("u2", wkdb.ConversationTypeChat)))
+
+}
+
+func TestConversationMention(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+	err = s.conversationManager.Start()
+	assert.NoError(t, err)
+	defer s.conversationManager.Stop()
+
+	s.conversationManager.Push("g1", wkproto.ChannelTypeGroup, []string{"u1", "u2", "u3"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u1",
+			MessageSeq: 10,
+			SendPacket: &wkproto.SendPacket{Payload: []byte(`{"type":1,"content":"@u2 @u4","mention":{"uids":["u2","u4"]}}`)},
+		},
+		{
+			FromUid:    "u1",
+			MessageSeq: 11,
+			SendPacket: &wkproto.SendPacket{Payload: []byte(`{"type":1,"content":"hello"}`)},
+		},
+	})
+
+	conversations := s.conversationManager.GetUserConversationFromCache("u2", wkdb.ConversationTypeChat)
+	assert.Equal(t, 1, len(conversations))
+	assert.Equal(t, uint64(10), conversations[0].MentionMsgSeq)
+	assert.False(t, conversations[0].MentionAll)
+
+	// 没有被@的用户和发送者不记录
+	conversations = s.conversationManager.GetUserConversationFromCache("u3", wkdb.ConversationTypeChat)
+	assert.Equal(t, uint64(0), conversations[0].MentionMsgSeq)
+	conversations = s.conversationManager.GetUserConversationFromCache("u1", wkdb.ConversationTypeChat)
+	assert.Equal(t, uint64(0), conversations[0].MentionMsgSeq)
+
+	// 不是订阅者的用户不记录
+	assert.Nil(t, s.conversationManager.GetUserConversationFromCache("u4", wkdb.ConversationTypeChat))
+
+	s.conversationManager.Push("g1", wkproto.ChannelTypeGroup, []string{"u1", "u2", "u3"}, []ReactorChannelMessage{
+		{
+			FromUid:    "u2",
+			MessageSeq: 12,
+			SendPacket: &wkproto.SendPacket{Payload: []byte(`{"type":1,"content":"@all","mention":{"all":1}}`)},
+		},
+	})
+
+	// u2发送了消息，已读过之前的@
+	conversations = s.conversationManager.GetUserConversationFromCache("u2", wkdb.ConversationTypeChat)
+	assert.Equal(t, uint64(0), conversations[0].MentionMsgSeq)
+
+	conversations = s.conversationManager.GetUserConversationFromCache("u3", wkdb.ConversationTypeChat)
+	assert.Equal(t, uint64(12), conversations[0].MentionMsgSeq)
+	assert.True(t, conversations[0].MentionAll)
 }
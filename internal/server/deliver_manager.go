@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
@@ -22,6 +23,8 @@ type deliverManager struct {
 	nextDeliverIndex int // 下一个投递者索引
 
 	nodeManager *nodeManager // 节点管理
+
+	pending atomic.Int64 // 已提交但还未投递完成的请求数量
 }
 
 func newDeliverManager(s *Server) *deliverManager {
@@ -68,15 +71,22 @@ func (d *deliverManager) handleDeliver(req *deliverReq) {
 			return
 		}
 		deliver := d.nextDeliver()
+		d.pending.Add(1)
 		select {
 		case deliver.reqC <- req:
 			return
 		default:
+			d.pending.Add(-1)
 			retry++
 		}
 	}
 }
 
+// pendingCount 还未投递完成的请求数量
+func (d *deliverManager) pendingCount() int64 {
+	return d.pending.Load()
+}
+
 func (d *deliverManager) nextDeliver() *deliverr {
 	i := d.nextDeliverIndex % len(d.deliverrs)
 	d.nextDeliverIndex++
@@ -137,6 +147,7 @@ func (d *deliverr) loop() {
 func (d *deliverr) handleDeliverReqs(req []*deliverReq) {
 	for _, r := range req {
 		d.handleDeliverReq(r)
+		d.dm.pending.Add(-1)
 	}
 }
 
@@ -393,6 +404,9 @@ func (d *deliverr) deliver(req *deliverReq, uids []string) {
 
 // 加密消息
 func encryptMessagePayload(payload []byte, conn *connContext) ([]byte, error) {
+	if conn.cipherSession != nil {
+		return conn.cipherSession.Encrypt(payload)
+	}
 	aesKey, aesIV := conn.aesKey, conn.aesIV
 	// 加密payload
 	payloadEnc, err := wkutil.AesEncryptPkcs7Base64(payload, []byte(aesKey), []byte(aesIV))
@@ -403,6 +417,9 @@ func encryptMessagePayload(payload []byte, conn *connContext) ([]byte, error) {
 }
 
 func makeMsgKey(signStr string, conn *connContext) (string, error) {
+	if conn.cipherSession != nil {
+		return conn.cipherSession.Sign(signStr), nil
+	}
 	aesKey, aesIV := conn.aesKey, conn.aesIV
 	// 生成MsgKey
 	msgKeyBytes, err := wkutil.AesEncryptPkcs7Base64([]byte(signStr), []byte(aesKey), []byte(aesIV))
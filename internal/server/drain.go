@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cluster "github.com/WuKongIM/WuKongIM/pkg/cluster/clusterserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wknet"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const (
+	drainStatusNone     = "none"     // 未下线
+	drainStatusDraining = "draining" // 下线中
+	drainStatusDrained  = "drained"  // 已下线，可以安全停止
+)
+
+// DrainStatus 节点下线状态
+type DrainStatus struct {
+	Status            string   `json:"status"`                // 下线状态 none: 未下线 draining: 下线中 drained: 已下线
+	StartedAt         int64    `json:"started_at"`            // 开始时间（unix秒）
+	FinishedAt        int64    `json:"finished_at,omitempty"` // 完成时间（unix秒）
+	SlotLeaders       int      `json:"slot_leaders"`          // 未转移出去的槽领导数量
+	ChannelLeaders    int      `json:"channel_leaders"`       // 未转移出去的频道领导数量
+	DisconnectedConns int      `json:"disconnected_conns"`    // 已断开的客户端连接数量
+	PendingDeliveries int64    `json:"pending_deliveries"`    // 未投递完成的消息请求数量
+	Errors            []string `json:"errors,omitempty"`      // 下线过程中的错误（超时等）
+}
+
+// drainManager 节点下线管理（滚动升级时使用）
+// 下线流程：拒绝新连接 -> 转移槽和频道领导 -> 分批断开客户端连接（告知建议重连的节点）-> 等待消息投递完成 -> 保存最近会话
+type drainManager struct {
+	s *Server
+	wklog.Log
+
+	draining atomic.Bool
+
+	mu     sync.Mutex
+	status DrainStatus
+	routes []*nodeRoute // 建议客户端重连的其他节点
+	doneC  chan struct{}
+}
+
+func newDrainManager(s *Server) *drainManager {
+	return &drainManager{
+		s:      s,
+		Log:    wklog.NewWKLog("drainManager"),
+		status: DrainStatus{Status: drainStatusNone},
+	}
+}
+
+// isDraining 是否已开始下线（下线开始后不再接受新连接）
+func (d *drainManager) isDraining() bool {
+	return d.draining.Load()
+}
+
+// drain 开始下线（重复调用只会执行一次），等待下线完成或ctx结束后返回当前的下线状态
+func (d *drainManager) drain(ctx context.Context) DrainStatus {
+	d.mu.Lock()
+	if d.doneC == nil {
+		d.doneC = make(chan struct{})
+		d.status = DrainStatus{
+			Status:    drainStatusDraining,
+			StartedAt: time.Now().Unix(),
+		}
+		d.draining.Store(true)
+		go d.run()
+	}
+	doneC := d.doneC
+	d.mu.Unlock()
+
+	select {
+	case <-doneC:
+	case <-ctx.Done():
+	}
+	return d.getStatus()
+}
+
+// getStatus 获取下线状态
+func (d *drainManager) getStatus() DrainStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	status := d.status
+	status.PendingDeliveries = d.s.deliverManager.pendingCount()
+	return status
+}
+
+// alternateRoute 获取一个建议客户端重连的节点，没有可用节点返回nil
+func (d *drainManager) alternateRoute(i int) *nodeRoute {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.routes) == 0 {
+		return nil
+	}
+	return d.routes[i%len(d.routes)]
+}
+
+func (d *drainManager) run() {
+	d.Info("node draining...")
+
+	timeoutCtx, cancel := context.WithTimeout(d.s.ctx, d.s.opts.Drain.Timeout)
+	defer cancel()
+
+	// 转移槽和频道的领导
+	if d.s.opts.ClusterOn() {
+		state, err := d.s.clusterServer.DrainLeaders(timeoutCtx)
+		d.mu.Lock()
+		d.status.SlotLeaders = state.SlotLeaders
+		d.status.ChannelLeaders = state.ChannelLeaders
+		d.mu.Unlock()
+		if err != nil {
+			if errors.Is(err, cluster.ErrNoAllowVoteNode) {
+				d.Warn("no other online node, skip transferring leaders")
+			} else {
+				d.Warn("drain leaders failed", zap.Error(err))
+				d.addError(fmt.Sprintf("drain leaders: %v", err))
+			}
+		}
+
+		routes := d.s.routeManager.otherRoutes()
+		d.mu.Lock()
+		d.routes = routes
+		d.mu.Unlock()
+	}
+
+	// 分批断开客户端连接
+	disconnected := d.disconnectConns(timeoutCtx)
+	d.mu.Lock()
+	d.status.DisconnectedConns = disconnected
+	d.mu.Unlock()
+
+	// 等待已经提交的消息投递完成
+	if err := d.waitDeliveries(timeoutCtx); err != nil {
+		d.Warn("wait deliveries failed", zap.Error(err), zap.Int64("pending", d.s.deliverManager.pendingCount()))
+		d.addError(fmt.Sprintf("wait deliveries: %v", err))
+	}
+
+	// 保存最近会话
+	d.s.conversationManager.Flush()
+
+	d.mu.Lock()
+	d.status.Status = drainStatusDrained
+	d.status.FinishedAt = time.Now().Unix()
+	close(d.doneC)
+	d.mu.Unlock()
+
+	d.Info("node drained")
+}
+
+func (d *drainManager) addError(err string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.Errors = append(d.status.Errors, err)
+}
+
+// disconnectConns 在DisconnectWindow内分批断开所有客户端连接，避免客户端同时重连
+// 断开前发送DisconnectPacket，Reason里带有建议重连的节点地址，ctx结束后剩余的连接立即断开
+func (d *drainManager) disconnectConns(ctx context.Context) int {
+	conns := d.s.engine.GetAllConn()
+	if len(conns) == 0 {
+		return 0
+	}
+	interval := time.Millisecond * 100
+	batchCount := int(d.s.opts.Drain.DisconnectWindow / interval)
+	if batchCount < 1 {
+		batchCount = 1
+	}
+	batchSize := (len(conns) + batchCount - 1) / batchCount
+
+	d.Info("disconnect conns", zap.Int("count", len(conns)), zap.Int("batchSize", batchSize))
+
+	for i, conn := range conns {
+		d.disconnect(conn, d.alternateRoute(i))
+		if (i+1)%batchSize != 0 || i+1 == len(conns) || ctx.Err() != nil {
+			continue
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+		}
+	}
+	return len(conns)
+}
+
+func (d *drainManager) disconnect(conn wknet.Conn, route *nodeRoute) {
+	connCtx, ok := conn.Context().(*connContext)
+	if !ok || connCtx == nil { // 还没认证的连接直接关闭
+		_ = conn.Close()
+		return
+	}
+	reason := ""
+	if route != nil {
+		reason = wkutil.ToJSON(route)
+	}
+	_ = d.s.userReactor.writePacket(connCtx, &wkproto.DisconnectPacket{
+		ReasonCode: ReasonNodeDraining,
+		Reason:     reason,
+	})
+	d.s.timingWheel.AfterFunc(time.Second*2, func() {
+		connCtx.close()
+	})
+}
+
+// waitDeliveries 等待已经提交的消息投递完成
+func (d *drainManager) waitDeliveries(ctx context.Context) error {
+	tk := time.NewTicker(time.Millisecond * 100)
+	defer tk.Stop()
+	for d.s.deliverManager.pendingCount() > 0 {
+		select {
+		case <-tk.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainManager(t *testing.T) {
+	s := NewTestServer(t)
+	s.opts.Cluster.NodeId = 0 // 不转移领导，只测试下线流程
+	d := s.drainManager
+
+	assert.False(t, d.isDraining())
+	assert.Equal(t, drainStatusNone, d.getStatus().Status)
+
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	status := d.drain(timeoutCtx)
+	assert.True(t, d.isDraining())
+	assert.Equal(t, drainStatusDrained, status.Status)
+	assert.Equal(t, 0, status.DisconnectedConns)
+	assert.Equal(t, int64(0), status.PendingDeliveries)
+	assert.Empty(t, status.Errors)
+	assert.NotZero(t, status.FinishedAt)
+
+	// 重复下线直接返回之前的结果
+	again := d.drain(timeoutCtx)
+	assert.Equal(t, status, again)
+}
+
+func TestDrainAlternateRoute(t *testing.T) {
+	d := &drainManager{}
+	assert.Nil(t, d.alternateRoute(0))
+
+	d.routes = []*nodeRoute{
+		{NodeId: 1, TCPAddr: "127.0.0.1:5100"},
+		{NodeId: 2, TCPAddr: "127.0.0.1:5200"},
+	}
+	assert.Equal(t, uint64(1), d.alternateRoute(0).NodeId)
+	assert.Equal(t, uint64(2), d.alternateRoute(1).NodeId)
+	assert.Equal(t, uint64(1), d.alternateRoute(2).NodeId)
+}
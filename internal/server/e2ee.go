@@ -0,0 +1,244 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/keylock"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const e2eeKeysChangedCMD = "e2eeKeysChanged"
+
+// 设备公钥变化的原因
+const (
+	e2eeKeysChangedReasonDeviceAdded     = "device_added"     // 新增设备
+	e2eeKeysChangedReasonIdentityChanged = "identity_changed" // 设备的身份公钥变化（例如重装）
+	e2eeKeysChangedReasonDeviceRemoved   = "device_removed"   // 设备被删除
+)
+
+// e2eeManager 端到端加密的密钥分发（Signal协议的身份公钥、签名预共享公钥和一次性预共享公钥）
+// 服务端只保存和分发设备的公钥，私钥只保存在客户端，消息内容由客户端加密，服务端无法解密
+// 设备公钥与用户存放在同一个槽内，所有操作都在用户所在槽的领导节点执行
+type e2eeManager struct {
+	s *Server
+	wklog.Log
+	claimLock  *keylock.KeyLock // 领取一次性预共享公钥的锁，保证同一个公钥只会被领取一次
+	messageAPI *MessageAPI
+}
+
+func newE2EEManager(s *Server) *e2eeManager {
+	return &e2eeManager{
+		s:          s,
+		Log:        wklog.NewWKLog("e2eeManager"),
+		claimLock:  keylock.NewKeyLock(),
+		messageAPI: NewMessageAPI(s),
+	}
+}
+
+func (e *e2eeManager) start() error {
+	e.claimLock.StartCleanLoop()
+	return nil
+}
+
+func (e *e2eeManager) stop() {
+	e.claimLock.StopCleanLoop()
+}
+
+// e2eeKeyBundle 发起会话需要的设备公钥，一次性预共享公钥用完后为空
+type e2eeKeyBundle struct {
+	Uid           string           `json:"uid"`
+	DeviceId      string           `json:"device_id"`
+	IdentityKey   []byte           `json:"identity_key"`
+	SignedPrekey  wkdb.E2EEPrekey  `json:"signed_prekey"`
+	OneTimePrekey *wkdb.E2EEPrekey `json:"one_time_prekey,omitempty"`
+	RemainPrekeys int              `json:"remain_prekeys"` // 剩余的一次性预共享公钥数量
+	UpdatedAt     int64            `json:"updated_at"`
+}
+
+// upload 上传设备的公钥，身份公钥变化时旧的一次性预共享公钥会被删除
+func (e *e2eeManager) upload(keys wkdb.E2EEDeviceKeys, oneTimePrekeys []wkdb.E2EEPrekey) error {
+	old, err := e.s.store.GetE2EEDeviceKey(keys.Uid, keys.DeviceId)
+	if err != nil {
+		return err
+	}
+	reason := ""
+	if wkdb.IsEmptyE2EEDeviceKeys(old) {
+		devices, err := e.s.store.GetE2EEDeviceKeys(keys.Uid)
+		if err != nil {
+			return err
+		}
+		if len(devices) >= e.s.opts.E2EE.MaxDevices {
+			return fmt.Errorf("上传公钥的设备数量不能超过%d个！", e.s.opts.E2EE.MaxDevices)
+		}
+		reason = e2eeKeysChangedReasonDeviceAdded
+	} else if string(old.IdentityKey) != string(keys.IdentityKey) {
+		reason = e2eeKeysChangedReasonIdentityChanged
+	}
+
+	count := 0
+	if reason == "" { // 身份公钥没变时保留已有的一次性预共享公钥
+		if count, err = e.s.store.GetE2EEOneTimePrekeyCount(keys.Uid, keys.DeviceId); err != nil {
+			return err
+		}
+	}
+	if count+len(oneTimePrekeys) > e.s.opts.E2EE.MaxOneTimePrekeys {
+		return fmt.Errorf("一次性预共享公钥的数量不能超过%d个！", e.s.opts.E2EE.MaxOneTimePrekeys)
+	}
+
+	now := time.Now()
+	keys.CreatedAt = &now
+	keys.UpdatedAt = &now
+	if err = e.s.store.AddOrUpdateE2EEDeviceKeys(keys, oneTimePrekeys); err != nil {
+		return err
+	}
+	if reason != "" {
+		e.notifyKeysChanged(keys.Uid, keys.DeviceId, reason)
+	}
+	return nil
+}
+
+// addOneTimePrekeys 补充设备的一次性预共享公钥
+func (e *e2eeManager) addOneTimePrekeys(uid string, deviceId string, prekeys []wkdb.E2EEPrekey) error {
+	keys, err := e.s.store.GetE2EEDeviceKey(uid, deviceId)
+	if err != nil {
+		return err
+	}
+	if wkdb.IsEmptyE2EEDeviceKeys(keys) {
+		return errors.New("设备的公钥不存在，请先上传！")
+	}
+	count, err := e.s.store.GetE2EEOneTimePrekeyCount(uid, deviceId)
+	if err != nil {
+		return err
+	}
+	if count+len(prekeys) > e.s.opts.E2EE.MaxOneTimePrekeys {
+		return fmt.Errorf("一次性预共享公钥的数量不能超过%d个！", e.s.opts.E2EE.MaxOneTimePrekeys)
+	}
+	return e.s.store.AddE2EEOneTimePrekeys(uid, deviceId, prekeys)
+}
+
+// claim 领取用户设备的公钥，每个设备领取一个一次性预共享公钥，领取后从服务端删除
+// deviceIds为空时领取用户所有设备的公钥
+func (e *e2eeManager) claim(uid string, deviceIds []string) ([]e2eeKeyBundle, error) {
+	e.claimLock.Lock(uid)
+	defer e.claimLock.Unlock(uid)
+
+	devices, err := e.s.store.GetE2EEDeviceKeys(uid)
+	if err != nil {
+		return nil, err
+	}
+	bundles := make([]e2eeKeyBundle, 0, len(devices))
+	for _, device := range devices {
+		if len(deviceIds) > 0 && !wkutil.ArrayContains(deviceIds, device.DeviceId) {
+			continue
+		}
+		bundle := newE2EEKeyBundle(device)
+		prekeys, err := e.s.store.GetE2EEOneTimePrekeys(uid, device.DeviceId, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(prekeys) > 0 {
+			count, err := e.s.store.GetE2EEOneTimePrekeyCount(uid, device.DeviceId)
+			if err != nil {
+				return nil, err
+			}
+			if err = e.s.store.RemoveE2EEOneTimePrekeys(uid, device.DeviceId, []uint32{prekeys[0].KeyId}); err != nil {
+				return nil, err
+			}
+			bundle.OneTimePrekey = &prekeys[0]
+			bundle.RemainPrekeys = count - 1
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
+// delete 删除设备的公钥（例如设备退出登录）
+func (e *e2eeManager) delete(uid string, deviceId string) error {
+	keys, err := e.s.store.GetE2EEDeviceKey(uid, deviceId)
+	if err != nil {
+		return err
+	}
+	if wkdb.IsEmptyE2EEDeviceKeys(keys) {
+		return nil
+	}
+	if err = e.s.store.DeleteE2EEDeviceKeys(uid, deviceId); err != nil {
+		return err
+	}
+	e.notifyKeysChanged(uid, deviceId, e2eeKeysChangedReasonDeviceRemoved)
+	return nil
+}
+
+func newE2EEKeyBundle(k wkdb.E2EEDeviceKeys) e2eeKeyBundle {
+	bundle := e2eeKeyBundle{
+		Uid:          k.Uid,
+		DeviceId:     k.DeviceId,
+		IdentityKey:  k.IdentityKey,
+		SignedPrekey: k.SignedPrekey,
+	}
+	if k.UpdatedAt != nil {
+		bundle.UpdatedAt = k.UpdatedAt.Unix()
+	}
+	return bundle
+}
+
+// notifyKeysChanged 以系统账号给用户自己、单聊的联系人和所在的群发送cmd消息，通知客户端重新获取用户设备的公钥
+// 联系人和群从用户的最近会话中获取（最近会话与用户存放在同一个槽内）
+func (e *e2eeManager) notifyKeysChanged(uid string, deviceId string, reason string) {
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"cmd": e2eeKeysChangedCMD,
+		"data": map[string]interface{}{
+			"uid":       uid,
+			"device_id": deviceId,
+			"reason":    reason,
+		},
+	}))
+	go func() {
+		conversations, err := e.s.store.GetConversations(uid)
+		if err != nil {
+			e.Error("notifyKeysChanged: get conversations failed", zap.Error(err), zap.String("uid", uid))
+			return
+		}
+		e.sendNotify(uid, wkproto.ChannelTypePerson, payload)
+		for _, conversation := range conversations {
+			channelId := conversation.ChannelId
+			switch conversation.ChannelType {
+			case wkproto.ChannelTypePerson:
+				from, to := GetFromUIDAndToUIDWith(conversation.ChannelId)
+				if from == uid {
+					channelId = to
+				} else {
+					channelId = from
+				}
+				if channelId == "" || channelId == uid || channelId == e.s.opts.SystemUID {
+					continue
+				}
+			case wkproto.ChannelTypeGroup:
+			default:
+				continue
+			}
+			e.sendNotify(channelId, conversation.ChannelType, payload)
+		}
+	}()
+}
+
+func (e *e2eeManager) sendNotify(channelId string, channelType uint8, payload []byte) {
+	req := MessageSendReq{
+		Header: MessageHeader{
+			SyncOnce: 1,
+		},
+		FromUID:     e.s.opts.SystemUID,
+		ChannelID:   channelId,
+		ChannelType: channelType,
+		Payload:     payload,
+	}
+	_, err := e.messageAPI.sendMessageToChannel(req, channelId, channelType, wkutil.GenUUID(), wkproto.StreamFlagIng)
+	if err != nil {
+		e.Warn("notifyKeysChanged: send cmd message failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+	}
+}
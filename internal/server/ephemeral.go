@@ -0,0 +1,455 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+)
+
+// ephemeralManager 瞬时信号管理（比如"正在输入"）
+// 瞬时信号不走频道的消息流程（不存储、不更新最近会话、不重试、不触发离线webhook），
+// 由频道领导节点验证权限后，通过接收者tag只投递给当前在线的订阅者
+type ephemeralManager struct {
+	s *Server
+	wklog.Log
+
+	sendC    chan *ephemeralSendReq // 客户端发送的信号
+	deliverC chan *ephemeralDeliver // 需要投递给本节点用户的信号
+
+	coalesceMu sync.Mutex
+	coalesces  map[string]time.Time // 合并窗口内已发送过的信号
+
+	cleanTimer *timingwheel.Timer
+	stopper    *syncutil.Stopper
+}
+
+func newEphemeralManager(s *Server) *ephemeralManager {
+	return &ephemeralManager{
+		s:         s,
+		Log:       wklog.NewWKLog("ephemeralManager"),
+		sendC:     make(chan *ephemeralSendReq, 1024),
+		deliverC:  make(chan *ephemeralDeliver, 1024),
+		coalesces: make(map[string]time.Time),
+		stopper:   syncutil.NewStopper(),
+	}
+}
+
+func (e *ephemeralManager) start() error {
+	for i := 0; i < e.s.opts.Ephemeral.WorkerCount; i++ {
+		e.stopper.RunWorker(e.loop)
+	}
+	e.cleanTimer = e.s.Schedule(time.Minute, e.cleanCoalesces)
+	return nil
+}
+
+func (e *ephemeralManager) stop() {
+	if e.cleanTimer != nil {
+		e.cleanTimer.Stop()
+	}
+	e.stopper.Stop()
+}
+
+func (e *ephemeralManager) loop() {
+	for {
+		select {
+		case req := <-e.sendC:
+			e.handleSendReq(req)
+		case d := <-e.deliverC:
+			e.deliverLocal(d.signal, d.uids)
+		case <-e.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// addSendPacket 客户端发送的瞬时信号（发送包设置了SettingEphemeral）
+func (e *ephemeralManager) addSendPacket(conn *connContext, packet *wkproto.SendPacket) {
+	conn.keepActivity()
+
+	select {
+	case e.sendC <- &ephemeralSendReq{conn: conn, packet: packet}:
+	default:
+		e.Warn("sendC is full, ignore ephemeral signal", zap.String("uid", conn.uid), zap.String("channelId", packet.ChannelID))
+		e.writeSendack(conn, packet, 0, wkproto.ReasonRateLimit)
+	}
+}
+
+func (e *ephemeralManager) handleSendReq(req *ephemeralSendReq) {
+	conn, packet := req.conn, req.packet
+
+	reasonCode, messageId := e.send(conn, packet)
+	e.writeSendack(conn, packet, messageId, reasonCode)
+}
+
+func (e *ephemeralManager) send(conn *connContext, packet *wkproto.SendPacket) (wkproto.ReasonCode, int64) {
+	if IsSpecialCharOfChannel(packet.ChannelID, packet.ChannelType) || packet.ChannelID == "" {
+		return wkproto.ReasonChannelIDError, 0
+	}
+
+	payload, err := e.s.checkAndDecodePayload(packet, conn)
+	if err != nil {
+		e.Warn("decrypt ephemeral payload error", zap.String("uid", conn.uid), zap.String("deviceId", conn.deviceId), zap.Error(err))
+		return wkproto.ReasonPayloadDecodeError, 0
+	}
+
+	fakeChannelId := packet.ChannelID
+	if packet.ChannelType == wkproto.ChannelTypePerson {
+		fakeChannelId = GetFakeChannelIDWith(packet.ChannelID, conn.uid)
+	}
+
+	// 合并窗口内相同的信号只发送一次
+	coalesceKey := fmt.Sprintf("%s:%s:%s:%s", conn.uid, conn.deviceId, wkutil.ChannelToKey(fakeChannelId, packet.ChannelType), wkutil.MD5(string(payload)))
+	if e.coalesced(coalesceKey) {
+		return wkproto.ReasonSuccess, 0
+	}
+
+	signal := &ephemeralSignal{
+		fromUid:      conn.uid,
+		fromDeviceId: conn.deviceId,
+		channelId:    fakeChannelId,
+		channelType:  packet.ChannelType,
+		setting:      packet.Setting,
+		clientMsgNo:  packet.ClientMsgNo,
+		topic:        packet.Topic,
+		messageId:    e.s.channelReactor.messageIDGen.Generate().Int64(),
+		payload:      payload,
+	}
+
+	leader, err := e.s.cluster.LeaderOfChannelForRead(signal.channelId, signal.channelType)
+	if err != nil {
+		e.Error("get leader of channel failed", zap.Error(err), zap.String("channelId", signal.channelId), zap.Uint8("channelType", signal.channelType))
+		return wkproto.ReasonSystemError, 0
+	}
+	var reasonCode wkproto.ReasonCode
+	if leader.Id == e.s.opts.Cluster.NodeId {
+		reasonCode = e.handleSignal(signal)
+	} else {
+		reasonCode, err = e.requestSignal(leader.Id, signal)
+		if err != nil {
+			e.Error("request ephemeral signal failed", zap.Error(err), zap.Uint64("leaderId", leader.Id), zap.String("channelId", signal.channelId))
+			return wkproto.ReasonSystemError, 0
+		}
+	}
+	if reasonCode == wkproto.ReasonSuccess {
+		e.markCoalesce(coalesceKey)
+	}
+	return reasonCode, signal.messageId
+}
+
+func (e *ephemeralManager) writeSendack(conn *connContext, packet *wkproto.SendPacket, messageId int64, reasonCode wkproto.ReasonCode) {
+	err := conn.writePacket(&wkproto.SendackPacket{
+		Framer:      packet.Framer,
+		MessageID:   messageId,
+		ClientSeq:   packet.ClientSeq,
+		ClientMsgNo: packet.ClientMsgNo,
+		ReasonCode:  reasonCode,
+	})
+	if err != nil {
+		e.Error("write ephemeral sendack failed", zap.Error(err), zap.String("uid", conn.uid))
+	}
+}
+
+func (e *ephemeralManager) coalesced(key string) bool {
+	e.coalesceMu.Lock()
+	defer e.coalesceMu.Unlock()
+	last, ok := e.coalesces[key]
+	return ok && time.Since(last) < e.s.opts.GetEphemeralCoalesceInterval()
+}
+
+func (e *ephemeralManager) markCoalesce(key string) {
+	e.coalesceMu.Lock()
+	defer e.coalesceMu.Unlock()
+	e.coalesces[key] = time.Now()
+}
+
+func (e *ephemeralManager) cleanCoalesces() {
+	interval := e.s.opts.GetEphemeralCoalesceInterval()
+	e.coalesceMu.Lock()
+	defer e.coalesceMu.Unlock()
+	for key, last := range e.coalesces {
+		if time.Since(last) >= interval {
+			delete(e.coalesces, key)
+		}
+	}
+}
+
+// 请求频道领导节点处理信号
+func (e *ephemeralManager) requestSignal(nodeId uint64, signal *ephemeralSignal) (wkproto.ReasonCode, error) {
+	timeoutCtx, cancel := context.WithTimeout(e.s.ctx, e.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := e.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/ephemeral", signal.Marshal())
+	if err != nil {
+		return wkproto.ReasonSystemError, err
+	}
+	if resp.Status != proto.Status_OK {
+		return wkproto.ReasonSystemError, errors.New(string(resp.Body))
+	}
+	if len(resp.Body) == 0 {
+		return wkproto.ReasonSystemError, errors.New("ephemeral resp is empty")
+	}
+	return wkproto.ReasonCode(resp.Body[0]), nil
+}
+
+// handleSignalReq 处理其他节点转发过来的信号（本节点为频道领导）
+func (e *ephemeralManager) handleSignalReq(c *wkserver.Context) {
+	signal := &ephemeralSignal{}
+	if err := signal.Unmarshal(c.Body()); err != nil {
+		e.Error("handleSignalReq: unmarshal failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	reasonCode := e.handleSignal(signal)
+	c.Write([]byte{reasonCode.Byte()})
+}
+
+// handleSignal 在频道领导节点上验证权限并按接收者tag分发给各节点
+func (e *ephemeralManager) handleSignal(signal *ephemeralSignal) wkproto.ReasonCode {
+	var channelInfo wkdb.ChannelInfo
+	if signal.channelType != wkproto.ChannelTypePerson {
+		var err error
+		channelInfo, err = e.s.store.GetChannel(signal.channelId, signal.channelType)
+		if err != nil && err != wkdb.ErrNotFound {
+			e.Error("handleSignal: get channel info failed", zap.Error(err), zap.String("channelId", signal.channelId), zap.Uint8("channelType", signal.channelType))
+			return wkproto.ReasonSystemError
+		}
+	}
+	reasonCode, err := e.s.channelReactor.hasPermission(signal.channelId, signal.channelType, signal.fromUid, channelInfo)
+	if err != nil {
+		e.Error("handleSignal: hasPermission error", zap.Error(err), zap.String("channelId", signal.channelId), zap.Uint8("channelType", signal.channelType))
+		return wkproto.ReasonSystemError
+	}
+	if reasonCode != wkproto.ReasonSuccess {
+		return reasonCode
+	}
+
+	ch := e.s.channelReactor.loadOrCreateChannel(signal.channelId, signal.channelType)
+	tg := e.s.tagManager.getReceiverTag(ch.receiverTagKey.Load())
+	if tg == nil {
+		tg, err = ch.makeReceiverTag()
+		if err != nil {
+			e.Error("handleSignal: makeReceiverTag failed", zap.Error(err), zap.String("channelId", signal.channelId), zap.Uint8("channelType", signal.channelType))
+			return wkproto.ReasonSystemError
+		}
+	}
+
+	for _, nodeUser := range tg.users {
+		if nodeUser.nodeId == e.s.opts.Cluster.NodeId {
+			e.addDeliver(&ephemeralDeliver{signal: signal, uids: nodeUser.uids})
+			continue
+		}
+		deliver := &ephemeralDeliver{signal: signal, uids: nodeUser.uids}
+		err = e.s.cluster.Send(nodeUser.nodeId, &proto.Message{
+			MsgType: uint32(ClusterMsgTypeEphemeral),
+			Content: deliver.Marshal(),
+		})
+		if err != nil {
+			e.Warn("handleSignal: send to node failed", zap.Error(err), zap.Uint64("nodeId", nodeUser.nodeId), zap.String("channelId", signal.channelId))
+		}
+	}
+	return wkproto.ReasonSuccess
+}
+
+// handleEphemeralMessage 处理频道领导节点分发过来的信号
+func (e *ephemeralManager) handleEphemeralMessage(msg *proto.Message) {
+	deliver := &ephemeralDeliver{}
+	if err := deliver.Unmarshal(msg.Content); err != nil {
+		e.Error("handleEphemeralMessage: unmarshal failed", zap.Error(err))
+		return
+	}
+	e.addDeliver(deliver)
+}
+
+func (e *ephemeralManager) addDeliver(d *ephemeralDeliver) {
+	select {
+	case e.deliverC <- d:
+	default:
+		// 瞬时信号允许丢弃
+		e.Warn("deliverC is full, drop ephemeral signal", zap.String("channelId", d.signal.channelId), zap.Uint8("channelType", d.signal.channelType))
+	}
+}
+
+// deliverLocal 投递给本节点上在线的用户
+func (e *ephemeralManager) deliverLocal(signal *ephemeralSignal, uids []string) {
+	for _, uid := range uids {
+		userHandler := e.s.userReactor.getUser(uid)
+		if userHandler == nil { // 不在线的用户直接忽略
+			continue
+		}
+		for _, conn := range userHandler.getConns() {
+			if conn.uid == signal.fromUid && conn.deviceId == signal.fromDeviceId { // 自己发的不处理
+				continue
+			}
+			e.writeRecvPacket(conn, signal)
+		}
+	}
+}
+
+func (e *ephemeralManager) writeRecvPacket(conn *connContext, signal *ephemeralSignal) {
+	recvPacket := &wkproto.RecvPacket{
+		Framer: wkproto.Framer{
+			NoPersist: true,
+		},
+		Setting:     signal.setting,
+		MessageID:   signal.messageId,
+		ClientMsgNo: signal.clientMsgNo,
+		FromUID:     signal.fromUid,
+		ChannelID:   signal.channelId,
+		ChannelType: signal.channelType,
+		Topic:       signal.topic,
+		Timestamp:   int32(time.Now().Unix()),
+		Payload:     signal.payload,
+	}
+	// 个人频道：接收者看到的频道为对方，发送者的其他设备看到的频道为接收者
+	if recvPacket.ChannelType == wkproto.ChannelTypePerson {
+		uid1, uid2 := GetFromUIDAndToUIDWith(signal.channelId)
+		toUid := uid1
+		if uid1 == signal.fromUid {
+			toUid = uid2
+		}
+		recvPacket.ChannelID = toUid
+		if recvPacket.ChannelID == conn.uid {
+			recvPacket.ChannelID = signal.fromUid
+		}
+	}
+
+	payloadEnc, err := encryptMessagePayload(recvPacket.Payload, conn)
+	if err != nil {
+		e.Error("加密payload失败！", zap.Error(err))
+		return
+	}
+	recvPacket.Payload = payloadEnc
+	msgKey, err := makeMsgKey(recvPacket.VerityString(), conn)
+	if err != nil {
+		e.Error("生成MsgKey失败！", zap.Error(err))
+		return
+	}
+	recvPacket.MsgKey = msgKey
+	if err = conn.writePacket(recvPacket); err != nil {
+		e.Warn("write ephemeral recvPacket failed", zap.Error(err), zap.String("uid", conn.uid), zap.String("channelId", recvPacket.ChannelID))
+	}
+}
+
+type ephemeralSendReq struct {
+	conn   *connContext
+	packet *wkproto.SendPacket
+}
+
+// ephemeralSignal 瞬时信号
+type ephemeralSignal struct {
+	fromUid      string
+	fromDeviceId string
+	channelId    string // 频道ID（个人频道为fakeChannelId）
+	channelType  uint8
+	setting      wkproto.Setting
+	clientMsgNo  string
+	topic        string
+	messageId    int64
+	payload      []byte // 已解密的内容
+}
+
+func (s *ephemeralSignal) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	s.marshalWithEncoder(enc)
+	return enc.Bytes()
+}
+
+func (s *ephemeralSignal) marshalWithEncoder(enc *wkproto.Encoder) {
+	enc.WriteString(s.fromUid)
+	enc.WriteString(s.fromDeviceId)
+	enc.WriteString(s.channelId)
+	enc.WriteUint8(s.channelType)
+	enc.WriteUint8(s.setting.Uint8())
+	enc.WriteString(s.clientMsgNo)
+	enc.WriteString(s.topic)
+	enc.WriteInt64(s.messageId)
+	enc.WriteBinary(s.payload)
+}
+
+func (s *ephemeralSignal) Unmarshal(data []byte) error {
+	return s.unmarshalWithDecoder(wkproto.NewDecoder(data))
+}
+
+func (s *ephemeralSignal) unmarshalWithDecoder(dec *wkproto.Decoder) error {
+	var err error
+	if s.fromUid, err = dec.String(); err != nil {
+		return err
+	}
+	if s.fromDeviceId, err = dec.String(); err != nil {
+		return err
+	}
+	if s.channelId, err = dec.String(); err != nil {
+		return err
+	}
+	if s.channelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	var setting uint8
+	if setting, err = dec.Uint8(); err != nil {
+		return err
+	}
+	s.setting = wkproto.Setting(setting)
+	if s.clientMsgNo, err = dec.String(); err != nil {
+		return err
+	}
+	if s.topic, err = dec.String(); err != nil {
+		return err
+	}
+	if s.messageId, err = dec.Int64(); err != nil {
+		return err
+	}
+	if s.payload, err = dec.Binary(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ephemeralDeliver 投递给指定节点上用户的信号
+type ephemeralDeliver struct {
+	signal *ephemeralSignal
+	uids   []string
+}
+
+func (d *ephemeralDeliver) Marshal() []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	d.signal.marshalWithEncoder(enc)
+	enc.WriteUint32(uint32(len(d.uids)))
+	for _, uid := range d.uids {
+		enc.WriteString(uid)
+	}
+	return enc.Bytes()
+}
+
+func (d *ephemeralDeliver) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	d.signal = &ephemeralSignal{}
+	if err := d.signal.unmarshalWithDecoder(dec); err != nil {
+		return err
+	}
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	d.uids = make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var uid string
+		if uid, err = dec.String(); err != nil {
+			return err
+		}
+		d.uids = append(d.uids, uid)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEphemeralDeliverMarshal(t *testing.T) {
+	d := &ephemeralDeliver{
+		signal: &ephemeralSignal{
+			fromUid:      "u1",
+			fromDeviceId: "d1",
+			channelId:    "g1",
+			channelType:  wkproto.ChannelTypeGroup,
+			setting:      SettingEphemeral,
+			clientMsgNo:  "no1",
+			messageId:    100,
+			payload:      []byte(`{"type":"typing"}`),
+		},
+		uids: []string{"u2", "u3"},
+	}
+
+	result := &ephemeralDeliver{}
+	err := result.Unmarshal(d.Marshal())
+	assert.NoError(t, err)
+	assert.Equal(t, d, result)
+}
+
+func TestEphemeralCoalesce(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	s.opts.Ephemeral.CoalesceInterval = time.Millisecond * 50
+	e := newEphemeralManager(s)
+
+	assert.False(t, e.coalesced("k1"))
+	e.markCoalesce("k1")
+	assert.True(t, e.coalesced("k1"))
+	assert.False(t, e.coalesced("k2"))
+
+	time.Sleep(time.Millisecond * 60)
+	assert.False(t, e.coalesced("k1"))
+	e.cleanCoalesces()
+	assert.Empty(t, e.coalesces)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// followerReadHeader 标记请求已经被转发给频道副本，副本没有追上时直接转发给频道领导，不再转发给其他副本
+const followerReadHeader = "X-WK-Follower-Read"
+
+// syncMessagesReadBound 同步频道消息时副本需要满足的读要求
+// knownSeq为客户端已知的最大消息序号，副本已提交的日志下标不能小于它
+// latest表示需要读到频道最新的消息，这时副本还需要追上领导的读下标
+func syncMessagesReadBound(startMessageSeq, endMessageSeq uint64, pullMode PullMode) (knownSeq uint64, latest bool) {
+	if startMessageSeq == 0 && endMessageSeq == 0 {
+		return 0, true
+	}
+	if pullMode == PullModeUp { // 向上拉取，start包含，end不包含
+		if endMessageSeq == 0 {
+			if startMessageSeq > 0 {
+				knownSeq = startMessageSeq - 1
+			}
+			return knownSeq, true
+		}
+		return endMessageSeq - 1, false
+	}
+	// 向下拉取，start为上界（包含）
+	return startMessageSeq, false
+}
+
+// followerReadable 本节点作为频道的追随者副本是否可以提供读
+// 本节点是频道领导时返回false（由原来的领导读流程处理）
+func (s *Server) followerReadable(channelId string, channelType uint8, knownSeq uint64, latest bool) bool {
+	committedIndex, ok := s.cluster.LocalChannelCommittedIndex(channelId, channelType)
+	if !ok || committedIndex < knownSeq {
+		return false
+	}
+	cfg, err := s.cluster.ChannelClusterConfigForRead(channelId, channelType)
+	if err != nil || cfg.LeaderId == s.opts.Cluster.NodeId {
+		return false
+	}
+	if !latest {
+		return true
+	}
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	readIndexes, err := s.cluster.ChannelReadIndexes(timeoutCtx, cfg.LeaderId, []wkdb.Channel{{ChannelId: channelId, ChannelType: channelType}})
+	if err != nil {
+		s.Warn("followerReadable: get channel read index failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("leaderId", cfg.LeaderId))
+		return false
+	}
+	return committedIndex >= readIndexes[0]
+}
+
+// followerReadableBatch 批量判断本节点是否可以作为频道的追随者副本提供最新消息的读，返回可读的频道下标
+// knownSeqs与channels一一对应
+func (s *Server) followerReadableBatch(channels []wkdb.Channel, knownSeqs []uint64) map[int]bool {
+	type readCandidate struct {
+		idx            int
+		committedIndex uint64
+	}
+	leaderCandidates := make(map[uint64][]readCandidate)
+	for i, ch := range channels {
+		committedIndex, ok := s.cluster.LocalChannelCommittedIndex(ch.ChannelId, ch.ChannelType)
+		if !ok || committedIndex < knownSeqs[i] {
+			continue
+		}
+		cfg, err := s.cluster.ChannelClusterConfigForRead(ch.ChannelId, ch.ChannelType)
+		if err != nil || cfg.LeaderId == s.opts.Cluster.NodeId {
+			continue
+		}
+		leaderCandidates[cfg.LeaderId] = append(leaderCandidates[cfg.LeaderId], readCandidate{idx: i, committedIndex: committedIndex})
+	}
+
+	readable := make(map[int]bool)
+	for leaderId, candidates := range leaderCandidates {
+		reqChannels := make([]wkdb.Channel, 0, len(candidates))
+		for _, c := range candidates {
+			reqChannels = append(reqChannels, channels[c.idx])
+		}
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), s.opts.Cluster.ReqTimeout)
+		readIndexes, err := s.cluster.ChannelReadIndexes(timeoutCtx, leaderId, reqChannels)
+		cancel()
+		if err != nil {
+			s.Warn("followerReadableBatch: get channel read indexes failed", zap.Error(err), zap.Uint64("leaderId", leaderId), zap.Int("channels", len(reqChannels)))
+			continue
+		}
+		for i, c := range candidates {
+			if c.committedIndex >= readIndexes[i] {
+				readable[c.idx] = true
+			}
+		}
+	}
+	return readable
+}
+
+// pickFollowerReadNode 本节点不是频道副本时，随机选择一个在线的频道副本（包括领导）提供读
+func (s *Server) pickFollowerReadNode(channelId string, channelType uint8) *pb.Node {
+	cfg, err := s.cluster.ChannelClusterConfigForRead(channelId, channelType)
+	if err != nil {
+		return nil
+	}
+	if wkutil.ArrayContainsUint64(cfg.Replicas, s.opts.Cluster.NodeId) { // 本节点是副本，没有追上时直接读领导
+		return nil
+	}
+	candidates := make([]uint64, 0, len(cfg.Replicas))
+	for _, replicaId := range cfg.Replicas {
+		if wkutil.ArrayContainsUint64(cfg.Learners, replicaId) || !s.cluster.NodeIsOnline(replicaId) {
+			continue
+		}
+		candidates = append(candidates, replicaId)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	nodeInfo, err := s.cluster.NodeInfoById(candidates[rand.Intn(len(candidates))])
+	if err != nil {
+		return nil
+	}
+	return nodeInfo
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMessagesReadBound(t *testing.T) {
+	tests := []struct {
+		start, end uint64
+		pullMode   PullMode
+		knownSeq   uint64
+		latest     bool
+	}{
+		{start: 0, end: 0, pullMode: PullModeDown, knownSeq: 0, latest: true},
+		{start: 0, end: 0, pullMode: PullModeUp, knownSeq: 0, latest: true},
+		{start: 10, end: 0, pullMode: PullModeUp, knownSeq: 9, latest: true},
+		{start: 10, end: 20, pullMode: PullModeUp, knownSeq: 19, latest: false},
+		{start: 20, end: 10, pullMode: PullModeDown, knownSeq: 20, latest: false},
+		{start: 20, end: 0, pullMode: PullModeDown, knownSeq: 20, latest: false},
+	}
+	for _, tt := range tests {
+		knownSeq, latest := syncMessagesReadBound(tt.start, tt.end, tt.pullMode)
+		assert.Equal(t, tt.knownSeq, knownSeq, "start=%d end=%d pullMode=%d", tt.start, tt.end, tt.pullMode)
+		assert.Equal(t, tt.latest, latest, "start=%d end=%d pullMode=%d", tt.start, tt.end, tt.pullMode)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+var mentionField = []byte(`"mention"`)
+
+// messageMention 消息的@信息
+// 发送者在消息payload（json）的mention字段里指定，例如：{"type":1,"content":"@u2 你好","mention":{"uids":["u2"],"all":0}}
+// 只有频道的订阅者（消息的接收者）才会被记录@，其他的uid会被忽略
+type messageMention struct {
+	UIDs []string `json:"uids,omitempty"` // @的用户
+	All  int      `json:"all,omitempty"`  // 是否@所有人
+}
+
+// parseMessageMention 解析消息的@信息，没有@或payload不是json时返回nil
+func parseMessageMention(payload []byte) *messageMention {
+	if len(payload) == 0 || !bytes.Contains(payload, mentionField) {
+		return nil
+	}
+	var content struct {
+		Mention *messageMention `json:"mention"`
+	}
+	if err := json.Unmarshal(payload, &content); err != nil {
+		return nil
+	}
+	if content.Mention == nil || (content.Mention.All != 1 && len(content.Mention.UIDs) == 0) {
+		return nil
+	}
+	return content.Mention
+}
+
+func (m *messageMention) mentionAll() bool {
+	return m.All == 1
+}
+
+// mentioned 是否@了指定用户（发送者自己不算）
+func (m *messageMention) mentioned(uid string, fromUid string) bool {
+	if uid == fromUid {
+		return false
+	}
+	if m.mentionAll() {
+		return true
+	}
+	for _, mentionUid := range m.UIDs {
+		if mentionUid == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// mentionedUids 返回uids中被@的用户
+func (m *messageMention) mentionedUids(uids []string, fromUid string) []string {
+	var mentionedUids []string
+	for _, uid := range uids {
+		if m.mentioned(uid, fromUid) {
+			mentionedUids = append(mentionedUids, uid)
+		}
+	}
+	return mentionedUids
+}
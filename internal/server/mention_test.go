@@ -0,0 +1,28 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMessageMention(t *testing.T) {
+	assert.Nil(t, parseMessageMention(nil))
+	assert.Nil(t, parseMessageMention([]byte(`{"type":1,"content":"hello"}`)))
+	assert.Nil(t, parseMessageMention([]byte(`"mention" not json`)))
+	assert.Nil(t, parseMessageMention([]byte(`{"mention":{"uids":[]}}`)))
+
+	mention := parseMessageMention([]byte(`{"type":1,"content":"@u2","mention":{"uids":["u2","u3"]}}`))
+	assert.NotNil(t, mention)
+	assert.False(t, mention.mentionAll())
+	assert.True(t, mention.mentioned("u2", "u1"))
+	assert.False(t, mention.mentioned("u4", "u1"))
+	assert.Equal(t, []string{"u2"}, mention.mentionedUids([]string{"u1", "u2", "u4"}, "u1"))
+
+	// @所有人不包含发送者
+	mention = parseMessageMention([]byte(`{"mention":{"all":1}}`))
+	assert.NotNil(t, mention)
+	assert.True(t, mention.mentionAll())
+	assert.True(t, mention.mentioned("u2", "u1"))
+	assert.False(t, mention.mentioned("u1", "u1"))
+}
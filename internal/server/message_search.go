@@ -0,0 +1,707 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+)
+
+// 单次检索最多扫描的结果数量（page * limit）
+const messageSearchMaxWindow = 1000
+
+// Tokenizer 分词器，负责将消息文本切分为检索关键词
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+var (
+	tokenizersLock sync.RWMutex
+	tokenizers     = map[string]Tokenizer{
+		"default":    cjkTokenizer{},
+		"whitespace": whitespaceTokenizer{},
+	}
+)
+
+// RegisterTokenizer 注册自定义分词器，通过配置 messageSearch.tokenizer 指定使用
+func RegisterTokenizer(name string, tokenizer Tokenizer) {
+	tokenizersLock.Lock()
+	defer tokenizersLock.Unlock()
+	tokenizers[name] = tokenizer
+}
+
+func getTokenizer(name string) Tokenizer {
+	tokenizersLock.RLock()
+	defer tokenizersLock.RUnlock()
+	if tokenizer, ok := tokenizers[name]; ok {
+		return tokenizer
+	}
+	return tokenizers["default"]
+}
+
+// whitespaceTokenizer 按空白和标点切分
+type whitespaceTokenizer struct{}
+
+func (whitespaceTokenizer) Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// cjkTokenizer 非中日韩文字按空白和标点切分，中日韩文字按二元组(bigram)切分
+type cjkTokenizer struct{}
+
+func (cjkTokenizer) Tokenize(text string) []string {
+	var (
+		terms []string
+		word  []rune
+		cjk   []rune
+	)
+	flushWord := func() {
+		if len(word) > 0 {
+			terms = append(terms, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			terms = append(terms, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			terms = append(terms, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return terms
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// messageSearchText 获取消息用于检索的文本，json格式的payload取content字段，否则取整个payload
+func messageSearchText(payload []byte) string {
+	if len(payload) == 0 || !utf8.Valid(payload) {
+		return ""
+	}
+	var content struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(payload, &content); err == nil {
+		return content.Content
+	}
+	return string(payload)
+}
+
+// messageSearchIndexer 将频道副本上提交的消息写入全文检索索引
+// 副本提交日志后只记录频道的提交进度，由索引协程异步从存储中读取消息建立索引，领导和追随者副本都有完整的索引，领导切换或迁移后检索不受影响
+// 每个频道已建立索引的消息seq持久化保存，节点重启后频道重新加载时只为未建立索引的消息建立索引
+type messageSearchIndexer struct {
+	s *Server
+	wklog.Log
+	stopper   *syncutil.Stopper
+	tokenizer Tokenizer
+
+	mu       sync.Mutex
+	pending  map[string]uint64 // 待建立索引的频道和频道已提交的消息seq
+	pendingC chan struct{}
+}
+
+func newMessageSearchIndexer(s *Server) *messageSearchIndexer {
+	return &messageSearchIndexer{
+		s:         s,
+		Log:       wklog.NewWKLog("messageSearchIndexer"),
+		stopper:   syncutil.NewStopper(),
+		tokenizer: getTokenizer(s.opts.MessageSearch.Tokenizer),
+		pending:   make(map[string]uint64),
+		pendingC:  make(chan struct{}, 1),
+	}
+}
+
+func (m *messageSearchIndexer) start() error {
+	m.stopper.RunWorker(m.expireLoop)
+	if m.s.opts.MessageSearch.On {
+		m.stopper.RunWorker(m.indexLoop)
+	}
+	return nil
+}
+
+func (m *messageSearchIndexer) stop() {
+	m.stopper.Stop()
+}
+
+// applyFnc 频道日志在副本上提交后记录频道的提交进度
+func (m *messageSearchIndexer) applyFnc() func(channelId string, channelType uint8, committedIndex uint64) {
+	if !m.s.opts.MessageSearch.On {
+		return nil
+	}
+	return func(channelId string, channelType uint8, committedIndex uint64) {
+		if m.s.opts.IsCmdChannel(channelId) {
+			return
+		}
+		m.addPending(wkutil.ChannelToKey(channelId, channelType), committedIndex)
+	}
+}
+
+func (m *messageSearchIndexer) addPending(channelKey string, committedSeq uint64) {
+	m.mu.Lock()
+	if committedSeq > m.pending[channelKey] {
+		m.pending[channelKey] = committedSeq
+	}
+	m.mu.Unlock()
+
+	select {
+	case m.pendingC <- struct{}{}:
+	default:
+	}
+}
+
+func (m *messageSearchIndexer) indexLoop() {
+	tk := time.NewTicker(time.Second * 5) // 定时重试建立索引失败的频道
+	defer tk.Stop()
+	for {
+		select {
+		case <-m.pendingC:
+		case <-tk.C:
+		case <-m.stopper.ShouldStop():
+			return
+		}
+
+		m.mu.Lock()
+		pending := m.pending
+		m.pending = make(map[string]uint64)
+		m.mu.Unlock()
+
+		for channelKey, committedSeq := range pending {
+			channelId, channelType := wkutil.ChannelFromlKey(channelKey)
+			if err := m.indexChannel(channelId, channelType, committedSeq); err != nil {
+				m.Warn("index channel messages failed, retry later", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("committedSeq", committedSeq))
+				m.addPending(channelKey, committedSeq)
+			}
+		}
+	}
+}
+
+// indexChannel 为频道已提交但未建立索引的消息分批建立索引
+// 索引按日志所在的频道建立（子区的消息在子区频道内），与消息的存储位置保持一致
+func (m *messageSearchIndexer) indexChannel(channelId string, channelType uint8, committedSeq uint64) error {
+	indexedSeq, err := m.s.store.GetMessageSearchIndexedSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	batchSize := m.s.opts.MessageSearch.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	for indexedSeq < committedSeq {
+		messages, err := m.s.store.LoadNextRangeMsgs(channelId, channelType, indexedSeq+1, committedSeq+1, batchSize)
+		if err != nil {
+			return err
+		}
+		nextSeq := committedSeq
+		if len(messages) > 0 && len(messages) >= batchSize {
+			nextSeq = uint64(messages[len(messages)-1].MessageSeq)
+		}
+		if err = m.s.store.AddMessageSearchIndex(m.indexReqs(channelId, channelType, messages)); err != nil {
+			return err
+		}
+		if err = m.s.store.SetMessageSearchIndexedSeq(channelId, channelType, nextSeq); err != nil {
+			return err
+		}
+		indexedSeq = nextSeq
+	}
+	return nil
+}
+
+func (m *messageSearchIndexer) indexReqs(channelId string, channelType uint8, messages []wkdb.Message) []wkdb.MessageSearchIndexReq {
+	reqs := make([]wkdb.MessageSearchIndexReq, 0, len(messages))
+	for _, msg := range messages {
+		if msg.MessageSeq == 0 {
+			continue
+		}
+		terms := m.tokenizer.Tokenize(messageSearchText(msg.Payload))
+		if len(terms) == 0 {
+			continue
+		}
+		var expireAt uint64
+		if msg.Expire > 0 {
+			expireAt = uint64(msg.Timestamp) + uint64(msg.Expire)
+		}
+		reqs = append(reqs, wkdb.MessageSearchIndexReq{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			MessageSeq:  uint64(msg.MessageSeq),
+			ExpireAt:    expireAt,
+			Terms:       terms,
+		})
+	}
+	return reqs
+}
+
+func (m *messageSearchIndexer) expireLoop() {
+	tk := time.NewTicker(m.s.opts.MessageSearch.ExpireScanInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			count, err := m.s.store.RemoveExpiredMessageSearchIndex(uint64(time.Now().Unix()), 10000)
+			if err != nil {
+				m.Error("RemoveExpiredMessageSearchIndex error", zap.Error(err))
+			} else if count > 0 {
+				m.Debug("remove expired message search index", zap.Int("count", count))
+			}
+		case <-m.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// searchChannels 在本节点检索指定频道（包含频道的子区）的消息，每个频道最多返回limit条
+func (m *messageSearchIndexer) searchChannels(keyword string, channels []*messageSearchChannelReq, limit int) ([]*messageSearchResult, error) {
+	terms := m.tokenizer.Tokenize(keyword)
+	words := strings.Fields(strings.ToLower(keyword))
+	if len(terms) == 0 || len(words) == 0 {
+		return nil, nil
+	}
+	channels, err := m.withThreadChannels(channels)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	results := make([]*messageSearchResult, 0)
+	for _, channel := range channels {
+		seqs, err := m.s.store.SearchMessageIndex(channel.ChannelId, channel.ChannelType, terms, 0, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, seq := range seqs {
+			msg, err := m.s.store.LoadMsg(channel.ChannelId, channel.ChannelType, seq)
+			if err != nil {
+				if err == wkdb.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			if msg.Expire > 0 && int64(msg.Timestamp)+int64(msg.Expire) <= now {
+				continue
+			}
+			text := messageSearchText(msg.Payload)
+			if !containsAllWords(text, words) { // 分词结果只用于召回，最终以原文是否包含关键词为准
+				continue
+			}
+			resp := &MessageResp{}
+			resp.from(msg, m.s)
+			results = append(results, &messageSearchResult{
+				MessageResp: resp,
+				Highlight:   highlightText(text, words),
+			})
+		}
+	}
+	return results, nil
+}
+
+// withThreadChannels 加上频道的子区频道，子区的回复索引在子区频道下，子区频道与父频道在同一个节点上
+func (m *messageSearchIndexer) withThreadChannels(channels []*messageSearchChannelReq) ([]*messageSearchChannelReq, error) {
+	result := make([]*messageSearchChannelReq, 0, len(channels))
+	for _, channel := range channels {
+		result = append(result, channel)
+		threads, err := m.s.store.GetThreads(channel.ChannelId, channel.ChannelType, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, thread := range threads {
+			result = append(result, &messageSearchChannelReq{
+				ChannelId:   wkdb.ThreadChannelId(channel.ChannelId, thread.RootMessageSeq),
+				ChannelType: channel.ChannelType,
+			})
+		}
+	}
+	return result, nil
+}
+
+func containsAllWords(text string, words []string) bool {
+	lowerText := strings.ToLower(text)
+	for _, word := range words {
+		if !strings.Contains(lowerText, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlightText 截取第一个命中关键词附近的文本，并用<em></em>标记所有命中的关键词
+func highlightText(text string, words []string) string {
+	const around = 30
+	runes := []rune(text)
+	lowerRunes := []rune(strings.ToLower(text))
+	if len(lowerRunes) != len(runes) { // 大小写转换后长度不一致，无法按位置对齐
+		return text
+	}
+	marks := make([]bool, len(runes))
+	first := -1
+	for _, word := range words {
+		wordRunes := []rune(word)
+		for i := 0; i+len(wordRunes) <= len(lowerRunes); i++ {
+			if string(lowerRunes[i:i+len(wordRunes)]) != word {
+				continue
+			}
+			for j := i; j < i+len(wordRunes); j++ {
+				marks[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+	if first == -1 {
+		return text
+	}
+	start := first - around
+	if start < 0 {
+		start = 0
+	}
+	end := first + around
+	if end > len(runes) {
+		end = len(runes)
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	for i := start; i < end; i++ {
+		if marks[i] && (i == start || !marks[i-1]) {
+			b.WriteString("<em>")
+		}
+		b.WriteRune(runes[i])
+		if marks[i] && (i == end-1 || !marks[i+1]) {
+			b.WriteString("</em>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// searchMessagesForCluster 按频道所在的领导节点分组检索，合并后按时间倒序分页
+func (s *Server) searchMessagesForCluster(keyword string, channels []*messageSearchChannelReq, page, limit int) ([]*messageSearchResult, error) {
+	if len(channels) == 0 {
+		return nil, nil
+	}
+	window := page * limit
+	if window > messageSearchMaxWindow {
+		window = messageSearchMaxWindow
+	}
+
+	localChannels := make([]*messageSearchChannelReq, 0)
+	peerChannelsMap := make(map[uint64][]*messageSearchChannelReq)
+
+	// 本节点是已追上领导的频道副本时，直接检索本节点的索引
+	var followerReadable map[int]bool
+	if s.opts.FollowerRead.On {
+		readChannels := make([]wkdb.Channel, 0, len(channels))
+		for _, channel := range channels {
+			readChannels = append(readChannels, wkdb.Channel{ChannelId: channel.ChannelId, ChannelType: channel.ChannelType})
+		}
+		followerReadable = s.followerReadableBatch(readChannels, make([]uint64, len(channels)))
+	}
+
+	for i, channel := range channels {
+		if followerReadable[i] {
+			localChannels = append(localChannels, channel)
+			continue
+		}
+		leaderInfo, err := s.cluster.LeaderOfChannelForRead(channel.ChannelId, channel.ChannelType)
+		if err != nil {
+			s.Warn("searchMessagesForCluster: 获取频道所在节点失败！", zap.Error(err), zap.String("channelId", channel.ChannelId), zap.Uint8("channelType", channel.ChannelType))
+			continue
+		}
+		if leaderInfo.Id == s.opts.Cluster.NodeId {
+			localChannels = append(localChannels, channel)
+		} else {
+			peerChannelsMap[leaderInfo.Id] = append(peerChannelsMap[leaderInfo.Id], channel)
+		}
+	}
+
+	var (
+		results    = make([]*messageSearchResult, 0)
+		resultLock sync.Mutex
+		reqErr     error
+		wg         sync.WaitGroup
+	)
+	for nodeId, peerChannels := range peerChannelsMap {
+		wg.Add(1)
+		go func(nodeId uint64, peerChannels []*messageSearchChannelReq) {
+			defer wg.Done()
+			peerResults, err := s.requestSearchMessages(nodeId, keyword, peerChannels, window)
+			resultLock.Lock()
+			defer resultLock.Unlock()
+			if err != nil {
+				s.Error("请求检索消息失败！", zap.Error(err), zap.Uint64("nodeId", nodeId))
+				reqErr = err
+				return
+			}
+			results = append(results, peerResults...)
+		}(nodeId, peerChannels)
+	}
+	if len(localChannels) > 0 {
+		localResults, err := s.messageSearchIndexer.searchChannels(keyword, localChannels, window)
+		if err != nil {
+			return nil, err
+		}
+		resultLock.Lock()
+		results = append(results, localResults...)
+		resultLock.Unlock()
+	}
+	wg.Wait()
+	if reqErr != nil {
+		return nil, reqErr
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Timestamp == results[j].Timestamp {
+			return results[i].MessageId > results[j].MessageId
+		}
+		return results[i].Timestamp > results[j].Timestamp
+	})
+
+	start := (page - 1) * limit
+	if start >= len(results) {
+		return []*messageSearchResult{}, nil
+	}
+	end := start + limit
+	if end > len(results) {
+		end = len(results)
+	}
+	return results[start:end], nil
+}
+
+// messageSearchChannelsReq 请求其他节点检索指定频道的消息
+type messageSearchChannelsReq struct {
+	Keyword  string                     `json:"keyword"`
+	Channels []*messageSearchChannelReq `json:"channels"`
+	Limit    int                        `json:"limit"`
+}
+
+func (s *Server) requestSearchMessages(nodeId uint64, keyword string, channels []*messageSearchChannelReq, limit int) ([]*messageSearchResult, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/messageSearch", []byte(wkutil.ToJSON(messageSearchChannelsReq{
+		Keyword:  keyword,
+		Channels: channels,
+		Limit:    limit,
+	})))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var results []*messageSearchResult
+	if err := wkutil.ReadJSONByByte(resp.Body, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// handleSearchChannelsReq 处理其他节点检索本节点指定频道消息的请求
+func (m *messageSearchIndexer) handleSearchChannelsReq(c *wkserver.Context) {
+	var req messageSearchChannelsReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		m.Error("handleSearchChannelsReq: decode req failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if !m.s.opts.MessageSearch.On {
+		c.WriteErr(errors.New("消息检索未开启！"))
+		return
+	}
+	results, err := m.searchChannels(req.Keyword, req.Channels, req.Limit)
+	if err != nil {
+		m.Error("handleSearchChannelsReq: search failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	if results == nil {
+		results = make([]*messageSearchResult, 0)
+	}
+	c.Write([]byte(wkutil.ToJSON(results)))
+}
+
+// userSearchChannels 获取用户的最近会话频道作为检索范围，需要再通过searchableChannels校验成员关系
+func (s *Server) userSearchChannels(uid string) ([]*messageSearchChannelReq, error) {
+	conversations, err := s.store.GetConversations(uid)
+	if err != nil && err != wkdb.ErrNotFound {
+		return nil, err
+	}
+	conversations = append(conversations, s.conversationManager.GetUserConversationFromCache(uid, wkdb.ConversationTypeChat)...)
+	conversations = append(conversations, s.conversationManager.GetUserConversationFromCache(uid, wkdb.ConversationTypeTopic)...)
+
+	channels := make([]*messageSearchChannelReq, 0, len(conversations))
+	exists := make(map[string]struct{}, len(conversations))
+	for _, conversation := range conversations {
+		if conversation.ChannelType == wkproto.ChannelTypePerson && conversation.ChannelId == s.opts.SystemUID {
+			continue
+		}
+		channelKey := wkutil.ChannelToKey(conversation.ChannelId, conversation.ChannelType)
+		if _, ok := exists[channelKey]; ok {
+			continue
+		}
+		exists[channelKey] = struct{}{}
+		channels = append(channels, &messageSearchChannelReq{
+			ChannelId:   conversation.ChannelId,
+			ChannelType: conversation.ChannelType,
+		})
+	}
+	return channels, nil
+}
+
+// searchableChannels 返回用户当前是订阅者的频道，订阅者在频道所在槽的领导节点上校验
+// 个人频道的fakeChannelId由双方uid组成，不需要校验；话题频道校验其继承的订阅者名单
+func (s *Server) searchableChannels(uid string, channels []*messageSearchChannelReq) ([]*messageSearchChannelReq, error) {
+	result := make([]*messageSearchChannelReq, 0, len(channels))
+	nodeChannels := make(map[uint64][]*messageSearchChannelReq) // 节点 -> 需要校验的订阅者名单所属频道
+	listChannels := make(map[string][]*messageSearchChannelReq) // 订阅者名单所属频道 -> 检索的频道
+	for _, channel := range channels {
+		if channel.ChannelType == wkproto.ChannelTypePerson {
+			result = append(result, channel)
+			continue
+		}
+		listChannel := &messageSearchChannelReq{ChannelId: channel.ChannelId, ChannelType: channel.ChannelType}
+		if channel.ChannelType == wkproto.ChannelTypeCommunityTopic {
+			subscriber, _, _, err := s.topicInheritChannels(channel.ChannelId)
+			if err != nil {
+				return nil, err
+			}
+			listChannel = &messageSearchChannelReq{ChannelId: subscriber.channelId, ChannelType: subscriber.channelType}
+		}
+		listKey := wkutil.ChannelToKey(listChannel.ChannelId, listChannel.ChannelType)
+		if _, ok := listChannels[listKey]; !ok {
+			nodeId := s.opts.Cluster.NodeId
+			if s.opts.ClusterOn() {
+				leaderId, err := s.cluster.SlotLeaderIdOfChannel(listChannel.ChannelId, listChannel.ChannelType)
+				if err != nil {
+					return nil, err
+				}
+				nodeId = leaderId
+			}
+			nodeChannels[nodeId] = append(nodeChannels[nodeId], listChannel)
+		}
+		listChannels[listKey] = append(listChannels[listKey], channel)
+	}
+
+	for nodeId, reqChannels := range nodeChannels {
+		var (
+			subscribed []*messageSearchChannelReq
+			err        error
+		)
+		if nodeId == s.opts.Cluster.NodeId {
+			subscribed, err = s.subscribedChannels(uid, reqChannels)
+		} else {
+			subscribed, err = s.requestSubscribedChannels(nodeId, uid, reqChannels)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, listChannel := range subscribed {
+			result = append(result, listChannels[wkutil.ChannelToKey(listChannel.ChannelId, listChannel.ChannelType)]...)
+		}
+	}
+	return result, nil
+}
+
+// subscribedChannels 返回用户在本节点数据中是订阅者的频道
+func (s *Server) subscribedChannels(uid string, channels []*messageSearchChannelReq) ([]*messageSearchChannelReq, error) {
+	subscribed := make([]*messageSearchChannelReq, 0, len(channels))
+	for _, channel := range channels {
+		exist, err := s.store.ExistSubscriber(channel.ChannelId, channel.ChannelType, uid)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			subscribed = append(subscribed, channel)
+		}
+	}
+	return subscribed, nil
+}
+
+type messageSearchSubscribedReq struct {
+	Uid      string                     `json:"uid"`
+	Channels []*messageSearchChannelReq `json:"channels"`
+}
+
+func (s *Server) requestSubscribedChannels(nodeId uint64, uid string, channels []*messageSearchChannelReq) ([]*messageSearchChannelReq, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/messageSearch/subscribed", []byte(wkutil.ToJSON(messageSearchSubscribedReq{
+		Uid:      uid,
+		Channels: channels,
+	})))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var subscribed []*messageSearchChannelReq
+	if err := wkutil.ReadJSONByByte(resp.Body, &subscribed); err != nil {
+		return nil, err
+	}
+	return subscribed, nil
+}
+
+// handleSearchSubscribedReq 处理其他节点在本节点（频道所在槽的领导节点）校验用户是否为频道订阅者的请求
+func (s *Server) handleSearchSubscribedReq(c *wkserver.Context) {
+	var req messageSearchSubscribedReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		s.Error("handleSearchSubscribedReq: decode req failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	subscribed, err := s.subscribedChannels(req.Uid, req.Channels)
+	if err != nil {
+		s.Error("handleSearchSubscribedReq: check subscriber failed", zap.Error(err), zap.String("uid", req.Uid))
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(subscribed)))
+}
+
+type messageSearchChannelReq struct {
+	ChannelId   string `json:"channel_id"` // 频道id（个人频道为fakeChannelId）
+	ChannelType uint8  `json:"channel_type"`
+}
+
+type messageSearchResult struct {
+	*MessageResp
+	Highlight string `json:"highlight"` // 命中关键词的高亮片段
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCJKTokenizer(t *testing.T) {
+	terms := cjkTokenizer{}.Tokenize("Hello, 悟空IM消息 world!")
+	assert.Equal(t, []string{"hello", "悟空", "im", "消息", "world"}, terms)
+
+	terms = cjkTokenizer{}.Tokenize("你好世界")
+	assert.Equal(t, []string{"你好", "好世", "世界"}, terms)
+}
+
+func TestMessageSearchText(t *testing.T) {
+	assert.Equal(t, "hello", messageSearchText([]byte(`{"type":1,"content":"hello"}`)))
+	assert.Equal(t, "plain text", messageSearchText([]byte("plain text")))
+	assert.Equal(t, "", messageSearchText([]byte{0xff, 0xfe}))
+}
+
+func TestHighlightText(t *testing.T) {
+	assert.Equal(t, "say <em>Hello</em> to <em>hello</em>", highlightText("say Hello to hello", []string{"hello"}))
+	assert.Equal(t, "你好<em>世界</em>", highlightText("你好世界", []string{"世界"}))
+	assert.Equal(t, "nothing", highlightText("nothing", []string{"hello"}))
+}
+
+func TestMessageSearchIndexChannel(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+	s.opts.MessageSearch.BatchSize = 2
+
+	messages := make([]wkdb.Message, 0)
+	for i := 1; i <= 5; i++ {
+		messages = append(messages, wkdb.Message{RecvPacket: wkproto.RecvPacket{MessageID: int64(i), MessageSeq: uint32(i), ChannelID: "g1", ChannelType: wkproto.ChannelTypeGroup, Payload: []byte(`{"content":"hello"}`)}})
+	}
+	err = s.store.DB().AppendMessages("g1", wkproto.ChannelTypeGroup, messages)
+	assert.NoError(t, err)
+
+	// 只为已提交的消息建立索引
+	err = s.messageSearchIndexer.indexChannel("g1", wkproto.ChannelTypeGroup, 3)
+	assert.NoError(t, err)
+	indexedSeq, err := s.store.GetMessageSearchIndexedSeq("g1", wkproto.ChannelTypeGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), indexedSeq)
+	seqs, err := s.store.SearchMessageIndex("g1", wkproto.ChannelTypeGroup, []string{"hello"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 2, 1}, seqs)
+
+	err = s.messageSearchIndexer.indexChannel("g1", wkproto.ChannelTypeGroup, 5)
+	assert.NoError(t, err)
+	indexedSeq, err = s.store.GetMessageSearchIndexedSeq("g1", wkproto.ChannelTypeGroup)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), indexedSeq)
+	seqs, err = s.store.SearchMessageIndex("g1", wkproto.ChannelTypeGroup, []string{"hello"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5, 4, 3, 2, 1}, seqs)
+}
+
+func TestMessageSearchSubscribedChannels(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	err = s.store.DB().AddSubscribers("g1", wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: "u1"}})
+	assert.NoError(t, err)
+	err = s.store.DB().AddSubscribers("g2", wkproto.ChannelTypeGroup, []wkdb.Member{{Uid: "u2"}})
+	assert.NoError(t, err)
+
+	// 不是订阅者的频道（比如已被移除）不能检索
+	channels, err := s.subscribedChannels("u1", []*messageSearchChannelReq{
+		{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup},
+		{ChannelId: "g2", ChannelType: wkproto.ChannelTypeGroup},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, channels, 1)
+	assert.Equal(t, "g1", channels[0].ChannelId)
+}
+
+func TestMessageSearchThreadChannels(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	threadChannelId := wkdb.ThreadChannelId("g1", 1)
+	err = s.store.DB().AppendMessages("g1", wkproto.ChannelTypeGroup, []wkdb.Message{{RecvPacket: wkproto.RecvPacket{MessageID: 1, MessageSeq: 1, ChannelID: "g1", ChannelType: wkproto.ChannelTypeGroup, Timestamp: 100, Payload: []byte("hello root")}}})
+	assert.NoError(t, err)
+	err = s.store.DB().AppendMessages(threadChannelId, wkproto.ChannelTypeGroup, []wkdb.Message{{RecvPacket: wkproto.RecvPacket{MessageID: 2, MessageSeq: 1, ChannelID: threadChannelId, ChannelType: wkproto.ChannelTypeGroup, Timestamp: 101, Payload: []byte("hello reply")}}})
+	assert.NoError(t, err)
+	assert.NoError(t, s.messageSearchIndexer.indexChannel("g1", wkproto.ChannelTypeGroup, 1))
+	assert.NoError(t, s.messageSearchIndexer.indexChannel(threadChannelId, wkproto.ChannelTypeGroup, 1))
+
+	// 检索父频道时一并检索子区，子区内的回复seq与父频道的消息相同也不会冲突
+	results, err := s.messageSearchIndexer.searchChannels("hello", []*messageSearchChannelReq{{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup}}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, int64(1), results[0].MessageId)
+	assert.Empty(t, results[0].ThreadId)
+	assert.Equal(t, int64(2), results[1].MessageId)
+	assert.Equal(t, "g1", results[1].ChannelID)
+	assert.Equal(t, threadChannelId, results[1].ThreadId)
+	assert.Equal(t, int64(1), results[1].RootMessageId)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
@@ -382,7 +383,17 @@ type MessageResp struct {
 	Expire       uint32             `json:"expire"`                // 消息过期时间
 	Timestamp    int32              `json:"timestamp"`             // 服务器消息时间戳(10位，到秒)
 	Payload      []byte             `json:"payload"`               // 消息内容
+
+	ThreadReplyCount  uint64 `json:"thread_reply_count,omitempty"`   // 子区的回复数量
+	ThreadLastReplyAt int64  `json:"thread_last_reply_at,omitempty"` // 子区的最后回复时间（unix秒）
+
+	ThreadId       string `json:"thread_id,omitempty"`        // 子区ID（子区频道ID），只有子区内的回复才有
+	RootMessageId  int64  `json:"root_message_id,omitempty"`  // 子区根消息的ID
+	RootMessageSeq uint64 `json:"root_message_seq,omitempty"` // 子区根消息的seq
 	// Streams      []*StreamItemResp  `json:"streams,omitempty"`     // 消息流内容
+
+	FromUserAttributes map[string]json.RawMessage `json:"from_user_attributes,omitempty"` // 发送者属性（只有webhook的消息通知才有，由attribute.messageUserAttributes配置）
+	ChannelAttributes  map[string]json.RawMessage `json:"channel_attributes,omitempty"`   // 频道属性（只有webhook的消息通知才有，由attribute.messageChannelAttributes配置）
 }
 
 func (m *MessageResp) from(messageD wkdb.Message, s *Server) {
@@ -409,9 +420,19 @@ func (m *MessageResp) from(messageD wkdb.Message, s *Server) {
 	m.Timestamp = messageD.Timestamp
 
 	realChannelID := messageD.ChannelID
+	// 子区内的回复，频道ID为子区频道ID，返回父频道并带上子区信息
+	if parentChannelId, rootMessageSeq, ok := wkdb.ParseThreadChannelId(messageD.ChannelID); ok {
+		realChannelID = parentChannelId
+		m.ThreadId = messageD.ChannelID
+		m.RootMessageSeq = rootMessageSeq
+		rootMsg, err := s.store.LoadMsg(parentChannelId, messageD.ChannelType, rootMessageSeq)
+		if err == nil {
+			m.RootMessageId = rootMsg.MessageID
+		}
+	}
 	if messageD.ChannelType == wkproto.ChannelTypePerson {
-		if strings.Contains(messageD.ChannelID, "@") {
-			channelIDs := strings.Split(messageD.ChannelID, "@")
+		if strings.Contains(realChannelID, "@") {
+			channelIDs := strings.Split(realChannelID, "@")
 			for _, channelID := range channelIDs {
 				if fromUid != channelID {
 					realChannelID = channelID
@@ -425,12 +446,22 @@ func (m *MessageResp) from(messageD wkdb.Message, s *Server) {
 	m.Payload = messageD.Payload
 }
 
+// fillExtra 填充消息的扩展数据
+func (m *MessageResp) fillExtra(extra wkdb.MessageExtra) {
+	m.ThreadReplyCount = extra.ThreadReplyCount
+	if extra.ThreadLastReplyAt != nil {
+		m.ThreadLastReplyAt = extra.ThreadLastReplyAt.Unix()
+	}
+}
+
 type MessageOfflineNotify struct {
 	MessageResp
 	ToUIDs          []string `json:"to_uids"`
 	Compress        string   `json:"compress,omitempty"`         // 压缩ToUIDs 如果为空 表示不压缩 为gzip则采用gzip压缩
 	CompresssToUIDs []byte   `json:"compress_to_uids,omitempty"` // 已压缩的to_uids
 	SourceID        int64    `json:"source_id,omitempty"`        // 来源节点ID
+	MentionAll      int      `json:"mention_all,omitempty"`      // 消息是否@所有人
+	MentionUIDs     []string `json:"mention_uids,omitempty"`     // to_uids中被@的用户（@所有人时为空），推送时可以优先处理
 }
 
 // MessageHeader Message header
@@ -614,6 +645,10 @@ type syncUserConversationResp struct {
 	ReadedToMsgSeq  uint32         `json:"readed_to_msg_seq"`  // 已读至的消息seq
 	Version         int64          `json:"version"`            // 数据版本
 	Recents         []*MessageResp `json:"recents"`            // 最近N条消息
+
+	Mention       int    `json:"mention,omitempty"`         // 是否有未读的@我（1.有）
+	MentionAll    int    `json:"mention_all,omitempty"`     // 最近一条未读的@是否是@所有人
+	MentionMsgSeq uint32 `json:"mention_msg_seq,omitempty"` // 最近一条未读的@我的消息seq
 }
 
 func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConversationResp {
@@ -626,12 +661,18 @@ func newSyncUserConversationResp(conversation wkdb.Conversation) *syncUserConver
 			realChannelId = from
 		}
 	}
-	return &syncUserConversationResp{
+	resp := &syncUserConversationResp{
 		ChannelId:      realChannelId,
 		ChannelType:    conversation.ChannelType,
 		Unread:         int(conversation.UnreadCount),
 		ReadedToMsgSeq: uint32(conversation.ReadToMsgSeq),
 	}
+	if conversation.MentionMsgSeq > conversation.ReadToMsgSeq {
+		resp.Mention = 1
+		resp.MentionAll = wkutil.BoolToInt(conversation.MentionAll)
+		resp.MentionMsgSeq = uint32(conversation.MentionMsgSeq)
+	}
+	return resp
 }
 
 type channelRecentMessageReq struct {
@@ -680,18 +721,22 @@ type subscriberAddReq struct {
 	Reset          int      `json:"reset"`           // 是否重置订阅者 （0.不重置 1.重置），选择重置，将删除原来的所有成员
 	TempSubscriber int      `json:"temp_subscriber"` //  是否是临时订阅者 (1. 是 0. 否)
 	Subscribers    []string `json:"subscribers"`     // 订阅者
+	Role           uint8    `json:"role"`            // 新添加订阅者的角色 (0.普通成员 1.管理员 2.群主)
 }
 
 func (s subscriberAddReq) Check() error {
 	if strings.TrimSpace(s.ChannelId) == "" {
 		return errors.New("频道ID不能为空！")
 	}
-	if IsSpecialChar(s.ChannelId) {
+	if IsSpecialCharOfChannel(s.ChannelId, s.ChannelType) {
 		return errors.New("频道ID不能包含特殊字符！")
 	}
 	if stringArrayIsEmpty(s.Subscribers) {
 		return errors.New("订阅者不能为空！")
 	}
+	if wkdb.MemberRole(s.Role) > wkdb.MemberRoleOwner {
+		return errors.New("订阅者角色不正确！")
+	}
 	return nil
 }
 
@@ -706,7 +751,7 @@ func (s subscriberRemoveReq) Check() error {
 	if strings.TrimSpace(s.ChannelID) == "" {
 		return errors.New("频道ID不能为空！")
 	}
-	if IsSpecialChar(s.ChannelID) {
+	if IsSpecialCharOfChannel(s.ChannelID, s.ChannelType) {
 		return errors.New("频道ID不能包含特殊字符！")
 	}
 	if stringArrayIsEmpty(s.Subscribers) {
@@ -763,7 +808,7 @@ func (r whitelistReq) Check() error {
 	if r.ChannelID == "" {
 		return errors.New("channel_id不能为空！")
 	}
-	if IsSpecialChar(r.ChannelID) {
+	if IsSpecialCharOfChannel(r.ChannelID, r.ChannelType) {
 		return errors.New("频道ID不能包含特殊字符！")
 	}
 	if r.ChannelType == 0 {
@@ -856,6 +901,7 @@ type MessageSendReq struct {
 	Expire      uint32        `json:"expire"`        // 消息过期时间
 	Subscribers []string      `json:"subscribers"`   // 订阅者 如果此字段有值，表示消息只发给指定的订阅者
 	Payload     []byte        `json:"payload"`       // 消息内容
+	SendAt      int64         `json:"send_at"`       // 定时发送的时间（unix秒），大于当前时间时消息将在此时间发送
 }
 
 // Check 检查输入
@@ -866,6 +912,11 @@ func (m MessageSendReq) Check() error {
 	return nil
 }
 
+// IsScheduled 是否为定时消息
+func (m MessageSendReq) IsScheduled() bool {
+	return m.SendAt > time.Now().Unix()
+}
+
 type allowSendReq struct {
 	From string `json:"from"` // 发送者
 	To   string `json:"to"`   // 接收者
@@ -2,17 +2,22 @@ package server
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"os/user"
 	"path"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/auth/resource"
+	"github.com/WuKongIM/WuKongIM/pkg/wkbackup"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/crypto/tls"
 	"github.com/google/uuid"
@@ -42,6 +47,37 @@ const (
 	TestMode = "test"
 )
 
+type TokenAuthMode string
+
+const (
+	// 验证通过/user/token接口存储的token
+	TokenAuthModeStore TokenAuthMode = "store"
+	// 验证业务端签名的令牌（HS256/RS256/EdDSA）
+	TokenAuthModeJwt TokenAuthMode = "jwt"
+)
+
+// WebhookOptions webhook配置（可重新加载）
+type WebhookOptions struct {
+	HTTPAddr                    string        // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
+	GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
+	MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
+	MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
+	MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
+}
+
+// TokenAuthOptions token验证配置（可重新加载）
+type TokenAuthOptions struct {
+	Mode               TokenAuthMode // 验证模式 store: 验证通过/user/token接口存储的token（默认） jwt: 验证业务端签名的令牌
+	Secret             string        // HS256签名的密钥
+	PublicKeyFile      string        // RS256或EdDSA签名的公钥文件（PEM格式）
+	JwksFile           string        // JWKS格式的公钥文件，令牌头部带kid时从此文件中查找公钥
+	JwksReloadInterval time.Duration // JWKS文件的重新加载间隔，0表示不重新加载
+	Issuer             string        // 令牌的签发者(iss)，为空则不验证
+	Audience           string        // 令牌的接收者(aud)，为空则不验证
+	Leeway             time.Duration // 验证过期时间时允许的时钟误差
+	RevokedTokenTTL    time.Duration // 吊销令牌时未指定令牌过期时间，吊销记录的保留时长（应不小于令牌的最长有效期）
+}
+
 type Role string
 
 const (
@@ -104,14 +140,8 @@ type Options struct {
 		Suffix     string // 临时频道的后缀
 		CacheCount int    // 临时频道缓存数量
 	}
-	Webhook struct { // 两者配其一即可
-		HTTPAddr                    string        // webhook的http地址 通过此地址通知数据给第三方 格式为 http://xxxxx
-		GRPCAddr                    string        //  webhook的grpc地址 如果此地址有值 则不会再调用HttpAddr配置的地址,格式为 ip:port
-		MsgNotifyEventPushInterval  time.Duration // 消息通知事件推送间隔，默认500毫秒发起一次推送
-		MsgNotifyEventCountPerPush  int           // 每次webhook消息通知事件推送消息数量限制 默认一次请求最多推送100条
-		MsgNotifyEventRetryMaxCount int           // 消息通知事件消息推送失败最大重试次数 默认为5次，超过将丢弃
-	}
-	Datasource struct { // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
+	Webhook    WebhookOptions // 两者配其一即可
+	Datasource struct {       // 数据源配置，不填写则使用自身数据存储逻辑，如果填写则使用第三方数据源，数据格式请查看文档
 		Addr          string // 数据源地址
 		ChannelInfoOn bool   // 是否开启频道信息获取
 	}
@@ -127,6 +157,76 @@ type Options struct {
 		WorkerScanInterval time.Duration // 处理最近会话扫描间隔
 
 	}
+	MessageSearch struct { // 消息全文检索配置
+		On                 bool          // 是否开启消息全文检索索引
+		Tokenizer          string        // 分词器名称 default: 中日韩二元组+空白切分 whitespace: 空白切分，可通过RegisterTokenizer注册自定义分词器
+		BatchSize          int           // 每次批量建立索引的最大消息数量
+		ExpireScanInterval time.Duration // 扫描过期消息索引的间隔
+	}
+	Presence struct { // 用户在线状态配置
+		On                  bool // 是否开启用户在线状态订阅
+		MaxSubscribePerConn int  // 单个连接最多可订阅的用户数量
+	}
+	Ephemeral struct { // 瞬时信号配置（比如"正在输入"）
+		CoalesceInterval time.Duration // 合并窗口，窗口内同一设备在同一频道发送的相同信号只投递一次
+		WorkerCount      int           // 处理信号的协程数量
+	}
+	FollowerRead struct { // 副本读配置（分布式模式下生效）
+		On bool // 是否开启副本读，开启后频道消息的同步和检索可以由已追上领导的副本提供，副本落后时回退到频道领导
+	}
+	Backup struct { // 在线备份配置
+		Target  string             // 备份目标，本地目录（例如：/data/backup 或 file:///data/backup，集群模式下需要是所有节点都能访问的共享目录）或S3兼容存储（例如：s3://bucket/prefix）
+		Timeout time.Duration      // 单次备份的超时时间
+		S3      wkbackup.S3Options // S3兼容存储的配置
+	}
+	Drain struct { // 节点下线（滚动升级）配置
+		Timeout          time.Duration // 下线的超时时间，超时后不再等待领导转移和消息投递完成
+		DisconnectWindow time.Duration // 客户端连接在此时间窗口内分批断开，避免客户端同时重连
+	}
+	Route struct { // 客户端路由（/route）配置
+		RefreshInterval time.Duration // 获取其他节点地址和连接数的间隔，超过3个间隔没有获取到信息的节点视为不可用
+		LoadThreshold   float64       // 用户所在槽的领导节点连接数超过平均值的(1+LoadThreshold)倍时，改为选择连接数最少的节点
+	}
+	ScheduledMessage struct { // 定时消息配置
+		ScanInterval time.Duration // 槽领导节点扫描到期定时消息的间隔
+		BatchSize    int           // 每个槽每次最多发送的定时消息数量
+		MaxDelay     time.Duration // 定时消息最长可以延迟多久发送
+	}
+	Broadcast struct { // 广播（给所有用户或部分用户发送消息）配置
+		ScanInterval time.Duration // 槽领导节点检查需要执行的广播任务的间隔
+		BatchSize    int           // 每批处理的用户数量，每批处理完成后保存一次进度
+		DefaultRate  int           // 广播默认每秒发送的消息数量（整个集群）
+	}
+	Attribute struct { // 用户和频道的自定义属性配置
+		MaxCount                 int      // 每个用户或频道最多的属性数量
+		MaxNameLen               int      // 属性名的最大长度
+		MaxValueSize             int      // 属性值（json编码后）的最大字节数
+		MessageUserAttributes    []string // webhook的msg.notify和msg.offline的消息中附带的发送者属性
+		MessageChannelAttributes []string // webhook的msg.notify和msg.offline的消息中附带的频道属性
+	}
+	UserData struct { // 用户数据导出和删除配置
+		ScanInterval  time.Duration // 槽领导节点检查需要执行的用户数据任务的间隔
+		RetryInterval time.Duration // 有节点离线等原因未完成的任务的重试间隔
+		BatchSize     int           // 每次查询或匿名化的消息数量
+		ExportDir     string        // 导出文件的存放目录，为空时为数据目录下的userdata目录
+	}
+	E2EE struct { // 端到端加密的密钥分发配置
+		MaxDevices        int // 每个用户最多上传公钥的设备数量
+		MaxOneTimePrekeys int // 每个设备最多保存的一次性预共享公钥数量
+	}
+	TransportEncrypt struct { // 客户端连接的消息加密配置
+		AEADOn        bool          // 是否允许新版本客户端（协议版本5及以上）协商AES-GCM或ChaCha20-Poly1305加密，关闭后都使用旧的AES-CBC加密
+		RekeyMessages uint64        // AEAD加密每个密钥周期最多加密的消息数量，超过后更换密钥，0表示不按数量更换
+		RekeyInterval time.Duration // AEAD加密每个密钥周期的最长时间，超过后更换密钥，0表示不按时间更换
+	}
+	Bot struct { // 机器人（消息推送到机器人的回调地址）配置
+		WorkerCount      int           // 匹配机器人消息的并发数量
+		QueueSize        int           // 每个机器人待推送的消息队列长度，每个机器人单独按顺序推送，队列满时丢弃
+		DefaultTimeout   time.Duration // 回调的默认超时时间（机器人未指定时）
+		MaxTimeout       time.Duration // 机器人可以指定的最长超时时间
+		DefaultRateLimit int           // 每个机器人每秒默认最多推送的消息数量（机器人未指定时）
+		MaxReplies       int           // 回调的响应里最多回复的消息数量
+	}
 	ManagerToken   string // 管理者的token
 	ManagerUID     string // 管理者的uid
 	SystemUID      string // 系统账号的uid，主要用来发消息
@@ -147,6 +247,8 @@ type Options struct {
 
 	TokenAuthOn bool // 是否开启token验证 不配置将根据mode属性判断 debug模式下默认为false release模式为true
 
+	TokenAuth TokenAuthOptions // token验证配置（tokenAuthOn开启后生效）
+
 	EventPoolSize int // 事件协程池大小,此池主要处理im的一些通知事件 比如webhook，上下线等等 默认为1024
 
 	WhitelistOffOfPerson bool // 是否关闭个人白名单验证
@@ -185,6 +287,15 @@ type Options struct {
 		SlotReactorSubCount    int // 槽reactor sub的数量
 
 		PongMaxTick int // 节点超过多少tick没有回应心跳就认为是掉线
+
+		ChannelLeaderBalanceOn          bool          // 是否开启频道领导按消息速率自动均衡
+		ChannelLeaderBalanceInterval    time.Duration // 频道领导均衡的检查间隔
+		ChannelLeaderBalanceThreshold   float64       // 节点消息速率超过集群平均值多少比例才进行均衡（0.2表示超过平均值20%）
+		ChannelLeaderBalanceMinRate     float64       // 节点消息速率超出平均值小于这个值（条/秒）时不均衡
+		ChannelLeaderBalanceMaxTransfer int           // 每次均衡最多转移的频道领导数量
+
+		Zone string // 节点所在的可用区，槽和频道的副本会尽量分布在不同的可用区
+		Rack string // 节点所在的机架，同一可用区内副本会尽量分布在不同的机架
 	}
 
 	Trace struct {
@@ -236,6 +347,10 @@ type Options struct {
 	PprofOn          bool        // 是否开启pprof
 	OldV1Api         string      //旧v1版本的api地址，如果不为空则开启数据迁移任务，将v1的数据迁移到v2
 	MigrateStartStep MigrateStep // 从那步开始迁移，默认顺序是 message,user,channel
+
+	// 保护可重新加载的配置项（见ApplyReloadable），服务运行后需要通过对应的Get方法读取这些配置项。
+	// 使用指针，LoadReloadable复制出的新配置与当前配置共用同一个锁
+	reloadMu *sync.RWMutex
 }
 
 type MigrateStep string
@@ -255,6 +370,7 @@ func NewOptions(op ...Option) *Options {
 		panic(err)
 	}
 	opts := &Options{
+		reloadMu:             &sync.RWMutex{},
 		Proto:                wkproto.New(),
 		HandlePoolSize:       2048,
 		Version:              version.Version,
@@ -313,6 +429,22 @@ func NewOptions(op ...Option) *Options {
 			ChannelInfoOn: false,
 		},
 		TokenAuthOn: false,
+		TokenAuth: struct {
+			Mode               TokenAuthMode
+			Secret             string
+			PublicKeyFile      string
+			JwksFile           string
+			JwksReloadInterval time.Duration
+			Issuer             string
+			Audience           string
+			Leeway             time.Duration
+			RevokedTokenTTL    time.Duration
+		}{
+			Mode:               TokenAuthModeStore,
+			JwksReloadInterval: time.Minute,
+			Leeway:             time.Second * 30,
+			RevokedTokenTTL:    time.Hour * 24 * 30,
+		},
 		Conversation: struct {
 			On                 bool
 			CacheExpire        time.Duration
@@ -334,6 +466,127 @@ func NewOptions(op ...Option) *Options {
 			WorkerCount:        10,
 			WorkerScanInterval: time.Minute * 5,
 		},
+		MessageSearch: struct {
+			On                 bool
+			Tokenizer          string
+			BatchSize          int
+			ExpireScanInterval time.Duration
+		}{
+			On:                 false,
+			Tokenizer:          "default",
+			BatchSize:          1000,
+			ExpireScanInterval: time.Minute * 10,
+		},
+		Presence: struct {
+			On                  bool
+			MaxSubscribePerConn int
+		}{
+			On:                  false,
+			MaxSubscribePerConn: 1000,
+		},
+		Ephemeral: struct {
+			CoalesceInterval time.Duration
+			WorkerCount      int
+		}{
+			CoalesceInterval: time.Second * 3,
+			WorkerCount:      4,
+		},
+		FollowerRead: struct {
+			On bool
+		}{
+			On: true,
+		},
+		Backup: struct {
+			Target  string
+			Timeout time.Duration
+			S3      wkbackup.S3Options
+		}{
+			Timeout: time.Minute * 30,
+		},
+		Drain: struct {
+			Timeout          time.Duration
+			DisconnectWindow time.Duration
+		}{
+			Timeout:          time.Minute,
+			DisconnectWindow: time.Second * 10,
+		},
+		Route: struct {
+			RefreshInterval time.Duration
+			LoadThreshold   float64
+		}{
+			RefreshInterval: time.Second * 5,
+			LoadThreshold:   0.2,
+		},
+		ScheduledMessage: struct {
+			ScanInterval time.Duration
+			BatchSize    int
+			MaxDelay     time.Duration
+		}{
+			ScanInterval: time.Second,
+			BatchSize:    100,
+			MaxDelay:     time.Hour * 24 * 30,
+		},
+		Broadcast: struct {
+			ScanInterval time.Duration
+			BatchSize    int
+			DefaultRate  int
+		}{
+			ScanInterval: time.Second * 5,
+			BatchSize:    200,
+			DefaultRate:  1000,
+		},
+		Attribute: struct {
+			MaxCount                 int
+			MaxNameLen               int
+			MaxValueSize             int
+			MessageUserAttributes    []string
+			MessageChannelAttributes []string
+		}{
+			MaxCount:     100,
+			MaxNameLen:   64,
+			MaxValueSize: 4096,
+		},
+		UserData: struct {
+			ScanInterval  time.Duration
+			RetryInterval time.Duration
+			BatchSize     int
+			ExportDir     string
+		}{
+			ScanInterval:  time.Second * 5,
+			RetryInterval: time.Minute,
+			BatchSize:     500,
+		},
+		E2EE: struct {
+			MaxDevices        int
+			MaxOneTimePrekeys int
+		}{
+			MaxDevices:        10,
+			MaxOneTimePrekeys: 200,
+		},
+		TransportEncrypt: struct {
+			AEADOn        bool
+			RekeyMessages uint64
+			RekeyInterval time.Duration
+		}{
+			AEADOn:        true,
+			RekeyMessages: 100000,
+			RekeyInterval: time.Hour,
+		},
+		Bot: struct {
+			WorkerCount      int
+			QueueSize        int
+			DefaultTimeout   time.Duration
+			MaxTimeout       time.Duration
+			DefaultRateLimit int
+			MaxReplies       int
+		}{
+			WorkerCount:      20,
+			QueueSize:        1024,
+			DefaultTimeout:   time.Second * 5,
+			MaxTimeout:       time.Second * 30,
+			DefaultRateLimit: 20,
+			MaxReplies:       10,
+		},
 		DeliveryMsgPoolSize: 10240,
 		EventPoolSize:       1024,
 		MessageRetry: struct {
@@ -390,6 +643,15 @@ func NewOptions(op ...Option) *Options {
 			ChannelReactorSubCount int
 			SlotReactorSubCount    int
 			PongMaxTick            int
+
+			ChannelLeaderBalanceOn          bool
+			ChannelLeaderBalanceInterval    time.Duration
+			ChannelLeaderBalanceThreshold   float64
+			ChannelLeaderBalanceMinRate     float64
+			ChannelLeaderBalanceMaxTransfer int
+
+			Zone string
+			Rack string
 		}{
 			NodeId:                 1001,
 			Addr:                   "tcp://0.0.0.0:11110",
@@ -405,6 +667,12 @@ func NewOptions(op ...Option) *Options {
 			ChannelReactorSubCount: 64,
 			SlotReactorSubCount:    64,
 			PongMaxTick:            30,
+
+			ChannelLeaderBalanceOn:          true,
+			ChannelLeaderBalanceInterval:    time.Minute,
+			ChannelLeaderBalanceThreshold:   0.2,
+			ChannelLeaderBalanceMinRate:     10,
+			ChannelLeaderBalanceMaxTransfer: 10,
 		},
 		Trace: struct {
 			Endpoint         string
@@ -556,6 +824,15 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.UserMsgQueueMaxSize = o.getInt("userMsgQueueMaxSize", o.UserMsgQueueMaxSize)
 
 	o.TokenAuthOn = o.getBool("tokenAuthOn", o.TokenAuthOn)
+	o.TokenAuth.Mode = TokenAuthMode(o.getString("tokenAuth.mode", string(o.TokenAuth.Mode)))
+	o.TokenAuth.Secret = o.getString("tokenAuth.secret", o.TokenAuth.Secret)
+	o.TokenAuth.PublicKeyFile = o.getString("tokenAuth.publicKeyFile", o.TokenAuth.PublicKeyFile)
+	o.TokenAuth.JwksFile = o.getString("tokenAuth.jwksFile", o.TokenAuth.JwksFile)
+	o.TokenAuth.JwksReloadInterval = o.getDuration("tokenAuth.jwksReloadInterval", o.TokenAuth.JwksReloadInterval)
+	o.TokenAuth.Issuer = o.getString("tokenAuth.issuer", o.TokenAuth.Issuer)
+	o.TokenAuth.Audience = o.getString("tokenAuth.audience", o.TokenAuth.Audience)
+	o.TokenAuth.Leeway = o.getDuration("tokenAuth.leeway", o.TokenAuth.Leeway)
+	o.TokenAuth.RevokedTokenTTL = o.getDuration("tokenAuth.revokedTokenTTL", o.TokenAuth.RevokedTokenTTL)
 
 	o.UnitTest = o.vp.GetBool("unitTest")
 
@@ -592,6 +869,70 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Conversation.WorkerCount = o.getInt("conversation.workerNum", o.Conversation.WorkerCount)
 	o.Conversation.WorkerScanInterval = o.getDuration("conversation.workerScanInterval", o.Conversation.WorkerScanInterval)
 
+	o.MessageSearch.On = o.getBool("messageSearch.on", o.MessageSearch.On)
+	o.MessageSearch.Tokenizer = o.getString("messageSearch.tokenizer", o.MessageSearch.Tokenizer)
+	o.MessageSearch.BatchSize = o.getInt("messageSearch.batchSize", o.MessageSearch.BatchSize)
+	o.MessageSearch.ExpireScanInterval = o.getDuration("messageSearch.expireScanInterval", o.MessageSearch.ExpireScanInterval)
+
+	o.Presence.On = o.getBool("presence.on", o.Presence.On)
+	o.Presence.MaxSubscribePerConn = o.getInt("presence.maxSubscribePerConn", o.Presence.MaxSubscribePerConn)
+
+	o.Ephemeral.CoalesceInterval = o.getDuration("ephemeral.coalesceInterval", o.Ephemeral.CoalesceInterval)
+	o.Ephemeral.WorkerCount = o.getInt("ephemeral.workerCount", o.Ephemeral.WorkerCount)
+
+	o.FollowerRead.On = o.getBool("followerRead.on", o.FollowerRead.On)
+
+	o.Backup.Target = o.getString("backup.target", o.Backup.Target)
+	o.Backup.Timeout = o.getDuration("backup.timeout", o.Backup.Timeout)
+	o.Backup.S3.Endpoint = o.getString("backup.s3.endpoint", o.Backup.S3.Endpoint)
+	o.Backup.S3.Region = o.getString("backup.s3.region", o.Backup.S3.Region)
+	o.Backup.S3.AccessKeyID = o.getString("backup.s3.accessKeyID", o.Backup.S3.AccessKeyID)
+	o.Backup.S3.SecretAccessKey = o.getString("backup.s3.secretAccessKey", o.Backup.S3.SecretAccessKey)
+	o.Backup.S3.ForcePathStyle = o.getBool("backup.s3.forcePathStyle", o.Backup.S3.ForcePathStyle)
+
+	o.Drain.Timeout = o.getDuration("drain.timeout", o.Drain.Timeout)
+	o.Drain.DisconnectWindow = o.getDuration("drain.disconnectWindow", o.Drain.DisconnectWindow)
+
+	o.Route.RefreshInterval = o.getDuration("route.refreshInterval", o.Route.RefreshInterval)
+	o.Route.LoadThreshold = o.getFloat64("route.loadThreshold", o.Route.LoadThreshold)
+
+	o.ScheduledMessage.ScanInterval = o.getDuration("scheduledMessage.scanInterval", o.ScheduledMessage.ScanInterval)
+	o.ScheduledMessage.BatchSize = o.getInt("scheduledMessage.batchSize", o.ScheduledMessage.BatchSize)
+	o.ScheduledMessage.MaxDelay = o.getDuration("scheduledMessage.maxDelay", o.ScheduledMessage.MaxDelay)
+
+	o.Broadcast.ScanInterval = o.getDuration("broadcast.scanInterval", o.Broadcast.ScanInterval)
+	o.Broadcast.BatchSize = o.getInt("broadcast.batchSize", o.Broadcast.BatchSize)
+	o.Broadcast.DefaultRate = o.getInt("broadcast.defaultRate", o.Broadcast.DefaultRate)
+
+	o.Attribute.MaxCount = o.getInt("attribute.maxCount", o.Attribute.MaxCount)
+	o.Attribute.MaxNameLen = o.getInt("attribute.maxNameLen", o.Attribute.MaxNameLen)
+	o.Attribute.MaxValueSize = o.getInt("attribute.maxValueSize", o.Attribute.MaxValueSize)
+	if userAttrs := o.getStringSlice("attribute.messageUserAttributes"); len(userAttrs) > 0 {
+		o.Attribute.MessageUserAttributes = userAttrs
+	}
+	if channelAttrs := o.getStringSlice("attribute.messageChannelAttributes"); len(channelAttrs) > 0 {
+		o.Attribute.MessageChannelAttributes = channelAttrs
+	}
+
+	o.UserData.ScanInterval = o.getDuration("userData.scanInterval", o.UserData.ScanInterval)
+	o.UserData.RetryInterval = o.getDuration("userData.retryInterval", o.UserData.RetryInterval)
+	o.UserData.BatchSize = o.getInt("userData.batchSize", o.UserData.BatchSize)
+	o.UserData.ExportDir = o.getString("userData.exportDir", o.UserData.ExportDir)
+
+	o.E2EE.MaxDevices = o.getInt("e2ee.maxDevices", o.E2EE.MaxDevices)
+	o.E2EE.MaxOneTimePrekeys = o.getInt("e2ee.maxOneTimePrekeys", o.E2EE.MaxOneTimePrekeys)
+
+	o.TransportEncrypt.AEADOn = o.getBool("transportEncrypt.aeadOn", o.TransportEncrypt.AEADOn)
+	o.TransportEncrypt.RekeyMessages = o.getUint64("transportEncrypt.rekeyMessages", o.TransportEncrypt.RekeyMessages)
+	o.TransportEncrypt.RekeyInterval = o.getDuration("transportEncrypt.rekeyInterval", o.TransportEncrypt.RekeyInterval)
+
+	o.Bot.WorkerCount = o.getInt("bot.workerCount", o.Bot.WorkerCount)
+	o.Bot.QueueSize = o.getInt("bot.queueSize", o.Bot.QueueSize)
+	o.Bot.DefaultTimeout = o.getDuration("bot.defaultTimeout", o.Bot.DefaultTimeout)
+	o.Bot.MaxTimeout = o.getDuration("bot.maxTimeout", o.Bot.MaxTimeout)
+	o.Bot.DefaultRateLimit = o.getInt("bot.defaultRateLimit", o.Bot.DefaultRateLimit)
+	o.Bot.MaxReplies = o.getInt("bot.maxReplies", o.Bot.MaxReplies)
+
 	if o.WSSConfig.CertFile != "" && o.WSSConfig.KeyFile != "" {
 		certificate, err := tls.LoadX509KeyPair(o.WSSConfig.CertFile, o.WSSConfig.KeyFile)
 		if err != nil {
@@ -704,6 +1045,13 @@ func (o *Options) ConfigureWithViper(vp *viper.Viper) {
 	o.Cluster.ChannelReactorSubCount = o.getInt("cluster.channelReactorSubCount", o.Cluster.ChannelReactorSubCount)
 	o.Cluster.SlotReactorSubCount = o.getInt("cluster.slotReactorSubCount", o.Cluster.SlotReactorSubCount)
 	o.Cluster.APIUrl = o.getString("cluster.apiUrl", o.Cluster.APIUrl)
+	o.Cluster.ChannelLeaderBalanceOn = o.getBool("cluster.channelLeaderBalanceOn", o.Cluster.ChannelLeaderBalanceOn)
+	o.Cluster.ChannelLeaderBalanceInterval = o.getDuration("cluster.channelLeaderBalanceInterval", o.Cluster.ChannelLeaderBalanceInterval)
+	o.Cluster.ChannelLeaderBalanceThreshold = o.getFloat64("cluster.channelLeaderBalanceThreshold", o.Cluster.ChannelLeaderBalanceThreshold)
+	o.Cluster.ChannelLeaderBalanceMinRate = o.getFloat64("cluster.channelLeaderBalanceMinRate", o.Cluster.ChannelLeaderBalanceMinRate)
+	o.Cluster.ChannelLeaderBalanceMaxTransfer = o.getInt("cluster.channelLeaderBalanceMaxTransfer", o.Cluster.ChannelLeaderBalanceMaxTransfer)
+	o.Cluster.Zone = o.getString("cluster.zone", o.Cluster.Zone)
+	o.Cluster.Rack = o.getString("cluster.rack", o.Cluster.Rack)
 
 	// =================== trace ===================
 	o.Trace.Endpoint = o.getString("trace.endpoint", o.Trace.Endpoint)
@@ -778,7 +1126,15 @@ func (o *Options) configureAuth() {
 	o.Auth.On = o.getBool("auth.on", o.Auth.On)
 	o.Auth.SuperToken = o.getString("auth.superToken", o.Auth.SuperToken)
 	o.Auth.Kind = auth.Kind(o.getString("auth.kind", string(o.Auth.Kind)))
-	authUsers := o.getStringSlice("auth.users")
+	usersCfgs, err := parseAuthUsers(o.getStringSlice("auth.users"), o.ManagerUID)
+	if err != nil {
+		wklog.Panic("parse auth users failed", zap.Error(err))
+	}
+	o.Auth.Users = usersCfgs
+}
+
+// parseAuthUsers 解析auth.users配置，格式为 用户名:密码:* 或 用户名:密码[资源:权限,资源:权限]
+func parseAuthUsers(authUsers []string, managerUID string) ([]auth.UserConfig, error) {
 
 	usersCfgs := make([]auth.UserConfig, 0)
 	for _, authUserStr := range authUsers {
@@ -792,12 +1148,12 @@ func (o *Options) configureAuth() {
 				permissionStr := match[0]
 				userStrs := strings.Split(authUserStr, ":")
 				if len(userStrs) < 2 {
-					wklog.Panic("auth user format error", zap.String("authUserStr", authUserStr))
+					return nil, fmt.Errorf("auth user format error: %s", authUserStr)
 				}
 				username := userStrs[0]
 
-				if username == o.ManagerUID {
-					wklog.Panic("auth user username can not be manager", zap.String("username", username))
+				if username == managerUID {
+					return nil, fmt.Errorf("auth user username can not be manager: %s", username)
 				}
 
 				password := userStrs[1]
@@ -835,19 +1191,19 @@ func (o *Options) configureAuth() {
 				}
 
 			} else {
-				wklog.Panic("auth user format error", zap.String("authUserStr", authUserStr))
+				return nil, fmt.Errorf("auth user format error: %s", authUserStr)
 			}
 		} else {
 			userStrs := strings.Split(authUserStr, ":")
 			if len(userStrs) != 3 {
-				wklog.Panic("auth user format error", zap.String("authUserStr", authUserStr))
+				return nil, fmt.Errorf("auth user format error: %s", authUserStr)
 			}
 			username := userStrs[0]
 			password := userStrs[1]
 			userCfg.Username = username
 			userCfg.Password = password
 			if userStrs[2] != string(resource.All) {
-				wklog.Panic("auth user permission format error", zap.String("authUserStr", authUserStr))
+				return nil, fmt.Errorf("auth user permission format error: %s", authUserStr)
 			}
 			userCfg.Permissions = []auth.PermissionConfig{
 				{
@@ -875,7 +1231,7 @@ func (o *Options) configureAuth() {
 
 	// 将系统管理员的权限设置为所有
 	usersCfgs = append(usersCfgs, auth.UserConfig{
-		Username: o.ManagerUID,
+		Username: managerUID,
 		Permissions: []auth.PermissionConfig{
 			{
 				Resource: resource.All,
@@ -884,7 +1240,7 @@ func (o *Options) configureAuth() {
 		},
 	})
 
-	o.Auth.Users = usersCfgs
+	return usersCfgs, nil
 }
 
 func (o *Options) ConfigureDataDir() {
@@ -919,18 +1275,7 @@ func (o *Options) ClusterOn() bool {
 }
 
 func (o *Options) configureLog(vp *viper.Viper) {
-	logLevel := vp.GetInt("logger.level")
-	// level
-	if logLevel == 0 { // 没有设置
-		if o.Mode == DebugMode {
-			logLevel = int(zapcore.DebugLevel)
-		} else {
-			logLevel = int(zapcore.InfoLevel)
-		}
-	} else {
-		logLevel = logLevel - 2
-	}
-	o.Logger.Level = zapcore.Level(logLevel)
+	o.Logger.Level = o.getLoggerLevel()
 	o.Logger.Dir = vp.GetString("logger.dir")
 	if strings.TrimSpace(o.Logger.Dir) == "" {
 		o.Logger.Dir = "logs"
@@ -947,6 +1292,22 @@ func (o *Options) configureLog(vp *viper.Viper) {
 	o.Logger.Loki.Password = o.getString("logger.loki.password", o.Logger.Loki.Password)
 }
 
+// getLoggerLevel 配置的logger.level从1开始（1为debug），没有设置时debug模式为debug级别，其他模式为info级别
+func (o *Options) getLoggerLevel() zapcore.Level {
+	logLevel := o.vp.GetInt("logger.level")
+	// level
+	if logLevel == 0 { // 没有设置
+		if o.Mode == DebugMode {
+			logLevel = int(zapcore.DebugLevel)
+		} else {
+			logLevel = int(zapcore.InfoLevel)
+		}
+	} else {
+		logLevel = logLevel - 2
+	}
+	return zapcore.Level(logLevel)
+}
+
 // IsTmpChannel 是否是临时频道
 func (o *Options) IsTmpChannel(channelID string) bool {
 	return strings.HasSuffix(channelID, o.TmpChannel.Suffix)
@@ -959,6 +1320,171 @@ func (o *Options) ConfigFileUsed() string {
 	return o.vp.ConfigFileUsed()
 }
 
+// LoadReloadable 重新读取配置文件，返回只更新了可重新加载的配置项的新配置（当前配置不变）
+// 可重新加载的配置项：logger.level、webhook.*、datasource.*、tokenAuthOn、tokenAuth.*、auth.on、auth.superToken、auth.kind、auth.users、
+// broadcast.defaultRate、presence.maxSubscribePerConn、ephemeral.coalesceInterval，其他配置项修改后需要重启节点才能生效
+func (o *Options) LoadReloadable() (*Options, error) {
+	if o.vp == nil {
+		return nil, errors.New("options not configured with viper")
+	}
+	if o.vp.ConfigFileUsed() != "" {
+		if err := o.vp.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("read config file error: %w", err)
+		}
+	}
+	d := NewOptions() // 配置项被删除时恢复为默认值
+	n := *o
+
+	n.Logger.Level = n.getLoggerLevel()
+
+	n.Webhook.HTTPAddr = n.getString("webhook.httpAddr", d.Webhook.HTTPAddr)
+	n.Webhook.GRPCAddr = n.getString("webhook.grpcAddr", d.Webhook.GRPCAddr)
+	n.Webhook.MsgNotifyEventPushInterval = n.getDuration("webhook.msgNotifyEventPushInterval", d.Webhook.MsgNotifyEventPushInterval)
+	n.Webhook.MsgNotifyEventCountPerPush = n.getInt("webhook.msgNotifyEventCountPerPush", d.Webhook.MsgNotifyEventCountPerPush)
+	n.Webhook.MsgNotifyEventRetryMaxCount = n.getInt("webhook.msgNotifyEventRetryMaxCount", d.Webhook.MsgNotifyEventRetryMaxCount)
+
+	n.Datasource.Addr = n.getString("datasource.addr", d.Datasource.Addr)
+	n.Datasource.ChannelInfoOn = n.getBool("datasource.channelInfoOn", d.Datasource.ChannelInfoOn)
+
+	n.TokenAuthOn = n.getBool("tokenAuthOn", d.TokenAuthOn)
+	n.TokenAuth.Mode = TokenAuthMode(n.getString("tokenAuth.mode", string(d.TokenAuth.Mode)))
+	n.TokenAuth.Secret = n.getString("tokenAuth.secret", d.TokenAuth.Secret)
+	n.TokenAuth.PublicKeyFile = n.getString("tokenAuth.publicKeyFile", d.TokenAuth.PublicKeyFile)
+	n.TokenAuth.JwksFile = n.getString("tokenAuth.jwksFile", d.TokenAuth.JwksFile)
+	n.TokenAuth.JwksReloadInterval = n.getDuration("tokenAuth.jwksReloadInterval", d.TokenAuth.JwksReloadInterval)
+	n.TokenAuth.Issuer = n.getString("tokenAuth.issuer", d.TokenAuth.Issuer)
+	n.TokenAuth.Audience = n.getString("tokenAuth.audience", d.TokenAuth.Audience)
+	n.TokenAuth.Leeway = n.getDuration("tokenAuth.leeway", d.TokenAuth.Leeway)
+	n.TokenAuth.RevokedTokenTTL = n.getDuration("tokenAuth.revokedTokenTTL", d.TokenAuth.RevokedTokenTTL)
+
+	n.Auth.On = n.getBool("auth.on", d.Auth.On)
+	n.Auth.SuperToken = n.getString("auth.superToken", d.Auth.SuperToken)
+	n.Auth.Kind = auth.Kind(n.getString("auth.kind", string(d.Auth.Kind)))
+	users, err := parseAuthUsers(n.getStringSlice("auth.users"), n.ManagerUID)
+	if err != nil {
+		return nil, err
+	}
+	n.Auth.Users = users
+
+	n.Broadcast.DefaultRate = n.getInt("broadcast.defaultRate", d.Broadcast.DefaultRate)
+	n.Presence.MaxSubscribePerConn = n.getInt("presence.maxSubscribePerConn", d.Presence.MaxSubscribePerConn)
+	n.Ephemeral.CoalesceInterval = n.getDuration("ephemeral.coalesceInterval", d.Ephemeral.CoalesceInterval)
+
+	if err = n.checkReloadable(); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// checkReloadable 检查可重新加载的配置项是否正确
+func (o *Options) checkReloadable() error {
+	if o.Logger.Level < zapcore.DebugLevel || o.Logger.Level > zapcore.FatalLevel {
+		return fmt.Errorf("logger.level must be between 1 and 7, but got %d", int(o.Logger.Level)+2)
+	}
+	if err := checkHTTPAddr(o.Webhook.HTTPAddr); err != nil {
+		return fmt.Errorf("webhook.httpAddr: %w", err)
+	}
+	if strings.TrimSpace(o.Webhook.GRPCAddr) != "" {
+		if _, _, err := net.SplitHostPort(o.Webhook.GRPCAddr); err != nil {
+			return fmt.Errorf("webhook.grpcAddr: %w", err)
+		}
+	}
+	if o.Webhook.MsgNotifyEventPushInterval <= 0 || o.Webhook.MsgNotifyEventCountPerPush <= 0 || o.Webhook.MsgNotifyEventRetryMaxCount <= 0 {
+		return errors.New("webhook.msgNotifyEventPushInterval, webhook.msgNotifyEventCountPerPush and webhook.msgNotifyEventRetryMaxCount must be greater than 0")
+	}
+	if err := checkHTTPAddr(o.Datasource.Addr); err != nil {
+		return fmt.Errorf("datasource.addr: %w", err)
+	}
+	if o.TokenAuth.Mode != TokenAuthModeStore && o.TokenAuth.Mode != TokenAuthModeJwt {
+		return fmt.Errorf("tokenAuth.mode must be store or jwt, but got %s", o.TokenAuth.Mode)
+	}
+	if o.Auth.Kind != auth.KindNone && o.Auth.Kind != auth.KindJWT {
+		return fmt.Errorf("auth.kind must be empty or jwt, but got %s", o.Auth.Kind)
+	}
+	if o.Broadcast.DefaultRate <= 0 {
+		return errors.New("broadcast.defaultRate must be greater than 0")
+	}
+	if o.Presence.MaxSubscribePerConn <= 0 {
+		return errors.New("presence.maxSubscribePerConn must be greater than 0")
+	}
+	if o.Ephemeral.CoalesceInterval < 0 {
+		return errors.New("ephemeral.coalesceInterval must not be negative")
+	}
+	return nil
+}
+
+// ReloadableChanged 返回新配置中发生变化的可重新加载的配置项（不修改当前配置）
+func (o *Options) ReloadableChanged(n *Options) []string {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.diffReloadable(n, false)
+}
+
+// ApplyReloadable 将新配置中可重新加载的配置项更新到当前配置，返回发生变化的配置项
+func (o *Options) ApplyReloadable(n *Options) []string {
+	o.reloadMu.Lock()
+	defer o.reloadMu.Unlock()
+	return o.diffReloadable(n, true)
+}
+
+func (o *Options) diffReloadable(n *Options, apply bool) []string {
+	changed := make([]string, 0)
+	set := func(key string, equal bool, fnc func()) {
+		if equal {
+			return
+		}
+		if apply {
+			fnc()
+		}
+		changed = append(changed, key)
+	}
+	set("logger.level", o.Logger.Level == n.Logger.Level, func() { o.Logger.Level = n.Logger.Level })
+
+	set("webhook.httpAddr", o.Webhook.HTTPAddr == n.Webhook.HTTPAddr, func() { o.Webhook.HTTPAddr = n.Webhook.HTTPAddr })
+	set("webhook.grpcAddr", o.Webhook.GRPCAddr == n.Webhook.GRPCAddr, func() { o.Webhook.GRPCAddr = n.Webhook.GRPCAddr })
+	set("webhook.msgNotifyEventPushInterval", o.Webhook.MsgNotifyEventPushInterval == n.Webhook.MsgNotifyEventPushInterval, func() {
+		o.Webhook.MsgNotifyEventPushInterval = n.Webhook.MsgNotifyEventPushInterval
+	})
+	set("webhook.msgNotifyEventCountPerPush", o.Webhook.MsgNotifyEventCountPerPush == n.Webhook.MsgNotifyEventCountPerPush, func() {
+		o.Webhook.MsgNotifyEventCountPerPush = n.Webhook.MsgNotifyEventCountPerPush
+	})
+	set("webhook.msgNotifyEventRetryMaxCount", o.Webhook.MsgNotifyEventRetryMaxCount == n.Webhook.MsgNotifyEventRetryMaxCount, func() {
+		o.Webhook.MsgNotifyEventRetryMaxCount = n.Webhook.MsgNotifyEventRetryMaxCount
+	})
+
+	set("datasource.addr", o.Datasource.Addr == n.Datasource.Addr, func() { o.Datasource.Addr = n.Datasource.Addr })
+	set("datasource.channelInfoOn", o.Datasource.ChannelInfoOn == n.Datasource.ChannelInfoOn, func() { o.Datasource.ChannelInfoOn = n.Datasource.ChannelInfoOn })
+
+	set("tokenAuthOn", o.TokenAuthOn == n.TokenAuthOn, func() { o.TokenAuthOn = n.TokenAuthOn })
+	set("tokenAuth", o.TokenAuth == n.TokenAuth, func() { o.TokenAuth = n.TokenAuth })
+
+	set("auth", reflect.DeepEqual(o.Auth, n.Auth), func() { o.Auth = n.Auth })
+
+	set("broadcast.defaultRate", o.Broadcast.DefaultRate == n.Broadcast.DefaultRate, func() { o.Broadcast.DefaultRate = n.Broadcast.DefaultRate })
+	set("presence.maxSubscribePerConn", o.Presence.MaxSubscribePerConn == n.Presence.MaxSubscribePerConn, func() {
+		o.Presence.MaxSubscribePerConn = n.Presence.MaxSubscribePerConn
+	})
+	set("ephemeral.coalesceInterval", o.Ephemeral.CoalesceInterval == n.Ephemeral.CoalesceInterval, func() {
+		o.Ephemeral.CoalesceInterval = n.Ephemeral.CoalesceInterval
+	})
+	return changed
+}
+
+// checkHTTPAddr 检查地址是否是http或https地址，为空表示不配置
+func checkHTTPAddr(addr string) error {
+	if strings.TrimSpace(addr) == "" {
+		return nil
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid http address: %s", addr)
+	}
+	return nil
+}
+
 // 是否是单机模式
 func (o *Options) IsSingleNode() bool {
 	return len(o.Cluster.InitNodes) == 0
@@ -1030,17 +1556,67 @@ func (o *Options) getDuration(key string, defaultValue time.Duration) time.Durat
 
 // WebhookOn WebhookOn
 func (o *Options) WebhookOn() bool {
-	return strings.TrimSpace(o.Webhook.HTTPAddr) != "" || o.WebhookGRPCOn()
+	webhook := o.GetWebhook()
+	return strings.TrimSpace(webhook.HTTPAddr) != "" || strings.TrimSpace(webhook.GRPCAddr) != ""
 }
 
 // WebhookGRPCOn 是否配置了webhook grpc地址
 func (o *Options) WebhookGRPCOn() bool {
-	return strings.TrimSpace(o.Webhook.GRPCAddr) != ""
+	return strings.TrimSpace(o.GetWebhook().GRPCAddr) != ""
 }
 
 // HasDatasource 是否有配置数据源
 func (o *Options) HasDatasource() bool {
-	return strings.TrimSpace(o.Datasource.Addr) != ""
+	return strings.TrimSpace(o.GetDatasourceAddr()) != ""
+}
+
+// GetWebhook 获取webhook配置
+func (o *Options) GetWebhook() WebhookOptions {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.Webhook
+}
+
+// GetDatasourceAddr 获取数据源地址
+func (o *Options) GetDatasourceAddr() string {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.Datasource.Addr
+}
+
+// GetTokenAuth 获取是否开启token验证和token验证配置
+func (o *Options) GetTokenAuth() (bool, TokenAuthOptions) {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.TokenAuthOn, o.TokenAuth
+}
+
+// GetAuth 获取管理接口的认证配置
+func (o *Options) GetAuth() auth.AuthConfig {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.Auth
+}
+
+// GetBroadcastDefaultRate 获取广播默认每秒发送的消息数量
+func (o *Options) GetBroadcastDefaultRate() int {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.Broadcast.DefaultRate
+}
+
+// GetPresenceMaxSubscribePerConn 获取单个连接最多可订阅的用户数量
+func (o *Options) GetPresenceMaxSubscribePerConn() int {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.Presence.MaxSubscribePerConn
+}
+
+// GetEphemeralCoalesceInterval 获取信号的合并窗口
+func (o *Options) GetEphemeralCoalesceInterval() time.Duration {
+	o.reloadMu.RLock()
+	defer o.reloadMu.RUnlock()
+	return o.Ephemeral.CoalesceInterval
 }
 
 // 获取客服频道的访客id
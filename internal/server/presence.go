@@ -0,0 +1,812 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// PresenceStatus 用户的在线状态
+type PresenceStatus uint8
+
+const (
+	PresenceStatusOffline PresenceStatus = iota // 离线
+	PresenceStatusOnline                        // 在线
+	PresenceStatusAway                          // 离开
+	PresenceStatusBusy                          // 忙碌
+)
+
+func (p PresenceStatus) String() string {
+	switch p {
+	case PresenceStatusOffline:
+		return "offline"
+	case PresenceStatusOnline:
+		return "online"
+	case PresenceStatusAway:
+		return "away"
+	case PresenceStatusBusy:
+		return "busy"
+	}
+	return "unknown"
+}
+
+// ParsePresenceStatus 解析用户可以自定义的状态（online/away/busy）
+func ParsePresenceStatus(s string) (PresenceStatus, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "online":
+		return PresenceStatusOnline, true
+	case "away":
+		return PresenceStatusAway, true
+	case "busy":
+		return PresenceStatusBusy, true
+	}
+	return PresenceStatusOffline, false
+}
+
+// presence事件推送给客户端时使用的cmd
+const presenceCMD = "presence"
+
+const (
+	presenceCheckInterval = time.Second * 30          // 定时刷新订阅节点和清理状态的间隔
+	presenceWatchTTL      = presenceCheckInterval * 3 // 订阅节点的有效期，订阅节点需要在有效期内刷新
+)
+
+// userPresence 用户的在线状态，由用户所在的领导节点维护
+type userPresence struct {
+	uid      string
+	status   PresenceStatus // 当前状态，离线时为offline
+	custom   PresenceStatus // 用户自定义的状态，在线时生效
+	text     string         // 自定义状态文本
+	lastSeen int64          // 最后在线时间（unix秒）
+}
+
+func (u *userPresence) clone() *userPresence {
+	c := *u
+	return &c
+}
+
+func (u *userPresence) marshalWithEncoder(enc *wkproto.Encoder) {
+	enc.WriteString(u.uid)
+	enc.WriteUint8(uint8(u.status))
+	enc.WriteUint8(uint8(u.custom))
+	enc.WriteString(u.text)
+	enc.WriteInt64(u.lastSeen)
+}
+
+func (u *userPresence) unmarshalWithDecoder(dec *wkproto.Decoder) error {
+	var err error
+	if u.uid, err = dec.String(); err != nil {
+		return err
+	}
+	var status uint8
+	if status, err = dec.Uint8(); err != nil {
+		return err
+	}
+	u.status = PresenceStatus(status)
+	var custom uint8
+	if custom, err = dec.Uint8(); err != nil {
+		return err
+	}
+	u.custom = PresenceStatus(custom)
+	if u.text, err = dec.String(); err != nil {
+		return err
+	}
+	if u.lastSeen, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *userPresence) toResp() *presenceResp {
+	return &presenceResp{
+		UID:      u.uid,
+		Status:   u.status.String(),
+		Text:     u.text,
+		LastSeen: u.lastSeen,
+	}
+}
+
+type userPresenceSet []*userPresence
+
+func (u userPresenceSet) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(u)))
+	for _, p := range u {
+		p.marshalWithEncoder(enc)
+	}
+	return enc.Bytes(), nil
+}
+
+func (u *userPresenceSet) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < count; i++ {
+		p := &userPresence{}
+		if err = p.unmarshalWithDecoder(dec); err != nil {
+			return err
+		}
+		*u = append(*u, p)
+	}
+	return nil
+}
+
+type presenceResp struct {
+	UID      string `json:"uid"`            // 用户uid
+	Status   string `json:"status"`         // 状态 online/away/busy/offline
+	Text     string `json:"text,omitempty"` // 自定义状态文本
+	LastSeen int64  `json:"last_seen"`      // 最后在线时间（unix秒），0表示未知
+}
+
+type presenceWatchReq struct {
+	NodeId uint64   `json:"node_id"` // 订阅节点
+	Uids   []string `json:"uids"`    // 本节点上被订阅的uid
+}
+
+// presenceManager 用户在线状态管理
+// 用户的状态由用户所在的领导节点维护（领导节点上有用户所有的连接），
+// 自定义状态和最后在线时间持久化在用户所在的槽内，内存中只保留在线用户的状态，领导切换后由新的领导节点从存储重建。
+// 有连接订阅了某个用户的节点会定时向此用户的领导节点登记，状态变化后只广播给登记过的节点，
+// 各节点再推送给本节点上订阅了此用户的连接
+type presenceManager struct {
+	s *Server
+	wklog.Log
+
+	mu     sync.RWMutex
+	states map[string]*userPresence // 本节点为领导节点的用户状态
+
+	subMu       sync.RWMutex
+	subscribers map[string]map[int64]*connContext // 被订阅的uid -> 本节点上订阅了此uid的连接
+	connSubs    map[int64]map[string]struct{}     // 连接ID -> 此连接订阅的uid
+
+	watchMu  sync.Mutex
+	watchers map[string]map[uint64]int64 // 被订阅的uid -> 订阅了此uid的节点 -> 登记的过期时间（unix秒）
+
+	notifyC chan *userPresence
+	stopC   chan struct{}
+}
+
+func newPresenceManager(s *Server) *presenceManager {
+	return &presenceManager{
+		s:           s,
+		Log:         wklog.NewWKLog("presenceManager"),
+		states:      make(map[string]*userPresence),
+		subscribers: make(map[string]map[int64]*connContext),
+		connSubs:    make(map[int64]map[string]struct{}),
+		watchers:    make(map[string]map[uint64]int64),
+		notifyC:     make(chan *userPresence, 1024),
+		stopC:       make(chan struct{}),
+	}
+}
+
+func (p *presenceManager) start() error {
+	go p.loopNotify()
+	go p.loopCheck()
+	return nil
+}
+
+func (p *presenceManager) stop() {
+	close(p.stopC)
+}
+
+// online 用户上线（在用户的领导节点上调用）
+func (p *presenceManager) online(uid string) {
+	p.mu.Lock()
+	state := p.getOrCreateState(uid)
+	status := state.custom
+	if status == PresenceStatusOffline {
+		status = PresenceStatusOnline
+	}
+	changed := state.status != status
+	state.status = status
+	state.lastSeen = time.Now().Unix()
+	event := state.clone()
+	p.mu.Unlock()
+
+	if changed {
+		p.broadcast(event)
+	}
+}
+
+// offline 用户所有连接都已断开（在用户的领导节点上调用）
+// 最后在线时间持久化后从内存中移除此用户的状态
+func (p *presenceManager) offline(uid string) {
+	if p.s.userReactor.getConnContextCount(uid) > 0 { // 用户已重新连接
+		return
+	}
+	p.mu.Lock()
+	state := p.getOrCreateState(uid)
+	changed := state.status != PresenceStatusOffline
+	state.status = PresenceStatusOffline
+	state.lastSeen = time.Now().Unix()
+	event := state.clone()
+	p.mu.Unlock()
+
+	if err := p.persist(event); err != nil {
+		p.Error("offline: persist presence failed", zap.Error(err), zap.String("uid", uid))
+	} else {
+		p.evictOffline(uid)
+	}
+
+	if changed {
+		p.broadcast(event)
+	}
+}
+
+// setStatus 设置用户自定义的状态（在用户的领导节点上调用）
+func (p *presenceManager) setStatus(uid string, status PresenceStatus, text string) (*userPresence, error) {
+	online := p.s.userReactor.getConnContextCount(uid) > 0
+
+	p.mu.Lock()
+	state := p.getOrCreateState(uid)
+	event := state.clone()
+	p.mu.Unlock()
+
+	event.custom = status
+	event.text = text
+	if online {
+		event.status = status
+		event.lastSeen = time.Now().Unix()
+	}
+	if err := p.persist(event); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	state = p.getOrCreateState(uid)
+	state.custom = status
+	state.text = text
+	if online {
+		state.status = status
+		state.lastSeen = event.lastSeen
+	}
+	event = state.clone()
+	p.mu.Unlock()
+
+	if !online {
+		p.evictOffline(uid)
+	}
+
+	p.broadcast(event)
+	return event, nil
+}
+
+// persist 持久化用户自定义的状态和最后在线时间
+func (p *presenceManager) persist(state *userPresence) error {
+	return p.s.store.SetUserPresence(wkdb.UserPresence{
+		Uid:      state.uid,
+		Custom:   uint8(state.custom),
+		Text:     state.text,
+		LastSeen: state.lastSeen,
+	})
+}
+
+// evictOffline 移除已持久化的离线用户状态
+func (p *presenceManager) evictOffline(uid string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if state := p.states[uid]; state != nil && state.status == PresenceStatusOffline {
+		delete(p.states, uid)
+	}
+}
+
+func (p *presenceManager) getOrCreateState(uid string) *userPresence {
+	state := p.states[uid]
+	if state == nil {
+		state = p.loadState(uid)
+		p.states[uid] = state
+	}
+	return state
+}
+
+// loadState 从存储中加载用户的状态（领导节点切换或状态已被移除时），状态为离线
+func (p *presenceManager) loadState(uid string) *userPresence {
+	state := &userPresence{
+		uid:    uid,
+		custom: PresenceStatusOnline,
+	}
+	presence, err := p.s.store.GetUserPresence(uid)
+	if err != nil {
+		p.Warn("loadState: get presence failed", zap.Error(err), zap.String("uid", uid))
+		return state
+	}
+	if wkdb.IsEmptyUserPresence(presence) {
+		return state
+	}
+	if custom := PresenceStatus(presence.Custom); custom != PresenceStatusOffline {
+		state.custom = custom
+	}
+	state.text = presence.Text
+	state.lastSeen = presence.LastSeen
+	return state
+}
+
+// localPresences 获取本节点（领导节点）上维护的用户状态
+func (p *presenceManager) localPresences(uids []string) []*userPresence {
+	presences := make([]*userPresence, 0, len(uids))
+	for _, uid := range uids {
+		p.mu.RLock()
+		state := p.states[uid]
+		if state != nil {
+			state = state.clone()
+		}
+		p.mu.RUnlock()
+		if state != nil {
+			presences = append(presences, state)
+			continue
+		}
+		// 内存中没有状态（用户离线或领导节点刚发生切换），从存储中加载，是否在线根据连接判断
+		state = p.loadState(uid)
+		if p.s.userReactor.getConnContextCount(uid) > 0 {
+			state.status = state.custom
+		}
+		presences = append(presences, state)
+	}
+	return presences
+}
+
+// getPresences 获取用户的状态，按用户所在的领导节点分组请求
+func (p *presenceManager) getPresences(uids []string) ([]*userPresence, error) {
+	uids = wkutil.RemoveRepeatedElement(uids)
+	nodeUids := make(map[uint64][]string)
+	for _, uid := range uids {
+		leaderId, err := p.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			return nil, err
+		}
+		nodeUids[leaderId] = append(nodeUids[leaderId], uid)
+	}
+
+	var (
+		presences = make([]*userPresence, 0, len(uids))
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		reqErr    error
+	)
+	for nodeId, nodeUidList := range nodeUids {
+		if nodeId == p.s.opts.Cluster.NodeId {
+			localPresences := p.localPresences(nodeUidList)
+			mu.Lock()
+			presences = append(presences, localPresences...)
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(nodeId uint64, nodeUidList []string) {
+			defer wg.Done()
+			results, err := p.requestPresences(nodeId, nodeUidList)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				reqErr = err
+				return
+			}
+			presences = append(presences, results...)
+		}(nodeId, nodeUidList)
+	}
+	wg.Wait()
+	if reqErr != nil {
+		return nil, reqErr
+	}
+	return presences, nil
+}
+
+func (p *presenceManager) requestPresences(nodeId uint64, uids []string) ([]*userPresence, error) {
+	timeoutCtx, cancel := context.WithTimeout(p.s.ctx, p.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := p.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/getPresences", []byte(wkutil.ToJSON(uids)))
+	if err != nil {
+		p.Error("requestPresences failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	var presences userPresenceSet
+	if err = presences.Unmarshal(resp.Body); err != nil {
+		return nil, err
+	}
+	return presences, nil
+}
+
+// handleGetPresences 处理其他节点获取用户状态的请求
+func (p *presenceManager) handleGetPresences(c *wkserver.Context) {
+	var uids []string
+	if err := wkutil.ReadJSONByByte(c.Body(), &uids); err != nil {
+		p.Error("handleGetPresences: decode uids failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	data, err := userPresenceSet(p.localPresences(uids)).Marshal()
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write(data)
+}
+
+// broadcast 将状态变化广播给订阅了此用户的节点
+func (p *presenceManager) broadcast(event *userPresence) {
+	nodeIds := p.watcherNodes(event.uid)
+	if len(nodeIds) > 0 {
+		data, err := userPresenceSet{event}.Marshal()
+		if err != nil {
+			p.Error("broadcast: marshal presence failed", zap.Error(err))
+			return
+		}
+		for _, nodeId := range nodeIds {
+			err = p.s.cluster.Send(nodeId, &proto.Message{
+				MsgType: uint32(ClusterMsgTypePresence),
+				Content: data,
+			})
+			if err != nil {
+				p.Warn("broadcast presence failed", zap.Error(err), zap.Uint64("nodeId", nodeId), zap.String("uid", event.uid))
+			}
+		}
+	}
+	p.notify(event)
+}
+
+// watcherNodes 获取订阅了此用户的其他节点（登记未过期的）
+func (p *presenceManager) watcherNodes(uid string) []uint64 {
+	now := time.Now().Unix()
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+	nodeIds := make([]uint64, 0, len(p.watchers[uid]))
+	for nodeId, expireAt := range p.watchers[uid] {
+		if nodeId == p.s.opts.Cluster.NodeId || expireAt < now {
+			continue
+		}
+		nodeIds = append(nodeIds, nodeId)
+	}
+	return nodeIds
+}
+
+// addWatchers 登记订阅了这些用户的节点（在用户的领导节点上调用）
+func (p *presenceManager) addWatchers(nodeId uint64, uids []string) {
+	expireAt := time.Now().Add(presenceWatchTTL).Unix()
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+	for _, uid := range uids {
+		nodes := p.watchers[uid]
+		if nodes == nil {
+			nodes = make(map[uint64]int64)
+			p.watchers[uid] = nodes
+		}
+		nodes[nodeId] = expireAt
+	}
+}
+
+// expireWatchers 移除过期的订阅节点（订阅节点不再刷新，比如订阅已取消或节点已下线）
+func (p *presenceManager) expireWatchers() {
+	now := time.Now().Unix()
+	p.watchMu.Lock()
+	defer p.watchMu.Unlock()
+	for uid, nodes := range p.watchers {
+		for nodeId, expireAt := range nodes {
+			if expireAt < now {
+				delete(nodes, nodeId)
+			}
+		}
+		if len(nodes) == 0 {
+			delete(p.watchers, uid)
+		}
+	}
+}
+
+// watch 向用户所在的领导节点登记本节点订阅了这些用户
+func (p *presenceManager) watch(uids []string) {
+	nodeUids := make(map[uint64][]string)
+	for _, uid := range uids {
+		leaderId, err := p.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			p.Warn("watch: get leader failed", zap.Error(err), zap.String("uid", uid))
+			continue
+		}
+		nodeUids[leaderId] = append(nodeUids[leaderId], uid)
+	}
+	for nodeId, nodeUidList := range nodeUids {
+		if nodeId == p.s.opts.Cluster.NodeId {
+			p.addWatchers(nodeId, nodeUidList)
+			continue
+		}
+		if err := p.requestWatch(nodeId, nodeUidList); err != nil {
+			p.Warn("watch: request failed", zap.Error(err), zap.Uint64("nodeId", nodeId))
+		}
+	}
+}
+
+func (p *presenceManager) requestWatch(nodeId uint64, uids []string) error {
+	req := presenceWatchReq{
+		NodeId: p.s.opts.Cluster.NodeId,
+		Uids:   uids,
+	}
+	timeoutCtx, cancel := context.WithTimeout(p.s.ctx, p.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := p.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/presence/watch", []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return errors.New(string(resp.Body))
+	}
+	return nil
+}
+
+// handleWatch 处理其他节点的订阅登记
+func (p *presenceManager) handleWatch(c *wkserver.Context) {
+	var req presenceWatchReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		p.Error("handleWatch: decode req failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	p.addWatchers(req.NodeId, req.Uids)
+	c.WriteOk()
+}
+
+func (p *presenceManager) loopCheck() {
+	tk := time.NewTicker(presenceCheckInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			p.watch(p.subscribedUids())
+			p.expireWatchers()
+			p.evictNotLeader()
+		case <-p.stopC:
+			return
+		}
+	}
+}
+
+// subscribedUids 本节点上被订阅的uid
+func (p *presenceManager) subscribedUids() []string {
+	p.subMu.RLock()
+	defer p.subMu.RUnlock()
+	uids := make([]string, 0, len(p.subscribers))
+	for uid := range p.subscribers {
+		uids = append(uids, uid)
+	}
+	return uids
+}
+
+// evictNotLeader 移除本节点已不是领导节点的用户状态，由新的领导节点从存储重建
+func (p *presenceManager) evictNotLeader() {
+	p.mu.RLock()
+	uids := make([]string, 0, len(p.states))
+	for uid := range p.states {
+		uids = append(uids, uid)
+	}
+	p.mu.RUnlock()
+
+	for _, uid := range uids {
+		leaderId, err := p.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil || leaderId == p.s.opts.Cluster.NodeId {
+			continue
+		}
+		p.mu.Lock()
+		delete(p.states, uid)
+		p.mu.Unlock()
+	}
+}
+
+// handlePresenceMessage 处理其他节点广播的状态变化
+func (p *presenceManager) handlePresenceMessage(msg *proto.Message) {
+	var presences userPresenceSet
+	if err := presences.Unmarshal(msg.Content); err != nil {
+		p.Error("handlePresenceMessage: unmarshal failed", zap.Error(err))
+		return
+	}
+	for _, presence := range presences {
+		p.notify(presence)
+	}
+}
+
+// notify 推送队列满时丢弃事件，不阻塞状态变更
+func (p *presenceManager) notify(event *userPresence) {
+	select {
+	case p.notifyC <- event:
+	default:
+		p.Warn("notify: queue is full, drop presence event", zap.String("uid", event.uid), zap.String("status", event.status.String()))
+	}
+}
+
+func (p *presenceManager) loopNotify() {
+	for {
+		select {
+		case event := <-p.notifyC:
+			p.notifyLocalSubscribers(event)
+		case <-p.stopC:
+			return
+		}
+	}
+}
+
+// notifyLocalSubscribers 推送给本节点上订阅了此用户的连接
+func (p *presenceManager) notifyLocalSubscribers(event *userPresence) {
+	p.subMu.RLock()
+	conns := make([]*connContext, 0, len(p.subscribers[event.uid]))
+	for _, conn := range p.subscribers[event.uid] {
+		conns = append(conns, conn)
+	}
+	p.subMu.RUnlock()
+
+	for _, conn := range conns {
+		p.writePresence(conn, event)
+	}
+}
+
+// writePresence 以不存储的cmd消息将用户状态推送给连接
+func (p *presenceManager) writePresence(conn *connContext, event *userPresence) {
+	if conn.isClosed() {
+		return
+	}
+	payload := []byte(wkutil.ToJSON(map[string]interface{}{
+		"cmd":  presenceCMD,
+		"data": event.toResp(),
+	}))
+	recvPacket := &wkproto.RecvPacket{
+		Framer: wkproto.Framer{
+			NoPersist: true,
+		},
+		MessageID:   p.s.channelReactor.messageIDGen.Generate().Int64(),
+		FromUID:     event.uid,
+		ChannelID:   p.s.opts.OrginalConvertCmdChannel(event.uid),
+		ChannelType: wkproto.ChannelTypePerson,
+		Timestamp:   int32(time.Now().Unix()),
+		Payload:     payload,
+	}
+	payloadEnc, err := encryptMessagePayload(recvPacket.Payload, conn)
+	if err != nil {
+		p.Error("writePresence: encrypt payload failed", zap.Error(err), zap.String("uid", conn.uid))
+		return
+	}
+	recvPacket.Payload = payloadEnc
+	msgKey, err := makeMsgKey(recvPacket.VerityString(), conn)
+	if err != nil {
+		p.Error("writePresence: make msgKey failed", zap.Error(err), zap.String("uid", conn.uid))
+		return
+	}
+	recvPacket.MsgKey = msgKey
+	if err = conn.writePacket(recvPacket); err != nil {
+		p.Error("writePresence: write packet failed", zap.Error(err), zap.String("uid", conn.uid))
+	}
+}
+
+// subscribe 连接订阅用户的状态，返回false表示超过了单个连接的订阅上限
+func (p *presenceManager) subscribe(conn *connContext, uid string) bool {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	uids := p.connSubs[conn.connId]
+	if uids == nil {
+		uids = make(map[string]struct{})
+		p.connSubs[conn.connId] = uids
+	}
+	if _, ok := uids[uid]; !ok && len(uids) >= p.s.opts.GetPresenceMaxSubscribePerConn() {
+		return false
+	}
+	uids[uid] = struct{}{}
+
+	conns := p.subscribers[uid]
+	if conns == nil {
+		conns = make(map[int64]*connContext)
+		p.subscribers[uid] = conns
+	}
+	conns[conn.connId] = conn
+	return true
+}
+
+// unsubscribe 连接取消订阅用户的状态
+func (p *presenceManager) unsubscribe(connId int64, uid string) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	p.unsubscribeNoLock(connId, uid)
+}
+
+func (p *presenceManager) unsubscribeNoLock(connId int64, uid string) {
+	if uids := p.connSubs[connId]; uids != nil {
+		delete(uids, uid)
+		if len(uids) == 0 {
+			delete(p.connSubs, connId)
+		}
+	}
+	if conns := p.subscribers[uid]; conns != nil {
+		delete(conns, connId)
+		if len(conns) == 0 {
+			delete(p.subscribers, uid)
+		}
+	}
+}
+
+// removeConn 连接关闭后移除此连接的所有订阅
+func (p *presenceManager) removeConn(connId int64) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+	for uid := range p.connSubs[connId] {
+		p.unsubscribeNoLock(connId, uid)
+	}
+}
+
+// handleSubPacket 处理客户端的订阅包，个人频道的订阅即订阅此用户的在线状态
+func (p *presenceManager) handleSubPacket(conn *connContext, packet *wkproto.SubPacket) {
+	reasonCode := wkproto.ReasonSuccess
+	switch {
+	case !p.s.opts.Presence.On:
+		reasonCode = wkproto.ReasonNotSupportChannelType
+	case packet.ChannelType != wkproto.ChannelTypePerson:
+		reasonCode = wkproto.ReasonNotSupportChannelType
+	case strings.TrimSpace(packet.ChannelID) == "" || IsSpecialChar(packet.ChannelID):
+		reasonCode = wkproto.ReasonChannelIDError
+	case packet.Action == wkproto.Subscribe:
+		// 鉴权需要请求对方的领导节点，异步处理
+		go p.handleSubscribe(conn, packet)
+		return
+	case packet.Action == wkproto.UnSubscribe:
+		p.unsubscribe(conn.connId, packet.ChannelID)
+	}
+	p.writeSuback(conn, packet, reasonCode)
+}
+
+func (p *presenceManager) handleSubscribe(conn *connContext, packet *wkproto.SubPacket) {
+	reasonCode := p.allowSubscribe(conn.uid, packet.ChannelID)
+	if reasonCode == wkproto.ReasonSuccess && !p.subscribe(conn, packet.ChannelID) {
+		reasonCode = wkproto.ReasonRateLimit
+	}
+	if !p.writeSuback(conn, packet, reasonCode) || reasonCode != wkproto.ReasonSuccess {
+		return
+	}
+
+	// 登记订阅节点并推送一次当前状态
+	p.watch([]string{packet.ChannelID})
+	presences, err := p.getPresences([]string{packet.ChannelID})
+	if err != nil {
+		p.Error("handleSubscribe: get presences failed", zap.Error(err), zap.String("uid", packet.ChannelID))
+		return
+	}
+	for _, presence := range presences {
+		p.writePresence(conn, presence)
+	}
+}
+
+// allowSubscribe 订阅权限与给对方发消息的权限一致（不在对方的黑名单内，开启白名单时需要在对方的白名单内）
+func (p *presenceManager) allowSubscribe(fromUid string, toUid string) wkproto.ReasonCode {
+	if fromUid == toUid {
+		return wkproto.ReasonSuccess
+	}
+	reasonCode, err := p.s.channelReactor.requestAllowSend(fromUid, toUid)
+	if err != nil {
+		p.Error("allowSubscribe: request allow send failed", zap.Error(err), zap.String("from", fromUid), zap.String("to", toUid))
+		return wkproto.ReasonSystemError
+	}
+	return reasonCode
+}
+
+func (p *presenceManager) writeSuback(conn *connContext, packet *wkproto.SubPacket, reasonCode wkproto.ReasonCode) bool {
+	err := conn.writePacket(&wkproto.SubackPacket{
+		SubNo:       packet.SubNo,
+		ChannelID:   packet.ChannelID,
+		ChannelType: packet.ChannelType,
+		Action:      packet.Action,
+		ReasonCode:  reasonCode,
+	})
+	if err != nil {
+		p.Error("writeSuback: write suback failed", zap.Error(err), zap.String("uid", conn.uid))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePresenceStatus(t *testing.T) {
+	status, ok := ParsePresenceStatus("Away")
+	assert.True(t, ok)
+	assert.Equal(t, PresenceStatusAway, status)
+
+	status, ok = ParsePresenceStatus("busy")
+	assert.True(t, ok)
+	assert.Equal(t, PresenceStatusBusy, status)
+
+	_, ok = ParsePresenceStatus("offline") // 离线状态不能自定义
+	assert.False(t, ok)
+}
+
+func TestUserPresenceSetMarshal(t *testing.T) {
+	presences := userPresenceSet{
+		{uid: "u1", status: PresenceStatusBusy, custom: PresenceStatusBusy, text: "开会中", lastSeen: 1700000000},
+		{uid: "u2", status: PresenceStatusOffline, custom: PresenceStatusOnline, lastSeen: 1700000001},
+	}
+	data, err := presences.Marshal()
+	assert.NoError(t, err)
+
+	var result userPresenceSet
+	err = result.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, presences, result)
+}
+
+func TestPresenceSubscribe(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	s.opts.Presence.MaxSubscribePerConn = 2
+	p := newPresenceManager(s)
+
+	conn := &connContext{}
+	conn.connId = 1
+	conn.uid = "u1"
+	assert.True(t, p.subscribe(conn, "u2"))
+	assert.True(t, p.subscribe(conn, "u3"))
+	assert.True(t, p.subscribe(conn, "u2")) // 重复订阅不计数
+	assert.False(t, p.subscribe(conn, "u4"))
+	assert.Len(t, p.subscribers["u2"], 1)
+
+	p.unsubscribe(conn.connId, "u2")
+	assert.Nil(t, p.subscribers["u2"])
+	assert.Len(t, p.connSubs[conn.connId], 1)
+
+	p.removeConn(conn.connId)
+	assert.Empty(t, p.subscribers)
+	assert.Empty(t, p.connSubs)
+}
+
+func TestPresenceWatchers(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	s.opts.Cluster.NodeId = 1
+	p := newPresenceManager(s)
+
+	p.addWatchers(1, []string{"u1"})
+	p.addWatchers(2, []string{"u1", "u2"})
+	assert.Equal(t, []uint64{2}, p.watcherNodes("u1")) // 不广播给本节点
+	assert.Empty(t, p.watcherNodes("u3"))
+
+	// 没有刷新的订阅节点过期后不再广播
+	p.watchers["u2"][2] = time.Now().Add(-time.Second).Unix()
+	assert.Empty(t, p.watcherNodes("u2"))
+	p.expireWatchers()
+	assert.Nil(t, p.watchers["u2"])
+	assert.Len(t, p.watchers["u1"], 2)
+}
+
+func TestPresenceNotifyDropWhenFull(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	p := newPresenceManager(s)
+	p.notifyC = make(chan *userPresence, 1)
+
+	p.notify(&userPresence{uid: "u1"})
+	p.notify(&userPresence{uid: "u2"}) // 队列已满，直接丢弃
+	assert.Len(t, p.notifyC, 1)
+	assert.Equal(t, "u1", (<-p.notifyC).uid)
+}
+
+func TestPresenceLoadState(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	err = s.store.DB().SetUserPresence(wkdb.UserPresence{Uid: "u1", Custom: uint8(PresenceStatusBusy), Text: "开会中", LastSeen: 1700000000})
+	assert.NoError(t, err)
+
+	// 内存中没有状态时从存储中重建，没有连接时为离线
+	presences := s.presenceManager.localPresences([]string{"u1", "u2"})
+	assert.Len(t, presences, 2)
+	assert.Equal(t, &userPresence{uid: "u1", status: PresenceStatusOffline, custom: PresenceStatusBusy, text: "开会中", lastSeen: 1700000000}, presences[0])
+	assert.Equal(t, &userPresence{uid: "u2", status: PresenceStatusOffline, custom: PresenceStatusOnline}, presences[1])
+	assert.Empty(t, s.presenceManager.states)
+
+	s.presenceManager.online("u1")
+	assert.Equal(t, PresenceStatusBusy, s.presenceManager.states["u1"].status)
+	assert.Equal(t, "开会中", s.presenceManager.states["u1"].text)
+}
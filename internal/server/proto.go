@@ -114,7 +114,14 @@ func (s *Server) onData(conn wknet.Conn) error {
 			}
 			offset += size
 			if frame.GetFrameType() == wkproto.SEND {
-				connCtx.addSendPacket(frame.(*wkproto.SendPacket))
+				sendPacket := frame.(*wkproto.SendPacket)
+				if sendPacket.Setting.IsSet(SettingEphemeral) { // 瞬时信号不走消息流程
+					s.ephemeralManager.addSendPacket(connCtx, sendPacket)
+				} else {
+					connCtx.addSendPacket(sendPacket)
+				}
+			} else if frame.GetFrameType() == wkproto.SUB { // 订阅用户在线状态
+				s.presenceManager.handleSubPacket(connCtx, frame.(*wkproto.SubPacket))
 			} else {
 				connCtx.addOtherPacket(frame)
 			}
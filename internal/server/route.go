@@ -0,0 +1,251 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// nodeRoute 节点对外的长连接地址（与/route返回的一致）
+type nodeRoute struct {
+	NodeId  uint64 `json:"node_id"`
+	TCPAddr string `json:"tcp_addr"`
+	WSAddr  string `json:"ws_addr"`
+	WSSAddr string `json:"wss_addr"`
+}
+
+// nodeRouteInfo 节点的路由信息（对外地址和负载）
+type nodeRouteInfo struct {
+	nodeRoute
+	ConnCount int  `json:"conn_count"` // 客户端连接数量
+	Draining  bool `json:"draining"`   // 是否下线中
+
+	updatedAt time.Time // 最后一次获取到此信息的时间
+}
+
+// routeManager 客户端路由管理，定时获取集群内其他节点的对外地址和连接数，为用户选择合适的连接节点
+// 选择规则：跳过离线、下线中以及长时间没有获取到信息的节点，优先选择用户所在槽的领导节点（减少转发），
+// 领导节点的连接数明显高于平均值时选择连接数最少的节点
+type routeManager struct {
+	s *Server
+	wklog.Log
+
+	mu    sync.RWMutex
+	nodes map[uint64]*nodeRouteInfo // 其他节点的路由信息
+
+	stopC chan struct{}
+}
+
+func newRouteManager(s *Server) *routeManager {
+	return &routeManager{
+		s:     s,
+		Log:   wklog.NewWKLog("routeManager"),
+		nodes: make(map[uint64]*nodeRouteInfo),
+		stopC: make(chan struct{}),
+	}
+}
+
+func (r *routeManager) start() error {
+	if r.s.opts.ClusterOn() {
+		go r.loopRefresh()
+	}
+	return nil
+}
+
+func (r *routeManager) stop() {
+	close(r.stopC)
+}
+
+func (r *routeManager) loopRefresh() {
+	tk := time.NewTicker(r.s.opts.Route.RefreshInterval)
+	defer tk.Stop()
+	for {
+		r.refresh()
+		select {
+		case <-tk.C:
+		case <-r.stopC:
+			return
+		}
+	}
+}
+
+// refresh 获取其他在线节点的路由信息
+func (r *routeManager) refresh() {
+	cfg := r.s.clusterServer.GetConfig()
+	nodeIds := make(map[uint64]struct{}, len(cfg.Nodes))
+	for _, node := range cfg.Nodes {
+		if node.Id == r.s.opts.Cluster.NodeId {
+			continue
+		}
+		nodeIds[node.Id] = struct{}{}
+		if !node.Online {
+			continue
+		}
+		info, err := r.requestRouteInfo(node.Id)
+		if err != nil {
+			r.Debug("request route info failed", zap.Error(err), zap.Uint64("nodeId", node.Id))
+			continue
+		}
+		info.NodeId = node.Id
+		info.updatedAt = time.Now()
+		r.mu.Lock()
+		r.nodes[node.Id] = info
+		r.mu.Unlock()
+	}
+
+	// 移除已经不在集群里的节点
+	r.mu.Lock()
+	for nodeId := range r.nodes {
+		if _, ok := nodeIds[nodeId]; !ok {
+			delete(r.nodes, nodeId)
+		}
+	}
+	r.mu.Unlock()
+}
+
+func (r *routeManager) requestRouteInfo(nodeId uint64) (*nodeRouteInfo, error) {
+	timeoutCtx, cancel := context.WithTimeout(r.s.ctx, r.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := r.s.cluster.RequestWithContext(timeoutCtx, nodeId, "/wk/routeInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, errors.New(string(resp.Body))
+	}
+	info := &nodeRouteInfo{}
+	if err = wkutil.ReadJSONByByte(resp.Body, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// handleRouteInfoReq 返回本节点的路由信息
+func (r *routeManager) handleRouteInfoReq(c *wkserver.Context) {
+	c.Write([]byte(wkutil.ToJSON(r.localInfo())))
+}
+
+// localInfo 本节点的路由信息
+func (r *routeManager) localInfo() *nodeRouteInfo {
+	return &nodeRouteInfo{
+		nodeRoute: nodeRoute{
+			NodeId:  r.s.opts.Cluster.NodeId,
+			TCPAddr: r.s.opts.External.TCPAddr,
+			WSAddr:  r.s.opts.External.WSAddr,
+			WSSAddr: r.s.opts.External.WSSAddr,
+		},
+		ConnCount: r.s.engine.ConnCount(),
+		Draining:  r.s.drainManager.isDraining(),
+		updatedAt: time.Now(),
+	}
+}
+
+// availableNodes 可以接受客户端连接的节点（包含本节点），按节点id排序
+func (r *routeManager) availableNodes() []*nodeRouteInfo {
+	nodes := make([]*nodeRouteInfo, 0)
+	if local := r.localInfo(); !local.Draining {
+		nodes = append(nodes, local)
+	}
+	if !r.s.opts.ClusterOn() {
+		return nodes
+	}
+
+	expire := time.Now().Add(-r.s.opts.Route.RefreshInterval * 3)
+	r.mu.RLock()
+	for nodeId, info := range r.nodes {
+		if info.Draining || info.updatedAt.Before(expire) || r.s.clusterServer.NodeDraining(nodeId) {
+			continue
+		}
+		if !r.s.clusterServer.NodeIsOnline(nodeId) {
+			continue
+		}
+		cp := *info
+		nodes = append(nodes, &cp)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].NodeId < nodes[j].NodeId
+	})
+	return nodes
+}
+
+// otherRoutes 除本节点外其他可用节点的地址
+func (r *routeManager) otherRoutes() []*nodeRoute {
+	routes := make([]*nodeRoute, 0)
+	for _, node := range r.availableNodes() {
+		if node.NodeId == r.s.opts.Cluster.NodeId {
+			continue
+		}
+		route := node.nodeRoute
+		routes = append(routes, &route)
+	}
+	return routes
+}
+
+// route 为用户选择连接节点，没有可用节点时返回本节点
+func (r *routeManager) route(uid string) *nodeRoute {
+	return r.routeOfNodes(uid, r.availableNodes())
+}
+
+func (r *routeManager) routeOfNodes(uid string, nodes []*nodeRouteInfo) *nodeRoute {
+	var leaderId uint64
+	if r.s.opts.ClusterOn() && uid != "" {
+		var err error
+		leaderId, err = r.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			r.Debug("get slot leader failed", zap.Error(err), zap.String("uid", uid))
+		}
+	}
+	node := pickRouteNode(nodes, leaderId, r.s.opts.Route.LoadThreshold)
+	if node == nil {
+		local := r.localInfo().nodeRoute
+		return &local
+	}
+
+	// 预估被选中节点的连接数，避免两次刷新之间的请求都选中同一个节点
+	if node.NodeId != r.s.opts.Cluster.NodeId {
+		r.mu.Lock()
+		if info := r.nodes[node.NodeId]; info != nil {
+			info.ConnCount++
+		}
+		r.mu.Unlock()
+	}
+	node.ConnCount++
+	route := node.nodeRoute
+	return &route
+}
+
+// pickRouteNode 优先选择领导节点，领导节点不可用或者连接数超过平均值的(1+loadThreshold)倍时选择连接数最少的节点
+func pickRouteNode(nodes []*nodeRouteInfo, leaderId uint64, loadThreshold float64) *nodeRouteInfo {
+	if len(nodes) == 0 {
+		return nil
+	}
+	total := 0
+	var leader, least *nodeRouteInfo
+	for _, node := range nodes {
+		total += node.ConnCount
+		if node.NodeId == leaderId {
+			leader = node
+		}
+		if least == nil || node.ConnCount < least.ConnCount {
+			least = node
+		}
+	}
+	if leader != nil {
+		avg := float64(total) / float64(len(nodes))
+		if float64(leader.ConnCount) <= avg*(1+loadThreshold) || leader.ConnCount-least.ConnCount <= 1 {
+			return leader
+		}
+	}
+	return least
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickRouteNode(t *testing.T) {
+	assert.Nil(t, pickRouteNode(nil, 1, 0.2))
+
+	newNodes := func(counts ...int) []*nodeRouteInfo {
+		nodes := make([]*nodeRouteInfo, 0, len(counts))
+		for i, count := range counts {
+			nodes = append(nodes, &nodeRouteInfo{nodeRoute: nodeRoute{NodeId: uint64(i + 1)}, ConnCount: count})
+		}
+		return nodes
+	}
+
+	// 领导节点负载正常时优先选择领导节点
+	assert.Equal(t, uint64(2), pickRouteNode(newNodes(100, 110, 90), 2, 0.2).NodeId)
+
+	// 领导节点负载过高时选择连接数最少的节点
+	assert.Equal(t, uint64(3), pickRouteNode(newNodes(100, 200, 90), 2, 0.2).NodeId)
+
+	// 领导节点不可用（不在列表里）时选择连接数最少的节点
+	assert.Equal(t, uint64(1), pickRouteNode(newNodes(10, 20), 5, 0.2).NodeId)
+
+	// 连接数很少时差距不超过1也选择领导节点
+	assert.Equal(t, uint64(2), pickRouteNode(newNodes(0, 1), 2, 0).NodeId)
+}
+
+func TestRouteOfNodesSpreadLoad(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	r := newRouteManager(s)
+
+	nodes := []*nodeRouteInfo{
+		{nodeRoute: nodeRoute{NodeId: 1, TCPAddr: "127.0.0.1:5100"}, ConnCount: 0},
+		{nodeRoute: nodeRoute{NodeId: 2, TCPAddr: "127.0.0.1:5200"}, ConnCount: 2},
+	}
+	// 没有领导节点时按连接数分配，选中后预估的连接数会增加
+	counts := map[uint64]int{}
+	for i := 0; i < 6; i++ {
+		route := r.routeOfNodes("", nodes)
+		counts[route.NodeId]++
+	}
+	assert.Equal(t, 4, counts[1])
+	assert.Equal(t, 2, counts[2])
+}
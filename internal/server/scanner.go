@@ -0,0 +1,45 @@
+package server
+
+import "time"
+
+// intervalScanner 后台定时扫描，例如槽的领导节点定时扫描需要执行的定时消息、广播和用户数据任务
+type intervalScanner struct {
+	interval time.Duration
+	scanFnc  func()
+	stopC    chan struct{}
+}
+
+func newIntervalScanner(interval time.Duration, scanFnc func()) *intervalScanner {
+	return &intervalScanner{
+		interval: interval,
+		scanFnc:  scanFnc,
+		stopC:    make(chan struct{}),
+	}
+}
+
+func (s *intervalScanner) start() {
+	go s.loop()
+}
+
+// stop 停止扫描，没有start也可以调用
+func (s *intervalScanner) stop() {
+	close(s.stopC)
+}
+
+// stopped 扫描停止后关闭，耗时的扫描或任务需要检查并及时退出
+func (s *intervalScanner) stopped() <-chan struct{} {
+	return s.stopC
+}
+
+func (s *intervalScanner) loop() {
+	tk := time.NewTicker(s.interval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			s.scanFnc()
+		case <-s.stopC:
+			return
+		}
+	}
+}
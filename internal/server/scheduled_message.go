@@ -0,0 +1,128 @@
+package server
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 定时消息到期后超过此时间仍然发送失败将被丢弃
+const scheduledMessageDropAfter = time.Hour
+
+// scheduledMessageManager 定时消息管理
+// 定时消息与目标频道存放在同一个槽内，由槽的领导节点定时扫描到期的消息并通过正常的发送流程发送，
+// 发送成功后删除。槽领导切换后由新的领导节点继续发送，发送和删除之间发生切换时消息可能重复发送，
+// 所以定时消息在创建时就确定clientMsgNo，客户端根据clientMsgNo去重
+type scheduledMessageManager struct {
+	s *Server
+	wklog.Log
+
+	messageAPI *MessageAPI
+
+	scanner *intervalScanner
+}
+
+func newScheduledMessageManager(s *Server) *scheduledMessageManager {
+	m := &scheduledMessageManager{
+		s:          s,
+		Log:        wklog.NewWKLog("scheduledMessageManager"),
+		messageAPI: NewMessageAPI(s),
+	}
+	m.scanner = newIntervalScanner(s.opts.ScheduledMessage.ScanInterval, m.scan)
+	return m
+}
+
+func (m *scheduledMessageManager) start() error {
+	if m.s.opts.ClusterOn() {
+		m.scanner.start()
+	}
+	return nil
+}
+
+func (m *scheduledMessageManager) stop() {
+	m.scanner.stop()
+}
+
+// scan 发送本节点作为领导的槽内已到期的定时消息
+func (m *scheduledMessageManager) scan() {
+	cfg := m.s.clusterServer.GetConfig()
+	now := time.Now().Unix()
+	for _, slot := range cfg.Slots {
+		if slot.Leader != m.s.opts.Cluster.NodeId {
+			continue
+		}
+		messages, err := m.s.store.GetDueScheduledMessages(slot.Id, now, m.s.opts.ScheduledMessage.BatchSize)
+		if err != nil {
+			m.Error("get due scheduled messages failed", zap.Error(err), zap.Uint32("slotId", slot.Id))
+			continue
+		}
+		for _, sm := range messages {
+			select {
+			case <-m.scanner.stopped():
+				return
+			default:
+			}
+			m.fire(sm, now)
+		}
+	}
+}
+
+func (m *scheduledMessageManager) fire(sm wkdb.ScheduledMessage, now int64) {
+	_, err := m.messageAPI.sendMessageToChannel(scheduledMessageToSendReq(sm), sm.ChannelId, sm.ChannelType, sm.ClientMsgNo, wkproto.StreamFlagIng)
+	if err != nil {
+		if now-sm.SendAt < int64(scheduledMessageDropAfter.Seconds()) {
+			m.Warn("send scheduled message failed, retry later", zap.Error(err), zap.Uint64("id", sm.Id), zap.String("channelId", sm.ChannelId), zap.Uint8("channelType", sm.ChannelType))
+			return
+		}
+		m.Error("send scheduled message failed, drop it", zap.Error(err), zap.Uint64("id", sm.Id), zap.String("channelId", sm.ChannelId), zap.Uint8("channelType", sm.ChannelType))
+	}
+	err = m.s.store.DeleteScheduledMessage(sm.ChannelId, sm.Id)
+	if err != nil {
+		m.Error("delete scheduled message failed", zap.Error(err), zap.Uint64("id", sm.Id))
+	}
+}
+
+// schedule 保存定时消息，返回定时消息
+func (m *scheduledMessageManager) schedule(req MessageSendReq, channelId string, channelType uint8, clientMsgNo string) (wkdb.ScheduledMessage, error) {
+	createdAt := time.Now()
+	sm := wkdb.ScheduledMessage{
+		Id:          m.s.store.NextPrimaryKey(),
+		ChannelId:   channelId,
+		ChannelType: channelType,
+		FromUid:     req.FromUID,
+		ClientMsgNo: clientMsgNo,
+		NoPersist:   wkutil.IntToBool(req.Header.NoPersist),
+		RedDot:      wkutil.IntToBool(req.Header.RedDot),
+		SyncOnce:    wkutil.IntToBool(req.Header.SyncOnce),
+		Expire:      req.Expire,
+		Payload:     req.Payload,
+		SendAt:      req.SendAt,
+		CreatedAt:   &createdAt,
+		UpdatedAt:   &createdAt,
+	}
+	err := m.s.store.AddOrUpdateScheduledMessage(sm)
+	if err != nil {
+		return wkdb.EmptyScheduledMessage, err
+	}
+	return sm, nil
+}
+
+func scheduledMessageToSendReq(sm wkdb.ScheduledMessage) MessageSendReq {
+	return MessageSendReq{
+		Header: MessageHeader{
+			NoPersist: wkutil.BoolToInt(sm.NoPersist),
+			RedDot:    wkutil.BoolToInt(sm.RedDot),
+			SyncOnce:  wkutil.BoolToInt(sm.SyncOnce),
+		},
+		ClientMsgNo: sm.ClientMsgNo,
+		FromUID:     sm.FromUid,
+		ChannelID:   sm.ChannelId,
+		ChannelType: sm.ChannelType,
+		Expire:      sm.Expire,
+		Payload:     sm.Payload,
+	}
+}
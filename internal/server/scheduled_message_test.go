@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledMessageToSendReq(t *testing.T) {
+	req := scheduledMessageToSendReq(wkdb.ScheduledMessage{
+		Id:          1,
+		ChannelId:   "g1",
+		ChannelType: 2,
+		FromUid:     "u1",
+		ClientMsgNo: "no1",
+		RedDot:      true,
+		SyncOnce:    true,
+		Expire:      60,
+		Payload:     []byte("hello"),
+		SendAt:      100,
+	})
+	assert.Equal(t, 0, req.Header.NoPersist)
+	assert.Equal(t, 1, req.Header.RedDot)
+	assert.Equal(t, 1, req.Header.SyncOnce)
+	assert.Equal(t, "g1", req.ChannelID)
+	assert.Equal(t, uint8(2), req.ChannelType)
+	assert.Equal(t, "u1", req.FromUID)
+	assert.Equal(t, "no1", req.ClientMsgNo)
+	assert.Equal(t, uint32(60), req.Expire)
+	assert.Equal(t, []byte("hello"), req.Payload)
+	// 到期发送时不能再次作为定时消息
+	assert.False(t, req.IsScheduled())
+}
+
+func TestCheckSendAt(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	m := NewMessageAPI(s)
+
+	now := time.Now()
+	assert.NoError(t, m.checkSendAt(0))
+	assert.NoError(t, m.checkSendAt(now.Add(-time.Minute).Unix()))
+	assert.NoError(t, m.checkSendAt(now.Add(time.Hour).Unix()))
+	assert.Error(t, m.checkSendAt(now.Add(s.opts.ScheduledMessage.MaxDelay+time.Hour).Unix()))
+
+	assert.True(t, MessageSendReq{SendAt: now.Add(time.Minute).Unix()}.IsScheduled())
+	assert.False(t, MessageSendReq{SendAt: now.Add(-time.Minute).Unix()}.IsScheduled())
+}
@@ -62,6 +62,25 @@ type Server struct {
 
 	conversationManager *ConversationManager // 会话管理
 
+	messageSearchIndexer *messageSearchIndexer // 消息全文检索索引
+
+	presenceManager  *presenceManager  // 用户在线状态管理
+	ephemeralManager *ephemeralManager // 瞬时信号管理
+	tokenVerifier    *tokenVerifier    // 签名令牌验证
+
+	backupManager *backupManager // 在线备份
+	drainManager  *drainManager  // 节点下线管理
+	routeManager  *routeManager  // 客户端路由管理
+
+	scheduledMessageManager *scheduledMessageManager // 定时消息管理
+	broadcastManager        *broadcastManager        // 广播管理
+	attributeManager        *attributeManager        // 用户和频道的自定义属性
+	userDataManager         *userDataManager         // 用户数据导出和删除
+	e2eeManager             *e2eeManager             // 端到端加密的密钥分发
+	threadManager           *threadManager           // 消息的子区
+	botManager              *botManager              // 机器人
+	configReloader          *configReloader          // 运行时重新加载配置
+
 	migrateTask *MigrateTask // 迁移任务
 
 	datasource IDatasource // 数据源
@@ -134,16 +153,32 @@ func New(opts *Options) *Server {
 			trace.GlobalTrace.Metrics.System().ExtranetOutgoingAdd(int64(n))
 		}),
 	)
-	s.webhook = newWebhook(s)                         // webhook
-	s.channelReactor = newChannelReactor(s, opts)     // 频道的reactor
-	s.userReactor = newUserReactor(s)                 // 用户的reactor
-	s.demoServer = NewDemoServer(s)                   // demo server
-	s.systemUIDManager = NewSystemUIDManager(s)       // 系统账号管理
-	s.apiServer = NewAPIServer(s)                     // api服务
-	s.managerServer = NewManagerServer(s)             // 管理者的api服务
-	s.retryManager = newRetryManager(s)               // 消息重试管理
-	s.conversationManager = NewConversationManager(s) // 会话管理
-	s.migrateTask = NewMigrateTask(s)                 // 迁移任务
+	s.webhook = newWebhook(s)                           // webhook
+	s.channelReactor = newChannelReactor(s, opts)       // 频道的reactor
+	s.userReactor = newUserReactor(s)                   // 用户的reactor
+	s.demoServer = NewDemoServer(s)                     // demo server
+	s.systemUIDManager = NewSystemUIDManager(s)         // 系统账号管理
+	s.apiServer = NewAPIServer(s)                       // api服务
+	s.managerServer = NewManagerServer(s)               // 管理者的api服务
+	s.retryManager = newRetryManager(s)                 // 消息重试管理
+	s.conversationManager = NewConversationManager(s)   // 会话管理
+	s.migrateTask = NewMigrateTask(s)                   // 迁移任务
+	s.messageSearchIndexer = newMessageSearchIndexer(s) // 消息全文检索索引
+	s.presenceManager = newPresenceManager(s)           // 用户在线状态管理
+	s.ephemeralManager = newEphemeralManager(s)         // 瞬时信号管理
+	s.tokenVerifier = newTokenVerifier(s)               // 签名令牌验证
+	s.backupManager = newBackupManager(s)               // 在线备份
+	s.drainManager = newDrainManager(s)                 // 节点下线管理
+	s.routeManager = newRouteManager(s)                 // 客户端路由管理
+
+	s.scheduledMessageManager = newScheduledMessageManager(s) // 定时消息管理
+	s.broadcastManager = newBroadcastManager(s)               // 广播管理
+	s.attributeManager = newAttributeManager(s)               // 用户和频道的自定义属性
+	s.userDataManager = newUserDataManager(s)                 // 用户数据导出和删除
+	s.e2eeManager = newE2EEManager(s)                         // 端到端加密的密钥分发
+	s.threadManager = newThreadManager(s)                     // 消息的子区
+	s.botManager = newBotManager(s)                           // 机器人
+	s.configReloader = newConfigReloader(s)                   // 运行时重新加载配置
 
 	// 初始化分布式服务
 	initNodes := make(map[uint64]string)
@@ -186,9 +221,18 @@ func New(opts *Options) *Server {
 			cluster.WithChannelReactorSubCount(s.opts.Cluster.ChannelReactorSubCount),
 			cluster.WithSlotReactorSubCount(s.opts.Cluster.SlotReactorSubCount),
 			cluster.WithPongMaxTick(s.opts.Cluster.PongMaxTick),
-			cluster.WithAuth(s.opts.Auth),
+			cluster.WithAuth(s.opts.GetAuth()),
 			cluster.WithJaegerApiUrl(s.opts.Trace.JaegerApiUrl),
 			cluster.WithServiceName(s.opts.Trace.ServiceName),
+			cluster.WithChannelLeaderBalanceOn(s.opts.Cluster.ChannelLeaderBalanceOn),
+			cluster.WithChannelLeaderBalanceInterval(s.opts.Cluster.ChannelLeaderBalanceInterval),
+			cluster.WithChannelLeaderBalanceThreshold(s.opts.Cluster.ChannelLeaderBalanceThreshold),
+			cluster.WithChannelLeaderBalanceMinRate(s.opts.Cluster.ChannelLeaderBalanceMinRate),
+			cluster.WithChannelLeaderBalanceMaxTransfer(s.opts.Cluster.ChannelLeaderBalanceMaxTransfer),
+			cluster.WithOnChannelApply(s.messageSearchIndexer.applyFnc()),
+			cluster.WithParentChannel(threadParentChannel),
+			cluster.WithZone(s.opts.Cluster.Zone),
+			cluster.WithRack(s.opts.Cluster.Rack),
 		),
 
 		// cluster.WithOnChannelMetaApply(func(channelID string, channelType uint8, logs []replica.Log) error {
@@ -329,6 +373,73 @@ func (s *Server) Start() error {
 
 	s.webhook.Start()
 
+	if s.opts.MessageSearch.On {
+		err = s.messageSearchIndexer.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.opts.Presence.On {
+		err = s.presenceManager.start()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.ephemeralManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.routeManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.scheduledMessageManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.broadcastManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.userDataManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.attributeManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.e2eeManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.threadManager.start()
+	if err != nil {
+		return err
+	}
+
+	err = s.botManager.start()
+	if err != nil {
+		return err
+	}
+
+	if tokenAuthOn, tokenAuth := s.opts.GetTokenAuth(); tokenAuthOn && tokenAuth.Mode == TokenAuthModeJwt {
+		err = s.tokenVerifier.start()
+		if err != nil {
+			return err
+		}
+	}
+	s.tokenVerifier.startCleanTimer()
+
 	// 判断是否开启迁移任务
 	if strings.TrimSpace(s.opts.OldV1Api) != "" {
 		s.migrateTask.Run()
@@ -359,6 +470,22 @@ func (s *Server) Stop() error {
 
 	s.retryManager.stop()
 	s.conversationManager.Stop()
+	if s.opts.MessageSearch.On {
+		s.messageSearchIndexer.stop()
+	}
+	if s.opts.Presence.On {
+		s.presenceManager.stop()
+	}
+	s.ephemeralManager.stop()
+	s.routeManager.stop()
+	s.scheduledMessageManager.stop()
+	s.broadcastManager.stop()
+	s.userDataManager.stop()
+	s.attributeManager.stop()
+	s.e2eeManager.stop()
+	s.threadManager.stop()
+	s.botManager.stop()
+	s.tokenVerifier.stop()
 	s.cluster.Stop()
 	s.apiServer.Stop()
 
@@ -393,6 +520,17 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+// Drain 节点下线（滚动升级时停止节点前调用）
+// 不再接受新连接，转移槽和频道领导，分批断开客户端并等待消息投递和最近会话保存完成
+func (s *Server) Drain(ctx context.Context) DrainStatus {
+	return s.drainManager.drain(ctx)
+}
+
+// ReloadConfig 重新加载本节点的配置文件（收到SIGHUP时调用），只有部分配置项支持重新加载，配置有误时继续使用旧的配置
+func (s *Server) ReloadConfig() ConfigReloadResult {
+	return s.configReloader.reload()
+}
+
 // 等待分布式就绪
 func (s *Server) MustWaitClusterReady() {
 	s.cluster.MustWaitClusterReady()
@@ -426,6 +564,11 @@ func (s *Server) onConnect(conn wknet.Conn) error {
 	conn.SetMaxIdle(time.Second * 2) // 在认证之前，连接最多空闲2秒
 	s.trace.Metrics.App().ConnCountAdd(1)
 
+	if s.drainManager.isDraining() { // 下线中不再接受新连接
+		_ = conn.Close()
+		return nil
+	}
+
 	if conn.InboundBuffer().BoundBufferSize() == 0 {
 		conn.SetValue(ConnKeyParseProxyProto, true) // 设置需要解析代理协议
 		return nil
@@ -469,6 +612,9 @@ func (s *Server) onClose(conn wknet.Conn) {
 	if connCtxObj != nil {
 		connCtx := connCtxObj.(*connContext)
 		s.userReactor.removeConnContextById(connCtx.uid, connCtx.connId)
+		if s.opts.Presence.On {
+			s.presenceManager.removeConn(connCtx.connId)
+		}
 
 		if connCtx.isAuth.Load() {
 			deviceOnlineCount := s.userReactor.getConnContextCountByDeviceFlag(connCtx.uid, connCtx.deviceFlag)
@@ -498,6 +644,14 @@ func (s *Server) checkAndDecodePayload(sendPacket *wkproto.SendPacket, conn *con
 	if !vail {
 		return nil, errors.New("sendPacket is illegal！")
 	}
+	if conn.cipherSession != nil {
+		decodePayload, err := conn.cipherSession.Decrypt(sendPacket.Payload)
+		if err != nil {
+			s.Error("Failed to decode payload！", zap.Error(err), zap.String("suite", conn.cipherSession.Suite().String()))
+			return nil, err
+		}
+		return decodePayload, nil
+	}
 	// decode payload
 	decodePayload, err := wkutil.AesDecryptPkcs7Base64(sendPacket.Payload, []byte(aesKey), []byte(aesIV))
 	if err != nil {
@@ -510,8 +664,15 @@ func (s *Server) checkAndDecodePayload(sendPacket *wkproto.SendPacket, conn *con
 
 // send packet is vail
 func (s *Server) sendPacketIsVail(sendPacket *wkproto.SendPacket, conn *connContext) (bool, error) {
-	aesKey, aesIV := conn.aesKey, conn.aesIV
 	signStr := sendPacket.VerityString()
+	if conn.cipherSession != nil {
+		if !conn.cipherSession.Verify(signStr, sendPacket.MsgKey) {
+			s.Error("msgKey is illegal！", zap.String("act", sendPacket.MsgKey), zap.String("sign", signStr), zap.Any("conn", conn))
+			return false, errors.New("msgKey is illegal！")
+		}
+		return true, nil
+	}
+	aesKey, aesIV := conn.aesKey, conn.aesIV
 	actMsgKey, err := wkutil.AesEncryptPkcs7Base64([]byte(signStr), []byte(aesKey), []byte(aesIV))
 	if err != nil {
 		s.Error("msgKey is illegal！", zap.Error(err), zap.String("sign", signStr), zap.String("aesKey", aesKey), zap.String("aesIV", aesIV), zap.Any("conn", conn))
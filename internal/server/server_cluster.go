@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkcipher"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -20,6 +21,12 @@ func (s *Server) handleClusterMessage(fromNodeId uint64, msg *proto.Message) {
 		s.handleNodePing(fromNodeId, msg)
 	case ClusterMsgTypeNodePong: // 节点Pong
 		s.handleNodePong(fromNodeId, msg)
+	case ClusterMsgTypePresence: // 用户在线状态变化
+		if s.opts.Presence.On {
+			s.presenceManager.handlePresenceMessage(msg)
+		}
+	case ClusterMsgTypeEphemeral: // 瞬时信号投递
+		s.ephemeralManager.handleEphemeralMessage(msg)
 
 	}
 	// switch ClusterMsgType(msg.MsgType) {
@@ -56,6 +63,54 @@ func (s *Server) setClusterRoutes() {
 	// 是否允许发送消息
 	s.cluster.Route("/wk/allowSend", s.handleAllowSend)
 
+	// 获取用户在线状态
+	s.cluster.Route("/wk/getPresences", s.presenceManager.handleGetPresences)
+	s.cluster.Route("/wk/presence/watch", s.presenceManager.handleWatch)
+
+	// 瞬时信号转发到频道领导节点
+	s.cluster.Route("/wk/ephemeral", s.ephemeralManager.handleSignalReq)
+
+	// 生成本节点的快照并上传到备份目标
+	s.cluster.Route("/wk/backup", s.backupManager.handleBackupReq)
+
+	// 获取本节点的对外地址和负载（客户端路由使用）
+	s.cluster.Route("/wk/routeInfo", s.routeManager.handleRouteInfoReq)
+
+	// 获取本节点作为领导的槽内的广播任务进度
+	s.cluster.Route("/wk/broadcastTasks", s.broadcastManager.handleTasksReq)
+
+	// 重新生成本节点上话题频道的接收者标签（社区订阅者变更后）
+	s.cluster.Route("/wk/refreshTopicReceiverTags", s.handleRefreshTopicReceiverTags)
+	// 在社区所在槽的领导节点上校验话题的父频道（社区）
+	s.cluster.Route("/wk/topic/checkParent", s.handleTopicParentReq)
+	// 获取本节点（社区所在槽的领导节点）上社区的订阅者
+	s.cluster.Route("/wk/topic/parentSubscribers", s.handleTopicSubscribersReq)
+
+	// 检索本节点指定频道的消息
+	s.cluster.Route("/wk/messageSearch", s.messageSearchIndexer.handleSearchChannelsReq)
+	// 校验用户是否为本节点（频道所在槽的领导节点）上频道的订阅者
+	s.cluster.Route("/wk/messageSearch/subscribed", s.handleSearchSubscribedReq)
+
+	// 获取本节点上的用户属性或频道属性
+	s.cluster.Route("/wk/getAttributes", s.attributeManager.handleGetAttributesReq)
+
+	// 获取用户在本节点数据中订阅的频道
+	s.cluster.Route("/wk/userData/channels", s.userDataManager.handleChannelsReq)
+	// 获取用户在本节点数据中发送的消息
+	s.cluster.Route("/wk/userData/messages", s.userDataManager.handleMessagesReq)
+	// 匿名化用户在本节点数据中发送的消息
+	s.cluster.Route("/wk/userData/eraseMessages", s.userDataManager.handleEraseMessagesReq)
+	// 获取用户在本节点（用户所在槽的领导节点）上的任务
+	s.cluster.Route("/wk/userData/job", s.userDataManager.handleJobReq)
+	// 清除用户在本节点上的最近会话缓存
+	s.cluster.Route("/wk/userData/purgeConversations", s.userDataManager.handlePurgeConversationsReq)
+	// 删除本节点上的导出文件
+	s.cluster.Route("/wk/userData/removeArchive", s.userDataManager.handleRemoveArchiveReq)
+	// 用户的领导节点踢掉用户的连接
+	s.cluster.Route("/wk/kickUser", s.handleKickUserReq)
+	// 重新加载本节点的配置
+	s.cluster.Route("/wk/config/reload", s.configReloader.handleReloadReq)
+
 }
 
 func (s *Server) handleChannelForward(c *wkserver.Context) {
@@ -256,8 +311,18 @@ func (s *Server) handleUserAuthResult(c *wkserver.Context) {
 	}
 
 	if authResult.ReasonCode == wkproto.ReasonSuccess {
+		var cipherSession *wkcipher.Session
+		if authResult.CipherSuite.IsAEAD() { // 使用领导节点协商的密钥，本节点的发送流和领导节点的不同
+			cipherSession, err = s.userReactor.newCipherSession(authResult.CipherSuite, authResult.CipherKey)
+			if err != nil {
+				s.Error("auth: handleUserAuthResult: new cipher session failed", zap.Error(err), zap.String("uid", authResult.Uid))
+				c.WriteErr(err)
+				return
+			}
+		}
 		connCtx.aesIV = authResult.AesIV
 		connCtx.aesKey = authResult.AesKey
+		connCtx.cipherSession = cipherSession
 		connCtx.deviceLevel = authResult.DeviceLevel
 		connCtx.deviceId = authResult.DeviceId
 		connCtx.protoVersion = authResult.ProtoVersion
@@ -270,6 +335,9 @@ func (s *Server) handleUserAuthResult(c *wkserver.Context) {
 			ReasonCode:    authResult.ReasonCode,
 			NodeId:        s.opts.Cluster.NodeId,
 		}
+		if cipherSession != nil {
+			connack.ServerVersion = wkcipher.Version
+		}
 		connack.HasServerVersion = authResult.ProtoVersion > 3 // 如果协议版本大于3，就返回serverVersion
 		_ = connCtx.writePacket(connack)
 	} else {
@@ -110,6 +110,38 @@ func (s *APIServer) setRoutes() {
 	routeapi := NewRouteAPI(s.s)
 	routeapi.Route(s.r)
 
+	// 在线备份API
+	backup := NewBackupAPI(s.s)
+	backup.Route(s.r)
+
+	// 节点下线API
+	drain := NewDrainAPI(s.s)
+	drain.Route(s.r)
+
+	// 广播API
+	broadcast := NewBroadcastAPI(s.s)
+	broadcast.Route(s.r)
+
+	// 用户和频道的自定义属性API
+	attribute := NewAttributeAPI(s.s)
+	attribute.Route(s.r)
+
+	// 用户数据导出和删除API
+	userData := NewUserDataAPI(s.s)
+	userData.Route(s.r)
+
+	// 端到端加密的密钥分发API
+	e2ee := NewE2EEAPI(s.s)
+	e2ee.Route(s.r)
+
+	// 消息的子区API
+	thread := NewThreadAPI(s.s)
+	thread.Route(s.r)
+
+	// 机器人API
+	bot := NewBotAPI(s.s)
+	bot.Route(s.r)
+
 	// 分布式api
 	clusterServer, ok := s.s.cluster.(*cluster.Server)
 	if ok {
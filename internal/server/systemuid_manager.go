@@ -59,6 +59,15 @@ func (s *SystemUIDManager) LoadIfNeed() error {
 	return nil
 }
 
+// Reset 清空缓存的系统账号，下次使用时重新加载（数据源配置重新加载后调用）
+func (s *SystemUIDManager) Reset() {
+	s.systemUIDs.Range(func(key, value any) bool {
+		s.systemUIDs.Delete(key)
+		return true
+	})
+	s.loaded.Store(false)
+}
+
 // SystemUID Is it a system account?
 func (s *SystemUIDManager) SystemUID(uid string) bool {
 	err := s.LoadIfNeed()
@@ -0,0 +1,295 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/keylock"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+const threadUpdatedCMD = "threadUpdated"
+
+// threadManager 消息的子区（回复串）
+// 子区是父频道下的子日志（子区频道），频道类型与父频道相同，沿用父频道的副本和领导，回复不会出现在父频道内
+// 子区的回复数量、参与者和根消息的扩展数据在子区频道的消息写入时由存储层更新
+type threadManager struct {
+	s *Server
+	wklog.Log
+	replyLock  *keylock.KeyLock // 子区频道的回复锁，保证相同client_msg_no的回复只保存一次
+	messageAPI *MessageAPI
+}
+
+func newThreadManager(s *Server) *threadManager {
+	return &threadManager{
+		s:          s,
+		Log:        wklog.NewWKLog("threadManager"),
+		replyLock:  keylock.NewKeyLock(),
+		messageAPI: NewMessageAPI(s),
+	}
+}
+
+func (t *threadManager) start() error {
+	t.replyLock.StartCleanLoop()
+	return nil
+}
+
+func (t *threadManager) stop() {
+	t.replyLock.StopCleanLoop()
+}
+
+// threadParentChannel 获取子区频道的父频道，用于子区频道创建分布式配置时沿用父频道的副本
+func threadParentChannel(channelId string, channelType uint8) (string, uint8, bool) {
+	parentChannelId, _, ok := wkdb.ParseThreadChannelId(channelId)
+	return parentChannelId, channelType, ok
+}
+
+// threadFakeChannelId 父频道在存储中的频道ID（个人频道为两个uid组合的频道ID）
+func threadFakeChannelId(loginUid string, channelId string, channelType uint8) string {
+	if channelType == wkproto.ChannelTypePerson {
+		return GetFakeChannelIDWith(loginUid, channelId)
+	}
+	return channelId
+}
+
+// reply 回复子区，需要在父频道的领导节点执行（子区频道的副本和领导跟随父频道，权限校验和子区的日志在同一个领导上）
+// fakeChannelId为父频道在存储中的频道ID
+// 回复不经过频道的消息处理流程，这里补上存储后的处理：
+// 1. 相同回复者和client_msg_no的回复已保存时直接返回（客户端重试），检查和保存在子区频道的锁内执行
+// 2. 开启webhook时加入msg.notify的推送队列（回复的频道为子区频道，seq是子区频道内的seq，不会与父频道的消息冲突）
+// 3. 搜索索引在子区频道的日志提交后建立，检索父频道时一并检索其子区
+// 故意跳过的：不投递给父频道的订阅者、不更新最近会话（参与者通过threadUpdated命令得知新回复），
+// 不推送给机器人（机器人的回复发送到会话，无法回到子区）
+func (t *threadManager) reply(req threadReplyReq, fakeChannelId string) (wkdb.Message, error) {
+	rootMsg, err := t.s.store.LoadMsg(fakeChannelId, req.ChannelType, req.RootMessageSeq)
+	if err != nil {
+		if err == wkdb.ErrNotFound {
+			return wkdb.EmptyMessage, errors.New("根消息不存在！")
+		}
+		t.Error("reply: load root message failed", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("rootMessageSeq", req.RootMessageSeq))
+		return wkdb.EmptyMessage, errors.New("获取根消息失败！")
+	}
+	if rootMsg.SyncOnce || rootMsg.NoPersist {
+		return wkdb.EmptyMessage, errors.New("根消息不支持回复！")
+	}
+
+	// 回复的权限与在父频道发送消息相同
+	channelInfo, err := t.s.store.GetChannel(fakeChannelId, req.ChannelType)
+	if err != nil && err != wkdb.ErrNotFound {
+		t.Error("reply: get channel failed", zap.Error(err), zap.String("channelId", fakeChannelId))
+		return wkdb.EmptyMessage, errors.New("获取频道信息失败！")
+	}
+	reasonCode, err := t.s.channelReactor.hasPermission(fakeChannelId, req.ChannelType, req.FromUid, channelInfo)
+	if err != nil {
+		t.Error("reply: check permission failed", zap.Error(err), zap.String("channelId", fakeChannelId), zap.String("fromUid", req.FromUid))
+		return wkdb.EmptyMessage, errors.New("检查回复权限失败！")
+	}
+	if reasonCode != wkproto.ReasonSuccess {
+		return wkdb.EmptyMessage, fmt.Errorf("没有回复的权限！(%s)", reasonCode.String())
+	}
+
+	threadChannelId := wkdb.ThreadChannelId(fakeChannelId, req.RootMessageSeq)
+	t.replyLock.Lock(threadChannelId)
+	defer t.replyLock.Unlock(threadChannelId)
+
+	clientMsgNo := req.ClientMsgNo
+	if clientMsgNo == "" {
+		clientMsgNo = fmt.Sprintf("%s0", wkutil.GenUUID())
+	} else if existMsg, ok := t.existReply(threadChannelId, req); ok {
+		return existMsg, nil
+	}
+	msg := wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:   t.s.channelReactor.messageIDGen.Generate().Int64(),
+			ClientMsgNo: clientMsgNo,
+			FromUID:     req.FromUid,
+			ChannelID:   threadChannelId,
+			ChannelType: req.ChannelType,
+			Timestamp:   int32(time.Now().Unix()),
+			Payload:     req.Payload,
+		},
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(t.s.ctx, t.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	results, err := t.s.store.AppendMessages(timeoutCtx, threadChannelId, req.ChannelType, []wkdb.Message{msg})
+	if err != nil {
+		t.Error("reply: append message failed", zap.Error(err), zap.String("threadChannelId", threadChannelId))
+		return wkdb.EmptyMessage, errors.New("保存回复失败！")
+	}
+	if len(results) > 0 {
+		msg.MessageSeq = uint32(results[0].LogIndex())
+	}
+
+	if t.s.opts.WebhookOn() {
+		if err = t.s.store.AppendMessageOfNotifyQueue([]wkdb.Message{msg}); err != nil {
+			t.Error("reply: AppendMessageOfNotifyQueue failed", zap.Error(err), zap.String("threadChannelId", threadChannelId))
+		}
+	}
+
+	t.notifyThreadUpdated(req, fakeChannelId, rootMsg, msg)
+	return msg, nil
+}
+
+// existReply 获取已保存的相同回复者和client_msg_no的回复
+func (t *threadManager) existReply(threadChannelId string, req threadReplyReq) (wkdb.Message, bool) {
+	messages, err := t.s.store.SearchMessages(wkdb.MessageSearchReq{
+		ClientMsgNo: req.ClientMsgNo,
+		Limit:       10,
+	})
+	if err != nil {
+		t.Warn("reply: search message by clientMsgNo failed", zap.Error(err), zap.String("clientMsgNo", req.ClientMsgNo))
+		return wkdb.EmptyMessage, false
+	}
+	for _, message := range messages {
+		if message.FromUID == req.FromUid && message.ChannelID == threadChannelId && message.ChannelType == req.ChannelType {
+			return message, true
+		}
+	}
+	return wkdb.EmptyMessage, false
+}
+
+// notifyThreadUpdated 通知子区的参与者子区有新的回复（cmd消息，不会出现在父频道）
+func (t *threadManager) notifyThreadUpdated(req threadReplyReq, fakeChannelId string, rootMsg wkdb.Message, msg wkdb.Message) {
+	go func() {
+		thread, err := t.s.store.GetThread(fakeChannelId, req.ChannelType, req.RootMessageSeq)
+		if err != nil {
+			t.Error("notifyThreadUpdated: get thread failed", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint64("rootMessageSeq", req.RootMessageSeq))
+			return
+		}
+		replyCount := thread.ReplyCount
+		if replyCount < uint64(msg.MessageSeq) { // 本节点的子区数据还没更新
+			replyCount = uint64(msg.MessageSeq)
+		}
+		for _, uid := range thread.Participants {
+			if uid == req.FromUid || t.s.systemUIDManager.SystemUID(uid) {
+				continue
+			}
+			channelId := req.ChannelId
+			if req.ChannelType == wkproto.ChannelTypePerson { // 接收者看到的个人频道是发送者
+				channelId = req.FromUid
+			}
+			payload := []byte(wkutil.ToJSON(map[string]interface{}{
+				"cmd": threadUpdatedCMD,
+				"data": map[string]interface{}{
+					"channel_id":       channelId,
+					"channel_type":     req.ChannelType,
+					"thread_id":        msg.ChannelID,
+					"root_message_id":  rootMsg.MessageID,
+					"root_message_seq": req.RootMessageSeq,
+					"reply_count":      replyCount,
+					"message_seq":      msg.MessageSeq,
+					"from_uid":         req.FromUid,
+					"last_reply_at":    msg.Timestamp,
+				},
+			}))
+			_, err = t.messageAPI.sendMessageToChannel(MessageSendReq{
+				Header: MessageHeader{
+					SyncOnce: 1,
+				},
+				FromUID:     t.s.opts.SystemUID,
+				ChannelID:   uid,
+				ChannelType: wkproto.ChannelTypePerson,
+				Payload:     payload,
+			}, uid, wkproto.ChannelTypePerson, wkutil.GenUUID(), wkproto.StreamFlagIng)
+			if err != nil {
+				t.Warn("notifyThreadUpdated: send notify failed", zap.Error(err), zap.String("uid", uid))
+			}
+		}
+	}()
+}
+
+// messageExtras 获取消息的扩展数据（子区的回复数量和最后回复时间），key为消息seq
+func (t *threadManager) messageExtras(fakeChannelId string, channelType uint8, messages []wkdb.Message) map[uint64]wkdb.MessageExtra {
+	if len(messages) == 0 {
+		return nil
+	}
+	startSeq := uint64(messages[0].MessageSeq)
+	endSeq := startSeq
+	for _, message := range messages {
+		seq := uint64(message.MessageSeq)
+		if seq < startSeq {
+			startSeq = seq
+		}
+		if seq > endSeq {
+			endSeq = seq
+		}
+	}
+	extras, err := t.s.store.GetMessageExtras(fakeChannelId, channelType, startSeq, endSeq)
+	if err != nil {
+		t.Warn("get message extras failed", zap.Error(err), zap.String("channelId", fakeChannelId), zap.Uint8("channelType", channelType))
+		return nil
+	}
+	if len(extras) == 0 {
+		return nil
+	}
+	extraMap := make(map[uint64]wkdb.MessageExtra, len(extras))
+	for _, extra := range extras {
+		extraMap[extra.MessageSeq] = extra
+	}
+	return extraMap
+}
+
+type threadReplyReq struct {
+	ChannelId      string `json:"channel_id"`       // 父频道ID
+	ChannelType    uint8  `json:"channel_type"`     // 父频道类型
+	RootMessageSeq uint64 `json:"root_message_seq"` // 根消息的seq
+	FromUid        string `json:"from_uid"`         // 回复者
+	ClientMsgNo    string `json:"client_msg_no"`    // 客户端消息编号
+	Payload        []byte `json:"payload"`          // 回复内容
+}
+
+func (r threadReplyReq) check() error {
+	if r.ChannelId == "" {
+		return errors.New("channel_id不能为空！")
+	}
+	if r.ChannelType == 0 {
+		return errors.New("channel_type不能为0！")
+	}
+	if r.RootMessageSeq == 0 {
+		return errors.New("root_message_seq不能为0！")
+	}
+	if r.FromUid == "" {
+		return errors.New("from_uid不能为空！")
+	}
+	if len(r.Payload) == 0 {
+		return errors.New("payload不能为空！")
+	}
+	return nil
+}
+
+type threadResp struct {
+	ChannelId      string   `json:"channel_id"`
+	ChannelType    uint8    `json:"channel_type"`
+	RootMessageSeq uint64   `json:"root_message_seq"`
+	ReplyCount     uint64   `json:"reply_count"`
+	LastReplyAt    int64    `json:"last_reply_at"` // 最后回复时间（unix秒）
+	Participants   []string `json:"participants"`
+	CreatedAt      int64    `json:"created_at"` // 创建时间（unix秒）
+}
+
+func newThreadResp(channelId string, t wkdb.Thread) *threadResp {
+	resp := &threadResp{
+		ChannelId:      channelId,
+		ChannelType:    t.ChannelType,
+		RootMessageSeq: t.RootMessageSeq,
+		ReplyCount:     t.ReplyCount,
+		Participants:   t.Participants,
+	}
+	if t.LastReplyAt != nil {
+		resp.LastReplyAt = t.LastReplyAt.Unix()
+	}
+	if t.CreatedAt != nil {
+		resp.CreatedAt = t.CreatedAt.Unix()
+	}
+	if resp.Participants == nil {
+		resp.Participants = make([]string, 0)
+	}
+	return resp
+}
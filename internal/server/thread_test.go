@@ -0,0 +1,133 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadParentChannel(t *testing.T) {
+	parentChannelId, parentChannelType, ok := threadParentChannel(wkdb.ThreadChannelId("g1", 5), wkproto.ChannelTypeGroup)
+	assert.True(t, ok)
+	assert.Equal(t, "g1", parentChannelId)
+	assert.Equal(t, wkproto.ChannelTypeGroup, parentChannelType)
+
+	_, _, ok = threadParentChannel("g1", wkproto.ChannelTypeGroup)
+	assert.False(t, ok)
+
+	assert.Equal(t, GetFakeChannelIDWith("u1", "u2"), threadFakeChannelId("u1", "u2", wkproto.ChannelTypePerson))
+	assert.Equal(t, "g1", threadFakeChannelId("u1", "g1", wkproto.ChannelTypeGroup))
+}
+
+func TestThreadReplyReqCheck(t *testing.T) {
+	req := threadReplyReq{
+		ChannelId:      "g1",
+		ChannelType:    wkproto.ChannelTypeGroup,
+		RootMessageSeq: 1,
+		FromUid:        "u1",
+		Payload:        []byte("reply"),
+	}
+	assert.NoError(t, req.check())
+
+	req.RootMessageSeq = 0
+	assert.Error(t, req.check())
+	req.RootMessageSeq = 1
+	req.Payload = nil
+	assert.Error(t, req.check())
+}
+
+func TestThreadMessageExtras(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	newMessage := func(seq uint32, fromUid string, timestamp int32) wkdb.Message {
+		return wkdb.Message{RecvPacket: wkproto.RecvPacket{MessageID: int64(timestamp), MessageSeq: seq, FromUID: fromUid, Timestamp: timestamp, Payload: []byte("hello")}}
+	}
+	messages := []wkdb.Message{newMessage(1, "u1", 100), newMessage(2, "u2", 101)}
+	err = s.store.DB().AppendMessages("g1", wkproto.ChannelTypeGroup, messages)
+	assert.NoError(t, err)
+	err = s.store.DB().AppendMessages(wkdb.ThreadChannelId("g1", 2), wkproto.ChannelTypeGroup, []wkdb.Message{newMessage(1, "u3", 200)})
+	assert.NoError(t, err)
+
+	extras := s.threadManager.messageExtras("g1", wkproto.ChannelTypeGroup, messages)
+	assert.Len(t, extras, 1)
+	assert.Equal(t, uint64(1), extras[2].ThreadReplyCount)
+
+	resp := &MessageResp{}
+	resp.from(messages[1], s)
+	resp.fillExtra(extras[2])
+	assert.Equal(t, uint64(1), resp.ThreadReplyCount)
+	assert.Equal(t, int64(200), resp.ThreadLastReplyAt)
+
+	thread, err := s.store.GetThread("g1", wkproto.ChannelTypeGroup, 2)
+	assert.NoError(t, err)
+	threadResp := newThreadResp("g1", thread)
+	assert.Equal(t, []string{"u2", "u3"}, threadResp.Participants)
+	assert.Equal(t, int64(200), threadResp.CreatedAt)
+}
+
+func TestThreadExistReply(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	newMessage := func(id int64, seq uint32, channelId string, fromUid string, clientMsgNo string) wkdb.Message {
+		return wkdb.Message{RecvPacket: wkproto.RecvPacket{MessageID: id, MessageSeq: seq, ChannelID: channelId, ChannelType: wkproto.ChannelTypeGroup, FromUID: fromUid, ClientMsgNo: clientMsgNo, Payload: []byte("hello")}}
+	}
+	threadChannelId := wkdb.ThreadChannelId("g1", 1)
+	// 父频道内相同client_msg_no的消息不算回复
+	err = s.store.DB().AppendMessages("g1", wkproto.ChannelTypeGroup, []wkdb.Message{newMessage(1, 1, "g1", "u1", "no1"), newMessage(2, 2, "g1", "u1", "no2")})
+	assert.NoError(t, err)
+	err = s.store.DB().AppendMessages(threadChannelId, wkproto.ChannelTypeGroup, []wkdb.Message{newMessage(3, 1, threadChannelId, "u1", "no2")})
+	assert.NoError(t, err)
+
+	req := threadReplyReq{ChannelId: "g1", ChannelType: wkproto.ChannelTypeGroup, RootMessageSeq: 1, FromUid: "u1", ClientMsgNo: "no2"}
+	msg, ok := s.threadManager.existReply(threadChannelId, req)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), msg.MessageID)
+
+	req.ClientMsgNo = "no1"
+	_, ok = s.threadManager.existReply(threadChannelId, req)
+	assert.False(t, ok)
+
+	req.ClientMsgNo = "no2"
+	req.FromUid = "u2"
+	_, ok = s.threadManager.existReply(threadChannelId, req)
+	assert.False(t, ok)
+}
+
+func TestThreadReplyMessageResp(t *testing.T) {
+	s := NewTestServer(t)
+	err := s.store.DB().Open()
+	assert.NoError(t, err)
+	defer func() {
+		_ = s.store.DB().Close()
+	}()
+
+	fakeChannelId := GetFakeChannelIDWith("u1", "u2")
+	threadChannelId := wkdb.ThreadChannelId(fakeChannelId, 1)
+	err = s.store.DB().AppendMessages(fakeChannelId, wkproto.ChannelTypePerson, []wkdb.Message{{RecvPacket: wkproto.RecvPacket{MessageID: 100, MessageSeq: 1, ChannelID: fakeChannelId, ChannelType: wkproto.ChannelTypePerson, FromUID: "u1", Payload: []byte("root")}}})
+	assert.NoError(t, err)
+
+	// 回复的频道为子区频道，返回时还原为父频道并带上子区信息
+	resp := &MessageResp{}
+	resp.from(wkdb.Message{RecvPacket: wkproto.RecvPacket{MessageID: 101, MessageSeq: 1, ChannelID: threadChannelId, ChannelType: wkproto.ChannelTypePerson, FromUID: "u2", Payload: []byte("reply")}}, s)
+	assert.Equal(t, "u1", resp.ChannelID)
+	assert.Equal(t, threadChannelId, resp.ThreadId)
+	assert.Equal(t, uint64(1), resp.RootMessageSeq)
+	assert.Equal(t, int64(100), resp.RootMessageId)
+
+	resp = &MessageResp{}
+	resp.from(wkdb.Message{RecvPacket: wkproto.RecvPacket{MessageID: 100, MessageSeq: 1, ChannelID: fakeChannelId, ChannelType: wkproto.ChannelTypePerson, FromUID: "u1", Payload: []byte("root")}}, s)
+	assert.Equal(t, "u2", resp.ChannelID)
+	assert.Empty(t, resp.ThreadId)
+}
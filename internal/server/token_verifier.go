@@ -0,0 +1,385 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RussellLuo/timingwheel"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTokenUidNotMatch        = errors.New("token uid not match")
+	ErrTokenDeviceFlagNotMatch = errors.New("token device flag not match")
+	ErrTokenDeviceIdNotMatch   = errors.New("token device id not match")
+	ErrTokenRevoked            = errors.New("token is revoked")
+)
+
+// revokedTokenCleanInterval 移除过期吊销记录的间隔
+const revokedTokenCleanInterval = time.Hour
+
+// connectTokenClaims 连接令牌的内容
+type connectTokenClaims struct {
+	UID         string `json:"uid,omitempty"`          // 用户uid，为空则取sub
+	DeviceFlag  *uint8 `json:"device_flag,omitempty"`  // 设备标识，不为空则必须与连接的设备标识一致
+	DeviceId    string `json:"device_id,omitempty"`    // 设备ID，不为空则必须与连接的设备ID一致
+	DeviceLevel uint8  `json:"device_level,omitempty"` // 设备等级 0.为从设备 1.为主设备
+	jwt.RegisteredClaims
+}
+
+func (c *connectTokenClaims) uid() string {
+	if c.UID != "" {
+		return c.UID
+	}
+	return c.Subject
+}
+
+// tokenVerifier 验证业务端签名的连接令牌（tokenAuth.mode为jwt时使用）
+type tokenVerifier struct {
+	s *Server
+	wklog.Log
+
+	mu   sync.RWMutex
+	cfg  TokenAuthOptions   // 当前使用的令牌验证配置，与公钥一起替换
+	keys *tokenVerifierKeys // 当前使用的公钥
+
+	reloadTimer *timingwheel.Timer
+	cleanTimer  *timingwheel.Timer
+}
+
+// tokenVerifierKeys 按令牌验证配置加载的公钥
+type tokenVerifierKeys struct {
+	publicKey   crypto.PublicKey       // publicKeyFile中的公钥
+	jwks        map[string]interface{} // kid -> 公钥（或HS256的密钥）
+	jwksModTime time.Time              // jwks文件的修改时间
+}
+
+func newTokenVerifier(s *Server) *tokenVerifier {
+	_, cfg := s.opts.GetTokenAuth()
+	return &tokenVerifier{
+		s:    s,
+		Log:  wklog.NewWKLog("tokenVerifier"),
+		cfg:  cfg,
+		keys: &tokenVerifierKeys{jwks: make(map[string]interface{})},
+	}
+}
+
+func (t *tokenVerifier) start() error {
+	t.mu.RLock()
+	cfg := t.cfg
+	t.mu.RUnlock()
+	keys, err := loadTokenVerifierKeys(cfg)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.keys = keys
+	t.mu.Unlock()
+	t.startReloadTimer(cfg)
+	return nil
+}
+
+// startCleanTimer 定时移除过期的吊销记录，验证模式可以重新加载，所以不论是否开启jwt模式都需要启动
+func (t *tokenVerifier) startCleanTimer() {
+	t.cleanTimer = t.s.Schedule(revokedTokenCleanInterval, t.removeExpiredRevokedTokens)
+}
+
+func (t *tokenVerifier) stop() {
+	t.stopReloadTimer()
+	if t.cleanTimer != nil {
+		t.cleanTimer.Stop()
+		t.cleanTimer = nil
+	}
+}
+
+func (t *tokenVerifier) stopReloadTimer() {
+	if t.reloadTimer != nil {
+		t.reloadTimer.Stop()
+		t.reloadTimer = nil
+	}
+}
+
+func (t *tokenVerifier) startReloadTimer(cfg TokenAuthOptions) {
+	if strings.TrimSpace(cfg.JwksFile) != "" && cfg.JwksReloadInterval > 0 {
+		t.reloadTimer = t.s.Schedule(cfg.JwksReloadInterval, t.reloadJwksIfChanged)
+	}
+}
+
+// loadTokenVerifierKeys 按配置加载公钥和jwks，并检查至少配置了一种验证方式，不影响正在使用的公钥
+// 没有开启jwt模式时返回空的公钥
+func loadTokenVerifierKeys(cfg TokenAuthOptions) (*tokenVerifierKeys, error) {
+	keys := &tokenVerifierKeys{
+		jwks: make(map[string]interface{}),
+	}
+	if strings.TrimSpace(cfg.PublicKeyFile) != "" {
+		publicKey, err := loadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys.publicKey = publicKey
+	}
+	if strings.TrimSpace(cfg.JwksFile) != "" {
+		jwks, modTime, err := loadJwks(cfg.JwksFile)
+		if err != nil {
+			return nil, err
+		}
+		keys.jwks = jwks
+		keys.jwksModTime = modTime
+	}
+	if cfg.Secret == "" && keys.publicKey == nil && len(keys.jwks) == 0 {
+		return nil, errors.New("tokenAuth: secret, publicKeyFile or jwksFile must be set in jwt mode")
+	}
+	return keys, nil
+}
+
+// loadTokenVerifierKeysWithOptions 重新加载配置时，在配置生效前按新的配置加载公钥，没有开启jwt模式时返回空的公钥
+func loadTokenVerifierKeysWithOptions(opts *Options) (*tokenVerifierKeys, error) {
+	on, cfg := opts.GetTokenAuth()
+	if !on || cfg.Mode != TokenAuthModeJwt {
+		return &tokenVerifierKeys{jwks: make(map[string]interface{})}, nil
+	}
+	return loadTokenVerifierKeys(cfg)
+}
+
+// reload 令牌验证配置生效后调用，替换为按新配置加载好的公钥
+func (t *tokenVerifier) reload(cfg TokenAuthOptions, keys *tokenVerifierKeys) {
+	t.stopReloadTimer()
+	t.mu.Lock()
+	t.cfg = cfg
+	t.keys = keys
+	t.mu.Unlock()
+	t.startReloadTimer(cfg)
+}
+
+// verify 验证连接令牌，返回令牌中的设备等级和令牌ID
+func (t *tokenVerifier) verify(connectPacket *wkproto.ConnectPacket) (wkproto.DeviceLevel, string, error) {
+	claims, err := t.parse(connectPacket.Token)
+	if err != nil {
+		return wkproto.DeviceLevelSlave, "", err
+	}
+	if err = checkConnectTokenClaims(claims, connectPacket); err != nil {
+		return wkproto.DeviceLevelSlave, "", err
+	}
+	if claims.ID != "" { // 带令牌ID的令牌支持吊销
+		revoked, err := t.s.store.ExistRevokedToken(claims.uid(), claims.ID)
+		if err != nil {
+			return wkproto.DeviceLevelSlave, "", err
+		}
+		if revoked {
+			return wkproto.DeviceLevelSlave, "", ErrTokenRevoked
+		}
+	}
+	return wkproto.DeviceLevel(claims.DeviceLevel), claims.ID, nil
+}
+
+// removeExpiredRevokedTokens 移除本节点上过期的吊销记录，过期的记录不再生效，只占用空间
+func (t *tokenVerifier) removeExpiredRevokedTokens() {
+	count, err := t.s.store.RemoveExpiredRevokedTokens(uint64(time.Now().Unix()), 10000)
+	if err != nil {
+		t.Error("RemoveExpiredRevokedTokens error", zap.Error(err))
+		return
+	}
+	if count > 0 {
+		t.Debug("remove expired revoked tokens", zap.Int("count", count))
+	}
+}
+
+func (t *tokenVerifier) parse(token string) (*connectTokenClaims, error) {
+	t.mu.RLock()
+	cfg := t.cfg
+	t.mu.RUnlock()
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(cfg.Audience))
+	}
+	claims := &connectTokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, t.keyFunc, parserOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (t *tokenVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	t.mu.RLock()
+	secret := t.cfg.Secret
+	keys := t.keys
+	t.mu.RUnlock()
+
+	if kid, _ := token.Header["kid"].(string); kid != "" {
+		key := keys.jwks[kid]
+		if key == nil {
+			return nil, fmt.Errorf("key not found for kid[%s]", kid)
+		}
+		return key, nil
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if secret == "" {
+			return nil, errors.New("secret is not set")
+		}
+		return []byte(secret), nil
+	default:
+		if keys.publicKey == nil {
+			return nil, errors.New("public key is not set")
+		}
+		return keys.publicKey, nil
+	}
+}
+
+func loadPublicKey(file string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var key crypto.PublicKey
+	if key, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+		if key, err = jwt.ParseEdPublicKeyFromPEM(data); err != nil {
+			return nil, fmt.Errorf("tokenAuth: parse public key file[%s] failed: %w", file, err)
+		}
+	}
+	return key, nil
+}
+
+func loadJwks(file string) (map[string]interface{}, time.Time, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	keys, err := parseJwks(data)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("tokenAuth: parse jwks file[%s] failed: %w", file, err)
+	}
+	return keys, stat.ModTime(), nil
+}
+
+// reloadJwksIfChanged jwks文件发生变化后重新加载，加载失败继续使用旧的公钥
+func (t *tokenVerifier) reloadJwksIfChanged() {
+	t.mu.RLock()
+	file := t.cfg.JwksFile
+	keys := t.keys
+	t.mu.RUnlock()
+	stat, err := os.Stat(file)
+	if err != nil {
+		t.Warn("stat jwks file failed", zap.Error(err), zap.String("file", file))
+		return
+	}
+	if stat.ModTime().Equal(keys.jwksModTime) {
+		return
+	}
+	jwks, modTime, err := loadJwks(file)
+	if err != nil {
+		t.Error("reload jwks failed", zap.Error(err), zap.String("file", file))
+		return
+	}
+	t.mu.Lock()
+	if t.keys == keys { // 期间没有重新加载配置
+		t.keys = &tokenVerifierKeys{
+			publicKey:   keys.publicKey,
+			jwks:        jwks,
+			jwksModTime: modTime,
+		}
+	}
+	t.mu.Unlock()
+	t.Info("jwks reloaded", zap.String("file", file))
+}
+
+func checkConnectTokenClaims(claims *connectTokenClaims, connectPacket *wkproto.ConnectPacket) error {
+	if claims.uid() != connectPacket.UID {
+		return ErrTokenUidNotMatch
+	}
+	if claims.DeviceFlag != nil && *claims.DeviceFlag != connectPacket.DeviceFlag.ToUint8() {
+		return ErrTokenDeviceFlagNotMatch
+	}
+	if claims.DeviceId != "" && claims.DeviceId != connectPacket.DeviceID {
+		return ErrTokenDeviceIdNotMatch
+	}
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	K   string `json:"k"`
+}
+
+// parseJwks 解析JWKS，支持RSA、OKP(Ed25519)和oct(HS256)类型的key
+func parseJwks(data []byte) (map[string]interface{}, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kid == "" {
+			return nil, errors.New("kid is empty")
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, err
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, err
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "OKP":
+			if k.Crv != "Ed25519" {
+				return nil, fmt.Errorf("unsupported crv[%s]", k.Crv)
+			}
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil {
+				return nil, err
+			}
+			if len(x) != ed25519.PublicKeySize {
+				return nil, errors.New("invalid ed25519 public key size")
+			}
+			keys[k.Kid] = ed25519.PublicKey(x)
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, err
+			}
+			keys[k.Kid] = secret
+		default:
+			return nil, fmt.Errorf("unsupported kty[%s]", k.Kty)
+		}
+	}
+	return keys, nil
+}
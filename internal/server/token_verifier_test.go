@@ -0,0 +1,95 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenVerifierHS256(t *testing.T) {
+	s := &Server{opts: NewOptions()}
+	s.opts.TokenAuth.Secret = "test_secret"
+	v := newTokenVerifier(s)
+
+	deviceFlag := wkproto.APP.ToUint8()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &connectTokenClaims{
+		UID:         "u1",
+		DeviceFlag:  &deviceFlag,
+		DeviceLevel: uint8(wkproto.DeviceLevelMaster),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte("test_secret"))
+	assert.NoError(t, err)
+
+	claims, err := v.parse(token)
+	assert.NoError(t, err)
+	assert.NoError(t, checkConnectTokenClaims(claims, &wkproto.ConnectPacket{UID: "u1", DeviceFlag: wkproto.APP}))
+	assert.Equal(t, ErrTokenUidNotMatch, checkConnectTokenClaims(claims, &wkproto.ConnectPacket{UID: "u2", DeviceFlag: wkproto.APP}))
+	assert.Equal(t, ErrTokenDeviceFlagNotMatch, checkConnectTokenClaims(claims, &wkproto.ConnectPacket{UID: "u1", DeviceFlag: wkproto.WEB}))
+
+	// 错误的密钥
+	badToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &connectTokenClaims{
+		UID: "u1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte("other_secret"))
+	assert.NoError(t, err)
+	_, err = v.parse(badToken)
+	assert.Error(t, err)
+
+	// 没有过期时间
+	noExpToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &connectTokenClaims{UID: "u1"}).SignedString([]byte("test_secret"))
+	assert.NoError(t, err)
+	_, err = v.parse(noExpToken)
+	assert.Error(t, err)
+}
+
+func TestTokenVerifierEdDSAWithJwks(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	err = os.WriteFile(jwksFile, []byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"k1","x":"`+base64.RawURLEncoding.EncodeToString(pub)+`"}]}`), 0644)
+	assert.NoError(t, err)
+
+	s := &Server{opts: NewOptions()}
+	s.opts.TokenAuth.JwksFile = jwksFile
+	s.opts.TokenAuth.JwksReloadInterval = 0
+	s.opts.TokenAuth.Issuer = "app"
+	v := newTokenVerifier(s)
+	assert.NoError(t, v.start())
+
+	newToken := func(kid string, issuer string) string {
+		tk := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &connectTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "u1",
+				Issuer:    issuer,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		})
+		tk.Header["kid"] = kid
+		token, err := tk.SignedString(priv)
+		assert.NoError(t, err)
+		return token
+	}
+
+	claims, err := v.parse(newToken("k1", "app"))
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", claims.uid())
+
+	_, err = v.parse(newToken("k2", "app")) // kid不存在
+	assert.Error(t, err)
+
+	_, err = v.parse(newToken("k1", "other")) // 签发者不匹配
+	assert.Error(t, err)
+}
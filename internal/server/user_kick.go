@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+var allDeviceFlags = []wkproto.DeviceFlag{wkproto.APP, wkproto.WEB, wkproto.PC}
+
+type kickUserReq struct {
+	Uid         string  `json:"uid"`
+	DeviceFlags []uint8 `json:"device_flags"`
+	TokenId     string  `json:"token_id,omitempty"` // 不为空则只踢掉使用此令牌（jti）认证的连接
+}
+
+// kickUser 踢掉用户指定设备的在线连接
+func (s *Server) kickUser(uid string, deviceFlags []wkproto.DeviceFlag) error {
+	req := kickUserReq{Uid: uid}
+	for _, deviceFlag := range deviceFlags {
+		req.DeviceFlags = append(req.DeviceFlags, deviceFlag.ToUint8())
+	}
+	return s.kick(req)
+}
+
+// kickUserByToken 踢掉用户使用指定令牌认证的在线连接（令牌被吊销后调用）
+func (s *Server) kickUserByToken(uid string, tokenId string) error {
+	return s.kick(kickUserReq{Uid: uid, TokenId: tokenId})
+}
+
+// kick 用户的连接都在用户所在槽的领导节点上（其他节点上的连接在领导节点上是代理连接，断开包会转发到连接所在的节点），
+// 本节点不是领导节点时请求领导节点执行
+func (s *Server) kick(req kickUserReq) error {
+	if s.opts.ClusterOn() {
+		leaderId, err := s.cluster.SlotLeaderIdOfChannel(req.Uid, wkproto.ChannelTypePerson)
+		if err != nil {
+			return err
+		}
+		if leaderId != s.opts.Cluster.NodeId {
+			timeoutCtx, cancel := context.WithTimeout(s.ctx, s.opts.Cluster.ReqTimeout)
+			defer cancel()
+			resp, err := s.cluster.RequestWithContext(timeoutCtx, leaderId, "/wk/kickUser", []byte(wkutil.ToJSON(req)))
+			if err != nil {
+				return err
+			}
+			if resp.Status != proto.Status_OK {
+				return errors.New(string(resp.Body))
+			}
+			return nil
+		}
+	}
+	s.kickLocalUser(req)
+	return nil
+}
+
+func (s *Server) kickLocalUser(req kickUserReq) {
+	deviceFlags := allDeviceFlags
+	if req.TokenId == "" {
+		deviceFlags = make([]wkproto.DeviceFlag, 0, len(req.DeviceFlags))
+		for _, deviceFlag := range req.DeviceFlags {
+			deviceFlags = append(deviceFlags, wkproto.DeviceFlag(deviceFlag))
+		}
+	}
+	for _, deviceFlag := range deviceFlags {
+		for _, conn := range s.userReactor.getConnContextByDeviceFlag(req.Uid, deviceFlag) {
+			if req.TokenId != "" && conn.tokenId != req.TokenId {
+				continue
+			}
+			_ = s.userReactor.writePacket(conn, &wkproto.DisconnectPacket{
+				ReasonCode: wkproto.ReasonConnectKick,
+			})
+			closeConn := conn
+			s.timingWheel.AfterFunc(time.Second*2, func() {
+				closeConn.close()
+			})
+		}
+	}
+}
+
+// handleKickUserReq 用户的领导节点踢掉用户的连接
+func (s *Server) handleKickUserReq(c *wkserver.Context) {
+	var req kickUserReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	leaderId, err := s.cluster.SlotLeaderIdOfChannel(req.Uid, wkproto.ChannelTypePerson)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	if leaderId != s.opts.Cluster.NodeId {
+		s.Warn("kick user: not is leader", zap.String("uid", req.Uid), zap.Uint64("leaderId", leaderId))
+		c.WriteErrorAndStatus(errors.New("not is leader"), proto.Status(errCodeNotIsUserLeader))
+		return
+	}
+	s.kickLocalUser(req)
+	c.WriteOk()
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkcipher"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
@@ -99,6 +100,7 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 	var (
 		connectPacket = msg.InPacket.(*wkproto.ConnectPacket)
 		devceLevel    wkproto.DeviceLevel
+		tokenId       string
 		isLocalConn   = msg.FromNodeId == r.s.opts.Cluster.NodeId // 是否是本地连接
 	)
 	var connCtx *connContext
@@ -123,6 +125,7 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 		r.Debug("auth: add conn", zap.Any("connCtx", connCtx))
 	}
 	// -------------------- token verify --------------------
+	tokenAuthOn, tokenAuth := r.s.opts.GetTokenAuth()
 	if connectPacket.UID == r.s.opts.ManagerUID {
 		if r.s.opts.ManagerTokenOn && connectPacket.Token != r.s.opts.ManagerToken {
 			r.Error("manager token verify fail", zap.String("uid", uid), zap.String("token", connectPacket.Token))
@@ -130,7 +133,21 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 			return wkproto.ReasonAuthFail, nil
 		}
 		devceLevel = wkproto.DeviceLevelSlave // 默认都是slave设备
-	} else if r.s.opts.TokenAuthOn {
+	} else if tokenAuthOn && tokenAuth.Mode == TokenAuthModeJwt { // 验证业务端签名的令牌
+		if connectPacket.Token == "" {
+			r.Error("token is empty")
+			r.authResponseConnackAuthFail(connCtx)
+			return wkproto.ReasonAuthFail, errors.New("token is empty")
+		}
+		level, jti, err := r.s.tokenVerifier.verify(connectPacket)
+		if err != nil {
+			r.Error("token verify fail", zap.Error(err), zap.String("uid", uid), zap.Any("conn", connCtx))
+			r.authResponseConnackAuthFail(connCtx)
+			return wkproto.ReasonAuthFail, err
+		}
+		devceLevel = level
+		tokenId = jti
+	} else if tokenAuthOn {
 		if connectPacket.Token == "" {
 			r.Error("token is empty")
 			r.authResponseConnackAuthFail(connCtx)
@@ -179,6 +196,12 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 		return wkproto.ReasonAuthFail, err
 	}
 	dhServerPublicKeyEnc := base64.StdEncoding.EncodeToString(dhServerPublicKey[:])
+	cipherSession, err := r.negotiateCipher(connectPacket, dhServerPrivKey, aesIV)
+	if err != nil {
+		r.Error("negotiate cipher err", zap.Error(err))
+		r.authResponseConnackAuthFail(connCtx)
+		return wkproto.ReasonAuthFail, err
+	}
 
 	// -------------------- same master kicks each other --------------------
 	oldConns := r.s.userReactor.getConnContextByDeviceFlag(uid, connectPacket.DeviceFlag)
@@ -233,7 +256,9 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 
 	connCtx.aesIV = aesIV
 	connCtx.aesKey = aesKey
+	connCtx.cipherSession = cipherSession
 	connCtx.deviceLevel = devceLevel
+	connCtx.tokenId = tokenId
 	connCtx.protoVersion = lastVersion
 	connCtx.isAuth.Store(true)
 
@@ -256,6 +281,9 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 		ServerVersion: lastVersion,
 		NodeId:        r.s.opts.Cluster.NodeId,
 	}
+	if cipherSession != nil { // 协商了AEAD加密，客户端通过服务端版本确认
+		connack.ServerVersion = wkcipher.Version
+	}
 	connack.HasServerVersion = hasServerVersion
 	r.authResponse(connCtx, connack)
 	// -------------------- user online --------------------
@@ -263,6 +291,9 @@ func (r *userReactor) handleAuth(uid string, msg ReactorUserMessage) (wkproto.Re
 	deviceOnlineCount := r.s.userReactor.getConnContextCountByDeviceFlag(uid, connectPacket.DeviceFlag)
 	totalOnlineCount := r.s.userReactor.getConnContextCount(uid)
 	r.s.webhook.Online(uid, connectPacket.DeviceFlag, connCtx.connId, deviceOnlineCount, totalOnlineCount)
+	if r.s.opts.Presence.On {
+		r.s.presenceManager.online(uid)
+	}
 	if totalOnlineCount <= 1 {
 		r.s.trace.Metrics.App().OnlineUserCountAdd(1) // 统计在线用户数
 	}
@@ -291,6 +322,30 @@ func (r *userReactor) getClientAesKeyAndIV(clientKey string, dhServerPrivKey [32
 	return aesKey, aesIV, nil
 }
 
+// negotiateCipher 协商AEAD加密，客户端的协议版本低于wkcipher.Version、未开启或客户端要求的加密方式不支持时返回nil（使用旧的AES-CBC加密）
+func (r *userReactor) negotiateCipher(connectPacket *wkproto.ConnectPacket, dhServerPrivKey [32]byte, salt string) (*wkcipher.Session, error) {
+	if !r.s.opts.TransportEncrypt.AEADOn || connectPacket.Version < wkcipher.Version {
+		return nil, nil
+	}
+	dhClientPubKey, suite, err := wkcipher.ParseClientKey(connectPacket.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	if !suite.IsAEAD() {
+		r.Warn("unsupported cipher suite, fallback to legacy", zap.String("suite", suite.String()), zap.String("uid", connectPacket.UID))
+		return nil, nil
+	}
+	shareKey := wkutil.GetCurve25519Key(dhServerPrivKey, dhClientPubKey)
+	return r.newCipherSession(suite, wkcipher.DeriveMasterKey(shareKey[:], []byte(salt)))
+}
+
+func (r *userReactor) newCipherSession(suite wkcipher.Suite, masterKey []byte) (*wkcipher.Session, error) {
+	return wkcipher.NewSession(suite, masterKey, wkcipher.RoleServer, wkcipher.Options{
+		RekeyMessages: r.s.opts.TransportEncrypt.RekeyMessages,
+		RekeyInterval: r.s.opts.TransportEncrypt.RekeyInterval,
+	})
+}
+
 func (r *userReactor) authResponse(connCtx *connContext, packet *wkproto.ConnackPacket) {
 	if connCtx.isRealConn {
 		_ = connCtx.writeDirectlyPacket(packet)
@@ -305,6 +360,8 @@ func (r *userReactor) authResponse(connCtx *connContext, packet *wkproto.Connack
 			AesIV:        connCtx.aesIV,
 			DeviceLevel:  connCtx.deviceLevel,
 			ProtoVersion: connCtx.protoVersion,
+			CipherSuite:  cipherSuiteOf(connCtx.cipherSession),
+			CipherKey:    cipherKeyOf(connCtx.cipherSession),
 		})
 		if err != nil {
 			r.Error("requestUserAuthResult error", zap.String("uid", connCtx.uid), zap.String("deviceId", connCtx.deviceId), zap.Error(err))
@@ -360,6 +417,8 @@ type UserAuthResult struct {
 	AesIV        string
 	DeviceLevel  wkproto.DeviceLevel
 	ProtoVersion uint8
+	CipherSuite  wkcipher.Suite // 协商的加密方式
+	CipherKey    []byte         // AEAD加密的主密钥
 }
 
 func (u *UserAuthResult) Marshal() ([]byte, error) {
@@ -374,6 +433,8 @@ func (u *UserAuthResult) Marshal() ([]byte, error) {
 	encoder.WriteString(u.AesIV)
 	encoder.WriteUint8(uint8(u.DeviceLevel))
 	encoder.WriteUint8(u.ProtoVersion)
+	encoder.WriteUint8(uint8(u.CipherSuite))
+	encoder.WriteBinary(u.CipherKey)
 	return encoder.Bytes(), nil
 }
 
@@ -414,9 +475,36 @@ func (u *UserAuthResult) Unmarshal(data []byte) error {
 	if u.ProtoVersion, err = decoder.Uint8(); err != nil {
 		return err
 	}
+
+	// 旧版本节点没有加密方式
+	if decoder.Len() == 0 {
+		return nil
+	}
+	var cipherSuite uint8
+	if cipherSuite, err = decoder.Uint8(); err != nil {
+		return err
+	}
+	u.CipherSuite = wkcipher.Suite(cipherSuite)
+	if u.CipherKey, err = decoder.Binary(); err != nil {
+		return err
+	}
 	return nil
 }
 
+func cipherSuiteOf(session *wkcipher.Session) wkcipher.Suite {
+	if session == nil {
+		return wkcipher.SuiteLegacy
+	}
+	return session.Suite()
+}
+
+func cipherKeyOf(session *wkcipher.Session) []byte {
+	if session == nil {
+		return nil
+	}
+	return session.MasterKey()
+}
+
 // =================================== ping ===================================
 
 func (r *userReactor) addPingReq(req *pingReq) {
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkcipher"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateCipher(t *testing.T) {
+	r := &userReactor{
+		s:   &Server{opts: NewOptions()},
+		Log: wklog.NewWKLog("userReactor"),
+	}
+	clientPrivKey, clientPubKey := wkutil.GetCurve25519KeypPair()
+	serverPrivKey, serverPubKey := wkutil.GetCurve25519KeypPair()
+	salt := wkutil.GetRandomString(16)
+
+	// 旧版本客户端使用AES-CBC
+	session, err := r.negotiateCipher(&wkproto.ConnectPacket{
+		Version:   wkproto.LatestVersion,
+		ClientKey: wkcipher.EncodeClientKey(clientPubKey, wkcipher.SuiteLegacy),
+	}, serverPrivKey, salt)
+	assert.NoError(t, err)
+	assert.Nil(t, session)
+
+	// 不支持的加密方式使用AES-CBC
+	session, err = r.negotiateCipher(&wkproto.ConnectPacket{
+		Version:   wkcipher.Version,
+		ClientKey: wkcipher.EncodeClientKey(clientPubKey, wkcipher.Suite(100)),
+	}, serverPrivKey, salt)
+	assert.NoError(t, err)
+	assert.Nil(t, session)
+
+	session, err = r.negotiateCipher(&wkproto.ConnectPacket{
+		Version:   wkcipher.Version,
+		ClientKey: wkcipher.EncodeClientKey(clientPubKey, wkcipher.SuiteChaCha20Poly1305),
+	}, serverPrivKey, salt)
+	assert.NoError(t, err)
+	assert.NotNil(t, session)
+	assert.Equal(t, wkcipher.SuiteChaCha20Poly1305, session.Suite())
+
+	// 客户端用自己的私钥和服务端的公钥得到相同的密钥
+	shareKey := wkutil.GetCurve25519Key(clientPrivKey, serverPubKey)
+	clientSession, err := wkcipher.NewSession(wkcipher.SuiteChaCha20Poly1305, wkcipher.DeriveMasterKey(shareKey[:], []byte(salt)), wkcipher.RoleClient, wkcipher.Options{})
+	assert.NoError(t, err)
+	conn := &connContext{connInfo: connInfo{cipherSession: session}}
+	payload, err := encryptMessagePayload([]byte("hello"), conn)
+	assert.NoError(t, err)
+	plaintext, err := clientSession.Decrypt(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+
+	msgKey, err := makeMsgKey("sign", conn)
+	assert.NoError(t, err)
+	assert.NotEqual(t, clientSession.Sign("sign"), msgKey)
+
+	// 关闭后都使用AES-CBC
+	r.s.opts.TransportEncrypt.AEADOn = false
+	session, err = r.negotiateCipher(&wkproto.ConnectPacket{
+		Version:   wkcipher.Version,
+		ClientKey: wkcipher.EncodeClientKey(clientPubKey, wkcipher.SuiteAES256GCM),
+	}, serverPrivKey, salt)
+	assert.NoError(t, err)
+	assert.Nil(t, session)
+}
+
+func TestUserAuthResultCipher(t *testing.T) {
+	result := &UserAuthResult{
+		ReasonCode:   wkproto.ReasonSuccess,
+		Uid:          "u1",
+		DeviceId:     "d1",
+		ConnId:       1,
+		ServerKey:    "serverKey",
+		AesKey:       "aesKey",
+		AesIV:        "aesIV",
+		DeviceLevel:  wkproto.DeviceLevelMaster,
+		ProtoVersion: wkproto.LatestVersion,
+		CipherSuite:  wkcipher.SuiteAES256GCM,
+		CipherKey:    []byte("01234567890123456789012345678901"),
+	}
+	data, err := result.Marshal()
+	assert.NoError(t, err)
+
+	result2 := &UserAuthResult{}
+	err = result2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, result, result2)
+
+	// 兼容旧版本节点的数据（没有加密方式）
+	legacy := &UserAuthResult{}
+	err = legacy.Unmarshal(data[:len(data)-1-2-len(result.CipherKey)])
+	assert.NoError(t, err)
+	assert.Equal(t, wkcipher.SuiteLegacy, legacy.CipherSuite)
+	assert.Equal(t, "aesIV", legacy.AesIV)
+}
@@ -375,6 +375,9 @@ func (u *userReactorSub) removeConnContextById(uid string, id int64) *connContex
 			u.Error("removeConnContextById: close user error", zap.String("uid", uid), zap.Int64("connId", id), zap.Error(err))
 		}
 		u.users.remove(uh.uid)
+		if uh.role == userRoleLeader && u.r.s.opts.Presence.On {
+			go u.r.s.presenceManager.offline(uh.uid) // 用户所有连接已断开
+		}
 	}
 	return conn
 }
@@ -395,6 +398,9 @@ func (u *userReactorSub) removeConnsByNodeId(uid string, nodeId uint64) []*connC
 			u.Error("removeConnsByNodeId: close user error", zap.String("uid", uid), zap.Uint64("nodeId", nodeId), zap.Error(err))
 		}
 		u.users.remove(uh.uid)
+		if uh.role == userRoleLeader && u.r.s.opts.Presence.On {
+			go u.r.s.presenceManager.offline(uh.uid) // 用户所有连接已断开
+		}
 	}
 	return conns
 }
@@ -0,0 +1,657 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
+	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// errUserDataNodeOffline 有节点离线，任务保持执行中状态，等待重试
+var errUserDataNodeOffline = errors.New("node offline")
+
+// errUserDataJobRunning 用户有正在执行的导出任务，删除任务保持执行中状态，等待重试
+var errUserDataJobRunning = errors.New("job running")
+
+// userDataManager 用户数据的导出和删除
+// 任务（wkdb.UserDataJob）保存在用户所在的槽内，由槽的领导节点执行：
+// 用户、设备、最近会话和用户属性与用户在同一个槽内，直接读取或通过槽删除；
+// 频道成员关系和用户发送的消息分布在各个节点上，通过节点间请求获取或匿名化。
+// 删除任务完成后任务记录保留，作为审计记录，用户的导出文件和导出任务一并删除
+type userDataManager struct {
+	s *Server
+	wklog.Log
+
+	mu      sync.Mutex
+	running map[uint64]struct{} // 正在执行的任务
+
+	scanner *intervalScanner
+}
+
+func newUserDataManager(s *Server) *userDataManager {
+	u := &userDataManager{
+		s:       s,
+		Log:     wklog.NewWKLog("userDataManager"),
+		running: make(map[uint64]struct{}),
+	}
+	u.scanner = newIntervalScanner(s.opts.UserData.ScanInterval, u.scan)
+	return u
+}
+
+func (u *userDataManager) start() error {
+	if u.s.opts.ClusterOn() {
+		u.scanner.start()
+	}
+	return nil
+}
+
+func (u *userDataManager) stop() {
+	u.scanner.stop()
+}
+
+// scan 执行本节点作为领导的槽内未完成的任务
+func (u *userDataManager) scan() {
+	for _, slot := range u.s.clusterServer.GetConfig().Slots {
+		if slot.Leader != u.s.opts.Cluster.NodeId {
+			continue
+		}
+		jobs, err := u.s.store.GetUserDataJobs(slot.Id)
+		if err != nil {
+			u.Error("get user data jobs failed", zap.Error(err), zap.Uint32("slotId", slot.Id))
+			continue
+		}
+		for _, job := range jobs {
+			if job.Status != wkdb.UserDataJobStatusRunning {
+				continue
+			}
+			// 上次未完成的任务等待重试间隔后再执行
+			if job.Error != "" && job.UpdatedAt != nil && time.Since(*job.UpdatedAt) < u.s.opts.UserData.RetryInterval {
+				continue
+			}
+			u.mu.Lock()
+			_, running := u.running[job.Id]
+			if !running {
+				u.running[job.Id] = struct{}{}
+			}
+			u.mu.Unlock()
+			if !running {
+				go u.run(job)
+			}
+		}
+	}
+}
+
+// create 创建任务
+func (u *userDataManager) create(uid string, jobType wkdb.UserDataJobType, operator string) (wkdb.UserDataJob, error) {
+	createdAt := time.Now()
+	job := wkdb.UserDataJob{
+		Id:        u.s.store.NextPrimaryKey(),
+		SlotId:    u.s.getSlotId(uid),
+		Uid:       uid,
+		Type:      jobType,
+		Status:    wkdb.UserDataJobStatusRunning,
+		Operator:  operator,
+		CreatedAt: &createdAt,
+		UpdatedAt: &createdAt,
+	}
+	if err := u.s.store.AddOrUpdateUserDataJob(job); err != nil {
+		return wkdb.EmptyUserDataJob, err
+	}
+	return job, nil
+}
+
+// jobsOfUid 获取用户的所有任务
+func (u *userDataManager) jobsOfUid(uid string) ([]wkdb.UserDataJob, error) {
+	jobs, err := u.s.store.GetUserDataJobs(u.s.getSlotId(uid))
+	if err != nil {
+		return nil, err
+	}
+	userJobs := make([]wkdb.UserDataJob, 0, len(jobs))
+	for _, job := range jobs {
+		if job.Uid == uid {
+			userJobs = append(userJobs, job)
+		}
+	}
+	return userJobs, nil
+}
+
+func (u *userDataManager) run(job wkdb.UserDataJob) {
+	defer func() {
+		u.mu.Lock()
+		delete(u.running, job.Id)
+		u.mu.Unlock()
+	}()
+
+	var err error
+	switch job.Type {
+	case wkdb.UserDataJobTypeExport:
+		err = u.export(&job)
+	case wkdb.UserDataJobTypeErase:
+		err = u.erase(&job)
+	default:
+		err = fmt.Errorf("unknown job type: %d", job.Type)
+	}
+
+	if err != nil {
+		u.Warn("user data job not finished", zap.Error(err), zap.Uint64("jobId", job.Id), zap.String("uid", job.Uid), zap.String("type", job.Type.String()))
+		job.Error = err.Error()
+		if !errors.Is(err, errUserDataNodeOffline) && !errors.Is(err, errUserDataJobRunning) {
+			job.Status = wkdb.UserDataJobStatusFailed
+		}
+	} else {
+		u.Info("user data job done", zap.Uint64("jobId", job.Id), zap.String("uid", job.Uid), zap.String("type", job.Type.String()), zap.String("operator", job.Operator))
+		job.Error = ""
+		job.Status = wkdb.UserDataJobStatusDone
+	}
+	updatedAt := time.Now()
+	job.UpdatedAt = &updatedAt
+	if err = u.s.store.AddOrUpdateUserDataJob(job); err != nil {
+		u.Error("save user data job failed", zap.Error(err), zap.Uint64("jobId", job.Id))
+	}
+}
+
+// userDataArchive 导出的用户数据
+type userDataArchive struct {
+	Uid           string              `json:"uid"`
+	JobId         uint64              `json:"job_id"`
+	ExportedAt    int64               `json:"exported_at"` // 导出时间（unix秒）
+	User          *wkdb.User          `json:"user,omitempty"`
+	Devices       []wkdb.Device       `json:"devices"`       // 设备（不包含token）
+	Conversations []wkdb.Conversation `json:"conversations"` // 最近会话
+	Attributes    []attributeResp     `json:"attributes"`    // 用户属性
+	Channels      []wkdb.Channel      `json:"channels"`      // 订阅的频道
+	Messages      []*MessageResp      `json:"messages"`      // 发送的消息
+}
+
+// export 导出用户数据到本节点的导出目录
+func (u *userDataManager) export(job *wkdb.UserDataJob) error {
+	uid := job.Uid
+	archive := &userDataArchive{
+		Uid:        uid,
+		JobId:      job.Id,
+		ExportedAt: time.Now().Unix(),
+	}
+
+	user, err := u.s.store.GetUser(uid)
+	if err != nil && err != wkdb.ErrNotFound {
+		return err
+	}
+	if !wkdb.IsEmptyUser(user) {
+		archive.User = &user
+	}
+
+	devices, err := u.s.store.GetDevices(uid)
+	if err != nil {
+		return err
+	}
+	for i := range devices {
+		devices[i].Token = ""
+	}
+	archive.Devices = devices
+
+	if archive.Conversations, err = u.s.store.GetConversations(uid); err != nil {
+		return err
+	}
+
+	attrs, err := u.s.store.GetUserAttributes(uid, nil)
+	if err != nil {
+		return err
+	}
+	archive.Attributes = newAttributeResps(attrs)
+
+	if archive.Channels, err = u.clusterChannels(uid); err != nil {
+		return err
+	}
+	if archive.Messages, err = u.clusterMessages(uid); err != nil {
+		return err
+	}
+
+	dir := u.exportDir()
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	archiveName := userDataArchiveName(job.Id)
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(path.Join(dir, archiveName), data, 0600); err != nil {
+		return err
+	}
+
+	job.NodeId = u.s.opts.Cluster.NodeId
+	job.Archive = archiveName
+	job.DeviceCount = uint64(len(archive.Devices))
+	job.ConversationCount = uint64(len(archive.Conversations))
+	job.ChannelCount = uint64(len(archive.Channels))
+	job.MessageCount = uint64(len(archive.Messages))
+	return nil
+}
+
+// erase 删除用户数据，每一步都可以重复执行，有节点离线时已完成的节点不再重复处理
+func (u *userDataManager) erase(job *wkdb.UserDataJob) error {
+	uid := job.Uid
+
+	// 通过用户的领导节点踢掉用户在集群内的在线连接
+	if err := u.s.kickUser(uid, allDeviceFlags); err != nil {
+		return err
+	}
+
+	// 先清除各个节点上用户的最近会话缓存，否则缓存中未保存的会话会在删除后被写回
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		if node.Id == u.s.opts.Cluster.NodeId {
+			u.s.conversationManager.DeleteUserFromCache(uid)
+			continue
+		}
+		if !node.Online {
+			return fmt.Errorf("%w: %d", errUserDataNodeOffline, node.Id)
+		}
+		if err := u.request(node.Id, "/wk/userData/purgeConversations", userDataReq{Uid: uid}, &struct{}{}); err != nil {
+			return err
+		}
+	}
+
+	// 删除用户所在槽内的数据，重试时数据已经删除，保留第一次的数量
+	devices, err := u.s.store.GetDevices(uid)
+	if err != nil {
+		return err
+	}
+	conversations, err := u.s.store.GetConversations(uid)
+	if err != nil {
+		return err
+	}
+	if len(devices) > 0 {
+		job.DeviceCount = uint64(len(devices))
+	}
+	if len(conversations) > 0 {
+		job.ConversationCount = uint64(len(conversations))
+	}
+	if err = u.s.store.EraseUser(uid); err != nil {
+		return err
+	}
+
+	// 移除频道成员关系
+	channels, err := u.clusterChannels(uid)
+	if err != nil {
+		return err
+	}
+	for _, ch := range channels {
+		if err = u.s.store.RemoveSubscribers(ch.ChannelId, ch.ChannelType, []string{uid}); err != nil {
+			return err
+		}
+		job.ChannelCount++
+
+		channelKey := wkutil.ChannelToKey(ch.ChannelId, ch.ChannelType)
+		channel := u.s.channelReactor.reactorSub(channelKey).channel(channelKey)
+		if channel != nil {
+			// 重新生成接收者标签
+			if _, err = channel.makeReceiverTag(); err != nil {
+				u.Warn("make receiver tag failed", zap.Error(err), zap.String("channelId", ch.ChannelId), zap.Uint8("channelType", ch.ChannelType))
+			}
+		}
+	}
+
+	// 删除用户的导出文件和导出任务
+	offlineNodes, err := u.removeExports(uid)
+	if err != nil {
+		return err
+	}
+
+	// 匿名化各个节点上的消息（频道的每个副本都有一份消息）
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		if wkutil.ArrayContainsUint64(job.ErasedNodes, node.Id) {
+			continue
+		}
+		if node.Id != u.s.opts.Cluster.NodeId && !node.Online {
+			if !wkutil.ArrayContainsUint64(offlineNodes, node.Id) {
+				offlineNodes = append(offlineNodes, node.Id)
+			}
+			continue
+		}
+		for {
+			count, err := u.nodeAnonymizeMessages(node.Id, uid)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				break
+			}
+			job.MessageCount += uint64(count)
+		}
+		job.ErasedNodes = append(job.ErasedNodes, node.Id)
+	}
+	if len(offlineNodes) > 0 {
+		return fmt.Errorf("%w: %v", errUserDataNodeOffline, offlineNodes)
+	}
+	return nil
+}
+
+// removeExports 删除用户的导出文件和导出任务，返回导出文件所在但离线的节点（这些任务等待重试）
+func (u *userDataManager) removeExports(uid string) ([]uint64, error) {
+	jobs, err := u.jobsOfUid(uid)
+	if err != nil {
+		return nil, err
+	}
+	var offlineNodes []uint64
+	for _, job := range jobs {
+		if job.Type != wkdb.UserDataJobTypeExport {
+			continue
+		}
+		// 占用任务，防止扫描时再执行，正在执行的导出任务等待下次重试
+		u.mu.Lock()
+		_, running := u.running[job.Id]
+		if !running {
+			u.running[job.Id] = struct{}{}
+		}
+		u.mu.Unlock()
+		if running {
+			return nil, fmt.Errorf("%w: export job %d", errUserDataJobRunning, job.Id)
+		}
+		offline, err := u.removeExport(job)
+		u.mu.Lock()
+		delete(u.running, job.Id)
+		u.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if offline && !wkutil.ArrayContainsUint64(offlineNodes, job.NodeId) {
+			offlineNodes = append(offlineNodes, job.NodeId)
+		}
+	}
+	return offlineNodes, nil
+}
+
+// removeExport 删除导出文件后删除导出任务，文件所在的节点离线时返回true
+func (u *userDataManager) removeExport(job wkdb.UserDataJob) (bool, error) {
+	if job.Archive != "" {
+		if job.NodeId == u.s.opts.Cluster.NodeId {
+			if err := u.removeLocalArchive(job.Id); err != nil {
+				return false, err
+			}
+		} else {
+			if !u.s.clusterServer.NodeIsOnline(job.NodeId) {
+				return true, nil
+			}
+			if err := u.request(job.NodeId, "/wk/userData/removeArchive", userDataReq{Uid: job.Uid, JobId: job.Id}, &struct{}{}); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, u.s.store.RemoveUserDataJob(job.SlotId, job.Id)
+}
+
+func (u *userDataManager) removeLocalArchive(jobId uint64) error {
+	err := os.Remove(path.Join(u.exportDir(), userDataArchiveName(jobId)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// getJob 从用户所在槽的领导节点获取任务，任务不存在或不属于该用户时返回EmptyUserDataJob
+func (u *userDataManager) getJob(uid string, jobId uint64) (wkdb.UserDataJob, error) {
+	var (
+		job wkdb.UserDataJob
+		err error
+	)
+	leaderId := u.s.opts.Cluster.NodeId
+	if u.s.opts.ClusterOn() {
+		if leaderId, err = u.s.cluster.SlotLeaderIdOfChannel(uid, wkproto.ChannelTypePerson); err != nil {
+			return wkdb.EmptyUserDataJob, err
+		}
+	}
+	if leaderId == u.s.opts.Cluster.NodeId {
+		job, err = u.s.store.GetUserDataJob(u.s.getSlotId(uid), jobId)
+	} else {
+		err = u.request(leaderId, "/wk/userData/job", userDataReq{Uid: uid, JobId: jobId}, &job)
+	}
+	if err != nil {
+		return wkdb.EmptyUserDataJob, err
+	}
+	if job.Uid != uid {
+		return wkdb.EmptyUserDataJob, nil
+	}
+	return job, nil
+}
+
+func (u *userDataManager) exportDir() string {
+	if u.s.opts.UserData.ExportDir != "" {
+		return u.s.opts.UserData.ExportDir
+	}
+	return path.Join(u.s.opts.DataDir, "userdata")
+}
+
+func userDataArchiveName(jobId uint64) string {
+	return fmt.Sprintf("%d.json", jobId)
+}
+
+// clusterChannels 获取用户在集群内订阅的频道，需要所有节点在线
+func (u *userDataManager) clusterChannels(uid string) ([]wkdb.Channel, error) {
+	channelMap := make(map[string]wkdb.Channel)
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		var (
+			channels []wkdb.Channel
+			err      error
+		)
+		if node.Id == u.s.opts.Cluster.NodeId {
+			channels, err = u.s.store.GetSubscribedChannels(uid)
+		} else if !node.Online {
+			return nil, fmt.Errorf("%w: %d", errUserDataNodeOffline, node.Id)
+		} else {
+			err = u.request(node.Id, "/wk/userData/channels", userDataReq{Uid: uid}, &channels)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, ch := range channels {
+			channelMap[wkutil.ChannelToKey(ch.ChannelId, ch.ChannelType)] = ch
+		}
+	}
+	channels := make([]wkdb.Channel, 0, len(channelMap))
+	for _, ch := range channelMap {
+		channels = append(channels, ch)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].ChannelType != channels[j].ChannelType {
+			return channels[i].ChannelType < channels[j].ChannelType
+		}
+		return channels[i].ChannelId < channels[j].ChannelId
+	})
+	return channels, nil
+}
+
+// clusterMessages 获取用户在集群内发送的消息，同一条消息在频道的每个副本上都有，按消息ID去重，需要所有节点在线
+func (u *userDataManager) clusterMessages(uid string) ([]*MessageResp, error) {
+	messageIds := make(map[int64]struct{})
+	var messages []*MessageResp
+	for _, node := range u.s.clusterServer.GetConfig().Nodes {
+		if node.Id != u.s.opts.Cluster.NodeId && !node.Online {
+			return nil, fmt.Errorf("%w: %d", errUserDataNodeOffline, node.Id)
+		}
+		var cursor wkdb.MessageCursor
+		for !cursor.Done {
+			resp, err := u.nodeMessages(node.Id, uid, cursor)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range resp.Messages {
+				if _, ok := messageIds[m.MessageId]; ok {
+					continue
+				}
+				messageIds[m.MessageId] = struct{}{}
+				messages = append(messages, m)
+			}
+			cursor = resp.Cursor
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].MessageId < messages[j].MessageId
+	})
+	return messages, nil
+}
+
+type userDataReq struct {
+	Uid    string             `json:"uid"`
+	JobId  uint64             `json:"job_id,omitempty"`
+	Cursor wkdb.MessageCursor `json:"cursor"`
+}
+
+type userDataMessagesResp struct {
+	Messages []*MessageResp     `json:"messages"`
+	Cursor   wkdb.MessageCursor `json:"cursor"`
+}
+
+func (u *userDataManager) nodeMessages(nodeId uint64, uid string, cursor wkdb.MessageCursor) (*userDataMessagesResp, error) {
+	if nodeId == u.s.opts.Cluster.NodeId {
+		return u.localMessages(uid, cursor)
+	}
+	resp := &userDataMessagesResp{}
+	if err := u.request(nodeId, "/wk/userData/messages", userDataReq{Uid: uid, Cursor: cursor}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (u *userDataManager) localMessages(uid string, cursor wkdb.MessageCursor) (*userDataMessagesResp, error) {
+	messages, nextCursor, err := u.s.store.GetMessagesOfFromUid(uid, cursor, u.s.opts.UserData.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	resp := &userDataMessagesResp{
+		Messages: make([]*MessageResp, 0, len(messages)),
+		Cursor:   nextCursor,
+	}
+	for _, m := range messages {
+		messageResp := &MessageResp{}
+		messageResp.from(m, u.s)
+		resp.Messages = append(resp.Messages, messageResp)
+	}
+	return resp, nil
+}
+
+// nodeAnonymizeMessages 匿名化节点上的一批消息，返回0表示已全部处理
+func (u *userDataManager) nodeAnonymizeMessages(nodeId uint64, uid string) (int, error) {
+	if nodeId == u.s.opts.Cluster.NodeId {
+		return u.s.store.AnonymizeMessagesOfFromUid(uid, u.s.opts.UserData.BatchSize)
+	}
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := u.request(nodeId, "/wk/userData/eraseMessages", userDataReq{Uid: uid}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+func (u *userDataManager) request(nodeId uint64, path string, req userDataReq, result interface{}) error {
+	timeoutCtx, cancel := context.WithTimeout(u.s.ctx, u.s.opts.Cluster.ReqTimeout)
+	defer cancel()
+	resp, err := u.s.cluster.RequestWithContext(timeoutCtx, nodeId, path, []byte(wkutil.ToJSON(req)))
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return errors.New(string(resp.Body))
+	}
+	return wkutil.ReadJSONByByte(resp.Body, result)
+}
+
+// handleChannelsReq 返回用户在本节点数据中订阅的频道
+func (u *userDataManager) handleChannelsReq(c *wkserver.Context) {
+	var req userDataReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	channels, err := u.s.store.GetSubscribedChannels(req.Uid)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(channels)))
+}
+
+// handleMessagesReq 分页返回用户在本节点数据中发送的消息
+func (u *userDataManager) handleMessagesReq(c *wkserver.Context) {
+	var req userDataReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	resp, err := u.localMessages(req.Uid, req.Cursor)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(resp)))
+}
+
+// handleEraseMessagesReq 匿名化用户在本节点数据中发送的一批消息
+func (u *userDataManager) handleEraseMessagesReq(c *wkserver.Context) {
+	var req userDataReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	count, err := u.s.store.AnonymizeMessagesOfFromUid(req.Uid, u.s.opts.UserData.BatchSize)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(map[string]interface{}{
+		"count": count,
+	})))
+}
+
+// handleJobReq 返回用户在本节点（用户所在槽的领导节点）上的任务
+func (u *userDataManager) handleJobReq(c *wkserver.Context) {
+	var req userDataReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	job, err := u.s.store.GetUserDataJob(u.s.getSlotId(req.Uid), req.JobId)
+	if err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(job)))
+}
+
+// handlePurgeConversationsReq 清除用户在本节点上的最近会话缓存
+func (u *userDataManager) handlePurgeConversationsReq(c *wkserver.Context) {
+	var req userDataReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	u.s.conversationManager.DeleteUserFromCache(req.Uid)
+	c.Write([]byte(wkutil.ToJSON(map[string]interface{}{})))
+}
+
+// handleRemoveArchiveReq 删除本节点上的导出文件
+func (u *userDataManager) handleRemoveArchiveReq(c *wkserver.Context) {
+	var req userDataReq
+	if err := wkutil.ReadJSONByByte(c.Body(), &req); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	if err := u.removeLocalArchive(req.JobId); err != nil {
+		c.WriteErr(err)
+		return
+	}
+	c.Write([]byte(wkutil.ToJSON(map[string]interface{}{})))
+}
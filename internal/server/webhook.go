@@ -19,6 +19,7 @@ import (
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"github.com/panjf2000/ants/v2"
 	"github.com/pkg/errors"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -31,6 +32,8 @@ type webhook struct {
 	eventPool        *ants.Pool
 	httpClient       *http.Client
 	webhookGRPCPool  *grpcpool.Pool // webhook grpc客户端
+	grpcPoolLock     sync.RWMutex
+	loopStarted      atomic.Bool // 推送协程是否已启动
 	stoped           chan struct{}
 	onlinestatusLock sync.RWMutex
 	onlinestatusList []string
@@ -47,12 +50,7 @@ func newWebhook(s *Server) *webhook {
 		webhookGRPCPool *grpcpool.Pool
 	)
 	if s.opts.WebhookGRPCOn() {
-		webhookGRPCPool, err = grpcpool.New(func() (*grpc.ClientConn, error) {
-			return grpc.Dial(s.opts.Webhook.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
-				Time:    5 * time.Minute, // send pings every 5 minute if there is no activity
-				Timeout: 2 * time.Second, // wait 1 second for ping ack before considering the connection dead
-			}))
-		}, 2, 20, time.Minute*5) // 初始化2个连接 最多20个连接
+		webhookGRPCPool, err = newWebhookGRPCPool(s.opts.GetWebhook().GRPCAddr)
 		if err != nil {
 			panic(err)
 		}
@@ -83,17 +81,67 @@ func newWebhook(s *Server) *webhook {
 	}
 }
 
+func newWebhookGRPCPool(addr string) (*grpcpool.Pool, error) {
+	return grpcpool.New(func() (*grpc.ClientConn, error) {
+		return grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    5 * time.Minute, // send pings every 5 minute if there is no activity
+			Timeout: 2 * time.Second, // wait 1 second for ping ack before considering the connection dead
+		}))
+	}, 2, 20, time.Minute*5) // 初始化2个连接 最多20个连接
+}
+
 func (w *webhook) Start() {
-	go w.notifyQueueLoop()
-	go w.loopOnlineStatus()
+	w.startLoops()
 }
 
 func (w *webhook) Stop() {
 	close(w.stoped)
+	w.grpcPoolLock.Lock()
+	if w.webhookGRPCPool != nil {
+		w.webhookGRPCPool.Close()
+	}
+	w.grpcPoolLock.Unlock()
+}
+
+// startLoops 开启了webhook才启动推送协程（启动后关闭webhook，协程会空转等待重新开启）
+func (w *webhook) startLoops() {
+	if !w.s.opts.WebhookOn() {
+		return
+	}
+	if !w.loopStarted.CompareAndSwap(false, true) {
+		return
+	}
+	go w.notifyQueueLoop()
+	go w.loopOnlineStatus()
+}
+
+// newGRPCPoolWithOptions 按新的配置创建grpc连接池（重新加载配置时在配置生效前调用），没有配置grpc地址时返回nil
+func (w *webhook) newGRPCPoolWithOptions(opts *Options) (*grpcpool.Pool, error) {
+	if !opts.WebhookGRPCOn() {
+		return nil, nil
+	}
+	return newWebhookGRPCPool(opts.GetWebhook().GRPCAddr)
+}
+
+// reload 重新加载的配置生效后调用，替换为新的grpc连接池（grpc地址变化时）并按需启动推送协程
+func (w *webhook) reload(grpcAddrChanged bool, pool *grpcpool.Pool) {
+	if grpcAddrChanged {
+		w.grpcPoolLock.Lock()
+		oldPool := w.webhookGRPCPool
+		w.webhookGRPCPool = pool
+		w.grpcPoolLock.Unlock()
+		if oldPool != nil {
+			oldPool.Close()
+		}
+	}
+	w.startLoops()
 }
 
 // Online 用户设备上线通知
 func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
+	if !w.s.opts.WebhookOn() {
+		return
+	}
 	w.onlinestatusLock.Lock()
 	defer w.onlinestatusLock.Unlock()
 	online := 1
@@ -103,6 +151,9 @@ func (w *webhook) Online(uid string, deviceFlag wkproto.DeviceFlag, connId int64
 }
 
 func (w *webhook) Offline(uid string, deviceFlag wkproto.DeviceFlag, connId int64, deviceOnlineCount int, totalOnlineCount int) {
+	if !w.s.opts.WebhookOn() {
+		return
+	}
 	w.onlinestatusLock.Lock()
 	defer w.onlinestatusLock.Unlock()
 	online := 0
@@ -118,6 +169,9 @@ func (w *webhook) TriggerEvent(event *Event) {
 		return
 	}
 	err := w.eventPool.Submit(func() {
+		if event.prepare != nil {
+			event.prepare()
+		}
 		jsonData, err := json.Marshal(event.Data)
 		if err != nil {
 			w.Error("webhook的event数据不能json化！", zap.Error(err))
@@ -157,33 +211,47 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 			compresssToUIDs = buff.Bytes()
 		}
 	}
+	messageResp := MessageResp{
+		Header: MessageHeader{
+			RedDot:    wkutil.BoolToInt(msg.SendPacket.RedDot),
+			SyncOnce:  wkutil.BoolToInt(msg.SendPacket.SyncOnce),
+			NoPersist: wkutil.BoolToInt(msg.SendPacket.NoPersist),
+		},
+		Setting:      msg.SendPacket.Setting.Uint8(),
+		ClientMsgNo:  msg.SendPacket.ClientMsgNo,
+		MessageId:    msg.MessageId,
+		MessageIdStr: strconv.FormatInt(msg.MessageId, 10),
+		MessageSeq:   uint64(msg.MessageSeq),
+		FromUID:      msg.FromUid,
+		ChannelID:    msg.SendPacket.ChannelID,
+		ChannelType:  msg.SendPacket.ChannelType,
+		Topic:        msg.SendPacket.Topic,
+		Expire:       msg.SendPacket.Expire,
+		Timestamp:    int32(time.Now().Unix()),
+		Payload:      msg.SendPacket.Payload,
+	}
+	data := &MessageOfflineNotify{
+		MessageResp:     messageResp,
+		ToUIDs:          toUIDs,
+		Compress:        compress,
+		CompresssToUIDs: compresssToUIDs,
+		SourceID:        int64(w.s.opts.Cluster.NodeId),
+	}
+	if !msg.SendPacket.NoPersist {
+		if mention := parseMessageMention(msg.SendPacket.Payload); mention != nil {
+			if mention.mentionAll() {
+				data.MentionAll = 1
+			} else {
+				data.MentionUIDs = mention.mentionedUids(subscribers, msg.FromUid)
+			}
+		}
+	}
 	// 推送离线到上层应用
 	w.TriggerEvent(&Event{
 		Event: EventMsgOffline,
-		Data: MessageOfflineNotify{
-			MessageResp: MessageResp{
-				Header: MessageHeader{
-					RedDot:    wkutil.BoolToInt(msg.SendPacket.RedDot),
-					SyncOnce:  wkutil.BoolToInt(msg.SendPacket.SyncOnce),
-					NoPersist: wkutil.BoolToInt(msg.SendPacket.NoPersist),
-				},
-				Setting:      msg.SendPacket.Setting.Uint8(),
-				ClientMsgNo:  msg.SendPacket.ClientMsgNo,
-				MessageId:    msg.MessageId,
-				MessageIdStr: strconv.FormatInt(msg.MessageId, 10),
-				MessageSeq:   uint64(msg.MessageSeq),
-				FromUID:      msg.FromUid,
-				ChannelID:    msg.SendPacket.ChannelID,
-				ChannelType:  msg.SendPacket.ChannelType,
-				Topic:        msg.SendPacket.Topic,
-				Expire:       msg.SendPacket.Expire,
-				Timestamp:    int32(time.Now().Unix()),
-				Payload:      msg.SendPacket.Payload,
-			},
-			ToUIDs:          toUIDs,
-			Compress:        compress,
-			CompresssToUIDs: compresssToUIDs,
-			SourceID:        int64(w.s.opts.Cluster.NodeId),
+		Data:  data,
+		prepare: func() {
+			w.s.attributeManager.fillMessageAttributes([]*MessageResp{&data.MessageResp})
 		},
 	})
 }
@@ -191,12 +259,26 @@ func (w *webhook) notifyOfflineMsg(msg ReactorChannelMessage, subscribers []stri
 // 通知上层应用 TODO: 此初报错可以做一个邮件报警处理类的东西，
 func (w *webhook) notifyQueueLoop() {
 	errorSleepTime := time.Second * 1 // 发生错误后sleep时间
-	ticker := time.NewTicker(w.s.opts.Webhook.MsgNotifyEventPushInterval)
+	pushInterval := w.s.opts.GetWebhook().MsgNotifyEventPushInterval
+	ticker := time.NewTicker(pushInterval)
 	defer ticker.Stop()
 	errMessageIDMap := make(map[int64]int) // 记录错误的消息ID value为错误次数
 	if w.s.opts.WebhookOn() {
 		for {
-			messages, err := w.s.store.GetMessagesOfNotifyQueue(w.s.opts.Webhook.MsgNotifyEventCountPerPush)
+			webhookOpts := w.s.opts.GetWebhook()
+			if pushInterval != webhookOpts.MsgNotifyEventPushInterval { // 推送间隔重新加载了
+				pushInterval = webhookOpts.MsgNotifyEventPushInterval
+				ticker.Reset(pushInterval)
+			}
+			if !w.s.opts.WebhookOn() { // webhook被关闭了
+				select {
+				case <-ticker.C:
+					continue
+				case <-w.stoped:
+					return
+				}
+			}
+			messages, err := w.s.store.GetMessagesOfNotifyQueue(webhookOpts.MsgNotifyEventCountPerPush)
 			if err != nil {
 				w.Error("获取通知队列内的消息失败！", zap.Error(err))
 				time.Sleep(errorSleepTime) // 如果报错就休息下
@@ -209,6 +291,7 @@ func (w *webhook) notifyQueueLoop() {
 					resp.from(msg, w.s)
 					messageResps = append(messageResps, resp)
 				}
+				w.s.attributeManager.fillMessageAttributes(messageResps)
 				messageData, err := json.Marshal(messageResps)
 				if err != nil {
 					w.Error("第三方消息通知的event数据不能json化！", zap.Error(err))
@@ -228,7 +311,7 @@ func (w *webhook) notifyQueueLoop() {
 						errCount := errMessageIDMap[message.MessageID]
 						errCount++
 						errMessageIDMap[message.MessageID] = errCount
-						if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
+						if errCount >= webhookOpts.MsgNotifyEventRetryMaxCount {
 							errMessageIDs = append(errMessageIDs, message.MessageID)
 						}
 					}
@@ -255,7 +338,7 @@ func (w *webhook) notifyQueueLoop() {
 				}
 				err = w.s.store.RemoveMessagesOfNotifyQueue(messageIDs)
 				if err != nil {
-					w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
+					w.Warn("从通知队列里移除消息失败！", zap.Error(err), zap.Int64s("messageIDs", messageIDs), zap.String("Webhook", webhookOpts.HTTPAddr))
 					time.Sleep(errorSleepTime) // 如果报错就休息下
 					continue
 				}
@@ -277,6 +360,14 @@ func (w *webhook) loopOnlineStatus() {
 	opLen := 0    // 最后一次操作在线状态数组的长度
 	errCount := 0 // webhook请求失败重试次数
 	for {
+		if !w.s.opts.WebhookOn() { // webhook被关闭了
+			w.onlinestatusLock.Lock()
+			w.onlinestatusList = w.onlinestatusList[:0]
+			opLen = 0
+			w.onlinestatusLock.Unlock()
+			time.Sleep(time.Second * 2)
+			continue
+		}
 		if opLen == 0 {
 			w.onlinestatusLock.Lock()
 			opLen = len(w.onlinestatusList)
@@ -304,8 +395,9 @@ func (w *webhook) loopOnlineStatus() {
 		if err != nil {
 			errCount++
 			w.Error("请求在线状态webhook失败！", zap.Error(err))
-			if errCount >= w.s.opts.Webhook.MsgNotifyEventRetryMaxCount {
-				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", w.s.opts.Webhook.MsgNotifyEventRetryMaxCount))
+			retryMaxCount := w.s.opts.GetWebhook().MsgNotifyEventRetryMaxCount
+			if errCount >= retryMaxCount {
+				w.Error("请求在线状态webhook失败通知超过最大次数！", zap.Int("MsgNotifyEventRetryMaxCount", retryMaxCount))
 
 				w.onlinestatusLock.Lock()
 				w.onlinestatusList = w.onlinestatusList[opLen:]
@@ -328,19 +420,20 @@ func (w *webhook) loopOnlineStatus() {
 }
 
 func (w *webhook) sendWebhookForHttp(event string, data []byte) error {
-	eventURL := fmt.Sprintf("%s?event=%s", w.s.opts.Webhook.HTTPAddr, event)
+	httpAddr := w.s.opts.GetWebhook().HTTPAddr
+	eventURL := fmt.Sprintf("%s?event=%s", httpAddr, event)
 	startTime := time.Now().UnixNano() / 1000 / 1000
 	w.Debug("webhook开始请求", zap.String("eventURL", eventURL))
 	resp, err := w.httpClient.Post(eventURL, "application/json", bytes.NewBuffer(data))
 	w.Debug("webhook请求结束 耗时", zap.Int64("mill", time.Now().UnixNano()/1000/1000-startTime))
 	if err != nil {
-		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", w.s.opts.Webhook.HTTPAddr), zap.Error(err))
+		w.Warn("调用第三方消息通知失败！", zap.String("Webhook", httpAddr), zap.Error(err))
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", w.s.opts.Webhook.HTTPAddr))
+		w.Warn("第三方消息通知接口返回状态错误！", zap.Int("status", resp.StatusCode), zap.String("Webhook", httpAddr))
 		return errors.New("第三方消息通知接口返回状态错误！")
 	}
 	return nil
@@ -354,7 +447,13 @@ func (w *webhook) sendWebhookForGRPC(event string, data []byte) error {
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
-	clientConn, err := w.webhookGRPCPool.Get(ctx)
+	w.grpcPoolLock.RLock()
+	pool := w.webhookGRPCPool
+	w.grpcPoolLock.RUnlock()
+	if pool == nil {
+		return errors.New("webhook grpc pool is nil")
+	}
+	clientConn, err := pool.Get(ctx)
 	if err != nil {
 		return err
 	}
@@ -395,6 +494,8 @@ const (
 type Event struct {
 	Event string      `json:"event"` // 事件标示
 	Data  interface{} `json:"data"`  // 事件数据
+
+	prepare func() // 在事件池内发送前执行，用于补充比较耗时获取的数据，避免阻塞触发事件的地方
 }
 
 func (e *Event) String() string {
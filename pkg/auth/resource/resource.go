@@ -9,9 +9,15 @@ var Slot = slot{
 
 // 频道资源
 var ClusterChannel = channel{
-	Migrate: "clusterchannelMigrate", // 迁移频道
-	Start:   "clusterchannelStart",   // 启动频道
-	Stop:    "clusterchannelStop",    // 停止频道
+	Migrate:        "clusterchannelMigrate",        // 迁移频道
+	Start:          "clusterchannelStart",          // 启动频道
+	Stop:           "clusterchannelStop",           // 停止频道
+	TransferLeader: "clusterchannelTransferLeader", // 转移频道领导
+}
+
+// 配置资源
+var Config = config{
+	Reload: "configReload", // 重新加载配置
 }
 
 type slot struct {
@@ -19,9 +25,14 @@ type slot struct {
 }
 
 type channel struct {
-	Migrate Id
-	Start   Id
-	Stop    Id
+	Migrate        Id
+	Start          Id
+	Stop           Id
+	TransferLeader Id
+}
+
+type config struct {
+	Reload Id
 }
 
 var All Id = "*"
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkcipher"
 	"github.com/WuKongIM/WuKongIM/pkg/wklog"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
@@ -30,6 +31,8 @@ type Client struct {
 	aesKey string // aes密钥
 	salt   string // 安全码
 
+	cipherSession *wkcipher.Session // AEAD加密会话，为空时使用aes加密
+
 	clientIDGen atomic.Uint64
 
 	addr   string
@@ -550,7 +553,11 @@ func (c *Client) handleRecvPacket(packet *wkproto.RecvPacket) {
 	var payload []byte
 	if c.onRecv != nil {
 		if !packet.Setting.IsSet(wkproto.SettingNoEncrypt) {
-			payload, err = wkutil.AesDecryptPkcs7Base64(packet.Payload, []byte(c.aesKey), []byte(c.salt))
+			if c.cipherSession != nil {
+				payload, err = c.cipherSession.Decrypt(packet.Payload)
+			} else {
+				payload, err = wkutil.AesDecryptPkcs7Base64(packet.Payload, []byte(c.aesKey), []byte(c.salt))
+			}
 			if err != nil {
 				c.Panic("解密消息payload失败！", zap.Error(err), zap.String("payload", string(packet.Payload)), zap.String("aesKey", c.aesKey), zap.String("salt", c.salt))
 			}
@@ -593,7 +600,11 @@ func (c *Client) SendMessage(channel *Channel, payload []byte, opt ...SendOption
 	newPayload := payload
 	if !opts.NoEncrypt {
 		// 加密消息内容
-		newPayload, err = wkutil.AesEncryptPkcs7Base64(payload, []byte(c.aesKey), []byte(c.salt))
+		if c.cipherSession != nil {
+			newPayload, err = c.cipherSession.Encrypt(payload)
+		} else {
+			newPayload, err = wkutil.AesEncryptPkcs7Base64(payload, []byte(c.aesKey), []byte(c.salt))
+		}
 		if err != nil {
 			c.Error("加密消息payload失败！", zap.Error(err), zap.String("aesKey", c.aesKey), zap.String("salt", c.salt))
 			return err
@@ -623,7 +634,9 @@ func (c *Client) SendMessage(channel *Channel, payload []byte, opt ...SendOption
 	packet.RedDot = true
 
 	// 加密消息通道
-	if !opts.NoEncrypt {
+	if !opts.NoEncrypt && c.cipherSession != nil {
+		packet.MsgKey = c.cipherSession.Sign(packet.VerityString())
+	} else if !opts.NoEncrypt {
 		signStr := packet.VerityString()
 		actMsgKey, err := wkutil.AesEncryptPkcs7Base64([]byte(signStr), []byte(c.aesKey), []byte(c.salt))
 		if err != nil {
@@ -675,11 +688,15 @@ func (c *Client) flusher() {
 func (c *Client) sendConnect() error {
 	var clientPubKey [32]byte
 	c.clientPrivKey, clientPubKey = wkutil.GetCurve25519KeypPair() // 生成服务器的DH密钥对
+	version := c.opts.ProtoVersion
+	if c.opts.CipherSuite.IsAEAD() && version < wkcipher.Version { // 协议版本5及以上才能协商AEAD加密，包的编码和版本4相同
+		version = wkcipher.Version
+	}
 	packet := &wkproto.ConnectPacket{
-		Version:         c.opts.ProtoVersion,
+		Version:         version,
 		DeviceID:        wkutil.GenUUID(),
 		DeviceFlag:      wkproto.APP,
-		ClientKey:       base64.StdEncoding.EncodeToString(clientPubKey[:]),
+		ClientKey:       wkcipher.EncodeClientKey(clientPubKey, c.opts.CipherSuite),
 		ClientTimestamp: time.Now().Unix(),
 		UID:             c.opts.UID,
 		Token:           c.opts.Token,
@@ -711,6 +728,13 @@ func (c *Client) sendConnect() error {
 
 	shareKey := wkutil.GetCurve25519Key(c.clientPrivKey, serverPubKey) // 共享key
 	c.aesKey = wkutil.MD5(base64.StdEncoding.EncodeToString(shareKey[:]))[:16]
+	c.cipherSession = nil
+	if c.opts.CipherSuite.IsAEAD() && connack.ServerVersion >= wkcipher.Version { // 服务端同意了AEAD加密
+		c.cipherSession, err = wkcipher.NewSession(c.opts.CipherSuite, wkcipher.DeriveMasterKey(shareKey[:], []byte(c.salt)), wkcipher.RoleClient, wkcipher.Options{})
+		if err != nil {
+			return err
+		}
+	}
 	c.status = CONNECTED
 
 	return nil
@@ -3,6 +3,7 @@ package client
 import (
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/wkcipher"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 )
 
@@ -37,6 +38,8 @@ type Options struct {
 	// ReconnectWait sets the time to backoff after attempting a reconnect
 	// to a server that we were already connected to previously.
 	ReconnectWait time.Duration
+
+	CipherSuite wkcipher.Suite // 期望的消息加密方式，默认为旧的AES-CBC，服务端不支持AEAD加密时也使用AES-CBC
 }
 
 // NewOptions 创建默认配置
@@ -65,6 +68,14 @@ func WithProtoVersion(version uint8) Option {
 	}
 }
 
+// WithCipherSuite 设置期望的消息加密方式
+func WithCipherSuite(suite wkcipher.Suite) Option {
+	return func(opts *Options) error {
+		opts.CipherSuite = suite
+		return nil
+	}
+}
+
 // WithUID 用户UID
 func WithUID(uid string) Option {
 	return func(opts *Options) error {
@@ -21,6 +21,7 @@ const (
 	CMDTypeSlotMigrate                       // 槽迁移
 	CMDTypeSlotUpdate                        // 槽更新
 	CMDTypeNodeStatusChange                  // 节点状态改变
+	CMDTypeNodeLabelsChange                  // 节点可用区和机架变更
 
 )
 
@@ -48,6 +49,8 @@ func (c CMDType) String() string {
 		return "CMDTypeSlotUpdate"
 	case CMDTypeNodeStatusChange:
 		return "CMDTypeNodeStatusChange"
+	case CMDTypeNodeLabelsChange:
+		return "CMDTypeNodeLabelsChange"
 	}
 	return "CMDTypeUnknown"
 }
@@ -169,6 +172,16 @@ func (c *CMD) CMDContent() (string, error) {
 			"nodeId": nodeId,
 			"status": status,
 		}), nil
+	case CMDTypeNodeLabelsChange:
+		nodeId, zone, rack, err := DecodeNodeLabelsChange(c.Data)
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"nodeId": nodeId,
+			"zone":   zone,
+			"rack":   rack,
+		}), nil
 	}
 
 	return "", nil
@@ -193,6 +206,27 @@ func DecodeApiServerAddrChange(data []byte) (uint64, string, error) {
 	return nodeId, apiServerAddr, err
 }
 
+func EncodeNodeLabelsChange(nodeId uint64, zone, rack string) ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(nodeId)
+	enc.WriteString(zone)
+	enc.WriteString(rack)
+	return enc.Bytes(), nil
+}
+
+func DecodeNodeLabelsChange(data []byte) (nodeId uint64, zone string, rack string, err error) {
+	dec := wkproto.NewDecoder(data)
+	if nodeId, err = dec.Uint64(); err != nil {
+		return
+	}
+	if zone, err = dec.String(); err != nil {
+		return
+	}
+	rack, err = dec.String()
+	return
+}
+
 func EncodeNodeOnlineStatusChange(nodeId uint64, online bool) ([]byte, error) {
 	enc := wkproto.NewEncoder()
 	defer enc.End()
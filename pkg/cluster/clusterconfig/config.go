@@ -182,6 +182,18 @@ func (c *Config) updateApiServerAddr(nodeId uint64, addr string) {
 	}
 }
 
+func (c *Config) updateNodeLabels(nodeId uint64, zone, rack string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, node := range c.cfg.Nodes {
+		if node.Id == nodeId {
+			node.Zone = zone
+			node.Rack = rack
+			return
+		}
+	}
+}
+
 func (c *Config) updateNodeOnlineStatus(nodeId uint64, online bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -431,6 +443,14 @@ func (c *Config) hasWillJoinNode() bool {
 	return false
 }
 
+// saveConfigTo 将当前配置写入到指定文件（用于备份）
+func (c *Config) saveConfigTo(filePath string) error {
+	c.mu.RLock()
+	data := wkutil.ToJSON(c.cfg)
+	c.mu.RUnlock()
+	return os.WriteFile(filePath, []byte(data), os.ModePerm)
+}
+
 func (c *Config) saveConfig() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
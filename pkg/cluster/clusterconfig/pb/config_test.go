@@ -36,3 +36,60 @@ func TestSlotSetMarshal(t *testing.T) {
 	assert.Equal(t, len(slotSet), len(slotSet2))
 
 }
+
+func TestNodeZoneMarshal(t *testing.T) {
+	node := &Node{
+		Id:   1,
+		Zone: "zone-a",
+		Rack: "rack-1",
+	}
+	data, err := node.Marshal()
+	assert.Nil(t, err)
+
+	node2 := &Node{}
+	err = node2.Unmarshal(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "zone-a", node2.Zone)
+	assert.Equal(t, "rack-1", node2.Rack)
+}
+
+func TestSpreadNodes(t *testing.T) {
+	nodes := []*Node{
+		{Id: 1, Zone: "a", Rack: "1"},
+		{Id: 2, Zone: "a", Rack: "2"},
+		{Id: 3, Zone: "a", Rack: "1"},
+		{Id: 4, Zone: "b", Rack: "1"},
+		{Id: 5, Zone: "c", Rack: "1"},
+	}
+	picked := SpreadNodes(nodes[:1], nodes[1:], 2)
+	assert.Equal(t, 2, len(picked))
+	assert.Equal(t, uint64(4), picked[0].Id)
+	assert.Equal(t, uint64(5), picked[1].Id)
+
+	// 可用区用完后按机架打散
+	picked = SpreadNodes(nodes[:1], nodes[1:3], 1)
+	assert.Equal(t, uint64(2), picked[0].Id)
+
+	// 没有可用区和机架时按顺序选择
+	plain := []*Node{{Id: 1}, {Id: 2}, {Id: 3}}
+	picked = SpreadNodes(nil, plain, 2)
+	assert.Equal(t, uint64(1), picked[0].Id)
+	assert.Equal(t, uint64(2), picked[1].Id)
+}
+
+func TestCheckPlacement(t *testing.T) {
+	nodes := []*Node{
+		{Id: 1, Zone: "a", Rack: "1"},
+		{Id: 2, Zone: "a", Rack: "2"},
+		{Id: 3, Zone: "b", Rack: "1"},
+	}
+	assert.Nil(t, CheckPlacement([]*Node{nodes[0], nodes[2]}, nodes))
+
+	violation := CheckPlacement([]*Node{nodes[0], nodes[1]}, nodes)
+	assert.NotNil(t, violation)
+	assert.Equal(t, "zone", violation.Level)
+	assert.Equal(t, 1, violation.Actual)
+	assert.Equal(t, 2, violation.Expected)
+
+	assert.Nil(t, CheckPlacement([]*Node{{Id: 1}, {Id: 2}}, []*Node{{Id: 1}, {Id: 2}, {Id: 3}}))
+}
@@ -0,0 +1,80 @@
+package pb
+
+// rackKey 机架在可用区内唯一，所以用可用区+机架区分机架
+func (n *Node) rackKey() string {
+	return n.Zone + "/" + n.Rack
+}
+
+// SpreadNodes 按可用区和机架打散选择节点
+// 依次从candidates中选出与已选节点（selected和本次已选出的节点）同可用区最少、其次同机架最少的节点，直到选出count个
+// 条件相同时按candidates的顺序选择，所以节点都没有设置可用区和机架时，结果与按顺序选择一致
+func SpreadNodes(selected []*Node, candidates []*Node, count int) []*Node {
+	zoneCount := make(map[string]int)
+	rackCount := make(map[string]int)
+	for _, n := range selected {
+		zoneCount[n.Zone]++
+		rackCount[n.rackKey()]++
+	}
+
+	picked := make([]*Node, 0, count)
+	used := make([]bool, len(candidates))
+	for len(picked) < count {
+		best := -1
+		for i, n := range candidates {
+			if used[i] {
+				continue
+			}
+			if best == -1 {
+				best = i
+				continue
+			}
+			b := candidates[best]
+			if zoneCount[n.Zone] < zoneCount[b.Zone] || (zoneCount[n.Zone] == zoneCount[b.Zone] && rackCount[n.rackKey()] < rackCount[b.rackKey()]) {
+				best = i
+			}
+		}
+		if best == -1 {
+			break
+		}
+		used[best] = true
+		n := candidates[best]
+		zoneCount[n.Zone]++
+		rackCount[n.rackKey()]++
+		picked = append(picked, n)
+	}
+	return picked
+}
+
+// PlacementViolation 副本没有按可用区（机架）打散
+type PlacementViolation struct {
+	Level    string `json:"level"`    // zone: 可用区 rack: 机架
+	Actual   int    `json:"actual"`   // 副本实际分布的可用区（机架）数量
+	Expected int    `json:"expected"` // 副本应该分布的可用区（机架）数量
+}
+
+// CheckPlacement 检查副本是否已经尽可能的分布在不同的可用区和机架上，nodes为可以放置副本的所有节点
+// 返回nil表示没有违规
+func CheckPlacement(replicas []*Node, nodes []*Node) *PlacementViolation {
+	if len(replicas) <= 1 {
+		return nil
+	}
+	allZones := make(map[string]struct{})
+	allRacks := make(map[string]struct{})
+	for _, n := range nodes {
+		allZones[n.Zone] = struct{}{}
+		allRacks[n.rackKey()] = struct{}{}
+	}
+	zones := make(map[string]struct{})
+	racks := make(map[string]struct{})
+	for _, n := range replicas {
+		zones[n.Zone] = struct{}{}
+		racks[n.rackKey()] = struct{}{}
+	}
+	if expected := min(len(replicas), len(allZones)); len(zones) < expected {
+		return &PlacementViolation{Level: "zone", Actual: len(zones), Expected: expected}
+	}
+	if expected := min(len(replicas), len(allRacks)); len(racks) < expected {
+		return &PlacementViolation{Level: "rack", Actual: len(racks), Expected: expected}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path"
 	"strconv"
 	"strings"
@@ -168,6 +169,17 @@ func (s *Server) Config() *pb.Config {
 	return s.cfg.config()
 }
 
+// Checkpoint 在指定目录生成配置日志和配置文件的快照，目录结构与配置目录一致
+func (s *Server) Checkpoint(dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if err := s.storage.Checkpoint(path.Join(dir, "cfglogdb")); err != nil {
+		return err
+	}
+	return s.cfg.saveConfigTo(path.Join(dir, path.Base(s.opts.ConfigPath)))
+}
+
 // SlotCount 获取槽数量
 func (s *Server) SlotCount() uint32 {
 	return s.cfg.slotCount()
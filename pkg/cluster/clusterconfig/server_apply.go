@@ -69,6 +69,8 @@ func (s *Server) handleCmd(cmd *CMD) error {
 		return s.handleSlotUpdate(cmd)
 	case CMDTypeNodeStatusChange: // 节点状态改变
 		return s.handleNodeStatusChange(cmd)
+	case CMDTypeNodeLabelsChange: // 节点可用区和机架变更
+		return s.handleNodeLabelsChange(cmd)
 	}
 	return nil
 }
@@ -180,3 +182,14 @@ func (s *Server) handleNodeStatusChange(cmd *CMD) error {
 	s.cfg.updateNodeStatus(nodeId, status)
 	return nil
 }
+
+func (s *Server) handleNodeLabelsChange(cmd *CMD) error {
+	nodeId, zone, rack, err := DecodeNodeLabelsChange(cmd.Data)
+	if err != nil {
+		s.Error("decode node labels change err", zap.Error(err))
+		return err
+	}
+
+	s.cfg.updateNodeLabels(nodeId, zone, rack)
+	return nil
+}
@@ -64,6 +64,34 @@ func (s *Server) ProposeApiServerAddr(nodeId uint64, apiServerAddr string) error
 	return nil
 }
 
+// ProposeNodeLabels 提案节点可用区和机架变更
+func (s *Server) ProposeNodeLabels(nodeId uint64, zone, rack string) error {
+
+	data, err := EncodeNodeLabelsChange(nodeId, zone, rack)
+	if err != nil {
+		return err
+	}
+
+	cmd := NewCMD(CMDTypeNodeLabelsChange, data)
+	cmdBytes, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+
+	err = s.proposeAndWait([]replica.Log{
+		{
+			Id:   uint64(s.cfgGenId.Generate().Int64()),
+			Data: cmdBytes,
+		},
+	})
+	if err != nil {
+		s.Error("ProposeNodeLabels failed", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 // ProposeJoin 提案节点加入
 func (s *Server) ProposeJoin(node *pb.Node) error {
 
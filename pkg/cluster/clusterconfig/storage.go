@@ -31,9 +31,19 @@ func NewPebbleShardLogStorage(path string) *PebbleShardLogStorage {
 }
 
 func (p *PebbleShardLogStorage) Open() error {
+	return p.open(false)
+}
+
+// OpenReadOnly 以只读方式打开（节点停止后离线检查日志时使用）
+func (p *PebbleShardLogStorage) OpenReadOnly() error {
+	return p.open(true)
+}
+
+func (p *PebbleShardLogStorage) open(readOnly bool) error {
 	var err error
 	p.db, err = pebble.Open(p.path, &pebble.Options{
 		FormatMajorVersion: pebble.FormatNewest,
+		ReadOnly:           readOnly,
 	})
 	if err != nil {
 		return err
@@ -49,6 +59,11 @@ func (p *PebbleShardLogStorage) Close() error {
 	return nil
 }
 
+// Checkpoint 在指定目录生成数据库的一致性快照（dir不能已存在）
+func (p *PebbleShardLogStorage) Checkpoint(dir string) error {
+	return p.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
 // AppendLog 追加日志
 func (p *PebbleShardLogStorage) AppendLog(logs []replica.Log) error {
 
@@ -60,6 +60,13 @@ func (s *Server) checkClusterConfig() error {
 			return err
 		}
 
+		// 检查槽副本是否按可用区（机架）打散
+		err = s.handleSlotPlacement()
+		if err != nil {
+			s.Error("handleSlotPlacement failed", zap.Error(err))
+			return err
+		}
+
 	}
 
 	// ================== 处理槽领导选举 ==================
@@ -120,8 +127,10 @@ func (s *Server) handleClusterConfigInit() error {
 	var replicas []uint64
 	for nodeId, addr := range s.opts.InitNodes {
 		apiAddr := ""
+		zone, rack := "", ""
 		if nodeId == s.opts.NodeId {
 			apiAddr = s.opts.ApiServerAddr
+			zone, rack = s.opts.Zone, s.opts.Rack
 		}
 		nodes = append(nodes, &pb.Node{
 			Id:            nodeId,
@@ -132,6 +141,8 @@ func (s *Server) handleClusterConfigInit() error {
 			Role:          pb.NodeRole_NodeRoleReplica,
 			Status:        pb.NodeStatus_NodeStatusJoined,
 			CreatedAt:     time.Now().Unix(),
+			Zone:          zone,
+			Rack:          rack,
 		})
 		replicas = append(replicas, nodeId)
 	}
@@ -202,6 +213,16 @@ func (s *Server) handleCompareLocalClusterConfig() error {
 		}
 	}
 
+	// 如果配置里自己节点的可用区和机架与本节点配置不同，则提案配置
+	localNode := s.cfgServer.Node(s.opts.NodeId)
+	if localNode != nil && (localNode.Zone != s.opts.Zone || localNode.Rack != s.opts.Rack) {
+		err := s.cfgServer.ProposeNodeLabels(s.opts.NodeId, s.opts.Zone, s.opts.Rack)
+		if err != nil {
+			s.Error("ProposeNodeLabels failed", zap.Error(err))
+			return err
+		}
+	}
+
 	if s.IsLeader() {
 		// 节点在线状态改变
 		err := s.handleNodeOnlineStatusChange()
@@ -284,6 +305,9 @@ func (s *Server) autoBalanceSlotLeaders(cfg *pb.Config) []*pb.Slot {
 	// ==================== 迁移槽领导 ====================
 
 	var nodeOnline = func(nodeId uint64) bool {
+		if s.NodeDraining(nodeId) { // 下线中的节点不参与均衡
+			return false
+		}
 		for _, node := range cfg.Nodes {
 			if node.Id == nodeId {
 				return node.Online
@@ -447,7 +471,8 @@ func (s *Server) handleNodeJoining() error {
 
 				// ------------------- 分配槽副本 -------------------
 				if fromSlotCount > 0 && !allocSlotLeader {
-					if wkutil.ArrayContainsUint64(slot.Replicas, node.Id) {
+					// 新节点替换后副本不能比原来更集中在同一个可用区（机架）
+					if wkutil.ArrayContainsUint64(slot.Replicas, node.Id) && placementNotWorse(slot.Replicas, node.Id, joiningNode, s.cfgServer.Nodes()) {
 						newSlot := slot.Clone()
 						newSlot.MigrateFrom = node.Id
 						newSlot.MigrateTo = joiningNode.Id
@@ -497,6 +522,7 @@ func (s *Server) handleNodeOnlineChange(nodeId uint64, online bool) error {
 	if online { // 节点上线
 
 		s.Info("节点上线", zap.Uint64("nodeId", nodeId))
+		s.clearNodeDraining(nodeId) // 节点重启后重新上线，不再是下线状态
 		slots := s.cfgServer.Slots()
 
 		onlineNodeCount := s.cfgServer.AllowVoteAndJoinedOnlineNodeCount()
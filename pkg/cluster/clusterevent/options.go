@@ -31,6 +31,8 @@ type Options struct {
 	HeartbeatIntervalTick int           // 心跳间隔tick
 	ElectionIntervalTick  int           // 选举间隔tick
 
+	Zone string // 本节点所在的可用区（副本会尽量分布在不同的可用区）
+	Rack string // 本节点所在的机架（同一可用区内副本会尽量分布在不同的机架）
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -152,3 +154,17 @@ func WithOnSlotElection(f func(slots []*pb.Slot) error) Option {
 		o.OnSlotElection = f
 	}
 }
+
+// WithZone 设置本节点所在的可用区
+func WithZone(zone string) Option {
+	return func(o *Options) {
+		o.Zone = zone
+	}
+}
+
+// WithRack 设置本节点所在的机架
+func WithRack(rack string) Option {
+	return func(o *Options) {
+		o.Rack = rack
+	}
+}
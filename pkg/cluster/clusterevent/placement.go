@@ -0,0 +1,125 @@
+package clusterevent
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// replicaNodes 获取副本对应的节点信息
+func replicaNodes(replicas []uint64, nodes []*pb.Node) []*pb.Node {
+	replicaNodes := make([]*pb.Node, 0, len(replicas))
+	for _, replicaId := range replicas {
+		for _, node := range nodes {
+			if node.Id == replicaId {
+				replicaNodes = append(replicaNodes, node)
+				break
+			}
+		}
+	}
+	return replicaNodes
+}
+
+// placementScore 副本分布的可用区数量和机架数量，数量越多越分散
+func placementScore(replicas []*pb.Node) (zoneCount int, rackCount int) {
+	zones := make(map[string]struct{})
+	racks := make(map[string]struct{})
+	for _, n := range replicas {
+		zones[n.Zone] = struct{}{}
+		racks[n.Zone+"/"+n.Rack] = struct{}{}
+	}
+	return len(zones), len(racks)
+}
+
+// placementNotWorse 将副本from替换为to后，副本的分布是否没有变得更集中
+func placementNotWorse(replicas []uint64, from uint64, to *pb.Node, nodes []*pb.Node) bool {
+	oldNodes := replicaNodes(replicas, nodes)
+	newNodes := replicaNodes(wkutil.RemoveUint64(append([]uint64{}, replicas...), from), nodes)
+	newNodes = append(newNodes, to)
+	oldZone, oldRack := placementScore(oldNodes)
+	newZone, newRack := placementScore(newNodes)
+	return newZone > oldZone || (newZone == oldZone && newRack >= oldRack)
+}
+
+// pickPlacementMigrate 为没有按可用区（机架）打散的槽选择一个需要迁移的副本和迁移的目标节点（只选择在线节点）
+// 优先迁移与其他副本同可用区（同机架）的非领导副本，目标节点由pb.SpreadNodes选出，迁移后副本必须更分散
+func pickPlacementMigrate(slot *pb.Slot, nodes []*pb.Node) (from uint64, to uint64, ok bool) {
+	replicas := replicaNodes(slot.Replicas, nodes)
+	if pb.CheckPlacement(replicas, nodes) == nil {
+		return 0, 0, false
+	}
+	candidates := make([]*pb.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Online && !wkutil.ArrayContainsUint64(slot.Replicas, node.Id) {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, 0, false
+	}
+
+	oldZone, oldRack := placementScore(replicas)
+	for _, replica := range replicas {
+		if replica.Id == slot.Leader { // 领导不参与迁移，避免槽领导切换
+			continue
+		}
+		others := make([]*pb.Node, 0, len(replicas)-1)
+		for _, r := range replicas {
+			if r.Id != replica.Id {
+				others = append(others, r)
+			}
+		}
+		picked := pb.SpreadNodes(others, candidates, 1)
+		if len(picked) == 0 {
+			continue
+		}
+		newZone, newRack := placementScore(append(others, picked[0]))
+		if newZone > oldZone || (newZone == oldZone && newRack > oldRack) {
+			return replica.Id, picked[0].Id, true
+		}
+	}
+	return 0, 0, false
+}
+
+// handleSlotPlacement 检查槽副本是否按可用区（机架）打散，没有打散则每次迁移一个槽的一个副本
+func (s *Server) handleSlotPlacement() error {
+	cfg := s.cfgServer.Config()
+
+	// 有未加入的节点或者有槽正在迁移，则不进行调整
+	for _, node := range cfg.Nodes {
+		if node.Status != pb.NodeStatus_NodeStatusJoined {
+			return nil
+		}
+	}
+	for _, slot := range cfg.Slots {
+		if slot.MigrateFrom != 0 || slot.MigrateTo != 0 || len(slot.Learners) > 0 {
+			return nil
+		}
+		if slot.Status == pb.SlotStatus_SlotStatusCandidate {
+			return nil
+		}
+	}
+
+	nodes := s.cfgServer.AllowVoteAndJoinedNodes()
+	if len(nodes) <= 1 {
+		return nil
+	}
+	for _, slot := range cfg.Slots {
+		from, to, ok := pickPlacementMigrate(slot, nodes)
+		if !ok {
+			continue
+		}
+		newSlot := slot.Clone()
+		newSlot.MigrateFrom = from
+		newSlot.MigrateTo = to
+		newSlot.Learners = append(newSlot.Learners, to)
+		s.Info("migrate slot replica for zone placement", zap.Uint32("slotId", slot.Id), zap.Uint64("from", from), zap.Uint64("to", to))
+		err := s.ProposeSlots([]*pb.Slot{newSlot})
+		if err != nil {
+			s.Error("handleSlotPlacement failed,ProposeSlots failed", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"time"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -36,6 +38,9 @@ type Server struct {
 	stopped atomic.Bool
 
 	pongC chan uint64
+
+	drainingNodes     map[uint64]time.Time // 正在下线的节点（值为下线标记的过期时间）
+	drainingNodesLock sync.RWMutex
 }
 
 func New(opts *Options) *Server {
@@ -57,6 +62,7 @@ func New(opts *Options) *Server {
 		stopper:       syncutil.NewStopper(),
 		pongTickMap:   make(map[uint64]int),
 		pongC:         make(chan uint64, 100),
+		drainingNodes: make(map[uint64]time.Time),
 	}
 
 	s.cfgServer = clusterconfig.New(clusterconfig.NewOptions(
@@ -122,6 +128,12 @@ func (s *Server) AddMessage(m reactor.Message) {
 	}
 }
 
+// Checkpoint 在指定目录生成集群配置的快照
+// 本地配置（local.json）不备份，恢复后节点会根据集群配置重新生成
+func (s *Server) Checkpoint(dir string) error {
+	return s.cfgServer.Checkpoint(dir)
+}
+
 func (s *Server) loadLocalConfig() error {
 	clusterCfgPath := s.localCfgPath
 	var err error
@@ -166,6 +178,28 @@ func (s *Server) NodeOnline(nodeId uint64) bool {
 	return s.cfgServer.NodeOnline(nodeId)
 }
 
+// SetNodeDraining 标记节点正在下线，标记在until之后失效（节点重启后上线也会清除）
+// 下线中的节点不会再被自动均衡分配槽领导
+func (s *Server) SetNodeDraining(nodeId uint64, until time.Time) {
+	s.drainingNodesLock.Lock()
+	defer s.drainingNodesLock.Unlock()
+	s.drainingNodes[nodeId] = until
+}
+
+// NodeDraining 节点是否正在下线
+func (s *Server) NodeDraining(nodeId uint64) bool {
+	s.drainingNodesLock.RLock()
+	defer s.drainingNodesLock.RUnlock()
+	until, ok := s.drainingNodes[nodeId]
+	return ok && time.Now().Before(until)
+}
+
+func (s *Server) clearNodeDraining(nodeId uint64) {
+	s.drainingNodesLock.Lock()
+	defer s.drainingNodesLock.Unlock()
+	delete(s.drainingNodes, nodeId)
+}
+
 func (s *Server) Config() *pb.Config {
 
 	return s.cfgServer.Config()
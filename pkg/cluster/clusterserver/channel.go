@@ -29,6 +29,12 @@ type channel struct {
 	cfg            wkdb.ChannelClusterConfig
 	pausePropopose atomic.Bool // 是否暂停提案
 
+	proposeCount   atomic.Uint64 // 提案成功的日志数量（用于统计消息速率）
+	lastSample     uint64        // 上次采样时的提案数量（只在频道领导均衡器中访问）
+	lastSampleTime time.Time     // 上次采样时间
+
+	committedIndex atomic.Uint64 // 本节点已提交的日志下标（副本读时用于判断副本是否已追上）
+
 	sendConfigTimeoutTick int // 发送配置超时（达到这个tick表示，需要发送配置请求了）
 
 	learnerToLock sync.Mutex
@@ -69,6 +75,7 @@ func newChannel(channelId string, channelType uint8, s *Server) *channel {
 		replica.WithOnConfigChange(c.onReplicaConfigChange),
 	)
 	c.rc = rc
+	c.committedIndex.Store(appliedIdx)
 	return c
 }
 
@@ -161,6 +168,13 @@ func (c *channel) GetLogs(startIndex uint64, endIndex uint64) ([]replica.Log, er
 }
 
 func (c *channel) ApplyLogs(startIndex, endIndex uint64) (uint64, error) {
+	if endIndex > 0 {
+		c.committedIndex.Store(endIndex - 1)
+	}
+	// 只通知提交进度，回调方异步读取已存储的日志，不阻塞副本的应用协程
+	if c.opts.OnChannelApply != nil && endIndex > startIndex {
+		c.opts.OnChannelApply(c.channelId, c.channelType, endIndex-1)
+	}
 	return 0, nil
 }
 
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// 子频道（例如消息的子区）与父频道使用相同的副本和领导，子频道的数据（例如子区的回复）在父频道的领导上校验后写入，
+// 父频道的副本或领导变化后，子频道在槽领导上加载配置时（频道激活、频道领导间隔比对配置等）逐步与父频道对齐，
+// 每次只做一步调整，并且复用频道迁移和领导转移的流程，新副本追上日志后才会成为副本或领导，不会丢失子频道的日志
+
+// parentChannel 获取子频道的父频道
+func (s *Server) parentChannel(channelId string, channelType uint8) (string, uint8, bool) {
+	if s.opts.ParentChannel == nil {
+		return "", 0, false
+	}
+	return s.opts.ParentChannel(channelId, channelType)
+}
+
+// syncChildChannelClusterConfigIfNeed 子频道的配置与父频道不一致时返回调整后的配置，调用者需要提案保存
+func (s *Server) syncChildChannelClusterConfigIfNeed(cfg wkdb.ChannelClusterConfig, parentChannelId string, parentChannelType uint8) (wkdb.ChannelClusterConfig, bool) {
+	if cfg.MigrateFrom != 0 || cfg.MigrateTo != 0 || len(cfg.Learners) > 0 { // 迁移中，等迁移完成后再对齐
+		return cfg, false
+	}
+	parentCfg, err := s.loadOnlyChannelClusterConfig(parentChannelId, parentChannelType)
+	if err != nil {
+		s.Debug("syncChildChannelClusterConfig: load parent config failed", zap.Error(err), zap.String("channelId", cfg.ChannelId), zap.String("parentChannelId", parentChannelId))
+		return cfg, false
+	}
+	newCfg, ok := nextChildChannelClusterConfig(cfg, parentCfg, s.clusterEventServer.NodeOnline)
+	if !ok {
+		return cfg, false
+	}
+	s.Info("sync child channel cluster config with parent", zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType), zap.String("parentChannelId", parentChannelId), zap.Uint64s("parentReplicas", parentCfg.Replicas), zap.Uint64("parentLeaderId", parentCfg.LeaderId), zap.String("cfg", newCfg.String()))
+	return newCfg, true
+}
+
+// nextChildChannelClusterConfig 子频道向父频道对齐的下一步
+// 1. 缺少父频道的副本：以学习者加入（与频道迁移相同），同时替换掉子频道多出的副本（优先替换领导）
+// 2. 多出父频道没有的追随者：直接移除；只多出领导时先把领导转移给父频道的领导
+// 3. 副本一致但领导不同：把领导转移给父频道的领导
+func nextChildChannelClusterConfig(cfg, parentCfg wkdb.ChannelClusterConfig, online func(nodeId uint64) bool) (wkdb.ChannelClusterConfig, bool) {
+	if cfg.MigrateFrom != 0 || cfg.MigrateTo != 0 || len(cfg.Learners) > 0 {
+		return cfg, false
+	}
+	if parentCfg.LeaderId == 0 || len(parentCfg.Replicas) == 0 {
+		return cfg, false
+	}
+
+	var missing uint64
+	for _, replicaId := range parentCfg.Replicas {
+		if !wkutil.ArrayContainsUint64(cfg.Replicas, replicaId) && online(replicaId) {
+			missing = replicaId
+			break
+		}
+	}
+	extras := make([]uint64, 0)
+	for _, replicaId := range cfg.Replicas {
+		if !wkutil.ArrayContainsUint64(parentCfg.Replicas, replicaId) {
+			extras = append(extras, replicaId)
+		}
+	}
+
+	newCfg := cfg.Clone() // Clone不会复制副本和学习者的切片
+	newCfg.Replicas = append([]uint64(nil), cfg.Replicas...)
+	newCfg.Learners = append([]uint64(nil), cfg.Learners...)
+	switch {
+	case missing != 0:
+		migrateFrom := missing // 只是加入新副本
+		if len(extras) > 0 {
+			migrateFrom = extras[0]
+			if wkutil.ArrayContainsUint64(extras, cfg.LeaderId) {
+				migrateFrom = cfg.LeaderId
+			}
+		}
+		newCfg.MigrateFrom = migrateFrom
+		newCfg.MigrateTo = missing
+		newCfg.Learners = append(newCfg.Learners, missing)
+	case len(extras) > 0:
+		for _, replicaId := range extras {
+			if replicaId != cfg.LeaderId {
+				newCfg.Replicas = wkutil.RemoveUint64(newCfg.Replicas, replicaId)
+			}
+		}
+		if len(newCfg.Replicas) == len(cfg.Replicas) { // 只多出领导
+			if !online(parentCfg.LeaderId) || !wkutil.ArrayContainsUint64(cfg.Replicas, parentCfg.LeaderId) {
+				return cfg, false
+			}
+			newCfg.MigrateFrom = cfg.LeaderId
+			newCfg.MigrateTo = parentCfg.LeaderId
+		}
+	case cfg.LeaderId != parentCfg.LeaderId:
+		if !online(parentCfg.LeaderId) {
+			return cfg, false
+		}
+		newCfg.MigrateFrom = cfg.LeaderId
+		newCfg.MigrateTo = parentCfg.LeaderId
+	default:
+		return cfg, false
+	}
+	newCfg.ConfVersion = uint64(time.Now().UnixNano())
+	return newCfg, true
+}
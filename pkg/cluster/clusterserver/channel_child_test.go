@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextChildChannelClusterConfig(t *testing.T) {
+	online := func(nodeId uint64) bool { return nodeId != 9 }
+	newCfg := func(leaderId uint64, replicas ...uint64) wkdb.ChannelClusterConfig {
+		return wkdb.ChannelClusterConfig{ChannelId: "g1@@1", ChannelType: 2, LeaderId: leaderId, Replicas: replicas}
+	}
+	parent := newCfg(2, 1, 2, 3)
+
+	// 一致
+	_, ok := nextChildChannelClusterConfig(newCfg(2, 1, 2, 3), parent, online)
+	assert.False(t, ok)
+
+	// 领导不同，转移领导
+	cfg, ok := nextChildChannelClusterConfig(newCfg(1, 1, 2, 3), parent, online)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), cfg.MigrateFrom)
+	assert.Equal(t, uint64(2), cfg.MigrateTo)
+	assert.Equal(t, 0, len(cfg.Learners))
+
+	// 缺少副本并且领导是多出的副本，新副本以学习者加入并替换领导
+	child := newCfg(4, 1, 2, 4)
+	cfg, ok = nextChildChannelClusterConfig(child, parent, online)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(4), cfg.MigrateFrom)
+	assert.Equal(t, uint64(3), cfg.MigrateTo)
+	assert.Equal(t, []uint64{3}, cfg.Learners)
+	assert.Equal(t, []uint64{1, 2, 4}, child.Replicas)
+
+	// 缺少副本，只加入
+	cfg, ok = nextChildChannelClusterConfig(newCfg(2, 1, 2), parent, online)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(3), cfg.MigrateFrom)
+	assert.Equal(t, uint64(3), cfg.MigrateTo)
+
+	// 多出的追随者直接移除
+	child = newCfg(2, 1, 2, 3, 4)
+	cfg, ok = nextChildChannelClusterConfig(child, parent, online)
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{1, 2, 3}, cfg.Replicas)
+	assert.Equal(t, []uint64{1, 2, 3, 4}, child.Replicas)
+
+	// 缺少的副本不在线时不加入
+	_, ok = nextChildChannelClusterConfig(newCfg(2, 1, 2), newCfg(2, 1, 2, 9), online)
+	assert.False(t, ok)
+
+	// 迁移中不调整
+	migrating := newCfg(1, 1, 2, 3)
+	migrating.MigrateFrom = 1
+	migrating.MigrateTo = 2
+	_, ok = nextChildChannelClusterConfig(migrating, parent, online)
+	assert.False(t, ok)
+}
@@ -0,0 +1,266 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wklog"
+	"github.com/lni/goutils/syncutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// channelRate 频道的消息速率
+type channelRate struct {
+	ch   *channel
+	rate float64 // 条/秒
+}
+
+// channelLeaderBalancer 按消息速率均衡各节点的频道领导
+// 每个节点定时统计自己作为领导的频道的消息速率，
+// 当本节点的消息速率明显高于集群平均值时，将热点频道的领导转移给负载较低的副本节点
+type channelLeaderBalancer struct {
+	s       *Server
+	opts    *Options
+	stopper *syncutil.Stopper
+	wklog.Log
+
+	mu    sync.RWMutex
+	load  ChannelLeaderLoad // 最近一次统计的本节点负载
+	rates []channelRate     // 最近一次统计的本节点领导频道的消息速率
+}
+
+func newChannelLeaderBalancer(s *Server) *channelLeaderBalancer {
+	return &channelLeaderBalancer{
+		s:       s,
+		opts:    s.opts,
+		stopper: syncutil.NewStopper(),
+		Log:     wklog.NewWKLog(fmt.Sprintf("channelLeaderBalancer[%d]", s.opts.NodeId)),
+		load: ChannelLeaderLoad{
+			NodeId: s.opts.NodeId,
+		},
+	}
+}
+
+func (b *channelLeaderBalancer) start() error {
+	if !b.opts.ChannelLeaderBalanceOn {
+		return nil
+	}
+	b.stopper.RunWorker(b.loop)
+	return nil
+}
+
+func (b *channelLeaderBalancer) stop() {
+	b.stopper.Stop()
+}
+
+func (b *channelLeaderBalancer) loop() {
+	tk := time.NewTicker(b.opts.ChannelLeaderBalanceInterval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			b.sample()
+			b.balance()
+		case <-b.stopper.ShouldStop():
+			return
+		}
+	}
+}
+
+// localLoad 本节点最近一次统计的负载
+func (b *channelLeaderBalancer) localLoad() ChannelLeaderLoad {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.load
+}
+
+// sample 统计本节点领导频道的消息速率
+func (b *channelLeaderBalancer) sample() {
+	now := time.Now()
+	rates := make([]channelRate, 0)
+	var total float64
+	b.s.channelManager.iterator(func(ch *channel) bool {
+		count := ch.proposeCount.Load()
+		lastTime := ch.lastSampleTime
+		lastCount := ch.lastSample
+		ch.lastSample = count
+		ch.lastSampleTime = now
+		if lastTime.IsZero() || !ch.isLeader() { // 第一次采样没有参照值
+			return true
+		}
+		elapsed := now.Sub(lastTime).Seconds()
+		if elapsed <= 0 {
+			return true
+		}
+		rate := float64(count-lastCount) / elapsed
+		rates = append(rates, channelRate{ch: ch, rate: rate})
+		total += rate
+		return true
+	})
+
+	b.mu.Lock()
+	b.rates = rates
+	b.load = ChannelLeaderLoad{
+		NodeId:      b.opts.NodeId,
+		LeaderCount: uint32(len(rates)),
+		MsgRate:     total,
+	}
+	b.mu.Unlock()
+}
+
+func (b *channelLeaderBalancer) balance() {
+	if b.s.stopped.Load() || b.s.draining.Load() {
+		return
+	}
+	b.mu.RLock()
+	local := b.load
+	rates := make([]channelRate, len(b.rates))
+	copy(rates, b.rates)
+	b.mu.RUnlock()
+
+	if local.MsgRate <= 0 {
+		return
+	}
+
+	loads := b.clusterLoads()
+	if len(loads) <= 1 {
+		return
+	}
+	loads[local.NodeId] = local.MsgRate
+
+	var total float64
+	for _, rate := range loads {
+		total += rate
+	}
+	avg := total / float64(len(loads))
+	localRate := local.MsgRate
+	if localRate <= avg*(1+b.opts.ChannelLeaderBalanceThreshold) || localRate-avg < b.opts.ChannelLeaderBalanceMinRate {
+		return
+	}
+
+	transfers := pickChannelLeaderTransfers(rates, loads, b.opts.NodeId, b.opts.ChannelLeaderBalanceMaxTransfer)
+	for _, t := range transfers {
+		timeoutCtx, cancel := context.WithTimeout(b.s.cancelCtx, b.opts.ReqTimeout)
+		err := b.s.TransferChannelLeader(timeoutCtx, t.channelId, t.channelType, t.to)
+		cancel()
+		if err != nil {
+			b.Warn("transfer channel leader failed", zap.Error(err), zap.String("channelId", t.channelId), zap.Uint8("channelType", t.channelType), zap.Uint64("to", t.to))
+			continue
+		}
+		b.Info("balance channel leader", zap.String("channelId", t.channelId), zap.Uint8("channelType", t.channelType), zap.Uint64("to", t.to), zap.Float64("rate", t.rate), zap.Float64("localRate", localRate), zap.Float64("avgRate", avg))
+	}
+}
+
+// clusterLoads 获取其他在线节点的消息速率，获取失败的节点不参与均衡
+func (b *channelLeaderBalancer) clusterLoads() map[uint64]float64 {
+	nodes := b.s.clusterEventServer.AllowVoteAndJoinedOnlineNodes()
+
+	var mu sync.Mutex
+	loads := make(map[uint64]float64, len(nodes))
+
+	timeoutCtx, cancel := context.WithTimeout(b.s.cancelCtx, b.opts.ReqTimeout)
+	defer cancel()
+	requestGroup, _ := errgroup.WithContext(timeoutCtx)
+	for _, n := range nodes {
+		if n.Id == b.opts.NodeId {
+			continue
+		}
+		node := b.s.nodeManager.node(n.Id)
+		if node == nil {
+			continue
+		}
+		requestGroup.Go(func() error {
+			load, err := node.requestChannelLeaderLoad(timeoutCtx)
+			if err != nil {
+				b.Warn("request channel leader load failed", zap.Error(err), zap.Uint64("nodeId", node.id))
+				return nil
+			}
+			mu.Lock()
+			loads[node.id] = load.MsgRate
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = requestGroup.Wait()
+	return loads
+}
+
+type channelLeaderTransfer struct {
+	channelId   string
+	channelType uint8
+	to          uint64
+	rate        float64
+}
+
+// pickChannelLeaderTransfers 从最热的频道开始，选出需要转移领导的频道和目标节点
+// 目标节点为频道副本中负载最低的节点，并且转移后目标节点的负载不能超过本节点
+func pickChannelLeaderTransfers(rates []channelRate, loads map[uint64]float64, localNodeId uint64, maxTransfer int) []channelLeaderTransfer {
+	if len(rates) == 0 || maxTransfer <= 0 {
+		return nil
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].rate > rates[j].rate
+	})
+
+	var total float64
+	for _, rate := range loads {
+		total += rate
+	}
+	avg := total / float64(len(loads))
+	localRate := loads[localNodeId]
+
+	transfers := make([]channelLeaderTransfer, 0, maxTransfer)
+	for _, r := range rates {
+		if len(transfers) >= maxTransfer || localRate <= avg {
+			break
+		}
+		if r.rate <= 0 {
+			break
+		}
+		r.ch.mu.Lock()
+		cfg := r.ch.cfg
+		r.ch.mu.Unlock()
+		if cfg.MigrateFrom != 0 || cfg.MigrateTo != 0 { // 迁移中
+			continue
+		}
+
+		var (
+			target     uint64
+			targetRate float64
+		)
+		for _, replicaId := range cfg.Replicas {
+			if replicaId == localNodeId {
+				continue
+			}
+			rate, ok := loads[replicaId]
+			if !ok { // 不在线或者获取负载失败
+				continue
+			}
+			if target == 0 || rate < targetRate {
+				target = replicaId
+				targetRate = rate
+			}
+		}
+		if target == 0 {
+			continue
+		}
+		// 转移后目标节点不能比本节点更忙，否则只是把热点换了个地方
+		if targetRate+r.rate >= localRate-r.rate {
+			continue
+		}
+		transfers = append(transfers, channelLeaderTransfer{
+			channelId:   cfg.ChannelId,
+			channelType: cfg.ChannelType,
+			to:          target,
+			rate:        r.rate,
+		})
+		loads[target] += r.rate
+		loads[localNodeId] -= r.rate
+		localRate -= r.rate
+	}
+	return transfers
+}
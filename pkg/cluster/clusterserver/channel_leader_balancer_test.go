@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelLeaderLoadMarshal(t *testing.T) {
+	load := &ChannelLeaderLoad{
+		NodeId:      1,
+		LeaderCount: 20,
+		MsgRate:     123.5,
+	}
+	data, err := load.Marshal()
+	assert.NoError(t, err)
+
+	load2 := &ChannelLeaderLoad{}
+	err = load2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, load, load2)
+}
+
+func TestPickChannelLeaderTransfers(t *testing.T) {
+	newCh := func(channelId string, replicas ...uint64) *channel {
+		return &channel{
+			cfg: wkdb.ChannelClusterConfig{
+				ChannelId:   channelId,
+				ChannelType: 2,
+				LeaderId:    1,
+				Replicas:    replicas,
+			},
+		}
+	}
+	rates := []channelRate{
+		{ch: newCh("small", 1, 2, 3), rate: 10},
+		{ch: newCh("hot", 1, 2, 3), rate: 500}, // 转移后节点2会比节点1更忙，跳过
+		{ch: newCh("warm", 1, 2, 3), rate: 200},
+		{ch: newCh("onlyLocal", 1), rate: 150}, // 没有其他副本
+	}
+	loads := map[uint64]float64{
+		1: 860,
+		2: 100,
+		3: 150,
+	}
+	transfers := pickChannelLeaderTransfers(rates, loads, 1, 10)
+	assert.Equal(t, 2, len(transfers))
+	assert.Equal(t, "warm", transfers[0].channelId)
+	assert.Equal(t, uint64(2), transfers[0].to)
+	assert.Equal(t, "small", transfers[1].channelId)
+	assert.Equal(t, uint64(3), transfers[1].to)
+}
@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/reactor"
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// TransferChannelLeader 将频道领导平滑转移到指定的副本节点
+// 转移复用频道迁移的流程（MigrateFrom为当前领导，MigrateTo为目标副本），
+// 目标副本日志接近追上时领导停止接受新提案，等目标副本完全追上后再切换领导，
+// 已经提案的日志会同步到新领导，不会丢失，切换期间被拒绝的提案会重新提交给新领导
+func (s *Server) TransferChannelLeader(ctx context.Context, channelId string, channelType uint8, toNodeId uint64) error {
+	if toNodeId == s.opts.NodeId && s.draining.Load() { // 下线中的节点不再接受领导转入
+		return ErrNodeDraining
+	}
+	slotLeaderId, err := s.SlotLeaderIdOfChannel(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	// 频道配置由槽领导统一修改
+	if slotLeaderId != s.opts.NodeId {
+		node := s.nodeManager.node(slotLeaderId)
+		if node == nil {
+			return ErrNodeNotExist
+		}
+		return node.requestChannelLeaderTransfer(ctx, &ChannelLeaderTransferReq{
+			ChannelId:   channelId,
+			ChannelType: channelType,
+			ToNodeId:    toNodeId,
+		})
+	}
+
+	s.channelKeyLock.Lock(channelId)
+	defer s.channelKeyLock.Unlock(channelId)
+
+	clusterConfig, err := s.getChannelClusterConfig(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if clusterConfig.LeaderId == toNodeId { // 已经是领导了
+		return nil
+	}
+	if !wkutil.ArrayContainsUint64(clusterConfig.Replicas, toNodeId) || wkutil.ArrayContainsUint64(clusterConfig.Learners, toNodeId) {
+		return ErrNotChannelReplica
+	}
+	if clusterConfig.MigrateFrom != 0 || clusterConfig.MigrateTo != 0 {
+		return ErrChannelMigrating
+	}
+	if !s.clusterEventServer.NodeOnline(toNodeId) || !s.clusterEventServer.NodeOnline(clusterConfig.LeaderId) {
+		return ErrNodeNotOnline
+	}
+
+	newClusterConfig := clusterConfig.Clone()
+	newClusterConfig.MigrateFrom = clusterConfig.LeaderId
+	newClusterConfig.MigrateTo = toNodeId
+	newClusterConfig.ConfVersion = uint64(time.Now().UnixNano())
+
+	err = s.opts.ChannelClusterStorage.Propose(ctx, newClusterConfig)
+	if err != nil {
+		return err
+	}
+
+	s.Info("transfer channel leader", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("from", clusterConfig.LeaderId), zap.Uint64("to", toNodeId))
+
+	// 通知频道领导开始转移（就算通知失败，频道领导也会间隔比对自己与槽领导的配置）
+	if newClusterConfig.LeaderId != s.opts.NodeId {
+		err = s.SendChannelClusterConfigUpdate(channelId, channelType, newClusterConfig.LeaderId)
+		if err != nil {
+			s.Warn("TransferChannelLeader: sendChannelClusterConfigUpdate failed", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		}
+	} else {
+		s.updateChannelClusterConfigNoLock(newClusterConfig)
+	}
+	return nil
+}
+
+// updateChannelClusterConfigNoLock 调用者已持有频道锁时使用
+func (s *Server) updateChannelClusterConfigNoLock(cfg wkdb.ChannelClusterConfig) {
+	handler := s.channelManager.get(cfg.ChannelId, cfg.ChannelType)
+	if handler == nil {
+		return
+	}
+	ch := handler.(*channel)
+	if err := ch.switchConfig(cfg); err != nil {
+		s.Error("updateChannelClusterConfigNoLock: switchConfig failed", zap.Error(err))
+	}
+}
+
+// isLeaderChangingErr 提案是否因为频道领导变更而被拒绝（这些错误都发生在日志追加之前，可以安全的重新提案）
+func isLeaderChangingErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch err.Error() {
+	case replica.ErrProposalDropped.Error(),
+		reactor.ErrPausePropopose.Error(),
+		reactor.ErrNotLeader.Error(),
+		ErrOldChannelClusterConfig.Error():
+		return true
+	}
+	return false
+}
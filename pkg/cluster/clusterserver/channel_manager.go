@@ -80,7 +80,22 @@ func (c *channelManager) getWithHandleKey(handleKey string) reactor.IHandler {
 }
 
 func (c *channelManager) proposeAndWait(ctx context.Context, channelId string, channelType uint8, logs []replica.Log) ([]reactor.ProposeResult, error) {
-	return c.channelReactor.ProposeAndWait(ctx, wkutil.ChannelToKey(channelId, channelType), logs)
+	key := wkutil.ChannelToKey(channelId, channelType)
+	results, err := c.channelReactor.ProposeAndWait(ctx, key, logs)
+	if err != nil {
+		return nil, err
+	}
+	if h := c.channelReactor.Handler(key); h != nil {
+		h.(*channel).proposeCount.Add(uint64(len(logs)))
+	}
+	return results, nil
+}
+
+// iterator 遍历本节点的所有频道
+func (c *channelManager) iterator(f func(ch *channel) bool) {
+	c.channelReactor.IteratorHandler(func(h reactor.IHandler) bool {
+		return f(h.(*channel))
+	})
 }
 
 func (c *channelManager) addMessage(m reactor.Message) {
@@ -0,0 +1,78 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+// ChannelReadIndexUnavailable 频道领导无法提供读下标（比如领导已经变更），这时只能由频道领导提供读
+const ChannelReadIndexUnavailable uint64 = math.MaxUint64
+
+// ChannelClusterConfigForRead 获取频道的分布式配置（不激活频道）
+func (s *Server) ChannelClusterConfigForRead(channelId string, channelType uint8) (wkdb.ChannelClusterConfig, error) {
+	return s.loadOnlyChannelClusterConfig(channelId, channelType)
+}
+
+// LocalChannelCommittedIndex 本节点作为频道副本已提交的日志下标
+// 本节点没有加载频道、不是频道的正式副本（学习者）或者频道领导不在线时返回false
+func (s *Server) LocalChannelCommittedIndex(channelId string, channelType uint8) (uint64, bool) {
+	handler := s.channelManager.get(channelId, channelType)
+	if handler == nil {
+		return 0, false
+	}
+	ch := handler.(*channel)
+	ch.mu.Lock()
+	cfg := ch.cfg
+	ch.mu.Unlock()
+
+	if !wkutil.ArrayContainsUint64(cfg.Replicas, s.opts.NodeId) || wkutil.ArrayContainsUint64(cfg.Learners, s.opts.NodeId) {
+		return 0, false
+	}
+	// 领导不在线时，本节点的配置和数据可能已经过期
+	if cfg.LeaderId == 0 || !s.clusterEventServer.NodeOnline(cfg.LeaderId) {
+		return 0, false
+	}
+	return ch.committedIndex.Load(), true
+}
+
+// ChannelReadIndexes 向频道领导获取频道的读下标（领导最新的日志下标）
+// 副本已提交的日志下标达到读下标后，副本上的数据不会比领导上的旧
+func (s *Server) ChannelReadIndexes(ctx context.Context, leaderId uint64, channels []wkdb.Channel) ([]uint64, error) {
+	if leaderId == s.opts.NodeId {
+		return s.localChannelReadIndexes(channels)
+	}
+	node := s.nodeManager.node(leaderId)
+	if node == nil {
+		return nil, ErrNodeNotExist
+	}
+	resp, err := node.requestChannelReadIndex(ctx, &ChannelReadIndexReq{Channels: channels})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Indexes) != len(channels) {
+		return nil, fmt.Errorf("read index count[%d] not match channel count[%d]", len(resp.Indexes), len(channels))
+	}
+	return resp.Indexes, nil
+}
+
+// localChannelReadIndexes 本节点作为频道领导提供读下标，本节点不是频道领导（或频道未加载）时返回ChannelReadIndexUnavailable
+func (s *Server) localChannelReadIndexes(channels []wkdb.Channel) ([]uint64, error) {
+	indexes := make([]uint64, len(channels))
+	for i, ch := range channels {
+		handler := s.channelManager.get(ch.ChannelId, ch.ChannelType)
+		if handler == nil || !handler.(*channel).isLeader() {
+			indexes[i] = ChannelReadIndexUnavailable
+			continue
+		}
+		lastIndex, err := s.opts.MessageLogStorage.LastIndex(wkutil.ChannelToKey(ch.ChannelId, ch.ChannelType))
+		if err != nil {
+			return nil, err
+		}
+		indexes[i] = lastIndex
+	}
+	return indexes, nil
+}
@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelReadIndexMarshal(t *testing.T) {
+	req := &ChannelReadIndexReq{
+		Channels: []wkdb.Channel{
+			{ChannelId: "g1", ChannelType: 2},
+			{ChannelId: "u1@u2", ChannelType: 1},
+		},
+	}
+	data, err := req.Marshal()
+	assert.NoError(t, err)
+	req2 := &ChannelReadIndexReq{}
+	err = req2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, req, req2)
+
+	resp := &ChannelReadIndexResp{
+		Indexes: []uint64{100, ChannelReadIndexUnavailable},
+	}
+	data, err = resp.Marshal()
+	assert.NoError(t, err)
+	resp2 := &ChannelReadIndexResp{}
+	err = resp2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, resp, resp2)
+}
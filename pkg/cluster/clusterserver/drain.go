@@ -0,0 +1,188 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// defaultDrainingMarkTTL 没有设置超时时下线标记的有效时间
+const defaultDrainingMarkTTL = time.Minute * 10
+
+// DrainState 本节点仍然担任领导的槽和频道数量
+type DrainState struct {
+	SlotLeaders    int `json:"slot_leaders"`    // 仍是领导的槽数量
+	ChannelLeaders int `json:"channel_leaders"` // 仍是领导的频道数量
+}
+
+// Done 是否已经没有任何领导
+func (d DrainState) Done() bool {
+	return d.SlotLeaders == 0 && d.ChannelLeaders == 0
+}
+
+// Draining 本节点是否正在下线
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// NodeDraining 指定节点是否正在下线
+func (s *Server) NodeDraining(nodeId uint64) bool {
+	return s.clusterEventServer.NodeDraining(nodeId)
+}
+
+// DrainLeaders 将本节点的槽领导和频道领导主动转移给其他在线副本（节点下线前调用，避免等待选举超时）
+// 下线期间本节点不再接受频道领导的转入，也不参与频道领导均衡。
+// 函数会一直重试转移直到本节点不再是任何槽和频道的领导，ctx结束时返回剩余的数量和ctx的错误
+func (s *Server) DrainLeaders(ctx context.Context) (DrainState, error) {
+	s.draining.Store(true)
+
+	if !s.hasOtherOnlineReplicaNode() { // 单节点或其他节点都不在线，没有可以转移的目标
+		return DrainState{}, ErrNoAllowVoteNode
+	}
+
+	// 通知所有节点本节点正在下线，避免槽领导被自动均衡转回本节点
+	until := time.Now().Add(defaultDrainingMarkTTL)
+	if deadline, ok := ctx.Deadline(); ok {
+		until = deadline.Add(time.Minute)
+	}
+	s.broadcastNodeDraining(ctx, until)
+
+	tk := time.NewTicker(time.Second)
+	defer tk.Stop()
+	for {
+		state := s.handoffLeaders(ctx)
+		if state.Done() {
+			s.Info("drain leaders finished")
+			return state, nil
+		}
+		select {
+		case <-tk.C:
+		case <-ctx.Done():
+			s.Warn("drain leaders timeout", zap.Int("slotLeaders", state.SlotLeaders), zap.Int("channelLeaders", state.ChannelLeaders))
+			return state, ctx.Err()
+		case <-s.stopper.ShouldStop():
+			return state, ErrStopped
+		}
+	}
+}
+
+// handoffLeaders 发起一轮领导转移，返回本节点当前仍是领导的数量（包含转移中的）
+func (s *Server) handoffLeaders(ctx context.Context) DrainState {
+	var state DrainState
+
+	// 每个节点已分配到的领导数量，优先转移给领导少的节点
+	leaderCount := make(map[uint64]int)
+	slots := s.clusterEventServer.Slots()
+	for _, slot := range slots {
+		leaderCount[slot.Leader]++
+	}
+
+	for _, slot := range slots {
+		if slot.Leader != s.opts.NodeId {
+			continue
+		}
+		state.SlotLeaders++
+		if slot.MigrateFrom != 0 || slot.MigrateTo != 0 { // 迁移中
+			continue
+		}
+		to := s.pickDrainTarget(slot.Replicas, slot.Learners, leaderCount)
+		if to == 0 {
+			s.Warn("no online replica to transfer slot leader", zap.Uint32("slotId", slot.Id), zap.Uint64s("replicas", slot.Replicas))
+			continue
+		}
+		if err := s.MigrateSlot(slot.Id, s.opts.NodeId, to); err != nil {
+			s.Warn("transfer slot leader failed", zap.Error(err), zap.Uint32("slotId", slot.Id), zap.Uint64("to", to))
+			continue
+		}
+		leaderCount[to]++
+		s.Info("transfer slot leader", zap.Uint32("slotId", slot.Id), zap.Uint64("to", to))
+	}
+
+	channels := make([]*channel, 0)
+	s.channelManager.iterator(func(ch *channel) bool {
+		if ch.isLeader() {
+			channels = append(channels, ch)
+		}
+		return true
+	})
+	state.ChannelLeaders = len(channels)
+	for _, ch := range channels {
+		if ctx.Err() != nil {
+			break
+		}
+		ch.mu.Lock()
+		cfg := ch.cfg
+		ch.mu.Unlock()
+		if cfg.MigrateFrom != 0 || cfg.MigrateTo != 0 { // 迁移中
+			continue
+		}
+		to := s.pickDrainTarget(cfg.Replicas, cfg.Learners, leaderCount)
+		if to == 0 {
+			continue
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, s.opts.ReqTimeout)
+		err := s.TransferChannelLeader(timeoutCtx, cfg.ChannelId, cfg.ChannelType, to)
+		cancel()
+		if err != nil {
+			s.Warn("transfer channel leader failed", zap.Error(err), zap.String("channelId", cfg.ChannelId), zap.Uint8("channelType", cfg.ChannelType), zap.Uint64("to", to))
+			continue
+		}
+		leaderCount[to]++
+	}
+	return state
+}
+
+// broadcastNodeDraining 通知所有在线节点本节点正在下线（通知失败的节点只是可能会把槽领导转回来，下一轮会再转走）
+func (s *Server) broadcastNodeDraining(ctx context.Context, until time.Time) {
+	s.clusterEventServer.SetNodeDraining(s.opts.NodeId, until)
+
+	req := &NodeDrainingReq{
+		NodeId: s.opts.NodeId,
+		Until:  until.UnixMilli(),
+	}
+	for _, n := range s.clusterEventServer.Nodes() {
+		if n.Id == s.opts.NodeId || !n.Online {
+			continue
+		}
+		node := s.nodeManager.node(n.Id)
+		if node == nil {
+			continue
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, s.opts.ReqTimeout)
+		err := node.requestNodeDraining(timeoutCtx, req)
+		cancel()
+		if err != nil {
+			s.Warn("request node draining failed", zap.Error(err), zap.Uint64("nodeId", n.Id))
+		}
+	}
+}
+
+func (s *Server) hasOtherOnlineReplicaNode() bool {
+	for _, node := range s.clusterEventServer.AllowVoteAndJoinedOnlineNodes() {
+		if node.Id != s.opts.NodeId {
+			return true
+		}
+	}
+	return false
+}
+
+// pickDrainTarget 从副本中选出领导最少的在线节点（不包含本节点、学习者和其他下线中的节点）
+func (s *Server) pickDrainTarget(replicas []uint64, learners []uint64, leaderCount map[uint64]int) uint64 {
+	var target uint64
+	for _, replicaId := range replicas {
+		if replicaId == s.opts.NodeId || wkutil.ArrayContainsUint64(learners, replicaId) || s.clusterEventServer.NodeDraining(replicaId) {
+			continue
+		}
+		node := s.clusterEventServer.Node(replicaId)
+		if node == nil || !node.Online || node.Role != pb.NodeRole_NodeRoleReplica {
+			continue
+		}
+		if target == 0 || leaderCount[replicaId] < leaderCount[target] {
+			target = replicaId
+		}
+	}
+	return target
+}
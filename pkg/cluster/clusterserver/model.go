@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -47,6 +48,10 @@ var (
 	ErrSlotLeaderNotFound           = errors.New("slot leader not found")
 	ErrEmptyRequest                 = errors.New("empty request")
 	ErrChannelClusterConfigNotFound = errors.New("channel cluster config not found")
+	ErrChannelMigrating             = errors.New("channel is migrating")
+	ErrNotChannelReplica            = errors.New("node is not channel replica")
+	ErrNodeNotOnline                = errors.New("node is not online")
+	ErrNodeDraining                 = errors.New("node is draining")
 )
 
 const (
@@ -347,6 +352,162 @@ func (c *ChannelClusterConfigReq) Unmarshal(data []byte) error {
 	return nil
 }
 
+// ChannelLeaderTransferReq 频道领导转移请求
+type ChannelLeaderTransferReq struct {
+	ChannelId   string `json:"channel_id"`   // 频道id
+	ChannelType uint8  `json:"channel_type"` // 频道类型
+	ToNodeId    uint64 `json:"to_node_id"`   // 新领导节点id
+}
+
+func (c *ChannelLeaderTransferReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteString(c.ChannelId)
+	enc.WriteUint8(c.ChannelType)
+	enc.WriteUint64(c.ToNodeId)
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelLeaderTransferReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.ChannelId, err = dec.String(); err != nil {
+		return err
+	}
+	if c.ChannelType, err = dec.Uint8(); err != nil {
+		return err
+	}
+	if c.ToNodeId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NodeDrainingReq 通知其他节点本节点正在下线
+type NodeDrainingReq struct {
+	NodeId uint64 // 下线的节点id
+	Until  int64  // 下线标记的过期时间（unix毫秒）
+}
+
+func (n *NodeDrainingReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(n.NodeId)
+	enc.WriteInt64(n.Until)
+	return enc.Bytes(), nil
+}
+
+func (n *NodeDrainingReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if n.NodeId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if n.Until, err = dec.Int64(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ChannelLeaderLoad 节点上频道领导的负载
+type ChannelLeaderLoad struct {
+	NodeId      uint64  // 节点id
+	LeaderCount uint32  // 作为领导的频道数量
+	MsgRate     float64 // 作为领导的频道的消息速率总和（条/秒）
+}
+
+func (c *ChannelLeaderLoad) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint64(c.NodeId)
+	enc.WriteUint32(c.LeaderCount)
+	enc.WriteUint64(math.Float64bits(c.MsgRate))
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelLeaderLoad) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	if c.NodeId, err = dec.Uint64(); err != nil {
+		return err
+	}
+	if c.LeaderCount, err = dec.Uint32(); err != nil {
+		return err
+	}
+	var rate uint64
+	if rate, err = dec.Uint64(); err != nil {
+		return err
+	}
+	c.MsgRate = math.Float64frombits(rate)
+	return nil
+}
+
+// ChannelReadIndexReq 获取频道读下标的请求
+type ChannelReadIndexReq struct {
+	Channels []wkdb.Channel
+}
+
+func (c *ChannelReadIndexReq) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint16(uint16(len(c.Channels)))
+	for _, ch := range c.Channels {
+		enc.WriteString(ch.ChannelId)
+		enc.WriteUint8(ch.ChannelType)
+	}
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelReadIndexReq) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	var count uint16
+	if count, err = dec.Uint16(); err != nil {
+		return err
+	}
+	c.Channels = make([]wkdb.Channel, count)
+	for i := uint16(0); i < count; i++ {
+		if c.Channels[i].ChannelId, err = dec.String(); err != nil {
+			return err
+		}
+		if c.Channels[i].ChannelType, err = dec.Uint8(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChannelReadIndexResp 频道读下标，与请求的频道一一对应
+type ChannelReadIndexResp struct {
+	Indexes []uint64
+}
+
+func (c *ChannelReadIndexResp) Marshal() ([]byte, error) {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint16(uint16(len(c.Indexes)))
+	for _, index := range c.Indexes {
+		enc.WriteUint64(index)
+	}
+	return enc.Bytes(), nil
+}
+
+func (c *ChannelReadIndexResp) Unmarshal(data []byte) error {
+	dec := wkproto.NewDecoder(data)
+	var err error
+	var count uint16
+	if count, err = dec.Uint16(); err != nil {
+		return err
+	}
+	c.Indexes = make([]uint64, count)
+	for i := uint16(0); i < count; i++ {
+		if c.Indexes[i], err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type ChannelProposeReq struct {
 	ChannelId   string        // 频道id
 	ChannelType uint8         // 频道类型
@@ -649,6 +810,8 @@ type ClusterJoinReq struct {
 	NodeId     uint64
 	ServerAddr string
 	Role       pb.NodeRole
+	Zone       string // 节点所在的可用区
+	Rack       string // 节点所在的机架
 }
 
 func (c *ClusterJoinReq) Marshal() ([]byte, error) {
@@ -657,6 +820,8 @@ func (c *ClusterJoinReq) Marshal() ([]byte, error) {
 	enc.WriteUint64(c.NodeId)
 	enc.WriteString(c.ServerAddr)
 	enc.WriteUint32(uint32(c.Role))
+	enc.WriteString(c.Zone)
+	enc.WriteString(c.Rack)
 	return enc.Bytes(), nil
 
 }
@@ -675,6 +840,14 @@ func (c *ClusterJoinReq) Unmarshal(data []byte) error {
 		return err
 	}
 	c.Role = pb.NodeRole(role)
+	if dec.Len() > 0 { // 兼容没有可用区和机架的旧版本
+		if c.Zone, err = dec.String(); err != nil {
+			return err
+		}
+		if c.Rack, err = dec.String(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -927,6 +1100,8 @@ func formatChannelType(channelType uint8) string {
 		channelTypeFormat = "个人"
 	case wkproto.ChannelTypeCommunity:
 		channelTypeFormat = "社区"
+	case wkproto.ChannelTypeCommunityTopic:
+		channelTypeFormat = "社区话题"
 	case wkproto.ChannelTypeCustomerService:
 		channelTypeFormat = "客服"
 	case wkproto.ChannelTypeInfo:
@@ -1091,6 +1266,9 @@ type NodeConfig struct {
 	ConfigVersion   uint64         `json:"config_version,omitempty"`    // 配置版本
 	Status          pb.NodeStatus  `json:"status,omitempty"`            // 状态
 	StatusFormat    string         `json:"status_format,omitempty"`     // 状态格式化
+
+	Zone string `json:"zone,omitempty"` // 可用区
+	Rack string `json:"rack,omitempty"` // 机架
 }
 
 func NewNodeConfigFromNode(n *pb.Node) *NodeConfig {
@@ -1119,6 +1297,8 @@ func NewNodeConfigFromNode(n *pb.Node) *NodeConfig {
 		AllowVote:     wkutil.BoolToInt(n.AllowVote),
 		Status:        n.Status,
 		StatusFormat:  status,
+		Zone:          n.Zone,
+		Rack:          n.Rack,
 	}
 }
 
@@ -1364,6 +1544,8 @@ func newConversationResp(c wkdb.Conversation) *conversationResp {
 	typeFormat := "聊天"
 	if c.Type == wkdb.ConversationTypeCMD {
 		typeFormat = "命令"
+	} else if c.Type == wkdb.ConversationTypeTopic {
+		typeFormat = "话题"
 	}
 	var createdAtFormat string
 	var updatedAtFormat string
@@ -210,6 +210,75 @@ func (n *node) requestChannelProposeMessage(ctx context.Context, req *ChannelPro
 	return proposeMessageResp, nil
 }
 
+func (n *node) requestChannelLeaderTransfer(ctx context.Context, req *ChannelLeaderTransferReq) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.RequestWithContext(ctx, "/channel/transferLeader", data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		if len(resp.Body) > 0 {
+			return errors.New(string(resp.Body))
+		}
+		return fmt.Errorf("requestChannelLeaderTransfer is failed, status:%d", resp.Status)
+	}
+	return nil
+}
+
+func (n *node) requestNodeDraining(ctx context.Context, req *NodeDrainingReq) error {
+	data, err := req.Marshal()
+	if err != nil {
+		return err
+	}
+	resp, err := n.client.RequestWithContext(ctx, "/node/draining", data)
+	if err != nil {
+		return err
+	}
+	if resp.Status != proto.Status_OK {
+		return fmt.Errorf("requestNodeDraining is failed, status:%d", resp.Status)
+	}
+	return nil
+}
+
+func (n *node) requestChannelLeaderLoad(ctx context.Context) (*ChannelLeaderLoad, error) {
+	resp, err := n.client.RequestWithContext(ctx, "/channel/leaderLoad", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestChannelLeaderLoad is failed, status:%d", resp.Status)
+	}
+	load := &ChannelLeaderLoad{}
+	err = load.Unmarshal(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return load, nil
+}
+
+func (n *node) requestChannelReadIndex(ctx context.Context, req *ChannelReadIndexReq) (*ChannelReadIndexResp, error) {
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := n.client.RequestWithContext(ctx, "/channel/readIndex", data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != proto.Status_OK {
+		return nil, fmt.Errorf("requestChannelReadIndex is failed, status:%d", resp.Status)
+	}
+	readIndexResp := &ChannelReadIndexResp{}
+	err = readIndexResp.Unmarshal(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return readIndexResp, nil
+}
+
 func (n *node) requestSlotLogInfo(ctx context.Context, req *SlotLogInfoReq) (*SlotLogInfoResp, error) {
 	data, err := req.Marshal()
 	if err != nil {
@@ -61,8 +61,12 @@ type Options struct {
 	// MessageLogStorage 消息日志存储
 	MessageLogStorage IShardLogStorage
 	OnSlotApply       func(slotId uint32, logs []replica.Log) error
+	// OnChannelApply 频道日志在副本（领导和追随者）上提交后的回调，参数为已提交的日志下标，在副本的应用协程内调用，不能阻塞
+	OnChannelApply func(channelId string, channelType uint8, committedIndex uint64)
 	// Send 发送消息
 	Send func(shardType ShardType, m reactor.Message)
+	// ParentChannel 获取子频道（例如消息的子区）的父频道，子频道沿用父频道的副本和领导，父频道变化后子频道会逐步对齐
+	ParentChannel func(channelId string, channelType uint8) (parentChannelId string, parentChannelType uint8, ok bool)
 	// ChannelElectionPoolSize 频道选举协程池大小(意味着同时在选举的频道数量)
 	ChannelElectionPoolSize int
 	// MaxChannelElectionBatchLen 批量选举，每次最多选举多少个频道（默认100）
@@ -96,6 +100,22 @@ type Options struct {
 	PongMaxTick int // 节点超过多少tick没有回应心跳就认为是掉线
 
 	Auth auth.AuthConfig
+
+	// ChannelLeaderBalanceOn 是否开启频道领导按消息速率自动均衡
+	ChannelLeaderBalanceOn bool
+	// ChannelLeaderBalanceInterval 频道领导均衡的检查间隔
+	ChannelLeaderBalanceInterval time.Duration
+	// ChannelLeaderBalanceThreshold 节点消息速率超过集群平均值多少比例才进行均衡（0.2表示超过平均值20%）
+	ChannelLeaderBalanceThreshold float64
+	// ChannelLeaderBalanceMinRate 节点消息速率超出平均值小于这个值（条/秒）时不均衡，避免低负载时频繁转移
+	ChannelLeaderBalanceMinRate float64
+	// ChannelLeaderBalanceMaxTransfer 每次均衡最多转移的频道领导数量
+	ChannelLeaderBalanceMaxTransfer int
+
+	// Zone 本节点所在的可用区，槽和频道的副本会尽量分布在不同的可用区
+	Zone string
+	// Rack 本节点所在的机架，同一可用区内副本会尽量分布在不同的机架
+	Rack string
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -128,6 +148,12 @@ func NewOptions(opt ...Option) *Options {
 		SlotReactorSubCount:    128,
 		PongMaxTick:            30,
 		SlotDbShardNum:         8,
+
+		ChannelLeaderBalanceOn:          true,
+		ChannelLeaderBalanceInterval:    time.Minute,
+		ChannelLeaderBalanceThreshold:   0.2,
+		ChannelLeaderBalanceMinRate:     10,
+		ChannelLeaderBalanceMaxTransfer: 10,
 	}
 	for _, o := range opt {
 		o(opts)
@@ -166,6 +192,20 @@ func WithOnSlotApply(fn func(slotId uint32, logs []replica.Log) error) Option {
 	}
 }
 
+// WithOnChannelApply 设置频道日志在副本上提交后的回调
+func WithOnChannelApply(fn func(channelId string, channelType uint8, committedIndex uint64)) Option {
+	return func(o *Options) {
+		o.OnChannelApply = fn
+	}
+}
+
+// WithParentChannel 设置获取子频道的父频道的方法
+func WithParentChannel(fn func(channelId string, channelType uint8) (string, uint8, bool)) Option {
+	return func(o *Options) {
+		o.ParentChannel = fn
+	}
+}
+
 func WithLogSyncLimitSizeOfEach(size int) Option {
 	return func(o *Options) {
 		o.LogSyncLimitSizeOfEach = size
@@ -409,3 +449,45 @@ func WithServiceName(serviceName string) Option {
 		o.ServiceName = serviceName
 	}
 }
+
+func WithChannelLeaderBalanceOn(on bool) Option {
+	return func(o *Options) {
+		o.ChannelLeaderBalanceOn = on
+	}
+}
+
+func WithChannelLeaderBalanceInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.ChannelLeaderBalanceInterval = interval
+	}
+}
+
+func WithChannelLeaderBalanceThreshold(threshold float64) Option {
+	return func(o *Options) {
+		o.ChannelLeaderBalanceThreshold = threshold
+	}
+}
+
+func WithChannelLeaderBalanceMinRate(rate float64) Option {
+	return func(o *Options) {
+		o.ChannelLeaderBalanceMinRate = rate
+	}
+}
+
+func WithChannelLeaderBalanceMaxTransfer(max int) Option {
+	return func(o *Options) {
+		o.ChannelLeaderBalanceMaxTransfer = max
+	}
+}
+
+func WithZone(zone string) Option {
+	return func(o *Options) {
+		o.Zone = zone
+	}
+}
+
+func WithRack(rack string) Option {
+	return func(o *Options) {
+		o.Rack = rack
+	}
+}
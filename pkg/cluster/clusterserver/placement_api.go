@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
+	"github.com/WuKongIM/WuKongIM/pkg/network"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkhttp"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// PlacementViolationResp 副本没有按可用区（机架）打散的槽或频道
+type PlacementViolationResp struct {
+	Type        string   `json:"type"`                   // slot: 槽 channel: 频道
+	SlotId      uint32   `json:"slot_id"`                // 槽id（频道所属的槽）
+	ChannelId   string   `json:"channel_id,omitempty"`   // 频道id
+	ChannelType uint8    `json:"channel_type,omitempty"` // 频道类型
+	Replicas    []uint64 `json:"replicas"`               // 副本节点
+	Zones       []string `json:"zones"`                  // 副本节点所在的可用区/机架，与replicas一一对应
+	*pb.PlacementViolation
+}
+
+type PlacementViolationRespTotal struct {
+	Total int                       `json:"total"` // 总数
+	Data  []*PlacementViolationResp `json:"data"`
+}
+
+// placementViolationsGet 获取副本没有按可用区（机架）打散的槽和频道
+// 槽从集群配置中检查，频道由各节点检查自己作为槽领导的频道配置，local=1时只返回本节点检查的频道
+func (s *Server) placementViolationsGet(c *wkhttp.Context) {
+	local := wkutil.ParseInt(c.Query("local")) == 1
+	limit := wkutil.ParseInt(c.Query("limit"))
+	if limit <= 0 {
+		limit = s.opts.PageSize
+	}
+
+	nodes := s.clusterEventServer.AllowVoteAndJoinedNodes()
+
+	channelViolations, err := s.localChannelPlacementViolations(nodes, limit)
+	if err != nil {
+		s.Error("localChannelPlacementViolations error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if local {
+		c.JSON(http.StatusOK, PlacementViolationRespTotal{
+			Total: len(channelViolations),
+			Data:  channelViolations,
+		})
+		return
+	}
+
+	violations := make([]*PlacementViolationResp, 0)
+	for _, slot := range s.clusterEventServer.Slots() {
+		if resp := newPlacementViolationResp(slot.Replicas, nodes); resp != nil {
+			resp.Type = "slot"
+			resp.SlotId = slot.Id
+			violations = append(violations, resp)
+		}
+	}
+	violations = append(violations, channelViolations...)
+
+	var violationLock sync.Mutex
+	requestGroup, _ := errgroup.WithContext(s.cancelCtx)
+	for _, node := range s.clusterEventServer.Nodes() {
+		if node.Id == s.opts.NodeId || !node.Online {
+			continue
+		}
+		apiServerAddr := node.ApiServerAddr
+		requestGroup.Go(func() error {
+			resp, err := network.Get(fmt.Sprintf("%s%s?local=1&limit=%d", apiServerAddr, s.formatPath("/placement/violations"), limit), nil, c.CopyRequestHeader(c.Request))
+			if err != nil {
+				return err
+			}
+			total := &PlacementViolationRespTotal{}
+			if err = wkutil.ReadJSONByByte([]byte(resp.Body), total); err != nil {
+				return err
+			}
+			violationLock.Lock()
+			violations = append(violations, total.Data...)
+			violationLock.Unlock()
+			return nil
+		})
+	}
+	if err = requestGroup.Wait(); err != nil {
+		s.Error("request placement violations error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	c.JSON(http.StatusOK, PlacementViolationRespTotal{
+		Total: len(violations),
+		Data:  violations,
+	})
+}
+
+// localChannelPlacementViolations 检查本节点作为槽领导的频道配置
+func (s *Server) localChannelPlacementViolations(nodes []*pb.Node, limit int) ([]*PlacementViolationResp, error) {
+	violations := make([]*PlacementViolationResp, 0)
+	_, err := s.opts.DB.SearchChannelClusterConfig(wkdb.ChannelClusterConfigSearchReq{
+		Limit: limit,
+	}, func(cfg wkdb.ChannelClusterConfig) bool {
+		slot := s.clusterEventServer.Slot(s.getSlotId(cfg.ChannelId))
+		if slot == nil || slot.Leader != s.opts.NodeId {
+			return false
+		}
+		resp := newPlacementViolationResp(cfg.Replicas, nodes)
+		if resp == nil {
+			return false
+		}
+		resp.Type = "channel"
+		resp.SlotId = slot.Id
+		resp.ChannelId = cfg.ChannelId
+		resp.ChannelType = cfg.ChannelType
+		violations = append(violations, resp)
+		return true
+	})
+	return violations, err
+}
+
+func newPlacementViolationResp(replicas []uint64, nodes []*pb.Node) *PlacementViolationResp {
+	replicaNodes := make([]*pb.Node, 0, len(replicas))
+	zones := make([]string, 0, len(replicas))
+	for _, replicaId := range replicas {
+		for _, node := range nodes {
+			if node.Id == replicaId {
+				replicaNodes = append(replicaNodes, node)
+				zones = append(zones, node.Zone+"/"+node.Rack)
+				break
+			}
+		}
+	}
+	violation := pb.CheckPlacement(replicaNodes, nodes)
+	if violation == nil {
+		return nil
+	}
+	return &PlacementViolationResp{
+		Replicas:           replicas,
+		Zones:              zones,
+		PlacementViolation: violation,
+	}
+}
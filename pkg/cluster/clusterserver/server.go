@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/WuKongIM/WuKongIM/pkg/auth"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterevent"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/icluster"
@@ -42,6 +43,7 @@ type Server struct {
 	netServer              *wkserver.Server        // 节点之间通讯的网络服务
 	channelElectionPool    *ants.Pool              // 频道选举的协程池
 	channelElectionManager *channelElectionManager // 频道选举管理者
+	channelLeaderBalancer  *channelLeaderBalancer  // 频道领导均衡
 	channelLoadPool        *ants.Pool              // 加载频道的协程池
 	channelLoadMap         map[string]struct{}     // 频道是否在加载中的map
 	channelLoadMapLock     sync.RWMutex            // 频道是否在加载中的map锁
@@ -54,9 +56,12 @@ type Server struct {
 	uptime                 time.Time // 服务器启动时间
 	wklog.Log
 
-	stopped atomic.Bool
+	stopped  atomic.Bool
+	draining atomic.Bool // 是否正在下线（领导转移中）
 
 	stopper *syncutil.Stopper
+
+	authLock sync.RWMutex // opts.Auth会在重新加载配置时被修改
 }
 
 func New(opts *Options) *Server {
@@ -116,6 +121,8 @@ func New(opts *Options) *Server {
 		clusterevent.WithSend(s.onSend),
 		clusterevent.WithConfigDir(cfgDir),
 		clusterevent.WithApiServerAddr(opts.ApiServerAddr),
+		clusterevent.WithZone(opts.Zone),
+		clusterevent.WithRack(opts.Rack),
 		clusterevent.WithCluster(s),
 		clusterevent.WithElectionIntervalTick(opts.ElectionIntervalTick),
 		clusterevent.WithHeartbeatIntervalTick(opts.HeartbeatIntervalTick),
@@ -148,6 +155,7 @@ func New(opts *Options) *Server {
 		}),
 	)
 	s.channelElectionManager = newChannelElectionManager(s)
+	s.channelLeaderBalancer = newChannelLeaderBalancer(s)
 	s.cancelCtx, s.cancelFnc = context.WithCancel(context.Background())
 	return s
 }
@@ -218,6 +226,11 @@ func (s *Server) Start() error {
 	if err != nil {
 		return err
 	}
+	// channel leader balancer
+	err = s.channelLeaderBalancer.start()
+	if err != nil {
+		return err
+	}
 
 	// 如果有新加入的节点 则执行加入逻辑
 	if s.needJoin() { // 需要加入集群
@@ -233,6 +246,7 @@ func (s *Server) Stop() {
 	s.stopped.Store(true)
 	s.cancelFnc()
 	s.stopper.Stop()
+	s.channelLeaderBalancer.stop()
 	s.nodeManager.stop()
 	s.channelElectionManager.stop()
 	s.netServer.Stop()
@@ -244,6 +258,17 @@ func (s *Server) Stop() {
 
 }
 
+// Checkpoint 在指定目录生成槽日志和集群配置的一致性快照，目录结构与分布式数据目录一致
+func (s *Server) Checkpoint(dir string) error {
+	if s.slotStorage == nil {
+		return errors.New("slot log storage is not pebble storage, checkpoint not supported")
+	}
+	if err := s.slotStorage.Checkpoint(path.Join(dir, "logdb")); err != nil {
+		return err
+	}
+	return s.clusterEventServer.Checkpoint(path.Join(dir, "config"))
+}
+
 // 提案频道分布式配置
 func (s *Server) ProposeChannelClusterConfig(ctx context.Context, cfg wkdb.ChannelClusterConfig) error {
 	return s.opts.ChannelClusterStorage.Propose(ctx, cfg)
@@ -254,6 +279,20 @@ func (s *Server) GetConfig() *pb.Config {
 	return s.clusterEventServer.Config()
 }
 
+// SetAuth 更新管理接口的认证配置（节点重新加载配置后调用）
+func (s *Server) SetAuth(cfg auth.AuthConfig) {
+	s.authLock.Lock()
+	defer s.authLock.Unlock()
+	s.opts.Auth = cfg
+}
+
+// auth 管理接口的认证配置
+func (s *Server) auth() auth.AuthConfig {
+	s.authLock.RLock()
+	defer s.authLock.RUnlock()
+	return s.opts.Auth
+}
+
 // 迁移槽
 func (s *Server) MigrateSlot(slotId uint32, fromNodeId, toNodeId uint64) error {
 
@@ -513,6 +552,8 @@ func (s *Server) joinLoop() {
 		NodeId:     s.opts.NodeId,
 		ServerAddr: s.opts.ServerAddr,
 		Role:       s.opts.Role,
+		Zone:       s.opts.Zone,
+		Rack:       s.opts.Rack,
 	}
 	for {
 		select {
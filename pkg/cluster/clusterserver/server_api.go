@@ -55,10 +55,14 @@ func (s *Server) ServerAPI(route *wkhttp.WKHttp, prefix string) {
 	route.GET(s.formatPath("/channels/:channel_id/:channel_type/replicas"), s.channelReplicas)         // 获取频道副本信息
 	route.GET(s.formatPath("/channels/:channel_id/:channel_type/localReplica"), s.channelLocalReplica) // 获取频道在本节点的副本信息
 
+	route.POST(s.formatPath("/channels/:channel_id/:channel_type/transferLeader"), s.channelTransferLeader) // 转移频道领导
+
 	route.GET(s.formatPath("/logs"), s.clusterLogs) // 获取节点日志
 
 	route.GET(s.formatPath("/message/trace"), s.messageTrace) // 获取消息轨迹
 
+	route.GET(s.formatPath("/placement/violations"), s.placementViolationsGet) // 获取副本没有按可用区（机架）打散的槽和频道
+
 }
 
 func (s *Server) nodesGet(c *wkhttp.Context) {
@@ -493,7 +497,7 @@ func (s *Server) slotMigrate(c *wkhttp.Context) {
 		MigrateTo   uint64 `json:"migrate_to"`   // 迁移的目标节点
 	}
 
-	if !s.opts.Auth.HasPermissionWithContext(c, resource.Slot.Migrate, auth.ActionWrite) {
+	if !s.auth().HasPermissionWithContext(c, resource.Slot.Migrate, auth.ActionWrite) {
 		c.ResponseStatus(http.StatusUnauthorized)
 		return
 	}
@@ -1827,6 +1831,40 @@ func (s *Server) channelMigrate(c *wkhttp.Context) {
 
 }
 
+func (s *Server) channelTransferLeader(c *wkhttp.Context) {
+
+	if !s.auth().HasPermissionWithContext(c, resource.ClusterChannel.TransferLeader, auth.ActionWrite) {
+		c.ResponseStatus(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ToNodeId uint64 `json:"to_node_id"` // 新领导节点id
+	}
+	if err := c.BindJSON(&req); err != nil {
+		s.Error("BindJSON error", zap.Error(err))
+		c.ResponseError(err)
+		return
+	}
+	if req.ToNodeId == 0 {
+		c.ResponseError(errors.New("to_node_id is empty"))
+		return
+	}
+
+	channelId := c.Param("channel_id")
+	channelType := wkutil.ParseUint8(c.Param("channel_type"))
+
+	timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ReqTimeout)
+	defer cancel()
+	err := s.TransferChannelLeader(timeoutCtx, channelId, channelType, req.ToNodeId)
+	if err != nil {
+		s.Error("channelTransferLeader: TransferChannelLeader error", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Uint64("toNodeId", req.ToNodeId))
+		c.ResponseError(err)
+		return
+	}
+	c.ResponseOK()
+}
+
 func (s *Server) channelClusterConfig(c *wkhttp.Context) {
 
 	start := time.Now()
@@ -1873,7 +1911,7 @@ func (s *Server) channelClusterConfig(c *wkhttp.Context) {
 
 func (s *Server) channelStart(c *wkhttp.Context) {
 
-	if !s.opts.Auth.HasPermissionWithContext(c, resource.ClusterChannel.Start, auth.ActionWrite) {
+	if !s.auth().HasPermissionWithContext(c, resource.ClusterChannel.Start, auth.ActionWrite) {
 		c.ResponseStatus(http.StatusUnauthorized)
 		return
 	}
@@ -1912,7 +1950,7 @@ func (s *Server) channelStart(c *wkhttp.Context) {
 
 func (s *Server) channelStop(c *wkhttp.Context) {
 
-	if !s.opts.Auth.HasPermissionWithContext(c, resource.ClusterChannel.Stop, auth.ActionWrite) {
+	if !s.auth().HasPermissionWithContext(c, resource.ClusterChannel.Stop, auth.ActionWrite) {
 		c.ResponseStatus(http.StatusUnauthorized)
 		return
 	}
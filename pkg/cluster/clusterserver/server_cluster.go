@@ -143,6 +143,26 @@ func (s *Server) ProposeChannelMessages(ctx context.Context, channelId string, c
 		return nil, ErrStopped
 	}
 
+	start := time.Now()
+	for {
+		results, err := s.proposeChannelMessages(ctx, channelId, channelType, logs)
+		// 频道领导转移中，等待新领导生效后重新提案
+		if !isLeaderChangingErr(err) || time.Since(start) >= s.opts.ProposeTimeout {
+			return results, err
+		}
+		s.Debug("channel leader is changing, retry propose", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType))
+		select {
+		case <-time.After(s.opts.TickInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.stopper.ShouldStop():
+			return nil, ErrStopped
+		}
+	}
+}
+
+func (s *Server) proposeChannelMessages(ctx context.Context, channelId string, channelType uint8, logs []replica.Log) ([]icluster.ProposeResult, error) {
+
 	// 加载或创建频道
 	ch, err := s.loadOrCreateChannel(ctx, channelId, channelType)
 	if err != nil {
@@ -372,11 +392,20 @@ func (s *Server) loadOrCreateChannelClusterConfigNoLock(ctx context.Context, cha
 		return wkdb.EmptyChannelClusterConfig, needProposeCfg, ErrEmptyChannelClusterConfig
 	}
 
+	// ================== 子频道的副本和领导与父频道对齐 ==================
+	parentChannelId, parentChannelType, isChild := s.parentChannel(channelId, channelType)
+	if isChild {
+		if newCfg, ok := s.syncChildChannelClusterConfigIfNeed(clusterCfg, parentChannelId, parentChannelType); ok {
+			clusterCfg = newCfg
+			needProposeCfg = true
+		}
+	}
+
 	// ================== 检查配置是否有新节点加入 ==================
-	// 如果当前节点是频道的领导者，但是副本数量小于设置的最大副本数量，则需要变更
+	// 如果当前节点是频道的领导者，但是副本数量小于设置的最大副本数量，则需要变更（子频道的副本跟随父频道，不单独加入新节点）
 	allowVoteAndJoinedNodeCount := s.clusterEventServer.AllowVoteAndJoinedNodeCount() // 允许投票的节点数量
 	currentReplicaCount := len(clusterCfg.Replicas)                                   // 当前副本数量
-	if len(clusterCfg.Learners) == 0 && currentReplicaCount < int(clusterCfg.ReplicaMaxCount) && allowVoteAndJoinedNodeCount > currentReplicaCount {
+	if !isChild && len(clusterCfg.Learners) == 0 && currentReplicaCount < int(clusterCfg.ReplicaMaxCount) && allowVoteAndJoinedNodeCount > currentReplicaCount {
 
 		s.Info("loadOrCreateChannelClusterConfig: need add new node to replicas", zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("currentReplicaCount", currentReplicaCount), zap.Uint64s("replicas", clusterCfg.Replicas), zap.Uint16("replicaMaxCount", clusterCfg.ReplicaMaxCount), zap.Int("allowVoteAndJoinedNodeCount", allowVoteAndJoinedNodeCount))
 
@@ -491,6 +520,15 @@ func (s *Server) loadOrCreateChannel(ctx context.Context, channelId string, chan
 
 // 创建一个频道的分布式配置
 func (s *Server) createChannelClusterConfig(channelId string, channelType uint8) (wkdb.ChannelClusterConfig, error) {
+	// 子频道沿用父频道的副本
+	if parentChannelId, parentChannelType, ok := s.parentChannel(channelId, channelType); ok {
+		clusterConfig, err := s.createChildChannelClusterConfig(channelId, channelType, parentChannelId, parentChannelType)
+		if err == nil {
+			return clusterConfig, nil
+		}
+		s.Warn("createChildChannelClusterConfig failed, create new config", zap.Error(err), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.String("parentChannelId", parentChannelId))
+	}
+
 	allowVoteNodes := s.clusterEventServer.AllowVoteAndJoinedNodes() // 获取允许投票的在线节点
 	if len(allowVoteNodes) == 0 {
 		return wkdb.EmptyChannelClusterConfig, ErrNoAllowVoteNode
@@ -517,20 +555,55 @@ func (s *Server) createChannelClusterConfig(channelId string, channelType uint8)
 		newAllowVoteNodes[i], newAllowVoteNodes[j] = newAllowVoteNodes[j], newAllowVoteNodes[i]
 	})
 
+	// 按可用区和机架打散选择其他副本
+	var (
+		localNode  *pb.Node
+		candidates = make([]*pb.Node, 0, len(newAllowVoteNodes))
+	)
 	for _, allowVoteNode := range newAllowVoteNodes {
 		if allowVoteNode.Id == s.opts.NodeId {
+			localNode = allowVoteNode
 			continue
 		}
-		if len(replicaIds) >= int(s.opts.ChannelMaxReplicaCount) {
-			break
-		}
-		replicaIds = append(replicaIds, allowVoteNode.Id)
-
+		candidates = append(candidates, allowVoteNode)
+	}
+	var selected []*pb.Node
+	if localNode != nil {
+		selected = append(selected, localNode)
+	}
+	for _, node := range pb.SpreadNodes(selected, candidates, int(s.opts.ChannelMaxReplicaCount)-len(replicaIds)) {
+		replicaIds = append(replicaIds, node.Id)
 	}
 	clusterConfig.Replicas = replicaIds
 	return clusterConfig, nil
 }
 
+// createChildChannelClusterConfig 创建子频道的分布式配置，副本和领导与父频道相同
+func (s *Server) createChildChannelClusterConfig(channelId string, channelType uint8, parentChannelId string, parentChannelType uint8) (wkdb.ChannelClusterConfig, error) {
+	timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ReqTimeout)
+	defer cancel()
+	parentCfg, _, err := s.loadOrCreateChannelClusterConfig(timeoutCtx, parentChannelId, parentChannelType)
+	if err != nil {
+		return wkdb.EmptyChannelClusterConfig, err
+	}
+	if parentCfg.LeaderId == 0 || len(parentCfg.Replicas) == 0 {
+		return wkdb.EmptyChannelClusterConfig, ErrEmptyChannelClusterConfig
+	}
+
+	createdAt := time.Now()
+	updatedAt := time.Now()
+	return wkdb.ChannelClusterConfig{
+		ChannelId:       channelId,
+		ChannelType:     channelType,
+		ReplicaMaxCount: parentCfg.ReplicaMaxCount,
+		Replicas:        append([]uint64(nil), parentCfg.Replicas...),
+		Term:            1,
+		LeaderId:        parentCfg.LeaderId,
+		CreatedAt:       &createdAt,
+		UpdatedAt:       &updatedAt,
+	}, nil
+}
+
 // func (s *Server) updateClusterConfigIfNeed(clusterCfg wkdb.ChannelClusterConfig) (wkdb.ChannelClusterConfig, bool, error) {
 
 // 	// 允许投票节点数量
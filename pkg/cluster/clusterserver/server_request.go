@@ -34,6 +34,15 @@ func (s *Server) setRoutes() {
 
 	// 获取槽日志信息
 	s.netServer.Route("/slot/logInfo", s.handleSlotLogInfo)
+
+	// 转移频道领导
+	s.netServer.Route("/channel/transferLeader", s.handleChannelLeaderTransfer)
+	// 获取节点频道领导的负载
+	s.netServer.Route("/channel/leaderLoad", s.handleChannelLeaderLoad)
+	// 获取频道的读下标（副本读）
+	s.netServer.Route("/channel/readIndex", s.handleChannelReadIndex)
+	// 节点下线通知
+	s.netServer.Route("/node/draining", s.handleNodeDraining)
 }
 
 func (s *Server) handleChannelLastLogInfo(c *wkserver.Context) {
@@ -293,6 +302,8 @@ func (s *Server) handleClusterJoin(c *wkserver.Context) {
 		AllowVote:   allowVote,
 		CreatedAt:   time.Now().Unix(),
 		Status:      pb.NodeStatus_NodeStatusWillJoin,
+		Zone:        req.Zone,
+		Rack:        req.Rack,
 	})
 	if err != nil {
 		s.Error("proposeJoin failed", zap.Error(err))
@@ -416,3 +427,71 @@ func (s *Server) handleSlotLogInfo(c *wkserver.Context) {
 	}
 	c.Write(data)
 }
+
+func (s *Server) handleChannelLeaderTransfer(c *wkserver.Context) {
+	req := &ChannelLeaderTransferReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("unmarshal ChannelLeaderTransferReq failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	timeoutCtx, cancel := context.WithTimeout(s.cancelCtx, s.opts.ReqTimeout)
+	defer cancel()
+	err := s.TransferChannelLeader(timeoutCtx, req.ChannelId, req.ChannelType, req.ToNodeId)
+	if err != nil {
+		s.Error("handleChannelLeaderTransfer: TransferChannelLeader failed", zap.Error(err), zap.String("channelId", req.ChannelId), zap.Uint8("channelType", req.ChannelType), zap.Uint64("toNodeId", req.ToNodeId))
+		c.WriteErr(err)
+		return
+	}
+	c.WriteOk()
+}
+
+func (s *Server) handleNodeDraining(c *wkserver.Context) {
+	req := &NodeDrainingReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("unmarshal NodeDrainingReq failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	s.Info("node is draining", zap.Uint64("nodeId", req.NodeId))
+	s.clusterEventServer.SetNodeDraining(req.NodeId, time.UnixMilli(req.Until))
+	c.WriteOk()
+}
+
+func (s *Server) handleChannelLeaderLoad(c *wkserver.Context) {
+	if s.draining.Load() { // 下线中的节点不参与均衡，其他节点不会把领导转给它
+		c.WriteErr(ErrNodeDraining)
+		return
+	}
+	load := s.channelLeaderBalancer.localLoad()
+	data, err := load.Marshal()
+	if err != nil {
+		s.Error("marshal ChannelLeaderLoad failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.Write(data)
+}
+
+func (s *Server) handleChannelReadIndex(c *wkserver.Context) {
+	req := &ChannelReadIndexReq{}
+	if err := req.Unmarshal(c.Body()); err != nil {
+		s.Error("unmarshal ChannelReadIndexReq failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	indexes, err := s.localChannelReadIndexes(req.Channels)
+	if err != nil {
+		s.Error("handleChannelReadIndex: localChannelReadIndexes failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	resp := &ChannelReadIndexResp{Indexes: indexes}
+	data, err := resp.Marshal()
+	if err != nil {
+		s.Error("marshal ChannelReadIndexResp failed", zap.Error(err))
+		c.WriteErr(err)
+		return
+	}
+	c.Write(data)
+}
@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig"
+)
+
+// LogState 日志的状态（离线检查用）
+type LogState struct {
+	Name         string `json:"name"`          // 日志名称 config 或 slot[id]
+	LastIndex    uint64 `json:"last_index"`    // 最后一条日志下标
+	LastTerm     uint32 `json:"last_term"`     // 最后一条日志的任期
+	AppliedIndex uint64 `json:"applied_index"` // 已应用的日志下标
+}
+
+// Issue 日志状态的问题，没有问题返回空字符串
+func (l LogState) Issue() string {
+	if l.LastIndex > 0 && l.LastTerm == 0 { // 任期从1开始，为0说明最后一条日志不存在
+		return fmt.Sprintf("last log not found, lastIndex: %d", l.LastIndex)
+	}
+	if l.AppliedIndex > l.LastIndex {
+		return fmt.Sprintf("applied index %d is greater than last index %d", l.AppliedIndex, l.LastIndex)
+	}
+	return ""
+}
+
+// InspectLogs 以只读方式打开节点的集群配置日志和槽日志，返回它们的最后日志下标和已应用下标（节点必须已停止）
+// dataDir为分布式的数据目录（与WithDataDir一致）
+func InspectLogs(dataDir string, slotDbShardNum int, slotCount uint32) ([]LogState, error) {
+	states := make([]LogState, 0, slotCount+1)
+
+	cfgStorage := clusterconfig.NewPebbleShardLogStorage(path.Join(dataDir, "config", "cfglogdb"))
+	if err := cfgStorage.OpenReadOnly(); err != nil {
+		return nil, err
+	}
+	defer cfgStorage.Close()
+
+	lastIndex, lastTerm, err := cfgStorage.LastIndexAndTerm()
+	if err != nil {
+		return nil, err
+	}
+	appliedIndex, err := cfgStorage.AppliedIndex()
+	if err != nil {
+		return nil, err
+	}
+	states = append(states, LogState{Name: "config", LastIndex: lastIndex, LastTerm: lastTerm, AppliedIndex: appliedIndex})
+
+	slotStorage := NewPebbleShardLogStorage(path.Join(dataDir, "logdb"), uint32(slotDbShardNum))
+	if err = slotStorage.OpenReadOnly(); err != nil {
+		return nil, err
+	}
+	defer slotStorage.Close()
+
+	for slotId := uint32(0); slotId < slotCount; slotId++ {
+		shardNo := SlotIdToKey(slotId)
+		lastIndex, lastTerm, err := slotStorage.LastIndexAndTerm(shardNo)
+		if err != nil {
+			return nil, err
+		}
+		appliedIndex, err := slotStorage.AppliedIndex(shardNo)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, LogState{Name: fmt.Sprintf("slot[%d]", slotId), LastIndex: lastIndex, LastTerm: lastTerm, AppliedIndex: appliedIndex})
+	}
+	return states, nil
+}
@@ -72,7 +72,17 @@ func (p *PebbleShardLogStorage) defaultPebbleOptions() *pebble.Options {
 }
 
 func (p *PebbleShardLogStorage) Open() error {
+	return p.open(false)
+}
+
+// OpenReadOnly 以只读方式打开（节点停止后离线检查日志时使用）
+func (p *PebbleShardLogStorage) OpenReadOnly() error {
+	return p.open(true)
+}
+
+func (p *PebbleShardLogStorage) open(readOnly bool) error {
 	opts := p.defaultPebbleOptions()
+	opts.ReadOnly = readOnly
 	for i := 0; i < int(p.shardNum); i++ {
 		db, err := pebble.Open(fmt.Sprintf("%s/shard%03d", p.path, i), opts)
 		if err != nil {
@@ -92,6 +102,16 @@ func (p *PebbleShardLogStorage) Close() error {
 	return nil
 }
 
+// Checkpoint 在指定目录下为每个分片生成一致性快照，目录结构与数据目录一致
+func (p *PebbleShardLogStorage) Checkpoint(dir string) error {
+	for i, db := range p.dbs {
+		if err := db.Checkpoint(fmt.Sprintf("%s/shard%03d", dir, i), pebble.WithFlushedWAL()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *PebbleShardLogStorage) shardDB(v string) *pebble.DB {
 	shardId := p.shardId(v)
 	return p.dbs[shardId]
@@ -78,6 +78,58 @@ const (
 
 	// 批量更新最近会话
 	CMDBatchUpdateConversation
+
+	// 添加或更新社区话题
+	CMDAddOrUpdateChannelTopic
+
+	// 更新订阅者角色
+	CMDUpdateSubscribersRole
+	// 更新订阅者禁言
+	CMDUpdateSubscribersMute
+
+	// 添加已吊销的令牌
+	CMDAddRevokedToken
+
+	// 添加或更新定时消息
+	CMDAddOrUpdateScheduledMessage
+	// 删除定时消息
+	CMDDeleteScheduledMessage
+
+	// 添加广播任务
+	CMDAddBroadcastTask
+	// 更新广播任务进度
+	CMDUpdateBroadcastTaskProgress
+	// 取消广播任务
+	CMDCancelBroadcastTask
+
+	// 设置用户属性
+	CMDSetUserAttributes
+	// 删除用户属性
+	CMDDeleteUserAttributes
+	// 设置频道属性
+	CMDSetChannelAttributes
+	// 删除频道属性
+	CMDDeleteChannelAttributes
+	// 添加或更新用户数据任务
+	CMDAddOrUpdateUserDataJob
+	// 删除用户数据
+	CMDEraseUser
+	// 添加或更新设备的端到端加密公钥
+	CMDAddOrUpdateE2EEDeviceKeys
+	// 删除设备的端到端加密公钥
+	CMDDeleteE2EEDeviceKeys
+	// 添加一次性预共享公钥
+	CMDAddE2EEOneTimePrekeys
+	// 移除一次性预共享公钥
+	CMDRemoveE2EEOneTimePrekeys
+	// 添加或更新机器人
+	CMDAddOrUpdateBot
+	// 移除机器人
+	CMDRemoveBot
+	// 移除用户数据任务
+	CMDRemoveUserDataJob
+	// 保存用户的在线状态
+	CMDSetUserPresence
 )
 
 func (c CMDType) Uint16() uint16 {
@@ -150,6 +202,52 @@ func (c CMDType) String() string {
 		return "CMDBatchUpdateConversation"
 	case CMDDeleteConversations:
 		return "CMDDeleteConversations"
+	case CMDAddOrUpdateChannelTopic:
+		return "CMDAddOrUpdateChannelTopic"
+	case CMDUpdateSubscribersRole:
+		return "CMDUpdateSubscribersRole"
+	case CMDUpdateSubscribersMute:
+		return "CMDUpdateSubscribersMute"
+	case CMDAddRevokedToken:
+		return "CMDAddRevokedToken"
+	case CMDAddOrUpdateScheduledMessage:
+		return "CMDAddOrUpdateScheduledMessage"
+	case CMDDeleteScheduledMessage:
+		return "CMDDeleteScheduledMessage"
+	case CMDAddBroadcastTask:
+		return "CMDAddBroadcastTask"
+	case CMDUpdateBroadcastTaskProgress:
+		return "CMDUpdateBroadcastTaskProgress"
+	case CMDCancelBroadcastTask:
+		return "CMDCancelBroadcastTask"
+	case CMDSetUserAttributes:
+		return "CMDSetUserAttributes"
+	case CMDDeleteUserAttributes:
+		return "CMDDeleteUserAttributes"
+	case CMDSetChannelAttributes:
+		return "CMDSetChannelAttributes"
+	case CMDDeleteChannelAttributes:
+		return "CMDDeleteChannelAttributes"
+	case CMDAddOrUpdateUserDataJob:
+		return "CMDAddOrUpdateUserDataJob"
+	case CMDEraseUser:
+		return "CMDEraseUser"
+	case CMDAddOrUpdateE2EEDeviceKeys:
+		return "CMDAddOrUpdateE2EEDeviceKeys"
+	case CMDDeleteE2EEDeviceKeys:
+		return "CMDDeleteE2EEDeviceKeys"
+	case CMDAddE2EEOneTimePrekeys:
+		return "CMDAddE2EEOneTimePrekeys"
+	case CMDRemoveE2EEOneTimePrekeys:
+		return "CMDRemoveE2EEOneTimePrekeys"
+	case CMDAddOrUpdateBot:
+		return "CMDAddOrUpdateBot"
+	case CMDRemoveBot:
+		return "CMDRemoveBot"
+	case CMDRemoveUserDataJob:
+		return "CMDRemoveUserDataJob"
+	case CMDSetUserPresence:
+		return "CMDSetUserPresence"
 	default:
 		return fmt.Sprintf("CMDUnknown[%d]", c)
 	}
@@ -178,7 +276,9 @@ func NewCMDWithVersion(cmdType CMDType, data []byte, version CmdVersion) *CMD {
 }
 
 func (c *CMD) Marshal() ([]byte, error) {
-	c.version = 1
+	if c.version == 0 {
+		c.version = 1
+	}
 	enc := wkproto.NewEncoder()
 	defer enc.End()
 	enc.WriteUint16(c.version.Uint16())
@@ -434,6 +534,218 @@ func (c *CMD) CMDContent() (string, error) {
 		}
 		return wkutil.ToJSON(channelClusterConfig), nil
 
+	case CMDAddOrUpdateChannelTopic:
+		topic, err := c.DecodeCMDChannelTopic()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(topic), nil
+
+	case CMDUpdateSubscribersRole:
+		channelId, channelType, uids, role, err := c.DecodeCMDUpdateSubscribersRole()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"uids":        uids,
+			"role":        role,
+		}), nil
+
+	case CMDUpdateSubscribersMute:
+		channelId, channelType, uids, muteExpireAt, err := c.DecodeCMDUpdateSubscribersMute()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":    channelId,
+			"channelType":  channelType,
+			"uids":         uids,
+			"muteExpireAt": muteExpireAt,
+		}), nil
+
+	case CMDAddRevokedToken:
+		revokedToken, err := c.DecodeCMDAddRevokedToken()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(revokedToken), nil
+
+	case CMDAddOrUpdateScheduledMessage:
+		m, err := c.DecodeCMDAddOrUpdateScheduledMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(m), nil
+
+	case CMDDeleteScheduledMessage:
+		id, err := c.DecodeCMDDeleteScheduledMessage()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"id": id,
+		}), nil
+
+	case CMDAddBroadcastTask:
+		t, err := c.DecodeCMDAddBroadcastTask()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(t), nil
+
+	case CMDUpdateBroadcastTaskProgress:
+		t, err := c.DecodeCMDUpdateBroadcastTaskProgress()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(t), nil
+
+	case CMDCancelBroadcastTask:
+		jobId, slotId, err := c.DecodeCMDCancelBroadcastTask()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"jobId":  jobId,
+			"slotId": slotId,
+		}), nil
+
+	case CMDSetUserAttributes:
+		uid, attrs, expectVersion, err := c.DecodeCMDSetUserAttributes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":           uid,
+			"attributes":    attrs,
+			"expectVersion": expectVersion,
+		}), nil
+
+	case CMDDeleteUserAttributes:
+		uid, names, err := c.DecodeCMDDeleteUserAttributes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":   uid,
+			"names": names,
+		}), nil
+
+	case CMDSetChannelAttributes:
+		channelId, channelType, attrs, expectVersion, err := c.DecodeCMDSetChannelAttributes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":     channelId,
+			"channelType":   channelType,
+			"attributes":    attrs,
+			"expectVersion": expectVersion,
+		}), nil
+
+	case CMDDeleteChannelAttributes:
+		channelId, channelType, names, err := c.DecodeCMDDeleteChannelAttributes()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"channelId":   channelId,
+			"channelType": channelType,
+			"names":       names,
+		}), nil
+
+	case CMDAddOrUpdateUserDataJob:
+		j, err := c.DecodeCMDAddOrUpdateUserDataJob()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(j), nil
+
+	case CMDEraseUser:
+		uid, err := c.DecodeCMDEraseUser()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid": uid,
+		}), nil
+
+	case CMDAddOrUpdateE2EEDeviceKeys:
+		keys, prekeys, err := c.DecodeCMDAddOrUpdateE2EEDeviceKeys()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"keys":           keys,
+			"oneTimePrekeys": prekeys,
+		}), nil
+
+	case CMDDeleteE2EEDeviceKeys:
+		uid, deviceId, err := c.DecodeCMDDeleteE2EEDeviceKeys()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":      uid,
+			"deviceId": deviceId,
+		}), nil
+
+	case CMDAddE2EEOneTimePrekeys:
+		uid, deviceId, prekeys, err := c.DecodeCMDAddE2EEOneTimePrekeys()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":            uid,
+			"deviceId":       deviceId,
+			"oneTimePrekeys": prekeys,
+		}), nil
+
+	case CMDRemoveE2EEOneTimePrekeys:
+		uid, deviceId, keyIds, err := c.DecodeCMDRemoveE2EEOneTimePrekeys()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid":      uid,
+			"deviceId": deviceId,
+			"keyIds":   keyIds,
+		}), nil
+
+	case CMDAddOrUpdateBot:
+		bot, err := c.DecodeCMDAddOrUpdateBot()
+		if err != nil {
+			return "", err
+		}
+		bot.Secret = "" // 密钥不输出
+		return wkutil.ToJSON(bot), nil
+
+	case CMDRemoveBot:
+		uid, err := c.DecodeCMDRemoveBot()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"uid": uid,
+		}), nil
+	case CMDRemoveUserDataJob:
+		slotId, id, err := c.DecodeCMDRemoveUserDataJob()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(map[string]interface{}{
+			"slotId": slotId,
+			"id":     id,
+		}), nil
+	case CMDSetUserPresence:
+		presence, err := c.DecodeCMDSetUserPresence()
+		if err != nil {
+			return "", err
+		}
+		return wkutil.ToJSON(presence), nil
+
 	}
 
 	return "", nil
@@ -458,7 +770,10 @@ func EncodeMembers(channelId string, channelType uint8, members []wkdb.Member) [
 }
 
 func (c *CMD) DecodeChannelUids() (channelId string, channelType uint8, uids []string, err error) {
-	decoder := wkproto.NewDecoder(c.Data)
+	return decodeChannelUids(wkproto.NewDecoder(c.Data))
+}
+
+func decodeChannelUids(decoder *wkproto.Decoder) (channelId string, channelType uint8, uids []string, err error) {
 	if channelId, err = decoder.String(); err != nil {
 		return
 	}
@@ -769,6 +1084,9 @@ func EncodeChannelInfo(c wkdb.ChannelInfo, version CmdVersion) ([]byte, error) {
 	if version > 0 {
 		enc.WriteString(c.Webhook)
 	}
+	if version > 2 {
+		enc.WriteUint8(wkutil.BoolToUint8(c.MuteAll))
+	}
 	return enc.Bytes(), nil
 }
 
@@ -823,6 +1141,13 @@ func (c *CMD) DecodeChannelInfo() (wkdb.ChannelInfo, error) {
 			return channelInfo, err
 		}
 	}
+	if c.version > 2 {
+		var muteAll uint8
+		if muteAll, err = dec.Uint8(); err != nil {
+			return channelInfo, err
+		}
+		channelInfo.MuteAll = wkutil.Uint8ToBool(muteAll)
+	}
 
 	return channelInfo, err
 }
@@ -1207,4 +1532,974 @@ func (c *CMD) DecodeCMDSystemUIDs() (uids []string, err error) {
 	return
 }
 
+func EncodeCMDChannelTopic(t wkdb.ChannelTopic) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(t.ParentChannelId)
+	encoder.WriteUint8(t.ParentChannelType)
+	encoder.WriteString(t.TopicId)
+	encoder.WriteString(t.Name)
+	encoder.WriteUint8(wkutil.BoolToUint8(t.Archived))
+	if t.CreatedAt != nil {
+		encoder.WriteUint64(uint64(t.CreatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	if t.UpdatedAt != nil {
+		encoder.WriteUint64(uint64(t.UpdatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDChannelTopic() (t wkdb.ChannelTopic, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if t.ParentChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if t.ParentChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if t.TopicId, err = decoder.String(); err != nil {
+		return
+	}
+	if t.Name, err = decoder.String(); err != nil {
+		return
+	}
+	var archived uint8
+	if archived, err = decoder.Uint8(); err != nil {
+		return
+	}
+	t.Archived = wkutil.Uint8ToBool(archived)
+
+	var createdAt uint64
+	if createdAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if createdAt > 0 {
+		ct := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+		t.CreatedAt = &ct
+	}
+	var updatedAt uint64
+	if updatedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if updatedAt > 0 {
+		ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+		t.UpdatedAt = &ct
+	}
+	return
+}
+
+func EncodeCMDUpdateSubscribersRole(channelId string, channelType uint8, uids []string, role wkdb.MemberRole) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteBytes(EncodeChannelUids(channelId, channelType, uids))
+	encoder.WriteUint8(uint8(role))
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUpdateSubscribersRole() (channelId string, channelType uint8, uids []string, role wkdb.MemberRole, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, channelType, uids, err = decodeChannelUids(decoder); err != nil {
+		return
+	}
+	var r uint8
+	if r, err = decoder.Uint8(); err != nil {
+		return
+	}
+	role = wkdb.MemberRole(r)
+	return
+}
+
+func EncodeCMDUpdateSubscribersMute(channelId string, channelType uint8, uids []string, muteExpireAt uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteBytes(EncodeChannelUids(channelId, channelType, uids))
+	encoder.WriteUint64(muteExpireAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUpdateSubscribersMute() (channelId string, channelType uint8, uids []string, muteExpireAt uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, channelType, uids, err = decodeChannelUids(decoder); err != nil {
+		return
+	}
+	if muteExpireAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAddRevokedToken(t wkdb.RevokedToken) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(t.Uid)
+	encoder.WriteString(t.TokenId)
+	encoder.WriteUint64(t.ExpireAt)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddRevokedToken() (t wkdb.RevokedToken, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if t.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if t.TokenId, err = decoder.String(); err != nil {
+		return
+	}
+	if t.ExpireAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateScheduledMessage(m wkdb.ScheduledMessage) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(m.Id)
+	encoder.WriteString(m.ChannelId)
+	encoder.WriteUint8(m.ChannelType)
+	encoder.WriteString(m.FromUid)
+	encoder.WriteString(m.ClientMsgNo)
+	encoder.WriteUint8(wkutil.BoolToUint8(m.NoPersist))
+	encoder.WriteUint8(wkutil.BoolToUint8(m.RedDot))
+	encoder.WriteUint8(wkutil.BoolToUint8(m.SyncOnce))
+	encoder.WriteUint32(m.Expire)
+	encoder.WriteBinary(m.Payload)
+	encoder.WriteInt64(m.SendAt)
+	if m.CreatedAt != nil {
+		encoder.WriteUint64(uint64(m.CreatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	if m.UpdatedAt != nil {
+		encoder.WriteUint64(uint64(m.UpdatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateScheduledMessage() (m wkdb.ScheduledMessage, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if m.Id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if m.ChannelId, err = decoder.String(); err != nil {
+		return
+	}
+	if m.ChannelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if m.FromUid, err = decoder.String(); err != nil {
+		return
+	}
+	if m.ClientMsgNo, err = decoder.String(); err != nil {
+		return
+	}
+	var noPersist, redDot, syncOnce uint8
+	if noPersist, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if redDot, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if syncOnce, err = decoder.Uint8(); err != nil {
+		return
+	}
+	m.NoPersist = wkutil.Uint8ToBool(noPersist)
+	m.RedDot = wkutil.Uint8ToBool(redDot)
+	m.SyncOnce = wkutil.Uint8ToBool(syncOnce)
+	if m.Expire, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if m.Payload, err = decoder.Binary(); err != nil {
+		return
+	}
+	if m.SendAt, err = decoder.Int64(); err != nil {
+		return
+	}
+
+	var createdAt uint64
+	if createdAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if createdAt > 0 {
+		ct := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+		m.CreatedAt = &ct
+	}
+	var updatedAt uint64
+	if updatedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if updatedAt > 0 {
+		ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+		m.UpdatedAt = &ct
+	}
+	return
+}
+
+func EncodeCMDDeleteScheduledMessage(id uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(id)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteScheduledMessage() (id uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	id, err = decoder.Uint64()
+	return
+}
+
+func EncodeCMDAddBroadcastTask(t wkdb.BroadcastTask) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteBytes(EncodeCMDUpdateBroadcastTaskProgress(t))
+	encoder.WriteString(t.FromUid)
+	encoder.WriteUint8(wkutil.BoolToUint8(t.NoPersist))
+	encoder.WriteUint8(wkutil.BoolToUint8(t.RedDot))
+	encoder.WriteBinary(t.Payload)
+	encoder.WriteUint32(uint32(len(t.Uids)))
+	for _, uid := range t.Uids {
+		encoder.WriteString(uid)
+	}
+	encoder.WriteUint8(t.DeviceFlags)
+	encoder.WriteInt64(t.UserCreatedGte)
+	encoder.WriteInt64(t.UserCreatedLt)
+	encoder.WriteUint32(t.Rate)
+	if t.CreatedAt != nil {
+		encoder.WriteUint64(uint64(t.CreatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddBroadcastTask() (t wkdb.BroadcastTask, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if t, err = decodeBroadcastTaskProgress(decoder); err != nil {
+		return
+	}
+	if t.FromUid, err = decoder.String(); err != nil {
+		return
+	}
+	var noPersist, redDot uint8
+	if noPersist, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if redDot, err = decoder.Uint8(); err != nil {
+		return
+	}
+	t.NoPersist = wkutil.Uint8ToBool(noPersist)
+	t.RedDot = wkutil.Uint8ToBool(redDot)
+	if t.Payload, err = decoder.Binary(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if count > 0 {
+		t.Uids = make([]string, 0, count)
+		for i := uint32(0); i < count; i++ {
+			var uid string
+			if uid, err = decoder.String(); err != nil {
+				return
+			}
+			t.Uids = append(t.Uids, uid)
+		}
+	}
+	if t.DeviceFlags, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if t.UserCreatedGte, err = decoder.Int64(); err != nil {
+		return
+	}
+	if t.UserCreatedLt, err = decoder.Int64(); err != nil {
+		return
+	}
+	if t.Rate, err = decoder.Uint32(); err != nil {
+		return
+	}
+	var createdAt uint64
+	if createdAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if createdAt > 0 {
+		ct := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+		t.CreatedAt = &ct
+	}
+	return
+}
+
+// EncodeCMDUpdateBroadcastTaskProgress 只编码广播任务的状态和进度
+func EncodeCMDUpdateBroadcastTaskProgress(t wkdb.BroadcastTask) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(t.JobId)
+	encoder.WriteUint32(t.SlotId)
+	encoder.WriteUint8(uint8(t.Status))
+	encoder.WriteUint64(t.Cursor)
+	encoder.WriteUint64(t.SentCount)
+	encoder.WriteUint64(t.FailedCount)
+	if t.UpdatedAt != nil {
+		encoder.WriteUint64(uint64(t.UpdatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDUpdateBroadcastTaskProgress() (t wkdb.BroadcastTask, err error) {
+	return decodeBroadcastTaskProgress(wkproto.NewDecoder(c.Data))
+}
+
+func decodeBroadcastTaskProgress(decoder *wkproto.Decoder) (t wkdb.BroadcastTask, err error) {
+	if t.JobId, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if t.SlotId, err = decoder.Uint32(); err != nil {
+		return
+	}
+	var status uint8
+	if status, err = decoder.Uint8(); err != nil {
+		return
+	}
+	t.Status = wkdb.BroadcastStatus(status)
+	if t.Cursor, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if t.SentCount, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if t.FailedCount, err = decoder.Uint64(); err != nil {
+		return
+	}
+	var updatedAt uint64
+	if updatedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if updatedAt > 0 {
+		ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+		t.UpdatedAt = &ct
+	}
+	return
+}
+
+func EncodeCMDCancelBroadcastTask(jobId uint64, slotId uint32) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(jobId)
+	encoder.WriteUint32(slotId)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDCancelBroadcastTask() (jobId uint64, slotId uint32, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if jobId, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if slotId, err = decoder.Uint32(); err != nil {
+		return
+	}
+	return
+}
+
+func EncodeCMDSetUserAttributes(uid string, attrs []wkdb.Attribute, expectVersion bool) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encodeAttributes(encoder, attrs, expectVersion)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSetUserAttributes() (uid string, attrs []wkdb.Attribute, expectVersion bool, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	attrs, expectVersion, err = decodeAttributes(decoder)
+	return
+}
+
+func EncodeCMDDeleteUserAttributes(uid string, names []string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encodeAttributeNames(encoder, names)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteUserAttributes() (uid string, names []string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	names, err = decodeAttributeNames(decoder)
+	return
+}
+
+func EncodeCMDSetChannelAttributes(channelId string, channelType uint8, attrs []wkdb.Attribute, expectVersion bool) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encodeAttributes(encoder, attrs, expectVersion)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSetChannelAttributes() (channelId string, channelType uint8, attrs []wkdb.Attribute, expectVersion bool, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	attrs, expectVersion, err = decodeAttributes(decoder)
+	return
+}
+
+func EncodeCMDDeleteChannelAttributes(channelId string, channelType uint8, names []string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(channelId)
+	encoder.WriteUint8(channelType)
+	encodeAttributeNames(encoder, names)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteChannelAttributes() (channelId string, channelType uint8, names []string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if channelId, err = decoder.String(); err != nil {
+		return
+	}
+	if channelType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	names, err = decodeAttributeNames(decoder)
+	return
+}
+
+func encodeAttributes(encoder *wkproto.Encoder, attrs []wkdb.Attribute, expectVersion bool) {
+	encoder.WriteUint8(wkutil.BoolToUint8(expectVersion))
+	encoder.WriteUint32(uint32(len(attrs)))
+	for _, attr := range attrs {
+		encoder.WriteString(attr.Name)
+		encoder.WriteUint8(uint8(attr.Type))
+		encoder.WriteBinary(attr.Value)
+		encoder.WriteUint64(attr.Version)
+		if attr.UpdatedAt != nil {
+			encoder.WriteUint64(uint64(attr.UpdatedAt.UnixNano()))
+		} else {
+			encoder.WriteUint64(0)
+		}
+	}
+}
+
+func decodeAttributes(decoder *wkproto.Decoder) (attrs []wkdb.Attribute, expectVersion bool, err error) {
+	var expect uint8
+	if expect, err = decoder.Uint8(); err != nil {
+		return
+	}
+	expectVersion = wkutil.Uint8ToBool(expect)
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if count == 0 {
+		return
+	}
+	attrs = make([]wkdb.Attribute, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var attr wkdb.Attribute
+		if attr.Name, err = decoder.String(); err != nil {
+			return
+		}
+		var attrType uint8
+		if attrType, err = decoder.Uint8(); err != nil {
+			return
+		}
+		attr.Type = wkdb.AttributeType(attrType)
+		if attr.Value, err = decoder.Binary(); err != nil {
+			return
+		}
+		if attr.Version, err = decoder.Uint64(); err != nil {
+			return
+		}
+		var updatedAt uint64
+		if updatedAt, err = decoder.Uint64(); err != nil {
+			return
+		}
+		if updatedAt > 0 {
+			ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+			attr.UpdatedAt = &ct
+		}
+		attrs = append(attrs, attr)
+	}
+	return
+}
+
+func encodeAttributeNames(encoder *wkproto.Encoder, names []string) {
+	encoder.WriteUint32(uint32(len(names)))
+	for _, name := range names {
+		encoder.WriteString(name)
+	}
+}
+
+func decodeAttributeNames(decoder *wkproto.Decoder) (names []string, err error) {
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if count == 0 {
+		return
+	}
+	names = make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var name string
+		if name, err = decoder.String(); err != nil {
+			return
+		}
+		names = append(names, name)
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateUserDataJob(j wkdb.UserDataJob) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint64(j.Id)
+	encoder.WriteUint32(j.SlotId)
+	encoder.WriteString(j.Uid)
+	encoder.WriteUint8(uint8(j.Type))
+	encoder.WriteUint8(uint8(j.Status))
+	encoder.WriteString(j.Operator)
+	encoder.WriteUint64(j.NodeId)
+	encoder.WriteString(j.Archive)
+	encoder.WriteString(j.Error)
+	encoder.WriteUint64(j.ChannelCount)
+	encoder.WriteUint64(j.MessageCount)
+	encoder.WriteUint64(j.ConversationCount)
+	encoder.WriteUint64(j.DeviceCount)
+	encoder.WriteUint32(uint32(len(j.ErasedNodes)))
+	for _, nodeId := range j.ErasedNodes {
+		encoder.WriteUint64(nodeId)
+	}
+	if j.CreatedAt != nil {
+		encoder.WriteUint64(uint64(j.CreatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	if j.UpdatedAt != nil {
+		encoder.WriteUint64(uint64(j.UpdatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateUserDataJob() (j wkdb.UserDataJob, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if j.Id, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if j.SlotId, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if j.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	var jobType, status uint8
+	if jobType, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if status, err = decoder.Uint8(); err != nil {
+		return
+	}
+	j.Type = wkdb.UserDataJobType(jobType)
+	j.Status = wkdb.UserDataJobStatus(status)
+	if j.Operator, err = decoder.String(); err != nil {
+		return
+	}
+	if j.NodeId, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if j.Archive, err = decoder.String(); err != nil {
+		return
+	}
+	if j.Error, err = decoder.String(); err != nil {
+		return
+	}
+	if j.ChannelCount, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if j.MessageCount, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if j.ConversationCount, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if j.DeviceCount, err = decoder.Uint64(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var nodeId uint64
+		if nodeId, err = decoder.Uint64(); err != nil {
+			return
+		}
+		j.ErasedNodes = append(j.ErasedNodes, nodeId)
+	}
+	var createdAt, updatedAt uint64
+	if createdAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if createdAt > 0 {
+		ct := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+		j.CreatedAt = &ct
+	}
+	if updatedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if updatedAt > 0 {
+		ut := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+		j.UpdatedAt = &ut
+	}
+	return
+}
+
+func EncodeCMDEraseUser(uid string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDEraseUser() (uid string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	uid, err = decoder.String()
+	return
+}
+
+func EncodeCMDRemoveUserDataJob(slotId uint32, id uint64) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteUint32(slotId)
+	encoder.WriteUint64(id)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveUserDataJob() (slotId uint32, id uint64, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if slotId, err = decoder.Uint32(); err != nil {
+		return
+	}
+	id, err = decoder.Uint64()
+	return
+}
+
+func EncodeCMDSetUserPresence(p wkdb.UserPresence) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(p.Uid)
+	encoder.WriteUint8(p.Custom)
+	encoder.WriteString(p.Text)
+	encoder.WriteInt64(p.LastSeen)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDSetUserPresence() (p wkdb.UserPresence, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if p.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if p.Custom, err = decoder.Uint8(); err != nil {
+		return
+	}
+	if p.Text, err = decoder.String(); err != nil {
+		return
+	}
+	p.LastSeen, err = decoder.Int64()
+	return
+}
+
+func EncodeCMDAddOrUpdateE2EEDeviceKeys(keys wkdb.E2EEDeviceKeys, oneTimePrekeys []wkdb.E2EEPrekey) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(keys.Uid)
+	encoder.WriteString(keys.DeviceId)
+	encoder.WriteBinary(keys.IdentityKey)
+	encoder.WriteUint32(keys.SignedPrekey.KeyId)
+	encoder.WriteBinary(keys.SignedPrekey.PublicKey)
+	encoder.WriteBinary(keys.SignedPrekey.Signature)
+	if keys.CreatedAt != nil {
+		encoder.WriteUint64(uint64(keys.CreatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	if keys.UpdatedAt != nil {
+		encoder.WriteUint64(uint64(keys.UpdatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	encodeE2EEPrekeys(encoder, oneTimePrekeys)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateE2EEDeviceKeys() (keys wkdb.E2EEDeviceKeys, oneTimePrekeys []wkdb.E2EEPrekey, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if keys.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if keys.DeviceId, err = decoder.String(); err != nil {
+		return
+	}
+	if keys.IdentityKey, err = decoder.Binary(); err != nil {
+		return
+	}
+	if keys.SignedPrekey.KeyId, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if keys.SignedPrekey.PublicKey, err = decoder.Binary(); err != nil {
+		return
+	}
+	if keys.SignedPrekey.Signature, err = decoder.Binary(); err != nil {
+		return
+	}
+	var createdAt, updatedAt uint64
+	if createdAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if createdAt > 0 {
+		ct := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+		keys.CreatedAt = &ct
+	}
+	if updatedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if updatedAt > 0 {
+		ut := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+		keys.UpdatedAt = &ut
+	}
+	oneTimePrekeys, err = decodeE2EEPrekeys(decoder)
+	return
+}
+
+func EncodeCMDDeleteE2EEDeviceKeys(uid string, deviceId string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteString(deviceId)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDDeleteE2EEDeviceKeys() (uid string, deviceId string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	deviceId, err = decoder.String()
+	return
+}
+
+func EncodeCMDAddE2EEOneTimePrekeys(uid string, deviceId string, prekeys []wkdb.E2EEPrekey) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteString(deviceId)
+	encodeE2EEPrekeys(encoder, prekeys)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddE2EEOneTimePrekeys() (uid string, deviceId string, prekeys []wkdb.E2EEPrekey, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	if deviceId, err = decoder.String(); err != nil {
+		return
+	}
+	prekeys, err = decodeE2EEPrekeys(decoder)
+	return
+}
+
+func EncodeCMDRemoveE2EEOneTimePrekeys(uid string, deviceId string, keyIds []uint32) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	encoder.WriteString(deviceId)
+	encoder.WriteUint32(uint32(len(keyIds)))
+	for _, keyId := range keyIds {
+		encoder.WriteUint32(keyId)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveE2EEOneTimePrekeys() (uid string, deviceId string, keyIds []uint32, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if uid, err = decoder.String(); err != nil {
+		return
+	}
+	if deviceId, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if count == 0 {
+		return
+	}
+	keyIds = make([]uint32, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var keyId uint32
+		if keyId, err = decoder.Uint32(); err != nil {
+			return
+		}
+		keyIds = append(keyIds, keyId)
+	}
+	return
+}
+
+// 一次性预共享公钥没有签名，只编码keyId和公钥
+func encodeE2EEPrekeys(encoder *wkproto.Encoder, prekeys []wkdb.E2EEPrekey) {
+	encoder.WriteUint32(uint32(len(prekeys)))
+	for _, prekey := range prekeys {
+		encoder.WriteUint32(prekey.KeyId)
+		encoder.WriteBinary(prekey.PublicKey)
+	}
+}
+
+func decodeE2EEPrekeys(decoder *wkproto.Decoder) (prekeys []wkdb.E2EEPrekey, err error) {
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if count == 0 {
+		return
+	}
+	prekeys = make([]wkdb.E2EEPrekey, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var prekey wkdb.E2EEPrekey
+		if prekey.KeyId, err = decoder.Uint32(); err != nil {
+			return
+		}
+		if prekey.PublicKey, err = decoder.Binary(); err != nil {
+			return
+		}
+		prekeys = append(prekeys, prekey)
+	}
+	return
+}
+
+func EncodeCMDAddOrUpdateBot(bot wkdb.Bot) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(bot.Uid)
+	encoder.WriteString(bot.CallbackUrl)
+	encoder.WriteString(bot.Secret)
+	encoder.WriteUint32(uint32(len(bot.Channels)))
+	for _, channel := range bot.Channels {
+		encoder.WriteString(channel.ChannelId)
+		encoder.WriteUint8(channel.ChannelType)
+	}
+	encoder.WriteUint8(uint8(bot.Triggers))
+	encoder.WriteString(bot.CommandPrefix)
+	encoder.WriteUint32(bot.RateLimit)
+	encoder.WriteUint64(uint64(bot.Timeout))
+	if bot.CreatedAt != nil {
+		encoder.WriteUint64(uint64(bot.CreatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	if bot.UpdatedAt != nil {
+		encoder.WriteUint64(uint64(bot.UpdatedAt.UnixNano()))
+	} else {
+		encoder.WriteUint64(0)
+	}
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDAddOrUpdateBot() (bot wkdb.Bot, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	if bot.Uid, err = decoder.String(); err != nil {
+		return
+	}
+	if bot.CallbackUrl, err = decoder.String(); err != nil {
+		return
+	}
+	if bot.Secret, err = decoder.String(); err != nil {
+		return
+	}
+	var count uint32
+	if count, err = decoder.Uint32(); err != nil {
+		return
+	}
+	if count > 0 {
+		bot.Channels = make([]wkdb.BotChannel, 0, count)
+		for i := uint32(0); i < count; i++ {
+			var channel wkdb.BotChannel
+			if channel.ChannelId, err = decoder.String(); err != nil {
+				return
+			}
+			if channel.ChannelType, err = decoder.Uint8(); err != nil {
+				return
+			}
+			bot.Channels = append(bot.Channels, channel)
+		}
+	}
+	var triggers uint8
+	if triggers, err = decoder.Uint8(); err != nil {
+		return
+	}
+	bot.Triggers = wkdb.BotTrigger(triggers)
+	if bot.CommandPrefix, err = decoder.String(); err != nil {
+		return
+	}
+	if bot.RateLimit, err = decoder.Uint32(); err != nil {
+		return
+	}
+	var timeout uint64
+	if timeout, err = decoder.Uint64(); err != nil {
+		return
+	}
+	bot.Timeout = time.Duration(timeout)
+	var createdAt, updatedAt uint64
+	if createdAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if createdAt > 0 {
+		ct := time.Unix(int64(createdAt/1e9), int64(createdAt%1e9))
+		bot.CreatedAt = &ct
+	}
+	if updatedAt, err = decoder.Uint64(); err != nil {
+		return
+	}
+	if updatedAt > 0 {
+		ut := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
+		bot.UpdatedAt = &ut
+	}
+	return
+}
+
+func EncodeCMDRemoveBot(uid string) []byte {
+	encoder := wkproto.NewEncoder()
+	defer encoder.End()
+	encoder.WriteString(uid)
+	return encoder.Bytes()
+}
+
+func (c *CMD) DecodeCMDRemoveBot() (uid string, err error) {
+	decoder := wkproto.NewDecoder(c.Data)
+	uid, err = decoder.String()
+	return
+}
+
 var ErrStoreStopped = fmt.Errorf("store stopped")
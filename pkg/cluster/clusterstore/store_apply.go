@@ -6,6 +6,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -102,6 +103,52 @@ func (s *Store) execCMD(cmd *CMD) error {
 		return s.handleSystemUIDsAdd(cmd)
 	case CMDSystemUIDsRemove: // 移除系统UID
 		return s.handleSystemUIDsRemove(cmd)
+	case CMDAddOrUpdateChannelTopic: // 添加或更新社区话题
+		return s.handleAddOrUpdateChannelTopic(cmd)
+	case CMDUpdateSubscribersRole: // 更新订阅者角色
+		return s.handleUpdateSubscribersRole(cmd)
+	case CMDUpdateSubscribersMute: // 更新订阅者禁言
+		return s.handleUpdateSubscribersMute(cmd)
+	case CMDAddRevokedToken: // 添加已吊销的令牌
+		return s.handleAddRevokedToken(cmd)
+	case CMDAddOrUpdateScheduledMessage: // 添加或更新定时消息
+		return s.handleAddOrUpdateScheduledMessage(cmd)
+	case CMDDeleteScheduledMessage: // 删除定时消息
+		return s.handleDeleteScheduledMessage(cmd)
+	case CMDAddBroadcastTask: // 添加广播任务
+		return s.handleAddBroadcastTask(cmd)
+	case CMDUpdateBroadcastTaskProgress: // 更新广播任务进度
+		return s.handleUpdateBroadcastTaskProgress(cmd)
+	case CMDCancelBroadcastTask: // 取消广播任务
+		return s.handleCancelBroadcastTask(cmd)
+	case CMDSetUserAttributes: // 设置用户属性
+		return s.handleSetUserAttributes(cmd)
+	case CMDDeleteUserAttributes: // 删除用户属性
+		return s.handleDeleteUserAttributes(cmd)
+	case CMDSetChannelAttributes: // 设置频道属性
+		return s.handleSetChannelAttributes(cmd)
+	case CMDDeleteChannelAttributes: // 删除频道属性
+		return s.handleDeleteChannelAttributes(cmd)
+	case CMDAddOrUpdateUserDataJob: // 添加或更新用户数据任务
+		return s.handleAddOrUpdateUserDataJob(cmd)
+	case CMDEraseUser: // 删除用户数据
+		return s.handleEraseUser(cmd)
+	case CMDRemoveUserDataJob: // 移除用户数据任务
+		return s.handleRemoveUserDataJob(cmd)
+	case CMDSetUserPresence: // 保存用户的在线状态
+		return s.handleSetUserPresence(cmd)
+	case CMDAddOrUpdateE2EEDeviceKeys: // 添加或更新设备的端到端加密公钥
+		return s.handleAddOrUpdateE2EEDeviceKeys(cmd)
+	case CMDDeleteE2EEDeviceKeys: // 删除设备的端到端加密公钥
+		return s.handleDeleteE2EEDeviceKeys(cmd)
+	case CMDAddE2EEOneTimePrekeys: // 添加一次性预共享公钥
+		return s.handleAddE2EEOneTimePrekeys(cmd)
+	case CMDRemoveE2EEOneTimePrekeys: // 移除一次性预共享公钥
+		return s.handleRemoveE2EEOneTimePrekeys(cmd)
+	case CMDAddOrUpdateBot: // 添加或更新机器人
+		return s.handleAddOrUpdateBot(cmd)
+	case CMDRemoveBot: // 移除机器人
+		return s.handleRemoveBot(cmd)
 
 	}
 	return nil
@@ -293,6 +340,8 @@ func (s *Store) handleBatchUpdateConversation(cmd *CMD) error {
 		var conversationType = wkdb.ConversationTypeChat
 		if s.opts.IsCmdChannel(model.ChannelId) {
 			conversationType = wkdb.ConversationTypeCMD
+		} else if model.ChannelType == wkproto.ChannelTypeCommunityTopic {
+			conversationType = wkdb.ConversationTypeTopic
 		}
 		for uid, seq := range model.Uids {
 			conversation := wkdb.Conversation{
@@ -327,3 +376,187 @@ func (s *Store) handleSystemUIDsRemove(cmd *CMD) error {
 	}
 	return s.wdb.RemoveSystemUids(uids)
 }
+
+func (s *Store) handleAddOrUpdateChannelTopic(cmd *CMD) error {
+	topic, err := cmd.DecodeCMDChannelTopic()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateChannelTopic(topic)
+}
+
+func (s *Store) handleUpdateSubscribersRole(cmd *CMD) error {
+	channelId, channelType, uids, role, err := cmd.DecodeCMDUpdateSubscribersRole()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateSubscribersRole(channelId, channelType, uids, role)
+}
+
+func (s *Store) handleUpdateSubscribersMute(cmd *CMD) error {
+	channelId, channelType, uids, muteExpireAt, err := cmd.DecodeCMDUpdateSubscribersMute()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateSubscribersMute(channelId, channelType, uids, muteExpireAt)
+}
+
+func (s *Store) handleAddRevokedToken(cmd *CMD) error {
+	revokedToken, err := cmd.DecodeCMDAddRevokedToken()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddRevokedToken(revokedToken)
+}
+
+func (s *Store) handleSetUserPresence(cmd *CMD) error {
+	presence, err := cmd.DecodeCMDSetUserPresence()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetUserPresence(presence)
+}
+
+func (s *Store) handleAddOrUpdateScheduledMessage(cmd *CMD) error {
+	m, err := cmd.DecodeCMDAddOrUpdateScheduledMessage()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateScheduledMessage(m)
+}
+
+func (s *Store) handleDeleteScheduledMessage(cmd *CMD) error {
+	id, err := cmd.DecodeCMDDeleteScheduledMessage()
+	if err != nil {
+		return err
+	}
+	return s.wdb.DeleteScheduledMessage(id)
+}
+
+func (s *Store) handleAddBroadcastTask(cmd *CMD) error {
+	t, err := cmd.DecodeCMDAddBroadcastTask()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddBroadcastTask(t)
+}
+
+func (s *Store) handleUpdateBroadcastTaskProgress(cmd *CMD) error {
+	t, err := cmd.DecodeCMDUpdateBroadcastTaskProgress()
+	if err != nil {
+		return err
+	}
+	return s.wdb.UpdateBroadcastTaskProgress(t)
+}
+
+func (s *Store) handleCancelBroadcastTask(cmd *CMD) error {
+	jobId, slotId, err := cmd.DecodeCMDCancelBroadcastTask()
+	if err != nil {
+		return err
+	}
+	return s.wdb.CancelBroadcastTask(jobId, slotId)
+}
+
+func (s *Store) handleSetUserAttributes(cmd *CMD) error {
+	uid, attrs, expectVersion, err := cmd.DecodeCMDSetUserAttributes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetUserAttributes(uid, attrs, expectVersion)
+}
+
+func (s *Store) handleDeleteUserAttributes(cmd *CMD) error {
+	uid, names, err := cmd.DecodeCMDDeleteUserAttributes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.DeleteUserAttributes(uid, names)
+}
+
+func (s *Store) handleSetChannelAttributes(cmd *CMD) error {
+	channelId, channelType, attrs, expectVersion, err := cmd.DecodeCMDSetChannelAttributes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.SetChannelAttributes(channelId, channelType, attrs, expectVersion)
+}
+
+func (s *Store) handleDeleteChannelAttributes(cmd *CMD) error {
+	channelId, channelType, names, err := cmd.DecodeCMDDeleteChannelAttributes()
+	if err != nil {
+		return err
+	}
+	return s.wdb.DeleteChannelAttributes(channelId, channelType, names)
+}
+
+func (s *Store) handleAddOrUpdateUserDataJob(cmd *CMD) error {
+	j, err := cmd.DecodeCMDAddOrUpdateUserDataJob()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateUserDataJob(j)
+}
+
+func (s *Store) handleRemoveUserDataJob(cmd *CMD) error {
+	slotId, id, err := cmd.DecodeCMDRemoveUserDataJob()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveUserDataJob(slotId, id)
+}
+
+func (s *Store) handleEraseUser(cmd *CMD) error {
+	uid, err := cmd.DecodeCMDEraseUser()
+	if err != nil {
+		return err
+	}
+	return s.wdb.EraseUser(uid)
+}
+
+func (s *Store) handleAddOrUpdateE2EEDeviceKeys(cmd *CMD) error {
+	keys, prekeys, err := cmd.DecodeCMDAddOrUpdateE2EEDeviceKeys()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateE2EEDeviceKeys(keys, prekeys)
+}
+
+func (s *Store) handleDeleteE2EEDeviceKeys(cmd *CMD) error {
+	uid, deviceId, err := cmd.DecodeCMDDeleteE2EEDeviceKeys()
+	if err != nil {
+		return err
+	}
+	return s.wdb.DeleteE2EEDeviceKeys(uid, deviceId)
+}
+
+func (s *Store) handleAddE2EEOneTimePrekeys(cmd *CMD) error {
+	uid, deviceId, prekeys, err := cmd.DecodeCMDAddE2EEOneTimePrekeys()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddE2EEOneTimePrekeys(uid, deviceId, prekeys)
+}
+
+func (s *Store) handleRemoveE2EEOneTimePrekeys(cmd *CMD) error {
+	uid, deviceId, keyIds, err := cmd.DecodeCMDRemoveE2EEOneTimePrekeys()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveE2EEOneTimePrekeys(uid, deviceId, keyIds)
+}
+
+func (s *Store) handleAddOrUpdateBot(cmd *CMD) error {
+	bot, err := cmd.DecodeCMDAddOrUpdateBot()
+	if err != nil {
+		return err
+	}
+	return s.wdb.AddOrUpdateBot(bot)
+}
+
+func (s *Store) handleRemoveBot(cmd *CMD) error {
+	uid, err := cmd.DecodeCMDRemoveBot()
+	if err != nil {
+		return err
+	}
+	return s.wdb.RemoveBot(uid)
+}
@@ -0,0 +1,49 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// SetUserAttributes 设置用户属性，用户属性与用户存放在同一个槽内
+func (s *Store) SetUserAttributes(uid string, attrs []wkdb.Attribute, expectVersion bool) error {
+	data := EncodeCMDSetUserAttributes(uid, attrs, expectVersion)
+	return s.proposeAttributeCMD(uid, NewCMD(CMDSetUserAttributes, data))
+}
+
+// DeleteUserAttributes 删除用户属性，names为空时删除所有属性
+func (s *Store) DeleteUserAttributes(uid string, names []string) error {
+	data := EncodeCMDDeleteUserAttributes(uid, names)
+	return s.proposeAttributeCMD(uid, NewCMD(CMDDeleteUserAttributes, data))
+}
+
+func (s *Store) GetUserAttributes(uid string, names []string) ([]wkdb.Attribute, error) {
+	return s.wdb.GetUserAttributes(uid, names)
+}
+
+// SetChannelAttributes 设置频道属性，频道属性与频道存放在同一个槽内
+func (s *Store) SetChannelAttributes(channelId string, channelType uint8, attrs []wkdb.Attribute, expectVersion bool) error {
+	data := EncodeCMDSetChannelAttributes(channelId, channelType, attrs, expectVersion)
+	return s.proposeAttributeCMD(channelId, NewCMD(CMDSetChannelAttributes, data))
+}
+
+// DeleteChannelAttributes 删除频道属性，names为空时删除所有属性
+func (s *Store) DeleteChannelAttributes(channelId string, channelType uint8, names []string) error {
+	data := EncodeCMDDeleteChannelAttributes(channelId, channelType, names)
+	return s.proposeAttributeCMD(channelId, NewCMD(CMDDeleteChannelAttributes, data))
+}
+
+func (s *Store) GetChannelAttributes(channelId string, channelType uint8, names []string) ([]wkdb.Attribute, error) {
+	return s.wdb.GetChannelAttributes(channelId, channelType, names)
+}
+
+func (s *Store) proposeAttributeCMD(key string, cmd *CMD) error {
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(key)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
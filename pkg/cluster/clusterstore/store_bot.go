@@ -0,0 +1,33 @@
+package clusterstore
+
+import "github.com/WuKongIM/WuKongIM/pkg/wkdb"
+
+// AddOrUpdateBot 添加或更新机器人，机器人与系统uid一样存储在槽0上
+func (s *Store) AddOrUpdateBot(bot wkdb.Bot) error {
+	data := EncodeCMDAddOrUpdateBot(bot)
+	return s.proposeBotCMD(NewCMD(CMDAddOrUpdateBot, data))
+}
+
+// RemoveBot 移除机器人
+func (s *Store) RemoveBot(uid string) error {
+	data := EncodeCMDRemoveBot(uid)
+	return s.proposeBotCMD(NewCMD(CMDRemoveBot, data))
+}
+
+func (s *Store) GetBot(uid string) (wkdb.Bot, error) {
+	return s.wdb.GetBot(uid)
+}
+
+func (s *Store) GetBots() ([]wkdb.Bot, error) {
+	return s.wdb.GetBots()
+}
+
+func (s *Store) proposeBotCMD(cmd *CMD) error {
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	var slotId uint32 = 0 // 机器人默认存储在slot 0上
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
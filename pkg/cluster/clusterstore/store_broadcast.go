@@ -0,0 +1,42 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddBroadcastTask 添加广播任务，任务保存在它负责的槽内
+func (s *Store) AddBroadcastTask(t wkdb.BroadcastTask) error {
+	data := EncodeCMDAddBroadcastTask(t)
+	return s.proposeBroadcastCMD(t.SlotId, NewCMD(CMDAddBroadcastTask, data))
+}
+
+// UpdateBroadcastTaskProgress 更新广播任务的状态和进度
+func (s *Store) UpdateBroadcastTaskProgress(t wkdb.BroadcastTask) error {
+	data := EncodeCMDUpdateBroadcastTaskProgress(t)
+	return s.proposeBroadcastCMD(t.SlotId, NewCMD(CMDUpdateBroadcastTaskProgress, data))
+}
+
+// CancelBroadcastTask 取消广播任务
+func (s *Store) CancelBroadcastTask(jobId uint64, slotId uint32) error {
+	data := EncodeCMDCancelBroadcastTask(jobId, slotId)
+	return s.proposeBroadcastCMD(slotId, NewCMD(CMDCancelBroadcastTask, data))
+}
+
+func (s *Store) GetBroadcastTask(jobId uint64, slotId uint32) (wkdb.BroadcastTask, error) {
+	return s.wdb.GetBroadcastTask(jobId, slotId)
+}
+
+func (s *Store) GetBroadcastTasks(jobId uint64) ([]wkdb.BroadcastTask, error) {
+	return s.wdb.GetBroadcastTasks(jobId)
+}
+
+func (s *Store) proposeBroadcastCMD(slotId uint32, cmd *CMD) error {
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
@@ -56,6 +56,43 @@ func (s *Store) RemoveAllSubscriber(channelId string, channelType uint8) error {
 	return err
 }
 
+// GetSubscriber 获取指定订阅者
+func (s *Store) GetSubscriber(channelId string, channelType uint8, uid string) (wkdb.Member, error) {
+	return s.wdb.GetSubscriber(channelId, channelType, uid)
+}
+
+// UpdateSubscribersRole 更新订阅者角色
+func (s *Store) UpdateSubscribersRole(channelId string, channelType uint8, uids []string, role wkdb.MemberRole) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	data := EncodeCMDUpdateSubscribersRole(channelId, channelType, uids, role)
+	cmd := NewCMD(CMDUpdateSubscribersRole, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// UpdateSubscribersMute 更新订阅者禁言到期时间（unix秒，0表示解除禁言）
+func (s *Store) UpdateSubscribersMute(channelId string, channelType uint8, uids []string, muteExpireAt uint64) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	data := EncodeCMDUpdateSubscribersMute(channelId, channelType, uids, muteExpireAt)
+	cmd := NewCMD(CMDUpdateSubscribersMute, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
 func (s *Store) GetSubscribers(channelID string, channelType uint8) ([]wkdb.Member, error) {
 	return s.wdb.GetSubscribers(channelID, channelType)
 }
@@ -234,3 +271,24 @@ func (s *Store) HasAllowlist(channelId string, channelType uint8) (bool, error)
 // 	_, err = s.opts.Cluster.ProposeChannelMeta(s.ctx, channelID, channelType, cmdData)
 // 	return err
 // }
+
+// AddOrUpdateChannelTopic 添加或更新社区话题（话题数据存放在父频道所在的槽）
+func (s *Store) AddOrUpdateChannelTopic(topic wkdb.ChannelTopic) error {
+	data := EncodeCMDChannelTopic(topic)
+	cmd := NewCMD(CMDAddOrUpdateChannelTopic, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		return err
+	}
+	slotId := s.opts.GetSlotId(topic.ParentChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetChannelTopic(parentChannelId string, parentChannelType uint8, topicId string) (wkdb.ChannelTopic, error) {
+	return s.wdb.GetChannelTopic(parentChannelId, parentChannelType, topicId)
+}
+
+func (s *Store) GetChannelTopics(parentChannelId string, parentChannelType uint8) ([]wkdb.ChannelTopic, error) {
+	return s.wdb.GetChannelTopics(parentChannelId, parentChannelType)
+}
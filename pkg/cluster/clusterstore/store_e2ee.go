@@ -0,0 +1,57 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddOrUpdateE2EEDeviceKeys 添加或更新设备的公钥，设备公钥与用户存放在同一个槽内
+func (s *Store) AddOrUpdateE2EEDeviceKeys(keys wkdb.E2EEDeviceKeys, oneTimePrekeys []wkdb.E2EEPrekey) error {
+	data := EncodeCMDAddOrUpdateE2EEDeviceKeys(keys, oneTimePrekeys)
+	return s.proposeE2EECMD(keys.Uid, NewCMD(CMDAddOrUpdateE2EEDeviceKeys, data))
+}
+
+// DeleteE2EEDeviceKeys 删除设备的公钥和一次性预共享公钥
+func (s *Store) DeleteE2EEDeviceKeys(uid string, deviceId string) error {
+	data := EncodeCMDDeleteE2EEDeviceKeys(uid, deviceId)
+	return s.proposeE2EECMD(uid, NewCMD(CMDDeleteE2EEDeviceKeys, data))
+}
+
+// AddE2EEOneTimePrekeys 补充一次性预共享公钥
+func (s *Store) AddE2EEOneTimePrekeys(uid string, deviceId string, prekeys []wkdb.E2EEPrekey) error {
+	data := EncodeCMDAddE2EEOneTimePrekeys(uid, deviceId, prekeys)
+	return s.proposeE2EECMD(uid, NewCMD(CMDAddE2EEOneTimePrekeys, data))
+}
+
+// RemoveE2EEOneTimePrekeys 移除已被领取的一次性预共享公钥
+func (s *Store) RemoveE2EEOneTimePrekeys(uid string, deviceId string, keyIds []uint32) error {
+	data := EncodeCMDRemoveE2EEOneTimePrekeys(uid, deviceId, keyIds)
+	return s.proposeE2EECMD(uid, NewCMD(CMDRemoveE2EEOneTimePrekeys, data))
+}
+
+func (s *Store) GetE2EEDeviceKeys(uid string) ([]wkdb.E2EEDeviceKeys, error) {
+	return s.wdb.GetE2EEDeviceKeys(uid)
+}
+
+func (s *Store) GetE2EEDeviceKey(uid string, deviceId string) (wkdb.E2EEDeviceKeys, error) {
+	return s.wdb.GetE2EEDeviceKey(uid, deviceId)
+}
+
+func (s *Store) GetE2EEOneTimePrekeys(uid string, deviceId string, limit int) ([]wkdb.E2EEPrekey, error) {
+	return s.wdb.GetE2EEOneTimePrekeys(uid, deviceId, limit)
+}
+
+func (s *Store) GetE2EEOneTimePrekeyCount(uid string, deviceId string) (int, error) {
+	return s.wdb.GetE2EEOneTimePrekeyCount(uid, deviceId)
+}
+
+func (s *Store) proposeE2EECMD(uid string, cmd *CMD) error {
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
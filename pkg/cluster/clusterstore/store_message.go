@@ -152,6 +152,31 @@ func (s *Store) SearchMessages(req wkdb.MessageSearchReq) ([]wkdb.Message, error
 	return s.wdb.SearchMessages(req)
 }
 
+// AddMessageSearchIndex 添加消息检索索引（索引只保存在本地，不走分布式提案）
+func (s *Store) AddMessageSearchIndex(reqs []wkdb.MessageSearchIndexReq) error {
+	return s.wdb.AddMessageSearchIndex(reqs)
+}
+
+// SearchMessageIndex 通过关键词检索频道内的消息seq
+func (s *Store) SearchMessageIndex(channelId string, channelType uint8, terms []string, offsetMessageSeq uint64, limit int) ([]uint64, error) {
+	return s.wdb.SearchMessageIndex(channelId, channelType, terms, offsetMessageSeq, limit)
+}
+
+// SetMessageSearchIndexedSeq 设置频道在本节点已建立检索索引的消息seq
+func (s *Store) SetMessageSearchIndexedSeq(channelId string, channelType uint8, messageSeq uint64) error {
+	return s.wdb.SetMessageSearchIndexedSeq(channelId, channelType, messageSeq)
+}
+
+// GetMessageSearchIndexedSeq 获取频道在本节点已建立检索索引的消息seq
+func (s *Store) GetMessageSearchIndexedSeq(channelId string, channelType uint8) (uint64, error) {
+	return s.wdb.GetMessageSearchIndexedSeq(channelId, channelType)
+}
+
+// RemoveExpiredMessageSearchIndex 移除过期消息的检索索引
+func (s *Store) RemoveExpiredMessageSearchIndex(now uint64, limit int) (int, error) {
+	return s.wdb.RemoveExpiredMessageSearchIndex(now, limit)
+}
+
 // 获取频道的槽id
 func (s *Store) getChannelSlotId(channelId string) uint32 {
 	return wkutil.GetSlotNum(int(s.opts.SlotCount), channelId)
@@ -368,3 +393,15 @@ func IsUserOwnChannel(channelId string, channelType uint8) bool {
 	}
 	return false
 }
+
+func (s *Store) GetThread(channelId string, channelType uint8, rootMessageSeq uint64) (wkdb.Thread, error) {
+	return s.wdb.GetThread(channelId, channelType, rootMessageSeq)
+}
+
+func (s *Store) GetThreads(channelId string, channelType uint8, limit int) ([]wkdb.Thread, error) {
+	return s.wdb.GetThreads(channelId, channelType, limit)
+}
+
+func (s *Store) GetMessageExtras(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]wkdb.MessageExtra, error) {
+	return s.wdb.GetMessageExtras(channelId, channelType, startMessageSeq, endMessageSeq)
+}
@@ -0,0 +1,47 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddOrUpdateScheduledMessage 添加或更新定时消息，定时消息与目标频道存放在同一个槽内
+func (s *Store) AddOrUpdateScheduledMessage(m wkdb.ScheduledMessage) error {
+	data := EncodeCMDAddOrUpdateScheduledMessage(m)
+	cmd := NewCMD(CMDAddOrUpdateScheduledMessage, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(m.ChannelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// DeleteScheduledMessage 删除定时消息
+func (s *Store) DeleteScheduledMessage(channelId string, id uint64) error {
+	data := EncodeCMDDeleteScheduledMessage(id)
+	cmd := NewCMD(CMDDeleteScheduledMessage, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(channelId)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetScheduledMessage(id uint64) (wkdb.ScheduledMessage, error) {
+	return s.wdb.GetScheduledMessage(id)
+}
+
+func (s *Store) GetScheduledMessagesOfChannel(channelId string, channelType uint8) ([]wkdb.ScheduledMessage, error) {
+	return s.wdb.GetScheduledMessagesOfChannel(channelId, channelType)
+}
+
+// GetDueScheduledMessages 获取槽内已到发送时间的定时消息
+func (s *Store) GetDueScheduledMessages(slotId uint32, sendAt int64, limit int) ([]wkdb.ScheduledMessage, error) {
+	return s.wdb.GetDueScheduledMessages(slotId, sendAt, limit)
+}
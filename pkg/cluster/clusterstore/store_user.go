@@ -24,6 +24,11 @@ func (s *Store) GetUser(uid string) (wkdb.User, error) {
 	return s.wdb.GetUser(uid)
 }
 
+// GetUsersAfter 按主键顺序获取本节点上主键大于id的用户
+func (s *Store) GetUsersAfter(id uint64, limit int) ([]wkdb.User, error) {
+	return s.wdb.GetUsersAfter(id, limit)
+}
+
 func (s *Store) UpdateUser(u wkdb.User) error {
 	data := EncodeCMDUser(u)
 	cmd := NewCMD(CMDUpdateUser, data)
@@ -67,6 +72,51 @@ func (s *Store) GetDevice(uid string, deviceFlag wkproto.DeviceFlag) (wkdb.Devic
 	return s.wdb.GetDevice(uid, uint64(deviceFlag))
 }
 
+// AddRevokedToken 吊销令牌，吊销记录与用户存放在同一个槽内
+func (s *Store) GetDevices(uid string) ([]wkdb.Device, error) {
+	return s.wdb.GetDevices(uid)
+}
+
+func (s *Store) AddRevokedToken(t wkdb.RevokedToken) error {
+	data := EncodeCMDAddRevokedToken(t)
+	cmd := NewCMD(CMDAddRevokedToken, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(t.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) ExistRevokedToken(uid string, tokenId string) (bool, error) {
+	return s.wdb.ExistRevokedToken(uid, tokenId)
+}
+
+// RemoveExpiredRevokedTokens 移除过期的吊销记录（各副本在本地移除）
+func (s *Store) RemoveExpiredRevokedTokens(now uint64, limit int) (int, error) {
+	return s.wdb.RemoveExpiredRevokedTokens(now, limit)
+}
+
+// SetUserPresence 保存用户的在线状态，状态与用户存放在同一个槽内
+func (s *Store) SetUserPresence(p wkdb.UserPresence) error {
+	data := EncodeCMDSetUserPresence(p)
+	cmd := NewCMD(CMDSetUserPresence, data)
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	slotId := s.opts.GetSlotId(p.Uid)
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+func (s *Store) GetUserPresence(uid string) (wkdb.UserPresence, error) {
+	return s.wdb.GetUserPresence(uid)
+}
+
 func (s *Store) NextPrimaryKey() uint64 {
 	return s.wdb.NextPrimaryKey()
 }
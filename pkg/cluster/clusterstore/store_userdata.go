@@ -0,0 +1,57 @@
+package clusterstore
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"go.uber.org/zap"
+)
+
+// AddOrUpdateUserDataJob 添加或更新用户数据任务，任务存放在用户所在的槽内
+func (s *Store) AddOrUpdateUserDataJob(j wkdb.UserDataJob) error {
+	data := EncodeCMDAddOrUpdateUserDataJob(j)
+	return s.proposeUserDataCMD(j.SlotId, NewCMD(CMDAddOrUpdateUserDataJob, data))
+}
+
+func (s *Store) GetUserDataJob(slotId uint32, id uint64) (wkdb.UserDataJob, error) {
+	return s.wdb.GetUserDataJob(slotId, id)
+}
+
+func (s *Store) GetUserDataJobs(slotId uint32) ([]wkdb.UserDataJob, error) {
+	return s.wdb.GetUserDataJobs(slotId)
+}
+
+// RemoveUserDataJob 移除用户数据任务
+func (s *Store) RemoveUserDataJob(slotId uint32, id uint64) error {
+	data := EncodeCMDRemoveUserDataJob(slotId, id)
+	return s.proposeUserDataCMD(slotId, NewCMD(CMDRemoveUserDataJob, data))
+}
+
+// EraseUser 删除用户和用户所在槽内的数据（用户、设备、最近会话、用户属性）
+func (s *Store) EraseUser(uid string) error {
+	data := EncodeCMDEraseUser(uid)
+	return s.proposeUserDataCMD(s.opts.GetSlotId(uid), NewCMD(CMDEraseUser, data))
+}
+
+func (s *Store) proposeUserDataCMD(slotId uint32, cmd *CMD) error {
+	cmdData, err := cmd.Marshal()
+	if err != nil {
+		s.Error("marshal cmd failed", zap.Error(err))
+		return err
+	}
+	_, err = s.opts.Cluster.ProposeDataToSlot(s.ctx, slotId, cmdData)
+	return err
+}
+
+// GetSubscribedChannels 获取用户在本节点数据中订阅的频道（只查询本节点）
+func (s *Store) GetSubscribedChannels(uid string) ([]wkdb.Channel, error) {
+	return s.wdb.GetSubscribedChannels(uid)
+}
+
+// GetMessagesOfFromUid 分页获取本节点数据中用户发送的消息（只查询本节点）
+func (s *Store) GetMessagesOfFromUid(uid string, cursor wkdb.MessageCursor, limit int) ([]wkdb.Message, wkdb.MessageCursor, error) {
+	return s.wdb.GetMessagesOfFromUid(uid, cursor, limit)
+}
+
+// AnonymizeMessagesOfFromUid 匿名化本节点数据中用户发送的消息，频道的每个副本节点都需要执行
+func (s *Store) AnonymizeMessagesOfFromUid(uid string, limit int) (int, error) {
+	return s.wdb.AnonymizeMessagesOfFromUid(uid, limit)
+}
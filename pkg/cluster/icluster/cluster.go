@@ -5,6 +5,7 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/clusterconfig/pb"
 	"github.com/WuKongIM/WuKongIM/pkg/cluster/replica"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver"
 	"github.com/WuKongIM/WuKongIM/pkg/wkserver/proto"
 )
@@ -20,6 +21,12 @@ type Cluster interface {
 	LeaderOfChannel(ctx context.Context, channelId string, channelType uint8) (nodeInfo *pb.Node, err error)
 	// SlotLeaderIdOfChannel 获取channel的leader节点信息(不激活频道)
 	LeaderOfChannelForRead(channelId string, channelType uint8) (nodeInfo *pb.Node, err error)
+	// ChannelClusterConfigForRead 获取频道的分布式配置（不激活频道）
+	ChannelClusterConfigForRead(channelId string, channelType uint8) (wkdb.ChannelClusterConfig, error)
+	// LocalChannelCommittedIndex 本节点作为频道副本已提交的日志下标，不能提供副本读时返回false
+	LocalChannelCommittedIndex(channelId string, channelType uint8) (committedIndex uint64, ok bool)
+	// ChannelReadIndexes 向频道领导获取频道的读下标，副本已提交的日志下标达到读下标后才能提供读
+	ChannelReadIndexes(ctx context.Context, leaderId uint64, channels []wkdb.Channel) ([]uint64, error)
 	// SlotLeaderIdOfChannel 获取频道所属槽的领导
 	SlotLeaderIdOfChannel(channelId string, channelType uint8) (nodeId uint64, err error)
 	// SlotLeaderOfChannel 获取频道所属槽的领导
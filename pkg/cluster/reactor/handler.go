@@ -161,6 +161,14 @@ func (h *handler) didCommit(startLogIndex uint64, endLogIndex uint64) {
 	h.proposeWait.didCommit(startLogIndex, endLogIndex)
 }
 
+func (h *handler) didProposeFail(key string, err error) {
+	h.proposeWait.didFail(key, err)
+}
+
+func (h *handler) proposeErr(key string) error {
+	return h.proposeWait.takeErr(key)
+}
+
 func (h *handler) addWait(key string, ids []uint64) chan []ProposeResult {
 	return h.proposeWait.add(key, ids)
 }
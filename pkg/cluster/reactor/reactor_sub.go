@@ -102,6 +102,7 @@ func (r *ReactorSub) run() {
 			err := req.handler.handler.Step(replica.NewProposeMessageWithLogs(r.opts.NodeId, term, req.logs))
 			if err != nil {
 				r.Error("step propose message failed", zap.Error(err))
+				req.handler.didProposeFail(req.waitKey, err) // 提案没有被接受，不用等到超时
 			}
 
 		// case handler := <-r.storeAppendRespC:
@@ -189,7 +190,11 @@ func (r *ReactorSub) proposeAndWait(ctx context.Context, handleKey string, logs
 
 	// -------------------- 等待提案结果 --------------------
 	select {
-	case items := <-waitC:
+	case items, ok := <-waitC:
+		if !ok {
+			trace.GlobalTrace.Metrics.Cluster().ProposeFailedCountAdd(trace.ClusterKindChannel, 1)
+			return nil, handler.proposeErr(waitKey)
+		}
 		return items, nil
 	case <-timeoutCtx.Done():
 		handler.removeWait(waitKey)
@@ -15,6 +15,7 @@ type proposeWait struct {
 
 	proposeResultMap map[string][]ProposeResult
 	proposeWaitMap   map[string]chan []ProposeResult
+	proposeErrMap    map[string]error // 提案失败的原因
 	hasAdd           atomic.Bool
 }
 
@@ -23,6 +24,7 @@ func newProposeWait(key string) *proposeWait {
 		Log:              wklog.NewWKLog(fmt.Sprintf("proposeWait[%s]", key)),
 		proposeWaitMap:   make(map[string]chan []ProposeResult),
 		proposeResultMap: make(map[string][]ProposeResult),
+		proposeErrMap:    make(map[string]error),
 	}
 }
 
@@ -111,11 +113,35 @@ func (m *proposeWait) didCommit(startLogIndex uint64, endLogIndex uint64) {
 
 }
 
+// didFail 提案被副本拒绝（比如领导转移中），关闭等待通道让等待者立即返回
+func (m *proposeWait) didFail(key string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	waitC, ok := m.proposeWaitMap[key]
+	if !ok {
+		return
+	}
+	m.proposeErrMap[key] = err
+	close(waitC)
+	delete(m.proposeResultMap, key)
+	delete(m.proposeWaitMap, key)
+}
+
+// takeErr 获取并移除提案失败的原因
+func (m *proposeWait) takeErr(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.proposeErrMap[key]
+	delete(m.proposeErrMap, key)
+	return err
+}
+
 func (m *proposeWait) remove(key string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.proposeResultMap, key)
 	delete(m.proposeWaitMap, key)
+	delete(m.proposeErrMap, key)
 }
 
 func (m *proposeWait) exist(key string) bool {
@@ -41,6 +41,29 @@ func TestMessageWait(t *testing.T) {
 	}
 }
 
+func TestMessageWaitFail(t *testing.T) {
+	m := newProposeWait("test")
+	key := "test"
+	waitC := m.add(key, []uint64{1, 2})
+	m.didPropose(key, 1, 1)
+	m.didPropose(key, 2, 2)
+
+	m.didFail(key, ErrPausePropopose)
+
+	select {
+	case _, ok := <-waitC:
+		assert.False(t, ok)
+	default:
+		t.Fatal("should close")
+	}
+	assert.Equal(t, ErrPausePropopose, m.takeErr(key))
+	assert.False(t, m.exist(key))
+
+	// 失败后再提交不应该再通知
+	m.didCommit(1, 3)
+	assert.Nil(t, m.takeErr(key))
+}
+
 func BenchmarkMessageWait(b *testing.B) {
 	messageIds := make([]uint64, 0)
 	m := newProposeWait("test")
@@ -24,6 +24,7 @@ type Options struct {
 	LearnerToTimeoutTick       int     // 学习者转换为跟随者的超时tick次数，当超过这个次数将重新发起转换请求
 
 	FollowerToLeaderMinLogGap uint64 // 跟随者转换为领导者的最小日志差距，需要AutoRoleSwith开启 (当跟随者的日志与领导者的日志差距小于这个配置时，跟随者会转换为领导者)
+	StopProposeTimeoutTick    int    // 领导转移时停止提案的超时tick次数，超过这个次数目标节点还没追上日志，则领导恢复接受提案（避免目标节点异常导致频道一直无法写入）
 
 	RequestTimeoutTick int // 请求超时tick数
 
@@ -44,6 +45,7 @@ func NewOptions() *Options {
 		LearnerToLeaderMinLogGap:   100,
 		FollowerToLeaderMinLogGap:  100,
 		LearnerToTimeoutTick:       10,
+		StopProposeTimeoutTick:     20,
 		RequestTimeoutTick:         10,
 	}
 }
@@ -171,6 +173,12 @@ func WithFollowerToLeaderMinLogGap(gap uint64) Option {
 	}
 }
 
+func WithStopProposeTimeoutTick(tick int) Option {
+	return func(o *Options) {
+		o.StopProposeTimeoutTick = tick
+	}
+}
+
 func WithRequestTimeoutTick(tick int) Option {
 	return func(o *Options) {
 		o.RequestTimeoutTick = tick
@@ -27,6 +27,7 @@ type Replica struct {
 	uncommittedSize logEncodingSize // 未提交的日志大小
 
 	stopPropose                  bool // 是否停止提案
+	stopProposeTimeoutTick       int  // 停止提案的超时计时器
 	isRoleTransitioning          bool // 是否角色转换中
 	roleTransitioningTimeoutTick int  // 角色转换超时计时器
 
@@ -289,11 +290,21 @@ func (r *Replica) switchConfig(cfg Config) {
 	}
 }
 
+// startStopPropose 领导转移前停止接受提案，已提案的日志会继续同步给目标节点
+func (r *Replica) startStopPropose() {
+	if r.stopPropose {
+		return
+	}
+	r.stopPropose = true
+	r.stopProposeTimeoutTick = 0
+}
+
 func (r *Replica) initLeaderInfo() {
 
 	r.isRoleTransitioning = false
 	r.roleTransitioningTimeoutTick = 0
 	r.stopPropose = false
+	r.stopProposeTimeoutTick = 0
 
 	r.lastSyncInfoMap = make(map[uint64]*SyncInfo)
 	r.replicas = nil
@@ -403,6 +414,7 @@ func (r *Replica) reset(term uint32) {
 	r.votes = make(map[uint64]bool)
 	r.msgs = nil
 	r.stopPropose = false
+	r.stopProposeTimeoutTick = 0
 	r.isRoleTransitioning = false
 	r.roleTransitioningTimeoutTick = 0
 	r.leader = None
@@ -471,6 +483,14 @@ func (r *Replica) tickHeartbeat() {
 		if r.roleTransitioningTimeoutTick >= r.opts.LearnerToTimeoutTick {
 			r.isRoleTransitioning = false
 		}
+	} else if r.stopPropose {
+		r.stopProposeTimeoutTick++
+		// 目标节点迟迟追不上日志（比如目标节点掉线了），恢复接受提案，等目标节点追上后再停止提案
+		if r.stopProposeTimeoutTick >= r.opts.StopProposeTimeoutTick {
+			r.Warn("stop propose timeout, resume propose", zap.Uint64("migrateFrom", r.cfg.MigrateFrom), zap.Uint64("migrateTo", r.cfg.MigrateTo), zap.Uint64("lastLogIndex", r.replicaLog.lastLogIndex))
+			r.stopPropose = false
+			r.stopProposeTimeoutTick = 0
+		}
 	}
 
 	if r.opts.ElectionOn { // 是否开启自动选举
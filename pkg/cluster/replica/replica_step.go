@@ -200,7 +200,7 @@ func (r *Replica) stepLeader(m Message) error {
 						// 发送学习者转为领导者
 						r.send(r.newMsgLearnerToLeader(m.From))
 					} else if m.Index+r.opts.LearnerToLeaderMinLogGap > r.replicaLog.lastLogIndex { // 如果日志差距达到预期，则当前领导停止接受任何提案，等待学习者日志完全追赶上
-						r.stopPropose = true // 停止提案
+						r.startStopPropose() // 停止提案
 					}
 
 				} else { // 学习者转追随者
@@ -219,7 +219,7 @@ func (r *Replica) stepLeader(m Message) error {
 					// 发送追随者转为领导者
 					r.send(r.newFollowerToLeader(m.From))
 				} else if m.Index+r.opts.FollowerToLeaderMinLogGap > r.replicaLog.lastLogIndex { // 如果日志差距达到预期，则当前领导停止接受任何提案，等待学习者日志完全追赶上
-					r.stopPropose = true // 停止提案
+					r.startStopPropose() // 停止提案
 				}
 			}
 
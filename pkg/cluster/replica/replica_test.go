@@ -523,3 +523,45 @@ func TestFollowerToLeader(t *testing.T) {
 	assert.True(t, hasMsg(rd.Messages, MsgSyncResp))
 	assert.True(t, hasMsg(rd.Messages, MsgFollowerToLeader))
 }
+
+// 领导转移时目标节点一直追不上日志，超时后恢复接受提案
+func TestFollowerToLeaderStopProposeTimeout(t *testing.T) {
+	r := New(1, WithAutoRoleSwith(true), WithStopProposeTimeoutTick(3))
+
+	r.appendLog(Log{Index: 1, Term: 1, Data: []byte("hello")})
+	r.appendLog(Log{Index: 2, Term: 1, Data: []byte("world")})
+
+	rd := r.Ready()
+	assert.True(t, hasMsg(rd.Messages, MsgInit))
+
+	err := r.Step(Message{
+		MsgType: MsgInitResp,
+		Config: Config{
+			Role:        RoleLeader,
+			Term:        1,
+			Replicas:    []uint64{1, 2, 3},
+			MigrateFrom: 1,
+			MigrateTo:   3,
+		},
+	})
+	assert.NoError(t, err)
+
+	// 目标节点日志接近追上，领导停止提案
+	err = r.Step(Message{
+		MsgType: MsgSyncReq,
+		Index:   2,
+		From:    3,
+	})
+	assert.NoError(t, err)
+	_ = r.Ready()
+
+	err = r.Propose([]byte("hi"))
+	assert.Equal(t, ErrProposalDropped, err)
+
+	for i := 0; i < 3; i++ {
+		r.Tick()
+	}
+
+	err = r.Propose([]byte("hi"))
+	assert.NoError(t, err)
+}
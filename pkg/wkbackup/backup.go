@@ -0,0 +1,234 @@
+package wkbackup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 备份在目标中的结构：
+// <backupId>/manifest.json               集群备份清单
+// <backupId>/node<nodeId>/manifest.json  节点备份清单
+// <backupId>/node<nodeId>/<path>         节点数据文件，path为相对节点数据目录的路径
+const manifestName = "manifest.json"
+
+var (
+	ErrBackupNotFound  = errors.New("backup not found")
+	ErrNodeNotInBackup = errors.New("node not in backup")
+	ErrDataDirNotEmpty = errors.New("data dir is not empty")
+)
+
+// Manifest 集群备份清单
+type Manifest struct {
+	BackupId      string          `json:"backup_id"`      // 备份id
+	CreatedAt     time.Time       `json:"created_at"`     // 备份开始时间
+	AppVersion    string          `json:"app_version"`    // 应用版本
+	ConfigVersion uint64          `json:"config_version"` // 集群配置版本
+	Nodes         []*NodeManifest `json:"nodes"`          // 节点备份清单
+}
+
+// Node 获取节点的备份清单
+func (m *Manifest) Node(nodeId uint64) *NodeManifest {
+	for _, n := range m.Nodes {
+		if n.NodeId == nodeId {
+			return n
+		}
+	}
+	return nil
+}
+
+// NodeManifest 节点备份清单
+type NodeManifest struct {
+	NodeId    uint64     `json:"node_id"`    // 节点id
+	CreatedAt time.Time  `json:"created_at"` // 快照时间
+	Files     []FileInfo `json:"files"`      // 数据文件
+}
+
+// Size 备份数据的总大小
+func (n *NodeManifest) Size() int64 {
+	var size int64
+	for _, f := range n.Files {
+		size += f.Size
+	}
+	return size
+}
+
+type FileInfo struct {
+	Path string `json:"path"` // 相对节点数据目录的路径（“/”分隔）
+	Size int64  `json:"size"` // 文件大小
+}
+
+// NewBackupId 根据备份时间生成备份id，按字符串排序与时间顺序一致
+func NewBackupId(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func nodePrefix(backupId string, nodeId uint64) string {
+	return fmt.Sprintf("%s/node%d", backupId, nodeId)
+}
+
+// UploadNode 将节点的快照目录上传到备份目标，并写入节点备份清单
+func UploadNode(ctx context.Context, target Target, backupId string, nodeId uint64, dir string) (*NodeManifest, error) {
+	nodeManifest := &NodeManifest{
+		NodeId:    nodeId,
+		CreatedAt: time.Now(),
+		Files:     make([]FileInfo, 0),
+	}
+	prefix := nodePrefix(backupId, nodeId)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err = target.Put(ctx, prefix+"/"+rel, f); err != nil {
+			return err
+		}
+		nodeManifest.Files = append(nodeManifest.Files, FileInfo{Path: rel, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = putJSON(ctx, target, prefix+"/"+manifestName, nodeManifest); err != nil {
+		return nil, err
+	}
+	return nodeManifest, nil
+}
+
+// WriteManifest 写入集群备份清单，所有节点都上传完成后才写入，清单存在表示备份完整
+func WriteManifest(ctx context.Context, target Target, m *Manifest) error {
+	return putJSON(ctx, target, m.BackupId+"/"+manifestName, m)
+}
+
+// ReadManifest 读取集群备份清单
+func ReadManifest(ctx context.Context, target Target, backupId string) (*Manifest, error) {
+	m := &Manifest{}
+	if err := getJSON(ctx, target, backupId+"/"+manifestName, m); err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrBackupNotFound
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// ListBackups 列出所有完整的备份，按备份时间从早到晚排序
+func ListBackups(ctx context.Context, target Target) ([]*Manifest, error) {
+	keys, err := target.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	manifests := make([]*Manifest, 0)
+	for _, key := range keys {
+		backupId, name, ok := strings.Cut(key, "/")
+		if !ok || name != manifestName {
+			continue
+		}
+		m, err := ReadManifest(ctx, target, backupId)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.Before(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// PickBackup 选择指定时间点（包含）之前最近的一次备份，manifests需要按时间排序
+func PickBackup(manifests []*Manifest, before time.Time) *Manifest {
+	var picked *Manifest
+	for _, m := range manifests {
+		if m.CreatedAt.After(before) {
+			break
+		}
+		picked = m
+	}
+	return picked
+}
+
+// RestoreNode 将节点的备份数据下载到节点的数据目录，数据目录中不能已存在数据
+func RestoreNode(ctx context.Context, target Target, m *Manifest, nodeId uint64, dataDir string) error {
+	nodeManifest := m.Node(nodeId)
+	if nodeManifest == nil {
+		return ErrNodeNotInBackup
+	}
+	for _, f := range nodeManifest.Files {
+		top, _, _ := strings.Cut(f.Path, "/")
+		if _, err := os.Stat(filepath.Join(dataDir, top)); err == nil {
+			return fmt.Errorf("%w: %s exists", ErrDataDirNotEmpty, filepath.Join(dataDir, top))
+		}
+	}
+	prefix := nodePrefix(m.BackupId, nodeId)
+	for _, f := range nodeManifest.Files {
+		if err := downloadFile(ctx, target, prefix+"/"+f.Path, filepath.Join(dataDir, filepath.FromSlash(f.Path)), f.Size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadFile(ctx context.Context, target Target, key string, dst string, size int64) error {
+	r, err := target.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err = os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("backup file %s size mismatch, expected %d, actual %d", key, size, n)
+	}
+	return nil
+}
+
+func putJSON(ctx context.Context, target Target, key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return target.Put(ctx, key, bytes.NewReader(data))
+}
+
+func getJSON(ctx context.Context, target Target, key string, v interface{}) error {
+	r, err := target.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
@@ -0,0 +1,96 @@
+package wkbackup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	target, err := NewTarget("file://"+t.TempDir(), S3Options{})
+	assert.NoError(t, err)
+
+	// 模拟节点快照目录
+	snapshotDir := t.TempDir()
+	writeTestFile(t, filepath.Join(snapshotDir, "db", "wukongimdb", "shard000", "000001.sst"), "sst")
+	writeTestFile(t, filepath.Join(snapshotDir, "cluster", "config", "remote.json"), "{}")
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	backupId := NewBackupId(created)
+	assert.Equal(t, "20240102T030405Z", backupId)
+
+	nodeManifest, err := UploadNode(ctx, target, backupId, 1, snapshotDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(nodeManifest.Files))
+	assert.Equal(t, int64(5), nodeManifest.Size())
+
+	// 节点清单写入了，但集群清单没写入前备份是不完整的
+	manifests, err := ListBackups(ctx, target)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(manifests))
+
+	err = WriteManifest(ctx, target, &Manifest{BackupId: backupId, CreatedAt: created, Nodes: []*NodeManifest{nodeManifest}})
+	assert.NoError(t, err)
+
+	manifests, err = ListBackups(ctx, target)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(manifests))
+	assert.Equal(t, backupId, manifests[0].BackupId)
+
+	dataDir := t.TempDir()
+	err = RestoreNode(ctx, target, manifests[0], 1, dataDir)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dataDir, "db", "wukongimdb", "shard000", "000001.sst"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sst", string(data))
+
+	// 数据目录已经有数据时不能恢复
+	err = RestoreNode(ctx, target, manifests[0], 1, dataDir)
+	assert.True(t, errors.Is(err, ErrDataDirNotEmpty))
+
+	err = RestoreNode(ctx, target, manifests[0], 2, t.TempDir())
+	assert.Equal(t, ErrNodeNotInBackup, err)
+}
+
+func TestPickBackup(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manifests := []*Manifest{
+		{BackupId: "a", CreatedAt: base},
+		{BackupId: "b", CreatedAt: base.Add(time.Hour)},
+		{BackupId: "c", CreatedAt: base.Add(2 * time.Hour)},
+	}
+	assert.Nil(t, PickBackup(manifests, base.Add(-time.Second)))
+	assert.Equal(t, "a", PickBackup(manifests, base.Add(time.Minute)).BackupId)
+	assert.Equal(t, "b", PickBackup(manifests, base.Add(time.Hour)).BackupId)
+	assert.Equal(t, "c", PickBackup(manifests, base.Add(24*time.Hour)).BackupId)
+}
+
+func writeTestFile(t *testing.T, p string, content string) {
+	err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+	assert.NoError(t, err)
+	err = os.WriteFile(p, []byte(content), os.ModePerm)
+	assert.NoError(t, err)
+}
+
+func TestNewTarget(t *testing.T) {
+	target, err := NewTarget("/data/backup", S3Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/data/backup", target.(*LocalTarget).root)
+
+	target, err = NewTarget("s3://wukongim/backup/prod", S3Options{Endpoint: "http://127.0.0.1:9000", ForcePathStyle: true})
+	assert.NoError(t, err)
+	s3t := target.(*s3Target)
+	assert.Equal(t, "wukongim", s3t.bucket)
+	assert.Equal(t, "backup/prod/20240102T030405Z/manifest.json", s3t.objectKey("20240102T030405Z/manifest.json"))
+
+	_, err = NewTarget("", S3Options{})
+	assert.Equal(t, ErrTargetEmpty, err)
+	_, err = NewTarget("ftp://host/backup", S3Options{})
+	assert.Error(t, err)
+}
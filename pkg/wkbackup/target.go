@@ -0,0 +1,128 @@
+package wkbackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrTargetEmpty = errors.New("backup target is empty")
+
+// Target 备份存储目标，key使用“/”分隔
+type Target interface {
+	// Put 写入对象
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get 读取对象
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List 列出指定前缀下的所有对象key
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// S3Options S3兼容存储的配置
+type S3Options struct {
+	Endpoint        string // 服务地址，例如：http://127.0.0.1:9000 （为空时使用AWS官方地址）
+	Region          string // 区域
+	AccessKeyID     string
+	SecretAccessKey string
+	ForcePathStyle  bool // 是否使用路径风格的地址（minio等需要开启）
+}
+
+// NewTarget 根据地址创建备份目标
+// 本地目录：/data/backup 或 file:///data/backup
+// S3兼容存储：s3://bucket/prefix
+func NewTarget(rawURL string, s3Opts S3Options) (Target, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, ErrTargetEmpty
+	}
+	if !strings.Contains(rawURL, "://") {
+		return NewLocalTarget(rawURL), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		return NewLocalTarget(u.Path), nil
+	case "s3":
+		return newS3Target(u.Host, strings.Trim(u.Path, "/"), s3Opts)
+	default:
+		return nil, fmt.Errorf("unsupported backup target scheme: %s", u.Scheme)
+	}
+}
+
+// LocalTarget 本地目录（也可以是挂载的共享目录）
+type LocalTarget struct {
+	root string
+}
+
+func NewLocalTarget(root string) *LocalTarget {
+	return &LocalTarget{root: root}
+}
+
+func (l *LocalTarget) Put(ctx context.Context, key string, r io.Reader) error {
+	p := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免读到不完整的对象
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+func (l *LocalTarget) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(l.path(key))
+}
+
+func (l *LocalTarget) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := filepath.Walk(l.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (l *LocalTarget) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
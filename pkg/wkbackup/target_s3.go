@@ -0,0 +1,102 @@
+package wkbackup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// s3Target S3兼容的对象存储，对象key为 prefix/key
+type s3Target struct {
+	bucket   string
+	prefix   string
+	client   *s3.S3
+	uploader *s3manager.Uploader
+}
+
+func newS3Target(bucket, prefix string, opts S3Options) (*s3Target, error) {
+	if bucket == "" {
+		return nil, errors.New("s3 backup target bucket is empty")
+	}
+	cfg := &aws.Config{
+		S3ForcePathStyle: aws.Bool(opts.ForcePathStyle),
+	}
+	if opts.Endpoint != "" {
+		cfg.Endpoint = aws.String(opts.Endpoint)
+	}
+	region := opts.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	cfg.Region = aws.String(region)
+	if opts.AccessKeyID != "" {
+		cfg.Credentials = credentials.NewStaticCredentials(opts.AccessKeyID, opts.SecretAccessKey, "")
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Target{
+		bucket:   bucket,
+		prefix:   prefix,
+		client:   s3.New(sess),
+		uploader: s3manager.NewUploader(sess),
+	}, nil
+}
+
+func (s *s3Target) Put(ctx context.Context, key string, r io.Reader) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   r,
+	})
+	return err
+}
+
+func (s *s3Target) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *s3Target) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.objectKey(prefix)),
+	}, func(out *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range out.Contents {
+			key := aws.StringValue(obj.Key)
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			keys = append(keys, key)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *s3Target) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
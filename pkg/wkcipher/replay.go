@@ -0,0 +1,34 @@
+package wkcipher
+
+const replayWindowSize = 64
+
+// replayWindow 滑动窗口记录已收到的序号，允许窗口内的乱序，拒绝重复和比窗口更旧的序号
+type replayWindow struct {
+	max    uint64 // 收到的最大序号
+	bitmap uint64 // 第i位表示序号max-i是否已收到
+}
+
+func (w *replayWindow) check(seq uint64) bool {
+	if seq > w.max {
+		return true
+	}
+	diff := w.max - seq
+	if diff >= replayWindowSize {
+		return false
+	}
+	return w.bitmap&(1<<diff) == 0
+}
+
+func (w *replayWindow) update(seq uint64) {
+	if seq > w.max {
+		shift := seq - w.max
+		if shift >= replayWindowSize {
+			w.bitmap = 1
+		} else {
+			w.bitmap = w.bitmap<<shift | 1
+		}
+		w.max = seq
+		return
+	}
+	w.bitmap |= 1 << (w.max - seq)
+}
@@ -0,0 +1,346 @@
+package wkcipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Version 客户端CONNECT的协议版本大于等于此版本时可以协商AEAD加密，协商成功后CONNACK的ServerVersion为此版本
+// 此版本的包编码和4版本相同
+const Version = 5
+
+// Suite 连接的加密方式
+type Suite uint8
+
+const (
+	SuiteLegacy           Suite = iota // AES-CBC(PKCS7)，MsgKey为MD5，密钥和IV在整个连接内不变（旧版本客户端）
+	SuiteAES256GCM                     // AES-256-GCM
+	SuiteChaCha20Poly1305              // ChaCha20-Poly1305
+)
+
+func (s Suite) String() string {
+	switch s {
+	case SuiteLegacy:
+		return "legacy"
+	case SuiteAES256GCM:
+		return "aes-256-gcm"
+	case SuiteChaCha20Poly1305:
+		return "chacha20-poly1305"
+	}
+	return fmt.Sprintf("unknown[%d]", s)
+}
+
+// IsAEAD 是否是AEAD加密
+func (s Suite) IsAEAD() bool {
+	return s == SuiteAES256GCM || s == SuiteChaCha20Poly1305
+}
+
+// Role 会话的角色，客户端和服务端的发送方向使用不同的密钥
+type Role uint8
+
+const (
+	RoleServer Role = iota
+	RoleClient
+)
+
+const (
+	frameVersion    = 1
+	frameHeaderSize = 1 + 4 + 4 + 8 // 版本 + 密钥周期 + 发送流 + 序号
+	keySize         = 32
+	maxRecvStreams  = 16 // 每个会话最多跟踪的发送流数量
+	maxRecvEpochs   = 4  // 每个会话最多缓存的接收密钥数量
+
+	directionC2S = "c2s"
+	directionS2C = "s2c"
+)
+
+var (
+	ErrFrameTooShort    = errors.New("wkcipher: frame too short")
+	ErrFrameVersion     = errors.New("wkcipher: unsupported frame version")
+	ErrReplay           = errors.New("wkcipher: replayed or too old sequence")
+	ErrUnsupportedSuite = errors.New("wkcipher: unsupported suite")
+)
+
+// Options 会话配置
+type Options struct {
+	RekeyMessages uint64        // 每个密钥周期最多加密的消息数量，超过后更换密钥，0表示不按数量更换
+	RekeyInterval time.Duration // 每个密钥周期的最长时间，超过后更换密钥，0表示不按时间更换
+}
+
+// ParseClientKey 解析CONNECT的ClientKey，前32字节为客户端的DH公钥，支持AEAD的客户端在公钥后追加1字节期望的加密方式
+func ParseClientKey(clientKey string) (pubKey [32]byte, suite Suite, err error) {
+	data, err := base64.StdEncoding.DecodeString(clientKey)
+	if err != nil {
+		return
+	}
+	if len(data) < 32 {
+		err = errors.New("wkcipher: client key too short")
+		return
+	}
+	copy(pubKey[:], data[:32])
+	suite = SuiteAES256GCM
+	if len(data) > 32 {
+		suite = Suite(data[32])
+	}
+	return
+}
+
+// EncodeClientKey 编码CONNECT的ClientKey，suite为SuiteLegacy时只有公钥
+func EncodeClientKey(pubKey [32]byte, suite Suite) string {
+	data := pubKey[:]
+	if suite != SuiteLegacy {
+		data = append(append([]byte{}, pubKey[:]...), byte(suite))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// DeriveMasterKey 由DH共享密钥和CONNACK的salt派生连接的主密钥，各个密钥周期的密钥都由主密钥派生
+func DeriveMasterKey(sharedKey []byte, salt []byte) []byte {
+	return deriveKey(sharedKey, salt, []byte("wukongim transport master"))
+}
+
+// Session 连接的AEAD加密会话
+// 加密后的数据为base64(版本(1) | 密钥周期(4) | 发送流(4) | 序号(8) | 密文和认证标签)，头部作为附加认证数据
+// 每个发送方向使用独立的密钥，同一个方向上的发送流（例如集群里不同节点上的同一个连接）使用随机的流id区分，nonce为流id和序号
+// 接收方按发送流记录已收到的序号，重复或过旧的序号会被拒绝
+type Session struct {
+	suite     Suite
+	masterKey []byte
+	opts      Options
+	sendDir   string
+	recvDir   string
+
+	sendMacKey []byte
+	recvMacKey []byte
+
+	sendMu     sync.Mutex
+	streamId   uint32
+	sendSeq    uint64
+	sendEpoch  uint32
+	epochCount uint64    // 当前密钥周期已加密的消息数量
+	epochStart time.Time // 当前密钥周期的开始时间
+	sendAEAD   cipher.AEAD
+
+	recvMu      sync.Mutex
+	recvAEADs   map[uint32]cipher.AEAD
+	replays     map[uint32]*replayWindow
+	streamOrder []uint32
+}
+
+// NewSession 创建加密会话，masterKey由DeriveMasterKey生成
+func NewSession(suite Suite, masterKey []byte, role Role, opts Options) (*Session, error) {
+	if !suite.IsAEAD() {
+		return nil, ErrUnsupportedSuite
+	}
+	if len(masterKey) != keySize {
+		return nil, fmt.Errorf("wkcipher: master key must be %d bytes", keySize)
+	}
+	s := &Session{
+		suite:     suite,
+		masterKey: append([]byte(nil), masterKey...),
+		opts:      opts,
+		sendDir:   directionS2C,
+		recvDir:   directionC2S,
+		recvAEADs: make(map[uint32]cipher.AEAD),
+		replays:   make(map[uint32]*replayWindow),
+	}
+	if role == RoleClient {
+		s.sendDir, s.recvDir = directionC2S, directionS2C
+	}
+	s.sendMacKey = deriveKey(s.masterKey, nil, []byte("wukongim transport "+s.sendDir+" mac"))
+	s.recvMacKey = deriveKey(s.masterKey, nil, []byte("wukongim transport "+s.recvDir+" mac"))
+
+	var streamId [4]byte
+	if _, err := io.ReadFull(rand.Reader, streamId[:]); err != nil {
+		return nil, err
+	}
+	s.streamId = binary.BigEndian.Uint32(streamId[:])
+
+	aead, err := s.newAEAD(s.sendDir, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.sendAEAD = aead
+	s.epochStart = time.Now()
+	return s, nil
+}
+
+func (s *Session) Suite() Suite {
+	return s.suite
+}
+
+// MasterKey 主密钥，用于把会话同步到连接所在的其他节点
+func (s *Session) MasterKey() []byte {
+	return s.masterKey
+}
+
+// Encrypt 加密数据，返回base64编码的结果
+func (s *Session) Encrypt(plaintext []byte) ([]byte, error) {
+	s.sendMu.Lock()
+	if s.needRekey() {
+		aead, err := s.newAEAD(s.sendDir, s.sendEpoch+1)
+		if err != nil {
+			s.sendMu.Unlock()
+			return nil, err
+		}
+		s.sendEpoch++
+		s.sendAEAD = aead
+		s.epochCount = 0
+		s.epochStart = time.Now()
+	}
+	s.sendSeq++
+	s.epochCount++
+	seq, epoch, aead := s.sendSeq, s.sendEpoch, s.sendAEAD
+	s.sendMu.Unlock()
+
+	frame := make([]byte, frameHeaderSize, frameHeaderSize+len(plaintext)+aead.Overhead())
+	frame[0] = frameVersion
+	binary.BigEndian.PutUint32(frame[1:5], epoch)
+	binary.BigEndian.PutUint32(frame[5:9], s.streamId)
+	binary.BigEndian.PutUint64(frame[9:17], seq)
+	frame = aead.Seal(frame, frame[5:17], plaintext, frame[:frameHeaderSize])
+
+	dst := make([]byte, base64.StdEncoding.EncodedLen(len(frame)))
+	base64.StdEncoding.Encode(dst, frame)
+	return dst, nil
+}
+
+// Decrypt 解密Encrypt加密的数据，重复或过旧的序号返回ErrReplay
+func (s *Session) Decrypt(data []byte) ([]byte, error) {
+	frame := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(frame, data)
+	if err != nil {
+		return nil, err
+	}
+	frame = frame[:n]
+	if len(frame) < frameHeaderSize {
+		return nil, ErrFrameTooShort
+	}
+	if frame[0] != frameVersion {
+		return nil, ErrFrameVersion
+	}
+	epoch := binary.BigEndian.Uint32(frame[1:5])
+	streamId := binary.BigEndian.Uint32(frame[5:9])
+	seq := binary.BigEndian.Uint64(frame[9:17])
+
+	s.recvMu.Lock()
+	defer s.recvMu.Unlock()
+
+	replay := s.replays[streamId]
+	if seq == 0 || (replay != nil && !replay.check(seq)) {
+		return nil, ErrReplay
+	}
+	aead, err := s.recvAEAD(epoch)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, frame[5:17], frame[frameHeaderSize:], frame[:frameHeaderSize])
+	if err != nil {
+		return nil, err
+	}
+	// 认证通过后才记录序号，防止伪造的数据影响正常的消息
+	if replay == nil {
+		replay = s.addReplayWindow(streamId)
+	}
+	replay.update(seq)
+	return plaintext, nil
+}
+
+// Sign 对发送的数据签名（替代旧版本的MsgKey）
+func (s *Session) Sign(signStr string) string {
+	return sign(s.sendMacKey, signStr)
+}
+
+// Verify 验证接收的数据的签名
+func (s *Session) Verify(signStr string, msgKey string) bool {
+	return hmac.Equal([]byte(sign(s.recvMacKey, signStr)), []byte(msgKey))
+}
+
+func (s *Session) needRekey() bool {
+	if s.epochCount == 0 {
+		return false
+	}
+	if s.opts.RekeyMessages > 0 && s.epochCount >= s.opts.RekeyMessages {
+		return true
+	}
+	return s.opts.RekeyInterval > 0 && time.Since(s.epochStart) >= s.opts.RekeyInterval
+}
+
+func (s *Session) recvAEAD(epoch uint32) (cipher.AEAD, error) {
+	if aead, ok := s.recvAEADs[epoch]; ok {
+		return aead, nil
+	}
+	aead, err := s.newAEAD(s.recvDir, epoch)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.recvAEADs) >= maxRecvEpochs { // 淘汰最旧的密钥周期
+		var oldest uint32
+		first := true
+		for e := range s.recvAEADs {
+			if first || e < oldest {
+				oldest = e
+				first = false
+			}
+		}
+		delete(s.recvAEADs, oldest)
+	}
+	s.recvAEADs[epoch] = aead
+	return aead, nil
+}
+
+func (s *Session) addReplayWindow(streamId uint32) *replayWindow {
+	if len(s.streamOrder) >= maxRecvStreams { // 淘汰最早的发送流
+		delete(s.replays, s.streamOrder[0])
+		s.streamOrder = s.streamOrder[1:]
+	}
+	w := &replayWindow{}
+	s.replays[streamId] = w
+	s.streamOrder = append(s.streamOrder, streamId)
+	return w
+}
+
+func (s *Session) newAEAD(direction string, epoch uint32) (cipher.AEAD, error) {
+	info := make([]byte, 0, 32)
+	info = append(info, "wukongim transport "+direction+" key"...)
+	info = binary.BigEndian.AppendUint32(info, epoch)
+	key := deriveKey(s.masterKey, nil, info)
+	switch s.suite {
+	case SuiteAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case SuiteChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	}
+	return nil, ErrUnsupportedSuite
+}
+
+func deriveKey(secret, salt, info []byte) []byte {
+	key := make([]byte, keySize)
+	// 读取的长度远小于hkdf的上限，不会出错
+	_, _ = io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key)
+	return key
+}
+
+func sign(key []byte, signStr string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signStr))
+	return hex.EncodeToString(mac.Sum(nil))
+}
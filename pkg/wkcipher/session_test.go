@@ -0,0 +1,131 @@
+package wkcipher
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSessions(t *testing.T, suite Suite, opts Options) (*Session, *Session) {
+	masterKey := DeriveMasterKey(bytes.Repeat([]byte{1}, 32), []byte("salt"))
+	server, err := NewSession(suite, masterKey, RoleServer, opts)
+	assert.NoError(t, err)
+	client, err := NewSession(suite, masterKey, RoleClient, opts)
+	assert.NoError(t, err)
+	return server, client
+}
+
+func TestSessionEncryptDecrypt(t *testing.T) {
+	for _, suite := range []Suite{SuiteAES256GCM, SuiteChaCha20Poly1305} {
+		server, client := newTestSessions(t, suite, Options{})
+
+		data, err := client.Encrypt([]byte("hello"))
+		assert.NoError(t, err)
+		plaintext, err := server.Decrypt(data)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), plaintext)
+
+		data, err = server.Encrypt([]byte("world"))
+		assert.NoError(t, err)
+		plaintext, err = client.Decrypt(data)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("world"), plaintext)
+
+		// 发送方向的密钥不同，不能解密自己发送的数据
+		data, err = server.Encrypt([]byte("world"))
+		assert.NoError(t, err)
+		_, err = server.Decrypt(data)
+		assert.Error(t, err)
+
+		assert.True(t, server.Verify("sign", client.Sign("sign")))
+		assert.False(t, server.Verify("sign", server.Sign("sign")))
+	}
+}
+
+func TestSessionReplay(t *testing.T) {
+	server, client := newTestSessions(t, SuiteAES256GCM, Options{})
+
+	first, err := client.Encrypt([]byte("1"))
+	assert.NoError(t, err)
+	second, err := client.Encrypt([]byte("2"))
+	assert.NoError(t, err)
+
+	// 窗口内允许乱序
+	_, err = server.Decrypt(second)
+	assert.NoError(t, err)
+	_, err = server.Decrypt(first)
+	assert.NoError(t, err)
+
+	// 重复的序号被拒绝
+	_, err = server.Decrypt(second)
+	assert.Equal(t, ErrReplay, err)
+
+	// 比窗口更旧的序号被拒绝
+	old, err := client.Encrypt([]byte("old"))
+	assert.NoError(t, err)
+	var last []byte
+	for i := 0; i < replayWindowSize+1; i++ {
+		last, err = client.Encrypt([]byte("new"))
+		assert.NoError(t, err)
+	}
+	_, err = server.Decrypt(last)
+	assert.NoError(t, err)
+	_, err = server.Decrypt(old)
+	assert.Equal(t, ErrReplay, err)
+}
+
+func TestSessionTampered(t *testing.T) {
+	server, client := newTestSessions(t, SuiteChaCha20Poly1305, Options{})
+
+	data, err := client.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-5] ^= 0x01
+	_, err = server.Decrypt(tampered)
+	assert.Error(t, err)
+
+	// 被篡改的数据不影响后续的正常数据
+	plaintext, err := server.Decrypt(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), plaintext)
+}
+
+func TestSessionRekey(t *testing.T) {
+	server, client := newTestSessions(t, SuiteAES256GCM, Options{RekeyMessages: 2})
+
+	for i := 0; i < 5; i++ {
+		data, err := client.Encrypt([]byte("hello"))
+		assert.NoError(t, err)
+		plaintext, err := server.Decrypt(data)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("hello"), plaintext)
+	}
+	assert.Equal(t, uint32(2), client.sendEpoch)
+
+	server, client = newTestSessions(t, SuiteAES256GCM, Options{RekeyInterval: time.Millisecond})
+	_, err := client.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 2)
+	data, err := client.Encrypt([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), client.sendEpoch)
+	_, err = server.Decrypt(data)
+	assert.NoError(t, err)
+}
+
+func TestParseClientKey(t *testing.T) {
+	var pubKey [32]byte
+	pubKey[0] = 9
+
+	key, suite, err := ParseClientKey(EncodeClientKey(pubKey, SuiteLegacy))
+	assert.NoError(t, err)
+	assert.Equal(t, pubKey, key)
+	assert.Equal(t, SuiteAES256GCM, suite) // 没有指定时默认AES-256-GCM
+
+	key, suite, err = ParseClientKey(EncodeClientKey(pubKey, SuiteChaCha20Poly1305))
+	assert.NoError(t, err)
+	assert.Equal(t, pubKey, key)
+	assert.Equal(t, SuiteChaCha20Poly1305, suite)
+}
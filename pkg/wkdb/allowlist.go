@@ -5,7 +5,6 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
 
@@ -37,7 +36,7 @@ func (wk *wukongDB) AddAllowlist(channelId string, channelType uint8, members []
 }
 
 func (wk *wukongDB) GetAllowlist(channelId string, channelType uint8) ([]Member, error) {
-	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
 		LowerBound: key.NewAllowlistPrimaryKey(channelId, channelType, 0),
 		UpperBound: key.NewAllowlistPrimaryKey(channelId, channelType, math.MaxUint64),
 	})
@@ -51,7 +50,7 @@ func (wk *wukongDB) GetAllowlist(channelId string, channelType uint8) ([]Member,
 }
 
 func (wk *wukongDB) HasAllowlist(channelId string, channelType uint8) (bool, error) {
-	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
 		LowerBound: key.NewAllowlistPrimaryKey(channelId, channelType, 0),
 		UpperBound: key.NewAllowlistPrimaryKey(channelId, channelType, math.MaxUint64),
 	})
@@ -71,7 +70,7 @@ func (wk *wukongDB) ExistAllowlist(channeId string, channelType uint8, uid strin
 		defer closer.Close()
 	}
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return false, nil
 		}
 		return false, err
@@ -90,7 +89,7 @@ func (wk *wukongDB) RemoveAllowlist(channelId string, channelType uint8, uids []
 
 	members, err := wk.getAllowlistByUids(channelId, channelType, uids)
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return nil
 		}
 		return err
@@ -146,7 +145,7 @@ func (wk *wukongDB) RemoveAllAllowlist(channelId string, channelType uint8) erro
 	return batch.Commit(wk.sync)
 }
 
-func (wk *wukongDB) removeAllowlist(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) removeAllowlist(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -168,7 +167,7 @@ func (wk *wukongDB) getAllowlistByUids(channelId string, channelType uint8, uids
 	db := wk.channelDb(channelId, channelType)
 	for _, uid := range uids {
 		id := key.HashWithString(uid)
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewAllowlistColumnKey(channelId, channelType, id, key.MinColumnKey),
 			UpperBound: key.NewAllowlistColumnKey(channelId, channelType, id, key.MaxColumnKey),
 		})
@@ -186,7 +185,7 @@ func (wk *wukongDB) getAllowlistByUids(channelId string, channelType uint8, uids
 	return members, nil
 }
 
-func (wk *wukongDB) writeAllowlist(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) writeAllowlist(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -236,7 +235,7 @@ func (wk *wukongDB) writeAllowlist(channelId string, channelType uint8, member M
 
 }
 
-func (wk *wukongDB) iterateAllowlist(iter *pebble.Iterator, iterFnc func(member Member) bool) error {
+func (wk *wukongDB) iterateAllowlist(iter Iterator, iterFnc func(member Member) bool) error {
 	var (
 		preId          uint64
 		preMember      Member
@@ -288,13 +287,13 @@ func (wk *wukongDB) iterateAllowlist(iter *pebble.Iterator, iterFnc func(member
 }
 
 // 增加频道白名单数量
-func (wk *wukongDB) incChannelInfoAllowlistCount(id uint64, count int, db *pebble.Batch) error {
+func (wk *wukongDB) incChannelInfoAllowlistCount(id uint64, count int, db Batch) error {
 	wk.dblock.allowlistCountLock.lock(id)
 	defer wk.dblock.allowlistCountLock.unlock(id)
 	return wk.incChannelInfoColumnCount(id, key.TableChannelInfo.Column.AllowlistCount, key.TableChannelInfo.SecondIndex.AllowlistCount, count, db)
 }
 
-func (wk *wukongDB) deleteAllAllowlistIndex(channelId string, channelType uint8, w pebble.Writer) error {
+func (wk *wukongDB) deleteAllAllowlistIndex(channelId string, channelType uint8, w Writer) error {
 	var err error
 	// uid index
 	if err = w.DeleteRange(key.NewAllowlistIndexKey(channelId, channelType, key.TableAllowlist.Index.Uid, 0), key.NewAllowlistIndexKey(channelId, channelType, key.TableAllowlist.Index.Uid, math.MaxUint64), wk.noSync); err != nil {
@@ -314,7 +313,7 @@ func (wk *wukongDB) deleteAllAllowlistIndex(channelId string, channelType uint8,
 	return nil
 }
 
-func (wk *wukongDB) deleteAllowlistIndex(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) deleteAllowlistIndex(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
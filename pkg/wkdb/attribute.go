@@ -0,0 +1,298 @@
+package wkdb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"go.uber.org/zap"
+)
+
+// attributeColumns 用户属性和频道属性的列
+type attributeColumns struct {
+	Name      [2]byte
+	Type      [2]byte
+	Value     [2]byte
+	Version   [2]byte
+	UpdatedAt [2]byte
+}
+
+// attributeTable 用户属性和频道属性的表结构一致，只是表和所属的对象不同
+type attributeTable struct {
+	db        KV
+	columns   attributeColumns
+	columnKey func(name string, columnName [2]byte) []byte
+	lowKey    []byte
+	highKey   []byte
+	parseKey  func(k []byte) (ownerHash uint64, nameHash uint64, columnName [2]byte, err error)
+}
+
+// 用户属性与用户存放在同一个db内
+func (wk *wukongDB) userAttributeTable(uid string) attributeTable {
+	return attributeTable{
+		db:      wk.shardDB(uid),
+		columns: key.TableUserAttribute.Column,
+		columnKey: func(name string, columnName [2]byte) []byte {
+			return key.NewUserAttributeColumnKey(uid, name, columnName)
+		},
+		lowKey:   key.NewUserAttributeLowKey(uid),
+		highKey:  key.NewUserAttributeHighKey(uid),
+		parseKey: key.ParseUserAttributeColumnKey,
+	}
+}
+
+// 频道属性与频道的消息存放在同一个db内
+func (wk *wukongDB) channelAttributeTable(channelId string, channelType uint8) attributeTable {
+	return attributeTable{
+		db:      wk.channelDb(channelId, channelType),
+		columns: key.TableChannelAttribute.Column,
+		columnKey: func(name string, columnName [2]byte) []byte {
+			return key.NewChannelAttributeColumnKey(channelId, channelType, name, columnName)
+		},
+		lowKey:   key.NewChannelAttributeLowKey(channelId, channelType),
+		highKey:  key.NewChannelAttributeHighKey(channelId, channelType),
+		parseKey: key.ParseChannelAttributeColumnKey,
+	}
+}
+
+func (wk *wukongDB) SetUserAttributes(uid string, attrs []Attribute, expectVersion bool) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("SetUserAttributes done", zap.Duration("cost", cost), zap.String("uid", uid), zap.Int("count", len(attrs)))
+			}
+		}()
+	}
+
+	wk.dblock.attributeLock.lockUser(uid)
+	defer wk.dblock.attributeLock.unlockUser(uid)
+
+	return wk.setAttributes(wk.userAttributeTable(uid), attrs, expectVersion)
+}
+
+func (wk *wukongDB) GetUserAttributes(uid string, names []string) ([]Attribute, error) {
+	return wk.getAttributes(wk.userAttributeTable(uid), names)
+}
+
+func (wk *wukongDB) DeleteUserAttributes(uid string, names []string) error {
+	wk.dblock.attributeLock.lockUser(uid)
+	defer wk.dblock.attributeLock.unlockUser(uid)
+
+	return wk.deleteAttributes(wk.userAttributeTable(uid), names)
+}
+
+func (wk *wukongDB) SetChannelAttributes(channelId string, channelType uint8, attrs []Attribute, expectVersion bool) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("SetChannelAttributes done", zap.Duration("cost", cost), zap.String("channelId", channelId), zap.Uint8("channelType", channelType), zap.Int("count", len(attrs)))
+			}
+		}()
+	}
+
+	wk.dblock.attributeLock.lockChannel(channelId, channelType)
+	defer wk.dblock.attributeLock.unlockChannel(channelId, channelType)
+
+	return wk.setAttributes(wk.channelAttributeTable(channelId, channelType), attrs, expectVersion)
+}
+
+func (wk *wukongDB) GetChannelAttributes(channelId string, channelType uint8, names []string) ([]Attribute, error) {
+	return wk.getAttributes(wk.channelAttributeTable(channelId, channelType), names)
+}
+
+func (wk *wukongDB) DeleteChannelAttributes(channelId string, channelType uint8, names []string) error {
+	wk.dblock.attributeLock.lockChannel(channelId, channelType)
+	defer wk.dblock.attributeLock.unlockChannel(channelId, channelType)
+
+	return wk.deleteAttributes(wk.channelAttributeTable(channelId, channelType), names)
+}
+
+func (wk *wukongDB) setAttributes(t attributeTable, attrs []Attribute, expectVersion bool) error {
+	if len(attrs) == 0 {
+		return nil
+	}
+	olds, err := wk.getAttributes(t, nil)
+	if err != nil {
+		return err
+	}
+	versions := make(map[string]uint64, len(olds))
+	for _, old := range olds {
+		versions[old.Name] = old.Version
+	}
+
+	batch := t.db.NewBatch()
+	defer batch.Close()
+
+	for _, attr := range attrs {
+		version := versions[attr.Name]
+		if expectVersion && attr.Version != version {
+			// 提交前已经校验过版本，这里不一致说明校验和提交之间有其他修改（例如槽领导切换）
+			wk.Warn("attribute version mismatch, skip", zap.String("name", attr.Name), zap.Uint64("expect", attr.Version), zap.Uint64("current", version))
+			continue
+		}
+		attr.Version = version + 1
+		// 同一批次内重复设置同一个属性时，以最后一个为准
+		versions[attr.Name] = attr.Version
+		if err = wk.writeAttribute(t, attr, batch); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) writeAttribute(t attributeTable, attr Attribute, w Writer) error {
+	var err error
+
+	// name
+	if err = w.Set(t.columnKey(attr.Name, t.columns.Name), []byte(attr.Name), wk.noSync); err != nil {
+		return err
+	}
+
+	// type
+	if err = w.Set(t.columnKey(attr.Name, t.columns.Type), []byte{uint8(attr.Type)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// value
+	if err = w.Set(t.columnKey(attr.Name, t.columns.Value), attr.Value, wk.noSync); err != nil {
+		return err
+	}
+
+	// version
+	version := make([]byte, 8)
+	wk.endian.PutUint64(version, attr.Version)
+	if err = w.Set(t.columnKey(attr.Name, t.columns.Version), version, wk.noSync); err != nil {
+		return err
+	}
+
+	// updatedAt
+	if attr.UpdatedAt != nil {
+		updatedAt := make([]byte, 8)
+		wk.endian.PutUint64(updatedAt, uint64(attr.UpdatedAt.UnixNano()))
+		if err = w.Set(t.columnKey(attr.Name, t.columns.UpdatedAt), updatedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) getAttributes(t attributeTable, names []string) ([]Attribute, error) {
+	var attrs []Attribute
+	if len(names) == 0 {
+		iter := t.db.NewIter(&IterOptions{
+			LowerBound: t.lowKey,
+			UpperBound: t.highKey,
+		})
+		defer iter.Close()
+		err := wk.iterAttribute(t, iter, func(attr Attribute) bool {
+			attrs = append(attrs, attr)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, name := range names {
+			attr, err := wk.getAttribute(t, name)
+			if err != nil {
+				return nil, err
+			}
+			if attr.Name == "" {
+				continue
+			}
+			attrs = append(attrs, attr)
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Name < attrs[j].Name
+	})
+	return attrs, nil
+}
+
+func (wk *wukongDB) getAttribute(t attributeTable, name string) (Attribute, error) {
+	iter := t.db.NewIter(&IterOptions{
+		LowerBound: t.columnKey(name, key.MinColumnKey),
+		UpperBound: t.columnKey(name, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var attr Attribute
+	err := wk.iterAttribute(t, iter, func(a Attribute) bool {
+		// 属性名的哈希冲突时不是同一个属性
+		if a.Name == name {
+			attr = a
+		}
+		return false
+	})
+	return attr, err
+}
+
+func (wk *wukongDB) deleteAttributes(t attributeTable, names []string) error {
+	batch := t.db.NewBatch()
+	defer batch.Close()
+
+	if len(names) == 0 {
+		if err := batch.DeleteRange(t.lowKey, t.highKey, wk.noSync); err != nil {
+			return err
+		}
+	} else {
+		for _, name := range names {
+			if err := batch.DeleteRange(t.columnKey(name, key.MinColumnKey), t.columnKey(name, key.MaxColumnKey), wk.noSync); err != nil {
+				return err
+			}
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) iterAttribute(t attributeTable, iter Iterator, iterFnc func(attr Attribute) bool) error {
+	var (
+		preNameHash    uint64
+		preAttr        Attribute
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, nameHash, columnName, err := t.parseKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if !hasData || nameHash != preNameHash {
+			if hasData {
+				if !iterFnc(preAttr) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preNameHash = nameHash
+			preAttr = Attribute{}
+		}
+
+		switch columnName {
+		case t.columns.Name:
+			preAttr.Name = string(iter.Value())
+		case t.columns.Type:
+			preAttr.Type = AttributeType(iter.Value()[0])
+		case t.columns.Value:
+			// 迭代器的值在移动后失效，需要拷贝
+			preAttr.Value = append([]byte(nil), iter.Value()...)
+		case t.columns.Version:
+			preAttr.Version = wk.endian.Uint64(iter.Value())
+		case t.columns.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				updatedAt := time.Unix(tm/1e9, tm%1e9)
+				preAttr.UpdatedAt = &updatedAt
+			}
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preAttr)
+	}
+	return nil
+}
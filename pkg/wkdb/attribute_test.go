@@ -0,0 +1,114 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserAttributes(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	updatedAt := time.Now()
+	err = d.SetUserAttributes("u1", []wkdb.Attribute{
+		{Name: "nickname", Type: wkdb.AttributeTypeString, Value: []byte(`"tom"`), UpdatedAt: &updatedAt},
+		{Name: "age", Type: wkdb.AttributeTypeInt, Value: []byte(`18`), UpdatedAt: &updatedAt},
+	}, false)
+	assert.NoError(t, err)
+
+	// 其他用户的属性不受影响
+	err = d.SetUserAttributes("u2", []wkdb.Attribute{
+		{Name: "age", Type: wkdb.AttributeTypeInt, Value: []byte(`30`)},
+	}, false)
+	assert.NoError(t, err)
+
+	attrs, err := d.GetUserAttributes("u1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(attrs))
+	assert.Equal(t, "age", attrs[0].Name)
+	assert.Equal(t, wkdb.AttributeTypeInt, attrs[0].Type)
+	assert.Equal(t, []byte(`18`), attrs[0].Value)
+	assert.Equal(t, uint64(1), attrs[0].Version)
+	assert.Equal(t, updatedAt.Unix(), attrs[0].UpdatedAt.Unix())
+	assert.Equal(t, "nickname", attrs[1].Name)
+
+	// 版本不一致的不修改
+	err = d.SetUserAttributes("u1", []wkdb.Attribute{
+		{Name: "age", Type: wkdb.AttributeTypeInt, Value: []byte(`19`), Version: 1},
+		{Name: "nickname", Type: wkdb.AttributeTypeString, Value: []byte(`"jerry"`), Version: 2},
+		{Name: "vip", Type: wkdb.AttributeTypeBool, Value: []byte(`true`), Version: 0},
+	}, true)
+	assert.NoError(t, err)
+
+	attrs, err = d.GetUserAttributes("u1", []string{"age", "nickname", "vip", "notfound"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(attrs))
+	assert.Equal(t, []byte(`19`), attrs[0].Value)
+	assert.Equal(t, uint64(2), attrs[0].Version)
+	assert.Equal(t, []byte(`"tom"`), attrs[1].Value)
+	assert.Equal(t, uint64(1), attrs[1].Version)
+	assert.Equal(t, "vip", attrs[2].Name)
+	assert.Equal(t, uint64(1), attrs[2].Version)
+
+	err = d.DeleteUserAttributes("u1", []string{"age"})
+	assert.NoError(t, err)
+	attrs, err = d.GetUserAttributes("u1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(attrs))
+
+	err = d.DeleteUserAttributes("u1", nil)
+	assert.NoError(t, err)
+	attrs, err = d.GetUserAttributes("u1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(attrs))
+
+	attrs, err = d.GetUserAttributes("u2", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(attrs))
+}
+
+func TestChannelAttributes(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.SetChannelAttributes("g1", 2, []wkdb.Attribute{
+		{Name: "topic", Type: wkdb.AttributeTypeJSON, Value: []byte(`{"a":1}`)},
+	}, false)
+	assert.NoError(t, err)
+
+	err = d.SetChannelAttributes("g1", 2, []wkdb.Attribute{
+		{Name: "topic", Type: wkdb.AttributeTypeJSON, Value: []byte(`{"a":2}`)},
+	}, false)
+	assert.NoError(t, err)
+
+	attrs, err := d.GetChannelAttributes("g1", 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(attrs))
+	assert.Equal(t, []byte(`{"a":2}`), attrs[0].Value)
+	assert.Equal(t, uint64(2), attrs[0].Version)
+
+	attrs, err = d.GetChannelAttributes("g1", 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(attrs))
+
+	err = d.DeleteChannelAttributes("g1", 2, nil)
+	assert.NoError(t, err)
+	attrs, err = d.GetChannelAttributes("g1", 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(attrs))
+}
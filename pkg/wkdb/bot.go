@@ -0,0 +1,217 @@
+package wkdb
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+// 机器人全局存放在槽0上（与系统账号相同），数据在默认的db内
+
+func (wk *wukongDB) AddOrUpdateBot(bot Bot) error {
+	db := wk.defaultShardDB()
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 更新时先删除旧的数据，避免残留已清空的字段
+	if err := batch.DeleteRange(key.NewBotColumnKey(bot.Uid, key.MinColumnKey), key.NewBotColumnKey(bot.Uid, key.MaxColumnKey), wk.noSync); err != nil {
+		return err
+	}
+	if err := wk.writeBot(bot, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetBot(uid string) (Bot, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: key.NewBotColumnKey(uid, key.MinColumnKey),
+		UpperBound: key.NewBotColumnKey(uid, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	bot := EmptyBot
+	err := wk.iterBot(iter, func(b Bot) bool {
+		bot = b
+		return false
+	})
+	if err != nil {
+		return EmptyBot, err
+	}
+	return bot, nil
+}
+
+func (wk *wukongDB) GetBots() ([]Bot, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: key.NewBotLowKey(),
+		UpperBound: key.NewBotHighKey(),
+	})
+	defer iter.Close()
+
+	var bots []Bot
+	err := wk.iterBot(iter, func(b Bot) bool {
+		bots = append(bots, b)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bots, nil
+}
+
+func (wk *wukongDB) RemoveBot(uid string) error {
+	return wk.defaultShardDB().DeleteRange(key.NewBotColumnKey(uid, key.MinColumnKey), key.NewBotColumnKey(uid, key.MaxColumnKey), wk.sync)
+}
+
+func (wk *wukongDB) writeBot(bot Bot, w Writer) error {
+	var err error
+
+	// uid
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.Uid), []byte(bot.Uid), wk.noSync); err != nil {
+		return err
+	}
+
+	// callbackUrl
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.CallbackUrl), []byte(bot.CallbackUrl), wk.noSync); err != nil {
+		return err
+	}
+
+	// secret
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.Secret), []byte(bot.Secret), wk.noSync); err != nil {
+		return err
+	}
+
+	// channels
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.Channels), encodeBotChannels(bot.Channels), wk.noSync); err != nil {
+		return err
+	}
+
+	// triggers
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.Triggers), []byte{uint8(bot.Triggers)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// commandPrefix
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.CommandPrefix), []byte(bot.CommandPrefix), wk.noSync); err != nil {
+		return err
+	}
+
+	// rateLimit
+	rateLimit := make([]byte, 4)
+	wk.endian.PutUint32(rateLimit, bot.RateLimit)
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.RateLimit), rateLimit, wk.noSync); err != nil {
+		return err
+	}
+
+	// timeout
+	timeout := make([]byte, 8)
+	wk.endian.PutUint64(timeout, uint64(bot.Timeout))
+	if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.Timeout), timeout, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if bot.CreatedAt != nil {
+		if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.CreatedAt), wk.timeBytes(*bot.CreatedAt), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// updatedAt
+	if bot.UpdatedAt != nil {
+		if err = w.Set(key.NewBotColumnKey(bot.Uid, key.TableBot.Column.UpdatedAt), wk.timeBytes(*bot.UpdatedAt), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) iterBot(iter Iterator, iterFnc func(b Bot) bool) error {
+	var (
+		preUidHash     uint64
+		preBot         Bot
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		uidHash, columnName, err := key.ParseBotColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if !hasData || uidHash != preUidHash {
+			if hasData {
+				if !iterFnc(preBot) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preUidHash = uidHash
+			preBot = Bot{}
+		}
+
+		switch columnName {
+		case key.TableBot.Column.Uid:
+			preBot.Uid = string(iter.Value())
+		case key.TableBot.Column.CallbackUrl:
+			preBot.CallbackUrl = string(iter.Value())
+		case key.TableBot.Column.Secret:
+			preBot.Secret = string(iter.Value())
+		case key.TableBot.Column.Channels:
+			channels, err := decodeBotChannels(iter.Value())
+			if err != nil {
+				return err
+			}
+			preBot.Channels = channels
+		case key.TableBot.Column.Triggers:
+			preBot.Triggers = BotTrigger(iter.Value()[0])
+		case key.TableBot.Column.CommandPrefix:
+			preBot.CommandPrefix = string(iter.Value())
+		case key.TableBot.Column.RateLimit:
+			preBot.RateLimit = wk.endian.Uint32(iter.Value())
+		case key.TableBot.Column.Timeout:
+			preBot.Timeout = time.Duration(wk.endian.Uint64(iter.Value()))
+		case key.TableBot.Column.CreatedAt:
+			preBot.CreatedAt = wk.parseTime(iter.Value())
+		case key.TableBot.Column.UpdatedAt:
+			preBot.UpdatedAt = wk.parseTime(iter.Value())
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preBot)
+	}
+	return nil
+}
+
+func encodeBotChannels(channels []BotChannel) []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(channels)))
+	for _, channel := range channels {
+		enc.WriteString(channel.ChannelId)
+		enc.WriteUint8(channel.ChannelType)
+	}
+	// 编码器结束后会被回收，需要拷贝
+	return append([]byte(nil), enc.Bytes()...)
+}
+
+func decodeBotChannels(data []byte) ([]BotChannel, error) {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return nil, err
+	}
+	channels := make([]BotChannel, 0, count)
+	for i := uint32(0); i < count; i++ {
+		var channel BotChannel
+		if channel.ChannelId, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if channel.ChannelType, err = dec.Uint8(); err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
@@ -0,0 +1,79 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBot(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Unix(100, 0)
+	bot := wkdb.Bot{
+		Uid:         "bot1",
+		CallbackUrl: "http://127.0.0.1:8080/bot",
+		Secret:      "secret",
+		Channels: []wkdb.BotChannel{
+			{ChannelId: "g1", ChannelType: 2},
+			{ChannelId: "g2", ChannelType: 2},
+		},
+		Triggers:      wkdb.BotTriggerDirect | wkdb.BotTriggerMention,
+		CommandPrefix: "/",
+		RateLimit:     10,
+		Timeout:       time.Second * 3,
+		CreatedAt:     &createdAt,
+		UpdatedAt:     &createdAt,
+	}
+	err = d.AddOrUpdateBot(bot)
+	assert.NoError(t, err)
+	err = d.AddOrUpdateBot(wkdb.Bot{Uid: "bot2", CallbackUrl: "http://127.0.0.1:8080/bot2"})
+	assert.NoError(t, err)
+
+	bot1, err := d.GetBot("bot1")
+	assert.NoError(t, err)
+	assert.Equal(t, bot.Uid, bot1.Uid)
+	assert.Equal(t, bot.CallbackUrl, bot1.CallbackUrl)
+	assert.Equal(t, bot.Secret, bot1.Secret)
+	assert.Equal(t, bot.Channels, bot1.Channels)
+	assert.True(t, bot1.Triggers.Has(wkdb.BotTriggerDirect))
+	assert.True(t, bot1.Triggers.Has(wkdb.BotTriggerMention))
+	assert.Equal(t, bot.CommandPrefix, bot1.CommandPrefix)
+	assert.Equal(t, bot.RateLimit, bot1.RateLimit)
+	assert.Equal(t, bot.Timeout, bot1.Timeout)
+	assert.Equal(t, createdAt.Unix(), bot1.CreatedAt.Unix())
+
+	// 更新后清空的字段不会残留
+	bot.Channels = nil
+	bot.CommandPrefix = ""
+	err = d.AddOrUpdateBot(bot)
+	assert.NoError(t, err)
+	bot1, err = d.GetBot("bot1")
+	assert.NoError(t, err)
+	assert.Len(t, bot1.Channels, 0)
+	assert.Equal(t, "", bot1.CommandPrefix)
+
+	bots, err := d.GetBots()
+	assert.NoError(t, err)
+	assert.Len(t, bots, 2)
+
+	err = d.RemoveBot("bot1")
+	assert.NoError(t, err)
+	bot1, err = d.GetBot("bot1")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyBot(bot1))
+
+	bots, err = d.GetBots()
+	assert.NoError(t, err)
+	assert.Len(t, bots, 1)
+	assert.Equal(t, "bot2", bots[0].Uid)
+}
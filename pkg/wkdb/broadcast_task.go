@@ -0,0 +1,338 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"go.uber.org/zap"
+)
+
+// 广播任务的消息头标记位
+const (
+	broadcastHeaderNoPersist uint8 = 1 << iota
+	broadcastHeaderRedDot
+)
+
+// 广播任务统一存放在默认的db内，按广播ID+槽ID存储
+func (wk *wukongDB) AddBroadcastTask(t BroadcastTask) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("AddBroadcastTask done", zap.Duration("cost", cost), zap.Uint64("jobId", t.JobId), zap.Uint32("slotId", t.SlotId))
+			}
+		}()
+	}
+
+	wk.dblock.broadcastTaskLock.lock(t.JobId, t.SlotId)
+	defer wk.dblock.broadcastTaskLock.unlock(t.JobId, t.SlotId)
+
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	if err := wk.writeBroadcastTask(t, batch); err != nil {
+		return err
+	}
+	if err := wk.writeBroadcastTaskProgress(t, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) UpdateBroadcastTaskProgress(t BroadcastTask) error {
+	wk.dblock.broadcastTaskLock.lock(t.JobId, t.SlotId)
+	defer wk.dblock.broadcastTaskLock.unlock(t.JobId, t.SlotId)
+
+	old, err := wk.GetBroadcastTask(t.JobId, t.SlotId)
+	if err != nil {
+		return err
+	}
+	if IsEmptyBroadcastTask(old) || old.Status.Finished() {
+		return nil
+	}
+
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err = wk.writeBroadcastTaskProgress(t, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) CancelBroadcastTask(jobId uint64, slotId uint32) error {
+	wk.dblock.broadcastTaskLock.lock(jobId, slotId)
+	defer wk.dblock.broadcastTaskLock.unlock(jobId, slotId)
+
+	old, err := wk.GetBroadcastTask(jobId, slotId)
+	if err != nil {
+		return err
+	}
+	if IsEmptyBroadcastTask(old) || old.Status.Finished() {
+		return nil
+	}
+
+	updatedAt := time.Now()
+	old.Status = BroadcastStatusCancelled
+	old.UpdatedAt = &updatedAt
+
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+	if err = wk.writeBroadcastTaskProgress(old, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetBroadcastTask(jobId uint64, slotId uint32) (BroadcastTask, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: key.NewBroadcastTaskColumnKey(jobId, slotId, key.MinColumnKey),
+		UpperBound: key.NewBroadcastTaskColumnKey(jobId, slotId, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	task := EmptyBroadcastTask
+	err := wk.iterBroadcastTask(iter, func(t BroadcastTask) bool {
+		task = t
+		return false
+	})
+	if err != nil {
+		return EmptyBroadcastTask, err
+	}
+	return task, nil
+}
+
+func (wk *wukongDB) GetBroadcastTasks(jobId uint64) ([]BroadcastTask, error) {
+	opts := &IterOptions{
+		LowerBound: key.NewTableRowLowKey(key.TableBroadcastTask.Id),
+		UpperBound: key.NewTableRowHighKey(key.TableBroadcastTask.Id),
+	}
+	if jobId != 0 {
+		opts = &IterOptions{
+			LowerBound: key.NewBroadcastTaskColumnKey(jobId, 0, key.MinColumnKey),
+			UpperBound: key.NewBroadcastTaskColumnKey(jobId, math.MaxUint32, key.MaxColumnKey),
+		}
+	}
+	iter := wk.defaultShardDB().NewIter(opts)
+	defer iter.Close()
+
+	var tasks []BroadcastTask
+	err := wk.iterBroadcastTask(iter, func(t BroadcastTask) bool {
+		tasks = append(tasks, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// writeBroadcastTask 写入广播任务的内容（创建后不再修改）
+func (wk *wukongDB) writeBroadcastTask(t BroadcastTask, w Writer) error {
+	var err error
+
+	// fromUid
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.FromUid), []byte(t.FromUid), wk.noSync); err != nil {
+		return err
+	}
+
+	// header
+	var header uint8
+	if t.NoPersist {
+		header |= broadcastHeaderNoPersist
+	}
+	if t.RedDot {
+		header |= broadcastHeaderRedDot
+	}
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.Header), []byte{header}, wk.noSync); err != nil {
+		return err
+	}
+
+	// payload
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.Payload), t.Payload, wk.noSync); err != nil {
+		return err
+	}
+
+	// uids
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.Uids), encodeBroadcastUids(t.Uids), wk.noSync); err != nil {
+		return err
+	}
+
+	// deviceFlags
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.DeviceFlags), []byte{t.DeviceFlags}, wk.noSync); err != nil {
+		return err
+	}
+
+	// userCreatedGte
+	userCreatedGte := make([]byte, 8)
+	wk.endian.PutUint64(userCreatedGte, uint64(t.UserCreatedGte))
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.UserCreatedGte), userCreatedGte, wk.noSync); err != nil {
+		return err
+	}
+
+	// userCreatedLt
+	userCreatedLt := make([]byte, 8)
+	wk.endian.PutUint64(userCreatedLt, uint64(t.UserCreatedLt))
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.UserCreatedLt), userCreatedLt, wk.noSync); err != nil {
+		return err
+	}
+
+	// rate
+	rate := make([]byte, 4)
+	wk.endian.PutUint32(rate, t.Rate)
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.Rate), rate, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if t.CreatedAt != nil {
+		createdAt := make([]byte, 8)
+		wk.endian.PutUint64(createdAt, uint64(t.CreatedAt.UnixNano()))
+		if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.CreatedAt), createdAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBroadcastTaskProgress 写入广播任务的状态和进度
+func (wk *wukongDB) writeBroadcastTaskProgress(t BroadcastTask, w Writer) error {
+	var err error
+
+	// status
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.Status), []byte{uint8(t.Status)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// cursor
+	cursor := make([]byte, 8)
+	wk.endian.PutUint64(cursor, t.Cursor)
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.Cursor), cursor, wk.noSync); err != nil {
+		return err
+	}
+
+	// sentCount
+	sentCount := make([]byte, 8)
+	wk.endian.PutUint64(sentCount, t.SentCount)
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.SentCount), sentCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// failedCount
+	failedCount := make([]byte, 8)
+	wk.endian.PutUint64(failedCount, t.FailedCount)
+	if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.FailedCount), failedCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// updatedAt
+	if t.UpdatedAt != nil {
+		updatedAt := make([]byte, 8)
+		wk.endian.PutUint64(updatedAt, uint64(t.UpdatedAt.UnixNano()))
+		if err = w.Set(key.NewBroadcastTaskColumnKey(t.JobId, t.SlotId, key.TableBroadcastTask.Column.UpdatedAt), updatedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeBroadcastUids(uids []string) []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(uids)))
+	for _, uid := range uids {
+		enc.WriteString(uid)
+	}
+	// 编码器结束后会被回收，需要拷贝
+	return append([]byte(nil), enc.Bytes()...)
+}
+
+func (wk *wukongDB) iterBroadcastTask(iter Iterator, iterFnc func(t BroadcastTask) bool) error {
+	var (
+		preJobId       uint64
+		preSlotId      uint32
+		preTask        BroadcastTask
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		jobId, slotId, columnName, err := key.ParseBroadcastTaskColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if !hasData || jobId != preJobId || slotId != preSlotId {
+			if hasData {
+				if !iterFnc(preTask) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preJobId = jobId
+			preSlotId = slotId
+			preTask = BroadcastTask{JobId: jobId, SlotId: slotId}
+		}
+
+		switch columnName {
+		case key.TableBroadcastTask.Column.Status:
+			preTask.Status = BroadcastStatus(iter.Value()[0])
+		case key.TableBroadcastTask.Column.FromUid:
+			preTask.FromUid = string(iter.Value())
+		case key.TableBroadcastTask.Column.Header:
+			header := iter.Value()[0]
+			preTask.NoPersist = header&broadcastHeaderNoPersist != 0
+			preTask.RedDot = header&broadcastHeaderRedDot != 0
+		case key.TableBroadcastTask.Column.Payload:
+			// 迭代器的值在移动后失效，需要拷贝
+			preTask.Payload = append([]byte(nil), iter.Value()...)
+		case key.TableBroadcastTask.Column.Uids:
+			dec := wkproto.NewDecoder(iter.Value())
+			count, err := dec.Uint32()
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				preTask.Uids = make([]string, 0, count)
+				for i := uint32(0); i < count; i++ {
+					uid, err := dec.String()
+					if err != nil {
+						return err
+					}
+					preTask.Uids = append(preTask.Uids, uid)
+				}
+			}
+		case key.TableBroadcastTask.Column.DeviceFlags:
+			preTask.DeviceFlags = iter.Value()[0]
+		case key.TableBroadcastTask.Column.UserCreatedGte:
+			preTask.UserCreatedGte = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableBroadcastTask.Column.UserCreatedLt:
+			preTask.UserCreatedLt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableBroadcastTask.Column.Rate:
+			preTask.Rate = wk.endian.Uint32(iter.Value())
+		case key.TableBroadcastTask.Column.Cursor:
+			preTask.Cursor = wk.endian.Uint64(iter.Value())
+		case key.TableBroadcastTask.Column.SentCount:
+			preTask.SentCount = wk.endian.Uint64(iter.Value())
+		case key.TableBroadcastTask.Column.FailedCount:
+			preTask.FailedCount = wk.endian.Uint64(iter.Value())
+		case key.TableBroadcastTask.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preTask.CreatedAt = &t
+			}
+		case key.TableBroadcastTask.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preTask.UpdatedAt = &t
+			}
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preTask)
+	}
+	return nil
+}
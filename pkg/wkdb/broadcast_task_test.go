@@ -0,0 +1,95 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastTask(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	task := wkdb.BroadcastTask{
+		JobId:          100,
+		SlotId:         3,
+		Status:         wkdb.BroadcastStatusRunning,
+		FromUid:        "system",
+		RedDot:         true,
+		Payload:        []byte("hello"),
+		Uids:           []string{"u1", "u2"},
+		DeviceFlags:    1 << 1,
+		UserCreatedGte: 10,
+		UserCreatedLt:  20,
+		Rate:           50,
+		CreatedAt:      &createdAt,
+		UpdatedAt:      &createdAt,
+	}
+	err = d.AddBroadcastTask(task)
+	assert.NoError(t, err)
+
+	err = d.AddBroadcastTask(wkdb.BroadcastTask{JobId: 100, SlotId: 0, Status: wkdb.BroadcastStatusRunning, Payload: []byte("hello")})
+	assert.NoError(t, err)
+	err = d.AddBroadcastTask(wkdb.BroadcastTask{JobId: 101, SlotId: 3, Status: wkdb.BroadcastStatusRunning, Payload: []byte("world")})
+	assert.NoError(t, err)
+
+	tk, err := d.GetBroadcastTask(100, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.BroadcastStatusRunning, tk.Status)
+	assert.Equal(t, "system", tk.FromUid)
+	assert.True(t, tk.RedDot)
+	assert.False(t, tk.NoPersist)
+	assert.Equal(t, []byte("hello"), tk.Payload)
+	assert.Equal(t, []string{"u1", "u2"}, tk.Uids)
+	assert.Equal(t, uint8(1<<1), tk.DeviceFlags)
+	assert.Equal(t, int64(10), tk.UserCreatedGte)
+	assert.Equal(t, int64(20), tk.UserCreatedLt)
+	assert.Equal(t, uint32(50), tk.Rate)
+
+	tasks, err := d.GetBroadcastTasks(100)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, uint32(0), tasks[0].SlotId)
+	assert.Equal(t, uint32(3), tasks[1].SlotId)
+
+	tasks, err = d.GetBroadcastTasks(0)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 3)
+
+	// 更新进度
+	tk.Cursor = 1
+	tk.SentCount = 1
+	tk.FailedCount = 0
+	err = d.UpdateBroadcastTaskProgress(tk)
+	assert.NoError(t, err)
+
+	// 取消后不再更新进度
+	err = d.CancelBroadcastTask(100, 3)
+	assert.NoError(t, err)
+	tk.Cursor = 2
+	tk.SentCount = 2
+	err = d.UpdateBroadcastTaskProgress(tk)
+	assert.NoError(t, err)
+
+	tk, err = d.GetBroadcastTask(100, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.BroadcastStatusCancelled, tk.Status)
+	assert.Equal(t, uint64(1), tk.Cursor)
+	assert.Equal(t, uint64(1), tk.SentCount)
+
+	// 不存在的任务
+	err = d.CancelBroadcastTask(102, 3)
+	assert.NoError(t, err)
+	tk, err = d.GetBroadcastTask(102, 3)
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyBroadcastTask(tk))
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
-	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
 
@@ -102,7 +101,7 @@ func (wk *wukongDB) GetChannel(channelId string, channelType uint8) (ChannelInfo
 		return EmptyChannelInfo, nil
 	}
 
-	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
 		LowerBound: key.NewChannelInfoColumnKey(id, key.MinColumnKey),
 		UpperBound: key.NewChannelInfoColumnKey(id, key.MaxColumnKey),
 	})
@@ -196,7 +195,7 @@ func (wk *wukongDB) SearchChannels(req ChannelSearchReq) ([]ChannelInfo, error)
 			}
 		}
 
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewChannelInfoSecondIndexKey(key.TableChannelInfo.SecondIndex.CreatedAt, start, 0),
 			UpperBound: key.NewChannelInfoSecondIndexKey(key.TableChannelInfo.SecondIndex.CreatedAt, end, 0),
 		})
@@ -221,7 +220,7 @@ func (wk *wukongDB) SearchChannels(req ChannelSearchReq) ([]ChannelInfo, error)
 				return nil, err
 			}
 
-			dataIter := db.NewIter(&pebble.IterOptions{
+			dataIter := db.NewIter(&IterOptions{
 				LowerBound: key.NewChannelInfoColumnKey(id, key.MinColumnKey),
 				UpperBound: key.NewChannelInfoColumnKey(id, key.MaxColumnKey),
 			})
@@ -268,7 +267,7 @@ func (wk *wukongDB) SearchChannels(req ChannelSearchReq) ([]ChannelInfo, error)
 	return allChannelInfos, nil
 }
 
-func (wk *wukongDB) searchChannelsByIndex(req ChannelSearchReq, db *pebble.DB, iterFnc func(ch ChannelInfo) bool) (bool, error) {
+func (wk *wukongDB) searchChannelsByIndex(req ChannelSearchReq, db KV, iterFnc func(ch ChannelInfo) bool) (bool, error) {
 	var lowKey []byte
 	var highKey []byte
 
@@ -343,7 +342,7 @@ func (wk *wukongDB) searchChannelsByIndex(req ChannelSearchReq, db *pebble.DB, i
 		return false, nil
 	}
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: lowKey,
 		UpperBound: highKey,
 	})
@@ -354,7 +353,7 @@ func (wk *wukongDB) searchChannelsByIndex(req ChannelSearchReq, db *pebble.DB, i
 			return false, err
 		}
 
-		dataIter := db.NewIter(&pebble.IterOptions{
+		dataIter := db.NewIter(&IterOptions{
 			LowerBound: key.NewChannelInfoColumnKey(id, key.MinColumnKey),
 			UpperBound: key.NewChannelInfoColumnKey(id, key.MaxColumnKey),
 		})
@@ -434,7 +433,7 @@ func (wk *wukongDB) GetChannelAppliedIndex(channelId string, channelType uint8)
 		defer closer.Close()
 	}
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return 0, nil
 		}
 		return 0, err
@@ -444,14 +443,14 @@ func (wk *wukongDB) GetChannelAppliedIndex(channelId string, channelType uint8)
 }
 
 // 增加频道属性数量 id为频道信息的唯一主键 count为math.MinInt 表示重置为0
-func (wk *wukongDB) incChannelInfoColumnCount(id uint64, columnName, indexName [2]byte, count int, batch *pebble.Batch) error {
+func (wk *wukongDB) incChannelInfoColumnCount(id uint64, columnName, indexName [2]byte, count int, batch Batch) error {
 	countKey := key.NewChannelInfoColumnKey(id, columnName)
 	if count == 0 { //
 		return batch.Set(countKey, []byte{0x00, 0x00, 0x00, 0x00}, wk.noSync)
 	}
 
 	countBytes, closer, err := batch.Get(countKey)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -481,7 +480,7 @@ func (wk *wukongDB) incChannelInfoColumnCount(id uint64, columnName, indexName [
 	return nil
 }
 
-func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo, w pebble.Writer) error {
+func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo, w Writer) error {
 
 	var (
 		err error
@@ -519,6 +518,13 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 		return err
 	}
 
+	// muteAll
+	muteAllBytes := make([]byte, 1)
+	muteAllBytes[0] = wkutil.BoolToUint8(channelInfo.MuteAll)
+	if err = w.Set(key.NewChannelInfoColumnKey(primaryKey, key.TableChannelInfo.Column.MuteAll), muteAllBytes, wk.noSync); err != nil {
+		return err
+	}
+
 	// createdAt
 	if channelInfo.CreatedAt != nil {
 		ct := uint64(channelInfo.CreatedAt.UnixNano())
@@ -547,7 +553,7 @@ func (wk *wukongDB) writeChannelInfo(primaryKey uint64, channelInfo ChannelInfo,
 	return nil
 }
 
-func (wk *wukongDB) writeChannelInfoBaseIndex(channelInfo ChannelInfo, w pebble.Writer) error {
+func (wk *wukongDB) writeChannelInfoBaseIndex(channelInfo ChannelInfo, w Writer) error {
 	primaryKey, err := wk.getChannelPrimaryKey(channelInfo.ChannelId, channelInfo.ChannelType)
 	if err != nil {
 		return err
@@ -587,7 +593,7 @@ func (wk *wukongDB) writeChannelInfoBaseIndex(channelInfo ChannelInfo, w pebble.
 	return nil
 }
 
-func (wk *wukongDB) deleteChannelInfoBaseIndex(channelInfo ChannelInfo, w pebble.Writer) error {
+func (wk *wukongDB) deleteChannelInfoBaseIndex(channelInfo ChannelInfo, w Writer) error {
 	if channelInfo.CreatedAt != nil {
 		// createdAt second index
 		ct := uint64(channelInfo.CreatedAt.UnixNano())
@@ -635,7 +641,7 @@ func (wk *wukongDB) deleteChannelInfoBaseIndex(channelInfo ChannelInfo, w pebble
 	return nil
 }
 
-func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelInfo ChannelInfo) bool) error {
+func (wk *wukongDB) iterChannelInfo(iter Iterator, iterFnc func(channelInfo ChannelInfo) bool) error {
 	var (
 		preId          uint64
 		preChannelInfo ChannelInfo
@@ -671,6 +677,8 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 			preChannelInfo.Large = wkutil.Uint8ToBool(iter.Value()[0])
 		case key.TableChannelInfo.Column.Disband:
 			preChannelInfo.Disband = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableChannelInfo.Column.MuteAll:
+			preChannelInfo.MuteAll = wkutil.Uint8ToBool(iter.Value()[0])
 		case key.TableChannelInfo.Column.SubscriberCount:
 			preChannelInfo.SubscriberCount = int(wk.endian.Uint32(iter.Value()))
 		case key.TableChannelInfo.Column.AllowlistCount:
@@ -699,7 +707,7 @@ func (wk *wukongDB) iterChannelInfo(iter *pebble.Iterator, iterFnc func(channelI
 	return nil
 }
 
-// func (wk *wukongDB) parseChannelInfo(iter *pebble.Iterator, limit int) ([]ChannelInfo, error) {
+// func (wk *wukongDB) parseChannelInfo(iter Iterator, limit int) ([]ChannelInfo, error) {
 
 // 	var (
 // 		channelInfos   = make([]ChannelInfo, 0, limit)
@@ -751,7 +759,7 @@ func (wk *wukongDB) getChannelPrimaryKey(channelId string, channelType uint8) (u
 	// primaryKey := key.NewChannelInfoIndexKey(key.TableChannelInfo.Index.Channel, key.ChannelIdToNum(channelId, channelType))
 	// indexValue, closer, err := wk.channelDb(channelId, channelType).Get(primaryKey)
 	// if err != nil {
-	// 	if err == pebble.ErrNotFound {
+	// 	if err == ErrNotFound {
 	// 		return 0, nil
 	// 	}
 	// 	return 0, err
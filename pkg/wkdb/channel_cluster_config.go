@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
 
@@ -77,7 +76,7 @@ func (wk *wukongDB) GetChannelClusterConfig(channelId string, channelType uint8)
 }
 
 func (wk *wukongDB) getChannelClusterConfigById(id uint64) (ChannelClusterConfig, error) {
-	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
 		LowerBound: key.NewChannelClusterConfigColumnKey(id, key.MinColumnKey),
 		UpperBound: key.NewChannelClusterConfigColumnKey(id, key.MaxColumnKey),
 	})
@@ -133,7 +132,7 @@ func (wk *wukongDB) DeleteChannelClusterConfig(channelId string, channelType uin
 
 func (wk *wukongDB) GetChannelClusterConfigs(offsetId uint64, limit int) ([]ChannelClusterConfig, error) {
 
-	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
 		LowerBound: key.NewChannelClusterConfigColumnKey(offsetId+1, key.MinColumnKey),
 		UpperBound: key.NewChannelClusterConfigColumnKey(math.MaxUint64, key.MaxColumnKey),
 	})
@@ -160,7 +159,7 @@ func (wk *wukongDB) SearchChannelClusterConfig(req ChannelClusterConfigSearchReq
 	if req.ChannelId != "" {
 		cfg, err := wk.GetChannelClusterConfig(req.ChannelId, req.ChannelType)
 		if err != nil {
-			if err == pebble.ErrNotFound {
+			if err == ErrNotFound {
 				return nil, nil
 			}
 			return nil, err
@@ -226,7 +225,7 @@ func (wk *wukongDB) SearchChannelClusterConfig(req ChannelClusterConfigSearchReq
 
 	db := wk.defaultShardDB()
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewChannelClusterConfigSecondIndexKey(key.TableChannelClusterConfig.SecondIndex.CreatedAt, start, 0),
 		UpperBound: key.NewChannelClusterConfigSecondIndexKey(key.TableChannelClusterConfig.SecondIndex.CreatedAt, end, 0),
 	})
@@ -250,7 +249,7 @@ func (wk *wukongDB) SearchChannelClusterConfig(req ChannelClusterConfigSearchReq
 			return nil, err
 		}
 
-		dataIter := db.NewIter(&pebble.IterOptions{
+		dataIter := db.NewIter(&IterOptions{
 			LowerBound: key.NewChannelClusterConfigColumnKey(id, key.MinColumnKey),
 			UpperBound: key.NewChannelClusterConfigColumnKey(id, key.MaxColumnKey),
 		})
@@ -282,7 +281,7 @@ func (wk *wukongDB) GetChannelClusterConfigCountWithSlotId(slotId uint32) (int,
 }
 
 func (wk *wukongDB) GetChannelClusterConfigWithSlotId(slotId uint32) ([]ChannelClusterConfig, error) {
-	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
 		LowerBound: key.NewChannelClusterConfigColumnKey(0, key.MinColumnKey),
 		UpperBound: key.NewChannelClusterConfigColumnKey(math.MaxUint64, key.MaxColumnKey),
 	})
@@ -303,7 +302,7 @@ func (wk *wukongDB) GetChannelClusterConfigWithSlotId(slotId uint32) ([]ChannelC
 	return results, nil
 }
 
-func (wk *wukongDB) deleteChannelClusterConfig(channelClusterConfig ChannelClusterConfig, w pebble.Writer) error {
+func (wk *wukongDB) deleteChannelClusterConfig(channelClusterConfig ChannelClusterConfig, w Writer) error {
 
 	// delete channel cluster config
 	err := w.DeleteRange(key.NewChannelClusterConfigColumnKey(channelClusterConfig.Id, key.MinColumnKey), key.NewChannelClusterConfigColumnKey(channelClusterConfig.Id, key.MaxColumnKey), wk.noSync)
@@ -320,10 +319,10 @@ func (wk *wukongDB) deleteChannelClusterConfig(channelClusterConfig ChannelClust
 	return nil
 }
 
-// func (wk *wukongDB) deleteChannelClusterConfigLeaderIndex(id uint64, w *pebble.Batch) error {
+// func (wk *wukongDB) deleteChannelClusterConfigLeaderIndex(id uint64, w Batch) error {
 
 // 	leaderValue, closer, err := w.Get(key.NewChannelClusterConfigColumnKey(id, key.TableChannelClusterConfig.Column.LeaderId))
-// 	if err != nil && err != pebble.ErrNotFound {
+// 	if err != nil && err != ErrNotFound {
 // 		return err
 // 	}
 // 	if closer != nil {
@@ -344,7 +343,7 @@ func (wk *wukongDB) deleteChannelClusterConfig(channelClusterConfig ChannelClust
 // 	return nil
 // }
 
-func (wk *wukongDB) writeChannelClusterConfig(primaryKey uint64, channelClusterConfig ChannelClusterConfig, w pebble.Writer) error {
+func (wk *wukongDB) writeChannelClusterConfig(primaryKey uint64, channelClusterConfig ChannelClusterConfig, w Writer) error {
 
 	// channelId
 	if err := w.Set(key.NewChannelClusterConfigColumnKey(primaryKey, key.TableChannelClusterConfig.Column.ChannelId), []byte(channelClusterConfig.ChannelId), wk.noSync); err != nil {
@@ -458,7 +457,7 @@ func (wk *wukongDB) writeChannelClusterConfig(primaryKey uint64, channelClusterC
 	return nil
 }
 
-func (wk *wukongDB) writeChannelClusterConfigIndex(primaryKey uint64, channelClusterConfig ChannelClusterConfig, w pebble.Writer) error {
+func (wk *wukongDB) writeChannelClusterConfigIndex(primaryKey uint64, channelClusterConfig ChannelClusterConfig, w Writer) error {
 
 	// channel index
 	primaryKeyBytes := make([]byte, 8)
@@ -489,7 +488,7 @@ func (wk *wukongDB) writeChannelClusterConfigIndex(primaryKey uint64, channelClu
 	return nil
 }
 
-func (wk *wukongDB) deleteChannelClusterConfigIndex(primaryKey uint64, channelClusterConfig ChannelClusterConfig, w pebble.Writer) error {
+func (wk *wukongDB) deleteChannelClusterConfigIndex(primaryKey uint64, channelClusterConfig ChannelClusterConfig, w Writer) error {
 
 	// channel index
 	if err := w.Delete(key.NewChannelClusterConfigIndexKey(channelClusterConfig.ChannelId, channelClusterConfig.ChannelType), wk.noSync); err != nil {
@@ -520,7 +519,7 @@ func (wk *wukongDB) deleteChannelClusterConfigIndex(primaryKey uint64, channelCl
 	return nil
 }
 
-func (wk *wukongDB) iteratorChannelClusterConfig(iter *pebble.Iterator, iterFnc func(cfg ChannelClusterConfig) bool) error {
+func (wk *wukongDB) iteratorChannelClusterConfig(iter Iterator, iterFnc func(cfg ChannelClusterConfig) bool) error {
 
 	var (
 		preId                   uint64
@@ -0,0 +1,174 @@
+package wkdb
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"go.uber.org/zap"
+)
+
+// 话题数据与父频道（社区）存放在同一个db内，方便按社区列出话题
+func (wk *wukongDB) AddOrUpdateChannelTopic(topic ChannelTopic) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("AddOrUpdateChannelTopic done", zap.Duration("cost", cost), zap.String("parentChannelId", topic.ParentChannelId), zap.String("topicId", topic.TopicId))
+			}
+		}()
+	}
+
+	db := wk.channelDb(topic.ParentChannelId, topic.ParentChannelType)
+
+	// 更新时保留原来的创建时间
+	if topic.CreatedAt == nil {
+		oldTopic, err := wk.GetChannelTopic(topic.ParentChannelId, topic.ParentChannelType, topic.TopicId)
+		if err != nil {
+			return err
+		}
+		if !IsEmptyChannelTopic(oldTopic) {
+			topic.CreatedAt = oldTopic.CreatedAt
+		}
+	}
+
+	batch := db.NewBatch()
+	defer batch.Close()
+	if err := wk.writeChannelTopic(topic, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetChannelTopic(parentChannelId string, parentChannelType uint8, topicId string) (ChannelTopic, error) {
+	iter := wk.channelDb(parentChannelId, parentChannelType).NewIter(&IterOptions{
+		LowerBound: key.NewChannelTopicColumnKey(parentChannelId, parentChannelType, topicId, key.MinColumnKey),
+		UpperBound: key.NewChannelTopicColumnKey(parentChannelId, parentChannelType, topicId, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	topic := EmptyChannelTopic
+	err := wk.iterChannelTopic(iter, func(t ChannelTopic) bool {
+		topic = t
+		return false
+	})
+	if err != nil {
+		return EmptyChannelTopic, err
+	}
+	if IsEmptyChannelTopic(topic) {
+		return EmptyChannelTopic, nil
+	}
+	topic.ParentChannelId = parentChannelId
+	topic.ParentChannelType = parentChannelType
+	return topic, nil
+}
+
+func (wk *wukongDB) GetChannelTopics(parentChannelId string, parentChannelType uint8) ([]ChannelTopic, error) {
+	iter := wk.channelDb(parentChannelId, parentChannelType).NewIter(&IterOptions{
+		LowerBound: key.NewChannelTopicLowKey(parentChannelId, parentChannelType),
+		UpperBound: key.NewChannelTopicHighKey(parentChannelId, parentChannelType),
+	})
+	defer iter.Close()
+
+	var topics []ChannelTopic
+	err := wk.iterChannelTopic(iter, func(t ChannelTopic) bool {
+		t.ParentChannelId = parentChannelId
+		t.ParentChannelType = parentChannelType
+		topics = append(topics, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+func (wk *wukongDB) writeChannelTopic(topic ChannelTopic, w Writer) error {
+	var err error
+
+	// topicId
+	if err = w.Set(key.NewChannelTopicColumnKey(topic.ParentChannelId, topic.ParentChannelType, topic.TopicId, key.TableChannelTopic.Column.TopicId), []byte(topic.TopicId), wk.noSync); err != nil {
+		return err
+	}
+
+	// name
+	if err = w.Set(key.NewChannelTopicColumnKey(topic.ParentChannelId, topic.ParentChannelType, topic.TopicId, key.TableChannelTopic.Column.Name), []byte(topic.Name), wk.noSync); err != nil {
+		return err
+	}
+
+	// archived
+	if err = w.Set(key.NewChannelTopicColumnKey(topic.ParentChannelId, topic.ParentChannelType, topic.TopicId, key.TableChannelTopic.Column.Archived), []byte{wkutil.BoolToUint8(topic.Archived)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if topic.CreatedAt != nil {
+		createdAt := make([]byte, 8)
+		wk.endian.PutUint64(createdAt, uint64(topic.CreatedAt.UnixNano()))
+		if err = w.Set(key.NewChannelTopicColumnKey(topic.ParentChannelId, topic.ParentChannelType, topic.TopicId, key.TableChannelTopic.Column.CreatedAt), createdAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// updatedAt
+	if topic.UpdatedAt != nil {
+		updatedAt := make([]byte, 8)
+		wk.endian.PutUint64(updatedAt, uint64(topic.UpdatedAt.UnixNano()))
+		if err = w.Set(key.NewChannelTopicColumnKey(topic.ParentChannelId, topic.ParentChannelType, topic.TopicId, key.TableChannelTopic.Column.UpdatedAt), updatedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) iterChannelTopic(iter Iterator, iterFnc func(topic ChannelTopic) bool) error {
+	var (
+		preTopicHash   uint64
+		preTopic       ChannelTopic
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		topicHash, columnName, err := key.ParseChannelTopicColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if topicHash != preTopicHash {
+			if hasData {
+				if !iterFnc(preTopic) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preTopicHash = topicHash
+			preTopic = ChannelTopic{}
+		}
+
+		switch columnName {
+		case key.TableChannelTopic.Column.TopicId:
+			preTopic.TopicId = string(iter.Value())
+		case key.TableChannelTopic.Column.Name:
+			preTopic.Name = string(iter.Value())
+		case key.TableChannelTopic.Column.Archived:
+			preTopic.Archived = wkutil.Uint8ToBool(iter.Value()[0])
+		case key.TableChannelTopic.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preTopic.CreatedAt = &t
+			}
+		case key.TableChannelTopic.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preTopic.UpdatedAt = &t
+			}
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preTopic)
+	}
+	return nil
+}
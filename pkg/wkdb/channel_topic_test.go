@@ -0,0 +1,69 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddOrUpdateChannelTopic(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	topic := wkdb.ChannelTopic{
+		ParentChannelId:   "community",
+		ParentChannelType: 4,
+		TopicId:           "topic1",
+		Name:              "topic one",
+		CreatedAt:         &createdAt,
+		UpdatedAt:         &createdAt,
+	}
+	err = d.AddOrUpdateChannelTopic(topic)
+	assert.NoError(t, err)
+
+	err = d.AddOrUpdateChannelTopic(wkdb.ChannelTopic{
+		ParentChannelId:   "community",
+		ParentChannelType: 4,
+		TopicId:           "topic2",
+		Name:              "topic two",
+	})
+	assert.NoError(t, err)
+
+	// 其他社区的话题
+	err = d.AddOrUpdateChannelTopic(wkdb.ChannelTopic{
+		ParentChannelId:   "other",
+		ParentChannelType: 4,
+		TopicId:           "topic1",
+	})
+	assert.NoError(t, err)
+
+	// 归档
+	topic.Archived = true
+	topic.CreatedAt = nil
+	err = d.AddOrUpdateChannelTopic(topic)
+	assert.NoError(t, err)
+
+	result, err := d.GetChannelTopic("community", 4, "topic1")
+	assert.NoError(t, err)
+	assert.Equal(t, "topic one", result.Name)
+	assert.True(t, result.Archived)
+	assert.Equal(t, createdAt.UnixNano(), result.CreatedAt.UnixNano())
+	assert.Equal(t, "community@topic1", result.ChannelId())
+
+	result, err = d.GetChannelTopic("community", 4, "notexist")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyChannelTopic(result))
+
+	topics, err := d.GetChannelTopics("community", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(topics))
+}
@@ -0,0 +1,46 @@
+package wkdb_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	tn := time.Now()
+	err = d.AddUser(wkdb.User{Uid: "test", CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "checkpoint")
+	err = d.Checkpoint(dir)
+	assert.NoError(t, err)
+
+	// 快照之后写入的数据不在快照中
+	err = d.AddUser(wkdb.User{Uid: "test2", CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+	err = d.Close()
+	assert.NoError(t, err)
+
+	restored := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err = restored.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := restored.Close()
+		assert.NoError(t, err)
+	}()
+
+	u, err := restored.GetUser("test")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", u.Uid)
+
+	exist, err := restored.ExistUser("test2")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+}
@@ -5,7 +5,7 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
 	"go.uber.org/zap"
 )
 
@@ -46,6 +46,18 @@ func (wk *wukongDB) AddOrUpdateConversations(uid string, conversations []Convers
 
 		if exist {
 			cn.CreatedAt = nil // 更新时不更新创建时间
+
+			// 没有携带@信息的更新保留还未读的@
+			if cn.MentionMsgSeq == 0 && oldConversation.MentionMsgSeq > cn.ReadToMsgSeq {
+				cn.MentionMsgSeq = oldConversation.MentionMsgSeq
+				cn.MentionAll = oldConversation.MentionAll
+			}
+		}
+
+		// 已读至的消息序号超过@的消息后清除@
+		if cn.MentionMsgSeq <= cn.ReadToMsgSeq {
+			cn.MentionMsgSeq = 0
+			cn.MentionAll = false
 		}
 
 		if err := wk.writeConversation(cn, batch); err != nil {
@@ -65,7 +77,7 @@ func (wk *wukongDB) AddOrUpdateConversations(uid string, conversations []Convers
 func (wk *wukongDB) GetConversations(uid string) ([]Conversation, error) {
 
 	db := wk.shardDB(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewConversationPrimaryKey(uid, 0),
 		UpperBound: key.NewConversationPrimaryKey(uid, math.MaxUint64),
 	})
@@ -85,7 +97,7 @@ func (wk *wukongDB) GetConversations(uid string) ([]Conversation, error) {
 func (wk *wukongDB) GetConversationsByType(uid string, tp ConversationType) ([]Conversation, error) {
 
 	db := wk.shardDB(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewConversationPrimaryKey(uid, 0),
 		UpperBound: key.NewConversationPrimaryKey(uid, math.MaxUint64),
 	})
@@ -134,7 +146,7 @@ func (wk *wukongDB) GetLastConversations(uid string, tp ConversationType, update
 
 func (wk *wukongDB) getLastConversationIds(uid string, updatedAt uint64, limit int) ([]uint64, error) {
 	db := wk.shardDB(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewConversationSecondIndexKey(uid, key.TableConversation.SecondIndex.UpdatedAt, updatedAt, 0),
 		UpperBound: key.NewConversationSecondIndexKey(uid, key.TableConversation.SecondIndex.UpdatedAt, math.MaxUint64, math.MaxUint64),
 	})
@@ -194,7 +206,7 @@ func (wk *wukongDB) SearchConversation(req ConversationSearchReq) ([]Conversatio
 	var conversations []Conversation
 	currentSize := 0
 	for _, db := range wk.dbs {
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewConversationUidHashKey(0),
 			UpperBound: key.NewConversationUidHashKey(math.MaxUint64),
 		})
@@ -218,7 +230,7 @@ func (wk *wukongDB) SearchConversation(req ConversationSearchReq) ([]Conversatio
 	return conversations, nil
 }
 
-func (wk *wukongDB) deleteConversation(uid string, channelId string, channelType uint8, w pebble.Writer) error {
+func (wk *wukongDB) deleteConversation(uid string, channelId string, channelType uint8, w Writer) error {
 	oldConversation, err := wk.GetConversation(uid, channelId, channelType)
 	if err != nil && err != ErrNotFound {
 		return err
@@ -252,7 +264,7 @@ func (wk *wukongDB) GetConversation(uid string, channelId string, channelType ui
 		return EmptyConversation, ErrNotFound
 	}
 
-	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
 		LowerBound: key.NewConversationColumnKey(uid, id, key.MinColumnKey),
 		UpperBound: key.NewConversationColumnKey(uid, id, key.MaxColumnKey),
 	})
@@ -277,7 +289,7 @@ func (wk *wukongDB) GetConversation(uid string, channelId string, channelType ui
 func (wk *wukongDB) ExistConversation(uid string, channelId string, channelType uint8) (bool, error) {
 	idBytes, closer, err := wk.shardDB(uid).Get(key.NewConversationIndexChannelKey(uid, channelId, channelType))
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return false, nil
 		}
 		return false, err
@@ -291,7 +303,7 @@ func (wk *wukongDB) ExistConversation(uid string, channelId string, channelType
 }
 
 func (wk *wukongDB) getConversation(uid string, id uint64) (Conversation, error) {
-	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
 		LowerBound: key.NewConversationColumnKey(uid, id, key.MinColumnKey),
 		UpperBound: key.NewConversationColumnKey(uid, id, key.MaxColumnKey),
 	})
@@ -314,7 +326,7 @@ func (wk *wukongDB) getConversation(uid string, id uint64) (Conversation, error)
 }
 
 // func (wk *wukongDB) getConversationIdsByUid(uid string) ([]uint64, error) {
-// 	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+// 	iter := wk.shardDB(uid).NewIter(&IterOptions{
 // 		LowerBound: key.NewConversationPrimaryKey(uid, 0),
 // 		UpperBound: key.NewConversationPrimaryKey(uid, math.MaxUint64),
 // 	})
@@ -332,7 +344,7 @@ func (wk *wukongDB) getConversation(uid string, id uint64) (Conversation, error)
 // 	return ids, nil
 // }
 
-// func (wk *wukongDB) updateOrAddReadedToMsgSeq(uid string, sessionId uint64, msgSeq uint64, w pebble.Writer) error {
+// func (wk *wukongDB) updateOrAddReadedToMsgSeq(uid string, sessionId uint64, msgSeq uint64, w Writer) error {
 // 	id, err := wk.getConversationIdBySession(uid, sessionId)
 // 	if err != nil {
 // 		return err
@@ -357,7 +369,7 @@ func (wk *wukongDB) getConversation(uid string, id uint64) (Conversation, error)
 func (wk *wukongDB) getConversationByChannel(uid string, channelId string, channelType uint8) (uint64, error) {
 	idBytes, closer, err := wk.shardDB(uid).Get(key.NewConversationIndexChannelKey(uid, channelId, channelType))
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return 0, nil
 		}
 		return 0, err
@@ -374,7 +386,7 @@ func (wk *wukongDB) getConversationByChannel(uid string, channelId string, chann
 
 }
 
-func (wk *wukongDB) writeConversation(conversation Conversation, w pebble.Writer) error {
+func (wk *wukongDB) writeConversation(conversation Conversation, w Writer) error {
 	var (
 		err error
 	)
@@ -415,6 +427,18 @@ func (wk *wukongDB) writeConversation(conversation Conversation, w pebble.Writer
 		return err
 	}
 
+	// mentionMsgSeq
+	var mentionMsgSeqBytes = make([]byte, 8)
+	wk.endian.PutUint64(mentionMsgSeqBytes, conversation.MentionMsgSeq)
+	if err = w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.MentionMsgSeq), mentionMsgSeqBytes, wk.noSync); err != nil {
+		return err
+	}
+
+	// mentionAll
+	if err = w.Set(key.NewConversationColumnKey(uid, id, key.TableConversation.Column.MentionAll), []byte{wkutil.BoolToUint8(conversation.MentionAll)}, wk.noSync); err != nil {
+		return err
+	}
+
 	// createdAt
 	if conversation.CreatedAt != nil {
 		createdAtBytes := make([]byte, 8)
@@ -443,7 +467,7 @@ func (wk *wukongDB) writeConversation(conversation Conversation, w pebble.Writer
 	return nil
 }
 
-func (wk *wukongDB) writeConversationIndex(conversation Conversation, w pebble.Writer) error {
+func (wk *wukongDB) writeConversationIndex(conversation Conversation, w Writer) error {
 
 	idBytes := make([]byte, 8)
 	wk.endian.PutUint64(idBytes, conversation.Id)
@@ -475,7 +499,7 @@ func (wk *wukongDB) writeConversationIndex(conversation Conversation, w pebble.W
 	return nil
 }
 
-func (wk *wukongDB) deleteConversationIndex(conversation Conversation, w pebble.Writer) error {
+func (wk *wukongDB) deleteConversationIndex(conversation Conversation, w Writer) error {
 	// channel index
 	if err := w.Delete(key.NewConversationIndexChannelKey(conversation.Uid, conversation.ChannelId, conversation.ChannelType), wk.noSync); err != nil {
 		return err
@@ -503,7 +527,7 @@ func (wk *wukongDB) deleteConversationIndex(conversation Conversation, w pebble.
 	return nil
 }
 
-func (wk *wukongDB) iterateConversation(iter *pebble.Iterator, iterFnc func(conversation Conversation) bool) error {
+func (wk *wukongDB) iterateConversation(iter Iterator, iterFnc func(conversation Conversation) bool) error {
 	var (
 		preId           uint64
 		preConversation Conversation
@@ -543,6 +567,10 @@ func (wk *wukongDB) iterateConversation(iter *pebble.Iterator, iterFnc func(conv
 			preConversation.UnreadCount = wk.endian.Uint32(iter.Value())
 		case key.TableConversation.Column.ReadedToMsgSeq:
 			preConversation.ReadToMsgSeq = wk.endian.Uint64(iter.Value())
+		case key.TableConversation.Column.MentionMsgSeq:
+			preConversation.MentionMsgSeq = wk.endian.Uint64(iter.Value())
+		case key.TableConversation.Column.MentionAll:
+			preConversation.MentionAll = wkutil.Uint8ToBool(iter.Value()[0])
 		case key.TableConversation.Column.CreatedAt:
 			tm := int64(wk.endian.Uint64(iter.Value()))
 			if tm > 0 {
@@ -567,7 +595,7 @@ func (wk *wukongDB) iterateConversation(iter *pebble.Iterator, iterFnc func(conv
 	return nil
 }
 
-// func (wk *wukongDB) parseConversations(iter *pebble.Iterator, limit int) ([]Conversation, error) {
+// func (wk *wukongDB) parseConversations(iter Iterator, limit int) ([]Conversation, error) {
 // 	var (
 // 		conversations   = make([]Conversation, 0)
 // 		preId           uint64
@@ -155,6 +155,92 @@ func TestDeleteConversation(t *testing.T) {
 	assert.Equal(t, conversations[1], conversations2[0])
 }
 
+func TestConversationMention(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	uid := "test1"
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{
+			Id:            1,
+			Uid:           uid,
+			ChannelId:     "g1",
+			ChannelType:   2,
+			ReadToMsgSeq:  5,
+			MentionMsgSeq: 8,
+			MentionAll:    true,
+		},
+	})
+	assert.NoError(t, err)
+
+	conversation, err := d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), conversation.MentionMsgSeq)
+	assert.True(t, conversation.MentionAll)
+
+	// 没有携带@信息并且没有读过@的消息，保留@
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{
+			Uid:          uid,
+			ChannelId:    "g1",
+			ChannelType:  2,
+			ReadToMsgSeq: 7,
+		},
+	})
+	assert.NoError(t, err)
+	conversation, err = d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), conversation.ReadToMsgSeq)
+	assert.Equal(t, uint64(8), conversation.MentionMsgSeq)
+
+	// 读过@的消息后清除
+	err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+		{
+			Uid:          uid,
+			ChannelId:    "g1",
+			ChannelType:  2,
+			ReadToMsgSeq: 8,
+		},
+	})
+	assert.NoError(t, err)
+	conversation, err = d.GetConversation(uid, "g1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), conversation.MentionMsgSeq)
+	assert.False(t, conversation.MentionAll)
+}
+
+func TestConversationMarshalMention(t *testing.T) {
+	conversation := wkdb.Conversation{
+		Id:            1,
+		Uid:           "u1",
+		ChannelId:     "g1",
+		ChannelType:   2,
+		ReadToMsgSeq:  5,
+		MentionMsgSeq: 8,
+		MentionAll:    true,
+	}
+	data, err := conversation.Marshal()
+	assert.NoError(t, err)
+
+	conversation2 := wkdb.Conversation{}
+	err = conversation2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, conversation, conversation2)
+
+	// 兼容旧版本的数据
+	conversation3 := wkdb.Conversation{}
+	err = conversation3.Unmarshal(data[:len(data)-9])
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), conversation3.MentionMsgSeq)
+	assert.Equal(t, uint64(5), conversation3.ReadToMsgSeq)
+}
+
 // func TestGetConversationBySessionIds(t *testing.T) {
 // 	d := newTestDB(t)
 // 	err := d.Open()
@@ -3,6 +3,8 @@ package wkdb
 type DB interface {
 	Open() error
 	Close() error
+	// Checkpoint 在指定目录生成数据库的快照（用于在线备份），每个分片各自一致，分片之间不是同一时间点
+	Checkpoint(dir string) error
 	// 获取下一个主键
 	NextPrimaryKey() uint64
 	// 消息
@@ -25,6 +27,30 @@ type DB interface {
 	TotalDB
 	//	系统账号
 	SystemUidDB
+	// 消息全文检索索引
+	MessageSearchIndexDB
+	// 社区话题
+	ChannelTopicDB
+	// 已吊销的连接令牌
+	RevokedTokenDB
+	// 定时消息
+	ScheduledMessageDB
+	BroadcastTaskDB
+
+	AttributeDB
+
+	// 用户数据的导出和删除
+	UserDataDB
+
+	// 端到端加密的设备公钥
+	E2EEKeyDB
+
+	// 消息的子区
+	ThreadDB
+
+	// 机器人
+	BotDB
+	UserPresenceDB
 }
 
 type MessageDB interface {
@@ -111,6 +137,9 @@ type UserDB interface {
 
 	// UpdateUser 更新用户
 	UpdateUser(u User) error
+
+	// GetUsersAfter 按主键顺序获取主键大于id的用户（分批遍历所有用户）
+	GetUsersAfter(id uint64, limit int) ([]User, error)
 }
 
 type ChannelDB interface {
@@ -129,6 +158,15 @@ type ChannelDB interface {
 	// GetSubscribers 获取订阅者
 	GetSubscribers(channelId string, channelType uint8) ([]Member, error)
 
+	// GetSubscriber 获取指定订阅者，不存在返回EmptyMember
+	GetSubscriber(channelId string, channelType uint8, uid string) (Member, error)
+
+	// UpdateSubscribersRole 更新订阅者的角色（不存在的订阅者忽略）
+	UpdateSubscribersRole(channelId string, channelType uint8, uids []string, role MemberRole) error
+
+	// UpdateSubscribersMute 更新订阅者的禁言到期时间（unix秒，0表示解除禁言，不存在的订阅者忽略）
+	UpdateSubscribersMute(channelId string, channelType uint8, uids []string, muteExpireAt uint64) error
+
 	// AddOrUpdateChannel  添加或更新channel
 	AddChannel(channelInfo ChannelInfo) (uint64, error)
 	// UpdateChannel 更新channel
@@ -338,6 +376,137 @@ type SystemUidDB interface {
 	GetSystemUids() ([]string, error)
 }
 
+type MessageSearchIndexDB interface {
+	// AddMessageSearchIndex 添加消息的全文检索索引（Terms为分词后的关键词）
+	AddMessageSearchIndex(reqs []MessageSearchIndexReq) error
+
+	// SearchMessageIndex 在频道内检索同时包含所有关键词的消息，返回消息seq（降序）
+	// offsetMessageSeq=0 表示从最新的消息开始检索，否则只返回小于offsetMessageSeq的消息
+	SearchMessageIndex(channelId string, channelType uint8, terms []string, offsetMessageSeq uint64, limit int) ([]uint64, error)
+
+	// RemoveExpiredMessageSearchIndex 移除过期时间小于等于now(秒)的消息检索索引，返回移除的消息数量
+	RemoveExpiredMessageSearchIndex(now uint64, limit int) (int, error)
+
+	// SetMessageSearchIndexedSeq 设置频道已建立检索索引的消息seq
+	SetMessageSearchIndexedSeq(channelId string, channelType uint8, messageSeq uint64) error
+
+	// GetMessageSearchIndexedSeq 获取频道已建立检索索引的消息seq
+	GetMessageSearchIndexedSeq(channelId string, channelType uint8) (uint64, error)
+}
+
+type ChannelTopicDB interface {
+	// AddOrUpdateChannelTopic 添加或更新社区话题
+	AddOrUpdateChannelTopic(topic ChannelTopic) error
+
+	// GetChannelTopic 获取社区下指定的话题，不存在返回EmptyChannelTopic
+	GetChannelTopic(parentChannelId string, parentChannelType uint8, topicId string) (ChannelTopic, error)
+
+	// GetChannelTopics 获取社区下的所有话题
+	GetChannelTopics(parentChannelId string, parentChannelType uint8) ([]ChannelTopic, error)
+}
+
+type RevokedTokenDB interface {
+	// AddRevokedToken 添加已吊销的令牌
+	AddRevokedToken(t RevokedToken) error
+
+	// ExistRevokedToken 令牌是否已被吊销（吊销记录过期后视为未吊销）
+	ExistRevokedToken(uid string, tokenId string) (bool, error)
+
+	// RemoveExpiredRevokedTokens 移除过期时间小于等于now(秒)的吊销记录，返回移除的记录数量
+	RemoveExpiredRevokedTokens(now uint64, limit int) (int, error)
+}
+
+type ScheduledMessageDB interface {
+	// AddOrUpdateScheduledMessage 添加或更新定时消息
+	AddOrUpdateScheduledMessage(m ScheduledMessage) error
+
+	// GetScheduledMessage 获取定时消息，不存在返回EmptyScheduledMessage
+	GetScheduledMessage(id uint64) (ScheduledMessage, error)
+
+	// GetScheduledMessagesOfChannel 获取频道的定时消息（按发送时间排序）
+	GetScheduledMessagesOfChannel(channelId string, channelType uint8) ([]ScheduledMessage, error)
+
+	// GetDueScheduledMessages 获取槽内发送时间小于或等于sendAt的定时消息（按发送时间排序），limit为0表示不限制
+	GetDueScheduledMessages(slotId uint32, sendAt int64, limit int) ([]ScheduledMessage, error)
+
+	// DeleteScheduledMessage 删除定时消息
+	DeleteScheduledMessage(id uint64) error
+}
+
+type BroadcastTaskDB interface {
+	// AddBroadcastTask 添加广播任务
+	AddBroadcastTask(t BroadcastTask) error
+
+	// UpdateBroadcastTaskProgress 更新广播任务的状态和进度，已结束的任务不再更新
+	UpdateBroadcastTaskProgress(t BroadcastTask) error
+
+	// CancelBroadcastTask 取消广播任务，任务不存在或已结束时忽略
+	CancelBroadcastTask(jobId uint64, slotId uint32) error
+
+	// GetBroadcastTask 获取广播任务，不存在返回EmptyBroadcastTask
+	GetBroadcastTask(jobId uint64, slotId uint32) (BroadcastTask, error)
+
+	// GetBroadcastTasks 获取广播的所有任务，jobId为0时获取所有广播的任务
+	GetBroadcastTasks(jobId uint64) ([]BroadcastTask, error)
+}
+
+type AttributeDB interface {
+	// SetUserAttributes 设置用户属性，attrs的Version为期望的当前版本（不存在的属性为0），版本不一致的属性不修改，
+	// 修改后的版本为当前版本加1。expectVersion为false时不检查版本。
+	// 在集群中这里是日志应用阶段，无法把版本不一致返回给请求方，调用方需要在提交前校验版本并保证校验和提交之间没有其他修改
+	SetUserAttributes(uid string, attrs []Attribute, expectVersion bool) error
+
+	// GetUserAttributes 获取用户属性（按属性名排序），names为空时获取所有属性
+	GetUserAttributes(uid string, names []string) ([]Attribute, error)
+
+	// DeleteUserAttributes 删除用户属性，names为空时删除所有属性
+	DeleteUserAttributes(uid string, names []string) error
+
+	// SetChannelAttributes 设置频道属性，版本规则同SetUserAttributes
+	SetChannelAttributes(channelId string, channelType uint8, attrs []Attribute, expectVersion bool) error
+
+	// GetChannelAttributes 获取频道属性（按属性名排序），names为空时获取所有属性
+	GetChannelAttributes(channelId string, channelType uint8, names []string) ([]Attribute, error)
+
+	// DeleteChannelAttributes 删除频道属性，names为空时删除所有属性
+	DeleteChannelAttributes(channelId string, channelType uint8, names []string) error
+}
+
+type UserDataDB interface {
+	// AddOrUpdateUserDataJob 添加或更新用户数据任务
+	AddOrUpdateUserDataJob(j UserDataJob) error
+
+	// GetUserDataJob 获取用户数据任务，不存在返回EmptyUserDataJob
+	GetUserDataJob(slotId uint32, id uint64) (UserDataJob, error)
+
+	// GetUserDataJobs 获取槽内的所有用户数据任务（按任务ID排序）
+	GetUserDataJobs(slotId uint32) ([]UserDataJob, error)
+
+	// RemoveUserDataJob 移除用户数据任务
+	RemoveUserDataJob(slotId uint32, id uint64) error
+
+	// EraseUser 删除用户和用户所在槽内的数据（用户、设备、最近会话、用户属性）
+	EraseUser(uid string) error
+
+	// GetSubscribedChannels 获取用户在本地数据中订阅的频道
+	GetSubscribedChannels(uid string) ([]Channel, error)
+
+	// GetMessagesOfFromUid 分页获取本地数据中用户发送的消息
+	GetMessagesOfFromUid(uid string, cursor MessageCursor, limit int) ([]Message, MessageCursor, error)
+
+	// AnonymizeMessagesOfFromUid 匿名化本地数据中用户发送的消息（清空发送者和消息内容，删除发送者索引和全文检索索引），
+	// 返回匿名化的消息数量，返回0表示已全部处理
+	AnonymizeMessagesOfFromUid(uid string, limit int) (int, error)
+}
+
+type MessageSearchIndexReq struct {
+	ChannelId   string   // 频道id
+	ChannelType uint8    // 频道类型
+	MessageSeq  uint64   // 消息seq
+	ExpireAt    uint64   // 消息过期时间（秒） 0表示永不过期
+	Terms       []string // 分词后的关键词
+}
+
 type MessageSearchReq struct {
 	MessageId        int64
 	FromUid          string // 发送者uid
@@ -405,3 +574,63 @@ type ChannelClusterConfigSearchReq struct {
 	Pre             bool   // 是否向前搜索
 
 }
+
+type E2EEKeyDB interface {
+	// AddOrUpdateE2EEDeviceKeys 添加或更新设备的身份公钥和签名预共享公钥，并添加一次性预共享公钥
+	// 身份公钥发生变化时（设备重装等）删除设备旧的一次性预共享公钥
+	AddOrUpdateE2EEDeviceKeys(keys E2EEDeviceKeys, oneTimePrekeys []E2EEPrekey) error
+
+	// GetE2EEDeviceKeys 获取用户所有设备的公钥
+	GetE2EEDeviceKeys(uid string) ([]E2EEDeviceKeys, error)
+
+	// GetE2EEDeviceKey 获取设备的公钥，不存在返回EmptyE2EEDeviceKeys
+	GetE2EEDeviceKey(uid string, deviceId string) (E2EEDeviceKeys, error)
+
+	// DeleteE2EEDeviceKeys 删除设备的公钥和一次性预共享公钥
+	DeleteE2EEDeviceKeys(uid string, deviceId string) error
+
+	// AddE2EEOneTimePrekeys 添加设备的一次性预共享公钥，key_id相同的会覆盖
+	AddE2EEOneTimePrekeys(uid string, deviceId string, prekeys []E2EEPrekey) error
+
+	// GetE2EEOneTimePrekeys 按key_id从小到大获取设备的一次性预共享公钥
+	GetE2EEOneTimePrekeys(uid string, deviceId string, limit int) ([]E2EEPrekey, error)
+
+	// RemoveE2EEOneTimePrekeys 移除设备的一次性预共享公钥（被领取后移除）
+	RemoveE2EEOneTimePrekeys(uid string, deviceId string, keyIds []uint32) error
+
+	// GetE2EEOneTimePrekeyCount 获取设备剩余的一次性预共享公钥数量
+	GetE2EEOneTimePrekeyCount(uid string, deviceId string) (int, error)
+}
+
+type ThreadDB interface {
+	// GetThread 获取频道内指定根消息的子区，不存在返回EmptyThread
+	GetThread(channelId string, channelType uint8, rootMessageSeq uint64) (Thread, error)
+
+	// GetThreads 获取频道内的子区，按最后回复时间从新到旧排序，limit为0时返回全部
+	GetThreads(channelId string, channelType uint8, limit int) ([]Thread, error)
+
+	// GetMessageExtras 获取频道内[startMessageSeq,endMessageSeq]范围的消息扩展数据，没有扩展数据的消息不返回
+	GetMessageExtras(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]MessageExtra, error)
+}
+
+type BotDB interface {
+	// AddOrUpdateBot 添加或更新机器人
+	AddOrUpdateBot(bot Bot) error
+
+	// GetBot 获取机器人，不存在返回EmptyBot
+	GetBot(uid string) (Bot, error)
+
+	// GetBots 获取所有机器人
+	GetBots() ([]Bot, error)
+
+	// RemoveBot 移除机器人
+	RemoveBot(uid string) error
+}
+
+type UserPresenceDB interface {
+	// SetUserPresence 保存用户的在线状态
+	SetUserPresence(p UserPresence) error
+
+	// GetUserPresence 获取用户的在线状态，不存在返回EmptyUserPresence
+	GetUserPresence(uid string) (UserPresence, error)
+}
@@ -5,7 +5,6 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
 
@@ -36,7 +35,7 @@ func (wk *wukongDB) AddDenylist(channelId string, channelType uint8, members []M
 }
 
 func (wk *wukongDB) GetDenylist(channelId string, channelType uint8) ([]Member, error) {
-	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
 		LowerBound: key.NewDenylistPrimaryKey(channelId, channelType, 0),
 		UpperBound: key.NewDenylistPrimaryKey(channelId, channelType, math.MaxUint64),
 	})
@@ -56,7 +55,7 @@ func (wk *wukongDB) ExistDenylist(channelId string, channelType uint8, uid strin
 		defer closer.Close()
 	}
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return false, nil
 		}
 		return false, err
@@ -74,7 +73,7 @@ func (wk *wukongDB) RemoveDenylist(channelId string, channelType uint8, uids []s
 
 	members, err := wk.getDenylistByUids(channelId, channelType, uids)
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return nil
 		}
 		return err
@@ -130,7 +129,7 @@ func (wk *wukongDB) RemoveAllDenylist(channelId string, channelType uint8) error
 	return batch.Commit(wk.sync)
 }
 
-func (wk *wukongDB) removeDenylist(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) removeDenylist(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -152,7 +151,7 @@ func (wk *wukongDB) getDenylistByUids(channelId string, channelType uint8, uids
 	db := wk.channelDb(channelId, channelType)
 	for _, uid := range uids {
 		id := key.HashWithString(uid)
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewDenylistColumnKey(channelId, channelType, id, key.MinColumnKey),
 			UpperBound: key.NewDenylistColumnKey(channelId, channelType, id, key.MaxColumnKey),
 		})
@@ -170,7 +169,7 @@ func (wk *wukongDB) getDenylistByUids(channelId string, channelType uint8, uids
 	return members, nil
 }
 
-func (wk *wukongDB) writeDenylist(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) writeDenylist(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -219,7 +218,7 @@ func (wk *wukongDB) writeDenylist(channelId string, channelType uint8, member Me
 	return nil
 }
 
-func (wk *wukongDB) iterateDenylist(iter *pebble.Iterator, iterFnc func(member Member) bool) error {
+func (wk *wukongDB) iterateDenylist(iter Iterator, iterFnc func(member Member) bool) error {
 	var (
 		preId          uint64
 		preMember      Member
@@ -271,13 +270,13 @@ func (wk *wukongDB) iterateDenylist(iter *pebble.Iterator, iterFnc func(member M
 }
 
 // 增加频道黑名单数量
-func (wk *wukongDB) incChannelInfoDenylistCount(id uint64, count int, batch *pebble.Batch) error {
+func (wk *wukongDB) incChannelInfoDenylistCount(id uint64, count int, batch Batch) error {
 	wk.dblock.denylistCountLock.lock(id)
 	defer wk.dblock.denylistCountLock.unlock(id)
 	return wk.incChannelInfoColumnCount(id, key.TableChannelInfo.Column.DenylistCount, key.TableChannelInfo.SecondIndex.DenylistCount, count, batch)
 }
 
-func (wk *wukongDB) deleteAllDenylistIndex(channelId string, channelType uint8, w pebble.Writer) error {
+func (wk *wukongDB) deleteAllDenylistIndex(channelId string, channelType uint8, w Writer) error {
 	var err error
 	// uid index
 	if err = w.DeleteRange(key.NewDenylistIndexKey(channelId, channelType, key.TableDenylist.Index.Uid, 0), key.NewDenylistIndexKey(channelId, channelType, key.TableDenylist.Index.Uid, math.MaxUint64), wk.noSync); err != nil {
@@ -297,7 +296,7 @@ func (wk *wukongDB) deleteAllDenylistIndex(channelId string, channelType uint8,
 	return nil
 }
 
-func (wk *wukongDB) deleteDenylistIndex(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) deleteDenylistIndex(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -6,12 +6,11 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 )
 
 func (wk *wukongDB) getDeviceId(uid string, deviceFlag uint64) (uint64, error) {
 	db := wk.shardDB(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.Uid, key.HashWithString(uid), 0),
 		UpperBound: key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.Uid, key.HashWithString(uid), math.MaxUint64),
 	})
@@ -34,8 +33,8 @@ func (wk *wukongDB) getDeviceId(uid string, deviceFlag uint64) (uint64, error) {
 	return 0, ErrNotFound
 }
 
-func (wk *wukongDB) getDeviceById(id uint64, db *pebble.DB) (Device, error) {
-	iter := db.NewIter(&pebble.IterOptions{
+func (wk *wukongDB) getDeviceById(id uint64, db KV) (Device, error) {
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewDeviceColumnKey(id, key.MinColumnKey),
 		UpperBound: key.NewDeviceColumnKey(id, key.MaxColumnKey),
 	})
@@ -69,7 +68,7 @@ func (wk *wukongDB) GetDevice(uid string, deviceFlag uint64) (Device, error) {
 	}
 
 	db := wk.shardDB(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewDeviceColumnKey(id, key.MinColumnKey),
 		UpperBound: key.NewDeviceColumnKey(id, key.MaxColumnKey),
 	})
@@ -97,7 +96,7 @@ func (wk *wukongDB) GetDevices(uid string) ([]Device, error) {
 
 	db := wk.shardDB(uid)
 	uidHash := key.HashWithString(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewDeviceSecondIndexKey(key.TableDevice.Column.Uid, uidHash, 0),
 		UpperBound: key.NewDeviceSecondIndexKey(key.TableDevice.Column.Uid, uidHash, math.MaxUint64),
 	})
@@ -121,7 +120,7 @@ func (wk *wukongDB) GetDevices(uid string) ([]Device, error) {
 func (wk *wukongDB) GetDeviceCount(uid string) (int, error) {
 	db := wk.shardDB(uid)
 	uidHash := key.HashWithString(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewDeviceSecondIndexKey(key.TableDevice.Column.Uid, uidHash, 0),
 		UpperBound: key.NewDeviceSecondIndexKey(key.TableDevice.Column.Uid, uidHash, math.MaxUint64),
 	})
@@ -237,7 +236,7 @@ func (wk *wukongDB) SearchDevice(req DeviceSearchReq) ([]Device, error) {
 			}
 		}
 
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.CreatedAt, start, 0),
 			UpperBound: key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.CreatedAt, end, 0),
 		})
@@ -262,7 +261,7 @@ func (wk *wukongDB) SearchDevice(req DeviceSearchReq) ([]Device, error) {
 				return nil, err
 			}
 
-			dataIter := db.NewIter(&pebble.IterOptions{
+			dataIter := db.NewIter(&IterOptions{
 				LowerBound: key.NewDeviceColumnKey(id, key.MinColumnKey),
 				UpperBound: key.NewDeviceColumnKey(id, key.MaxColumnKey),
 			})
@@ -327,7 +326,7 @@ func (wk *wukongDB) searchDeviceByIndex(req DeviceSearchReq, iterFnc func(d Devi
 	return false, nil
 }
 
-func (wk *wukongDB) writeDevice(d Device, w pebble.Writer) error {
+func (wk *wukongDB) writeDevice(d Device, w Writer) error {
 	var (
 		err error
 	)
@@ -395,7 +394,7 @@ func (wk *wukongDB) writeDevice(d Device, w pebble.Writer) error {
 	return nil
 }
 
-func (wk *wukongDB) deleteDeviceIndex(old Device, w pebble.Writer) error {
+func (wk *wukongDB) deleteDeviceIndex(old Device, w Writer) error {
 	// uid index
 	if err := w.Delete(key.NewDeviceSecondIndexKey(key.TableDevice.SecondIndex.Uid, key.HashWithString(old.Uid), old.Id), wk.noSync); err != nil {
 		return err
@@ -425,7 +424,7 @@ func (wk *wukongDB) deleteDeviceIndex(old Device, w pebble.Writer) error {
 
 // 解析出设备信息
 // id !=0 时，解析出id对应的用户信息
-func (wk *wukongDB) iterDevice(iter *pebble.Iterator, iterFnc func(d Device) bool) error {
+func (wk *wukongDB) iterDevice(iter Iterator, iterFnc func(d Device) bool) error {
 	var (
 		preId          uint64
 		preDevice      Device
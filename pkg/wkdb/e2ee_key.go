@@ -0,0 +1,305 @@
+package wkdb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"go.uber.org/zap"
+)
+
+// 设备公钥与用户存放在同一个db内
+
+func (wk *wukongDB) AddOrUpdateE2EEDeviceKeys(keys E2EEDeviceKeys, oneTimePrekeys []E2EEPrekey) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("AddOrUpdateE2EEDeviceKeys done", zap.Duration("cost", cost), zap.String("uid", keys.Uid), zap.String("deviceId", keys.DeviceId))
+			}
+		}()
+	}
+
+	wk.dblock.e2eeKeyLock.lock(keys.Uid, keys.DeviceId)
+	defer wk.dblock.e2eeKeyLock.unlock(keys.Uid, keys.DeviceId)
+
+	old, err := wk.GetE2EEDeviceKey(keys.Uid, keys.DeviceId)
+	if err != nil {
+		return err
+	}
+
+	db := wk.shardDB(keys.Uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	if !IsEmptyE2EEDeviceKeys(old) {
+		keys.CreatedAt = old.CreatedAt
+		// 身份公钥变化后旧的一次性预共享公钥对应的私钥已不存在
+		if string(old.IdentityKey) != string(keys.IdentityKey) {
+			if err = batch.DeleteRange(key.NewE2EEOneTimePrekeyLowKey(keys.Uid, keys.DeviceId), key.NewE2EEOneTimePrekeyHighKey(keys.Uid, keys.DeviceId), wk.noSync); err != nil {
+				return err
+			}
+		}
+	}
+	if err = wk.writeE2EEDeviceKeys(keys, batch); err != nil {
+		return err
+	}
+	for _, prekey := range oneTimePrekeys {
+		if err = batch.Set(key.NewE2EEOneTimePrekeyColumnKey(keys.Uid, keys.DeviceId, prekey.KeyId, key.TableE2EEOneTimePrekey.Column.PublicKey), prekey.PublicKey, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetE2EEDeviceKeys(uid string) ([]E2EEDeviceKeys, error) {
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
+		LowerBound: key.NewE2EEDeviceKeyLowKey(uid),
+		UpperBound: key.NewE2EEDeviceKeyHighKey(uid),
+	})
+	defer iter.Close()
+
+	var keys []E2EEDeviceKeys
+	err := wk.iterE2EEDeviceKeys(iter, func(k E2EEDeviceKeys) bool {
+		// uid的哈希冲突时不是同一个用户
+		if k.Uid == uid {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].DeviceId < keys[j].DeviceId
+	})
+	return keys, nil
+}
+
+func (wk *wukongDB) GetE2EEDeviceKey(uid string, deviceId string) (E2EEDeviceKeys, error) {
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
+		LowerBound: key.NewE2EEDeviceKeyColumnKey(uid, deviceId, key.MinColumnKey),
+		UpperBound: key.NewE2EEDeviceKeyColumnKey(uid, deviceId, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	result := EmptyE2EEDeviceKeys
+	err := wk.iterE2EEDeviceKeys(iter, func(k E2EEDeviceKeys) bool {
+		if k.Uid == uid && k.DeviceId == deviceId {
+			result = k
+		}
+		return false
+	})
+	return result, err
+}
+
+func (wk *wukongDB) DeleteE2EEDeviceKeys(uid string, deviceId string) error {
+	wk.dblock.e2eeKeyLock.lock(uid, deviceId)
+	defer wk.dblock.e2eeKeyLock.unlock(uid, deviceId)
+
+	batch := wk.shardDB(uid).NewBatch()
+	defer batch.Close()
+
+	if err := batch.DeleteRange(key.NewE2EEDeviceKeyColumnKey(uid, deviceId, key.MinColumnKey), key.NewE2EEDeviceKeyColumnKey(uid, deviceId, key.MaxColumnKey), wk.noSync); err != nil {
+		return err
+	}
+	if err := batch.DeleteRange(key.NewE2EEOneTimePrekeyLowKey(uid, deviceId), key.NewE2EEOneTimePrekeyHighKey(uid, deviceId), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) AddE2EEOneTimePrekeys(uid string, deviceId string, prekeys []E2EEPrekey) error {
+	if len(prekeys) == 0 {
+		return nil
+	}
+	wk.dblock.e2eeKeyLock.lock(uid, deviceId)
+	defer wk.dblock.e2eeKeyLock.unlock(uid, deviceId)
+
+	batch := wk.shardDB(uid).NewBatch()
+	defer batch.Close()
+	for _, prekey := range prekeys {
+		if err := batch.Set(key.NewE2EEOneTimePrekeyColumnKey(uid, deviceId, prekey.KeyId, key.TableE2EEOneTimePrekey.Column.PublicKey), prekey.PublicKey, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetE2EEOneTimePrekeys(uid string, deviceId string, limit int) ([]E2EEPrekey, error) {
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
+		LowerBound: key.NewE2EEOneTimePrekeyLowKey(uid, deviceId),
+		UpperBound: key.NewE2EEOneTimePrekeyHighKey(uid, deviceId),
+	})
+	defer iter.Close()
+
+	var prekeys []E2EEPrekey
+	for iter.First(); iter.Valid(); iter.Next() {
+		keyId, columnName, err := key.ParseE2EEOneTimePrekeyColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if columnName != key.TableE2EEOneTimePrekey.Column.PublicKey {
+			continue
+		}
+		prekeys = append(prekeys, E2EEPrekey{
+			KeyId: keyId,
+			// 迭代器的值在移动后失效，需要拷贝
+			PublicKey: append([]byte(nil), iter.Value()...),
+		})
+		if limit > 0 && len(prekeys) >= limit {
+			break
+		}
+	}
+	return prekeys, nil
+}
+
+func (wk *wukongDB) RemoveE2EEOneTimePrekeys(uid string, deviceId string, keyIds []uint32) error {
+	if len(keyIds) == 0 {
+		return nil
+	}
+	wk.dblock.e2eeKeyLock.lock(uid, deviceId)
+	defer wk.dblock.e2eeKeyLock.unlock(uid, deviceId)
+
+	batch := wk.shardDB(uid).NewBatch()
+	defer batch.Close()
+	for _, keyId := range keyIds {
+		if err := batch.Delete(key.NewE2EEOneTimePrekeyColumnKey(uid, deviceId, keyId, key.TableE2EEOneTimePrekey.Column.PublicKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetE2EEOneTimePrekeyCount(uid string, deviceId string) (int, error) {
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
+		LowerBound: key.NewE2EEOneTimePrekeyLowKey(uid, deviceId),
+		UpperBound: key.NewE2EEOneTimePrekeyHighKey(uid, deviceId),
+	})
+	defer iter.Close()
+
+	count := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		count++
+	}
+	return count, nil
+}
+
+func (wk *wukongDB) writeE2EEDeviceKeys(k E2EEDeviceKeys, w Writer) error {
+	var err error
+	columnKey := func(columnName [2]byte) []byte {
+		return key.NewE2EEDeviceKeyColumnKey(k.Uid, k.DeviceId, columnName)
+	}
+	columns := key.TableE2EEDeviceKey.Column
+
+	// uid
+	if err = w.Set(columnKey(columns.Uid), []byte(k.Uid), wk.noSync); err != nil {
+		return err
+	}
+
+	// deviceId
+	if err = w.Set(columnKey(columns.DeviceId), []byte(k.DeviceId), wk.noSync); err != nil {
+		return err
+	}
+
+	// identityKey
+	if err = w.Set(columnKey(columns.IdentityKey), k.IdentityKey, wk.noSync); err != nil {
+		return err
+	}
+
+	// signedPrekeyId
+	signedPrekeyId := make([]byte, 4)
+	wk.endian.PutUint32(signedPrekeyId, k.SignedPrekey.KeyId)
+	if err = w.Set(columnKey(columns.SignedPrekeyId), signedPrekeyId, wk.noSync); err != nil {
+		return err
+	}
+
+	// signedPrekey
+	if err = w.Set(columnKey(columns.SignedPrekey), k.SignedPrekey.PublicKey, wk.noSync); err != nil {
+		return err
+	}
+
+	// signedPrekeySignature
+	if err = w.Set(columnKey(columns.SignedPrekeySignature), k.SignedPrekey.Signature, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if k.CreatedAt != nil {
+		createdAt := make([]byte, 8)
+		wk.endian.PutUint64(createdAt, uint64(k.CreatedAt.UnixNano()))
+		if err = w.Set(columnKey(columns.CreatedAt), createdAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// updatedAt
+	if k.UpdatedAt != nil {
+		updatedAt := make([]byte, 8)
+		wk.endian.PutUint64(updatedAt, uint64(k.UpdatedAt.UnixNano()))
+		if err = w.Set(columnKey(columns.UpdatedAt), updatedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) iterE2EEDeviceKeys(iter Iterator, iterFnc func(k E2EEDeviceKeys) bool) error {
+	var (
+		preDeviceIdHash uint64
+		preKeys         E2EEDeviceKeys
+		lastNeedAppend  bool = true
+		hasData         bool = false
+	)
+	columns := key.TableE2EEDeviceKey.Column
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, deviceIdHash, columnName, err := key.ParseE2EEDeviceKeyColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if !hasData || deviceIdHash != preDeviceIdHash {
+			if hasData {
+				if !iterFnc(preKeys) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preDeviceIdHash = deviceIdHash
+			preKeys = E2EEDeviceKeys{}
+		}
+
+		switch columnName {
+		case columns.Uid:
+			preKeys.Uid = string(iter.Value())
+		case columns.DeviceId:
+			preKeys.DeviceId = string(iter.Value())
+		case columns.IdentityKey:
+			// 迭代器的值在移动后失效，需要拷贝
+			preKeys.IdentityKey = append([]byte(nil), iter.Value()...)
+		case columns.SignedPrekeyId:
+			preKeys.SignedPrekey.KeyId = wk.endian.Uint32(iter.Value())
+		case columns.SignedPrekey:
+			preKeys.SignedPrekey.PublicKey = append([]byte(nil), iter.Value()...)
+		case columns.SignedPrekeySignature:
+			preKeys.SignedPrekey.Signature = append([]byte(nil), iter.Value()...)
+		case columns.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				createdAt := time.Unix(tm/1e9, tm%1e9)
+				preKeys.CreatedAt = &createdAt
+			}
+		case columns.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				updatedAt := time.Unix(tm/1e9, tm%1e9)
+				preKeys.UpdatedAt = &updatedAt
+			}
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preKeys)
+	}
+	return nil
+}
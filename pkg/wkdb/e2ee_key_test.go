@@ -0,0 +1,137 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddOrUpdateE2EEDeviceKeys(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	tn := time.Now()
+	keys := wkdb.E2EEDeviceKeys{
+		Uid:         "u1",
+		DeviceId:    "d1",
+		IdentityKey: []byte("identity1"),
+		SignedPrekey: wkdb.E2EEPrekey{
+			KeyId:     1,
+			PublicKey: []byte("signed1"),
+			Signature: []byte("signature1"),
+		},
+		CreatedAt: &tn,
+		UpdatedAt: &tn,
+	}
+	err = d.AddOrUpdateE2EEDeviceKeys(keys, []wkdb.E2EEPrekey{
+		{KeyId: 3, PublicKey: []byte("otk3")},
+		{KeyId: 1, PublicKey: []byte("otk1")},
+		{KeyId: 2, PublicKey: []byte("otk2")},
+	})
+	assert.NoError(t, err)
+
+	err = d.AddOrUpdateE2EEDeviceKeys(wkdb.E2EEDeviceKeys{Uid: "u1", DeviceId: "d2", IdentityKey: []byte("identity2")}, nil)
+	assert.NoError(t, err)
+
+	key, err := d.GetE2EEDeviceKey("u1", "d1")
+	assert.NoError(t, err)
+	assert.Equal(t, keys.IdentityKey, key.IdentityKey)
+	assert.Equal(t, keys.SignedPrekey, key.SignedPrekey)
+	assert.Equal(t, tn.Unix(), key.CreatedAt.Unix())
+
+	allKeys, err := d.GetE2EEDeviceKeys("u1")
+	assert.NoError(t, err)
+	assert.Len(t, allKeys, 2)
+	assert.Equal(t, "d1", allKeys[0].DeviceId)
+	assert.Equal(t, "d2", allKeys[1].DeviceId)
+
+	prekeys, err := d.GetE2EEOneTimePrekeys("u1", "d1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, prekeys, 2)
+	assert.Equal(t, uint32(1), prekeys[0].KeyId)
+	assert.Equal(t, []byte("otk1"), prekeys[0].PublicKey)
+	assert.Equal(t, uint32(2), prekeys[1].KeyId)
+
+	// 身份公钥不变时保留一次性预共享公钥
+	keys.SignedPrekey.KeyId = 2
+	err = d.AddOrUpdateE2EEDeviceKeys(keys, nil)
+	assert.NoError(t, err)
+	count, err := d.GetE2EEOneTimePrekeyCount("u1", "d1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// 身份公钥变化时删除旧的一次性预共享公钥
+	keys.IdentityKey = []byte("identity1-new")
+	err = d.AddOrUpdateE2EEDeviceKeys(keys, []wkdb.E2EEPrekey{{KeyId: 10, PublicKey: []byte("otk10")}})
+	assert.NoError(t, err)
+	prekeys, err = d.GetE2EEOneTimePrekeys("u1", "d1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, prekeys, 1)
+	assert.Equal(t, uint32(10), prekeys[0].KeyId)
+}
+
+func TestE2EEOneTimePrekeys(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddE2EEOneTimePrekeys("u1", "d1", []wkdb.E2EEPrekey{
+		{KeyId: 1, PublicKey: []byte("otk1")},
+		{KeyId: 2, PublicKey: []byte("otk2")},
+	})
+	assert.NoError(t, err)
+	err = d.AddE2EEOneTimePrekeys("u1", "d2", []wkdb.E2EEPrekey{
+		{KeyId: 1, PublicKey: []byte("other")},
+	})
+	assert.NoError(t, err)
+
+	err = d.RemoveE2EEOneTimePrekeys("u1", "d1", []uint32{1})
+	assert.NoError(t, err)
+
+	prekeys, err := d.GetE2EEOneTimePrekeys("u1", "d1", 0)
+	assert.NoError(t, err)
+	assert.Len(t, prekeys, 1)
+	assert.Equal(t, uint32(2), prekeys[0].KeyId)
+
+	count, err := d.GetE2EEOneTimePrekeyCount("u1", "d2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestDeleteE2EEDeviceKeys(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddOrUpdateE2EEDeviceKeys(wkdb.E2EEDeviceKeys{Uid: "u1", DeviceId: "d1", IdentityKey: []byte("identity1")}, []wkdb.E2EEPrekey{{KeyId: 1, PublicKey: []byte("otk1")}})
+	assert.NoError(t, err)
+
+	err = d.DeleteE2EEDeviceKeys("u1", "d1")
+	assert.NoError(t, err)
+
+	key, err := d.GetE2EEDeviceKey("u1", "d1")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyE2EEDeviceKeys(key))
+
+	count, err := d.GetE2EEOneTimePrekeyCount("u1", "d1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
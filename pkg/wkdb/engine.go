@@ -0,0 +1,241 @@
+package wkdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// ErrNotSupported 存储引擎不支持此操作
+var ErrNotSupported = errors.New("not supported by the storage engine")
+
+// Engine 存储引擎
+// wukongDB的消息表和元数据表都编码为有序的key-value存储在引擎的分片里，
+// 新的引擎只需要实现分片的读写（KV），并通过enginetest的一致性测试
+type Engine interface {
+	// Name 引擎名称
+	Name() string
+	// OpenShard 打开指定的分片（分片数量由Options.ShardNum决定，一但设置就不能修改）
+	OpenShard(shardId uint32) (KV, error)
+}
+
+// KV 存储引擎的一个分片
+type KV interface {
+	Reader
+	Writer
+	// NewBatch 创建批量写，提交后原子生效
+	NewBatch() Batch
+	// NewIndexedBatch 创建可以读取到自身未提交写入的批量写
+	NewIndexedBatch() Batch
+	Close() error
+}
+
+// Reader 读取接口
+type Reader interface {
+	// Get 获取key的值，不存在时返回ErrNotFound，使用完值后需要调用closer.Close()
+	Get(key []byte) (value []byte, closer io.Closer, err error)
+	// NewIter 创建迭代器，范围为[LowerBound, UpperBound)，opts为nil时遍历所有数据
+	NewIter(opts *IterOptions) Iterator
+}
+
+// Writer 写入接口
+type Writer interface {
+	Set(key, value []byte, opts *WriteOptions) error
+	Delete(key []byte, opts *WriteOptions) error
+	// DeleteRange 删除[start, end)范围内的数据
+	DeleteRange(start, end []byte, opts *WriteOptions) error
+}
+
+// Batch 批量写
+type Batch interface {
+	Reader // 只有NewIndexedBatch创建的批量写可以读取
+	Writer
+	Commit(opts *WriteOptions) error
+	// Count 批量写里的操作数量
+	Count() uint32
+	// Reset 清空批量写，可以继续使用
+	Reset()
+	Close() error
+}
+
+// Iterator 有序迭代器，Key和Value返回的数据只在迭代器移动前有效
+type Iterator interface {
+	First() bool
+	Last() bool
+	Next() bool
+	Prev() bool
+	// SeekGE 移动到第一个大于或等于key的位置
+	SeekGE(key []byte) bool
+	Valid() bool
+	Key() []byte
+	Value() []byte
+	Error() error
+	Close() error
+}
+
+// IterOptions 迭代器配置
+type IterOptions struct {
+	LowerBound []byte // 包含
+	UpperBound []byte // 不包含
+}
+
+// WriteOptions 写入配置
+type WriteOptions struct {
+	Sync bool // 是否同步刷盘
+}
+
+var (
+	Sync   = &WriteOptions{Sync: true}
+	NoSync = &WriteOptions{Sync: false}
+)
+
+// Checkpointer 支持生成一致性快照的分片（在线备份使用）
+type Checkpointer interface {
+	Checkpoint(dir string) error
+}
+
+// ---------- pebble ----------
+
+// pebbleEngine 基于pebble的存储引擎（默认引擎），每个分片是一个pebble实例
+type pebbleEngine struct {
+	dir  string
+	opts *pebble.Options
+}
+
+// NewPebbleEngine 创建pebble存储引擎，分片存储在dir/wukongimdb/shardXXX
+func NewPebbleEngine(dir string, opts *pebble.Options) Engine {
+	return &pebbleEngine{
+		dir:  dir,
+		opts: opts,
+	}
+}
+
+func (p *pebbleEngine) Name() string {
+	return "pebble"
+}
+
+func (p *pebbleEngine) OpenShard(shardId uint32) (KV, error) {
+	db, err := pebble.Open(pebbleShardDir(p.dir, shardId), p.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &pebbleKV{db: db}, nil
+}
+
+func pebbleShardDir(dir string, shardId uint32) string {
+	return filepath.Join(dir, "wukongimdb", fmt.Sprintf("shard%03d", shardId))
+}
+
+type pebbleKV struct {
+	db *pebble.DB
+}
+
+func (p *pebbleKV) Get(key []byte) ([]byte, io.Closer, error) {
+	return pebbleGet(p.db, key)
+}
+
+func (p *pebbleKV) NewIter(opts *IterOptions) Iterator {
+	return p.db.NewIter(toPebbleIterOptions(opts))
+}
+
+func (p *pebbleKV) Set(key, value []byte, opts *WriteOptions) error {
+	return p.db.Set(key, value, toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleKV) Delete(key []byte, opts *WriteOptions) error {
+	return p.db.Delete(key, toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleKV) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	return p.db.DeleteRange(start, end, toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleKV) NewBatch() Batch {
+	return &pebbleBatch{batch: p.db.NewBatch()}
+}
+
+func (p *pebbleKV) NewIndexedBatch() Batch {
+	return &pebbleBatch{batch: p.db.NewIndexedBatch()}
+}
+
+func (p *pebbleKV) Close() error {
+	return p.db.Close()
+}
+
+// Checkpoint 生成分片的一致性快照
+func (p *pebbleKV) Checkpoint(dir string) error {
+	return p.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
+// Metrics pebble的运行指标
+func (p *pebbleKV) Metrics() *pebble.Metrics {
+	return p.db.Metrics()
+}
+
+type pebbleBatch struct {
+	batch *pebble.Batch
+}
+
+func (p *pebbleBatch) Get(key []byte) ([]byte, io.Closer, error) {
+	return pebbleGet(p.batch, key)
+}
+
+func (p *pebbleBatch) NewIter(opts *IterOptions) Iterator {
+	return p.batch.NewIter(toPebbleIterOptions(opts))
+}
+
+func (p *pebbleBatch) Set(key, value []byte, opts *WriteOptions) error {
+	return p.batch.Set(key, value, toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleBatch) Delete(key []byte, opts *WriteOptions) error {
+	return p.batch.Delete(key, toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleBatch) DeleteRange(start, end []byte, opts *WriteOptions) error {
+	return p.batch.DeleteRange(start, end, toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleBatch) Commit(opts *WriteOptions) error {
+	return p.batch.Commit(toPebbleWriteOptions(opts))
+}
+
+func (p *pebbleBatch) Count() uint32 {
+	return p.batch.Count()
+}
+
+func (p *pebbleBatch) Reset() {
+	p.batch.Reset()
+}
+
+func (p *pebbleBatch) Close() error {
+	return p.batch.Close()
+}
+
+func pebbleGet(r pebble.Reader, key []byte) ([]byte, io.Closer, error) {
+	value, closer, err := r.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, nil, ErrNotFound
+	}
+	return value, closer, err
+}
+
+func toPebbleIterOptions(opts *IterOptions) *pebble.IterOptions {
+	if opts == nil {
+		return nil
+	}
+	return &pebble.IterOptions{
+		LowerBound: opts.LowerBound,
+		UpperBound: opts.UpperBound,
+	}
+}
+
+func toPebbleWriteOptions(opts *WriteOptions) *pebble.WriteOptions {
+	if opts == nil || !opts.Sync {
+		return pebble.NoSync
+	}
+	return pebble.Sync
+}
@@ -0,0 +1,299 @@
+package wkdb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+var errBatchNotIndexed = errors.New("batch is not indexed")
+
+// memoryEngine 内存存储引擎，数据只保存在内存里（主要用于单元测试）
+// 同一个引擎重复打开分片会得到之前的数据，可以用来测试数据库的重新打开
+type memoryEngine struct {
+	mu     sync.Mutex
+	shards map[uint32]*memoryKV
+}
+
+// NewMemoryEngine 创建内存存储引擎
+func NewMemoryEngine() Engine {
+	return &memoryEngine{
+		shards: make(map[uint32]*memoryKV),
+	}
+}
+
+func (m *memoryEngine) Name() string {
+	return "memory"
+}
+
+func (m *memoryEngine) OpenShard(shardId uint32) (KV, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kv := m.shards[shardId]
+	if kv == nil {
+		kv = &memoryKV{
+			tree: btree.NewG[memoryItem](32, memoryItemLess),
+		}
+		m.shards[shardId] = kv
+	}
+	return kv, nil
+}
+
+type memoryItem struct {
+	key   []byte
+	value []byte
+}
+
+func memoryItemLess(a, b memoryItem) bool {
+	return bytes.Compare(a.key, b.key) < 0
+}
+
+const (
+	memoryOpSet = iota
+	memoryOpDelete
+	memoryOpDeleteRange
+)
+
+type memoryOp struct {
+	kind  int
+	key   []byte
+	value []byte
+	end   []byte // DeleteRange的结束key
+}
+
+type memoryKV struct {
+	mu   sync.RWMutex
+	tree *btree.BTreeG[memoryItem]
+}
+
+func (m *memoryKV) Get(key []byte) ([]byte, io.Closer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	item, ok := m.tree.Get(memoryItem{key: key})
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	return item.value, io.NopCloser(nil), nil
+}
+
+func (m *memoryKV) NewIter(opts *IterOptions) Iterator {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return &memoryIter{items: rangeMemoryItems(m.tree, opts), pos: -1}
+}
+
+func (m *memoryKV) Set(key, value []byte, _ *WriteOptions) error {
+	return m.apply([]memoryOp{newMemorySetOp(key, value)})
+}
+
+func (m *memoryKV) Delete(key []byte, _ *WriteOptions) error {
+	return m.apply([]memoryOp{{kind: memoryOpDelete, key: key}})
+}
+
+func (m *memoryKV) DeleteRange(start, end []byte, _ *WriteOptions) error {
+	return m.apply([]memoryOp{{kind: memoryOpDeleteRange, key: start, end: end}})
+}
+
+func (m *memoryKV) NewBatch() Batch {
+	return &memoryBatch{kv: m}
+}
+
+func (m *memoryKV) NewIndexedBatch() Batch {
+	return &memoryBatch{kv: m, indexed: true}
+}
+
+func (m *memoryKV) Close() error {
+	return nil
+}
+
+func (m *memoryKV) apply(ops []memoryOp) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	applyMemoryOps(m.tree, ops)
+	return nil
+}
+
+// snapshot 当前数据的快照（写时复制）
+func (m *memoryKV) snapshot() *btree.BTreeG[memoryItem] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tree.Clone()
+}
+
+func newMemorySetOp(key, value []byte) memoryOp {
+	return memoryOp{
+		kind:  memoryOpSet,
+		key:   append([]byte(nil), key...),
+		value: append([]byte(nil), value...),
+	}
+}
+
+func applyMemoryOps(tree *btree.BTreeG[memoryItem], ops []memoryOp) {
+	for _, op := range ops {
+		switch op.kind {
+		case memoryOpSet:
+			tree.ReplaceOrInsert(memoryItem{key: op.key, value: op.value})
+		case memoryOpDelete:
+			tree.Delete(memoryItem{key: op.key})
+		case memoryOpDeleteRange:
+			items := rangeMemoryItems(tree, &IterOptions{LowerBound: op.key, UpperBound: op.end})
+			for _, item := range items {
+				tree.Delete(item)
+			}
+		}
+	}
+}
+
+func rangeMemoryItems(tree *btree.BTreeG[memoryItem], opts *IterOptions) []memoryItem {
+	items := make([]memoryItem, 0)
+	iterator := func(item memoryItem) bool {
+		if opts != nil && opts.UpperBound != nil && bytes.Compare(item.key, opts.UpperBound) >= 0 {
+			return false
+		}
+		items = append(items, item)
+		return true
+	}
+	if opts != nil && opts.LowerBound != nil {
+		tree.AscendGreaterOrEqual(memoryItem{key: opts.LowerBound}, iterator)
+	} else {
+		tree.Ascend(iterator)
+	}
+	return items
+}
+
+type memoryBatch struct {
+	kv      *memoryKV
+	indexed bool
+	ops     []memoryOp
+}
+
+func (b *memoryBatch) Get(key []byte) ([]byte, io.Closer, error) {
+	if !b.indexed {
+		return nil, nil, errBatchNotIndexed
+	}
+	// 优先读取批量写里最后一次对此key的操作
+	for i := len(b.ops) - 1; i >= 0; i-- {
+		op := b.ops[i]
+		switch op.kind {
+		case memoryOpSet:
+			if bytes.Equal(op.key, key) {
+				return op.value, io.NopCloser(nil), nil
+			}
+		case memoryOpDelete:
+			if bytes.Equal(op.key, key) {
+				return nil, nil, ErrNotFound
+			}
+		case memoryOpDeleteRange:
+			if bytes.Compare(key, op.key) >= 0 && bytes.Compare(key, op.end) < 0 {
+				return nil, nil, ErrNotFound
+			}
+		}
+	}
+	return b.kv.Get(key)
+}
+
+func (b *memoryBatch) NewIter(opts *IterOptions) Iterator {
+	if !b.indexed {
+		return &memoryIter{pos: -1, err: errBatchNotIndexed}
+	}
+	tree := b.kv.snapshot()
+	applyMemoryOps(tree, b.ops)
+	return &memoryIter{items: rangeMemoryItems(tree, opts), pos: -1}
+}
+
+func (b *memoryBatch) Set(key, value []byte, _ *WriteOptions) error {
+	b.ops = append(b.ops, newMemorySetOp(key, value))
+	return nil
+}
+
+func (b *memoryBatch) Delete(key []byte, _ *WriteOptions) error {
+	b.ops = append(b.ops, memoryOp{kind: memoryOpDelete, key: append([]byte(nil), key...)})
+	return nil
+}
+
+func (b *memoryBatch) DeleteRange(start, end []byte, _ *WriteOptions) error {
+	b.ops = append(b.ops, memoryOp{kind: memoryOpDeleteRange, key: append([]byte(nil), start...), end: append([]byte(nil), end...)})
+	return nil
+}
+
+func (b *memoryBatch) Commit(_ *WriteOptions) error {
+	return b.kv.apply(b.ops)
+}
+
+func (b *memoryBatch) Count() uint32 {
+	return uint32(len(b.ops))
+}
+
+func (b *memoryBatch) Reset() {
+	b.ops = nil
+}
+
+func (b *memoryBatch) Close() error {
+	b.ops = nil
+	return nil
+}
+
+// memoryIter 迭代创建时的数据快照
+type memoryIter struct {
+	items []memoryItem
+	pos   int
+	err   error
+}
+
+func (it *memoryIter) First() bool {
+	it.pos = 0
+	return it.Valid()
+}
+
+func (it *memoryIter) Last() bool {
+	it.pos = len(it.items) - 1
+	return it.Valid()
+}
+
+func (it *memoryIter) Next() bool {
+	if !it.Valid() {
+		return false
+	}
+	it.pos++
+	return it.Valid()
+}
+
+func (it *memoryIter) Prev() bool {
+	if !it.Valid() {
+		return false
+	}
+	it.pos--
+	return it.Valid()
+}
+
+func (it *memoryIter) SeekGE(key []byte) bool {
+	it.pos = sort.Search(len(it.items), func(i int) bool {
+		return bytes.Compare(it.items[i].key, key) >= 0
+	})
+	return it.Valid()
+}
+
+func (it *memoryIter) Valid() bool {
+	return it.err == nil && it.pos >= 0 && it.pos < len(it.items)
+}
+
+func (it *memoryIter) Key() []byte {
+	return it.items[it.pos].key
+}
+
+func (it *memoryIter) Value() []byte {
+	return it.items[it.pos].value
+}
+
+func (it *memoryIter) Error() error {
+	return it.err
+}
+
+func (it *memoryIter) Close() error {
+	it.items = nil
+	return nil
+}
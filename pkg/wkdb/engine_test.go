@@ -0,0 +1,88 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/enginetest"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPebbleEngine(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T) wkdb.Engine {
+		return wkdb.NewPebbleEngine(t.TempDir(), &pebble.Options{})
+	})
+}
+
+func TestMemoryEngine(t *testing.T) {
+	enginetest.Run(t, func(t *testing.T) wkdb.Engine {
+		return wkdb.NewMemoryEngine()
+	})
+}
+
+func TestMemoryEngineDB(t *testing.T) {
+	engine := wkdb.NewMemoryEngine()
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithEngine(engine), wkdb.WithShardNum(2)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	tn := time.Now()
+	err = d.AddUser(wkdb.User{Uid: "u1", CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+
+	channelId := "channel"
+	channelType := uint8(2)
+	messages := make([]wkdb.Message, 0)
+	for i := 0; i < 10; i++ {
+		messages = append(messages, wkdb.Message{
+			RecvPacket: wkproto.RecvPacket{
+				ChannelID:   channelId,
+				ChannelType: channelType,
+				MessageID:   int64(i + 1),
+				MessageSeq:  uint32(i + 1),
+				Payload:     []byte("hello"),
+			},
+		})
+	}
+	err = d.AppendMessages(channelId, channelType, messages)
+	assert.NoError(t, err)
+
+	// 索引和数据一致
+	inspector := d.(wkdb.Inspector)
+	for _, table := range wkdb.InspectTables {
+		result, err := inspector.Verify(table, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, result.IssueCount, table)
+	}
+
+	err = d.TruncateLogTo(channelId, channelType, 8)
+	assert.NoError(t, err)
+
+	// 内存引擎不支持快照
+	err = d.Checkpoint(t.TempDir())
+	assert.ErrorIs(t, err, wkdb.ErrNotSupported)
+
+	err = d.Close()
+	assert.NoError(t, err)
+
+	// 同一个引擎重新打开数据还在
+	d = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithEngine(engine), wkdb.WithShardNum(2)))
+	err = d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	u, err := d.GetUser("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", u.Uid)
+
+	msgs, err := d.LoadNextRangeMsgs(channelId, channelType, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 7) // 截断后剩下1-7
+	assert.Equal(t, uint32(7), msgs[len(msgs)-1].MessageSeq)
+}
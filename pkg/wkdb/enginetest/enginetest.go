@@ -0,0 +1,340 @@
+// Package enginetest 存储引擎的一致性测试，所有wkdb.Engine的实现都需要通过
+//
+//	func TestMyEngine(t *testing.T) {
+//		enginetest.Run(t, func(t *testing.T) wkdb.Engine {
+//			return NewMyEngine(t.TempDir())
+//		})
+//	}
+package enginetest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewEngine 为每个子测试创建一个空的引擎
+type NewEngine func(t *testing.T) wkdb.Engine
+
+// Run 运行所有一致性测试
+func Run(t *testing.T, newEngine NewEngine) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, kv wkdb.KV)
+	}{
+		{"GetSetDelete", testGetSetDelete},
+		{"DeleteRange", testDeleteRange},
+		{"IterBounds", testIterBounds},
+		{"IterReverse", testIterReverse},
+		{"IterSeekGE", testIterSeekGE},
+		{"IterSnapshot", testIterSnapshot},
+		{"Batch", testBatch},
+		{"IndexedBatch", testIndexedBatch},
+		{"BatchReset", testBatchReset},
+		{"ValueCopy", testValueCopy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := openShard(t, newEngine(t), 0)
+			tt.fn(t, kv)
+		})
+	}
+
+	t.Run("Shards", func(t *testing.T) {
+		testShards(t, newEngine(t))
+	})
+	t.Run("Reopen", func(t *testing.T) {
+		testReopen(t, newEngine(t))
+	})
+}
+
+func openShard(t *testing.T, engine wkdb.Engine, shardId uint32) wkdb.KV {
+	kv, err := engine.OpenShard(shardId)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = kv.Close()
+	})
+	return kv
+}
+
+func get(t *testing.T, r wkdb.Reader, key string) (string, bool) {
+	value, closer, err := r.Get([]byte(key))
+	if err == wkdb.ErrNotFound {
+		return "", false
+	}
+	require.NoError(t, err)
+	v := string(value)
+	require.NoError(t, closer.Close())
+	return v, true
+}
+
+func set(t *testing.T, w wkdb.Writer, kvs ...string) {
+	for i := 0; i+1 < len(kvs); i += 2 {
+		require.NoError(t, w.Set([]byte(kvs[i]), []byte(kvs[i+1]), wkdb.NoSync))
+	}
+}
+
+// keys 迭代器正向遍历的所有key
+func keys(t *testing.T, r wkdb.Reader, opts *wkdb.IterOptions) []string {
+	iter := r.NewIter(opts)
+	defer iter.Close()
+	result := make([]string, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		result = append(result, string(iter.Key()))
+	}
+	require.NoError(t, iter.Error())
+	return result
+}
+
+func bounds(lower, upper string) *wkdb.IterOptions {
+	opts := &wkdb.IterOptions{}
+	if lower != "" {
+		opts.LowerBound = []byte(lower)
+	}
+	if upper != "" {
+		opts.UpperBound = []byte(upper)
+	}
+	return opts
+}
+
+func testGetSetDelete(t *testing.T, kv wkdb.KV) {
+	_, ok := get(t, kv, "a")
+	assert.False(t, ok)
+
+	set(t, kv, "a", "1")
+	v, ok := get(t, kv, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", v)
+
+	// 覆盖
+	require.NoError(t, kv.Set([]byte("a"), []byte("2"), wkdb.Sync))
+	v, _ = get(t, kv, "a")
+	assert.Equal(t, "2", v)
+
+	// 空值和不存在是不同的
+	set(t, kv, "empty", "")
+	v, ok = get(t, kv, "empty")
+	assert.True(t, ok)
+	assert.Equal(t, "", v)
+
+	require.NoError(t, kv.Delete([]byte("a"), wkdb.NoSync))
+	_, ok = get(t, kv, "a")
+	assert.False(t, ok)
+
+	// 删除不存在的key不报错
+	require.NoError(t, kv.Delete([]byte("notexist"), wkdb.NoSync))
+}
+
+func testDeleteRange(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "b", "2", "c", "3", "d", "4")
+	require.NoError(t, kv.DeleteRange([]byte("b"), []byte("d"), wkdb.NoSync))
+	assert.Equal(t, []string{"a", "d"}, keys(t, kv, nil))
+}
+
+func testIterBounds(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "b", "2", "b\x00", "3", "c", "4", "d", "5")
+
+	assert.Equal(t, []string{"a", "b", "b\x00", "c", "d"}, keys(t, kv, nil))
+	assert.Equal(t, []string{"b", "b\x00", "c"}, keys(t, kv, bounds("b", "d"))) // 下界包含，上界不包含
+	assert.Equal(t, []string{"c", "d"}, keys(t, kv, bounds("bb", "")))          // 只有下界
+	assert.Equal(t, []string{"a", "b"}, keys(t, kv, bounds("", "b\x00")))       // 只有上界
+	assert.Empty(t, keys(t, kv, bounds("x", "z")))                              // 范围内没有数据
+	assert.Equal(t, []string{"b", "b\x00"}, keys(t, kv, bounds("b", "c")))      // 前缀范围
+
+	iter := kv.NewIter(bounds("b", "d"))
+	defer iter.Close()
+	require.True(t, iter.First())
+	assert.Equal(t, "b", string(iter.Key()))
+	assert.Equal(t, "2", string(iter.Value()))
+}
+
+func testIterReverse(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "b", "2", "c", "3", "d", "4")
+
+	iter := kv.NewIter(bounds("a", "d"))
+	defer iter.Close()
+	result := make([]string, 0)
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		result = append(result, string(iter.Key()))
+	}
+	assert.Equal(t, []string{"c", "b", "a"}, result)
+
+	// 正反向切换
+	require.True(t, iter.First())
+	require.True(t, iter.Next())
+	assert.Equal(t, "b", string(iter.Key()))
+	require.True(t, iter.Prev())
+	assert.Equal(t, "a", string(iter.Key()))
+	assert.False(t, iter.Prev())
+	assert.False(t, iter.Valid())
+
+	empty := kv.NewIter(bounds("x", "z"))
+	defer empty.Close()
+	assert.False(t, empty.First())
+	assert.False(t, empty.Last())
+}
+
+func testIterSeekGE(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "c", "3", "e", "5")
+
+	iter := kv.NewIter(nil)
+	defer iter.Close()
+	require.True(t, iter.SeekGE([]byte("b")))
+	assert.Equal(t, "c", string(iter.Key()))
+	require.True(t, iter.SeekGE([]byte("c")))
+	assert.Equal(t, "c", string(iter.Key()))
+	require.True(t, iter.Next())
+	assert.Equal(t, "e", string(iter.Key()))
+	assert.False(t, iter.SeekGE([]byte("f")))
+}
+
+// 迭代器创建后的写入不影响迭代器
+func testIterSnapshot(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "b", "2")
+
+	iter := kv.NewIter(nil)
+	defer iter.Close()
+
+	set(t, kv, "c", "3")
+	require.NoError(t, kv.Delete([]byte("a"), wkdb.NoSync))
+
+	result := make([]string, 0)
+	for iter.First(); iter.Valid(); iter.Next() {
+		result = append(result, string(iter.Key()))
+	}
+	assert.Equal(t, []string{"a", "b"}, result)
+	assert.Equal(t, []string{"b", "c"}, keys(t, kv, nil))
+}
+
+func testBatch(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "x1", "1", "x2", "2")
+
+	batch := kv.NewBatch()
+	defer batch.Close()
+	set(t, batch, "b", "2", "c", "3")
+	require.NoError(t, batch.Delete([]byte("a"), wkdb.NoSync))
+	require.NoError(t, batch.DeleteRange([]byte("x"), []byte("y"), wkdb.NoSync))
+	assert.Equal(t, uint32(4), batch.Count())
+
+	// 提交前不可见
+	assert.Equal(t, []string{"a", "x1", "x2"}, keys(t, kv, nil))
+
+	require.NoError(t, batch.Commit(wkdb.Sync))
+	assert.Equal(t, []string{"b", "c"}, keys(t, kv, nil))
+
+	// 同一个key在批量写里按顺序生效
+	batch2 := kv.NewBatch()
+	defer batch2.Close()
+	set(t, batch2, "k", "1")
+	require.NoError(t, batch2.Delete([]byte("k"), wkdb.NoSync))
+	set(t, batch2, "k", "2")
+	require.NoError(t, batch2.Commit(wkdb.NoSync))
+	v, ok := get(t, kv, "k")
+	assert.True(t, ok)
+	assert.Equal(t, "2", v)
+}
+
+func testIndexedBatch(t *testing.T, kv wkdb.KV) {
+	set(t, kv, "a", "1", "b", "2", "c", "3")
+
+	batch := kv.NewIndexedBatch()
+	defer batch.Close()
+	set(t, batch, "a", "10", "d", "4")
+	require.NoError(t, batch.Delete([]byte("b"), wkdb.NoSync))
+
+	// 批量写里可以读到未提交的写入
+	v, ok := get(t, batch, "a")
+	assert.True(t, ok)
+	assert.Equal(t, "10", v)
+	_, ok = get(t, batch, "b")
+	assert.False(t, ok)
+	v, ok = get(t, batch, "c")
+	assert.True(t, ok)
+	assert.Equal(t, "3", v)
+	assert.Equal(t, []string{"a", "c", "d"}, keys(t, batch, nil))
+
+	require.NoError(t, batch.DeleteRange([]byte("c"), []byte("e"), wkdb.NoSync))
+	_, ok = get(t, batch, "d")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"a"}, keys(t, batch, nil))
+
+	// 数据库里还是原来的数据
+	v, _ = get(t, kv, "a")
+	assert.Equal(t, "1", v)
+	assert.Equal(t, []string{"a", "b", "c"}, keys(t, kv, nil))
+
+	require.NoError(t, batch.Commit(wkdb.Sync))
+	v, _ = get(t, kv, "a")
+	assert.Equal(t, "10", v)
+	assert.Equal(t, []string{"a"}, keys(t, kv, nil))
+}
+
+func testBatchReset(t *testing.T, kv wkdb.KV) {
+	batch := kv.NewBatch()
+	defer batch.Close()
+	for i := 0; i < 10; i++ {
+		set(t, batch, fmt.Sprintf("k%d", i), "v")
+	}
+	assert.Equal(t, uint32(10), batch.Count())
+	require.NoError(t, batch.Commit(wkdb.NoSync))
+
+	batch.Reset()
+	assert.Equal(t, uint32(0), batch.Count())
+	set(t, batch, "z", "v")
+	require.NoError(t, batch.Commit(wkdb.NoSync))
+	assert.Len(t, keys(t, kv, nil), 11)
+}
+
+// 写入后修改传入的buffer不影响已写入的数据
+func testValueCopy(t *testing.T, kv wkdb.KV) {
+	k := []byte("key")
+	v := []byte("value")
+	require.NoError(t, kv.Set(k, v, wkdb.NoSync))
+
+	batch := kv.NewBatch()
+	defer batch.Close()
+	bk := []byte("bkey")
+	bv := []byte("bvalue")
+	require.NoError(t, batch.Set(bk, bv, wkdb.NoSync))
+	bk[0], bv[0] = 'x', 'x'
+	require.NoError(t, batch.Commit(wkdb.NoSync))
+
+	k[0], v[0] = 'x', 'x'
+	value, ok := get(t, kv, "key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", value)
+	value, ok = get(t, kv, "bkey")
+	assert.True(t, ok)
+	assert.Equal(t, "bvalue", value)
+}
+
+// 不同分片的数据互相独立
+func testShards(t *testing.T, engine wkdb.Engine) {
+	kv0 := openShard(t, engine, 0)
+	kv1 := openShard(t, engine, 1)
+
+	set(t, kv0, "a", "0")
+	set(t, kv1, "a", "1", "b", "1")
+
+	v, _ := get(t, kv0, "a")
+	assert.Equal(t, "0", v)
+	v, _ = get(t, kv1, "a")
+	assert.Equal(t, "1", v)
+	assert.Equal(t, []string{"a"}, keys(t, kv0, nil))
+	assert.Equal(t, []string{"a", "b"}, keys(t, kv1, nil))
+}
+
+// 同一个引擎关闭分片后重新打开数据还在
+func testReopen(t *testing.T, engine wkdb.Engine) {
+	kv, err := engine.OpenShard(0)
+	require.NoError(t, err)
+	set(t, kv, "a", "1")
+	require.NoError(t, kv.Set([]byte("b"), []byte("2"), wkdb.Sync))
+	require.NoError(t, kv.Close())
+
+	kv = openShard(t, engine, 0)
+	assert.Equal(t, []string{"a", "b"}, keys(t, kv, nil))
+}
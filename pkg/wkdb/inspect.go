@@ -0,0 +1,613 @@
+package wkdb
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+)
+
+// 支持检查和重建索引的表
+const (
+	InspectTableMessage              = "message"
+	InspectTableUser                 = "user"
+	InspectTableChannel              = "channel"
+	InspectTableChannelClusterConfig = "channel_cluster_config"
+)
+
+// InspectTables 支持检查和重建索引的所有表
+var InspectTables = []string{InspectTableMessage, InspectTableUser, InspectTableChannel, InspectTableChannelClusterConfig}
+
+// 检查出的问题类型
+const (
+	IssueMissingIndex      = "missing_index"       // 数据存在但索引不存在
+	IssueIndexMismatch     = "index_mismatch"      // 索引存在但值与数据不一致
+	IssueDanglingIndex     = "dangling_index"      // 索引指向的数据不存在或数据不再对应此索引
+	IssueWrongShard        = "wrong_shard"         // 频道消息不在频道所属的分片上
+	IssueSeqGap            = "seq_gap"             // 频道消息的seq不连续
+	IssueLastMsgSeqBehind  = "last_msg_seq_behind" // 频道的LastMsgSeq小于实际最大的消息seq
+	IssueLastMsgSeqAhead   = "last_msg_seq_ahead"  // 频道的LastMsgSeq大于实际最大的消息seq（尾部消息丢失）
+	IssueAppliedIndexAhead = "applied_index_ahead" // 频道已应用的日志下标大于LastMsgSeq
+)
+
+// Inspector 离线数据检查与修复，节点停止后通过 wk db 命令使用
+// 检查数据时以只读方式打开数据库（WithReadOnly），重建索引时需要以可写方式打开
+type Inspector interface {
+	// ScanMessages 遍历消息，channelId为空时遍历所有频道的消息
+	ScanMessages(channelId string, channelType uint8, iterFnc func(m Message) bool) error
+	// ScanUsers 遍历用户
+	ScanUsers(iterFnc func(u User) bool) error
+	// ScanChannels 遍历频道
+	ScanChannels(iterFnc func(ch ChannelInfo) bool) error
+	// ScanConversations 遍历最近会话
+	ScanConversations(iterFnc func(c Conversation) bool) error
+	// ScanChannelClusterConfigs 遍历频道的分布式配置
+	ScanChannelClusterConfigs(iterFnc func(cfg ChannelClusterConfig) bool) error
+	// Verify 检查表的索引与数据是否一致，消息表还会检查频道的LastMsgSeq和AppliedIndex
+	// maxIssues为返回的问题详情的最大数量（问题总数不受限制），0表示不限制
+	Verify(table string, maxIssues int) (*VerifyResult, error)
+	// RebuildIndexes 删除表的所有索引并根据数据重新生成，消息表还会修正落后的LastMsgSeq，返回处理的数据行数
+	RebuildIndexes(table string) (int, error)
+}
+
+var _ Inspector = (*wukongDB)(nil)
+
+// VerifyIssue 检查出的问题
+type VerifyIssue struct {
+	Kind   string `json:"kind"`   // 问题类型
+	Shard  int    `json:"shard"`  // 所在分片
+	Detail string `json:"detail"` // 详情
+}
+
+// VerifyResult 检查结果
+type VerifyResult struct {
+	Table      string        `json:"table"`       // 表
+	Rows       int           `json:"rows"`        // 数据行数
+	Indexes    int           `json:"indexes"`     // 索引数量
+	Channels   int           `json:"channels"`    // 频道数量（消息表）
+	IssueCount int           `json:"issue_count"` // 问题总数
+	Issues     []VerifyIssue `json:"issues"`      // 问题详情
+}
+
+func (r *VerifyResult) addIssue(maxIssues int, issue VerifyIssue) {
+	r.IssueCount++
+	if maxIssues <= 0 || len(r.Issues) < maxIssues {
+		r.Issues = append(r.Issues, issue)
+	}
+}
+
+// expectedIndex 一行数据应有的索引
+type expectedIndex struct {
+	value    []byte
+	optional bool // 可以不存在（比如数量为0的数量索引）
+}
+
+// inspectTable 检查表的描述
+type inspectTable struct {
+	id  [2]byte
+	dbs []KV
+	// rowIndexes 读取主键对应的数据并生成这行数据应有的索引，数据不存在时返回nil
+	rowIndexes func(db KV, primary []byte) (map[string]expectedIndex, error)
+}
+
+func (wk *wukongDB) inspectTable(table string) (*inspectTable, error) {
+	switch table {
+	case InspectTableMessage:
+		return &inspectTable{id: key.TableMessage.Id, dbs: wk.dbs, rowIndexes: wk.messageRowIndexes}, nil
+	case InspectTableUser:
+		return &inspectTable{id: key.TableUser.Id, dbs: wk.dbs, rowIndexes: wk.userRowIndexes}, nil
+	case InspectTableChannel:
+		return &inspectTable{id: key.TableChannelInfo.Id, dbs: wk.dbs, rowIndexes: wk.channelRowIndexes}, nil
+	case InspectTableChannelClusterConfig:
+		return &inspectTable{id: key.TableChannelClusterConfig.Id, dbs: []KV{wk.defaultShardDB()}, rowIndexes: wk.channelClusterConfigRowIndexes}, nil
+	}
+	return nil, fmt.Errorf("unsupported table: %s", table)
+}
+
+func (wk *wukongDB) Verify(table string, maxIssues int) (*VerifyResult, error) {
+	t, err := wk.inspectTable(table)
+	if err != nil {
+		return nil, err
+	}
+	result := &VerifyResult{Table: table}
+	for shard, db := range t.dbs {
+		if err = wk.verifyRows(t, shard, db, result, maxIssues); err != nil {
+			return nil, err
+		}
+		if err = wk.verifyIndexes(t, shard, db, result, maxIssues); err != nil {
+			return nil, err
+		}
+		if table == InspectTableMessage {
+			if err = wk.verifyChannelSeqs(shard, db, result, maxIssues); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// verifyRows 检查每行数据应有的索引是否存在且值一致
+func (wk *wukongDB) verifyRows(t *inspectTable, shard int, db KV, result *VerifyResult, maxIssues int) error {
+	return wk.iterRowPrimaryKeys(t.id, db, func(primary []byte) error {
+		result.Rows++
+		indexes, err := t.rowIndexes(db, primary)
+		if err != nil {
+			return err
+		}
+		for k, expected := range indexes {
+			value, closer, err := db.Get([]byte(k))
+			if err != nil {
+				if err != ErrNotFound {
+					return err
+				}
+				if !expected.optional {
+					result.addIssue(maxIssues, VerifyIssue{Kind: IssueMissingIndex, Shard: shard, Detail: fmt.Sprintf("primary: %x index: %x", primary, k)})
+				}
+				continue
+			}
+			if !bytes.Equal(value, expected.value) {
+				result.addIssue(maxIssues, VerifyIssue{Kind: IssueIndexMismatch, Shard: shard, Detail: fmt.Sprintf("primary: %x index: %x value: %x expected: %x", primary, k, value, expected.value)})
+			}
+			closer.Close()
+		}
+		return nil
+	})
+}
+
+// verifyIndexes 检查每个索引指向的数据是否存在并且数据仍然对应此索引
+func (wk *wukongDB) verifyIndexes(t *inspectTable, shard int, db KV, result *VerifyResult, maxIssues int) error {
+	iter := db.NewIter(&IterOptions{
+		LowerBound: key.NewTableIndexLowKey(t.id),
+		UpperBound: key.NewTableIndexHighKey(t.id),
+	})
+	defer iter.Close()
+
+	var (
+		lastPrimary []byte
+		lastIndexes map[string]expectedIndex
+		err         error
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		result.Indexes++
+		primary := indexPrimaryKey(iter.Key(), iter.Value())
+		if !bytes.Equal(primary, lastPrimary) {
+			lastIndexes, err = t.rowIndexes(db, primary)
+			if err != nil {
+				return err
+			}
+			lastPrimary = primary
+		}
+		if lastIndexes == nil {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueDanglingIndex, Shard: shard, Detail: fmt.Sprintf("index: %x primary: %x row not exist", iter.Key(), primary)})
+			continue
+		}
+		if _, ok := lastIndexes[string(iter.Key())]; !ok {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueDanglingIndex, Shard: shard, Detail: fmt.Sprintf("index: %x primary: %x row does not reference this index", iter.Key(), primary)})
+		}
+	}
+	return nil
+}
+
+// verifyChannelSeqs 检查频道的消息seq是否连续、LastMsgSeq和AppliedIndex是否与实际消息一致
+func (wk *wukongDB) verifyChannelSeqs(shard int, db KV, result *VerifyResult, maxIssues int) error {
+	return wk.iterChannelSeqs(db, func(st channelSeqState) error {
+		result.Channels++
+		channelKey := wkutil.ChannelToKey(st.channelId, st.channelType)
+		if int(wk.channelDbIndex(st.channelId, st.channelType)) != shard {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueWrongShard, Shard: shard, Detail: fmt.Sprintf("channel: %s expected shard: %d", channelKey, wk.channelDbIndex(st.channelId, st.channelType))})
+		}
+		for _, gap := range st.gaps {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueSeqGap, Shard: shard, Detail: fmt.Sprintf("channel: %s missing seq: %d-%d", channelKey, gap[0], gap[1])})
+		}
+		lastMsgSeq, _, err := wk.GetChannelLastMessageSeq(st.channelId, st.channelType)
+		if err != nil {
+			return err
+		}
+		if lastMsgSeq < st.maxSeq {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueLastMsgSeqBehind, Shard: shard, Detail: fmt.Sprintf("channel: %s lastMsgSeq: %d maxSeq: %d", channelKey, lastMsgSeq, st.maxSeq)})
+		} else if lastMsgSeq > st.maxSeq {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueLastMsgSeqAhead, Shard: shard, Detail: fmt.Sprintf("channel: %s lastMsgSeq: %d maxSeq: %d", channelKey, lastMsgSeq, st.maxSeq)})
+		}
+		appliedIndex, err := wk.GetChannelAppliedIndex(st.channelId, st.channelType)
+		if err != nil {
+			return err
+		}
+		if appliedIndex > lastMsgSeq {
+			result.addIssue(maxIssues, VerifyIssue{Kind: IssueAppliedIndexAhead, Shard: shard, Detail: fmt.Sprintf("channel: %s appliedIndex: %d lastMsgSeq: %d", channelKey, appliedIndex, lastMsgSeq)})
+		}
+		return nil
+	})
+}
+
+func (wk *wukongDB) RebuildIndexes(table string) (int, error) {
+	t, err := wk.inspectTable(table)
+	if err != nil {
+		return 0, err
+	}
+	rows := 0
+	for _, db := range t.dbs {
+		if err = db.DeleteRange(key.NewTableIndexLowKey(t.id), key.NewTableIndexHighKey(t.id), wk.sync); err != nil {
+			return rows, err
+		}
+		batch := db.NewBatch()
+		err = wk.iterRowPrimaryKeys(t.id, db, func(primary []byte) error {
+			indexes, err := t.rowIndexes(db, primary)
+			if err != nil {
+				return err
+			}
+			for k, expected := range indexes {
+				if expected.optional {
+					continue
+				}
+				if err = batch.Set([]byte(k), expected.value, wk.noSync); err != nil {
+					return err
+				}
+			}
+			rows++
+			if batch.Count() >= 10000 {
+				if err = batch.Commit(wk.sync); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+			return nil
+		})
+		if err != nil {
+			batch.Close()
+			return rows, err
+		}
+		err = batch.Commit(wk.sync)
+		batch.Close()
+		if err != nil {
+			return rows, err
+		}
+
+		if table == InspectTableMessage {
+			err = wk.iterChannelSeqs(db, func(st channelSeqState) error {
+				lastMsgSeq, _, err := wk.GetChannelLastMessageSeq(st.channelId, st.channelType)
+				if err != nil {
+					return err
+				}
+				if lastMsgSeq >= st.maxSeq {
+					return nil
+				}
+				return wk.SetChannelLastMessageSeq(st.channelId, st.channelType, st.maxSeq)
+			})
+			if err != nil {
+				return rows, err
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (wk *wukongDB) ScanMessages(channelId string, channelType uint8, iterFnc func(m Message) bool) error {
+	if channelId != "" {
+		db := wk.channelDb(channelId, channelType)
+		_, err := wk.scanMessages(db, key.NewMessageSearchLowKeWith(channelId, channelType, 0), key.NewMessageSearchHighKeWith(channelId, channelType, math.MaxUint64), iterFnc)
+		return err
+	}
+	for _, db := range wk.dbs {
+		cont, err := wk.scanMessages(db, key.NewTableRowLowKey(key.TableMessage.Id), key.NewTableRowHighKey(key.TableMessage.Id), iterFnc)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) ScanUsers(iterFnc func(u User) bool) error {
+	return wk.scanTable(key.TableUser.Id, wk.dbs, func(iter Iterator, cont *bool) error {
+		return wk.iteratorUser(iter, func(u User) bool {
+			*cont = iterFnc(u)
+			return *cont
+		})
+	})
+}
+
+func (wk *wukongDB) ScanChannels(iterFnc func(ch ChannelInfo) bool) error {
+	return wk.scanTable(key.TableChannelInfo.Id, wk.dbs, func(iter Iterator, cont *bool) error {
+		return wk.iterChannelInfo(iter, func(ch ChannelInfo) bool {
+			*cont = iterFnc(ch)
+			return *cont
+		})
+	})
+}
+
+func (wk *wukongDB) ScanConversations(iterFnc func(c Conversation) bool) error {
+	return wk.scanTable(key.TableConversation.Id, wk.dbs, func(iter Iterator, cont *bool) error {
+		return wk.iterateConversation(iter, func(c Conversation) bool {
+			*cont = iterFnc(c)
+			return *cont
+		})
+	})
+}
+
+func (wk *wukongDB) ScanChannelClusterConfigs(iterFnc func(cfg ChannelClusterConfig) bool) error {
+	return wk.scanTable(key.TableChannelClusterConfig.Id, []KV{wk.defaultShardDB()}, func(iter Iterator, cont *bool) error {
+		return wk.iteratorChannelClusterConfig(iter, func(cfg ChannelClusterConfig) bool {
+			*cont = iterFnc(cfg)
+			return *cont
+		})
+	})
+}
+
+// scanTable 依次遍历每个分片中表的所有数据，cont被设置为false时停止遍历
+func (wk *wukongDB) scanTable(tableId [2]byte, dbs []KV, iterate func(iter Iterator, cont *bool) error) error {
+	for _, db := range dbs {
+		cont := true
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewTableRowLowKey(tableId),
+			UpperBound: key.NewTableRowHighKey(tableId),
+		})
+		err := iterate(iter, &cont)
+		iter.Close()
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+	return nil
+}
+
+// scanMessages 按消息主键（频道hash+seq）遍历消息，返回是否遍历完
+func (wk *wukongDB) scanMessages(db KV, lower, upper []byte, iterFnc func(m Message) bool) (bool, error) {
+	iter := db.NewIter(&IterOptions{LowerBound: lower, UpperBound: upper})
+	defer iter.Close()
+	for iter.First(); iter.Valid(); {
+		primary := rowPrimaryKey(iter.Key())
+		msg, err := wk.loadMessageByPrimary(db, primary)
+		if err != nil {
+			return false, err
+		}
+		if msg.MessageSeq != 0 && !iterFnc(msg) {
+			return false, nil
+		}
+		seekNextRow(iter, key.TableMessage.Id, primary)
+	}
+	return true, nil
+}
+
+func (wk *wukongDB) loadMessageByPrimary(db KV, primary []byte) (Message, error) {
+	rowIter := db.NewIter(&IterOptions{
+		LowerBound: rowColumnKey(key.TableMessage.Id, primary, key.MinColumnKey),
+		UpperBound: rowColumnKey(key.TableMessage.Id, primary, key.MaxColumnKey),
+	})
+	defer rowIter.Close()
+	var msg Message
+	err := wk.iteratorChannelMessages(rowIter, 0, func(m Message) bool {
+		msg = m
+		return false
+	})
+	return msg, err
+}
+
+// channelSeqState 一个频道在分片中的消息seq情况
+type channelSeqState struct {
+	channelId   string
+	channelType uint8
+	maxSeq      uint64
+	gaps        [][2]uint64 // 缺失的seq区间
+}
+
+// iterChannelSeqs 遍历分片中每个频道的消息seq情况（消息按频道hash+seq排序，同一频道的消息是连续的）
+func (wk *wukongDB) iterChannelSeqs(db KV, fn func(st channelSeqState) error) error {
+	var (
+		current     *channelSeqState
+		currentHash []byte
+		fnErr       error
+	)
+	_, err := wk.scanMessages(db, key.NewTableRowLowKey(key.TableMessage.Id), key.NewTableRowHighKey(key.TableMessage.Id), func(m Message) bool {
+		channelHash := make([]byte, 8)
+		wk.endian.PutUint64(channelHash, key.ChannelIdToNum(m.ChannelID, m.ChannelType))
+		if current == nil || !bytes.Equal(channelHash, currentHash) {
+			if current != nil {
+				if fnErr = fn(*current); fnErr != nil {
+					return false
+				}
+			}
+			current = &channelSeqState{channelId: m.ChannelID, channelType: m.ChannelType}
+			currentHash = channelHash
+		}
+		seq := uint64(m.MessageSeq)
+		if current.maxSeq != 0 && seq > current.maxSeq+1 {
+			current.gaps = append(current.gaps, [2]uint64{current.maxSeq + 1, seq - 1})
+		}
+		if seq > current.maxSeq {
+			current.maxSeq = seq
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	if current != nil {
+		return fn(*current)
+	}
+	return nil
+}
+
+func (wk *wukongDB) messageRowIndexes(db KV, primary []byte) (map[string]expectedIndex, error) {
+	msg, err := wk.loadMessageByPrimary(db, primary)
+	if err != nil {
+		return nil, err
+	}
+	if msg.MessageSeq == 0 {
+		return nil, nil
+	}
+	rec := newIndexRecorder()
+	if err = wk.writeMessage(msg.ChannelID, msg.ChannelType, msg, rec); err != nil {
+		return nil, err
+	}
+	return rec.indexes, nil
+}
+
+func (wk *wukongDB) userRowIndexes(db KV, primary []byte) (map[string]expectedIndex, error) {
+	iter := newRowIter(db, key.TableUser.Id, primary)
+	defer iter.Close()
+	var (
+		user  User
+		found bool
+	)
+	err := wk.iteratorUser(iter, func(u User) bool {
+		user = u
+		found = true
+		return false
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	rec := newIndexRecorder()
+	if err = wk.writeUserIndex(user, rec); err != nil {
+		return nil, err
+	}
+	return rec.indexes, nil
+}
+
+func (wk *wukongDB) channelRowIndexes(db KV, primary []byte) (map[string]expectedIndex, error) {
+	iter := newRowIter(db, key.TableChannelInfo.Id, primary)
+	defer iter.Close()
+	var (
+		channelInfo ChannelInfo
+		found       bool
+	)
+	err := wk.iterChannelInfo(iter, func(ch ChannelInfo) bool {
+		channelInfo = ch
+		found = true
+		return false
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	rec := newIndexRecorder()
+	if err = wk.writeChannelInfoBaseIndex(channelInfo, rec); err != nil {
+		return nil, err
+	}
+	// 数量索引（见incChannelInfoColumnCount），数量为0时可能不存在
+	counts := []struct {
+		indexName [2]byte
+		count     int
+	}{
+		{key.TableChannelInfo.SecondIndex.SubscriberCount, channelInfo.SubscriberCount},
+		{key.TableChannelInfo.SecondIndex.AllowlistCount, channelInfo.AllowlistCount},
+		{key.TableChannelInfo.SecondIndex.DenylistCount, channelInfo.DenylistCount},
+	}
+	for _, c := range counts {
+		rec.indexes[string(key.NewChannelInfoSecondIndexKey(c.indexName, uint64(c.count), channelInfo.Id))] = expectedIndex{optional: c.count == 0}
+	}
+	return rec.indexes, nil
+}
+
+func (wk *wukongDB) channelClusterConfigRowIndexes(db KV, primary []byte) (map[string]expectedIndex, error) {
+	iter := newRowIter(db, key.TableChannelClusterConfig.Id, primary)
+	defer iter.Close()
+	var (
+		channelClusterConfig ChannelClusterConfig
+		found                bool
+	)
+	err := wk.iteratorChannelClusterConfig(iter, func(cfg ChannelClusterConfig) bool {
+		channelClusterConfig = cfg
+		found = true
+		return false
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	rec := newIndexRecorder()
+	if err = wk.writeChannelClusterConfigIndex(wk.endian.Uint64(primary), channelClusterConfig, rec); err != nil {
+		return nil, err
+	}
+	return rec.indexes, nil
+}
+
+// iterRowPrimaryKeys 遍历分片中表的每一行数据的主键
+func (wk *wukongDB) iterRowPrimaryKeys(tableId [2]byte, db KV, fn func(primary []byte) error) error {
+	iter := db.NewIter(&IterOptions{
+		LowerBound: key.NewTableRowLowKey(tableId),
+		UpperBound: key.NewTableRowHighKey(tableId),
+	})
+	defer iter.Close()
+	for iter.First(); iter.Valid(); {
+		primary := rowPrimaryKey(iter.Key())
+		if err := fn(primary); err != nil {
+			return err
+		}
+		seekNextRow(iter, tableId, primary)
+	}
+	return nil
+}
+
+// 数据列的key结构为 tableId(2) + dataType(1) + 0(1) + primaryKey + columnName(2)
+func rowPrimaryKey(k []byte) []byte {
+	primary := make([]byte, len(k)-6)
+	copy(primary, k[4:len(k)-2])
+	return primary
+}
+
+func rowColumnKey(tableId [2]byte, primary []byte, columnName [2]byte) []byte {
+	k := key.NewTableRowLowKey(tableId)
+	k = append(k, primary...)
+	return append(k, columnName[0], columnName[1])
+}
+
+func newRowIter(db KV, tableId [2]byte, primary []byte) Iterator {
+	return db.NewIter(&IterOptions{
+		LowerBound: rowColumnKey(tableId, primary, key.MinColumnKey),
+		UpperBound: rowColumnKey(tableId, primary, key.MaxColumnKey),
+	})
+}
+
+// seekNextRow 跳到下一行数据
+func seekNextRow(iter Iterator, tableId [2]byte, primary []byte) {
+	iter.SeekGE(rowColumnKey(tableId, primary, key.MaxColumnKey))
+	for iter.Valid() && bytes.Equal(rowPrimaryKey(iter.Key()), primary) {
+		iter.Next()
+	}
+}
+
+// indexPrimaryKey 获取索引指向的数据主键
+// 唯一索引的key结构为 tableId(2) + dataType(1) + 0(1) + indexName(2) + columnValue(8)，值为主键
+// 其他索引的key结构为 tableId(2) + dataType(1) + 0(1) + indexName(2) + columnValue(8) + primaryKey
+func indexPrimaryKey(k []byte, value []byte) []byte {
+	var src []byte
+	if len(k) == 14 {
+		src = value
+	} else if len(k) > 14 {
+		src = k[14:]
+	}
+	primary := make([]byte, len(src))
+	copy(primary, src)
+	return primary
+}
+
+// indexRecorder 记录写入的索引，用于根据数据生成应有的索引（数据列的写入会被忽略）
+type indexRecorder struct {
+	indexes map[string]expectedIndex
+}
+
+func newIndexRecorder() *indexRecorder {
+	return &indexRecorder{indexes: make(map[string]expectedIndex)}
+}
+
+func (r *indexRecorder) Set(k, value []byte, _ *WriteOptions) error {
+	if key.IsIndexKey(k) {
+		v := make([]byte, len(value))
+		copy(v, value)
+		r.indexes[string(k)] = expectedIndex{value: v}
+	}
+	return nil
+}
+
+func (r *indexRecorder) Delete(_ []byte, _ *WriteOptions) error { return nil }
+
+func (r *indexRecorder) DeleteRange(_, _ []byte, _ *WriteOptions) error { return nil }
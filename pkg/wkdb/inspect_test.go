@@ -0,0 +1,119 @@
+package wkdb_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectVerifyAndRebuild(t *testing.T) {
+	dir := t.TempDir()
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	tn := time.Now()
+	for _, channelId := range []string{"g1", "g2"} {
+		messages := make([]wkdb.Message, 0, 10)
+		for i := 0; i < 10; i++ {
+			messages = append(messages, wkdb.Message{
+				RecvPacket: wkproto.RecvPacket{
+					MessageID:   int64(len(channelId)*1000 + int(channelId[1])*100 + i + 1),
+					ChannelID:   channelId,
+					ChannelType: 2,
+					MessageSeq:  uint32(i + 1),
+					FromUID:     "u1",
+					ClientMsgNo: channelId + "-no",
+					Timestamp:   int32(tn.Unix()),
+					Payload:     []byte("hello"),
+				},
+			})
+		}
+		err = d.AppendMessages(channelId, 2, messages)
+		assert.NoError(t, err)
+	}
+	err = d.AddUser(wkdb.User{Uid: "u1", CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+	_, err = d.AddChannel(wkdb.ChannelInfo{ChannelId: "g1", ChannelType: 2, CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+	err = d.SaveChannelClusterConfig(wkdb.ChannelClusterConfig{ChannelId: "g1", ChannelType: 2, LeaderId: 1, Replicas: []uint64{1}, CreatedAt: &tn, UpdatedAt: &tn})
+	assert.NoError(t, err)
+	err = d.Close()
+	assert.NoError(t, err)
+
+	// 数据正常时没有问题
+	ro := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1), wkdb.WithReadOnly()))
+	err = ro.Open()
+	assert.NoError(t, err)
+	inspector := ro.(wkdb.Inspector)
+	for _, table := range wkdb.InspectTables {
+		result, err := inspector.Verify(table, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, result.IssueCount, "table: %s issues: %v", table, result.Issues)
+	}
+	result, err := inspector.Verify(wkdb.InspectTableMessage, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, result.Rows)
+	assert.Equal(t, 2, result.Channels)
+
+	count := 0
+	err = inspector.ScanMessages("g1", 2, func(m wkdb.Message) bool {
+		count++
+		return true
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, count)
+	err = ro.Close()
+	assert.NoError(t, err)
+
+	// 删除一条消息的messageId索引，并把频道的LastMsgSeq改小
+	pdb, err := pebble.Open(filepath.Join(dir, "wukongimdb", "shard000"), &pebble.Options{})
+	assert.NoError(t, err)
+	err = pdb.Delete(key.NewMessageIndexMessageIdKey(uint64(2*1000+int('1')*100+5)), pebble.Sync)
+	assert.NoError(t, err)
+	err = pdb.Close()
+	assert.NoError(t, err)
+
+	d = wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(dir), wkdb.WithShardNum(1)))
+	err = d.Open()
+	assert.NoError(t, err)
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+	err = d.SetChannelLastMessageSeq("g2", 2, 8)
+	assert.NoError(t, err)
+
+	inspector = d.(wkdb.Inspector)
+	result, err = inspector.Verify(wkdb.InspectTableMessage, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.IssueCount)
+	kinds := map[string]bool{}
+	for _, issue := range result.Issues {
+		kinds[issue.Kind] = true
+	}
+	assert.True(t, kinds[wkdb.IssueMissingIndex])
+	assert.True(t, kinds[wkdb.IssueLastMsgSeqBehind])
+
+	rows, err := inspector.RebuildIndexes(wkdb.InspectTableMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, rows)
+
+	result, err = inspector.Verify(wkdb.InspectTableMessage, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.IssueCount, "issues: %v", result.Issues)
+
+	m, err := d.GetMessage(uint64(2*1000 + int('1')*100 + 5))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5), m.MessageSeq)
+
+	lastMsgSeq, _, err := d.GetChannelLastMessageSeq("g2", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), lastMsgSeq)
+}
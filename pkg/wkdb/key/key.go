@@ -900,3 +900,617 @@ func NewSystemUidColumnKey(id uint64, columnName [2]byte) []byte {
 	key[13] = columnName[1]
 	return key
 }
+
+// ---------------------- MessageSearch ----------------------
+
+// NewMessageSearchTermKey 倒排索引 channel hash + term hash + messageSeq
+func NewMessageSearchTermKey(channelHash uint64, termHash uint64, messageSeq uint64) []byte {
+	key := make([]byte, TableMessageSearch.SecondIndexSize)
+	key[0] = TableMessageSearch.Id[0]
+	key[1] = TableMessageSearch.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = TableMessageSearch.SecondIndex.Term[0]
+	key[5] = TableMessageSearch.SecondIndex.Term[1]
+	binary.BigEndian.PutUint64(key[6:], channelHash)
+	binary.BigEndian.PutUint64(key[14:], termHash)
+	binary.BigEndian.PutUint64(key[22:], messageSeq)
+	return key
+}
+
+func ParseMessageSearchTermKey(key []byte) (messageSeq uint64, err error) {
+	if len(key) != TableMessageSearch.SecondIndexSize {
+		err = fmt.Errorf("messageSearch: invalid term key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[22:])
+	return
+}
+
+// NewMessageSearchSeqKey 正排索引 channel hash + messageSeq + term hash，用于按消息删除倒排索引
+func NewMessageSearchSeqKey(channelHash uint64, messageSeq uint64, termHash uint64) []byte {
+	key := make([]byte, TableMessageSearch.OtherIndexSize)
+	key[0] = TableMessageSearch.Id[0]
+	key[1] = TableMessageSearch.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
+	key[4] = TableMessageSearch.OtherIndex.Seq[0]
+	key[5] = TableMessageSearch.OtherIndex.Seq[1]
+	binary.BigEndian.PutUint64(key[6:], channelHash)
+	binary.BigEndian.PutUint64(key[14:], messageSeq)
+	binary.BigEndian.PutUint64(key[22:], termHash)
+	return key
+}
+
+func ParseMessageSearchSeqKey(key []byte) (channelHash uint64, messageSeq uint64, termHash uint64, err error) {
+	if len(key) != TableMessageSearch.OtherIndexSize {
+		err = fmt.Errorf("messageSearch: invalid seq key length, keyLen: %d", len(key))
+		return
+	}
+	channelHash = binary.BigEndian.Uint64(key[6:])
+	messageSeq = binary.BigEndian.Uint64(key[14:])
+	termHash = binary.BigEndian.Uint64(key[22:])
+	return
+}
+
+// NewMessageSearchExpireKey 过期索引 expireAt + channel hash + messageSeq
+func NewMessageSearchExpireKey(expireAt uint64, channelHash uint64, messageSeq uint64) []byte {
+	key := make([]byte, TableMessageSearch.OtherIndexSize)
+	key[0] = TableMessageSearch.Id[0]
+	key[1] = TableMessageSearch.Id[1]
+	key[2] = dataTypeOther
+	key[3] = 0
+	key[4] = TableMessageSearch.OtherIndex.Expire[0]
+	key[5] = TableMessageSearch.OtherIndex.Expire[1]
+	binary.BigEndian.PutUint64(key[6:], expireAt)
+	binary.BigEndian.PutUint64(key[14:], channelHash)
+	binary.BigEndian.PutUint64(key[22:], messageSeq)
+	return key
+}
+
+func ParseMessageSearchExpireKey(key []byte) (expireAt uint64, channelHash uint64, messageSeq uint64, err error) {
+	if len(key) != TableMessageSearch.OtherIndexSize {
+		err = fmt.Errorf("messageSearch: invalid expire key length, keyLen: %d", len(key))
+		return
+	}
+	expireAt = binary.BigEndian.Uint64(key[6:])
+	channelHash = binary.BigEndian.Uint64(key[14:])
+	messageSeq = binary.BigEndian.Uint64(key[22:])
+	return
+}
+
+// ---------------------- ChannelTopic ----------------------
+
+func NewChannelTopicColumnKey(parentChannelId string, parentChannelType uint8, topicId string, columnName [2]byte) []byte {
+	key := make([]byte, TableChannelTopic.Size)
+	key[0] = TableChannelTopic.Id[0]
+	key[1] = TableChannelTopic.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(parentChannelId, parentChannelType))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(topicId))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+// NewChannelTopicLowKey 父频道下所有话题的最小key
+func NewChannelTopicLowKey(parentChannelId string, parentChannelType uint8) []byte {
+	key := make([]byte, TableChannelTopic.Size)
+	key[0] = TableChannelTopic.Id[0]
+	key[1] = TableChannelTopic.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(parentChannelId, parentChannelType))
+	binary.BigEndian.PutUint64(key[12:], 0)
+	key[20] = 0
+	key[21] = 0
+	return key
+}
+
+// NewChannelTopicHighKey 父频道下所有话题的最大key
+func NewChannelTopicHighKey(parentChannelId string, parentChannelType uint8) []byte {
+	key := make([]byte, TableChannelTopic.Size)
+	key[0] = TableChannelTopic.Id[0]
+	key[1] = TableChannelTopic.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(parentChannelId, parentChannelType))
+	binary.BigEndian.PutUint64(key[12:], math.MaxUint64)
+	key[20] = 0xff
+	key[21] = 0xff
+	return key
+}
+
+func ParseChannelTopicColumnKey(key []byte) (topicHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableChannelTopic.Size {
+		err = fmt.Errorf("channelTopic: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	topicHash = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ---------------------- RevokedToken ----------------------
+
+func NewRevokedTokenColumnKey(uid string, tokenId string, columnName [2]byte) []byte {
+	key := make([]byte, TableRevokedToken.Size)
+	key[0] = TableRevokedToken.Id[0]
+	key[1] = TableRevokedToken.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(tokenId))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseRevokedTokenColumnKey(key []byte) (uidHash uint64, tokenIdHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableRevokedToken.Size {
+		err = fmt.Errorf("revokedToken: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[4:])
+	tokenIdHash = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ---------------------- ScheduledMessage ----------------------
+
+func NewScheduledMessageColumnKey(id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableScheduledMessage.Size)
+	key[0] = TableScheduledMessage.Id[0]
+	key[1] = TableScheduledMessage.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], id)
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+// NewScheduledMessageSecondIndexKey 二级索引 slotId/channel hash + sendAt + primaryKey
+func NewScheduledMessageSecondIndexKey(indexName [2]byte, prefix uint64, sendAt uint64, id uint64) []byte {
+	key := make([]byte, TableScheduledMessage.SecondIndexSize)
+	key[0] = TableScheduledMessage.Id[0]
+	key[1] = TableScheduledMessage.Id[1]
+	key[2] = dataTypeSecondIndex
+	key[3] = 0
+	key[4] = indexName[0]
+	key[5] = indexName[1]
+	binary.BigEndian.PutUint64(key[6:], prefix)
+	binary.BigEndian.PutUint64(key[14:], sendAt)
+	binary.BigEndian.PutUint64(key[22:], id)
+	return key
+}
+
+func ParseScheduledMessageColumnKey(key []byte) (id uint64, columnName [2]byte, err error) {
+	if len(key) != TableScheduledMessage.Size {
+		err = fmt.Errorf("scheduledMessage: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	id = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+func ParseScheduledMessageSecondIndexKey(key []byte) (sendAt uint64, id uint64, err error) {
+	if len(key) != TableScheduledMessage.SecondIndexSize {
+		err = fmt.Errorf("scheduledMessage: second index invalid key length, keyLen: %d", len(key))
+		return
+	}
+	sendAt = binary.BigEndian.Uint64(key[14:])
+	id = binary.BigEndian.Uint64(key[22:])
+	return
+}
+
+// ---------------------- BroadcastTask ----------------------
+
+func NewBroadcastTaskColumnKey(jobId uint64, slotId uint32, columnName [2]byte) []byte {
+	key := make([]byte, TableBroadcastTask.Size)
+	key[0] = TableBroadcastTask.Id[0]
+	key[1] = TableBroadcastTask.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], jobId)
+	binary.BigEndian.PutUint64(key[12:], uint64(slotId))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseBroadcastTaskColumnKey(key []byte) (jobId uint64, slotId uint32, columnName [2]byte, err error) {
+	if len(key) != TableBroadcastTask.Size {
+		err = fmt.Errorf("broadcastTask: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	jobId = binary.BigEndian.Uint64(key[4:])
+	slotId = uint32(binary.BigEndian.Uint64(key[12:]))
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ---------------------- Attribute ----------------------
+
+func newAttributeColumnKey(tableId [2]byte, size int, ownerId uint64, name string, columnName [2]byte) []byte {
+	key := make([]byte, size)
+	key[0] = tableId[0]
+	key[1] = tableId[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], ownerId)
+	binary.BigEndian.PutUint64(key[12:], HashWithString(name))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func newAttributeOwnerPrefix(tableId [2]byte, ownerId uint64) []byte {
+	key := make([]byte, 12)
+	key[0] = tableId[0]
+	key[1] = tableId[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], ownerId)
+	return key
+}
+
+func parseAttributeColumnKey(key []byte, size int) (ownerId uint64, nameHash uint64, columnName [2]byte, err error) {
+	if len(key) != size {
+		err = fmt.Errorf("attribute: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	ownerId = binary.BigEndian.Uint64(key[4:])
+	nameHash = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+func NewUserAttributeColumnKey(uid string, name string, columnName [2]byte) []byte {
+	return newAttributeColumnKey(TableUserAttribute.Id, TableUserAttribute.Size, HashWithString(uid), name, columnName)
+}
+
+// NewUserAttributeLowKey 用户属性的起始key（包含）
+func NewUserAttributeLowKey(uid string) []byte {
+	return newAttributeOwnerPrefix(TableUserAttribute.Id, HashWithString(uid))
+}
+
+// NewUserAttributeHighKey 用户属性的结束key（不包含）
+func NewUserAttributeHighKey(uid string) []byte {
+	return newAttributeOwnerPrefix(TableUserAttribute.Id, HashWithString(uid)+1)
+}
+
+func ParseUserAttributeColumnKey(key []byte) (uidHash uint64, nameHash uint64, columnName [2]byte, err error) {
+	return parseAttributeColumnKey(key, TableUserAttribute.Size)
+}
+
+func NewChannelAttributeColumnKey(channelId string, channelType uint8, name string, columnName [2]byte) []byte {
+	return newAttributeColumnKey(TableChannelAttribute.Id, TableChannelAttribute.Size, ChannelIdToNum(channelId, channelType), name, columnName)
+}
+
+// NewChannelAttributeLowKey 频道属性的起始key（包含）
+func NewChannelAttributeLowKey(channelId string, channelType uint8) []byte {
+	return newAttributeOwnerPrefix(TableChannelAttribute.Id, ChannelIdToNum(channelId, channelType))
+}
+
+// NewChannelAttributeHighKey 频道属性的结束key（不包含）
+func NewChannelAttributeHighKey(channelId string, channelType uint8) []byte {
+	return newAttributeOwnerPrefix(TableChannelAttribute.Id, ChannelIdToNum(channelId, channelType)+1)
+}
+
+func ParseChannelAttributeColumnKey(key []byte) (channelHash uint64, nameHash uint64, columnName [2]byte, err error) {
+	return parseAttributeColumnKey(key, TableChannelAttribute.Size)
+}
+
+// ---------------------- UserDataJob ----------------------
+
+func NewUserDataJobColumnKey(slotId uint32, id uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableUserDataJob.Size)
+	key[0] = TableUserDataJob.Id[0]
+	key[1] = TableUserDataJob.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], uint64(slotId))
+	binary.BigEndian.PutUint64(key[12:], id)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseUserDataJobColumnKey(key []byte) (slotId uint32, id uint64, columnName [2]byte, err error) {
+	if len(key) != TableUserDataJob.Size {
+		err = fmt.Errorf("userDataJob: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	slotId = uint32(binary.BigEndian.Uint64(key[4:]))
+	id = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ParseSubscriberIndexKey 解析订阅者的唯一索引key
+func ParseSubscriberIndexKey(key []byte) (channelHash uint64, id uint64, err error) {
+	if len(key) != TableSubscriber.IndexSize || key[2] != dataTypeIndex {
+		err = fmt.Errorf("subscriber: invalid index key length, keyLen: %d", len(key))
+		return
+	}
+	channelHash = binary.BigEndian.Uint64(key[6:])
+	id = binary.BigEndian.Uint64(key[14:])
+	return
+}
+
+// ---------------------- E2EE ----------------------
+
+func NewE2EEDeviceKeyColumnKey(uid string, deviceId string, columnName [2]byte) []byte {
+	key := make([]byte, TableE2EEDeviceKey.Size)
+	key[0] = TableE2EEDeviceKey.Id[0]
+	key[1] = TableE2EEDeviceKey.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(deviceId))
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func newE2EEUidPrefix(tableId [2]byte, uidHash uint64) []byte {
+	key := make([]byte, 12)
+	key[0] = tableId[0]
+	key[1] = tableId[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], uidHash)
+	return key
+}
+
+// NewE2EEDeviceKeyLowKey 用户所有设备公钥的起始key（包含）
+func NewE2EEDeviceKeyLowKey(uid string) []byte {
+	return newE2EEUidPrefix(TableE2EEDeviceKey.Id, HashWithString(uid))
+}
+
+// NewE2EEDeviceKeyHighKey 用户所有设备公钥的结束key（不包含）
+func NewE2EEDeviceKeyHighKey(uid string) []byte {
+	return newE2EEUidPrefix(TableE2EEDeviceKey.Id, HashWithString(uid)+1)
+}
+
+func ParseE2EEDeviceKeyColumnKey(key []byte) (uidHash uint64, deviceIdHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableE2EEDeviceKey.Size {
+		err = fmt.Errorf("e2eeDeviceKey: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[4:])
+	deviceIdHash = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+func NewE2EEOneTimePrekeyColumnKey(uid string, deviceId string, keyId uint32, columnName [2]byte) []byte {
+	key := make([]byte, TableE2EEOneTimePrekey.Size)
+	key[0] = TableE2EEOneTimePrekey.Id[0]
+	key[1] = TableE2EEOneTimePrekey.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	binary.BigEndian.PutUint64(key[12:], HashWithString(deviceId))
+	binary.BigEndian.PutUint32(key[20:], keyId)
+	key[24] = columnName[0]
+	key[25] = columnName[1]
+	return key
+}
+
+func newE2EEOneTimePrekeyDevicePrefix(uid string, deviceIdHash uint64) []byte {
+	key := make([]byte, 20)
+	copy(key, newE2EEUidPrefix(TableE2EEOneTimePrekey.Id, HashWithString(uid)))
+	binary.BigEndian.PutUint64(key[12:], deviceIdHash)
+	return key
+}
+
+// NewE2EEOneTimePrekeyLowKey 设备一次性预共享公钥的起始key（包含）
+func NewE2EEOneTimePrekeyLowKey(uid string, deviceId string) []byte {
+	return newE2EEOneTimePrekeyDevicePrefix(uid, HashWithString(deviceId))
+}
+
+// NewE2EEOneTimePrekeyHighKey 设备一次性预共享公钥的结束key（不包含）
+func NewE2EEOneTimePrekeyHighKey(uid string, deviceId string) []byte {
+	return newE2EEOneTimePrekeyDevicePrefix(uid, HashWithString(deviceId)+1)
+}
+
+// NewE2EEOneTimePrekeyUidLowKey 用户所有一次性预共享公钥的起始key（包含）
+func NewE2EEOneTimePrekeyUidLowKey(uid string) []byte {
+	return newE2EEUidPrefix(TableE2EEOneTimePrekey.Id, HashWithString(uid))
+}
+
+// NewE2EEOneTimePrekeyUidHighKey 用户所有一次性预共享公钥的结束key（不包含）
+func NewE2EEOneTimePrekeyUidHighKey(uid string) []byte {
+	return newE2EEUidPrefix(TableE2EEOneTimePrekey.Id, HashWithString(uid)+1)
+}
+
+func ParseE2EEOneTimePrekeyColumnKey(key []byte) (keyId uint32, columnName [2]byte, err error) {
+	if len(key) != TableE2EEOneTimePrekey.Size {
+		err = fmt.Errorf("e2eeOneTimePrekey: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	keyId = binary.BigEndian.Uint32(key[20:])
+	columnName[0] = key[24]
+	columnName[1] = key[25]
+	return
+}
+
+// ---------------------- Thread ----------------------
+
+func NewThreadColumnKey(parentChannelId string, parentChannelType uint8, rootMessageSeq uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableThread.Size)
+	key[0] = TableThread.Id[0]
+	key[1] = TableThread.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(parentChannelId, parentChannelType))
+	binary.BigEndian.PutUint64(key[12:], rootMessageSeq)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+// NewThreadLowKey 父频道下所有子区的最小key
+func NewThreadLowKey(parentChannelId string, parentChannelType uint8) []byte {
+	return NewThreadColumnKey(parentChannelId, parentChannelType, 0, MinColumnKey)
+}
+
+// NewThreadHighKey 父频道下所有子区的最大key
+func NewThreadHighKey(parentChannelId string, parentChannelType uint8) []byte {
+	return NewThreadColumnKey(parentChannelId, parentChannelType, math.MaxUint64, MaxColumnKey)
+}
+
+func ParseThreadColumnKey(key []byte) (rootMessageSeq uint64, columnName [2]byte, err error) {
+	if len(key) != TableThread.Size {
+		err = fmt.Errorf("thread: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	rootMessageSeq = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ---------------------- MessageExtra ----------------------
+
+func NewMessageExtraColumnKey(channelId string, channelType uint8, messageSeq uint64, columnName [2]byte) []byte {
+	key := make([]byte, TableMessageExtra.Size)
+	key[0] = TableMessageExtra.Id[0]
+	key[1] = TableMessageExtra.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], channelIdToNum(channelId, channelType))
+	binary.BigEndian.PutUint64(key[12:], messageSeq)
+	key[20] = columnName[0]
+	key[21] = columnName[1]
+	return key
+}
+
+func ParseMessageExtraColumnKey(key []byte) (messageSeq uint64, columnName [2]byte, err error) {
+	if len(key) != TableMessageExtra.Size {
+		err = fmt.Errorf("messageExtra: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	messageSeq = binary.BigEndian.Uint64(key[12:])
+	columnName[0] = key[20]
+	columnName[1] = key[21]
+	return
+}
+
+// ---------------------- Bot ----------------------
+
+func NewBotColumnKey(uid string, columnName [2]byte) []byte {
+	key := make([]byte, TableBot.Size)
+	key[0] = TableBot.Id[0]
+	key[1] = TableBot.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+// NewBotLowKey 所有机器人的最小key
+func NewBotLowKey() []byte {
+	key := make([]byte, TableBot.Size)
+	key[0] = TableBot.Id[0]
+	key[1] = TableBot.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], 0)
+	key[12] = MinColumnKey[0]
+	key[13] = MinColumnKey[1]
+	return key
+}
+
+// NewBotHighKey 所有机器人的最大key
+func NewBotHighKey() []byte {
+	key := make([]byte, TableBot.Size)
+	key[0] = TableBot.Id[0]
+	key[1] = TableBot.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], math.MaxUint64)
+	key[12] = MaxColumnKey[0]
+	key[13] = MaxColumnKey[1]
+	return key
+}
+
+func ParseBotColumnKey(key []byte) (uidHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableBot.Size {
+		err = fmt.Errorf("bot: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ---------------------- UserPresence ----------------------
+
+func NewUserPresenceColumnKey(uid string, columnName [2]byte) []byte {
+	key := make([]byte, TableUserPresence.Size)
+	key[0] = TableUserPresence.Id[0]
+	key[1] = TableUserPresence.Id[1]
+	key[2] = dataTypeTable
+	key[3] = 0
+	binary.BigEndian.PutUint64(key[4:], HashWithString(uid))
+	key[12] = columnName[0]
+	key[13] = columnName[1]
+	return key
+}
+
+func ParseUserPresenceColumnKey(key []byte) (uidHash uint64, columnName [2]byte, err error) {
+	if len(key) != TableUserPresence.Size {
+		err = fmt.Errorf("userPresence: invalid key length, keyLen: %d", len(key))
+		return
+	}
+	uidHash = binary.BigEndian.Uint64(key[4:])
+	columnName[0] = key[12]
+	columnName[1] = key[13]
+	return
+}
+
+// ---------------------- Inspect ----------------------
+
+// NewTableRowLowKey 表数据的起始key（包含）
+func NewTableRowLowKey(tableId [2]byte) []byte {
+	return []byte{tableId[0], tableId[1], dataTypeTable, 0}
+}
+
+// NewTableRowHighKey 表数据的结束key（不包含）
+func NewTableRowHighKey(tableId [2]byte) []byte {
+	return []byte{tableId[0], tableId[1], dataTypeTable + 1, 0}
+}
+
+// NewTableIndexLowKey 表索引（唯一索引和二级索引）的起始key（包含）
+func NewTableIndexLowKey(tableId [2]byte) []byte {
+	return []byte{tableId[0], tableId[1], dataTypeIndex, 0}
+}
+
+// NewTableIndexHighKey 表索引（唯一索引和二级索引）的结束key（不包含）
+func NewTableIndexHighKey(tableId [2]byte) []byte {
+	return []byte{tableId[0], tableId[1], dataTypeSecondIndex + 1, 0}
+}
+
+// IsIndexKey 是否是索引key（唯一索引或二级索引）
+func IsIndexKey(key []byte) bool {
+	if len(key) < 4 {
+		return false
+	}
+	return key[2] == dataTypeIndex || key[2] == dataTypeSecondIndex
+}
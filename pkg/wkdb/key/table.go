@@ -234,9 +234,11 @@ var TableSubscriber = struct {
 	IndexSize       int
 	SecondIndexSize int
 	Column          struct {
-		Uid       [2]byte
-		CreatedAt [2]byte
-		UpdatedAt [2]byte
+		Uid          [2]byte
+		CreatedAt    [2]byte
+		UpdatedAt    [2]byte
+		Role         [2]byte // 成员角色
+		MuteExpireAt [2]byte // 禁言到期时间
 	}
 	Index struct {
 		Uid [2]byte
@@ -251,13 +253,17 @@ var TableSubscriber = struct {
 	IndexSize:       2 + 2 + 2 + 8 + 8,     // tableId + dataType + indexName + channel hash + columnHash
 	SecondIndexSize: 2 + 2 + 2 + 8 + 8 + 8, // tableId + dataType + secondIndexName + channel hash +  columnValue + primaryKey
 	Column: struct {
-		Uid       [2]byte
-		CreatedAt [2]byte
-		UpdatedAt [2]byte
+		Uid          [2]byte
+		CreatedAt    [2]byte
+		UpdatedAt    [2]byte
+		Role         [2]byte
+		MuteExpireAt [2]byte
 	}{
-		Uid:       [2]byte{0x04, 0x01},
-		CreatedAt: [2]byte{0x04, 0x02},
-		UpdatedAt: [2]byte{0x04, 0x03},
+		Uid:          [2]byte{0x04, 0x01},
+		CreatedAt:    [2]byte{0x04, 0x02},
+		UpdatedAt:    [2]byte{0x04, 0x03},
+		Role:         [2]byte{0x04, 0x04},
+		MuteExpireAt: [2]byte{0x04, 0x05},
 	},
 	Index: struct {
 		Uid [2]byte
@@ -313,6 +319,7 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte // 黑名单数量
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		MuteAll         [2]byte // 全员禁言（管理员除外）
 	}
 	Index struct {
 		Channel [2]byte
@@ -343,6 +350,7 @@ var TableChannelInfo = struct {
 		DenylistCount   [2]byte
 		CreatedAt       [2]byte
 		UpdatedAt       [2]byte
+		MuteAll         [2]byte
 	}{
 		Id:              [2]byte{0x06, 0x01},
 		ChannelId:       [2]byte{0x06, 0x02},
@@ -355,6 +363,7 @@ var TableChannelInfo = struct {
 		DenylistCount:   [2]byte{0x06, 0x09},
 		CreatedAt:       [2]byte{0x06, 0x0A},
 		UpdatedAt:       [2]byte{0x06, 0x0B},
+		MuteAll:         [2]byte{0x06, 0x0C},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -490,6 +499,8 @@ var TableConversation = struct {
 		ReadedToMsgSeq [2]byte
 		CreatedAt      [2]byte
 		UpdatedAt      [2]byte
+		MentionMsgSeq  [2]byte
+		MentionAll     [2]byte
 	}
 	Index struct {
 		Channel [2]byte
@@ -513,6 +524,8 @@ var TableConversation = struct {
 		ReadedToMsgSeq [2]byte
 		CreatedAt      [2]byte
 		UpdatedAt      [2]byte
+		MentionMsgSeq  [2]byte
+		MentionAll     [2]byte
 	}{
 		Uid:            [2]byte{0x09, 0x01},
 		ChannelId:      [2]byte{0x09, 0x02},
@@ -522,6 +535,8 @@ var TableConversation = struct {
 		ReadedToMsgSeq: [2]byte{0x09, 0x06},
 		CreatedAt:      [2]byte{0x09, 0x07},
 		UpdatedAt:      [2]byte{0x09, 0x08},
+		MentionMsgSeq:  [2]byte{0x09, 0x09},
+		MentionAll:     [2]byte{0x09, 0x0A},
 	},
 	Index: struct {
 		Channel [2]byte
@@ -657,15 +672,18 @@ var TableChannelCommon = struct {
 	Id     [2]byte
 	Size   int
 	Column struct {
-		AppliedIndex [2]byte
+		AppliedIndex     [2]byte
+		SearchIndexedSeq [2]byte
 	}
 }{
 	Id:   [2]byte{0x0D, 0x01},
 	Size: 2 + 2 + 8 + 2, // tableId + dataType  + channel hash + columnKey
 	Column: struct {
-		AppliedIndex [2]byte
+		AppliedIndex     [2]byte
+		SearchIndexedSeq [2]byte
 	}{
-		AppliedIndex: [2]byte{0x0D, 0x01},
+		AppliedIndex:     [2]byte{0x0D, 0x01},
+		SearchIndexedSeq: [2]byte{0x0D, 0x02},
 	},
 }
 
@@ -722,3 +740,550 @@ var TableSystemUid = struct {
 		Uid: [2]byte{0x10, 0x01},
 	},
 }
+
+// ======================== MessageSearch 消息全文检索索引 ========================
+
+var TableMessageSearch = struct {
+	Id              [2]byte
+	SecondIndexSize int
+	OtherIndexSize  int
+	SecondIndex     struct {
+		Term [2]byte
+	}
+	OtherIndex struct {
+		Seq    [2]byte
+		Expire [2]byte
+	}
+}{
+	Id:              [2]byte{0x0E, 0x01},
+	SecondIndexSize: 2 + 2 + 2 + 8 + 8 + 8, // tableId + dataType + secondIndexName + channel hash + term hash + messageSeq
+	OtherIndexSize:  2 + 2 + 2 + 8 + 8 + 8, // tableId + dataType + otherIndexName + channel hash/expireAt + messageSeq/channel hash + term hash/messageSeq
+	SecondIndex: struct {
+		Term [2]byte
+	}{
+		Term: [2]byte{0x0E, 0x01},
+	},
+	OtherIndex: struct {
+		Seq    [2]byte
+		Expire [2]byte
+	}{
+		Seq:    [2]byte{0x0E, 0x01},
+		Expire: [2]byte{0x0E, 0x02},
+	},
+}
+
+// ======================== ChannelTopic 社区话题 ========================
+// ---------------------
+// | tableID  | dataType	| parent channel hash | topic hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	   		  |  8 字节	   | 2 字节		|
+// ---------------------
+
+var TableChannelTopic = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		TopicId   [2]byte
+		Name      [2]byte
+		Archived  [2]byte
+		CreatedAt [2]byte
+		UpdatedAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x11, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + parent channel hash + topic hash + columnKey
+	Column: struct {
+		TopicId   [2]byte
+		Name      [2]byte
+		Archived  [2]byte
+		CreatedAt [2]byte
+		UpdatedAt [2]byte
+	}{
+		TopicId:   [2]byte{0x11, 0x01},
+		Name:      [2]byte{0x11, 0x02},
+		Archived:  [2]byte{0x11, 0x03},
+		CreatedAt: [2]byte{0x11, 0x04},
+		UpdatedAt: [2]byte{0x11, 0x05},
+	},
+}
+
+// ======================== RevokedToken 已吊销的连接令牌 ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | token id hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节   |  8 字节	     | 2 字节		|
+// ---------------------
+
+var TableRevokedToken = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		TokenId  [2]byte
+		ExpireAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x12, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + uid hash + token id hash + columnKey
+	Column: struct {
+		TokenId  [2]byte
+		ExpireAt [2]byte
+	}{
+		TokenId:  [2]byte{0x12, 0x01},
+		ExpireAt: [2]byte{0x12, 0x02},
+	},
+}
+
+// ======================== ScheduledMessage 定时消息 ========================
+// ---------------------
+// | tableID  | dataType	| primaryKey | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节     | 2 字节		|
+// ---------------------
+// 二级索引
+// | tableID  | dataType	| secondIndexName | slotId/channel hash | sendAt | primaryKey |
+// | 2 byte   | 1 byte   	| 2 字节          | 8 字节              | 8 字节 | 8 字节     |
+// ---------------------
+
+var TableScheduledMessage = struct {
+	Id              [2]byte
+	Size            int
+	SecondIndexSize int
+	SecondIndex     struct {
+		SlotSendAt    [2]byte
+		ChannelSendAt [2]byte
+	}
+	Column struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		FromUid     [2]byte
+		ClientMsgNo [2]byte
+		Header      [2]byte
+		Expire      [2]byte
+		Payload     [2]byte
+		SendAt      [2]byte
+		CreatedAt   [2]byte
+		UpdatedAt   [2]byte
+	}
+}{
+	Id:              [2]byte{0x13, 0x01},
+	Size:            2 + 2 + 8 + 2,         // tableId + dataType + primaryKey + columnKey
+	SecondIndexSize: 2 + 2 + 2 + 8 + 8 + 8, // tableId + dataType + secondIndexName + slotId/channel hash + sendAt + primaryKey
+	SecondIndex: struct {
+		SlotSendAt    [2]byte
+		ChannelSendAt [2]byte
+	}{
+		SlotSendAt:    [2]byte{0x13, 0x01},
+		ChannelSendAt: [2]byte{0x13, 0x02},
+	},
+	Column: struct {
+		ChannelId   [2]byte
+		ChannelType [2]byte
+		FromUid     [2]byte
+		ClientMsgNo [2]byte
+		Header      [2]byte
+		Expire      [2]byte
+		Payload     [2]byte
+		SendAt      [2]byte
+		CreatedAt   [2]byte
+		UpdatedAt   [2]byte
+	}{
+		ChannelId:   [2]byte{0x13, 0x01},
+		ChannelType: [2]byte{0x13, 0x02},
+		FromUid:     [2]byte{0x13, 0x03},
+		ClientMsgNo: [2]byte{0x13, 0x04},
+		Header:      [2]byte{0x13, 0x05},
+		Expire:      [2]byte{0x13, 0x06},
+		Payload:     [2]byte{0x13, 0x07},
+		SendAt:      [2]byte{0x13, 0x08},
+		CreatedAt:   [2]byte{0x13, 0x09},
+		UpdatedAt:   [2]byte{0x13, 0x0a},
+	},
+}
+
+// ======================== BroadcastTask 广播任务 ========================
+// ---------------------
+// | tableID  | dataType	| jobId   | slotId  | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节  | 8 字节  | 2 字节		|
+// ---------------------
+
+var TableBroadcastTask = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Status         [2]byte
+		FromUid        [2]byte
+		Header         [2]byte
+		Payload        [2]byte
+		Uids           [2]byte
+		DeviceFlags    [2]byte
+		UserCreatedGte [2]byte
+		UserCreatedLt  [2]byte
+		Rate           [2]byte
+		Cursor         [2]byte
+		SentCount      [2]byte
+		FailedCount    [2]byte
+		CreatedAt      [2]byte
+		UpdatedAt      [2]byte
+	}
+}{
+	Id:   [2]byte{0x14, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + jobId + slotId + columnKey
+	Column: struct {
+		Status         [2]byte
+		FromUid        [2]byte
+		Header         [2]byte
+		Payload        [2]byte
+		Uids           [2]byte
+		DeviceFlags    [2]byte
+		UserCreatedGte [2]byte
+		UserCreatedLt  [2]byte
+		Rate           [2]byte
+		Cursor         [2]byte
+		SentCount      [2]byte
+		FailedCount    [2]byte
+		CreatedAt      [2]byte
+		UpdatedAt      [2]byte
+	}{
+		Status:         [2]byte{0x14, 0x01},
+		FromUid:        [2]byte{0x14, 0x02},
+		Header:         [2]byte{0x14, 0x03},
+		Payload:        [2]byte{0x14, 0x04},
+		Uids:           [2]byte{0x14, 0x05},
+		DeviceFlags:    [2]byte{0x14, 0x06},
+		UserCreatedGte: [2]byte{0x14, 0x07},
+		UserCreatedLt:  [2]byte{0x14, 0x08},
+		Rate:           [2]byte{0x14, 0x09},
+		Cursor:         [2]byte{0x14, 0x0a},
+		SentCount:      [2]byte{0x14, 0x0b},
+		FailedCount:    [2]byte{0x14, 0x0c},
+		CreatedAt:      [2]byte{0x14, 0x0d},
+		UpdatedAt:      [2]byte{0x14, 0x0e},
+	},
+}
+
+// ======================== UserAttribute 用户属性 ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | name hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节   | 8 字节    | 2 字节		|
+// ---------------------
+
+var TableUserAttribute = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Name      [2]byte
+		Type      [2]byte
+		Value     [2]byte
+		Version   [2]byte
+		UpdatedAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x15, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + uid hash + name hash + columnKey
+	Column: struct {
+		Name      [2]byte
+		Type      [2]byte
+		Value     [2]byte
+		Version   [2]byte
+		UpdatedAt [2]byte
+	}{
+		Name:      [2]byte{0x15, 0x01},
+		Type:      [2]byte{0x15, 0x02},
+		Value:     [2]byte{0x15, 0x03},
+		Version:   [2]byte{0x15, 0x04},
+		UpdatedAt: [2]byte{0x15, 0x05},
+	},
+}
+
+// ======================== ChannelAttribute 频道属性 ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | name hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节       | 8 字节    | 2 字节		|
+// ---------------------
+
+var TableChannelAttribute = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Name      [2]byte
+		Type      [2]byte
+		Value     [2]byte
+		Version   [2]byte
+		UpdatedAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x16, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + name hash + columnKey
+	Column: struct {
+		Name      [2]byte
+		Type      [2]byte
+		Value     [2]byte
+		Version   [2]byte
+		UpdatedAt [2]byte
+	}{
+		Name:      [2]byte{0x16, 0x01},
+		Type:      [2]byte{0x16, 0x02},
+		Value:     [2]byte{0x16, 0x03},
+		Version:   [2]byte{0x16, 0x04},
+		UpdatedAt: [2]byte{0x16, 0x05},
+	},
+}
+
+// ======================== UserDataJob 用户数据导出和删除任务 ========================
+// ---------------------
+// | tableID  | dataType	| slotId  | id      | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节  | 8 字节  | 2 字节		|
+// ---------------------
+
+var TableUserDataJob = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid               [2]byte
+		Type              [2]byte
+		Status            [2]byte
+		Operator          [2]byte
+		NodeId            [2]byte
+		Archive           [2]byte
+		Error             [2]byte
+		ChannelCount      [2]byte
+		MessageCount      [2]byte
+		ConversationCount [2]byte
+		DeviceCount       [2]byte
+		ErasedNodes       [2]byte
+		CreatedAt         [2]byte
+		UpdatedAt         [2]byte
+	}
+}{
+	Id:   [2]byte{0x17, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + slotId + id + columnKey
+	Column: struct {
+		Uid               [2]byte
+		Type              [2]byte
+		Status            [2]byte
+		Operator          [2]byte
+		NodeId            [2]byte
+		Archive           [2]byte
+		Error             [2]byte
+		ChannelCount      [2]byte
+		MessageCount      [2]byte
+		ConversationCount [2]byte
+		DeviceCount       [2]byte
+		ErasedNodes       [2]byte
+		CreatedAt         [2]byte
+		UpdatedAt         [2]byte
+	}{
+		Uid:               [2]byte{0x17, 0x01},
+		Type:              [2]byte{0x17, 0x02},
+		Status:            [2]byte{0x17, 0x03},
+		Operator:          [2]byte{0x17, 0x04},
+		NodeId:            [2]byte{0x17, 0x05},
+		Archive:           [2]byte{0x17, 0x06},
+		Error:             [2]byte{0x17, 0x07},
+		ChannelCount:      [2]byte{0x17, 0x08},
+		MessageCount:      [2]byte{0x17, 0x09},
+		ConversationCount: [2]byte{0x17, 0x0a},
+		DeviceCount:       [2]byte{0x17, 0x0b},
+		ErasedNodes:       [2]byte{0x17, 0x0c},
+		CreatedAt:         [2]byte{0x17, 0x0d},
+		UpdatedAt:         [2]byte{0x17, 0x0e},
+	},
+}
+
+// ======================== E2EEDeviceKey 设备的端到端加密公钥 ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | device id hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节   | 8 字节         | 2 字节		|
+// ---------------------
+
+var TableE2EEDeviceKey = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid                   [2]byte
+		DeviceId              [2]byte
+		IdentityKey           [2]byte
+		SignedPrekeyId        [2]byte
+		SignedPrekey          [2]byte
+		SignedPrekeySignature [2]byte
+		CreatedAt             [2]byte
+		UpdatedAt             [2]byte
+	}
+}{
+	Id:   [2]byte{0x18, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + uid hash + device id hash + columnKey
+	Column: struct {
+		Uid                   [2]byte
+		DeviceId              [2]byte
+		IdentityKey           [2]byte
+		SignedPrekeyId        [2]byte
+		SignedPrekey          [2]byte
+		SignedPrekeySignature [2]byte
+		CreatedAt             [2]byte
+		UpdatedAt             [2]byte
+	}{
+		Uid:                   [2]byte{0x18, 0x01},
+		DeviceId:              [2]byte{0x18, 0x02},
+		IdentityKey:           [2]byte{0x18, 0x03},
+		SignedPrekeyId:        [2]byte{0x18, 0x04},
+		SignedPrekey:          [2]byte{0x18, 0x05},
+		SignedPrekeySignature: [2]byte{0x18, 0x06},
+		CreatedAt:             [2]byte{0x18, 0x07},
+		UpdatedAt:             [2]byte{0x18, 0x08},
+	},
+}
+
+// ======================== E2EEOneTimePrekey 设备的一次性预共享公钥 ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | device id hash | key id | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节   | 8 字节         | 4 字节 | 2 字节		|
+// ---------------------
+
+var TableE2EEOneTimePrekey = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		PublicKey [2]byte
+	}
+}{
+	Id:   [2]byte{0x19, 0x01},
+	Size: 2 + 2 + 8 + 8 + 4 + 2, // tableId + dataType + uid hash + device id hash + key id + columnKey
+	Column: struct {
+		PublicKey [2]byte
+	}{
+		PublicKey: [2]byte{0x19, 0x01},
+	},
+}
+
+// ======================== Thread 消息的子区 ========================
+// ---------------------
+// | tableID  | dataType	| parent channel hash | root message seq | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	   		  |  8 字节	         | 2 字节		|
+// ---------------------
+
+var TableThread = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ReplyCount   [2]byte
+		LastReplyAt  [2]byte
+		Participants [2]byte
+		CreatedAt    [2]byte
+	}
+}{
+	Id:   [2]byte{0x1A, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + parent channel hash + root message seq + columnKey
+	Column: struct {
+		ReplyCount   [2]byte
+		LastReplyAt  [2]byte
+		Participants [2]byte
+		CreatedAt    [2]byte
+	}{
+		ReplyCount:   [2]byte{0x1A, 0x01},
+		LastReplyAt:  [2]byte{0x1A, 0x02},
+		Participants: [2]byte{0x1A, 0x03},
+		CreatedAt:    [2]byte{0x1A, 0x04},
+	},
+}
+
+// ======================== MessageExtra 消息的扩展数据 ========================
+// ---------------------
+// | tableID  | dataType	| channel hash | message seq | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节 	   |  8 字节	 | 2 字节		|
+// ---------------------
+
+var TableMessageExtra = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		ThreadReplyCount  [2]byte
+		ThreadLastReplyAt [2]byte
+	}
+}{
+	Id:   [2]byte{0x1B, 0x01},
+	Size: 2 + 2 + 8 + 8 + 2, // tableId + dataType + channel hash + message seq + columnKey
+	Column: struct {
+		ThreadReplyCount  [2]byte
+		ThreadLastReplyAt [2]byte
+	}{
+		ThreadReplyCount:  [2]byte{0x1B, 0x01},
+		ThreadLastReplyAt: [2]byte{0x1B, 0x02},
+	},
+}
+
+// ======================== Bot 机器人 ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节    | 2 字节		|
+// ---------------------
+
+var TableBot = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid           [2]byte
+		CallbackUrl   [2]byte
+		Secret        [2]byte
+		Channels      [2]byte
+		Triggers      [2]byte
+		CommandPrefix [2]byte
+		RateLimit     [2]byte
+		Timeout       [2]byte
+		CreatedAt     [2]byte
+		UpdatedAt     [2]byte
+	}
+}{
+	Id:   [2]byte{0x1C, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType + uid hash + columnKey
+	Column: struct {
+		Uid           [2]byte
+		CallbackUrl   [2]byte
+		Secret        [2]byte
+		Channels      [2]byte
+		Triggers      [2]byte
+		CommandPrefix [2]byte
+		RateLimit     [2]byte
+		Timeout       [2]byte
+		CreatedAt     [2]byte
+		UpdatedAt     [2]byte
+	}{
+		Uid:           [2]byte{0x1C, 0x01},
+		CallbackUrl:   [2]byte{0x1C, 0x02},
+		Secret:        [2]byte{0x1C, 0x03},
+		Channels:      [2]byte{0x1C, 0x04},
+		Triggers:      [2]byte{0x1C, 0x05},
+		CommandPrefix: [2]byte{0x1C, 0x06},
+		RateLimit:     [2]byte{0x1C, 0x07},
+		Timeout:       [2]byte{0x1C, 0x08},
+		CreatedAt:     [2]byte{0x1C, 0x09},
+		UpdatedAt:     [2]byte{0x1C, 0x0A},
+	},
+}
+
+// ======================== UserPresence 用户的在线状态 ========================
+// ---------------------
+// | tableID  | dataType	| uid hash | columnKey |
+// | 2 byte   | 1 byte   	| 8 字节   | 2 字节		|
+// ---------------------
+
+var TableUserPresence = struct {
+	Id     [2]byte
+	Size   int
+	Column struct {
+		Uid      [2]byte
+		Custom   [2]byte
+		Text     [2]byte
+		LastSeen [2]byte
+	}
+}{
+	Id:   [2]byte{0x1D, 0x01},
+	Size: 2 + 2 + 8 + 2, // tableId + dataType + uid hash + columnKey
+	Column: struct {
+		Uid      [2]byte
+		Custom   [2]byte
+		Text     [2]byte
+		LastSeen [2]byte
+	}{
+		Uid:      [2]byte{0x1D, 0x01},
+		Custom:   [2]byte{0x1D, 0x02},
+		Text:     [2]byte{0x1D, 0x03},
+		LastSeen: [2]byte{0x1D, 0x04},
+	},
+}
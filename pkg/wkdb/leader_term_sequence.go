@@ -4,7 +4,6 @@ import (
 	"math"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 )
 
 func (wk *wukongDB) SetLeaderTermStartIndex(shardNo string, term uint32, index uint64) error {
@@ -15,7 +14,7 @@ func (wk *wukongDB) SetLeaderTermStartIndex(shardNo string, term uint32, index u
 
 func (wk *wukongDB) LeaderLastTerm(shardNo string) (uint32, error) {
 
-	iter := wk.shardDB(shardNo).NewIter(&pebble.IterOptions{
+	iter := wk.shardDB(shardNo).NewIter(&IterOptions{
 		LowerBound: key.NewLeaderTermSequenceTermKey(shardNo, 0),
 		UpperBound: key.NewLeaderTermSequenceTermKey(shardNo, math.MaxUint32),
 	})
@@ -34,7 +33,7 @@ func (wk *wukongDB) LeaderLastTerm(shardNo string) (uint32, error) {
 func (wk *wukongDB) LeaderTermStartIndex(shardNo string, term uint32) (uint64, error) {
 	indexBytes, closer, err := wk.shardDB(shardNo).Get(key.NewLeaderTermSequenceTermKey(shardNo, term))
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return 0, nil
 		}
 		return 0, err
@@ -45,7 +44,7 @@ func (wk *wukongDB) LeaderTermStartIndex(shardNo string, term uint32) (uint64, e
 
 func (wk *wukongDB) LeaderLastTermGreaterThan(shardNo string, term uint32) (uint32, error) {
 
-	iter := wk.shardDB(shardNo).NewIter(&pebble.IterOptions{
+	iter := wk.shardDB(shardNo).NewIter(&IterOptions{
 		LowerBound: key.NewLeaderTermSequenceTermKey(shardNo, term),
 		UpperBound: key.NewLeaderTermSequenceTermKey(shardNo, math.MaxUint32),
 	})
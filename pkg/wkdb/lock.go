@@ -16,6 +16,11 @@ type dblock struct {
 	userLock               *userLock
 	addOrUpdateChannelLock *addOrUpdateChannelLock
 	conversationLock       *conversationLock
+	scheduledMessageLock   *scheduledMessageLock
+	broadcastTaskLock      *broadcastTaskLock
+	attributeLock          *attributeLock
+	userDataJobLock        *userDataJobLock
+	e2eeKeyLock            *e2eeKeyLock
 }
 
 func newDBLock() *dblock {
@@ -28,6 +33,11 @@ func newDBLock() *dblock {
 		totalLock:              newTotalLock(),
 		addOrUpdateChannelLock: newAddOrUpdateChannelLock(),
 		conversationLock:       newConversationLock(),
+		scheduledMessageLock:   newScheduledMessageLock(),
+		broadcastTaskLock:      newBroadcastTaskLock(),
+		attributeLock:          newAttributeLock(),
+		userDataJobLock:        newUserDataJobLock(),
+		e2eeKeyLock:            newE2EEKeyLock(),
 	}
 
 }
@@ -40,6 +50,11 @@ func (d *dblock) start() {
 	d.userLock.StartCleanLoop()
 	d.addOrUpdateChannelLock.StartCleanLoop()
 	d.conversationLock.StartCleanLoop()
+	d.scheduledMessageLock.StartCleanLoop()
+	d.broadcastTaskLock.StartCleanLoop()
+	d.attributeLock.StartCleanLoop()
+	d.userDataJobLock.StartCleanLoop()
+	d.e2eeKeyLock.StartCleanLoop()
 }
 
 func (d *dblock) stop() {
@@ -50,6 +65,11 @@ func (d *dblock) stop() {
 	d.userLock.StopCleanLoop()
 	d.addOrUpdateChannelLock.StopCleanLoop()
 	d.conversationLock.StopCleanLoop()
+	d.scheduledMessageLock.StopCleanLoop()
+	d.broadcastTaskLock.StopCleanLoop()
+	d.attributeLock.StopCleanLoop()
+	d.userDataJobLock.StopCleanLoop()
+	d.e2eeKeyLock.StopCleanLoop()
 }
 
 type channelClusterConfigLock struct {
@@ -248,3 +268,101 @@ func (c *conversationLock) lock(uid string) {
 func (c *conversationLock) unlock(uid string) {
 	c.Unlock(uid)
 }
+
+type scheduledMessageLock struct {
+	*keylock.KeyLock
+}
+
+func newScheduledMessageLock() *scheduledMessageLock {
+	return &scheduledMessageLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (c *scheduledMessageLock) lock(id uint64) {
+	c.Lock(strconv.FormatUint(id, 10))
+}
+
+func (c *scheduledMessageLock) unlock(id uint64) {
+	c.Unlock(strconv.FormatUint(id, 10))
+}
+
+type broadcastTaskLock struct {
+	*keylock.KeyLock
+}
+
+func newBroadcastTaskLock() *broadcastTaskLock {
+	return &broadcastTaskLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (b *broadcastTaskLock) lock(jobId uint64, slotId uint32) {
+	b.Lock(strconv.FormatUint(jobId, 10) + "-" + strconv.FormatUint(uint64(slotId), 10))
+}
+
+func (b *broadcastTaskLock) unlock(jobId uint64, slotId uint32) {
+	b.Unlock(strconv.FormatUint(jobId, 10) + "-" + strconv.FormatUint(uint64(slotId), 10))
+}
+
+type attributeLock struct {
+	*keylock.KeyLock
+}
+
+func newAttributeLock() *attributeLock {
+	return &attributeLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (a *attributeLock) lockUser(uid string) {
+	a.Lock("user-" + uid)
+}
+
+func (a *attributeLock) unlockUser(uid string) {
+	a.Unlock("user-" + uid)
+}
+
+func (a *attributeLock) lockChannel(channelId string, channelType uint8) {
+	a.Lock("channel-" + ChannelToKey(channelId, channelType))
+}
+
+func (a *attributeLock) unlockChannel(channelId string, channelType uint8) {
+	a.Unlock("channel-" + ChannelToKey(channelId, channelType))
+}
+
+type userDataJobLock struct {
+	*keylock.KeyLock
+}
+
+func newUserDataJobLock() *userDataJobLock {
+	return &userDataJobLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (u *userDataJobLock) lock(slotId uint32, id uint64) {
+	u.Lock(strconv.FormatUint(uint64(slotId), 10) + "-" + strconv.FormatUint(id, 10))
+}
+
+func (u *userDataJobLock) unlock(slotId uint32, id uint64) {
+	u.Unlock(strconv.FormatUint(uint64(slotId), 10) + "-" + strconv.FormatUint(id, 10))
+}
+
+type e2eeKeyLock struct {
+	*keylock.KeyLock
+}
+
+func newE2EEKeyLock() *e2eeKeyLock {
+	return &e2eeKeyLock{
+		keylock.NewKeyLock(),
+	}
+}
+
+func (e *e2eeKeyLock) lock(uid string, deviceId string) {
+	e.Lock(uid + "-" + deviceId)
+}
+
+func (e *e2eeKeyLock) unlock(uid string, deviceId string) {
+	e.Unlock(uid + "-" + deviceId)
+}
@@ -12,7 +12,6 @@ import (
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
 	wkproto "github.com/WuKongIM/WuKongIMGoProto"
-	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 )
@@ -42,6 +41,13 @@ func (wk *wukongDB) AppendMessages(channelId string, channelType uint8, msgs []M
 		}
 	}
 
+	// 子区频道的消息需要更新子区和根消息的扩展数据
+	if parentChannelId, rootMessageSeq, ok := ParseThreadChannelId(channelId); ok {
+		if err := wk.writeThreadReplies(parentChannelId, channelType, rootMessageSeq, msgs, batch); err != nil {
+			return err
+		}
+	}
+
 	// 消息总数量+1
 	// err := wk.IncMessageCount(len(msgs))
 	// if err != nil {
@@ -51,12 +57,16 @@ func (wk *wukongDB) AppendMessages(channelId string, channelType uint8, msgs []M
 	return batch.Commit(wk.sync)
 }
 
-func (wk *wukongDB) channelDb(channelId string, channelType uint8) *pebble.DB {
+func (wk *wukongDB) channelDb(channelId string, channelType uint8) KV {
 	dbIndex := wk.channelDbIndex(channelId, channelType)
 	return wk.shardDBById(uint32(dbIndex))
 }
 
 func (wk *wukongDB) channelDbIndex(channelId string, channelType uint8) uint32 {
+	// 子区频道与父频道存放在同一个db内
+	if parentChannelId, _, ok := ParseThreadChannelId(channelId); ok {
+		channelId = parentChannelId
+	}
 	return uint32(key.ChannelIdToNum(channelId, channelType) % uint64(len(wk.dbs)))
 }
 
@@ -127,9 +137,10 @@ func (wk *wukongDB) AppendMessagesBatch(reqs []AppendMessagesReq) error {
 
 }
 
-func (wk *wukongDB) writeMessagesBatch(db *pebble.DB, reqs []AppendMessagesReq) error {
+func (wk *wukongDB) writeMessagesBatch(db KV, reqs []AppendMessagesReq) error {
 	batch := db.NewBatch()
 	defer batch.Close()
+	var threadReqs []AppendMessagesReq
 	for _, req := range reqs {
 		lastMsg := req.Messages[len(req.Messages)-1]
 		for _, msg := range req.Messages {
@@ -141,6 +152,16 @@ func (wk *wukongDB) writeMessagesBatch(db *pebble.DB, reqs []AppendMessagesReq)
 		if err != nil {
 			return err
 		}
+		if _, _, ok := ParseThreadChannelId(req.ChannelId); ok {
+			threadReqs = appendThreadReq(threadReqs, req)
+		}
+	}
+	// 子区频道的消息需要更新子区和根消息的扩展数据（同一个子区在一批内只更新一次）
+	for _, req := range threadReqs {
+		parentChannelId, rootMessageSeq, _ := ParseThreadChannelId(req.ChannelId)
+		if err := wk.writeThreadReplies(parentChannelId, req.ChannelType, rootMessageSeq, req.Messages, batch); err != nil {
+			return err
+		}
 	}
 	if err := batch.Commit(wk.sync); err != nil {
 		return err
@@ -154,7 +175,7 @@ func (wk *wukongDB) GetMessage(messageId uint64) (Message, error) {
 	for _, db := range wk.dbs {
 		result, closer, err := db.Get(messageIdKey)
 		if err != nil {
-			if err == pebble.ErrNotFound {
+			if err == ErrNotFound {
 				continue
 			}
 			return EmptyMessage, err
@@ -166,7 +187,7 @@ func (wk *wukongDB) GetMessage(messageId uint64) (Message, error) {
 		}
 		var arr [16]byte
 		copy(arr[:], result)
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewMessageColumnKeyWithPrimary(arr, key.MinColumnKey),
 			UpperBound: key.NewMessageColumnKeyWithPrimary(arr, key.MaxColumnKey),
 		})
@@ -236,7 +257,7 @@ func (wk *wukongDB) LoadPrevRangeMsgs(channelId string, channelType uint8, start
 
 	db := wk.channelDb(channelId, channelType)
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, minSeq),
 		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, maxSeq),
 	})
@@ -272,7 +293,7 @@ func (wk *wukongDB) LoadNextRangeMsgs(channelId string, channelType uint8, start
 
 	db := wk.channelDb(channelId, channelType)
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, minSeq),
 		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, maxSeq),
 	})
@@ -295,7 +316,7 @@ func (wk *wukongDB) LoadMsg(channelId string, channelType uint8, seq uint64) (Me
 
 	db := wk.channelDb(channelId, channelType)
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, seq),
 		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, seq+1),
 	})
@@ -358,7 +379,7 @@ func (wk *wukongDB) LoadNextRangeMsgsForSize(channelId string, channelType uint8
 	}
 	db := wk.channelDb(channelId, channelType)
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewMessagePrimaryKey(channelId, channelType, minSeq),
 		UpperBound: key.NewMessagePrimaryKey(channelId, channelType, maxSeq),
 	})
@@ -391,10 +412,31 @@ func (wk *wukongDB) TruncateLogTo(channelId string, channelType uint8, messageSe
 	if err != nil {
 		return err
 	}
+	// 被截断的消息也需要从检索索引中移除
+	err = wk.removeMessageSearchIndexRange(db, key.ChannelIdToNum(channelId, channelType), messageSeq, math.MaxUint64, batch)
+	if err != nil {
+		return err
+	}
+	indexedSeq, err := wk.GetMessageSearchIndexedSeq(channelId, channelType)
+	if err != nil {
+		return err
+	}
+	if indexedSeq >= messageSeq {
+		seqBytes := make([]byte, 8)
+		wk.endian.PutUint64(seqBytes, messageSeq-1)
+		if err = batch.Set(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.SearchIndexedSeq), seqBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
 	err = wk.setChannelLastMessageSeq(channelId, channelType, messageSeq-1, batch, wk.noSync)
 	if err != nil {
 		return err
 	}
+	if parentChannelId, rootMessageSeq, ok := ParseThreadChannelId(channelId); ok {
+		if err = wk.truncateThread(parentChannelId, channelType, rootMessageSeq, messageSeq-1, batch); err != nil {
+			return err
+		}
+	}
 
 	return batch.Commit(wk.sync)
 }
@@ -409,7 +451,7 @@ func (wk *wukongDB) GetChannelLastMessageSeq(channelId string, channelType uint8
 	db := wk.channelDb(channelId, channelType)
 	result, closer, err := db.Get(key.NewChannelLastMessageSeqKey(channelId, channelType))
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return 0, 0, nil
 		}
 		return 0, 0, err
@@ -475,7 +517,7 @@ func (wk *wukongDB) SetChannellastMessageSeqBatch(reqs []SetChannelLastMessageSe
 var minMessagePrimaryKey = [16]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
 var maxMessagePrimaryKey = [16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
 
-func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, iterFnc func(m Message) bool) (bool, error) {
+func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db KV, iterFnc func(m Message) bool) (bool, error) {
 	var lowKey []byte
 	var highKey []byte
 
@@ -504,7 +546,7 @@ func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, it
 		return false, nil
 	}
 
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: lowKey,
 		UpperBound: highKey,
 	})
@@ -517,7 +559,7 @@ func (wk *wukongDB) searchMessageByIndex(req MessageSearchReq, db *pebble.DB, it
 			continue
 		}
 
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MinColumnKey),
 			UpperBound: key.NewMessageColumnKeyWithPrimary(primaryBytes, key.MaxColumnKey),
 		})
@@ -622,7 +664,7 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 			}
 		}
 
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewMessagePrimaryKey(req.ChannelId, req.ChannelType, startSeq),
 			UpperBound: key.NewMessagePrimaryKey(req.ChannelId, req.ChannelType, endSeq),
 		})
@@ -661,7 +703,7 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 				}
 			}
 
-			iter := db.NewIter(&pebble.IterOptions{
+			iter := db.NewIter(&IterOptions{
 				LowerBound: key.NewMessageIndexMessageIdKey(startMessageId),
 				UpperBound: key.NewMessageIndexMessageIdKey(endMessageId),
 			})
@@ -683,7 +725,7 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 
 			for ; iter.Valid(); iterStepFnc() {
 				copy(pkey[:], iter.Value())
-				resultIter := db.NewIter(&pebble.IterOptions{
+				resultIter := db.NewIter(&IterOptions{
 					LowerBound: key.NewMessageColumnKeyWithPrimary(pkey, key.MinColumnKey),
 					UpperBound: key.NewMessageColumnKeyWithPrimary(pkey, key.MaxColumnKey),
 				})
@@ -720,7 +762,7 @@ func (wk *wukongDB) SearchMessages(req MessageSearchReq) ([]Message, error) {
 	return allMsgs, nil
 }
 
-func (wk *wukongDB) setChannelLastMessageSeq(channelId string, channelType uint8, seq uint64, w pebble.Writer, o *pebble.WriteOptions) error {
+func (wk *wukongDB) setChannelLastMessageSeq(channelId string, channelType uint8, seq uint64, w Writer, o *WriteOptions) error {
 	data := make([]byte, 16)
 	wk.endian.PutUint64(data, seq)
 	setTime := time.Now().UnixNano()
@@ -729,11 +771,11 @@ func (wk *wukongDB) setChannelLastMessageSeq(channelId string, channelType uint8
 	return w.Set(key.NewChannelLastMessageSeqKey(channelId, channelType), data, o)
 }
 
-func (wk *wukongDB) iteratorChannelMessages(iter *pebble.Iterator, limit int, iterFnc func(m Message) bool) error {
+func (wk *wukongDB) iteratorChannelMessages(iter Iterator, limit int, iterFnc func(m Message) bool) error {
 	return wk.iteratorChannelMessagesDirection(iter, limit, false, iterFnc)
 }
 
-func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limit int, reverse bool, iterFnc func(m Message) bool) error {
+func (wk *wukongDB) iteratorChannelMessagesDirection(iter Iterator, limit int, reverse bool, iterFnc func(m Message) bool) error {
 	var (
 		size           int
 		preMessageSeq  uint64
@@ -829,7 +871,7 @@ func (wk *wukongDB) iteratorChannelMessagesDirection(iter *pebble.Iterator, limi
 
 }
 
-func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, limitSize uint64) ([]Message, error) {
+func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter Iterator, limitSize uint64) ([]Message, error) {
 	var (
 		msgs           = make([]Message, 0)
 		preMessageSeq  uint64
@@ -903,7 +945,7 @@ func (wk *wukongDB) parseChannelMessagesWithLimitSize(iter *pebble.Iterator, lim
 
 }
 
-func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Message, w pebble.Writer) error {
+func (wk *wukongDB) writeMessage(channelId string, channelType uint8, msg Message, w Writer) error {
 
 	var (
 		messageIdBytes = make([]byte, 8)
@@ -4,7 +4,6 @@ import (
 	"math"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 )
 
 // AppendMessageOfNotifyQueue 添加消息到通知队列
@@ -22,7 +21,7 @@ func (wk *wukongDB) AppendMessageOfNotifyQueue(messages []Message) error {
 // GetMessagesOfNotifyQueue 获取通知队列的消息
 func (wk *wukongDB) GetMessagesOfNotifyQueue(count int) ([]Message, error) {
 
-	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
 		LowerBound: key.NewMessageNotifyQueueKey(0),
 		UpperBound: key.NewMessageNotifyQueueKey(math.MaxUint64),
 	})
@@ -45,7 +44,7 @@ func (wk *wukongDB) RemoveMessagesOfNotifyQueue(messageIDs []int64) error {
 	return batch.Commit(wk.sync)
 }
 
-func (wk *wukongDB) writeMessageOfNotifyQueue(msg Message, w *pebble.Batch) error {
+func (wk *wukongDB) writeMessageOfNotifyQueue(msg Message, w Batch) error {
 	data, err := msg.Marshal()
 	if err != nil {
 		return err
@@ -53,7 +52,7 @@ func (wk *wukongDB) writeMessageOfNotifyQueue(msg Message, w *pebble.Batch) erro
 	return w.Set(key.NewMessageNotifyQueueKey(uint64(msg.MessageID)), data, wk.sync)
 }
 
-func (wk *wukongDB) parseMessageOfNotifyQueue(iter *pebble.Iterator, limit int) ([]Message, error) {
+func (wk *wukongDB) parseMessageOfNotifyQueue(iter Iterator, limit int) ([]Message, error) {
 
 	msgs := make([]Message, 0, limit)
 	for iter.First(); iter.Valid(); iter.Next() {
@@ -0,0 +1,235 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"go.uber.org/zap"
+)
+
+func (wk *wukongDB) AddMessageSearchIndex(reqs []MessageSearchIndexReq) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("addMessageSearchIndex done", zap.Duration("cost", cost), zap.Int("reqs", len(reqs)))
+			}
+		}()
+	}
+
+	// 按照db进行分组
+	dbMap := make(map[uint32][]MessageSearchIndexReq)
+	for _, req := range reqs {
+		shardId := wk.channelDbIndex(req.ChannelId, req.ChannelType)
+		dbMap[shardId] = append(dbMap[shardId], req)
+	}
+
+	for shardId, reqs := range dbMap {
+		db := wk.shardDBById(shardId)
+		batch := db.NewBatch()
+		for _, req := range reqs {
+			if err := wk.writeMessageSearchIndex(req, batch); err != nil {
+				batch.Close()
+				return err
+			}
+		}
+		err := batch.Commit(wk.noSync)
+		batch.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) SearchMessageIndex(channelId string, channelType uint8, terms []string, offsetMessageSeq uint64, limit int) ([]uint64, error) {
+	termHashes := uniqueTermHashes(terms)
+	if len(termHashes) == 0 {
+		return nil, nil
+	}
+
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	db := wk.channelDb(channelId, channelType)
+
+	maxSeq := uint64(math.MaxUint64)
+	if offsetMessageSeq > 0 {
+		maxSeq = offsetMessageSeq
+	}
+
+	// 以第一个关键词的倒排索引作为候选集，其他关键词逐个校验
+	iter := db.NewIter(&IterOptions{
+		LowerBound: key.NewMessageSearchTermKey(channelHash, termHashes[0], 0),
+		UpperBound: key.NewMessageSearchTermKey(channelHash, termHashes[0], maxSeq),
+	})
+	defer iter.Close()
+
+	seqs := make([]uint64, 0, limit)
+	for iter.Last(); iter.Valid(); iter.Prev() {
+		messageSeq, err := key.ParseMessageSearchTermKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		matched := true
+		for _, termHash := range termHashes[1:] {
+			exist, err := wk.existKey(db, key.NewMessageSearchTermKey(channelHash, termHash, messageSeq))
+			if err != nil {
+				return nil, err
+			}
+			if !exist {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		seqs = append(seqs, messageSeq)
+		if limit > 0 && len(seqs) >= limit {
+			break
+		}
+	}
+	return seqs, nil
+}
+
+func (wk *wukongDB) RemoveExpiredMessageSearchIndex(now uint64, limit int) (int, error) {
+	var count int
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewMessageSearchExpireKey(0, 0, 0),
+			UpperBound: key.NewMessageSearchExpireKey(now+1, 0, 0),
+		})
+		batch := db.NewBatch()
+		for iter.First(); iter.Valid(); iter.Next() {
+			_, channelHash, messageSeq, err := key.ParseMessageSearchExpireKey(iter.Key())
+			if err != nil {
+				wk.Warn("parse message search expire key failed", zap.Error(err))
+				continue
+			}
+			if err = wk.removeMessageSearchIndexRange(db, channelHash, messageSeq, messageSeq+1, batch); err != nil {
+				iter.Close()
+				batch.Close()
+				return count, err
+			}
+			count++
+			if limit > 0 && count >= limit {
+				break
+			}
+		}
+		iter.Close()
+		err := batch.Commit(wk.sync)
+		batch.Close()
+		if err != nil {
+			return count, err
+		}
+		if limit > 0 && count >= limit {
+			break
+		}
+	}
+	return count, nil
+}
+
+func (wk *wukongDB) SetMessageSearchIndexedSeq(channelId string, channelType uint8, messageSeq uint64) error {
+	seqBytes := make([]byte, 8)
+	wk.endian.PutUint64(seqBytes, messageSeq)
+	return wk.channelDb(channelId, channelType).Set(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.SearchIndexedSeq), seqBytes, wk.sync)
+}
+
+func (wk *wukongDB) GetMessageSearchIndexedSeq(channelId string, channelType uint8) (uint64, error) {
+	data, closer, err := wk.channelDb(channelId, channelType).Get(key.NewChannelCommonColumnKey(channelId, channelType, key.TableChannelCommon.Column.SearchIndexedSeq))
+	if closer != nil {
+		defer closer.Close()
+	}
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return wk.endian.Uint64(data), nil
+}
+
+func (wk *wukongDB) writeMessageSearchIndex(req MessageSearchIndexReq, w Writer) error {
+	channelHash := key.ChannelIdToNum(req.ChannelId, req.ChannelType)
+
+	expireBytes := make([]byte, 8)
+	wk.endian.PutUint64(expireBytes, req.ExpireAt)
+
+	for _, termHash := range uniqueTermHashes(req.Terms) {
+		if err := w.Set(key.NewMessageSearchTermKey(channelHash, termHash, req.MessageSeq), nil, wk.noSync); err != nil {
+			return err
+		}
+		if err := w.Set(key.NewMessageSearchSeqKey(channelHash, req.MessageSeq, termHash), expireBytes, wk.noSync); err != nil {
+			return err
+		}
+	}
+	if req.ExpireAt > 0 {
+		if err := w.Set(key.NewMessageSearchExpireKey(req.ExpireAt, channelHash, req.MessageSeq), nil, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeMessageSearchIndexRange 通过正排索引移除[startSeq,endSeq)范围内消息的检索索引
+func (wk *wukongDB) removeMessageSearchIndexRange(db KV, channelHash uint64, startSeq, endSeq uint64, w Writer) error {
+	lowKey := key.NewMessageSearchSeqKey(channelHash, startSeq, 0)
+	highKey := key.NewMessageSearchSeqKey(channelHash, endSeq, 0)
+	iter := db.NewIter(&IterOptions{
+		LowerBound: lowKey,
+		UpperBound: highKey,
+	})
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, messageSeq, termHash, err := key.ParseMessageSearchSeqKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if err = w.Delete(key.NewMessageSearchTermKey(channelHash, termHash, messageSeq), wk.noSync); err != nil {
+			return err
+		}
+		if len(iter.Value()) == 8 {
+			expireAt := wk.endian.Uint64(iter.Value())
+			if expireAt > 0 {
+				if err = w.Delete(key.NewMessageSearchExpireKey(expireAt, channelHash, messageSeq), wk.noSync); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.DeleteRange(lowKey, highKey, wk.noSync)
+}
+
+func (wk *wukongDB) existKey(db KV, k []byte) (bool, error) {
+	_, closer, err := db.Get(k)
+	if err != nil {
+		if err == ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	closer.Close()
+	return true, nil
+}
+
+func uniqueTermHashes(terms []string) []uint64 {
+	hashes := make([]uint64, 0, len(terms))
+	exists := make(map[uint64]struct{}, len(terms))
+	for _, term := range terms {
+		if term == "" {
+			continue
+		}
+		h := key.HashWithString(term)
+		if _, ok := exists[h]; ok {
+			continue
+		}
+		exists[h] = struct{}{}
+		hashes = append(hashes, h)
+	}
+	return hashes
+}
@@ -0,0 +1,106 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAndSearchMessageIndex(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	err = d.AddMessageSearchIndex([]wkdb.MessageSearchIndexReq{
+		{ChannelId: channelId, ChannelType: channelType, MessageSeq: 1, Terms: []string{"hello", "world"}},
+		{ChannelId: channelId, ChannelType: channelType, MessageSeq: 2, Terms: []string{"hello", "wukong"}},
+		{ChannelId: channelId, ChannelType: channelType, MessageSeq: 3, Terms: []string{"hello", "world", "wukong"}},
+		{ChannelId: "other", ChannelType: channelType, MessageSeq: 1, Terms: []string{"hello"}},
+	})
+	assert.NoError(t, err)
+
+	seqs, err := d.SearchMessageIndex(channelId, channelType, []string{"hello"}, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 2, 1}, seqs)
+
+	seqs, err = d.SearchMessageIndex(channelId, channelType, []string{"world", "hello"}, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 1}, seqs)
+
+	seqs, err = d.SearchMessageIndex(channelId, channelType, []string{"hello"}, 3, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{2}, seqs)
+}
+
+func TestRemoveExpiredMessageSearchIndex(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	err = d.AddMessageSearchIndex([]wkdb.MessageSearchIndexReq{
+		{ChannelId: channelId, ChannelType: channelType, MessageSeq: 1, ExpireAt: 100, Terms: []string{"hello"}},
+		{ChannelId: channelId, ChannelType: channelType, MessageSeq: 2, ExpireAt: 200, Terms: []string{"hello"}},
+		{ChannelId: channelId, ChannelType: channelType, MessageSeq: 3, Terms: []string{"hello"}},
+	})
+	assert.NoError(t, err)
+
+	count, err := d.RemoveExpiredMessageSearchIndex(150, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	seqs, err := d.SearchMessageIndex(channelId, channelType, []string{"hello"}, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{3, 2}, seqs)
+}
+
+func TestTruncateLogToRemoveMessageSearchIndex(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel"
+	channelType := uint8(2)
+
+	reqs := make([]wkdb.MessageSearchIndexReq, 0)
+	for i := 1; i <= 10; i++ {
+		reqs = append(reqs, wkdb.MessageSearchIndexReq{ChannelId: channelId, ChannelType: channelType, MessageSeq: uint64(i), Terms: []string{"hello"}})
+	}
+	err = d.AddMessageSearchIndex(reqs)
+	assert.NoError(t, err)
+	err = d.SetMessageSearchIndexedSeq(channelId, channelType, 10)
+	assert.NoError(t, err)
+
+	err = d.TruncateLogTo(channelId, channelType, 6)
+	assert.NoError(t, err)
+
+	seqs, err := d.SearchMessageIndex(channelId, channelType, []string{"hello"}, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{5, 4, 3, 2, 1}, seqs)
+
+	// 已建立索引的seq回退到截断的位置
+	indexedSeq, err := d.GetMessageSearchIndexedSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), indexedSeq)
+}
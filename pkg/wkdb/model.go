@@ -124,6 +124,7 @@ type ChannelInfo struct {
 	LastMsgSeq      uint64     `json:"last_msg_seq,omitempty"`     // 最新消息序号
 	LastMsgTime     uint64     `json:"last_msg_time,omitempty"`    // 最后一次消息时间
 	Webhook         string     `json:"webhook,omitempty"`          // webhook地址
+	MuteAll         bool       `json:"mute_all,omitempty"`         // 是否全员禁言（管理员和群主除外）
 	CreatedAt       *time.Time `json:"created_at,omitempty"`       // 创建时间
 	UpdatedAt       *time.Time `json:"updated_at,omitempty"`       // 更新时间
 }
@@ -187,6 +188,375 @@ func (c *ChannelInfo) Unmarshal(data []byte) error {
 	return nil
 }
 
+var EmptyChannelTopic = ChannelTopic{}
+
+func IsEmptyChannelTopic(t ChannelTopic) bool {
+	return strings.TrimSpace(t.TopicId) == ""
+}
+
+// ChannelTopic 社区话题，话题频道的频道ID为 父频道ID@话题ID
+type ChannelTopic struct {
+	ParentChannelId   string     `json:"parent_channel_id,omitempty"`   // 父频道（社区）ID
+	ParentChannelType uint8      `json:"parent_channel_type,omitempty"` // 父频道（社区）类型
+	TopicId           string     `json:"topic_id,omitempty"`            // 话题ID
+	Name              string     `json:"name,omitempty"`                // 话题名称
+	Archived          bool       `json:"archived,omitempty"`            // 是否已归档
+	CreatedAt         *time.Time `json:"created_at,omitempty"`          // 创建时间
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`          // 更新时间
+}
+
+// ChannelId 话题频道ID
+func (t ChannelTopic) ChannelId() string {
+	return fmt.Sprintf("%s@%s", t.ParentChannelId, t.TopicId)
+}
+
+// threadChannelIdSeparator 子区频道ID的分隔符，子区频道ID为 父频道ID____thread根消息seq
+const threadChannelIdSeparator = "____thread"
+
+// ThreadChannelId 获取子区的频道ID，子区是父频道下的子日志，频道类型与父频道相同
+func ThreadChannelId(parentChannelId string, rootMessageSeq uint64) string {
+	return parentChannelId + threadChannelIdSeparator + strconv.FormatUint(rootMessageSeq, 10)
+}
+
+// ParseThreadChannelId 解析子区的频道ID，不是子区频道时ok为false
+func ParseThreadChannelId(channelId string) (parentChannelId string, rootMessageSeq uint64, ok bool) {
+	idx := strings.LastIndex(channelId, threadChannelIdSeparator)
+	if idx <= 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(channelId[idx+len(threadChannelIdSeparator):], 10, 64)
+	if err != nil || seq == 0 {
+		return "", 0, false
+	}
+	return channelId[:idx], seq, true
+}
+
+// ThreadMaxParticipants 子区最多记录的参与者数量
+const ThreadMaxParticipants = 500
+
+var EmptyThread = Thread{}
+
+func IsEmptyThread(t Thread) bool {
+	return t.RootMessageSeq == 0
+}
+
+// Thread 消息的子区（回复串），子区的消息存放在子区频道的日志内，不会出现在父频道
+// 子区的数据在子区频道的消息写入时更新，所有副本上的数据一致
+type Thread struct {
+	ChannelId      string     `json:"channel_id,omitempty"`       // 父频道ID
+	ChannelType    uint8      `json:"channel_type,omitempty"`     // 父频道类型
+	RootMessageSeq uint64     `json:"root_message_seq,omitempty"` // 根消息的seq
+	ReplyCount     uint64     `json:"reply_count,omitempty"`      // 回复数量（子区日志的最大seq）
+	LastReplyAt    *time.Time `json:"last_reply_at,omitempty"`    // 最后回复时间
+	Participants   []string   `json:"participants,omitempty"`     // 参与者（根消息的发送者和回复者）
+	CreatedAt      *time.Time `json:"created_at,omitempty"`       // 创建时间（第一条回复的时间）
+}
+
+// ThreadChannelId 子区频道ID
+func (t Thread) ThreadChannelId() string {
+	return ThreadChannelId(t.ChannelId, t.RootMessageSeq)
+}
+
+// MessageExtra 消息的扩展数据
+type MessageExtra struct {
+	MessageSeq        uint64     `json:"message_seq,omitempty"`          // 消息seq
+	ThreadReplyCount  uint64     `json:"thread_reply_count,omitempty"`   // 子区的回复数量
+	ThreadLastReplyAt *time.Time `json:"thread_last_reply_at,omitempty"` // 子区的最后回复时间
+}
+
+// BotTrigger 机器人的触发方式
+type BotTrigger uint8
+
+const (
+	BotTriggerDirect  BotTrigger = 1 << iota // 单聊：发给机器人的个人频道消息
+	BotTriggerMention                        // @机器人：频道消息的mention里指定了机器人
+)
+
+func (t BotTrigger) Has(trigger BotTrigger) bool {
+	return t&trigger != 0
+}
+
+// BotChannel 机器人绑定的频道，频道内的所有消息都推送给机器人
+type BotChannel struct {
+	ChannelId   string `json:"channel_id"`
+	ChannelType uint8  `json:"channel_type"`
+}
+
+var EmptyBot = Bot{}
+
+func IsEmptyBot(b Bot) bool {
+	return strings.TrimSpace(b.Uid) == ""
+}
+
+// Bot 机器人，匹配的消息会推送到机器人的回调地址，回调的响应里可以带回复的消息
+// 机器人数据全局存放在槽0上，各个节点缓存所有机器人
+type Bot struct {
+	Uid           string        `json:"uid,omitempty"`            // 机器人的uid，回复消息以此uid发送
+	CallbackUrl   string        `json:"callback_url,omitempty"`   // 推送消息的回调地址
+	Secret        string        `json:"secret,omitempty"`         // 推送消息的签名密钥
+	Channels      []BotChannel  `json:"channels,omitempty"`       // 绑定的频道
+	Triggers      BotTrigger    `json:"triggers,omitempty"`       // 触发方式
+	CommandPrefix string        `json:"command_prefix,omitempty"` // 命令前缀，消息内容以此开头时触发（例如/）
+	RateLimit     uint32        `json:"rate_limit,omitempty"`     // 每秒最多推送的消息数量，0表示使用默认配置
+	Timeout       time.Duration `json:"timeout,omitempty"`        // 推送的超时时间，0表示使用默认配置
+	CreatedAt     *time.Time    `json:"created_at,omitempty"`     // 创建时间
+	UpdatedAt     *time.Time    `json:"updated_at,omitempty"`     // 更新时间
+}
+
+// RevokedToken 已吊销的连接令牌（签名令牌认证模式下使用）
+type RevokedToken struct {
+	Uid      string `json:"uid,omitempty"`       // 用户uid
+	TokenId  string `json:"token_id,omitempty"`  // 令牌ID（jti）
+	ExpireAt uint64 `json:"expire_at,omitempty"` // 令牌的过期时间（unix秒），过期后吊销记录不再生效，0表示永久
+}
+
+var EmptyUserPresence = UserPresence{}
+
+func IsEmptyUserPresence(p UserPresence) bool {
+	return strings.TrimSpace(p.Uid) == ""
+}
+
+// UserPresence 用户的在线状态（只持久化用户自定义的部分和最后在线时间，是否在线由连接决定）
+type UserPresence struct {
+	Uid      string `json:"uid,omitempty"`       // 用户uid
+	Custom   uint8  `json:"custom,omitempty"`    // 用户自定义的状态
+	Text     string `json:"text,omitempty"`      // 自定义状态文本
+	LastSeen int64  `json:"last_seen,omitempty"` // 最后在线时间（unix秒）
+}
+
+var EmptyScheduledMessage = ScheduledMessage{}
+
+func IsEmptyScheduledMessage(m ScheduledMessage) bool {
+	return m.Id == 0
+}
+
+// ScheduledMessage 定时消息，到达发送时间后由目标频道所在槽的领导节点发送
+type ScheduledMessage struct {
+	Id          uint64     `json:"id,omitempty"`            // 定时消息ID
+	ChannelId   string     `json:"channel_id,omitempty"`    // 目标频道ID
+	ChannelType uint8      `json:"channel_type,omitempty"`  // 目标频道类型
+	FromUid     string     `json:"from_uid,omitempty"`      // 发送者uid
+	ClientMsgNo string     `json:"client_msg_no,omitempty"` // 客户端消息编号，为空时发送时生成
+	NoPersist   bool       `json:"no_persist,omitempty"`    // 是否不存储
+	RedDot      bool       `json:"red_dot,omitempty"`       // 是否显示红点
+	SyncOnce    bool       `json:"sync_once,omitempty"`     // 是否只同步一次
+	Expire      uint32     `json:"expire,omitempty"`        // 消息过期时间
+	Payload     []byte     `json:"payload,omitempty"`       // 消息内容
+	SendAt      int64      `json:"send_at,omitempty"`       // 发送时间（unix秒）
+	CreatedAt   *time.Time `json:"created_at,omitempty"`    // 创建时间
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`    // 更新时间
+}
+
+var EmptyBroadcastTask = BroadcastTask{}
+
+func IsEmptyBroadcastTask(t BroadcastTask) bool {
+	return t.JobId == 0
+}
+
+// BroadcastStatus 广播任务状态
+type BroadcastStatus uint8
+
+const (
+	BroadcastStatusUnknown   BroadcastStatus = iota
+	BroadcastStatusRunning                   // 发送中
+	BroadcastStatusDone                      // 已完成
+	BroadcastStatusCancelled                 // 已取消
+)
+
+func (b BroadcastStatus) String() string {
+	switch b {
+	case BroadcastStatusRunning:
+		return "running"
+	case BroadcastStatusDone:
+		return "done"
+	case BroadcastStatusCancelled:
+		return "cancelled"
+	}
+	return "unknown"
+}
+
+// Finished 任务是否已结束（结束的任务不再更新进度）
+func (b BroadcastStatus) Finished() bool {
+	return b == BroadcastStatusDone || b == BroadcastStatusCancelled
+}
+
+// BroadcastTask 广播任务在一个槽内的部分，一个广播按槽拆分为多个任务，由槽的领导节点发送给槽内的用户
+type BroadcastTask struct {
+	JobId          uint64          `json:"job_id,omitempty"`           // 广播ID
+	SlotId         uint32          `json:"slot_id"`                    // 槽ID
+	Status         BroadcastStatus `json:"status,omitempty"`           // 任务状态
+	FromUid        string          `json:"from_uid,omitempty"`         // 发送者uid
+	NoPersist      bool            `json:"no_persist,omitempty"`       // 是否不存储
+	RedDot         bool            `json:"red_dot,omitempty"`          // 是否显示红点
+	Payload        []byte          `json:"payload,omitempty"`          // 消息内容
+	Uids           []string        `json:"uids,omitempty"`             // 指定的用户（只包含此槽内的用户），为空表示发送给槽内的所有用户
+	DeviceFlags    uint8           `json:"device_flags,omitempty"`     // 设备标记的位掩码（1<<deviceFlag），用户有任意一个匹配的设备才发送，0表示不限制
+	UserCreatedGte int64           `json:"user_created_gte,omitempty"` // 用户创建时间大于或等于（unix秒），0表示不限制
+	UserCreatedLt  int64           `json:"user_created_lt,omitempty"`  // 用户创建时间小于（unix秒），0表示不限制
+	Rate           uint32          `json:"rate,omitempty"`             // 每秒最多发送的消息数量
+	Cursor         uint64          `json:"cursor,omitempty"`           // 已处理到的位置（发送给所有用户时为用户主键，指定用户时为已处理的用户数量）
+	SentCount      uint64          `json:"sent_count,omitempty"`       // 已发送的数量
+	FailedCount    uint64          `json:"failed_count,omitempty"`     // 发送失败的数量
+	CreatedAt      *time.Time      `json:"created_at,omitempty"`       // 创建时间
+	UpdatedAt      *time.Time      `json:"updated_at,omitempty"`       // 更新时间
+}
+
+// AttributeType 属性值的类型
+type AttributeType uint8
+
+const (
+	AttributeTypeUnknown AttributeType = iota
+	AttributeTypeString                // 字符串
+	AttributeTypeInt                   // 整数
+	AttributeTypeFloat                 // 浮点数
+	AttributeTypeBool                  // 布尔
+	AttributeTypeJSON                  // 任意json
+)
+
+func (a AttributeType) String() string {
+	switch a {
+	case AttributeTypeString:
+		return "string"
+	case AttributeTypeInt:
+		return "int"
+	case AttributeTypeFloat:
+		return "float"
+	case AttributeTypeBool:
+		return "bool"
+	case AttributeTypeJSON:
+		return "json"
+	}
+	return "unknown"
+}
+
+// ParseAttributeType 解析属性类型，无法识别时返回AttributeTypeUnknown
+func ParseAttributeType(s string) AttributeType {
+	switch s {
+	case "string":
+		return AttributeTypeString
+	case "int":
+		return AttributeTypeInt
+	case "float":
+		return AttributeTypeFloat
+	case "bool":
+		return AttributeTypeBool
+	case "json":
+		return AttributeTypeJSON
+	}
+	return AttributeTypeUnknown
+}
+
+// Attribute 用户或频道的自定义属性
+type Attribute struct {
+	Name      string        // 属性名
+	Type      AttributeType // 属性类型
+	Value     []byte        // 属性值（json编码）
+	Version   uint64        // 版本，每次修改加1
+	UpdatedAt *time.Time    // 更新时间
+}
+
+// UserDataJobType 用户数据任务类型
+type UserDataJobType uint8
+
+const (
+	UserDataJobTypeUnknown UserDataJobType = iota
+	UserDataJobTypeExport                  // 导出用户数据
+	UserDataJobTypeErase                   // 删除用户数据
+)
+
+func (u UserDataJobType) String() string {
+	switch u {
+	case UserDataJobTypeExport:
+		return "export"
+	case UserDataJobTypeErase:
+		return "erase"
+	}
+	return "unknown"
+}
+
+// UserDataJobStatus 用户数据任务状态
+type UserDataJobStatus uint8
+
+const (
+	UserDataJobStatusUnknown UserDataJobStatus = iota
+	UserDataJobStatusRunning                   // 执行中
+	UserDataJobStatusDone                      // 已完成
+	UserDataJobStatusFailed                    // 执行失败
+)
+
+func (u UserDataJobStatus) String() string {
+	switch u {
+	case UserDataJobStatusRunning:
+		return "running"
+	case UserDataJobStatusDone:
+		return "done"
+	case UserDataJobStatusFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Finished 任务是否已结束
+func (u UserDataJobStatus) Finished() bool {
+	return u == UserDataJobStatusDone || u == UserDataJobStatusFailed
+}
+
+// UserDataJob 用户数据的导出或删除任务，任务存放在用户所在的槽内，删除任务的记录同时作为审计记录保留
+type UserDataJob struct {
+	Id                uint64            `json:"id,omitempty"`                 // 任务ID
+	SlotId            uint32            `json:"slot_id"`                      // 用户所在的槽
+	Uid               string            `json:"uid,omitempty"`                // 用户uid
+	Type              UserDataJobType   `json:"type,omitempty"`               // 任务类型
+	Status            UserDataJobStatus `json:"status,omitempty"`             // 任务状态
+	Operator          string            `json:"operator,omitempty"`           // 操作者
+	NodeId            uint64            `json:"node_id,omitempty"`            // 导出文件所在的节点
+	Archive           string            `json:"archive,omitempty"`            // 导出文件名
+	Error             string            `json:"error,omitempty"`              // 失败原因
+	ChannelCount      uint64            `json:"channel_count,omitempty"`      // 处理的频道成员关系数量
+	MessageCount      uint64            `json:"message_count,omitempty"`      // 处理的消息数量
+	ConversationCount uint64            `json:"conversation_count,omitempty"` // 处理的最近会话数量
+	DeviceCount       uint64            `json:"device_count,omitempty"`       // 处理的设备数量
+	ErasedNodes       []uint64          `json:"erased_nodes,omitempty"`       // 已完成消息匿名化的节点
+	CreatedAt         *time.Time        `json:"created_at,omitempty"`         // 创建时间
+	UpdatedAt         *time.Time        `json:"updated_at,omitempty"`         // 更新时间
+}
+
+var EmptyUserDataJob = UserDataJob{}
+
+func IsEmptyUserDataJob(j UserDataJob) bool {
+	return j.Id == 0
+}
+
+// MessageCursor 跨db遍历消息时的位置
+type MessageCursor struct {
+	Shard   uint32   `json:"shard"`   // 当前遍历的db
+	Primary [16]byte `json:"primary"` // 上一条消息的主键
+	Done    bool     `json:"done"`    // 是否已遍历完
+}
+
+// E2EEPrekey 端到端加密的预共享公钥（签名预共享公钥或一次性预共享公钥）
+type E2EEPrekey struct {
+	KeyId     uint32 `json:"key_id"`
+	PublicKey []byte `json:"public_key"`
+	Signature []byte `json:"signature,omitempty"` // 签名预共享公钥的签名（由身份私钥签名）
+}
+
+// E2EEDeviceKeys 设备的端到端加密公钥，私钥只保存在客户端
+type E2EEDeviceKeys struct {
+	Uid          string     `json:"uid"`
+	DeviceId     string     `json:"device_id"`
+	IdentityKey  []byte     `json:"identity_key"`  // 身份公钥
+	SignedPrekey E2EEPrekey `json:"signed_prekey"` // 签名预共享公钥
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+var EmptyE2EEDeviceKeys = E2EEDeviceKeys{}
+
+func IsEmptyE2EEDeviceKeys(k E2EEDeviceKeys) bool {
+	return k.DeviceId == ""
+}
+
 var EmptyConversation = Conversation{}
 
 func IsEmptyConversation(c Conversation) bool {
@@ -277,6 +647,9 @@ const (
 
 	// ConversationTypeCMD 指令
 	ConversationTypeCMD
+
+	// ConversationTypeTopic 社区话题
+	ConversationTypeTopic
 )
 
 // Conversation Conversation
@@ -289,6 +662,9 @@ type Conversation struct {
 	UnreadCount  uint32           `json:"unread_count,omitempty"`      // 未读消息数量（这个可以用户自己设置）
 	ReadToMsgSeq uint64           `json:"readed_to_msg_seq,omitempty"` // 已经读至的消息序号
 
+	MentionMsgSeq uint64 `json:"mention_msg_seq,omitempty"` // 最近一条@我的消息序号（已读至的消息序号超过后清除）
+	MentionAll    bool   `json:"mention_all,omitempty"`     // 最近一条@我的消息是否是@所有人
+
 	CreatedAt *time.Time `json:"created_at,omitempty"` // 创建时间
 	UpdatedAt *time.Time `json:"updated_at,omitempty"` // 更新时间
 }
@@ -316,6 +692,9 @@ func (c *Conversation) Marshal() ([]byte, error) {
 		enc.WriteUint64(0)
 	}
 
+	enc.WriteUint64(c.MentionMsgSeq)
+	enc.WriteUint8(wkutil.BoolToUint8(c.MentionAll))
+
 	return enc.Bytes(), nil
 }
 
@@ -369,6 +748,18 @@ func (c *Conversation) Unmarshal(data []byte) error {
 		c.UpdatedAt = &ct
 	}
 
+	// 兼容旧版本的数据（没有@信息）
+	if dec.Len() > 0 {
+		if c.MentionMsgSeq, err = dec.Uint64(); err != nil {
+			return err
+		}
+		var mentionAll uint8
+		if mentionAll, err = dec.Uint8(); err != nil {
+			return err
+		}
+		c.MentionAll = wkutil.Uint8ToBool(mentionAll)
+	}
+
 	return nil
 }
 
@@ -725,16 +1116,56 @@ type Channel struct {
 	ChannelType uint8  `json:"channel_type,omitempty"`
 }
 
+// MemberRole 频道成员角色
+type MemberRole uint8
+
+const (
+	MemberRoleMember MemberRole = iota // 普通成员
+	MemberRoleAdmin                    // 管理员
+	MemberRoleOwner                    // 群主
+)
+
+func (r MemberRole) String() string {
+	switch r {
+	case MemberRoleMember:
+		return "member"
+	case MemberRoleAdmin:
+		return "admin"
+	case MemberRoleOwner:
+		return "owner"
+	}
+	return fmt.Sprintf("unknown role: %d", r)
+}
+
+// IsManager 是否是管理者（管理员或群主）
+func (r MemberRole) IsManager() bool {
+	return r == MemberRoleAdmin || r == MemberRoleOwner
+}
+
 type Member struct {
-	Id        uint64     `json:"id"`
-	Uid       string     `json:"uid"`
-	CreatedAt *time.Time `json:"created_at,omitempty"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Id           uint64     `json:"id"`
+	Uid          string     `json:"uid"`
+	Role         MemberRole `json:"role,omitempty"`           // 成员角色
+	MuteExpireAt uint64     `json:"mute_expire_at,omitempty"` // 禁言到期时间（unix秒），0表示未禁言
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
 
 	version uint16 // 数据版本
 }
 
+var EmptyMember = Member{}
+
+func IsEmptyMember(m Member) bool {
+	return m.Uid == ""
+}
+
+// IsMuted 成员在指定时间是否处于禁言中
+func (m Member) IsMuted(now time.Time) bool {
+	return m.MuteExpireAt > 0 && uint64(now.Unix()) < m.MuteExpireAt
+}
+
 func (m *Member) Marshal() ([]byte, error) {
+	m.version = 1
 	enc := wkproto.NewEncoder()
 	defer enc.End()
 
@@ -752,6 +1183,8 @@ func (m *Member) Marshal() ([]byte, error) {
 	} else {
 		enc.WriteUint64(0)
 	}
+	enc.WriteUint8(uint8(m.Role))
+	enc.WriteUint64(m.MuteExpireAt)
 	return enc.Bytes(), nil
 }
 
@@ -785,5 +1218,15 @@ func (m *Member) Unmarshal(data []byte) error {
 		ct := time.Unix(int64(updatedAt/1e9), int64(updatedAt%1e9))
 		m.UpdatedAt = &ct
 	}
+	if m.version > 0 {
+		var role uint8
+		if role, err = dec.Uint8(); err != nil {
+			return err
+		}
+		m.Role = MemberRole(role)
+		if m.MuteExpireAt, err = dec.Uint64(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
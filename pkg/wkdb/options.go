@@ -10,6 +10,8 @@ type Options struct {
 	ShardNum     int               // 数据库分区数量，一但设置就不能修改
 	IsCmdChannel func(string) bool // 是否是cmd频道
 	MemTableSize int
+	ReadOnly     bool   // 只读方式打开（离线检查数据时使用）
+	Engine       Engine // 存储引擎，为nil时使用pebble
 }
 
 func NewOptions(opt ...Option) *Options {
@@ -76,3 +78,17 @@ func WithMemTableSize(size int) Option {
 		o.MemTableSize = size
 	}
 }
+
+// WithReadOnly 以只读方式打开数据库
+func WithReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// WithEngine 指定存储引擎（例如单元测试使用NewMemoryEngine()）
+func WithEngine(engine Engine) Option {
+	return func(o *Options) {
+		o.Engine = engine
+	}
+}
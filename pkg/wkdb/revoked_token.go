@@ -0,0 +1,114 @@
+package wkdb
+
+import (
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"go.uber.org/zap"
+)
+
+// 吊销记录与用户存放在同一个db内
+func (wk *wukongDB) AddRevokedToken(t RevokedToken) error {
+	batch := wk.shardDB(t.Uid).NewBatch()
+	defer batch.Close()
+
+	if err := batch.Set(key.NewRevokedTokenColumnKey(t.Uid, t.TokenId, key.TableRevokedToken.Column.TokenId), []byte(t.TokenId), wk.noSync); err != nil {
+		return err
+	}
+	expireAt := make([]byte, 8)
+	wk.endian.PutUint64(expireAt, t.ExpireAt)
+	if err := batch.Set(key.NewRevokedTokenColumnKey(t.Uid, t.TokenId, key.TableRevokedToken.Column.ExpireAt), expireAt, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) ExistRevokedToken(uid string, tokenId string) (bool, error) {
+	db := wk.shardDB(uid)
+
+	// token id可能存在hash冲突，需要比较原始值
+	tokenIdBytes, closer, err := db.Get(key.NewRevokedTokenColumnKey(uid, tokenId, key.TableRevokedToken.Column.TokenId))
+	if err != nil {
+		if err == ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	exist := string(tokenIdBytes) == tokenId
+	closer.Close()
+	if !exist {
+		return false, nil
+	}
+
+	expireAtBytes, closer, err := db.Get(key.NewRevokedTokenColumnKey(uid, tokenId, key.TableRevokedToken.Column.ExpireAt))
+	if err != nil {
+		if err == ErrNotFound {
+			return true, nil
+		}
+		return false, err
+	}
+	defer closer.Close()
+	expireAt := wk.endian.Uint64(expireAtBytes)
+	if expireAt != 0 && expireAt <= uint64(time.Now().Unix()) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// RemoveExpiredRevokedTokens 吊销记录过期后令牌本身也已过期，不再需要保留
+func (wk *wukongDB) RemoveExpiredRevokedTokens(now uint64, limit int) (int, error) {
+	var count int
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewTableRowLowKey(key.TableRevokedToken.Id),
+			UpperBound: key.NewTableRowHighKey(key.TableRevokedToken.Id),
+		})
+		batch := db.NewBatch()
+		for iter.First(); iter.Valid(); iter.Next() {
+			_, _, columnName, err := key.ParseRevokedTokenColumnKey(iter.Key())
+			if err != nil {
+				wk.Warn("parse revoked token column key failed", zap.Error(err))
+				continue
+			}
+			if columnName != key.TableRevokedToken.Column.ExpireAt {
+				continue
+			}
+			value := iter.Value()
+			if len(value) != 8 {
+				continue
+			}
+			expireAt := wk.endian.Uint64(value)
+			if expireAt == 0 || expireAt > now {
+				continue
+			}
+			// 同一条记录的列key只有列名不同
+			tokenIdKey := append([]byte(nil), iter.Key()...)
+			tokenIdKey[len(tokenIdKey)-2] = key.TableRevokedToken.Column.TokenId[0]
+			tokenIdKey[len(tokenIdKey)-1] = key.TableRevokedToken.Column.TokenId[1]
+			if err = batch.Delete(tokenIdKey, wk.noSync); err != nil {
+				iter.Close()
+				batch.Close()
+				return count, err
+			}
+			if err = batch.Delete(append([]byte(nil), iter.Key()...), wk.noSync); err != nil {
+				iter.Close()
+				batch.Close()
+				return count, err
+			}
+			count++
+			if limit > 0 && count >= limit {
+				break
+			}
+		}
+		iter.Close()
+		err := batch.Commit(wk.sync)
+		batch.Close()
+		if err != nil {
+			return count, err
+		}
+		if limit > 0 && count >= limit {
+			break
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,72 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAndExistRevokedToken(t *testing.T) {
+
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	now := uint64(time.Now().Unix())
+
+	err = d.AddRevokedToken(wkdb.RevokedToken{Uid: "u1", TokenId: "jti1", ExpireAt: now + 3600})
+	assert.NoError(t, err)
+	err = d.AddRevokedToken(wkdb.RevokedToken{Uid: "u1", TokenId: "jti2", ExpireAt: now - 1})
+	assert.NoError(t, err)
+
+	exist, err := d.ExistRevokedToken("u1", "jti1")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+
+	// 吊销记录已过期
+	exist, err = d.ExistRevokedToken("u1", "jti2")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+
+	exist, err = d.ExistRevokedToken("u2", "jti1")
+	assert.NoError(t, err)
+	assert.False(t, exist)
+}
+
+func TestRemoveExpiredRevokedTokens(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	err = d.AddRevokedToken(wkdb.RevokedToken{Uid: "u1", TokenId: "jti1", ExpireAt: 100})
+	assert.NoError(t, err)
+	err = d.AddRevokedToken(wkdb.RevokedToken{Uid: "u1", TokenId: "jti2", ExpireAt: 200})
+	assert.NoError(t, err)
+	err = d.AddRevokedToken(wkdb.RevokedToken{Uid: "u2", TokenId: "jti3"})
+	assert.NoError(t, err)
+
+	count, err := d.RemoveExpiredRevokedTokens(150, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = d.RemoveExpiredRevokedTokens(150, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// 永久吊销的记录不会被移除
+	exist, err := d.ExistRevokedToken("u2", "jti3")
+	assert.NoError(t, err)
+	assert.True(t, exist)
+}
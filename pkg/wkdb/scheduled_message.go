@@ -0,0 +1,306 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"go.uber.org/zap"
+)
+
+// 定时消息头的标记位
+const (
+	scheduledHeaderNoPersist uint8 = 1 << iota
+	scheduledHeaderRedDot
+	scheduledHeaderSyncOnce
+)
+
+// 定时消息数量不多，统一存放在默认的db内，通过槽和频道两个二级索引按发送时间查询
+func (wk *wukongDB) AddOrUpdateScheduledMessage(m ScheduledMessage) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("AddOrUpdateScheduledMessage done", zap.Duration("cost", cost), zap.Uint64("id", m.Id), zap.String("channelId", m.ChannelId))
+			}
+		}()
+	}
+
+	wk.dblock.scheduledMessageLock.lock(m.Id)
+	defer wk.dblock.scheduledMessageLock.unlock(m.Id)
+
+	db := wk.defaultShardDB()
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	old, err := wk.GetScheduledMessage(m.Id)
+	if err != nil {
+		return err
+	}
+	if !IsEmptyScheduledMessage(old) {
+		// 发送时间或频道可能变了，先删除旧的索引
+		if err = wk.deleteScheduledMessageIndex(old, batch); err != nil {
+			return err
+		}
+		if m.CreatedAt == nil {
+			m.CreatedAt = old.CreatedAt
+		}
+	}
+
+	if err = wk.writeScheduledMessage(m, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetScheduledMessage(id uint64) (ScheduledMessage, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: key.NewScheduledMessageColumnKey(id, key.MinColumnKey),
+		UpperBound: key.NewScheduledMessageColumnKey(id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	m := EmptyScheduledMessage
+	err := wk.iterScheduledMessage(iter, func(sm ScheduledMessage) bool {
+		m = sm
+		return false
+	})
+	if err != nil {
+		return EmptyScheduledMessage, err
+	}
+	return m, nil
+}
+
+func (wk *wukongDB) GetScheduledMessagesOfChannel(channelId string, channelType uint8) ([]ScheduledMessage, error) {
+	channelHash := key.ChannelIdToNum(channelId, channelType)
+	return wk.getScheduledMessagesByIndex(
+		key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.ChannelSendAt, channelHash, 0, 0),
+		key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.ChannelSendAt, channelHash, math.MaxUint64, math.MaxUint64),
+		0,
+	)
+}
+
+func (wk *wukongDB) GetDueScheduledMessages(slotId uint32, sendAt int64, limit int) ([]ScheduledMessage, error) {
+	if sendAt < 0 {
+		return nil, nil
+	}
+	return wk.getScheduledMessagesByIndex(
+		key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.SlotSendAt, uint64(slotId), 0, 0),
+		key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.SlotSendAt, uint64(slotId), uint64(sendAt), math.MaxUint64),
+		limit,
+	)
+}
+
+func (wk *wukongDB) DeleteScheduledMessage(id uint64) error {
+	wk.dblock.scheduledMessageLock.lock(id)
+	defer wk.dblock.scheduledMessageLock.unlock(id)
+
+	m, err := wk.GetScheduledMessage(id)
+	if err != nil {
+		return err
+	}
+	if IsEmptyScheduledMessage(m) {
+		return nil
+	}
+
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	if err = wk.deleteScheduledMessageIndex(m, batch); err != nil {
+		return err
+	}
+	if err = batch.DeleteRange(key.NewScheduledMessageColumnKey(id, key.MinColumnKey), key.NewScheduledMessageColumnKey(id, key.MaxColumnKey), wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+// getScheduledMessagesByIndex 按二级索引的范围[low, high]查询定时消息
+func (wk *wukongDB) getScheduledMessagesByIndex(low, high []byte, limit int) ([]ScheduledMessage, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: low,
+		UpperBound: high,
+	})
+	defer iter.Close()
+
+	var messages []ScheduledMessage
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, id, err := key.ParseScheduledMessageSecondIndexKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		m, err := wk.GetScheduledMessage(id)
+		if err != nil {
+			return nil, err
+		}
+		if IsEmptyScheduledMessage(m) {
+			wk.Warn("scheduled message not found by index", zap.Uint64("id", id))
+			continue
+		}
+		messages = append(messages, m)
+		if limit > 0 && len(messages) >= limit {
+			break
+		}
+	}
+	return messages, nil
+}
+
+func (wk *wukongDB) writeScheduledMessage(m ScheduledMessage, w Writer) error {
+	var err error
+
+	// channelId
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.ChannelId), []byte(m.ChannelId), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelType
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.ChannelType), []byte{m.ChannelType}, wk.noSync); err != nil {
+		return err
+	}
+
+	// fromUid
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.FromUid), []byte(m.FromUid), wk.noSync); err != nil {
+		return err
+	}
+
+	// clientMsgNo
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.ClientMsgNo), []byte(m.ClientMsgNo), wk.noSync); err != nil {
+		return err
+	}
+
+	// header
+	var header uint8
+	if m.NoPersist {
+		header |= scheduledHeaderNoPersist
+	}
+	if m.RedDot {
+		header |= scheduledHeaderRedDot
+	}
+	if m.SyncOnce {
+		header |= scheduledHeaderSyncOnce
+	}
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.Header), []byte{header}, wk.noSync); err != nil {
+		return err
+	}
+
+	// expire
+	expire := make([]byte, 4)
+	wk.endian.PutUint32(expire, m.Expire)
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.Expire), expire, wk.noSync); err != nil {
+		return err
+	}
+
+	// payload
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.Payload), m.Payload, wk.noSync); err != nil {
+		return err
+	}
+
+	// sendAt
+	sendAt := make([]byte, 8)
+	wk.endian.PutUint64(sendAt, uint64(m.SendAt))
+	if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.SendAt), sendAt, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if m.CreatedAt != nil {
+		createdAt := make([]byte, 8)
+		wk.endian.PutUint64(createdAt, uint64(m.CreatedAt.UnixNano()))
+		if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.CreatedAt), createdAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// updatedAt
+	if m.UpdatedAt != nil {
+		updatedAt := make([]byte, 8)
+		wk.endian.PutUint64(updatedAt, uint64(m.UpdatedAt.UnixNano()))
+		if err = w.Set(key.NewScheduledMessageColumnKey(m.Id, key.TableScheduledMessage.Column.UpdatedAt), updatedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// slot index
+	if err = w.Set(key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.SlotSendAt, uint64(wk.channelSlotId(m.ChannelId)), uint64(m.SendAt), m.Id), nil, wk.noSync); err != nil {
+		return err
+	}
+
+	// channel index
+	if err = w.Set(key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.ChannelSendAt, key.ChannelIdToNum(m.ChannelId, m.ChannelType), uint64(m.SendAt), m.Id), nil, wk.noSync); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (wk *wukongDB) deleteScheduledMessageIndex(m ScheduledMessage, w Writer) error {
+	if err := w.Delete(key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.SlotSendAt, uint64(wk.channelSlotId(m.ChannelId)), uint64(m.SendAt), m.Id), wk.noSync); err != nil {
+		return err
+	}
+	return w.Delete(key.NewScheduledMessageSecondIndexKey(key.TableScheduledMessage.SecondIndex.ChannelSendAt, key.ChannelIdToNum(m.ChannelId, m.ChannelType), uint64(m.SendAt), m.Id), wk.noSync)
+}
+
+func (wk *wukongDB) iterScheduledMessage(iter Iterator, iterFnc func(m ScheduledMessage) bool) error {
+	var (
+		preId          uint64
+		preMessage     ScheduledMessage
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		id, columnName, err := key.ParseScheduledMessageColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if id != preId {
+			if hasData {
+				if !iterFnc(preMessage) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = id
+			preMessage = ScheduledMessage{Id: id}
+		}
+
+		switch columnName {
+		case key.TableScheduledMessage.Column.ChannelId:
+			preMessage.ChannelId = string(iter.Value())
+		case key.TableScheduledMessage.Column.ChannelType:
+			preMessage.ChannelType = iter.Value()[0]
+		case key.TableScheduledMessage.Column.FromUid:
+			preMessage.FromUid = string(iter.Value())
+		case key.TableScheduledMessage.Column.ClientMsgNo:
+			preMessage.ClientMsgNo = string(iter.Value())
+		case key.TableScheduledMessage.Column.Header:
+			header := iter.Value()[0]
+			preMessage.NoPersist = header&scheduledHeaderNoPersist != 0
+			preMessage.RedDot = header&scheduledHeaderRedDot != 0
+			preMessage.SyncOnce = header&scheduledHeaderSyncOnce != 0
+		case key.TableScheduledMessage.Column.Expire:
+			preMessage.Expire = wk.endian.Uint32(iter.Value())
+		case key.TableScheduledMessage.Column.Payload:
+			// 迭代器的值在移动后失效，需要拷贝
+			preMessage.Payload = append([]byte(nil), iter.Value()...)
+		case key.TableScheduledMessage.Column.SendAt:
+			preMessage.SendAt = int64(wk.endian.Uint64(iter.Value()))
+		case key.TableScheduledMessage.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preMessage.CreatedAt = &t
+			}
+		case key.TableScheduledMessage.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				t := time.Unix(tm/1e9, tm%1e9)
+				preMessage.UpdatedAt = &t
+			}
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preMessage)
+	}
+	return nil
+}
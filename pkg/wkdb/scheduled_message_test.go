@@ -0,0 +1,105 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledMessage(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	m1 := wkdb.ScheduledMessage{
+		Id:          1,
+		ChannelId:   "g1",
+		ChannelType: 2,
+		FromUid:     "u1",
+		ClientMsgNo: "no1",
+		RedDot:      true,
+		Expire:      60,
+		Payload:     []byte("hello"),
+		SendAt:      200,
+		CreatedAt:   &createdAt,
+		UpdatedAt:   &createdAt,
+	}
+	err = d.AddOrUpdateScheduledMessage(m1)
+	assert.NoError(t, err)
+
+	err = d.AddOrUpdateScheduledMessage(wkdb.ScheduledMessage{
+		Id:          2,
+		ChannelId:   "g1",
+		ChannelType: 2,
+		FromUid:     "u2",
+		SyncOnce:    true,
+		Payload:     []byte("world"),
+		SendAt:      100,
+	})
+	assert.NoError(t, err)
+
+	m, err := d.GetScheduledMessage(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "g1", m.ChannelId)
+	assert.Equal(t, uint8(2), m.ChannelType)
+	assert.Equal(t, "u1", m.FromUid)
+	assert.Equal(t, "no1", m.ClientMsgNo)
+	assert.True(t, m.RedDot)
+	assert.False(t, m.NoPersist)
+	assert.False(t, m.SyncOnce)
+	assert.Equal(t, uint32(60), m.Expire)
+	assert.Equal(t, []byte("hello"), m.Payload)
+	assert.Equal(t, int64(200), m.SendAt)
+	assert.Equal(t, createdAt.Unix(), m.CreatedAt.Unix())
+
+	// 按发送时间排序
+	messages, err := d.GetScheduledMessagesOfChannel("g1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, uint64(2), messages[0].Id)
+	assert.Equal(t, uint64(1), messages[1].Id)
+
+	slotId := wkutil.GetSlotNum(int(wkdb.NewOptions().SlotCount), "g1")
+	messages, err = d.GetDueScheduledMessages(slotId, 150, 0)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(2), messages[0].Id)
+
+	// 重新设置发送时间，保留创建时间
+	m1.SendAt = 50
+	m1.CreatedAt = nil
+	err = d.AddOrUpdateScheduledMessage(m1)
+	assert.NoError(t, err)
+
+	messages, err = d.GetDueScheduledMessages(slotId, 150, 1)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(1), messages[0].Id)
+	assert.Equal(t, createdAt.Unix(), messages[0].CreatedAt.Unix())
+
+	messages, err = d.GetDueScheduledMessages(slotId, 150, 0)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	// 删除
+	err = d.DeleteScheduledMessage(1)
+	assert.NoError(t, err)
+
+	m, err = d.GetScheduledMessage(1)
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyScheduledMessage(m))
+
+	messages, err = d.GetScheduledMessagesOfChannel("g1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uint64(2), messages[0].Id)
+}
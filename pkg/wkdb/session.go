@@ -33,7 +33,7 @@ package wkdb
 
 // func (wk *wukongDB) GetSession(uid string, id uint64) (Session, error) {
 
-// 	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+// 	iter := wk.shardDB(uid).NewIter(&IterOptions{
 // 		LowerBound: key.NewSessionColumnKey(uid, id, key.MinColumnKey),
 // 		UpperBound: key.NewSessionColumnKey(uid, id, key.MaxColumnKey),
 // 	})
@@ -70,7 +70,7 @@ package wkdb
 // 	currentSize := 0
 // 	var err error
 // 	for _, db := range wk.dbs {
-// 		iter := db.NewIter(&pebble.IterOptions{
+// 		iter := db.NewIter(&IterOptions{
 // 			LowerBound: key.NewSessionUidHashKey(0),
 // 			UpperBound: key.NewSessionUidHashKey(math.MaxUint64),
 // 		})
@@ -106,7 +106,7 @@ package wkdb
 // 	return batch.Commit(wk.sync)
 // }
 
-// func (wk *wukongDB) deleteSession(uid string, id uint64, w pebble.Writer) error {
+// func (wk *wukongDB) deleteSession(uid string, id uint64, w Writer) error {
 // 	session, err := wk.GetSession(uid, id)
 // 	if err != nil {
 // 		return err
@@ -114,7 +114,7 @@ package wkdb
 // 	return wk.deleteSessionBySession(session, w)
 // }
 
-// func (wk *wukongDB) deleteSessionBySession(session Session, w pebble.Writer) error {
+// func (wk *wukongDB) deleteSessionBySession(session Session, w Writer) error {
 // 	if IsEmptySession(session) {
 // 		return nil
 // 	}
@@ -195,7 +195,7 @@ package wkdb
 
 // func (wk *wukongDB) GetSessions(uid string) ([]Session, error) {
 
-// 	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+// 	iter := wk.shardDB(uid).NewIter(&IterOptions{
 // 		LowerBound: key.NewSessionColumnKey(uid, 0, key.MinColumnKey),
 // 		UpperBound: key.NewSessionColumnKey(uid, math.MaxUint64, key.MaxColumnKey),
 // 	})
@@ -234,7 +234,7 @@ package wkdb
 // func (wk *wukongDB) getSessionIdsByType(uid string, sessionType SessionType) ([]uint64, error) {
 // 	db := wk.shardDB(uid)
 
-// 	iter := db.NewIter(&pebble.IterOptions{
+// 	iter := db.NewIter(&IterOptions{
 // 		LowerBound: key.NewSessionSecondIndexKey(uid, key.TableSession.SecondIndex.SessionType, uint64(sessionType), 0),
 // 		UpperBound: key.NewSessionSecondIndexKey(uid, key.TableSession.SecondIndex.SessionType, uint64(sessionType), math.MaxUint64),
 // 	})
@@ -310,7 +310,7 @@ package wkdb
 // 	return nil
 // }
 
-// func (wk *wukongDB) updateSessionUpdatedAt(uids map[string]uint64, channelId string, channelType uint8, w pebble.Writer) error {
+// func (wk *wukongDB) updateSessionUpdatedAt(uids map[string]uint64, channelId string, channelType uint8, w Writer) error {
 // 	nw := time.Now()
 // 	updatedAtBytes := make([]byte, 8)
 // 	wk.endian.PutUint64(updatedAtBytes, uint64(nw.UnixNano()))
@@ -426,7 +426,7 @@ package wkdb
 
 // func (wk *wukongDB) getLastSessionIdsOrderByUpdatedAt(uid string, updatedAt uint64, limit int) ([]uint64, error) {
 
-// 	iter := wk.shardDB(uid).NewIter(&pebble.IterOptions{
+// 	iter := wk.shardDB(uid).NewIter(&IterOptions{
 // 		LowerBound: key.NewSessionSecondIndexKey(uid, key.TableSession.SecondIndex.UpdatedAt, updatedAt, 0),
 // 		UpperBound: key.NewSessionSecondIndexKey(uid, key.TableSession.SecondIndex.UpdatedAt, math.MaxUint64, 0),
 // 	})
@@ -454,7 +454,7 @@ package wkdb
 
 // 	result, closer, err := wk.shardDB(uid).Get(key.NewSessionChannelIndexKey(uid, channelId, channelType))
 // 	if err != nil {
-// 		if err == pebble.ErrNotFound {
+// 		if err == ErrNotFound {
 // 			return 0, nil
 // 		}
 // 		return 0, err
@@ -468,7 +468,7 @@ package wkdb
 // 	return 0, nil
 // }
 
-// func (wk *wukongDB) iteratorSession(iter *pebble.Iterator, iterFnc func(s Session) bool) error {
+// func (wk *wukongDB) iteratorSession(iter Iterator, iterFnc func(s Session) bool) error {
 
 // 	var (
 // 		preId      uint64
@@ -518,7 +518,7 @@ package wkdb
 // 	return nil
 // }
 
-// func (wk *wukongDB) writeSession(id uint64, session Session, isCreate bool, w pebble.Writer) error {
+// func (wk *wukongDB) writeSession(id uint64, session Session, isCreate bool, w Writer) error {
 // 	var (
 // 		err error
 // 	)
@@ -569,7 +569,7 @@ package wkdb
 // 	return nil
 // }
 
-// func (wk *wukongDB) writeSessionIndex(id uint64, session Session, w pebble.Writer) error {
+// func (wk *wukongDB) writeSessionIndex(id uint64, session Session, w Writer) error {
 
 // 	idBytes := make([]byte, 8)
 // 	wk.endian.PutUint64(idBytes, id)
@@ -605,7 +605,7 @@ package wkdb
 // 	return nil
 // }
 
-// func (wk *wukongDB) writeSessionUpdatedAtIndex(uid string, id uint64, updatedAt time.Time, w pebble.Writer) error {
+// func (wk *wukongDB) writeSessionUpdatedAtIndex(uid string, id uint64, updatedAt time.Time, w Writer) error {
 // 	fmt.Println("writeSessionUpdatedAtIndex---->", uid, id, updatedAt.UnixNano())
 // 	if err := w.Set(key.NewSessionSecondIndexKey(uid, key.TableSession.SecondIndex.UpdatedAt, uint64(updatedAt.UnixNano()), id), nil, wk.noSync); err != nil {
 // 		return err
@@ -613,7 +613,7 @@ package wkdb
 // 	return nil
 // }
 
-// func (wk *wukongDB) deleteSessionIndex(id uint64, session Session, w pebble.Writer) error {
+// func (wk *wukongDB) deleteSessionIndex(id uint64, session Session, w Writer) error {
 
 // 	if err := w.Delete(key.NewSessionChannelIndexKey(session.Uid, session.ChannelId, session.ChannelType), wk.noSync); err != nil {
 // 		return err
@@ -625,7 +625,7 @@ package wkdb
 // 	return nil
 // }
 
-// func (wk *wukongDB) deleteSessionTimeIndex(id uint64, session Session, w pebble.Writer) error {
+// func (wk *wukongDB) deleteSessionTimeIndex(id uint64, session Session, w Writer) error {
 
 // 	// if err := w.Delete(key.NewSessionSecondIndexKey(session.Uid, key.TableSession.SecondIndex.CreatedAt, uint64(session.CreatedAt.UnixNano()), id), wk.noSync); err != nil {
 // 	// 	return err
@@ -637,7 +637,7 @@ package wkdb
 // 	return wk.deleteSessionUpdatedAtIndex(session.Uid, id, w)
 // }
 
-// func (wk *wukongDB) deleteSessionUpdatedAtIndex(uid string, id uint64, w pebble.Writer) error {
+// func (wk *wukongDB) deleteSessionUpdatedAtIndex(uid string, id uint64, w Writer) error {
 // 	oldSession, err := wk.GetSession(uid, id)
 // 	if err != nil && err != ErrSessionNotExist {
 // 		return err
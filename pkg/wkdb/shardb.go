@@ -1,14 +1,14 @@
 package wkdb
 
 // type shardb struct {
-// 	*pebble.DB
+// 	KV
 // 	stopper        *syncutil.Stopper
 // 	appendMessageC chan AppendMessagesReq
 
 // 	wkdb DB
 // }
 
-// func newShardb(db *pebble.DB, wkdb DB) *shardb {
+// func newShardb(db KV, wkdb DB) *shardb {
 // 	return &shardb{
 // 		DB:             db,
 // 		appendMessageC: make(chan AppendMessagesReq, 1024),
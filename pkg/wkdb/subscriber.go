@@ -8,7 +8,6 @@ import (
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
 	"github.com/WuKongIM/WuKongIM/pkg/wkutil"
-	"github.com/cockroachdb/pebble"
 	"go.uber.org/zap"
 )
 
@@ -44,7 +43,7 @@ func (wk *wukongDB) AddSubscribers(channelId string, channelType uint8, subscrib
 
 func (wk *wukongDB) GetSubscribers(channelId string, channelType uint8) ([]Member, error) {
 
-	iter := wk.channelDb(channelId, channelType).NewIter(&pebble.IterOptions{
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
 		LowerBound: key.NewSubscriberColumnKey(channelId, channelType, 0, key.MinColumnKey),
 		UpperBound: key.NewSubscriberColumnKey(channelId, channelType, math.MaxUint64, key.MaxColumnKey),
 	})
@@ -73,7 +72,7 @@ func (wk *wukongDB) RemoveSubscribers(channelId string, channelType uint8, subsc
 	// 通过uids获取订阅者对象
 	members, err := wk.getSubscribersByUids(channelId, channelType, subscribers)
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return nil
 		}
 		return err
@@ -101,7 +100,7 @@ func (wk *wukongDB) ExistSubscriber(channelId string, channelType uint8, uid str
 		defer closer.Close()
 	}
 	if err != nil {
-		if err == pebble.ErrNotFound {
+		if err == ErrNotFound {
 			return false, nil
 		}
 		return false, err
@@ -109,6 +108,57 @@ func (wk *wukongDB) ExistSubscriber(channelId string, channelType uint8, uid str
 	return true, nil
 }
 
+func (wk *wukongDB) GetSubscriber(channelId string, channelType uint8, uid string) (Member, error) {
+	id := key.HashWithString(uid)
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
+		LowerBound: key.NewSubscriberColumnKey(channelId, channelType, id, key.MinColumnKey),
+		UpperBound: key.NewSubscriberColumnKey(channelId, channelType, id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	member := EmptyMember
+	err := wk.iterateSubscriber(iter, func(m Member) bool {
+		member = m
+		return false
+	})
+	if err != nil {
+		return EmptyMember, err
+	}
+	return member, nil
+}
+
+func (wk *wukongDB) UpdateSubscribersRole(channelId string, channelType uint8, uids []string, role MemberRole) error {
+	return wk.updateSubscribersColumn(channelId, channelType, uids, key.TableSubscriber.Column.Role, []byte{uint8(role)})
+}
+
+func (wk *wukongDB) UpdateSubscribersMute(channelId string, channelType uint8, uids []string, muteExpireAt uint64) error {
+	value := make([]byte, 8)
+	wk.endian.PutUint64(value, muteExpireAt)
+	return wk.updateSubscribersColumn(channelId, channelType, uids, key.TableSubscriber.Column.MuteExpireAt, value)
+}
+
+// 更新订阅者的某一列，只更新已存在的订阅者
+func (wk *wukongDB) updateSubscribersColumn(channelId string, channelType uint8, uids []string, columnName [2]byte, value []byte) error {
+	db := wk.channelDb(channelId, channelType)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	uids = wkutil.RemoveRepeatedElement(uids)
+	for _, uid := range uids {
+		exist, err := wk.ExistSubscriber(channelId, channelType, uid)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			continue
+		}
+		if err = batch.Set(key.NewSubscriberColumnKey(channelId, channelType, key.HashWithString(uid), columnName), value, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return batch.Commit(wk.sync)
+}
+
 func (wk *wukongDB) RemoveAllSubscriber(channelId string, channelType uint8) error {
 
 	if wk.opts.EnableCost {
@@ -155,7 +205,7 @@ func (wk *wukongDB) RemoveAllSubscriber(channelId string, channelType uint8) err
 	return batch.Commit(wk.sync)
 }
 
-func (wk *wukongDB) removeSubscriber(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) removeSubscriber(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -178,7 +228,7 @@ func (wk *wukongDB) getSubscribersByUids(channelId string, channelType uint8, ui
 	for _, uid := range uids {
 		id := key.HashWithString(uid)
 
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewSubscriberColumnKey(channelId, channelType, id, key.MinColumnKey),
 			UpperBound: key.NewSubscriberColumnKey(channelId, channelType, id, key.MaxColumnKey),
 		})
@@ -197,14 +247,14 @@ func (wk *wukongDB) getSubscribersByUids(channelId string, channelType uint8, ui
 }
 
 // 增加频道白名单数量
-func (wk *wukongDB) incChannelInfoSubscriberCount(id uint64, count int, batch *pebble.Batch) error {
+func (wk *wukongDB) incChannelInfoSubscriberCount(id uint64, count int, batch Batch) error {
 	wk.dblock.subscriberCountLock.lock(id)
 	defer wk.dblock.subscriberCountLock.unlock(id)
 
 	return wk.incChannelInfoColumnCount(id, key.TableChannelInfo.Column.SubscriberCount, key.TableChannelInfo.SecondIndex.SubscriberCount, count, batch)
 }
 
-func (wk *wukongDB) iterateSubscriber(iter *pebble.Iterator, iterFnc func(member Member) bool) error {
+func (wk *wukongDB) iterateSubscriber(iter Iterator, iterFnc func(member Member) bool) error {
 
 	var (
 		preId          uint64
@@ -235,6 +285,10 @@ func (wk *wukongDB) iterateSubscriber(iter *pebble.Iterator, iterFnc func(member
 		switch columnName {
 		case key.TableSubscriber.Column.Uid:
 			preMember.Uid = string(iter.Value())
+		case key.TableSubscriber.Column.Role:
+			preMember.Role = MemberRole(iter.Value()[0])
+		case key.TableSubscriber.Column.MuteExpireAt:
+			preMember.MuteExpireAt = wk.endian.Uint64(iter.Value())
 		case key.TableSubscriber.Column.CreatedAt:
 			tm := int64(wk.endian.Uint64(iter.Value()))
 			if tm > 0 {
@@ -257,7 +311,7 @@ func (wk *wukongDB) iterateSubscriber(iter *pebble.Iterator, iterFnc func(member
 	return nil
 }
 
-func (wk *wukongDB) writeSubscriber(channelId string, channelType uint8, member Member, w pebble.Writer) error {
+func (wk *wukongDB) writeSubscriber(channelId string, channelType uint8, member Member, w Writer) error {
 	var (
 		err error
 	)
@@ -276,6 +330,18 @@ func (wk *wukongDB) writeSubscriber(channelId string, channelType uint8, member
 		return err
 	}
 
+	// role
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, member.Id, key.TableSubscriber.Column.Role), []byte{uint8(member.Role)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// muteExpireAt
+	muteExpireAt := make([]byte, 8)
+	wk.endian.PutUint64(muteExpireAt, member.MuteExpireAt)
+	if err = w.Set(key.NewSubscriberColumnKey(channelId, channelType, member.Id, key.TableSubscriber.Column.MuteExpireAt), muteExpireAt, wk.noSync); err != nil {
+		return err
+	}
+
 	// createdAt
 	if member.CreatedAt != nil {
 		ct := uint64(member.CreatedAt.UnixNano())
@@ -309,7 +375,7 @@ func (wk *wukongDB) writeSubscriber(channelId string, channelType uint8, member
 	return nil
 }
 
-func (wk *wukongDB) deleteAllSubscriberIndex(channelId string, channelType uint8, w pebble.Writer) error {
+func (wk *wukongDB) deleteAllSubscriberIndex(channelId string, channelType uint8, w Writer) error {
 
 	var err error
 	// uid index
@@ -330,7 +396,7 @@ func (wk *wukongDB) deleteAllSubscriberIndex(channelId string, channelType uint8
 	return nil
 }
 
-func (wk *wukongDB) deleteSubscriberIndex(channelId string, channelType uint8, oldMember Member, w pebble.Writer) error {
+func (wk *wukongDB) deleteSubscriberIndex(channelId string, channelType uint8, oldMember Member, w Writer) error {
 	var err error
 	// uid index
 	if err = w.Delete(key.NewSubscriberIndexKey(channelId, channelType, key.TableSubscriber.Index.Uid, key.HashWithString(oldMember.Uid)), wk.noSync); err != nil {
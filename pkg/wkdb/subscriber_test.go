@@ -234,3 +234,57 @@ func TestRemoveAllSubscriber(t *testing.T) {
 
 	assert.Equal(t, 0, len(subscribers2))
 }
+
+func TestUpdateSubscribersRoleAndMute(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "channel1"
+	channelType := uint8(2)
+	err = d.AddSubscribers(channelId, channelType, []wkdb.Member{
+		{
+			Uid:  "uid1",
+			Role: wkdb.MemberRoleOwner,
+		},
+		{
+			Uid: "uid2",
+		},
+	})
+	assert.NoError(t, err)
+
+	member, err := d.GetSubscriber(channelId, channelType, "uid1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.MemberRoleOwner, member.Role)
+
+	err = d.UpdateSubscribersRole(channelId, channelType, []string{"uid2", "notexist"}, wkdb.MemberRoleAdmin)
+	assert.NoError(t, err)
+
+	muteExpireAt := uint64(time.Now().Add(time.Hour).Unix())
+	err = d.UpdateSubscribersMute(channelId, channelType, []string{"uid2"}, muteExpireAt)
+	assert.NoError(t, err)
+
+	member, err = d.GetSubscriber(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.Equal(t, "uid2", member.Uid)
+	assert.Equal(t, wkdb.MemberRoleAdmin, member.Role)
+	assert.Equal(t, muteExpireAt, member.MuteExpireAt)
+	assert.True(t, member.IsMuted(time.Now()))
+
+	// 不存在的订阅者不会被创建
+	member, err = d.GetSubscriber(channelId, channelType, "notexist")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyMember(member))
+
+	// 解除禁言
+	err = d.UpdateSubscribersMute(channelId, channelType, []string{"uid2"}, 0)
+	assert.NoError(t, err)
+	member, err = d.GetSubscriber(channelId, channelType, "uid2")
+	assert.NoError(t, err)
+	assert.False(t, member.IsMuted(time.Now()))
+}
@@ -4,7 +4,6 @@ import (
 	"math"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 )
 
 func (wk *wukongDB) AddSystemUids(uids []string) error {
@@ -32,7 +31,7 @@ func (wk *wukongDB) RemoveSystemUids(uids []string) error {
 }
 
 func (wk *wukongDB) GetSystemUids() ([]string, error) {
-	iter := wk.defaultShardDB().NewIter(&pebble.IterOptions{
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
 		LowerBound: key.NewSystemUidColumnKey(0, key.TableSystemUid.Column.Uid),
 		UpperBound: key.NewSystemUidColumnKey(math.MaxUint64, key.TableSystemUid.Column.Uid),
 	})
@@ -40,15 +39,15 @@ func (wk *wukongDB) GetSystemUids() ([]string, error) {
 	return wk.parseSystemUid(iter)
 }
 
-func (wk *wukongDB) writeSystemUid(id uint64, uid string, w *pebble.Batch) error {
+func (wk *wukongDB) writeSystemUid(id uint64, uid string, w Batch) error {
 	return w.Set(key.NewSystemUidColumnKey(id, key.TableSystemUid.Column.Uid), []byte(uid), wk.noSync)
 }
 
-func (wk *wukongDB) removeSystemUid(id uint64, w *pebble.Batch) error {
+func (wk *wukongDB) removeSystemUid(id uint64, w Batch) error {
 	return w.Delete(key.NewSystemUidColumnKey(id, key.TableSystemUid.Column.Uid), wk.noSync)
 }
 
-func (wk *wukongDB) parseSystemUid(iter *pebble.Iterator) ([]string, error) {
+func (wk *wukongDB) parseSystemUid(iter Iterator) ([]string, error) {
 	var uids []string
 	for iter.First(); iter.Valid(); iter.Next() {
 		uids = append(uids, string(iter.Value()))
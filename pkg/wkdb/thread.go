@@ -0,0 +1,336 @@
+package wkdb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+)
+
+// 子区的数据与父频道存放在同一个db内（子区频道的消息也存放在父频道的db内），在子区频道的消息写入时一起更新
+
+func (wk *wukongDB) GetThread(channelId string, channelType uint8, rootMessageSeq uint64) (Thread, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
+		LowerBound: key.NewThreadColumnKey(channelId, channelType, rootMessageSeq, key.MinColumnKey),
+		UpperBound: key.NewThreadColumnKey(channelId, channelType, rootMessageSeq, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	thread := EmptyThread
+	err := wk.iterThread(iter, func(t Thread) bool {
+		thread = t
+		return false
+	})
+	if err != nil {
+		return EmptyThread, err
+	}
+	if IsEmptyThread(thread) {
+		return EmptyThread, nil
+	}
+	thread.ChannelId = channelId
+	thread.ChannelType = channelType
+	return thread, nil
+}
+
+func (wk *wukongDB) GetThreads(channelId string, channelType uint8, limit int) ([]Thread, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
+		LowerBound: key.NewThreadLowKey(channelId, channelType),
+		UpperBound: key.NewThreadHighKey(channelId, channelType),
+	})
+	defer iter.Close()
+
+	var threads []Thread
+	err := wk.iterThread(iter, func(t Thread) bool {
+		t.ChannelId = channelId
+		t.ChannelType = channelType
+		threads = append(threads, t)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		return threadLastReplyAt(threads[i]) > threadLastReplyAt(threads[j])
+	})
+	if limit > 0 && len(threads) > limit {
+		threads = threads[:limit]
+	}
+	return threads, nil
+}
+
+func (wk *wukongDB) GetMessageExtras(channelId string, channelType uint8, startMessageSeq, endMessageSeq uint64) ([]MessageExtra, error) {
+	iter := wk.channelDb(channelId, channelType).NewIter(&IterOptions{
+		LowerBound: key.NewMessageExtraColumnKey(channelId, channelType, startMessageSeq, key.MinColumnKey),
+		UpperBound: key.NewMessageExtraColumnKey(channelId, channelType, endMessageSeq, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var (
+		extras []MessageExtra
+		extra  MessageExtra
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		messageSeq, columnName, err := key.ParseMessageExtraColumnKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		if messageSeq != extra.MessageSeq {
+			if extra.MessageSeq != 0 {
+				extras = append(extras, extra)
+			}
+			extra = MessageExtra{MessageSeq: messageSeq}
+		}
+		switch columnName {
+		case key.TableMessageExtra.Column.ThreadReplyCount:
+			extra.ThreadReplyCount = wk.endian.Uint64(iter.Value())
+		case key.TableMessageExtra.Column.ThreadLastReplyAt:
+			extra.ThreadLastReplyAt = wk.parseTime(iter.Value())
+		}
+	}
+	if extra.MessageSeq != 0 {
+		extras = append(extras, extra)
+	}
+	return extras, nil
+}
+
+// writeThreadReplies 子区频道写入消息后更新子区和根消息的扩展数据
+// msgs为本次写入子区频道的所有消息（按seq从小到大）
+func (wk *wukongDB) writeThreadReplies(parentChannelId string, channelType uint8, rootMessageSeq uint64, msgs []Message, w Writer) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	thread, err := wk.GetThread(parentChannelId, channelType, rootMessageSeq)
+	if err != nil {
+		return err
+	}
+	if IsEmptyThread(thread) {
+		thread = Thread{
+			ChannelId:      parentChannelId,
+			ChannelType:    channelType,
+			RootMessageSeq: rootMessageSeq,
+		}
+		createdAt := time.Unix(int64(msgs[0].Timestamp), 0)
+		thread.CreatedAt = &createdAt
+
+		// 根消息的发送者是第一个参与者
+		rootMsg, err := wk.LoadMsg(parentChannelId, channelType, rootMessageSeq)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		if err == nil && rootMsg.FromUID != "" {
+			thread.Participants = append(thread.Participants, rootMsg.FromUID)
+		}
+	}
+
+	for _, msg := range msgs {
+		if uint64(msg.MessageSeq) > thread.ReplyCount {
+			thread.ReplyCount = uint64(msg.MessageSeq)
+		}
+		replyAt := time.Unix(int64(msg.Timestamp), 0)
+		if thread.LastReplyAt == nil || replyAt.After(*thread.LastReplyAt) {
+			thread.LastReplyAt = &replyAt
+		}
+		thread.Participants = addThreadParticipant(thread.Participants, msg.FromUID)
+	}
+
+	if err = wk.writeThread(thread, w); err != nil {
+		return err
+	}
+	return wk.writeThreadMessageExtra(thread, w)
+}
+
+// truncateThread 子区频道的日志被截断后更新子区的数据，messageSeq为截断后保留的最大seq
+func (wk *wukongDB) truncateThread(parentChannelId string, channelType uint8, rootMessageSeq uint64, messageSeq uint64, w Writer) error {
+	if messageSeq == 0 { // 没有回复了，删除子区
+		if err := w.DeleteRange(key.NewThreadColumnKey(parentChannelId, channelType, rootMessageSeq, key.MinColumnKey), key.NewThreadColumnKey(parentChannelId, channelType, rootMessageSeq, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+		return w.DeleteRange(key.NewMessageExtraColumnKey(parentChannelId, channelType, rootMessageSeq, key.MinColumnKey), key.NewMessageExtraColumnKey(parentChannelId, channelType, rootMessageSeq, key.MaxColumnKey), wk.noSync)
+	}
+	thread, err := wk.GetThread(parentChannelId, channelType, rootMessageSeq)
+	if err != nil {
+		return err
+	}
+	if IsEmptyThread(thread) || thread.ReplyCount <= messageSeq {
+		return nil
+	}
+	thread.ReplyCount = messageSeq
+	lastMsg, err := wk.LoadMsg(ThreadChannelId(parentChannelId, rootMessageSeq), channelType, messageSeq)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if err == nil {
+		lastReplyAt := time.Unix(int64(lastMsg.Timestamp), 0)
+		thread.LastReplyAt = &lastReplyAt
+	}
+	if err = wk.writeThread(thread, w); err != nil {
+		return err
+	}
+	return wk.writeThreadMessageExtra(thread, w)
+}
+
+func (wk *wukongDB) writeThread(thread Thread, w Writer) error {
+	var err error
+
+	// replyCount
+	replyCount := make([]byte, 8)
+	wk.endian.PutUint64(replyCount, thread.ReplyCount)
+	if err = w.Set(key.NewThreadColumnKey(thread.ChannelId, thread.ChannelType, thread.RootMessageSeq, key.TableThread.Column.ReplyCount), replyCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// lastReplyAt
+	if thread.LastReplyAt != nil {
+		if err = w.Set(key.NewThreadColumnKey(thread.ChannelId, thread.ChannelType, thread.RootMessageSeq, key.TableThread.Column.LastReplyAt), wk.timeBytes(*thread.LastReplyAt), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// participants
+	if err = w.Set(key.NewThreadColumnKey(thread.ChannelId, thread.ChannelType, thread.RootMessageSeq, key.TableThread.Column.Participants), encodeThreadParticipants(thread.Participants), wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if thread.CreatedAt != nil {
+		if err = w.Set(key.NewThreadColumnKey(thread.ChannelId, thread.ChannelType, thread.RootMessageSeq, key.TableThread.Column.CreatedAt), wk.timeBytes(*thread.CreatedAt), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeThreadMessageExtra 更新根消息的回复数量和最后回复时间
+func (wk *wukongDB) writeThreadMessageExtra(thread Thread, w Writer) error {
+	replyCount := make([]byte, 8)
+	wk.endian.PutUint64(replyCount, thread.ReplyCount)
+	if err := w.Set(key.NewMessageExtraColumnKey(thread.ChannelId, thread.ChannelType, thread.RootMessageSeq, key.TableMessageExtra.Column.ThreadReplyCount), replyCount, wk.noSync); err != nil {
+		return err
+	}
+	if thread.LastReplyAt != nil {
+		if err := w.Set(key.NewMessageExtraColumnKey(thread.ChannelId, thread.ChannelType, thread.RootMessageSeq, key.TableMessageExtra.Column.ThreadLastReplyAt), wk.timeBytes(*thread.LastReplyAt), wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) iterThread(iter Iterator, iterFnc func(t Thread) bool) error {
+	var (
+		preRootSeq     uint64
+		preThread      Thread
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		rootSeq, columnName, err := key.ParseThreadColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if rootSeq != preRootSeq {
+			if hasData {
+				if !iterFnc(preThread) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preRootSeq = rootSeq
+			preThread = Thread{RootMessageSeq: rootSeq}
+		}
+
+		switch columnName {
+		case key.TableThread.Column.ReplyCount:
+			preThread.ReplyCount = wk.endian.Uint64(iter.Value())
+		case key.TableThread.Column.LastReplyAt:
+			preThread.LastReplyAt = wk.parseTime(iter.Value())
+		case key.TableThread.Column.Participants:
+			participants, err := decodeThreadParticipants(iter.Value())
+			if err != nil {
+				return err
+			}
+			preThread.Participants = participants
+		case key.TableThread.Column.CreatedAt:
+			preThread.CreatedAt = wk.parseTime(iter.Value())
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preThread)
+	}
+	return nil
+}
+
+func (wk *wukongDB) timeBytes(t time.Time) []byte {
+	data := make([]byte, 8)
+	wk.endian.PutUint64(data, uint64(t.UnixNano()))
+	return data
+}
+
+func (wk *wukongDB) parseTime(data []byte) *time.Time {
+	tm := int64(wk.endian.Uint64(data))
+	if tm <= 0 {
+		return nil
+	}
+	t := time.Unix(tm/1e9, tm%1e9)
+	return &t
+}
+
+func threadLastReplyAt(t Thread) int64 {
+	if t.LastReplyAt == nil {
+		return 0
+	}
+	return t.LastReplyAt.UnixNano()
+}
+
+// addThreadParticipant 添加子区的参与者，已存在或超过最大数量时不添加
+func addThreadParticipant(participants []string, uid string) []string {
+	if uid == "" || len(participants) >= ThreadMaxParticipants {
+		return participants
+	}
+	for _, participant := range participants {
+		if participant == uid {
+			return participants
+		}
+	}
+	return append(participants, uid)
+}
+
+func encodeThreadParticipants(participants []string) []byte {
+	enc := wkproto.NewEncoder()
+	defer enc.End()
+	enc.WriteUint32(uint32(len(participants)))
+	for _, uid := range participants {
+		enc.WriteString(uid)
+	}
+	// 编码器结束后会被回收，需要拷贝
+	return append([]byte(nil), enc.Bytes()...)
+}
+
+func decodeThreadParticipants(data []byte) ([]string, error) {
+	dec := wkproto.NewDecoder(data)
+	count, err := dec.Uint32()
+	if err != nil {
+		return nil, err
+	}
+	participants := make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		uid, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, uid)
+	}
+	return participants, nil
+}
+
+// appendThreadReq 合并同一个子区频道的消息
+func appendThreadReq(reqs []AppendMessagesReq, req AppendMessagesReq) []AppendMessagesReq {
+	for i, r := range reqs {
+		if r.ChannelId == req.ChannelId && r.ChannelType == req.ChannelType {
+			reqs[i].Messages = append(append([]Message(nil), r.Messages...), req.Messages...)
+			return reqs
+		}
+	}
+	return append(reqs, req)
+}
@@ -0,0 +1,111 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newThreadTestMessage(seq uint32, fromUid string, timestamp int32) wkdb.Message {
+	return wkdb.Message{
+		RecvPacket: wkproto.RecvPacket{
+			MessageID:  int64(seq) + int64(timestamp)*1000,
+			MessageSeq: seq,
+			FromUID:    fromUid,
+			Timestamp:  timestamp,
+			Payload:    []byte("hello"),
+		},
+	}
+}
+
+func TestThreadChannelId(t *testing.T) {
+	channelId := wkdb.ThreadChannelId("g1", 10)
+	parentChannelId, rootMessageSeq, ok := wkdb.ParseThreadChannelId(channelId)
+	assert.True(t, ok)
+	assert.Equal(t, "g1", parentChannelId)
+	assert.Equal(t, uint64(10), rootMessageSeq)
+
+	_, _, ok = wkdb.ParseThreadChannelId("g1")
+	assert.False(t, ok)
+	_, _, ok = wkdb.ParseThreadChannelId("g1____threadx")
+	assert.False(t, ok)
+}
+
+func TestThreadReplies(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channelId := "g1"
+	channelType := uint8(2)
+	err = d.AppendMessages(channelId, channelType, []wkdb.Message{
+		newThreadTestMessage(1, "u1", 100),
+		newThreadTestMessage(2, "u2", 101),
+	})
+	assert.NoError(t, err)
+
+	threadChannelId := wkdb.ThreadChannelId(channelId, 1)
+	err = d.AppendMessages(threadChannelId, channelType, []wkdb.Message{
+		newThreadTestMessage(1, "u2", 200),
+		newThreadTestMessage(2, "u3", 201),
+	})
+	assert.NoError(t, err)
+
+	err = d.AppendMessages(threadChannelId, channelType, []wkdb.Message{
+		newThreadTestMessage(3, "u1", 202),
+		newThreadTestMessage(4, "u4", 203),
+	})
+	assert.NoError(t, err)
+	err = d.AppendMessages(wkdb.ThreadChannelId(channelId, 2), channelType, []wkdb.Message{newThreadTestMessage(1, "u1", 300)})
+	assert.NoError(t, err)
+
+	thread, err := d.GetThread(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), thread.ReplyCount)
+	assert.Equal(t, int64(203), thread.LastReplyAt.Unix())
+	assert.Equal(t, int64(200), thread.CreatedAt.Unix())
+	assert.Equal(t, []string{"u1", "u2", "u3", "u4"}, thread.Participants)
+
+	// 子区的消息不在父频道内
+	lastSeq, _, err := d.GetChannelLastMessageSeq(channelId, channelType)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), lastSeq)
+
+	threads, err := d.GetThreads(channelId, channelType, 0)
+	assert.NoError(t, err)
+	assert.Len(t, threads, 2)
+	assert.Equal(t, uint64(2), threads[0].RootMessageSeq)
+	assert.Equal(t, uint64(1), threads[1].RootMessageSeq)
+
+	extras, err := d.GetMessageExtras(channelId, channelType, 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, extras, 2)
+	assert.Equal(t, uint64(1), extras[0].MessageSeq)
+	assert.Equal(t, uint64(4), extras[0].ThreadReplyCount)
+	assert.Equal(t, uint64(1), extras[1].ThreadReplyCount)
+
+	// 截断子区日志
+	err = d.TruncateLogTo(threadChannelId, channelType, 3)
+	assert.NoError(t, err)
+	thread, err = d.GetThread(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), thread.ReplyCount)
+	assert.Equal(t, int64(201), thread.LastReplyAt.Unix())
+
+	err = d.TruncateLogTo(threadChannelId, channelType, 1)
+	assert.NoError(t, err)
+	thread, err = d.GetThread(channelId, channelType, 1)
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyThread(thread))
+	extras, err = d.GetMessageExtras(channelId, channelType, 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, extras, 1)
+	assert.Equal(t, uint64(2), extras[0].MessageSeq)
+}
@@ -2,7 +2,6 @@ package wkdb
 
 import (
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 )
 
 func (wk *wukongDB) IncMessageCount(v int) error {
@@ -13,7 +12,7 @@ func (wk *wukongDB) IncMessageCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Message)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -41,7 +40,7 @@ func (wk *wukongDB) IncUserCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.User)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -68,7 +67,7 @@ func (wk *wukongDB) IncDeviceCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Device)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -95,7 +94,7 @@ func (wk *wukongDB) IncSessionCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Session)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -122,7 +121,7 @@ func (wk *wukongDB) IncChannelCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Channel)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -149,7 +148,7 @@ func (wk *wukongDB) IncConversationCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Conversation)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -176,7 +175,7 @@ func (wk *wukongDB) GetTotalMessageCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Message)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
@@ -199,7 +198,7 @@ func (wk *wukongDB) GetTotalUserCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.User)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
@@ -222,7 +221,7 @@ func (wk *wukongDB) GetTotalDeviceCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Device)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
@@ -245,7 +244,7 @@ func (wk *wukongDB) GetTotalSessionCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Session)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
@@ -269,7 +268,7 @@ func (wk *wukongDB) GetTotalChannelCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Channel)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
@@ -292,7 +291,7 @@ func (wk *wukongDB) GetTotalConversationCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.Conversation)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
@@ -315,7 +314,7 @@ func (wk *wukongDB) IncChannelClusterConfigCount(v int) error {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.ChannelClusterConfig)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return err
 	}
 	if closer != nil {
@@ -342,7 +341,7 @@ func (wk *wukongDB) GetTotalChannelClusterConfigCount() (int, error) {
 
 	keyBytes := key.NewTotalColumnKey(key.TableTotal.Column.ChannelClusterConfig)
 	result, closer, err := db.Get(keyBytes)
-	if err != nil && err != pebble.ErrNotFound {
+	if err != nil && err != ErrNotFound {
 		return 0, err
 	}
 	if closer != nil {
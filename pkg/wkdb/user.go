@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
-	"github.com/cockroachdb/pebble"
 )
 
 func (wk *wukongDB) GetUser(uid string) (User, error) {
@@ -21,7 +20,7 @@ func (wk *wukongDB) GetUser(uid string) (User, error) {
 	}
 
 	db := wk.shardDB(uid)
-	iter := db.NewIter(&pebble.IterOptions{
+	iter := db.NewIter(&IterOptions{
 		LowerBound: key.NewUserColumnKey(id, key.MinColumnKey),
 		UpperBound: key.NewUserColumnKey(id, key.MaxColumnKey),
 	})
@@ -108,7 +107,7 @@ func (wk *wukongDB) SearchUser(req UserSearchReq) ([]User, error) {
 			}
 		}
 
-		iter := db.NewIter(&pebble.IterOptions{
+		iter := db.NewIter(&IterOptions{
 			LowerBound: key.NewUserSecondIndexKey(key.TableUser.SecondIndex.CreatedAt, start, 0),
 			UpperBound: key.NewUserSecondIndexKey(key.TableUser.SecondIndex.CreatedAt, end, 0),
 		})
@@ -132,7 +131,7 @@ func (wk *wukongDB) SearchUser(req UserSearchReq) ([]User, error) {
 				return nil, err
 			}
 
-			dataIter := db.NewIter(&pebble.IterOptions{
+			dataIter := db.NewIter(&IterOptions{
 				LowerBound: key.NewUserColumnKey(id, key.MinColumnKey),
 				UpperBound: key.NewUserColumnKey(id, key.MaxColumnKey),
 			})
@@ -237,7 +236,7 @@ func (wk *wukongDB) UpdateUser(u User) error {
 	return batch.Commit(wk.sync)
 }
 
-// func (wk *wukongDB) incUserDeviceCount(uid string, count int, db *pebble.DB) error {
+// func (wk *wukongDB) incUserDeviceCount(uid string, count int, db KV) error {
 
 // 	wk.dblock.userLock.Lock(uid)
 // 	defer wk.dblock.userLock.unlock(uid)
@@ -251,7 +250,7 @@ func (wk *wukongDB) UpdateUser(u User) error {
 // 	}
 
 // 	deviceCountBytes, closer, err := db.Get(key.NewUserColumnKey(id, key.TableUser.Column.DeviceCount))
-// 	if err != nil && err != pebble.ErrNotFound {
+// 	if err != nil && err != ErrNotFound {
 // 		return err
 // 	}
 // 	if closer != nil {
@@ -276,7 +275,7 @@ func (wk *wukongDB) getUserId(uid string) (uint64, error) {
 	// indexKey := key.NewUserIndexUidKey(uid)
 	// uidIndexValue, closer, err := wk.shardDB(uid).Get(indexKey)
 	// if err != nil {
-	// 	if err == pebble.ErrNotFound {
+	// 	if err == ErrNotFound {
 	// 		return 0, nil
 	// 	}
 	// 	return 0, err
@@ -291,7 +290,7 @@ func (wk *wukongDB) getUserId(uid string) (uint64, error) {
 	return key.HashWithString(uid), nil
 }
 
-func (wk *wukongDB) writeUser(u User, w pebble.Writer) error {
+func (wk *wukongDB) writeUser(u User, w Writer) error {
 	var (
 		err error
 	)
@@ -331,7 +330,7 @@ func (wk *wukongDB) writeUser(u User, w pebble.Writer) error {
 	return nil
 }
 
-func (wk *wukongDB) writeUserIndex(u User, w pebble.Writer) error {
+func (wk *wukongDB) writeUserIndex(u User, w Writer) error {
 	if u.CreatedAt != nil {
 		// createdAt
 		if err := w.Set(key.NewUserSecondIndexKey(key.TableUser.SecondIndex.CreatedAt, uint64(u.CreatedAt.UnixNano()), u.Id), nil, wk.noSync); err != nil {
@@ -349,7 +348,7 @@ func (wk *wukongDB) writeUserIndex(u User, w pebble.Writer) error {
 	return nil
 }
 
-func (wk *wukongDB) deleteUserIndex(u User, w pebble.Writer) error {
+func (wk *wukongDB) deleteUserIndex(u User, w Writer) error {
 	if u.CreatedAt != nil {
 		// createdAt
 		if err := w.Delete(key.NewUserSecondIndexKey(key.TableUser.SecondIndex.CreatedAt, uint64(u.CreatedAt.UnixNano()), u.Id), wk.noSync); err != nil {
@@ -368,7 +367,7 @@ func (wk *wukongDB) deleteUserIndex(u User, w pebble.Writer) error {
 	return nil
 }
 
-func (wk *wukongDB) iteratorUser(iter *pebble.Iterator, iterFnc func(u User) bool) error {
+func (wk *wukongDB) iteratorUser(iter Iterator, iterFnc func(u User) bool) error {
 	var (
 		preId          uint64
 		preUser        User
@@ -434,3 +433,34 @@ func (wk *wukongDB) iteratorUser(iter *pebble.Iterator, iterFnc func(u User) boo
 	}
 	return nil
 }
+
+// GetUsersAfter 用户分布在各个分片内，从每个分片读取主键大于id的limit个用户，合并后返回主键最小的limit个
+func (wk *wukongDB) GetUsersAfter(id uint64, limit int) ([]User, error) {
+	if id == math.MaxUint64 || limit <= 0 {
+		return nil, nil
+	}
+	users := make([]User, 0, limit)
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewUserColumnKey(id+1, key.MinColumnKey),
+			UpperBound: key.NewUserColumnKey(math.MaxUint64, key.MaxColumnKey),
+		})
+		count := 0
+		err := wk.iteratorUser(iter, func(u User) bool {
+			users = append(users, u)
+			count++
+			return count < limit
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Id < users[j].Id
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
@@ -0,0 +1,64 @@
+package wkdb
+
+import (
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+)
+
+// 用户的在线状态与用户存放在同一个db内
+
+func (wk *wukongDB) SetUserPresence(p UserPresence) error {
+	batch := wk.shardDB(p.Uid).NewBatch()
+	defer batch.Close()
+
+	if err := batch.Set(key.NewUserPresenceColumnKey(p.Uid, key.TableUserPresence.Column.Uid), []byte(p.Uid), wk.noSync); err != nil {
+		return err
+	}
+	if err := batch.Set(key.NewUserPresenceColumnKey(p.Uid, key.TableUserPresence.Column.Custom), []byte{p.Custom}, wk.noSync); err != nil {
+		return err
+	}
+	if err := batch.Set(key.NewUserPresenceColumnKey(p.Uid, key.TableUserPresence.Column.Text), []byte(p.Text), wk.noSync); err != nil {
+		return err
+	}
+	lastSeen := make([]byte, 8)
+	wk.endian.PutUint64(lastSeen, uint64(p.LastSeen))
+	if err := batch.Set(key.NewUserPresenceColumnKey(p.Uid, key.TableUserPresence.Column.LastSeen), lastSeen, wk.noSync); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetUserPresence(uid string) (UserPresence, error) {
+	iter := wk.shardDB(uid).NewIter(&IterOptions{
+		LowerBound: key.NewUserPresenceColumnKey(uid, key.MinColumnKey),
+		UpperBound: key.NewUserPresenceColumnKey(uid, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var p UserPresence
+	for iter.First(); iter.Valid(); iter.Next() {
+		_, columnName, err := key.ParseUserPresenceColumnKey(iter.Key())
+		if err != nil {
+			return EmptyUserPresence, err
+		}
+		value := iter.Value()
+		switch columnName {
+		case key.TableUserPresence.Column.Uid:
+			p.Uid = string(value)
+		case key.TableUserPresence.Column.Custom:
+			if len(value) > 0 {
+				p.Custom = value[0]
+			}
+		case key.TableUserPresence.Column.Text:
+			p.Text = string(value)
+		case key.TableUserPresence.Column.LastSeen:
+			if len(value) == 8 {
+				p.LastSeen = int64(wk.endian.Uint64(value))
+			}
+		}
+	}
+	// uid可能存在hash冲突，需要比较原始值
+	if p.Uid != uid {
+		return EmptyUserPresence, nil
+	}
+	return p, nil
+}
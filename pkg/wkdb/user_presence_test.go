@@ -0,0 +1,38 @@
+package wkdb_test
+
+import (
+	"testing"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAndGetUserPresence(t *testing.T) {
+
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	presence, err := d.GetUserPresence("u1")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyUserPresence(presence))
+
+	err = d.SetUserPresence(wkdb.UserPresence{Uid: "u1", Custom: 3, Text: "开会中", LastSeen: 1700000000})
+	assert.NoError(t, err)
+
+	presence, err = d.GetUserPresence("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, wkdb.UserPresence{Uid: "u1", Custom: 3, Text: "开会中", LastSeen: 1700000000}, presence)
+
+	// 用户数据被抹除后状态也被删除
+	err = d.EraseUser("u1")
+	assert.NoError(t, err)
+	presence, err = d.GetUserPresence("u1")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyUserPresence(presence))
+}
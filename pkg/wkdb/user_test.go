@@ -1,6 +1,7 @@
 package wkdb_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -125,3 +126,39 @@ func TestExistUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, exist)
 }
+
+func TestGetUsersAfter(t *testing.T) {
+	d := wkdb.NewWukongDB(wkdb.NewOptions(wkdb.WithDir(t.TempDir()), wkdb.WithShardNum(3)))
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for i := 0; i < 20; i++ {
+		err = d.AddUser(wkdb.User{Uid: fmt.Sprintf("u%d", i)})
+		assert.NoError(t, err)
+	}
+
+	// 分批遍历所有用户，主键递增且不重复
+	var (
+		cursor uint64
+		uids   = map[string]bool{}
+	)
+	for {
+		users, err := d.GetUsersAfter(cursor, 6)
+		assert.NoError(t, err)
+		if len(users) == 0 {
+			break
+		}
+		assert.LessOrEqual(t, len(users), 6)
+		for _, u := range users {
+			assert.Greater(t, u.Id, cursor)
+			cursor = u.Id
+			uids[u.Uid] = true
+		}
+	}
+	assert.Len(t, uids, 20)
+}
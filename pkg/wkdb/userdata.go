@@ -0,0 +1,497 @@
+package wkdb
+
+import (
+	"math"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb/key"
+	"go.uber.org/zap"
+)
+
+// 用户数据任务统一存放在默认的db内，按槽ID+任务ID存储
+func (wk *wukongDB) AddOrUpdateUserDataJob(j UserDataJob) error {
+	wk.dblock.userDataJobLock.lock(j.SlotId, j.Id)
+	defer wk.dblock.userDataJobLock.unlock(j.SlotId, j.Id)
+
+	batch := wk.defaultShardDB().NewBatch()
+	defer batch.Close()
+
+	if err := wk.writeUserDataJob(j, batch); err != nil {
+		return err
+	}
+	return batch.Commit(wk.sync)
+}
+
+func (wk *wukongDB) GetUserDataJob(slotId uint32, id uint64) (UserDataJob, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: key.NewUserDataJobColumnKey(slotId, id, key.MinColumnKey),
+		UpperBound: key.NewUserDataJobColumnKey(slotId, id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	job := EmptyUserDataJob
+	err := wk.iterUserDataJob(iter, func(j UserDataJob) bool {
+		job = j
+		return false
+	})
+	if err != nil {
+		return EmptyUserDataJob, err
+	}
+	return job, nil
+}
+
+func (wk *wukongDB) GetUserDataJobs(slotId uint32) ([]UserDataJob, error) {
+	iter := wk.defaultShardDB().NewIter(&IterOptions{
+		LowerBound: key.NewUserDataJobColumnKey(slotId, 0, key.MinColumnKey),
+		UpperBound: key.NewUserDataJobColumnKey(slotId, math.MaxUint64, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var jobs []UserDataJob
+	err := wk.iterUserDataJob(iter, func(j UserDataJob) bool {
+		jobs = append(jobs, j)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RemoveUserDataJob 移除用户数据任务
+func (wk *wukongDB) RemoveUserDataJob(slotId uint32, id uint64) error {
+	wk.dblock.userDataJobLock.lock(slotId, id)
+	defer wk.dblock.userDataJobLock.unlock(slotId, id)
+
+	return wk.defaultShardDB().DeleteRange(key.NewUserDataJobColumnKey(slotId, id, key.MinColumnKey), key.NewUserDataJobColumnKey(slotId, id, key.MaxColumnKey), wk.sync)
+}
+
+// EraseUser 用户、设备、最近会话和用户属性都与用户存放在同一个db内，在一个批次内删除
+func (wk *wukongDB) EraseUser(uid string) error {
+	if wk.opts.EnableCost {
+		start := time.Now()
+		defer func() {
+			cost := time.Since(start)
+			if cost.Milliseconds() > 200 {
+				wk.Info("EraseUser done", zap.Duration("cost", cost), zap.String("uid", uid))
+			}
+		}()
+	}
+
+	wk.dblock.attributeLock.lockUser(uid)
+	defer wk.dblock.attributeLock.unlockUser(uid)
+
+	db := wk.shardDB(uid)
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	// 用户
+	user, err := wk.GetUser(uid)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	if !IsEmptyUser(user) {
+		if err = wk.deleteUserIndex(user, batch); err != nil {
+			return err
+		}
+		if err = batch.DeleteRange(key.NewUserColumnKey(user.Id, key.MinColumnKey), key.NewUserColumnKey(user.Id, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// 设备
+	devices, err := wk.GetDevices(uid)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		if err = wk.deleteDeviceIndex(device, batch); err != nil {
+			return err
+		}
+		if err = batch.DeleteRange(key.NewDeviceColumnKey(device.Id, key.MinColumnKey), key.NewDeviceColumnKey(device.Id, key.MaxColumnKey), wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// 最近会话
+	conversations, err := wk.GetConversations(uid)
+	if err != nil {
+		return err
+	}
+	for _, conversation := range conversations {
+		if err = wk.deleteConversation(uid, conversation.ChannelId, conversation.ChannelType, batch); err != nil {
+			return err
+		}
+	}
+
+	// 用户属性
+	attrTable := wk.userAttributeTable(uid)
+	if err = batch.DeleteRange(attrTable.lowKey, attrTable.highKey, wk.noSync); err != nil {
+		return err
+	}
+
+	// 端到端加密的设备公钥和一次性预共享公钥
+	if err = batch.DeleteRange(key.NewE2EEDeviceKeyLowKey(uid), key.NewE2EEDeviceKeyHighKey(uid), wk.noSync); err != nil {
+		return err
+	}
+	if err = batch.DeleteRange(key.NewE2EEOneTimePrekeyUidLowKey(uid), key.NewE2EEOneTimePrekeyUidHighKey(uid), wk.noSync); err != nil {
+		return err
+	}
+
+	// 在线状态
+	if err = batch.DeleteRange(key.NewUserPresenceColumnKey(uid, key.MinColumnKey), key.NewUserPresenceColumnKey(uid, key.MaxColumnKey), wk.noSync); err != nil {
+		return err
+	}
+
+	return batch.Commit(wk.sync)
+}
+
+// GetSubscribedChannels 订阅者的唯一索引包含频道哈希和订阅者id，通过索引找到频道哈希后再从频道信息中获取频道id和类型
+func (wk *wukongDB) GetSubscribedChannels(uid string) ([]Channel, error) {
+	uidHash := key.HashWithString(uid)
+	var channels []Channel
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewTableIndexLowKey(key.TableSubscriber.Id),
+			UpperBound: key.NewTableIndexHighKey(key.TableSubscriber.Id),
+		})
+		var channelHashes []uint64
+		for iter.First(); iter.Valid(); iter.Next() {
+			channelHash, id, err := key.ParseSubscriberIndexKey(iter.Key())
+			if err != nil {
+				continue
+			}
+			if id == uidHash {
+				channelHashes = append(channelHashes, channelHash)
+			}
+		}
+		iter.Close()
+
+		for _, channelHash := range channelHashes {
+			channelInfo, err := wk.getChannelInfoById(db, channelHash)
+			if err != nil {
+				return nil, err
+			}
+			if IsEmptyChannelInfo(channelInfo) {
+				wk.Warn("subscribed channel not found", zap.String("uid", uid), zap.Uint64("channelHash", channelHash))
+				continue
+			}
+			channels = append(channels, Channel{
+				ChannelId:   channelInfo.ChannelId,
+				ChannelType: channelInfo.ChannelType,
+			})
+		}
+	}
+	return channels, nil
+}
+
+// GetMessagesOfFromUid 通过发送者索引按db依次遍历，cursor为上一页返回的位置
+func (wk *wukongDB) GetMessagesOfFromUid(uid string, cursor MessageCursor, limit int) ([]Message, MessageCursor, error) {
+	var msgs []Message
+	for shard := cursor.Shard; shard < uint32(len(wk.dbs)); shard++ {
+		db := wk.dbs[shard]
+		lowPrimary := minMessagePrimaryKey
+		if shard == cursor.Shard {
+			lowPrimary = cursor.Primary
+		}
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewMessageSecondIndexFromUidKey(uid, lowPrimary),
+			UpperBound: key.NewMessageSecondIndexFromUidKey(uid, maxMessagePrimaryKey),
+		})
+		for iter.First(); iter.Valid(); iter.Next() {
+			primary, err := key.ParseMessageSecondIndexKey(iter.Key())
+			if err != nil {
+				iter.Close()
+				return nil, cursor, err
+			}
+			// 上一页的最后一条消息
+			if shard == cursor.Shard && primary == cursor.Primary {
+				continue
+			}
+			msg, err := wk.getMessageByPrimary(db, primary)
+			if err != nil {
+				iter.Close()
+				return nil, cursor, err
+			}
+			cursor = MessageCursor{Shard: shard, Primary: primary}
+			// uid的哈希冲突时不是同一个用户
+			if msg.FromUID != uid {
+				continue
+			}
+			msgs = append(msgs, msg)
+			if limit > 0 && len(msgs) >= limit {
+				iter.Close()
+				return msgs, cursor, nil
+			}
+		}
+		iter.Close()
+		cursor = MessageCursor{Shard: shard + 1}
+	}
+	cursor.Done = true
+	return msgs, cursor, nil
+}
+
+func (wk *wukongDB) AnonymizeMessagesOfFromUid(uid string, limit int) (int, error) {
+	var count int
+	for _, db := range wk.dbs {
+		iter := db.NewIter(&IterOptions{
+			LowerBound: key.NewMessageSecondIndexFromUidKey(uid, minMessagePrimaryKey),
+			UpperBound: key.NewMessageSecondIndexFromUidKey(uid, maxMessagePrimaryKey),
+		})
+		batch := db.NewBatch()
+		for iter.First(); iter.Valid(); iter.Next() {
+			primary, err := key.ParseMessageSecondIndexKey(iter.Key())
+			if err != nil {
+				wk.Warn("parse message second index key failed", zap.Error(err))
+				continue
+			}
+			msg, err := wk.getMessageByPrimary(db, primary)
+			if err != nil {
+				iter.Close()
+				batch.Close()
+				return count, err
+			}
+			if msg.FromUID != uid {
+				continue
+			}
+			if err = wk.anonymizeMessage(db, primary, msg, batch); err != nil {
+				iter.Close()
+				batch.Close()
+				return count, err
+			}
+			count++
+			if limit > 0 && count >= limit {
+				break
+			}
+		}
+		iter.Close()
+		err := batch.Commit(wk.sync)
+		batch.Close()
+		if err != nil {
+			return count, err
+		}
+		if limit > 0 && count >= limit {
+			break
+		}
+	}
+	return count, nil
+}
+
+// anonymizeMessage 清空消息的发送者和内容，消息本身保留以免频道的消息序号出现空洞
+func (wk *wukongDB) anonymizeMessage(db KV, primary [16]byte, msg Message, w Writer) error {
+	if err := w.Set(key.NewMessageColumnKeyWithPrimary(primary, key.TableMessage.Column.FromUid), nil, wk.noSync); err != nil {
+		return err
+	}
+	if err := w.Set(key.NewMessageColumnKeyWithPrimary(primary, key.TableMessage.Column.Payload), nil, wk.noSync); err != nil {
+		return err
+	}
+	if err := w.Delete(key.NewMessageSecondIndexFromUidKey(msg.FromUID, primary), wk.noSync); err != nil {
+		return err
+	}
+	channelHash := wk.endian.Uint64(primary[:8])
+	messageSeq := uint64(msg.MessageSeq)
+	return wk.removeMessageSearchIndexRange(db, channelHash, messageSeq, messageSeq+1, w)
+}
+
+func (wk *wukongDB) getMessageByPrimary(db KV, primary [16]byte) (Message, error) {
+	iter := db.NewIter(&IterOptions{
+		LowerBound: key.NewMessageColumnKeyWithPrimary(primary, key.MinColumnKey),
+		UpperBound: key.NewMessageColumnKeyWithPrimary(primary, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	var msg Message
+	err := wk.iteratorChannelMessages(iter, 0, func(m Message) bool {
+		msg = m
+		return false
+	})
+	return msg, err
+}
+
+func (wk *wukongDB) getChannelInfoById(db KV, id uint64) (ChannelInfo, error) {
+	iter := db.NewIter(&IterOptions{
+		LowerBound: key.NewChannelInfoColumnKey(id, key.MinColumnKey),
+		UpperBound: key.NewChannelInfoColumnKey(id, key.MaxColumnKey),
+	})
+	defer iter.Close()
+
+	channelInfo := EmptyChannelInfo
+	err := wk.iterChannelInfo(iter, func(c ChannelInfo) bool {
+		channelInfo = c
+		return false
+	})
+	return channelInfo, err
+}
+
+func (wk *wukongDB) writeUserDataJob(j UserDataJob, w Writer) error {
+	var err error
+
+	// uid
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.Uid), []byte(j.Uid), wk.noSync); err != nil {
+		return err
+	}
+
+	// type
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.Type), []byte{uint8(j.Type)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// status
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.Status), []byte{uint8(j.Status)}, wk.noSync); err != nil {
+		return err
+	}
+
+	// operator
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.Operator), []byte(j.Operator), wk.noSync); err != nil {
+		return err
+	}
+
+	// nodeId
+	nodeId := make([]byte, 8)
+	wk.endian.PutUint64(nodeId, j.NodeId)
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.NodeId), nodeId, wk.noSync); err != nil {
+		return err
+	}
+
+	// archive
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.Archive), []byte(j.Archive), wk.noSync); err != nil {
+		return err
+	}
+
+	// error
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.Error), []byte(j.Error), wk.noSync); err != nil {
+		return err
+	}
+
+	// channelCount
+	channelCount := make([]byte, 8)
+	wk.endian.PutUint64(channelCount, j.ChannelCount)
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.ChannelCount), channelCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// messageCount
+	messageCount := make([]byte, 8)
+	wk.endian.PutUint64(messageCount, j.MessageCount)
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.MessageCount), messageCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// conversationCount
+	conversationCount := make([]byte, 8)
+	wk.endian.PutUint64(conversationCount, j.ConversationCount)
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.ConversationCount), conversationCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// deviceCount
+	deviceCount := make([]byte, 8)
+	wk.endian.PutUint64(deviceCount, j.DeviceCount)
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.DeviceCount), deviceCount, wk.noSync); err != nil {
+		return err
+	}
+
+	// erasedNodes
+	erasedNodes := make([]byte, 8*len(j.ErasedNodes))
+	for i, nodeId := range j.ErasedNodes {
+		wk.endian.PutUint64(erasedNodes[i*8:], nodeId)
+	}
+	if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.ErasedNodes), erasedNodes, wk.noSync); err != nil {
+		return err
+	}
+
+	// createdAt
+	if j.CreatedAt != nil {
+		createdAt := make([]byte, 8)
+		wk.endian.PutUint64(createdAt, uint64(j.CreatedAt.UnixNano()))
+		if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.CreatedAt), createdAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+
+	// updatedAt
+	if j.UpdatedAt != nil {
+		updatedAt := make([]byte, 8)
+		wk.endian.PutUint64(updatedAt, uint64(j.UpdatedAt.UnixNano()))
+		if err = w.Set(key.NewUserDataJobColumnKey(j.SlotId, j.Id, key.TableUserDataJob.Column.UpdatedAt), updatedAt, wk.noSync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) iterUserDataJob(iter Iterator, iterFnc func(j UserDataJob) bool) error {
+	var (
+		preId          uint64
+		preJob         UserDataJob
+		lastNeedAppend bool = true
+		hasData        bool = false
+	)
+	for iter.First(); iter.Valid(); iter.Next() {
+		slotId, id, columnName, err := key.ParseUserDataJobColumnKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		if id != preId {
+			if preId != 0 {
+				if !iterFnc(preJob) {
+					lastNeedAppend = false
+					break
+				}
+			}
+			preId = id
+			preJob = UserDataJob{
+				Id:     id,
+				SlotId: slotId,
+			}
+		}
+
+		switch columnName {
+		case key.TableUserDataJob.Column.Uid:
+			preJob.Uid = string(iter.Value())
+		case key.TableUserDataJob.Column.Type:
+			preJob.Type = UserDataJobType(iter.Value()[0])
+		case key.TableUserDataJob.Column.Status:
+			preJob.Status = UserDataJobStatus(iter.Value()[0])
+		case key.TableUserDataJob.Column.Operator:
+			preJob.Operator = string(iter.Value())
+		case key.TableUserDataJob.Column.NodeId:
+			preJob.NodeId = wk.endian.Uint64(iter.Value())
+		case key.TableUserDataJob.Column.Archive:
+			preJob.Archive = string(iter.Value())
+		case key.TableUserDataJob.Column.Error:
+			preJob.Error = string(iter.Value())
+		case key.TableUserDataJob.Column.ChannelCount:
+			preJob.ChannelCount = wk.endian.Uint64(iter.Value())
+		case key.TableUserDataJob.Column.MessageCount:
+			preJob.MessageCount = wk.endian.Uint64(iter.Value())
+		case key.TableUserDataJob.Column.ConversationCount:
+			preJob.ConversationCount = wk.endian.Uint64(iter.Value())
+		case key.TableUserDataJob.Column.DeviceCount:
+			preJob.DeviceCount = wk.endian.Uint64(iter.Value())
+		case key.TableUserDataJob.Column.ErasedNodes:
+			value := iter.Value()
+			preJob.ErasedNodes = nil
+			for i := 0; i+8 <= len(value); i += 8 {
+				preJob.ErasedNodes = append(preJob.ErasedNodes, wk.endian.Uint64(value[i:]))
+			}
+		case key.TableUserDataJob.Column.CreatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				createdAt := time.Unix(tm/1e9, tm%1e9)
+				preJob.CreatedAt = &createdAt
+			}
+		case key.TableUserDataJob.Column.UpdatedAt:
+			tm := int64(wk.endian.Uint64(iter.Value()))
+			if tm > 0 {
+				updatedAt := time.Unix(tm/1e9, tm%1e9)
+				preJob.UpdatedAt = &updatedAt
+			}
+		}
+		hasData = true
+	}
+	if lastNeedAppend && hasData {
+		_ = iterFnc(preJob)
+	}
+	return nil
+}
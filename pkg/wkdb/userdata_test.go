@@ -0,0 +1,233 @@
+package wkdb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/WuKongIM/WuKongIM/pkg/wkdb"
+	wkproto "github.com/WuKongIM/WuKongIMGoProto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserDataJob(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	job := wkdb.UserDataJob{
+		Id:        1,
+		SlotId:    10,
+		Uid:       "u1",
+		Type:      wkdb.UserDataJobTypeErase,
+		Status:    wkdb.UserDataJobStatusRunning,
+		Operator:  "admin",
+		CreatedAt: &createdAt,
+		UpdatedAt: &createdAt,
+	}
+	err = d.AddOrUpdateUserDataJob(job)
+	assert.NoError(t, err)
+
+	job.Status = wkdb.UserDataJobStatusDone
+	job.MessageCount = 3
+	job.ErasedNodes = []uint64{1, 2}
+	err = d.AddOrUpdateUserDataJob(job)
+	assert.NoError(t, err)
+
+	// 其他槽的任务
+	err = d.AddOrUpdateUserDataJob(wkdb.UserDataJob{Id: 2, SlotId: 11, Uid: "u2", Type: wkdb.UserDataJobTypeExport})
+	assert.NoError(t, err)
+
+	result, err := d.GetUserDataJob(10, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", result.Uid)
+	assert.Equal(t, wkdb.UserDataJobTypeErase, result.Type)
+	assert.Equal(t, wkdb.UserDataJobStatusDone, result.Status)
+	assert.Equal(t, "admin", result.Operator)
+	assert.Equal(t, uint64(3), result.MessageCount)
+	assert.Equal(t, []uint64{1, 2}, result.ErasedNodes)
+	assert.Equal(t, createdAt.Unix(), result.CreatedAt.Unix())
+
+	result, err = d.GetUserDataJob(10, 2)
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyUserDataJob(result))
+
+	jobs, err := d.GetUserDataJobs(11)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, "u2", jobs[0].Uid)
+
+	err = d.RemoveUserDataJob(11, 2)
+	assert.NoError(t, err)
+
+	jobs, err = d.GetUserDataJobs(11)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(jobs))
+
+	result, err = d.GetUserDataJob(10, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", result.Uid)
+}
+
+func TestEraseUser(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	createdAt := time.Now()
+	for _, uid := range []string{"u1", "u2"} {
+		err = d.AddUser(wkdb.User{Uid: uid, CreatedAt: &createdAt, UpdatedAt: &createdAt})
+		assert.NoError(t, err)
+		err = d.AddDevice(wkdb.Device{Id: d.NextPrimaryKey(), Uid: uid, Token: "token", DeviceFlag: 1, CreatedAt: &createdAt, UpdatedAt: &createdAt})
+		assert.NoError(t, err)
+		err = d.AddOrUpdateConversations(uid, []wkdb.Conversation{
+			{Id: d.NextPrimaryKey(), Uid: uid, ChannelId: "g1", ChannelType: 2, CreatedAt: &createdAt, UpdatedAt: &createdAt},
+		})
+		assert.NoError(t, err)
+		err = d.SetUserAttributes(uid, []wkdb.Attribute{{Name: "age", Type: wkdb.AttributeTypeInt, Value: []byte(`18`)}}, false)
+		assert.NoError(t, err)
+	}
+
+	err = d.EraseUser("u1")
+	assert.NoError(t, err)
+
+	user, err := d.GetUser("u1")
+	assert.NoError(t, err)
+	assert.True(t, wkdb.IsEmptyUser(user))
+	devices, err := d.GetDevices("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(devices))
+	conversations, err := d.GetConversations("u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(conversations))
+	attrs, err := d.GetUserAttributes("u1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(attrs))
+
+	// 其他用户不受影响
+	user, err = d.GetUser("u2")
+	assert.NoError(t, err)
+	assert.Equal(t, "u2", user.Uid)
+	devices, err = d.GetDevices("u2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(devices))
+	conversations, err = d.GetConversations("u2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(conversations))
+}
+
+func TestGetSubscribedChannels(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	channels := []wkdb.Channel{
+		{ChannelId: "g1", ChannelType: 2},
+		{ChannelId: "g2", ChannelType: 2},
+		{ChannelId: "g3", ChannelType: 2},
+	}
+	for _, ch := range channels {
+		_, err = d.AddChannel(wkdb.ChannelInfo{ChannelId: ch.ChannelId, ChannelType: ch.ChannelType})
+		assert.NoError(t, err)
+	}
+	err = d.AddSubscribers("g1", 2, []wkdb.Member{{Uid: "u1"}, {Uid: "u2"}})
+	assert.NoError(t, err)
+	err = d.AddSubscribers("g2", 2, []wkdb.Member{{Uid: "u2"}})
+	assert.NoError(t, err)
+	err = d.AddSubscribers("g3", 2, []wkdb.Member{{Uid: "u1"}})
+	assert.NoError(t, err)
+
+	result, err := d.GetSubscribedChannels("u1")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []wkdb.Channel{channels[0], channels[2]}, result)
+}
+
+func TestMessagesOfFromUid(t *testing.T) {
+	d := newTestDB(t)
+	err := d.Open()
+	assert.NoError(t, err)
+
+	defer func() {
+		err := d.Close()
+		assert.NoError(t, err)
+	}()
+
+	for _, channelId := range []string{"g1", "g2", "g3"} {
+		var messages []wkdb.Message
+		for i := 0; i < 4; i++ {
+			fromUid := "u1"
+			if i%2 == 1 {
+				fromUid = "u2"
+			}
+			messages = append(messages, wkdb.Message{
+				RecvPacket: wkproto.RecvPacket{
+					MessageID:   int64(len(channelId)*100 + i + 1),
+					ChannelID:   channelId,
+					ChannelType: 2,
+					FromUID:     fromUid,
+					MessageSeq:  uint32(i + 1),
+					Payload:     []byte("hello"),
+				},
+			})
+		}
+		err = d.AppendMessages(channelId, 2, messages)
+		assert.NoError(t, err)
+	}
+
+	// 分页获取
+	var (
+		msgs   []wkdb.Message
+		cursor wkdb.MessageCursor
+	)
+	for !cursor.Done {
+		var page []wkdb.Message
+		page, cursor, err = d.GetMessagesOfFromUid("u1", cursor, 2)
+		assert.NoError(t, err)
+		msgs = append(msgs, page...)
+	}
+	assert.Equal(t, 6, len(msgs))
+	for _, m := range msgs {
+		assert.Equal(t, "u1", m.FromUID)
+	}
+
+	count, err := d.AnonymizeMessagesOfFromUid("u1", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+	count, err = d.AnonymizeMessagesOfFromUid("u1", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = d.AnonymizeMessagesOfFromUid("u1", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	msgs, cursor, err = d.GetMessagesOfFromUid("u1", wkdb.MessageCursor{}, 0)
+	assert.NoError(t, err)
+	assert.True(t, cursor.Done)
+	assert.Equal(t, 0, len(msgs))
+
+	// 消息保留，发送者和内容被清空
+	m, err := d.LoadMsg("g1", 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "", m.FromUID)
+	assert.Equal(t, 0, len(m.Payload))
+
+	m, err = d.LoadMsg("g1", 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "u2", m.FromUID)
+	assert.Equal(t, []byte("hello"), m.Payload)
+}
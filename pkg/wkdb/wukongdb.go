@@ -3,10 +3,8 @@ package wkdb
 import (
 	"context"
 	"encoding/binary"
-	"fmt"
 	"hash"
 	"hash/fnv"
-	"path/filepath"
 	"time"
 
 	"github.com/WuKongIM/WuKongIM/pkg/trace"
@@ -20,14 +18,14 @@ import (
 var _ DB = (*wukongDB)(nil)
 
 type wukongDB struct {
-	dbs      []*pebble.DB
+	dbs      []KV
 	shardNum uint32 // 分区数量，这个一但设置就不能修改
 	opts     *Options
-	sync     *pebble.WriteOptions
+	sync     *WriteOptions
 	endian   binary.ByteOrder
 	wklog.Log
 	prmaryKeyGen *snowflake.Node // 消息ID生成器
-	noSync       *pebble.WriteOptions
+	noSync       *WriteOptions
 	dblock       *dblock
 	cancelCtx    context.Context
 	cancelFunc   context.CancelFunc
@@ -49,14 +47,10 @@ func NewWukongDB(opts *Options) DB {
 		cancelCtx:    cancelCtx,
 		cancelFunc:   cancelFunc,
 		h:            fnv.New32(),
-		sync: &pebble.WriteOptions{
-			Sync: true,
-		},
-		noSync: &pebble.WriteOptions{
-			Sync: false,
-		},
-		Log:    wklog.NewWKLog("wukongDB"),
-		dblock: newDBLock(),
+		sync:         Sync,
+		noSync:       NoSync,
+		Log:          wklog.NewWKLog("wukongDB"),
+		dblock:       newDBLock(),
 	}
 }
 
@@ -96,10 +90,14 @@ func (wk *wukongDB) Open() error {
 
 	wk.dblock.start()
 
-	opts := wk.defaultPebbleOptions()
+	engine := wk.opts.Engine
+	if engine == nil { // 默认使用pebble
+		opts := wk.defaultPebbleOptions()
+		opts.ReadOnly = wk.opts.ReadOnly
+		engine = NewPebbleEngine(wk.opts.DataDir, opts)
+	}
 	for i := 0; i < int(wk.shardNum); i++ {
-
-		db, err := pebble.Open(filepath.Join(wk.opts.DataDir, "wukongimdb", fmt.Sprintf("shard%03d", i)), opts)
+		db, err := engine.OpenShard(uint32(i))
 		if err != nil {
 			return err
 		}
@@ -122,7 +120,23 @@ func (wk *wukongDB) Close() error {
 	return nil
 }
 
-func (wk *wukongDB) shardDB(v string) *pebble.DB {
+// Checkpoint 在指定目录下为每个分片生成一致性快照，目录结构与数据目录一致
+// 分片是逐个生成快照的，每个分片是各自时间点的一致性快照，分片之间不是同一时间点：
+// 快照期间的写入可能只出现在后生成快照的分片里，跨分片的数据（例如频道消息和订阅者的最近会话）恢复后可能不一致
+func (wk *wukongDB) Checkpoint(dir string) error {
+	for i, db := range wk.dbs {
+		checkpointer, ok := db.(Checkpointer)
+		if !ok {
+			return ErrNotSupported
+		}
+		if err := checkpointer.Checkpoint(pebbleShardDir(dir, uint32(i))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (wk *wukongDB) shardDB(v string) KV {
 	shardId := wk.shardId(v)
 	return wk.dbs[shardId]
 }
@@ -140,11 +154,11 @@ func (wk *wukongDB) shardId(v string) uint32 {
 	return h.Sum32() % wk.shardNum
 }
 
-func (wk *wukongDB) shardDBById(id uint32) *pebble.DB {
+func (wk *wukongDB) shardDBById(id uint32) KV {
 	return wk.dbs[id]
 }
 
-func (wk *wukongDB) defaultShardDB() *pebble.DB {
+func (wk *wukongDB) defaultShardDB() KV {
 	return wk.dbs[0]
 }
 
@@ -169,7 +183,11 @@ func (wk *wukongDB) collectMetricsLoop() {
 func (wk *wukongDB) collectMetrics() {
 
 	for i := uint32(0); i < uint32(wk.shardNum); i++ {
-		ms := wk.dbs[i].Metrics()
+		pdb, ok := wk.dbs[i].(*pebbleKV) // 只有pebble引擎有运行指标
+		if !ok {
+			return
+		}
+		ms := pdb.Metrics()
 
 		// ========== compact 压缩相关 ==========
 		trace.GlobalTrace.Metrics.DB().CompactTotalCountSet(i, ms.Compact.Count)
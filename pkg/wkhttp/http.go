@@ -135,6 +135,32 @@ func (c *Context) Forward(url string) {
 	c.ForwardWithBody(url, bodyBytes)
 }
 
+// ForwardRaw 原样转发请求，响应的状态码、header和body都不做修改（用于转发文件下载等非json的响应）
+func (c *Context) ForwardRaw(url string) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, url, c.Request.Body)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	req.URL.RawQuery = c.Request.URL.RawQuery
+	req.Header = c.Request.Header.Clone()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.ResponseError(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(key, value)
+		}
+	}
+	c.Writer.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(c.Writer, resp.Body)
+}
+
 // CopyRequestHeader 复制request的header参数
 func (c *Context) CopyRequestHeader(request *http.Request) map[string]string {
 	headerMap := map[string]string{}
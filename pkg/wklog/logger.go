@@ -120,6 +120,16 @@ func newEncoderConfig() zapcore.EncoderConfig {
 	}
 }
 
+// SetLevel 修改日志级别，运行时重新加载配置时调用
+func SetLevel(level zapcore.Level) {
+	atom.SetLevel(level)
+}
+
+// GetLevel 当前的日志级别
+func GetLevel() zapcore.Level {
+	return atom.Level()
+}
+
 // func timeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 // 	enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
 // }